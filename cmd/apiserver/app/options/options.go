@@ -23,6 +23,7 @@ import (
 
 	"github.com/kubesphere/ks-devops/pkg/client/cache"
 	"github.com/kubesphere/ks-devops/pkg/client/devops/jclient"
+	"github.com/kubesphere/ks-devops/pkg/client/devops/sharding"
 	"github.com/kubesphere/ks-devops/pkg/client/sonarqube"
 	v1 "k8s.io/api/core/v1"
 	"sigs.k8s.io/controller-runtime/pkg/manager"
@@ -182,6 +183,15 @@ func (s *ServerRunOptions) NewAPIServer(stopCh <-chan struct{}) (*apiserver.APIS
 	}
 	apiServer.Client = m.GetClient()
 	apiServer.RuntimeCache = m.GetCache()
+
+	if apiServer.DevopsClient != nil && len(s.JenkinsOptions.Instances) > 0 {
+		// route the requests of the DevOps projects to their Jenkins instances
+		shardingClient, err := sharding.NewClientFromOptions(s.JenkinsOptions, apiServer.Client)
+		if err != nil {
+			return nil, err
+		}
+		apiServer.DevopsClient = shardingClient
+	}
 	apiServer.Server = server
 	return apiServer, nil
 }
@@ -19,10 +19,14 @@ package app
 import (
 	"github.com/kubesphere/ks-devops/controllers/addon"
 	"github.com/kubesphere/ks-devops/controllers/argocd"
+	"github.com/kubesphere/ks-devops/controllers/deploytarget"
 	"github.com/kubesphere/ks-devops/controllers/fluxcd"
 	"github.com/kubesphere/ks-devops/controllers/gitrepository"
+	"github.com/kubesphere/ks-devops/controllers/imageupdater"
 	"github.com/kubesphere/ks-devops/controllers/jenkins/devopscredential"
 	"github.com/kubesphere/ks-devops/controllers/jenkins/devopsproject"
+	jenkinshealth "github.com/kubesphere/ks-devops/controllers/jenkins/health"
+	"github.com/kubesphere/ks-devops/controllers/jenkins/jenkinsimport"
 	"github.com/kubesphere/ks-devops/pkg/server/errors"
 
 	"github.com/jenkins-zh/jenkins-client/pkg/core"
@@ -31,20 +35,37 @@ import (
 	jenkinspipeline "github.com/kubesphere/ks-devops/controllers/jenkins/pipeline"
 	"github.com/kubesphere/ks-devops/controllers/jenkins/pipelinerun"
 	"github.com/kubesphere/ks-devops/pkg/client/devops"
+	jenkinsclient "github.com/kubesphere/ks-devops/pkg/client/devops/jenkins"
+	"github.com/kubesphere/ks-devops/pkg/client/devops/sharding"
 	"github.com/kubesphere/ks-devops/pkg/client/k8s"
+	"github.com/kubesphere/ks-devops/pkg/client/sonarqube"
 	"github.com/kubesphere/ks-devops/pkg/informers"
+	"github.com/kubesphere/ks-devops/pkg/kapis/devops/v1alpha3/gitops"
 	"k8s.io/klog/v2"
 	"sigs.k8s.io/controller-runtime/pkg/manager"
 )
 
 func addControllers(mgr manager.Manager, client k8s.Client, informerFactory informers.InformerFactory,
-	devopsClient devops.Interface, jenkinsCore core.JenkinsCore,
+	devopsClient devops.Interface, jenkinsCore core.JenkinsCore, jenkinsHealth *jenkinsclient.HealthMonitor,
 	s *options.DevOpsControllerManagerOptions) error {
 	if devopsClient == nil {
 		return errors.New("devopsClient should not be nil")
 	}
 
-	reconcilers := getAllControllers(mgr, client, informerFactory, devopsClient, s, jenkinsCore)
+	// it's not nil if the requests to Jenkins go through the circuit breaker
+	var jenkinsHealthChecker jenkinsclient.HealthChecker
+	if jenkinsHealth != nil {
+		if err := addHealthReporters(mgr, devopsClient, jenkinsHealth, s); err != nil {
+			klog.Errorf("unable to create the Jenkins health reporter, err: %v", err)
+			return err
+		}
+		jenkinsHealthChecker = jenkinsHealth
+	}
+	// they are not nil if the DevOps projects are placed among multiple Jenkins instances
+	jenkinsCores, _ := devopsClient.(sharding.CoreGetter)
+	jenkinsHealths, _ := devopsClient.(sharding.HealthGetter)
+
+	reconcilers := getAllControllers(mgr, client, informerFactory, devopsClient, s, jenkinsCore, jenkinsHealthChecker, jenkinsHealths)
 	reconcilers["pipeline"] = func(mgr manager.Manager) (err error) {
 		// add PipelineRun controller
 		if err = (&pipelinerun.Reconciler{
@@ -52,7 +73,10 @@ func addControllers(mgr manager.Manager, client k8s.Client, informerFactory info
 			Scheme:               mgr.GetScheme(),
 			DevOpsClient:         devopsClient,
 			JenkinsCore:          jenkinsCore,
+			JenkinsCores:         jenkinsCores,
 			PipelineRunDataStore: s.FeatureOptions.PipelineRunDataStore,
+			Health:               jenkinsHealthChecker,
+			Healths:              jenkinsHealths,
 		}).SetupWithManager(mgr); err != nil {
 			klog.Errorf("unable to create pipelinerun-controller, err: %v", err)
 			return
@@ -60,17 +84,35 @@ func addControllers(mgr manager.Manager, client k8s.Client, informerFactory info
 
 		// add PipelineRun Synchronizer
 		if err = (&pipelinerun.SyncReconciler{
-			Client:      mgr.GetClient(),
-			JenkinsCore: jenkinsCore,
+			Client:       mgr.GetClient(),
+			JenkinsCore:  jenkinsCore,
+			JenkinsCores: jenkinsCores,
 		}).SetupWithManager(mgr); err != nil {
 			klog.Errorf("unable to create pipelinerun-synchronizer, err: %v", err)
 			return
 		}
 
+		// add the controller which triggers the downstream Pipelines
+		if err = (&pipelinerun.DownstreamReconciler{
+			Client: mgr.GetClient(),
+		}).SetupWithManager(mgr); err != nil {
+			klog.Errorf("unable to create pipelinerun-downstream-trigger, err: %v", err)
+			return
+		}
+
+		// add the controller which creates PipelineRuns according to the schedules of Pipelines
+		if err = (&pipelinerun.ScheduleReconciler{
+			Client: mgr.GetClient(),
+		}).SetupWithManager(mgr); err != nil {
+			klog.Errorf("unable to create pipelinerun-scheduler, err: %v", err)
+			return
+		}
+
 		// add Pipeline metadata controller
 		err = (&jenkinspipeline.Reconciler{
-			Client:      mgr.GetClient(),
-			JenkinsCore: jenkinsCore,
+			Client:       mgr.GetClient(),
+			JenkinsCore:  jenkinsCore,
+			JenkinsCores: jenkinsCores,
 		}).SetupWithManager(mgr)
 		return
 	}
@@ -91,8 +133,35 @@ func addControllers(mgr manager.Manager, client k8s.Client, informerFactory info
 	return nil
 }
 
+// addHealthReporters reports the health of every Jenkins instance into its own ConfigMap
+func addHealthReporters(mgr manager.Manager, devopsClient devops.Interface, jenkinsHealth *jenkinsclient.HealthMonitor,
+	s *options.DevOpsControllerManagerOptions) (err error) {
+	monitors := map[string]*jenkinsclient.HealthMonitor{jenkinsclient.DefaultInstanceName: jenkinsHealth}
+	if shardingClient, ok := devopsClient.(*sharding.Client); ok {
+		registry := shardingClient.Registry()
+		for _, name := range registry.Names() {
+			if instance, _ := registry.Get(name); instance != nil && instance.Health != nil {
+				monitors[name] = instance.Health
+			}
+		}
+	}
+
+	for name, monitor := range monitors {
+		if err = (&jenkinshealth.Reporter{
+			Client:    mgr.GetClient(),
+			Namespace: s.JenkinsOptions.Namespace,
+			Instance:  name,
+			Monitor:   monitor,
+		}).SetupWithManager(mgr); err != nil {
+			return
+		}
+	}
+	return
+}
+
 func getAllControllers(mgr manager.Manager, client k8s.Client, informerFactory informers.InformerFactory,
-	devopsClient devops.Interface, s *options.DevOpsControllerManagerOptions, jenkinsCore core.JenkinsCore) map[string]func(mgr manager.Manager) error {
+	devopsClient devops.Interface, s *options.DevOpsControllerManagerOptions, jenkinsCore core.JenkinsCore,
+	jenkinsHealth jenkinsclient.HealthChecker, jenkinsHealths sharding.HealthGetter) map[string]func(mgr manager.Manager) error {
 
 	argocdReconciler := &argocd.Reconciler{
 		Client:        mgr.GetClient(),
@@ -137,13 +206,24 @@ func getAllControllers(mgr manager.Manager, client k8s.Client, informerFactory i
 	fluxcdAppStatusReconciler := &fluxcd.ApplicationStatusReconciler{
 		Client: mgr.GetClient(),
 	}
+	fluxcdImageUpdaterReconciler := &fluxcd.ImageUpdaterReconciler{
+		Client: mgr.GetClient(),
+	}
+	nativeImageUpdaterReconciler := &imageupdater.Reconciler{
+		Client: mgr.GetClient(),
+		GitWriter: gitops.NewRepoWriter(gitops.NewGitRepoFactory(mgr.GetClient(), s.GitOpsOptions),
+			"image-updater"),
+	}
 
 	return map[string]func(mgr manager.Manager) error{
 		gitRepoReconcilers.GetName(): func(mgr manager.Manager) error {
 			err := (&gitrepository.PullRequestStatusReconciler{
-				Client:          mgr.GetClient(),
-				ExternalAddress: s.FeatureOptions.ExternalAddress,
-				ClusterName:     s.FeatureOptions.ClusterName,
+				Client:            mgr.GetClient(),
+				ExternalAddress:   s.FeatureOptions.ExternalAddress,
+				ClusterName:       s.FeatureOptions.ClusterName,
+				StageStatus:       s.FeatureOptions.SCMStageStatus,
+				BuildDetailGetter: newBuildDetailGetter(jenkinsCore, devopsClient, s.SonarQubeOptions),
+				JenkinsNamespace:  s.JenkinsOptions.Namespace,
 			}).SetupWithManager(mgr)
 			if err != nil {
 				return err
@@ -193,7 +273,8 @@ func getAllControllers(mgr manager.Manager, client k8s.Client, informerFactory i
 				err = mgr.Add(jenkinspipeline.NewController(client.Kubernetes(),
 					client.KubeSphere(), devopsClient,
 					informerFactory.KubernetesSharedInformerFactory().Core().V1().Namespaces(),
-					informerFactory.KubeSphereSharedInformerFactory().Devops().V1alpha3().Pipelines()))
+					informerFactory.KubeSphereSharedInformerFactory().Devops().V1alpha3().Pipelines()).
+					WithHealthChecker(jenkinsHealth, jenkinsHealths))
 			}
 
 			if err == nil {
@@ -203,6 +284,18 @@ func getAllControllers(mgr manager.Manager, client k8s.Client, informerFactory i
 				}
 				err = jenkinsfileReconciler.SetupWithManager(mgr)
 			}
+			if err == nil {
+				err = (&jenkinspipeline.DriftReconciler{
+					Client:       mgr.GetClient(),
+					DevOpsClient: devopsClient,
+				}).SetupWithManager(mgr)
+			}
+			if shardingClient, ok := devopsClient.(*sharding.Client); ok && err == nil {
+				err = (&devopsproject.PlacementReconciler{
+					Client:   mgr.GetClient(),
+					Registry: shardingClient.Registry(),
+				}).SetupWithManager(mgr)
+			}
 			if err == nil {
 				err = jenkinsAgentLabelsReconciler.SetupWithManager(mgr)
 			}
@@ -238,5 +331,43 @@ func getAllControllers(mgr manager.Manager, client k8s.Client, informerFactory i
 			}
 			return fluxcdApplicationReconciler.SetupWithManager(mgr)
 		},
+		fluxcdApplicationReconciler.GetGroupName() + "-image-updater": func(mgr manager.Manager) error {
+			return fluxcdImageUpdaterReconciler.SetupWithManager(mgr)
+		},
+		imageupdater.KindNative + "-image-updater": func(mgr manager.Manager) error {
+			return nativeImageUpdaterReconciler.SetupWithManager(mgr)
+		},
+		"deploytarget": func(mgr manager.Manager) error {
+			return (&deploytarget.Reconciler{
+				Client:     mgr.GetClient(),
+				HostConfig: mgr.GetConfig(),
+			}).SetupWithManager(mgr)
+		},
+		"jenkinsimport": func(mgr manager.Manager) error {
+			jobSource, err := jenkinsclient.NewDevopsClient(s.JenkinsOptions)
+			if err != nil {
+				return err
+			}
+			return (&jenkinsimport.Reconciler{
+				Client: mgr.GetClient(),
+				Source: jobSource,
+			}).SetupWithManager(mgr)
+		},
+	}
+}
+
+// newBuildDetailGetter creates the getter of the build details, the SonarQube is optional
+func newBuildDetailGetter(jenkinsCore core.JenkinsCore, devopsClient devops.Interface,
+	sonarOptions *sonarqube.Options) *gitrepository.JenkinsBuildDetailGetter {
+	getter := &gitrepository.JenkinsBuildDetailGetter{JenkinsCore: jenkinsCore}
+	// the builds are on the Jenkins instances of the DevOps projects
+	getter.JenkinsCores, _ = devopsClient.(sharding.CoreGetter)
+	if sonarOptions != nil && sonarOptions.Host != "" {
+		if sonarClient, err := sonarqube.NewSonarQubeClient(sonarOptions); err == nil {
+			getter.Sonar = sonarqube.NewSonar(sonarClient.SonarQube())
+		} else {
+			klog.Errorf("failed to create the SonarQube client, the quality gates are unknown: %v", err)
+		}
 	}
+	return getter
 }
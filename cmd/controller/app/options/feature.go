@@ -31,6 +31,8 @@ type FeatureOptions struct {
 	ExternalAddress      string
 	ClusterName          string
 	PipelineRunDataStore string
+	// SCMStageStatus indicates whether to report the status of each stage to the git providers
+	SCMStageStatus bool
 }
 
 // GetControllers returns the controllers map
@@ -84,6 +86,8 @@ func (o *FeatureOptions) AddFlags(fs *pflag.FlagSet, c *FeatureOptions) {
 	fs.StringVarP(&o.ClusterName, "cluster-name", "", "default", "Current cluster name")
 	fs.StringVarP(&o.PipelineRunDataStore, "pipelinerun-data-store", "", "configmap",
 		"The data store type of the PipelineRun data, could be empty or configmap")
+	fs.BoolVarP(&o.SCMStageStatus, "scm-stage-status", "", false,
+		"Report the status of each stage to the git providers besides the status of the whole PipelineRun")
 }
 
 func (o *FeatureOptions) knownControllers() []string {
@@ -121,4 +121,5 @@ func TestFeatureOptions(t *testing.T) {
 	assert.NotNil(t, flagSet.Lookup("external-address"))
 	assert.NotNil(t, flagSet.Lookup("cluster-name"))
 	assert.NotNil(t, flagSet.Lookup("pipelinerun-data-store"))
+	assert.NotNil(t, flagSet.Lookup("scm-stage-status"))
 }
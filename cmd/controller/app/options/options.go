@@ -26,6 +26,7 @@ import (
 	"github.com/kubesphere/ks-devops/pkg/client/devops/jenkins"
 	"github.com/kubesphere/ks-devops/pkg/client/k8s"
 	"github.com/kubesphere/ks-devops/pkg/client/s3"
+	"github.com/kubesphere/ks-devops/pkg/client/sonarqube"
 
 	"k8s.io/apimachinery/pkg/labels"
 
@@ -44,6 +45,8 @@ type DevOpsControllerManagerOptions struct {
 	S3Options         *s3.Options
 	FeatureOptions    *FeatureOptions
 	ArgoCDOption      *config.ArgoCDOption
+	GitOpsOptions     *config.GitOpsOptions
+	SonarQubeOptions  *sonarqube.Options
 
 	// KubeSphere is using sigs.k8s.io/application as fundamental object to implement Application Management.
 	// There are other projects also built on sigs.k8s.io/application, when KubeSphere installed along side
@@ -69,6 +72,8 @@ func NewDevOpsControllerManagerOptions() *DevOpsControllerManagerOptions {
 		ApplicationSelector: "",
 		KubernetesOptions:   &k8s.KubernetesOptions{},
 		ArgoCDOption:        &config.ArgoCDOption{},
+		GitOpsOptions:       config.NewGitOpsOptions(),
+		SonarQubeOptions:    sonarqube.NewSonarQubeOptions(),
 	}
 
 	return s
@@ -81,6 +86,7 @@ func (s *DevOpsControllerManagerOptions) Flags() cliflag.NamedFlagSets {
 	s.JenkinsOptions.AddFlags(fss.FlagSet("devops"), s.JenkinsOptions)
 	s.FeatureOptions.AddFlags(fss.FlagSet("feature"), s.FeatureOptions)
 	s.ArgoCDOption.AddFlags(fss.FlagSet("argocd"), s.ArgoCDOption)
+	s.SonarQubeOptions.AddFlags(fss.FlagSet("sonarqube"), s.SonarQubeOptions)
 
 	fs := fss.FlagSet("leaderelection")
 	s.bindLeaderElectionFlags(s.LeaderElection, fs)
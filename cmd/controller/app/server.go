@@ -37,7 +37,10 @@ import (
 	"github.com/kubesphere/ks-devops/pkg/apis"
 	"github.com/kubesphere/ks-devops/pkg/client/devops"
 	"github.com/kubesphere/ks-devops/pkg/client/devops/jclient"
+	"github.com/kubesphere/ks-devops/pkg/client/devops/jenkins"
+	"github.com/kubesphere/ks-devops/pkg/client/devops/sharding"
 	"github.com/kubesphere/ks-devops/pkg/client/k8s"
+	"github.com/kubesphere/ks-devops/pkg/client/sonarqube"
 	"github.com/kubesphere/ks-devops/pkg/config"
 	"github.com/kubesphere/ks-devops/pkg/indexers"
 	"github.com/kubesphere/ks-devops/pkg/informers"
@@ -54,6 +57,12 @@ func NewControllerManagerCommand() *cobra.Command {
 		if conf.ArgoCDOption == nil {
 			conf.ArgoCDOption = &config.ArgoCDOption{}
 		}
+		if conf.GitOpsOptions == nil {
+			conf.GitOpsOptions = config.NewGitOpsOptions()
+		}
+		if conf.SonarQubeOptions == nil {
+			conf.SonarQubeOptions = sonarqube.NewSonarQubeOptions()
+		}
 		// make sure LeaderElection is not nil
 		// override devops controller manager options
 		s = &options.DevOpsControllerManagerOptions{
@@ -61,6 +70,8 @@ func NewControllerManagerCommand() *cobra.Command {
 			JenkinsOptions:    conf.JenkinsOptions,
 			S3Options:         conf.S3Options,
 			ArgoCDOption:      conf.ArgoCDOption,
+			GitOpsOptions:     conf.GitOpsOptions,
+			SonarQubeOptions:  conf.SonarQubeOptions,
 			FeatureOptions:    s.FeatureOptions,
 			LeaderElection:    s.LeaderElection,
 			LeaderElect:       s.LeaderElect,
@@ -122,9 +133,15 @@ func Run(s *options.DevOpsControllerManagerOptions, ctx context.Context) error {
 
 	// Init DevOps client while Jenkins options and Jenkins host
 	var devopsClient devops.Interface
+	// jenkinsHealth gives the circuit-breaker semantics to the requests sent to Jenkins
+	var jenkinsHealth *jenkins.HealthMonitor
 	if s.JenkinsOptions != nil && len(s.JenkinsOptions.Host) != 0 {
 		// Make sure that Jenkins host is not empty
-		devopsClient, err = jclient.NewJenkinsClient(s.JenkinsOptions)
+		jenkinsHealth = jenkins.NewHealthMonitor(s.JenkinsOptions)
+		var jenkinsClient *jclient.JenkinsClient
+		if jenkinsClient, err = jclient.NewJenkinsClient(s.JenkinsOptions); err == nil {
+			devopsClient = jenkinsClient.WithHealthMonitor(jenkinsHealth)
+		}
 		if !s.JenkinsOptions.SkipVerify && err != nil {
 			errMsg := fmt.Sprintf("failed to connect jenkins, please check jenkins status, error: %v", err)
 			if s.JenkinsOptions.SkipVerify {
@@ -141,6 +158,9 @@ func Run(s *options.DevOpsControllerManagerOptions, ctx context.Context) error {
 		UserName: s.JenkinsOptions.Username,
 		Token:    s.JenkinsOptions.ApiToken,
 	}
+	if jenkinsHealth != nil {
+		jenkinsCore.RoundTripper = jenkinsHealth.RoundTripper(nil)
+	}
 
 	// Init informers
 	informerFactory := informers.NewInformerFactories(
@@ -183,11 +203,23 @@ func Run(s *options.DevOpsControllerManagerOptions, ctx context.Context) error {
 	// register common meta types into schemas.
 	metav1.AddToGroupVersion(mgr.GetScheme(), metav1.SchemeGroupVersion)
 
+	if devopsClient != nil && len(s.JenkinsOptions.Instances) > 0 {
+		// route the requests of the DevOps projects to their Jenkins instances
+		var shardingClient *sharding.Client
+		if shardingClient, err = sharding.NewClientFromOptions(s.JenkinsOptions, mgr.GetClient()); err != nil {
+			return fmt.Errorf("unable to create the Jenkins instances: %v", err)
+		}
+		// every Jenkins instance has its own health monitor, the default one is shared with the other clients
+		shardingClient.Registry().EnableHealthMonitors(s.JenkinsOptions, jenkinsHealth)
+		devopsClient = shardingClient
+	}
+
 	if err = addControllers(mgr,
 		kubernetesClient,
 		informerFactory,
 		devopsClient,
 		jenkinsCore,
+		jenkinsHealth,
 		s); err != nil {
 		return fmt.Errorf("unable to register controllers to the manager: %v", err)
 	}
@@ -0,0 +1,102 @@
+/*
+Copyright 2022 The KubeSphere Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package app
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/spf13/cobra"
+	"k8s.io/klog/v2"
+	runtimeclient "sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/kubesphere/ks-devops/pkg/backup"
+)
+
+// PassphraseEnv is the environment variable of the passphrase which encrypts or decrypts the credentials
+const PassphraseEnv = "DEVOPS_BACKUP_PASSPHRASE"
+
+type backupOption struct {
+	*ToolOptions
+	project          string
+	output           string
+	credentials      string
+	pipelineRunLimit int
+	client           runtimeclient.Client
+}
+
+func (o *backupOption) preRunE(cmd *cobra.Command, args []string) (err error) {
+	if o.project == "" {
+		return fmt.Errorf("the flag --project is required")
+	}
+	if !backup.CredentialMode(o.credentials).IsValid() {
+		return fmt.Errorf("invalid credential mode: %s", o.credentials)
+	}
+	o.client, err = NewRuntimeClient(o.kubeconfig)
+	return
+}
+
+func (o *backupOption) runE(cmd *cobra.Command, args []string) (err error) {
+	var writer io.Writer = cmd.OutOrStdout()
+	if o.output != "" && o.output != "-" {
+		var file *os.File
+		if file, err = os.Create(o.output); err != nil {
+			return
+		}
+		defer func() {
+			_ = file.Close()
+		}()
+		writer = file
+	}
+
+	klog.Infof("backup DevOps project %s ..", o.project)
+	err = backup.Backup(context.Background(), o.client, o.project, backup.Options{
+		Credentials:      backup.CredentialMode(o.credentials),
+		Passphrase:       os.Getenv(PassphraseEnv),
+		PipelineRunLimit: o.pipelineRunLimit,
+	}, writer)
+	if err != nil {
+		klog.Errorf("backup DevOps project %s error: %+v", o.project, err)
+	}
+	return
+}
+
+// NewBackupCmd creates a command to export a DevOps project into an archive
+func NewBackupCmd(opts *ToolOptions) (cmd *cobra.Command) {
+	opt := &backupOption{ToolOptions: opts}
+
+	cmd = &cobra.Command{
+		Use:   "backup",
+		Short: "export a DevOps project and its resources into an archive",
+		Example: `devops-tools backup --project demo -o demo.tar.gz
+DEVOPS_BACKUP_PASSPHRASE=secret devops-tools backup --project demo --credentials encrypt --pipelinerun-limit 5 -o demo.tar.gz`,
+		PreRunE: opt.preRunE,
+		RunE:    opt.runE,
+	}
+
+	flags := cmd.Flags()
+	flags.StringVarP(&opt.project, "project", "p", "", "the name of the DevOps project")
+	flags.StringVarP(&opt.output, "output", "o", "", "the path of the archive, write to stdout if it's empty")
+	flags.StringVarP(&opt.credentials, "credentials", "", string(backup.CredentialModeRedact),
+		"how to export the credentials, available values: plain, encrypt, redact, skip. "+
+			"The passphrase of encrypt comes from the environment variable "+PassphraseEnv)
+	flags.IntVarP(&opt.pipelineRunLimit, "pipelinerun-limit", "", 0,
+		"the max number of the recent completed PipelineRuns of each Pipeline, the PipelineRuns are not exported if it's zero")
+	return
+}
@@ -0,0 +1,118 @@
+/*
+Copyright 2022 The KubeSphere Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package app
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/clientcmd"
+	runtimeclient "sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/kubesphere/ks-devops/pkg/config"
+	"github.com/kubesphere/ks-devops/pkg/doctor"
+)
+
+type doctorOption struct {
+	*ToolOptions
+	webhookEndpoint string
+	requiredPlugins []string
+	timeout         time.Duration
+
+	conf   *config.Config
+	client runtimeclient.Client
+}
+
+func (o *doctorOption) preRunE(cmd *cobra.Command, args []string) (err error) {
+	if o.conf, err = config.TryLoadFromDisk(); err != nil {
+		return fmt.Errorf("failed to load the configuration, error: %v", err)
+	}
+	o.conf.TryLoadFromEnv()
+
+	scheme := runtime.NewScheme()
+	if err = clientgoscheme.AddToScheme(scheme); err != nil {
+		return
+	}
+	if err = apiextensionsv1.AddToScheme(scheme); err != nil {
+		return
+	}
+	restConfig, err := clientcmd.BuildConfigFromFlags("", o.kubeconfig)
+	if err != nil {
+		return
+	}
+	o.client, err = runtimeclient.New(restConfig, runtimeclient.Options{Scheme: scheme})
+	return
+}
+
+func (o *doctorOption) runE(cmd *cobra.Command, args []string) (err error) {
+	ctx, cancel := context.WithTimeout(context.Background(), o.timeout)
+	defer cancel()
+
+	cascNamespace := SysNs
+	if o.conf.JenkinsOptions != nil && o.conf.JenkinsOptions.Namespace != "" {
+		cascNamespace = o.conf.JenkinsOptions.Namespace
+	}
+
+	report := doctor.Run(ctx,
+		&doctor.JenkinsChecker{Options: o.conf.JenkinsOptions, RequiredPlugins: o.requiredPlugins},
+		&doctor.CasCChecker{
+			Client:        o.client,
+			Namespace:     cascNamespace,
+			ConfigMapName: CascCM,
+			ConfigMapKey:  "jenkins_user.yaml",
+			LabelSelector: "jenkins.agent.pod",
+		},
+		doctor.NewArgoCDChecker(o.client, o.conf.ArgoCDOption),
+		doctor.NewFluxCDChecker(o.client, o.conf.FluxCDOption),
+		&doctor.S3Checker{Options: o.conf.S3Options},
+		&doctor.SonarQubeChecker{Options: o.conf.SonarQubeOptions},
+		&doctor.WebhookChecker{Endpoint: o.webhookEndpoint},
+	)
+	report.Print(cmd.OutOrStdout())
+
+	if failures := report.Failures(); failures > 0 {
+		err = fmt.Errorf("found %d failures", failures)
+	}
+	return
+}
+
+// NewDoctorCmd creates a command to diagnose the integrations of DevOps
+func NewDoctorCmd(opts *ToolOptions) (cmd *cobra.Command) {
+	opt := &doctorOption{ToolOptions: opts}
+
+	cmd = &cobra.Command{
+		Use:   "doctor",
+		Short: "diagnose the integrations of DevOps, such as Jenkins, ArgoCD, FluxCD, S3 and SonarQube",
+		Long: `diagnose the integrations of DevOps according to the configuration file kubesphere.yaml
+which is loaded from /etc/kubesphere or the current directory. It exits with a non-zero code if any check fails.`,
+		PreRunE: opt.preRunE,
+		RunE:    opt.runE,
+	}
+
+	flags := cmd.Flags()
+	flags.StringVarP(&opt.webhookEndpoint, "webhook-endpoint", "", doctor.DefaultWebhookEndpoint,
+		"the address of the DevOps API server which receives the webhooks, skip checking the webhooks if it's empty")
+	flags.StringSliceVarP(&opt.requiredPlugins, "required-plugins", "", doctor.DefaultRequiredPlugins,
+		"the Jenkins plugins which are required")
+	flags.DurationVarP(&opt.timeout, "timeout", "", time.Minute, "the timeout of all the checks")
+	return
+}
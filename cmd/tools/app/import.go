@@ -0,0 +1,112 @@
+/*
+Copyright 2022 The KubeSphere Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package app
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+	"k8s.io/klog/v2"
+	runtimeclient "sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/kubesphere/ks-devops/pkg/backup"
+)
+
+type importOption struct {
+	*ToolOptions
+	file      string
+	project   string
+	workspace string
+	conflict  string
+	timeout   time.Duration
+	client    runtimeclient.Client
+}
+
+func (o *importOption) preRunE(cmd *cobra.Command, args []string) (err error) {
+	if !backup.ConflictPolicy(o.conflict).IsValid() {
+		return fmt.Errorf("invalid conflict policy: %s", o.conflict)
+	}
+	o.client, err = NewRuntimeClient(o.kubeconfig)
+	return
+}
+
+func (o *importOption) runE(cmd *cobra.Command, args []string) (err error) {
+	var reader io.Reader = cmd.InOrStdin()
+	if o.file != "" && o.file != "-" {
+		var file *os.File
+		if file, err = os.Open(o.file); err != nil {
+			return
+		}
+		defer func() {
+			_ = file.Close()
+		}()
+		reader = file
+	}
+
+	klog.Infof("import DevOps project ..")
+	var report *backup.Report
+	report, err = backup.Import(context.Background(), o.client, reader, backup.ImportOptions{
+		Project:          o.project,
+		Workspace:        o.workspace,
+		Passphrase:       os.Getenv(PassphraseEnv),
+		Conflict:         backup.ConflictPolicy(o.conflict),
+		NamespaceTimeout: o.timeout,
+	})
+	if report != nil {
+		for _, item := range report.Created {
+			cmd.Printf("created %s\n", item)
+		}
+		for _, item := range report.Updated {
+			cmd.Printf("updated %s\n", item)
+		}
+		for _, item := range report.Skipped {
+			cmd.Printf("skipped %s (already exists)\n", item)
+		}
+	}
+	if err != nil {
+		klog.Errorf("import DevOps project error: %+v", err)
+	}
+	return
+}
+
+// NewImportCmd creates a command to import a DevOps project from an archive
+func NewImportCmd(opts *ToolOptions) (cmd *cobra.Command) {
+	opt := &importOption{ToolOptions: opts}
+
+	cmd = &cobra.Command{
+		Use:   "import",
+		Short: "import a DevOps project and its resources from an archive",
+		Example: `devops-tools import -f demo.tar.gz
+devops-tools import -f demo.tar.gz --project demo-copy --workspace other --conflict overwrite`,
+		PreRunE: opt.preRunE,
+		RunE:    opt.runE,
+	}
+
+	flags := cmd.Flags()
+	flags.StringVarP(&opt.file, "file", "f", "", "the path of the archive, read from stdin if it's empty")
+	flags.StringVarP(&opt.project, "project", "p", "", "the new name of the DevOps project, keep the original name if it's empty")
+	flags.StringVarP(&opt.workspace, "workspace", "w", "", "the new workspace of the DevOps project, keep the original one if it's empty")
+	flags.StringVarP(&opt.conflict, "conflict", "", string(backup.ConflictPolicySkip),
+		"how to handle the existing resources, available values: skip, overwrite, fail")
+	flags.DurationVarP(&opt.timeout, "timeout", "", backup.DefaultNamespaceTimeout,
+		"the timeout of waiting for the namespace of the DevOps project")
+	return
+}
@@ -0,0 +1,123 @@
+/*
+Copyright 2022 The KubeSphere Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package app
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"k8s.io/klog/v2"
+	runtimeclient "sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/kubesphere/ks-devops/pkg/client/devops/jenkins"
+	"github.com/kubesphere/ks-devops/pkg/config"
+	"github.com/kubesphere/ks-devops/pkg/jenkinsimport"
+)
+
+type importJenkinsOption struct {
+	*ToolOptions
+	folder    string
+	project   string
+	workspace string
+	dryRun    bool
+	force     bool
+	timeout   time.Duration
+
+	source jenkinsimport.JobSource
+	client runtimeclient.Client
+}
+
+func (o *importJenkinsOption) preRunE(cmd *cobra.Command, args []string) (err error) {
+	if o.folder = strings.Trim(o.folder, "/"); o.folder == "" {
+		return fmt.Errorf("the Jenkins folder is required")
+	}
+	if o.project == "" {
+		o.project = path.Base(o.folder)
+	}
+
+	var conf *config.Config
+	if conf, err = config.TryLoadFromDisk(); err != nil {
+		return fmt.Errorf("failed to load the configuration, error: %v", err)
+	}
+	conf.TryLoadFromEnv()
+	if conf.JenkinsOptions == nil || conf.JenkinsOptions.Host == "" {
+		return fmt.Errorf("the address of Jenkins is not configured")
+	}
+	if o.source, err = jenkins.NewDevopsClient(conf.JenkinsOptions); err != nil {
+		return
+	}
+	o.client, err = NewRuntimeClient(o.kubeconfig)
+	return
+}
+
+func (o *importJenkinsOption) runE(cmd *cobra.Command, args []string) (err error) {
+	ctx := context.Background()
+	if !o.dryRun {
+		klog.Infof("ensure DevOps project %s ..", o.project)
+		if err = jenkinsimport.EnsureProject(ctx, o.client, o.project, o.workspace, o.timeout); err != nil {
+			return
+		}
+	}
+
+	klog.Infof("import the jobs of Jenkins folder %s ..", o.folder)
+	importer := &jenkinsimport.Importer{Client: o.client, Source: o.source}
+	var report *jenkinsimport.Report
+	if report, err = importer.Import(ctx, jenkinsimport.Options{
+		Folder:    o.folder,
+		Namespace: o.project,
+		DryRun:    o.dryRun,
+		Force:     o.force,
+	}); err != nil {
+		klog.Errorf("import the jobs of Jenkins folder error: %+v", err)
+		return
+	}
+	report.Print(cmd.OutOrStdout())
+	return
+}
+
+// NewImportJenkinsCmd creates a command to import the existing jobs of a Jenkins folder as Pipelines
+func NewImportJenkinsCmd(opts *ToolOptions) (cmd *cobra.Command) {
+	opt := &importJenkinsOption{ToolOptions: opts}
+
+	cmd = &cobra.Command{
+		Use:   "import-jenkins",
+		Short: "import the existing jobs of a Jenkins folder as the Pipelines of a DevOps project",
+		Long: `import the existing jobs of a Jenkins folder as the Pipelines of a DevOps project.
+The address of Jenkins is loaded from the configuration file kubesphere.yaml which is in /etc/kubesphere
+or the current directory. The jobs with the unsupported settings are skipped unless --force is given,
+because those settings will be lost once the Pipelines are synchronized to Jenkins.`,
+		Example: `devops-tools import-jenkins --folder team-a --dry-run
+devops-tools import-jenkins --folder legacy/team-a --project team-a --workspace demo`,
+		PreRunE: opt.preRunE,
+		RunE:    opt.runE,
+	}
+
+	flags := cmd.Flags()
+	flags.StringVarP(&opt.folder, "folder", "", "", "the Jenkins folder which contains the jobs, such as parent/child")
+	flags.StringVarP(&opt.project, "project", "p", "",
+		"the name of the DevOps project, use the last part of the folder if it's empty")
+	flags.StringVarP(&opt.workspace, "workspace", "w", "", "the workspace of the DevOps project if it does not exist")
+	flags.BoolVarP(&opt.dryRun, "dry-run", "", false, "only report what is going to be imported")
+	flags.BoolVarP(&opt.force, "force", "", false, "import the jobs even if they have the unsupported settings")
+	flags.DurationVarP(&opt.timeout, "timeout", "", jenkinsimport.DefaultNamespaceTimeout,
+		"the timeout of waiting for the namespace of the DevOps project")
+	return
+}
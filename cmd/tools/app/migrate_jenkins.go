@@ -0,0 +1,100 @@
+/*
+Copyright 2022 The KubeSphere Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package app
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"k8s.io/klog/v2"
+	runtimeclient "sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/kubesphere/ks-devops/pkg/client/devops/sharding"
+	"github.com/kubesphere/ks-devops/pkg/config"
+)
+
+type migrateJenkinsOption struct {
+	*ToolOptions
+	sharding.MigrateOptions
+
+	registry *sharding.Registry
+	client   runtimeclient.Client
+}
+
+func (o *migrateJenkinsOption) preRunE(cmd *cobra.Command, args []string) (err error) {
+	if o.Project == "" || o.Target == "" {
+		return fmt.Errorf("the DevOps project and the target Jenkins instance are required")
+	}
+
+	var conf *config.Config
+	if conf, err = config.TryLoadFromDisk(); err != nil {
+		return fmt.Errorf("failed to load the configuration, error: %v", err)
+	}
+	conf.TryLoadFromEnv()
+	if conf.JenkinsOptions == nil || conf.JenkinsOptions.Host == "" {
+		return fmt.Errorf("the address of Jenkins is not configured")
+	}
+	if o.registry, err = sharding.NewRegistryFromOptions(conf.JenkinsOptions); err != nil {
+		return
+	}
+	o.client, err = NewRuntimeClient(o.kubeconfig)
+	return
+}
+
+func (o *migrateJenkinsOption) runE(cmd *cobra.Command, args []string) (err error) {
+	klog.Infof("migrate DevOps project %s to Jenkins instance %s ..", o.Project, o.Target)
+	migrator := &sharding.Migrator{Client: o.client, Registry: o.registry}
+	var result *sharding.MigrateResult
+	if result, err = migrator.Migrate(context.Background(), o.MigrateOptions); err != nil {
+		klog.Errorf("migrate DevOps project error: %+v", err)
+		return
+	}
+
+	out := cmd.OutOrStdout()
+	_, _ = fmt.Fprintf(out, "source: %s\ntarget: %s\n", result.Source, result.Target)
+	_, _ = fmt.Fprintf(out, "credentials: %s\n", strings.Join(result.Credentials, ", "))
+	_, _ = fmt.Fprintf(out, "pipelines: %s\n", strings.Join(result.Pipelines, ", "))
+	return
+}
+
+// NewMigrateJenkinsCmd creates a command to move a DevOps project to another Jenkins instance
+func NewMigrateJenkinsCmd(opts *ToolOptions) (cmd *cobra.Command) {
+	opt := &migrateJenkinsOption{ToolOptions: opts}
+
+	cmd = &cobra.Command{
+		Use:   "migrate-jenkins",
+		Short: "move a DevOps project to another Jenkins instance",
+		Long: `move a DevOps project to another Jenkins instance.
+The Jenkins instances are loaded from the configuration file kubesphere.yaml which is in /etc/kubesphere
+or the current directory. The Jenkins folder, credentials and Pipelines are created in the target instance,
+then the target instance is recorded in the status of the DevOps project. The build history is not migrated.`,
+		Example: `devops-tools migrate-jenkins --project team-a --to jenkins-b --dry-run
+devops-tools migrate-jenkins --project team-a --to jenkins-b --delete-source`,
+		PreRunE: opt.preRunE,
+		RunE:    opt.runE,
+	}
+
+	flags := cmd.Flags()
+	flags.StringVarP(&opt.Project, "project", "p", "", "the name of the DevOps project")
+	flags.StringVarP(&opt.Target, "to", "", "", "the name of the target Jenkins instance")
+	flags.BoolVarP(&opt.DryRun, "dry-run", "", false, "only report what is going to be migrated")
+	flags.BoolVarP(&opt.DeleteSource, "delete-source", "", false,
+		"delete the Jenkins folder of the DevOps project from the source instance")
+	return
+}
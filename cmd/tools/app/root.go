@@ -45,5 +45,7 @@ func NewToolsCmd() (cmd *cobra.Command) {
 		"path of kubernetes kubeconfig file, default: Using the inClusterConfig")
 
 	rootCmd.AddCommand(NewRestoreCmd(opts.kubeconfig))
+	rootCmd.AddCommand(NewBackupCmd(opts), NewImportCmd(opts), NewDoctorCmd(opts),
+		NewImportJenkinsCmd(opts), NewMigrateJenkinsCmd(opts))
 	return rootCmd
 }
@@ -20,31 +20,51 @@ import (
 	"bytes"
 	"context"
 	"fmt"
-	"html/template"
+	"sync"
+	"text/template"
+	"time"
 
 	"github.com/go-logr/logr"
 	"github.com/kubesphere/ks-devops/pkg/api/devops/v1alpha3"
 	"github.com/kubesphere/ks-devops/pkg/utils/k8sutil"
+	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
 	"sigs.k8s.io/yaml"
 )
 
 //+kubebuilder:rbac:groups=devops.kubesphere.io,resources=addons,verbs=get;delete;create;update;watch;list
 //+kubebuilder:rbac:groups=devops.kubesphere.io,resources=addonstrategies,verbs=get;delete;create;update;watch;list
-//+kubebuilder:rbac:groups=devops.kubesphere.io,resources=releasercontrollers,verbs=get;delete;create;update;list
-//+kubebuilder:rbac:groups=argoproj.io,resources=argocds,verbs=get;delete;create;update;list
+//+kubebuilder:rbac:groups=devops.kubesphere.io,resources=releasercontrollers,verbs=get;delete;create;update;list;watch
+//+kubebuilder:rbac:groups=argoproj.io,resources=argocds,verbs=get;delete;create;update;list;watch
+//+kubebuilder:rbac:groups=apps,resources=deployments;statefulsets,verbs=get;list;watch
+//+kubebuilder:rbac:groups=batch,resources=jobs,verbs=get;create;delete
 
 // Reconciler takes the responsible for addon lifecycle
 type Reconciler struct {
 	client.Client
 	log      logr.Logger
 	recorder record.EventRecorder
+
+	// controller and cache are used to watch the custom resources of the operators,
+	// their kinds are unknown until the addons are installed
+	controller controller.Controller
+	cache      cache.Cache
+	watched    sync.Map
 }
 
 const defaultNamespace = "default"
@@ -60,26 +80,47 @@ func (r *Reconciler) Reconcile(ctx context.Context, req ctrl.Request) (result ct
 	}
 
 	if beingDeleting(addon) {
-		return r.cleanup(addon)
+		err = r.cleanup(ctx, addon)
+		return
 	}
 
-	err = r.addonHandle(ctx, addon)
+	if err = r.addonHandle(ctx, addon); err == nil && (addon.Status.Phase == v1alpha3.AddonPhaseInstalling ||
+		addon.Status.Phase == v1alpha3.AddonPhaseUpgrading) {
+		result.RequeueAfter = installingRequeuePeriod
+	}
 	return
 }
 
+// installingRequeuePeriod is the period to check an addon which is still being installed
+const installingRequeuePeriod = 30 * time.Second
+
 func beingDeleting(addon *v1alpha3.Addon) bool {
 	return addon != nil && !addon.DeletionTimestamp.IsZero()
 }
 
-func (r *Reconciler) cleanup(addon *v1alpha3.Addon) (result ctrl.Result, err error) {
-	var obj *unstructured.Unstructured
-	if obj, _, err = r.findTemplateInstance(context.Background(), addon); err != nil && !apierrors.IsNotFound(err) {
-		return
+func (r *Reconciler) cleanup(ctx context.Context, addon *v1alpha3.Addon) (err error) {
+	var strategy *v1alpha3.AddonStrategy
+	if strategy, err = r.getStrategy(ctx, addon); err == nil {
+		switch strategy.Spec.Type {
+		case v1alpha3.AddonInstallStrategyHelm:
+			err = r.uninstallHelm(ctx, addon)
+		case v1alpha3.AddonInstallStrategySimple:
+			err = r.deleteSimple(ctx, addon, strategy)
+		case v1alpha3.AddonInstallStrategySimpleOperator:
+			var obj *unstructured.Unstructured
+			if obj, _, err = r.findTemplateInstance(ctx, addon); err == nil {
+				err = r.Client.Delete(ctx, obj)
+			}
+			err = client.IgnoreNotFound(err)
+		}
+	} else if apierrors.IsNotFound(err) {
+		// nothing can be cleaned up without the strategy
+		err = nil
 	}
 
-	if err = r.Client.Delete(context.Background(), obj); err == nil {
+	if err == nil {
 		k8sutil.RemoveFinalizer(&addon.ObjectMeta, v1alpha3.AddonFinalizerName)
-		err = r.Client.Update(context.Background(), addon)
+		err = r.Client.Update(ctx, addon)
 	}
 	return
 }
@@ -87,40 +128,150 @@ func (r *Reconciler) cleanup(addon *v1alpha3.Addon) (result ctrl.Result, err err
 const (
 	// EventReasonMissing represents the reason because of missing something
 	EventReasonMissing = "Missing"
+	// EventReasonFailed represents the reason because of failing to install an addon
+	EventReasonFailed = "Failed"
+	// EventReasonIncompatible represents the reason because of an incompatible version
+	EventReasonIncompatible = "Incompatible"
 )
 
 func (r *Reconciler) addonHandle(ctx context.Context, addon *v1alpha3.Addon) (err error) {
+	var strategy *v1alpha3.AddonStrategy
+	var upgrade *v1alpha3.AddonUpgradePath
+	if strategy, err = r.getStrategy(ctx, addon); err == nil {
+		if upgrade, err = findUpgradePath(addon, strategy); err != nil {
+			// there is no need to retry until the addon or strategy is changed
+			r.recorder.Eventf(addon, corev1.EventTypeWarning, EventReasonIncompatible, err.Error())
+			setAddonStatus(addon, v1alpha3.AddonPhaseFailed, addon.Status.Version, metav1.ConditionFalse,
+				EventReasonIncompatible, err.Error())
+			err = nil
+		} else {
+			err = r.installOrUpgrade(ctx, addon, strategy, upgrade)
+		}
+	}
+
+	if err != nil {
+		r.recorder.Eventf(addon, corev1.EventTypeWarning, EventReasonFailed, "failed to install addon: %v", err)
+		setAddonStatus(addon, v1alpha3.AddonPhaseFailed, addon.Status.Version, metav1.ConditionFalse, EventReasonFailed, err.Error())
+	}
+
+	// add finalizer, and update the status at the same time
+	k8sutil.AddFinalizer(&addon.ObjectMeta, v1alpha3.AddonFinalizerName)
+	if updateErr := r.Update(ctx, addon); err == nil {
+		err = updateErr
+	}
+	return
+}
+
+// installOrUpgrade installs the addon, or upgrades it with the hooks of the upgrade path
+func (r *Reconciler) installOrUpgrade(ctx context.Context, addon *v1alpha3.Addon, strategy *v1alpha3.AddonStrategy,
+	upgrade *v1alpha3.AddonUpgradePath) (err error) {
+	phase := v1alpha3.AddonPhaseInstalling
+	if isUpgrading(addon) {
+		phase = v1alpha3.AddonPhaseUpgrading
+	}
+
+	var done bool
+	if upgrade != nil && upgrade.PreUpgrade != "" {
+		if done, err = r.runHook(ctx, addon, strategy, upgrade.PreUpgrade); err != nil || !done {
+			setAddonProgressing(addon, phase, "PreUpgrade", "waiting for the pre-upgrade hook to be completed")
+			return
+		}
+	}
+
+	switch strategy.Spec.Type {
+	case v1alpha3.AddonInstallStrategyHelm:
+		done, err = r.installHelm(ctx, addon, strategy)
+	case v1alpha3.AddonInstallStrategySimple:
+		done, err = r.applySimple(ctx, addon, strategy)
+	case v1alpha3.AddonInstallStrategySimpleOperator:
+		done, err = r.applySimpleOperator(ctx, addon, strategy)
+	}
+	if err != nil || !done {
+		setAddonProgressing(addon, phase, string(phase), "waiting for the addon to be ready")
+		return
+	}
+
+	if upgrade != nil && upgrade.PostUpgrade != "" {
+		if done, err = r.runHook(ctx, addon, strategy, upgrade.PostUpgrade); err != nil || !done {
+			setAddonProgressing(addon, phase, "PostUpgrade", "waiting for the post-upgrade hook to be completed")
+			return
+		}
+	}
+	if upgrade != nil {
+		if err = r.deleteHooks(ctx, addon, strategy, upgrade); err != nil {
+			return
+		}
+	}
+	setAddonInstalled(addon, addon.Spec.Version)
+	return
+}
+
+func (r *Reconciler) applySimpleOperator(ctx context.Context, addon *v1alpha3.Addon, strategy *v1alpha3.AddonStrategy) (ready bool, err error) {
 	var tpl string
 	var obj *unstructured.Unstructured
 	if obj, tpl, err = r.findTemplateInstance(ctx, addon); err != nil {
 		if apierrors.IsNotFound(err) {
-			err = r.Client.Create(ctx, obj)
+			// the new custom resource has no status, it's ready once the operator reports it
+			if err = r.Client.Create(ctx, obj); err == nil {
+				err = r.watchOperatorObject(obj)
+			}
 		}
-	} else {
-		resVersion := obj.GetResourceVersion()
+		return
+	}
+	if err = r.watchOperatorObject(obj); err != nil {
+		return
+	}
 
-		if err = yaml.Unmarshal([]byte(tpl), obj); err != nil {
-			err = fmt.Errorf("failed parse template to addon, error is %v", err)
-			return
-		}
-		obj.SetResourceVersion(resVersion)
-		obj.SetName(addon.Name)
-		obj.SetNamespace(defaultNamespace)
-		err = r.Client.Update(ctx, obj)
+	desired := &unstructured.Unstructured{}
+	if err = yaml.Unmarshal([]byte(tpl), desired); err != nil {
+		err = fmt.Errorf("failed parse template to addon, error is %v", err)
+		return
+	}
+	if !needsUpdate(desired, obj) {
+		ready, err = isOperatorObjectReady(obj, strategy.Spec.HealthCheck)
+		return
 	}
 
-	// add finalizer
-	if err == nil {
-		k8sutil.AddFinalizer(&addon.ObjectMeta, v1alpha3.AddonFinalizerName)
-		err = r.Update(ctx, addon)
+	resVersion := obj.GetResourceVersion()
+	if err = yaml.Unmarshal([]byte(tpl), obj); err != nil {
+		err = fmt.Errorf("failed parse template to addon, error is %v", err)
+		return
 	}
+	obj.SetResourceVersion(resVersion)
+	obj.SetName(addon.Name)
+	obj.SetNamespace(defaultNamespace)
+	// the object is not ready until the operator handles the change
+	err = r.Client.Update(ctx, obj)
 	return
 }
 
-func (r *Reconciler) findTemplateInstance(ctx context.Context, addon *v1alpha3.Addon) (instance *unstructured.Unstructured, tpl string, err error) {
+// setAddonProgressing keeps the installed version, and marks the addon as not ready yet
+func setAddonProgressing(addon *v1alpha3.Addon, phase v1alpha3.AddonPhase, reason, message string) {
+	setAddonStatus(addon, phase, addon.Status.Version, metav1.ConditionFalse, reason, message)
+}
+
+// setAddonInstalled marks the addon as installed with the given version
+func setAddonInstalled(addon *v1alpha3.Addon, version string) {
+	setAddonStatus(addon, v1alpha3.AddonPhaseInstalled, version, metav1.ConditionTrue, string(v1alpha3.AddonPhaseInstalled), "")
+}
+
+func setAddonStatus(addon *v1alpha3.Addon, phase v1alpha3.AddonPhase, version string,
+	status metav1.ConditionStatus, reason, message string) {
+	addon.Status.Phase = phase
+	addon.Status.Version = version
+	meta.SetStatusCondition(&addon.Status.Conditions, metav1.Condition{
+		Type:               v1alpha3.AddonConditionReady,
+		Status:             status,
+		ObservedGeneration: addon.Generation,
+		Reason:             reason,
+		Message:            message,
+	})
+}
+
+func (r *Reconciler) getStrategy(ctx context.Context, addon *v1alpha3.Addon) (addonStrategy *v1alpha3.AddonStrategy, err error) {
 	strategy := addon.Spec.Strategy
 
-	addonStrategy := &v1alpha3.AddonStrategy{}
+	addonStrategy = &v1alpha3.AddonStrategy{}
 	if err = r.Client.Get(ctx, types.NamespacedName{Name: strategy.Name}, addonStrategy); err != nil {
 		r.recorder.Eventf(addon, corev1.EventTypeWarning, EventReasonMissing, "failed to get AddonStrategy with: %s", strategy.Name)
 		return
@@ -128,8 +279,16 @@ func (r *Reconciler) findTemplateInstance(ctx context.Context, addon *v1alpha3.A
 
 	if !r.supportedStrategy(addonStrategy) {
 		err = fmt.Errorf("not supported addon strategy: %s", strategy.Name)
+	}
+	return
+}
+
+func (r *Reconciler) findTemplateInstance(ctx context.Context, addon *v1alpha3.Addon) (instance *unstructured.Unstructured, tpl string, err error) {
+	var addonStrategy *v1alpha3.AddonStrategy
+	if addonStrategy, err = r.getStrategy(ctx, addon); err != nil {
 		return
 	}
+	strategy := addon.Spec.Strategy
 
 	if addonStrategy.Spec.Template == "" {
 		r.recorder.Eventf(addon, corev1.EventTypeWarning, EventReasonMissing, "no template found from %s", strategy.Name)
@@ -137,7 +296,7 @@ func (r *Reconciler) findTemplateInstance(ctx context.Context, addon *v1alpha3.A
 		return
 	}
 
-	if tpl, err = getTemplate(addonStrategy.Spec.Template, addon); err != nil {
+	if tpl, err = getTemplate(addonStrategy.Spec.Template, withParameters(addon, addonStrategy)); err != nil {
 		err = fmt.Errorf("failed to render template: %s, error: %v", addonStrategy.Spec.Template, err)
 		return
 	}
@@ -154,6 +313,20 @@ func (r *Reconciler) findTemplateInstance(ctx context.Context, addon *v1alpha3.A
 	return
 }
 
+// withParameters returns a copy of the addon which contains the parameters of the strategy as well,
+// the parameters of the addon take precedence over the strategy ones
+func withParameters(addon *v1alpha3.Addon, strategy *v1alpha3.AddonStrategy) (result *v1alpha3.Addon) {
+	result = addon.DeepCopy()
+	result.Spec.Parameters = make(map[string]string, len(strategy.Spec.Parameters)+len(addon.Spec.Parameters))
+	for key, val := range strategy.Spec.Parameters {
+		result.Spec.Parameters[key] = val
+	}
+	for key, val := range addon.Spec.Parameters {
+		result.Spec.Parameters[key] = val
+	}
+	return
+}
+
 func getTemplate(tpl string, addon *v1alpha3.Addon) (result string, err error) {
 	var addonTpl *template.Template
 	if addonTpl, err = template.New("addon").Parse(tpl); err == nil {
@@ -166,19 +339,94 @@ func getTemplate(tpl string, addon *v1alpha3.Addon) (result string, err error) {
 }
 
 func (r *Reconciler) supportedStrategy(strategy *v1alpha3.AddonStrategy) bool {
-	// TODO support more types in the future
 	if strategy != nil {
-		return strategy.Spec.Type == v1alpha3.AddonInstallStrategySimpleOperator
+		switch strategy.Spec.Type {
+		case v1alpha3.AddonInstallStrategySimple, v1alpha3.AddonInstallStrategyHelm,
+			v1alpha3.AddonInstallStrategySimpleOperator:
+			return true
+		}
 	}
 	return false
 }
 
 // SetupWithManager set the reconcilers
-func (r *Reconciler) SetupWithManager(mgr ctrl.Manager) error {
+func (r *Reconciler) SetupWithManager(mgr ctrl.Manager) (err error) {
 	r.log = ctrl.Log.WithName("AddonReconciler")
 	r.recorder = mgr.GetEventRecorderFor("addon-controller")
-	return ctrl.NewControllerManagedBy(mgr).
+	withAddonLabel := builder.WithPredicates(predicate.NewPredicateFuncs(func(object client.Object) bool {
+		_, ok := object.GetLabels()[v1alpha3.AddonLabelKey]
+		return ok
+	}))
+	r.cache = mgr.GetCache()
+	r.controller, err = ctrl.NewControllerManagedBy(mgr).
 		Named("addon_controller").
 		For(&v1alpha3.Addon{}).
-		Complete(r)
+		Watches(&appsv1.Deployment{}, handler.EnqueueRequestsFromMapFunc(r.findAddonsByWorkload), withAddonLabel).
+		Watches(&appsv1.StatefulSet{}, handler.EnqueueRequestsFromMapFunc(r.findAddonsByWorkload), withAddonLabel).
+		Watches(&v1alpha3.AddonStrategy{}, handler.EnqueueRequestsFromMapFunc(r.findAddonsByStrategy)).
+		Build(r)
+	return
+}
+
+// watchOperatorObject watches the kind of the custom resource, then its status changes trigger the addon
+func (r *Reconciler) watchOperatorObject(obj *unstructured.Unstructured) (err error) {
+	if r.controller == nil {
+		return
+	}
+	gvk := obj.GroupVersionKind()
+	if _, loaded := r.watched.LoadOrStore(gvk, true); loaded {
+		return
+	}
+
+	target := &unstructured.Unstructured{}
+	target.SetGroupVersionKind(gvk)
+	if err = r.controller.Watch(source.Kind[client.Object](r.cache, target,
+		handler.EnqueueRequestsFromMapFunc(r.findAddonsByOperatorObject))); err != nil {
+		r.watched.Delete(gvk)
+		err = fmt.Errorf("failed to watch %s, error: %v", gvk.String(), err)
+	}
+	return
+}
+
+// findAddonsByOperatorObject returns the addons which the custom resource of an operator belongs to
+func (r *Reconciler) findAddonsByOperatorObject(ctx context.Context, object client.Object) []reconcile.Request {
+	if object.GetNamespace() != defaultNamespace {
+		return nil
+	}
+	return r.findAddons(ctx, func(addon *v1alpha3.Addon) bool {
+		return addon.Name == object.GetName()
+	})
+}
+
+// findAddonsByWorkload returns the addons which the workload belongs to
+func (r *Reconciler) findAddonsByWorkload(ctx context.Context, object client.Object) []reconcile.Request {
+	name := object.GetLabels()[v1alpha3.AddonLabelKey]
+	return r.findAddons(ctx, func(addon *v1alpha3.Addon) bool {
+		return addon.Name == name
+	})
+}
+
+// findAddonsByStrategy returns the addons which refer to the strategy
+func (r *Reconciler) findAddonsByStrategy(ctx context.Context, object client.Object) []reconcile.Request {
+	return r.findAddons(ctx, func(addon *v1alpha3.Addon) bool {
+		return addon.Spec.Strategy.Name == object.GetName()
+	})
+}
+
+func (r *Reconciler) findAddons(ctx context.Context, filter func(*v1alpha3.Addon) bool) (requests []reconcile.Request) {
+	addonList := &v1alpha3.AddonList{}
+	if err := r.List(ctx, addonList); err != nil {
+		r.log.Error(err, "failed to list addons")
+		return
+	}
+
+	for i := range addonList.Items {
+		if addon := &addonList.Items[i]; filter(addon) {
+			requests = append(requests, reconcile.Request{NamespacedName: types.NamespacedName{
+				Namespace: addon.Namespace,
+				Name:      addon.Name,
+			}})
+		}
+	}
+	return
 }
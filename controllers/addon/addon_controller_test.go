@@ -60,6 +60,24 @@ func TestReconciler_supportedStrategy(t *testing.T) {
 			Spec: v1alpha3.AddStrategySpec{Type: "simple-operator"},
 		}},
 		want: true,
+	}, {
+		name: "simple",
+		args: args{strategy: &v1alpha3.AddonStrategy{
+			Spec: v1alpha3.AddStrategySpec{Type: "simple"},
+		}},
+		want: true,
+	}, {
+		name: "helm",
+		args: args{strategy: &v1alpha3.AddonStrategy{
+			Spec: v1alpha3.AddStrategySpec{Type: "helm"},
+		}},
+		want: true,
+	}, {
+		name: "operator",
+		args: args{strategy: &v1alpha3.AddonStrategy{
+			Spec: v1alpha3.AddStrategySpec{Type: "operator"},
+		}},
+		want: false,
 	}}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -175,6 +193,8 @@ spec:
 			err = c.Get(context.Background(), types.NamespacedName{Name: "ks-releaser", Namespace: "default"}, addon)
 			assert.Nil(t, err)
 			assert.ElementsMatch(t, []string{v1alpha3.AddonFinalizerName}, addon.Finalizers)
+			// the custom resource is not ready until the operator reports it
+			assert.Equal(t, v1alpha3.AddonPhaseInstalling, addon.Status.Phase)
 
 			obj := &unstructured.Unstructured{}
 			obj.SetKind("ReleaserController")
@@ -194,6 +214,42 @@ spec:
 			assert.True(t, ok)
 			assert.Equal(t, "ghcr.io/kubesphere-sigs/ks-releaser", image)
 		},
+	}, {
+		name: "the custom resource is ready",
+		fields: fields{
+			Client: fake.NewClientBuilder().WithScheme(schema).WithObjects(strategy.DeepCopy(), addon.DeepCopy(),
+				&unstructured.Unstructured{Object: map[string]interface{}{
+					"apiVersion": "devops.kubesphere.io/v1alpha1",
+					"kind":       "ReleaserController",
+					"metadata": map[string]interface{}{
+						"name":      "ks-releaser",
+						"namespace": "default",
+					},
+					"spec": map[string]interface{}{
+						"image":   "ghcr.io/kubesphere-sigs/ks-releaser",
+						"version": "v0.0.1",
+						"webhook": false,
+					},
+					"status": map[string]interface{}{"conditions": []interface{}{map[string]interface{}{
+						"type":   "Ready",
+						"status": "True",
+					}}},
+				}}).Build(),
+		},
+		args: args{
+			ctx:   context.TODO(),
+			addon: addon.DeepCopy(),
+		},
+		wantErr: func(t assert.TestingT, err error, i ...interface{}) bool {
+			return err == nil
+		},
+		verify: func(t *testing.T, c client.Client) {
+			addon := &v1alpha3.Addon{}
+			err := c.Get(context.Background(), types.NamespacedName{Name: "ks-releaser", Namespace: "default"}, addon)
+			assert.Nil(t, err)
+			assert.Equal(t, v1alpha3.AddonPhaseInstalled, addon.Status.Phase)
+			assert.Equal(t, "v0.0.1", addon.Status.Version)
+		},
 	}, {
 		name: "update existing addon",
 		fields: fields{
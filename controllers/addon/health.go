@@ -0,0 +1,114 @@
+/*
+Copyright 2022 The KubeSphere Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package addon
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/kubesphere/ks-devops/pkg/api/devops/v1alpha3"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// isObjectReady checks if the object is ready according to the health check, or its kind.
+// An object without any status is considered to be ready, because there is no way to know it.
+func isObjectReady(obj *unstructured.Unstructured, check *v1alpha3.AddonHealthCheck) (ready bool, err error) {
+	if check != nil && check.FieldPath != "" {
+		var val interface{}
+		var found bool
+		val, found, err = unstructured.NestedFieldNoCopy(obj.Object, strings.Split(check.FieldPath, ".")...)
+		ready = found && fmt.Sprint(val) == check.Value
+		return
+	}
+
+	switch obj.GetKind() {
+	case "Deployment", "StatefulSet":
+		replicas, found, _ := unstructured.NestedInt64(obj.Object, "spec", "replicas")
+		if !found {
+			replicas = 1
+		}
+		readyReplicas, _, _ := unstructured.NestedInt64(obj.Object, "status", "readyReplicas")
+		ready = isObservedGeneration(obj) && readyReplicas >= replicas
+	case "DaemonSet":
+		desired, _, _ := unstructured.NestedInt64(obj.Object, "status", "desiredNumberScheduled")
+		readyNumber, _, _ := unstructured.NestedInt64(obj.Object, "status", "numberReady")
+		ready = isObservedGeneration(obj) && readyNumber >= desired
+	case "Job":
+		if findCondition(obj, "Failed") == "True" {
+			err = fmt.Errorf("job %s/%s failed", obj.GetNamespace(), obj.GetName())
+			return
+		}
+		ready = findCondition(obj, "Complete") == "True"
+	default:
+		if status := findCondition(obj, "Ready"); status != "" {
+			ready = status == "True"
+		} else if status = findCondition(obj, "Available"); status != "" {
+			ready = status == "True"
+		} else {
+			ready = true
+		}
+	}
+	return
+}
+
+// isOperatorObjectReady checks if the custom resource of an operator is ready according to the health check,
+// or its Ready condition. It's not ready without the Ready condition, because the operator has not handled it yet.
+func isOperatorObjectReady(obj *unstructured.Unstructured, check *v1alpha3.AddonHealthCheck) (ready bool, err error) {
+	if check != nil && check.FieldPath != "" {
+		return isObjectReady(obj, check)
+	}
+	ready = findCondition(obj, "Ready") == "True"
+	return
+}
+
+func isObservedGeneration(obj *unstructured.Unstructured) bool {
+	observedGeneration, _, _ := unstructured.NestedInt64(obj.Object, "status", "observedGeneration")
+	return observedGeneration >= obj.GetGeneration()
+}
+
+// findCondition returns the status of the condition with the given type, or an empty string if not found
+func findCondition(obj *unstructured.Unstructured, conditionType string) string {
+	conditions, _, _ := unstructured.NestedSlice(obj.Object, "status", "conditions")
+	for _, item := range conditions {
+		if condition, ok := item.(map[string]interface{}); ok && condition["type"] == conditionType {
+			return fmt.Sprint(condition["status"])
+		}
+	}
+	return ""
+}
+
+// needsUpdate checks if the existing object is different from the desired one,
+// the fields which are not set in the desired object are ignored
+func needsUpdate(desired, existing *unstructured.Unstructured) bool {
+	for key, val := range desired.Object {
+		if key == "metadata" || key == "status" {
+			continue
+		}
+		if existingVal, ok := existing.Object[key]; !ok || !equality.Semantic.DeepDerivative(val, existingVal) {
+			return true
+		}
+	}
+
+	existingLabels := existing.GetLabels()
+	for key, val := range desired.GetLabels() {
+		if existingLabels[key] != val {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,199 @@
+/*
+Copyright 2022 The KubeSphere Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package addon
+
+import (
+	"testing"
+
+	"github.com/kubesphere/ks-devops/pkg/api/devops/v1alpha3"
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func Test_isObjectReady(t *testing.T) {
+	tests := []struct {
+		name      string
+		object    map[string]interface{}
+		check     *v1alpha3.AddonHealthCheck
+		wantReady bool
+		wantErr   bool
+	}{{
+		name:      "unknown object without status",
+		object:    map[string]interface{}{"kind": "ConfigMap"},
+		wantReady: true,
+	}, {
+		name: "the expected field value",
+		object: map[string]interface{}{
+			"kind":   "ArgoCD",
+			"status": map[string]interface{}{"phase": "Available"},
+		},
+		check:     &v1alpha3.AddonHealthCheck{FieldPath: "status.phase", Value: "Available"},
+		wantReady: true,
+	}, {
+		name:   "the expected field does not exist",
+		object: map[string]interface{}{"kind": "ArgoCD"},
+		check:  &v1alpha3.AddonHealthCheck{FieldPath: "status.phase", Value: "Available"},
+	}, {
+		name: "a ready Deployment",
+		object: map[string]interface{}{
+			"kind":   "Deployment",
+			"spec":   map[string]interface{}{"replicas": int64(2)},
+			"status": map[string]interface{}{"readyReplicas": int64(2)},
+		},
+		wantReady: true,
+	}, {
+		name: "a Deployment which is rolling out",
+		object: map[string]interface{}{
+			"kind":   "Deployment",
+			"spec":   map[string]interface{}{"replicas": int64(2)},
+			"status": map[string]interface{}{"readyReplicas": int64(1)},
+		},
+	}, {
+		name: "a Deployment whose change was not observed",
+		object: map[string]interface{}{
+			"kind":     "Deployment",
+			"metadata": map[string]interface{}{"generation": int64(2)},
+			"status":   map[string]interface{}{"readyReplicas": int64(1), "observedGeneration": int64(1)},
+		},
+	}, {
+		name: "a failed Job",
+		object: map[string]interface{}{
+			"kind": "Job",
+			"status": map[string]interface{}{"conditions": []interface{}{map[string]interface{}{
+				"type":   "Failed",
+				"status": "True",
+			}}},
+		},
+		wantErr: true,
+	}, {
+		name: "a custom resource which is not ready",
+		object: map[string]interface{}{
+			"kind": "ReleaserController",
+			"status": map[string]interface{}{"conditions": []interface{}{map[string]interface{}{
+				"type":   "Ready",
+				"status": "False",
+			}}},
+		},
+	}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ready, err := isObjectReady(&unstructured.Unstructured{Object: tt.object}, tt.check)
+			assert.Equal(t, tt.wantErr, err != nil, err)
+			assert.Equal(t, tt.wantReady, ready)
+		})
+	}
+}
+
+func Test_isOperatorObjectReady(t *testing.T) {
+	tests := []struct {
+		name      string
+		object    map[string]interface{}
+		check     *v1alpha3.AddonHealthCheck
+		wantReady bool
+	}{{
+		name:   "no status",
+		object: map[string]interface{}{"kind": "ReleaserController"},
+	}, {
+		name: "no Ready condition",
+		object: map[string]interface{}{
+			"kind": "ReleaserController",
+			"status": map[string]interface{}{"conditions": []interface{}{map[string]interface{}{
+				"type":   "Available",
+				"status": "True",
+			}}},
+		},
+	}, {
+		name: "ready",
+		object: map[string]interface{}{
+			"kind": "ReleaserController",
+			"status": map[string]interface{}{"conditions": []interface{}{map[string]interface{}{
+				"type":   "Ready",
+				"status": "True",
+			}}},
+		},
+		wantReady: true,
+	}, {
+		name: "health check by field",
+		object: map[string]interface{}{
+			"kind":   "ArgoCD",
+			"status": map[string]interface{}{"phase": "Available"},
+		},
+		check:     &v1alpha3.AddonHealthCheck{FieldPath: "status.phase", Value: "Available"},
+		wantReady: true,
+	}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ready, err := isOperatorObjectReady(&unstructured.Unstructured{Object: tt.object}, tt.check)
+			assert.Nil(t, err)
+			assert.Equal(t, tt.wantReady, ready)
+		})
+	}
+}
+
+func Test_needsUpdate(t *testing.T) {
+	existing := &unstructured.Unstructured{Object: map[string]interface{}{
+		"kind": "ReleaserController",
+		"metadata": map[string]interface{}{
+			"name":   "releaser",
+			"labels": map[string]interface{}{"app": "releaser"},
+		},
+		"spec": map[string]interface{}{
+			"image":   "ghcr.io/kubesphere-sigs/ks-releaser",
+			"version": "v0.0.1",
+			"webhook": false,
+		},
+	}}
+	tests := []struct {
+		name    string
+		desired map[string]interface{}
+		want    bool
+	}{{
+		name: "same",
+		desired: map[string]interface{}{
+			"kind": "ReleaserController",
+			"spec": map[string]interface{}{"version": "v0.0.1"},
+		},
+	}, {
+		name: "different version",
+		desired: map[string]interface{}{
+			"kind": "ReleaserController",
+			"spec": map[string]interface{}{"version": "v0.0.2"},
+		},
+		want: true,
+	}, {
+		name: "different labels",
+		desired: map[string]interface{}{
+			"kind": "ReleaserController",
+			"metadata": map[string]interface{}{
+				"labels": map[string]interface{}{"app": "fake"},
+			},
+		},
+		want: true,
+	}, {
+		name: "new field",
+		desired: map[string]interface{}{
+			"kind": "ReleaserController",
+			"data": map[string]interface{}{"key": "value"},
+		},
+		want: true,
+	}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, needsUpdate(&unstructured.Unstructured{Object: tt.desired}, existing))
+		})
+	}
+}
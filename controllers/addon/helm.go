@@ -0,0 +1,194 @@
+/*
+Copyright 2022 The KubeSphere Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package addon
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kubesphere/ks-devops/pkg/api/devops/v1alpha3"
+	helmv2 "github.com/kubesphere/ks-devops/pkg/external/fluxcd/helm/v2beta1"
+	fluxmeta "github.com/kubesphere/ks-devops/pkg/external/fluxcd/meta"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+//+kubebuilder:rbac:groups=helm.toolkit.fluxcd.io,resources=helmreleases,verbs=get;list;watch;create;update;delete
+//+kubebuilder:rbac:groups=source.toolkit.fluxcd.io,resources=helmrepositories,verbs=get;list;watch;create;update;delete
+
+// helmInterval is the interval of the FluxCD HelmRelease and HelmRepository
+const helmInterval = 10 * time.Minute
+
+// installHelm installs or upgrades the addon via a FluxCD HelmRelease which comes from the HelmRepo of the strategy
+func (r *Reconciler) installHelm(ctx context.Context, addon *v1alpha3.Addon, strategy *v1alpha3.AddonStrategy) (ready bool, err error) {
+	if strategy.Spec.HelmRepo == "" {
+		err = fmt.Errorf("no helm repository found from %s", strategy.Name)
+		return
+	}
+
+	if err = r.applyHelmRepository(ctx, addon.Name, strategy.Spec.HelmRepo); err != nil {
+		err = fmt.Errorf("failed to apply HelmRepository, error: %v", err)
+		return
+	}
+
+	var values []byte
+	if values, err = json.Marshal(parametersToValues(withParameters(addon, strategy).Spec.Parameters)); err != nil {
+		return
+	}
+
+	chart := strategy.Spec.Chart
+	if chart == "" {
+		chart = addon.Name
+	}
+
+	release := &helmv2.HelmRelease{}
+	if err = r.Get(ctx, types.NamespacedName{Namespace: defaultNamespace, Name: addon.Name}, release); err != nil &&
+		!apierrors.IsNotFound(err) {
+		return
+	}
+	notFound := err != nil
+
+	release.SetNamespace(defaultNamespace)
+	release.SetName(addon.Name)
+	release.Spec.Interval = metav1.Duration{Duration: helmInterval}
+	release.Spec.Chart.Spec = helmv2.HelmChartTemplateSpec{
+		Chart:   chart,
+		Version: addon.Spec.Version,
+		SourceRef: helmv2.CrossNamespaceObjectReference{
+			Kind: "HelmRepository",
+			Name: addon.Name,
+		},
+	}
+	release.Spec.Values = &apiextensionsv1.JSON{Raw: values}
+	if notFound {
+		err = r.Create(ctx, release)
+	} else {
+		err = r.Update(ctx, release)
+	}
+
+	if err == nil {
+		ready, err = isHelmReleaseReady(release)
+	}
+	return
+}
+
+// uninstallHelm uninstalls the addon by removing the FluxCD HelmRelease and HelmRepository
+func (r *Reconciler) uninstallHelm(ctx context.Context, addon *v1alpha3.Addon) (err error) {
+	release := &helmv2.HelmRelease{}
+	release.SetNamespace(defaultNamespace)
+	release.SetName(addon.Name)
+	if err = client.IgnoreNotFound(r.Delete(ctx, release)); err == nil {
+		err = client.IgnoreNotFound(r.Delete(ctx, newHelmRepository(addon.Name)))
+	}
+	return
+}
+
+func (r *Reconciler) applyHelmRepository(ctx context.Context, name, url string) (err error) {
+	repo := newHelmRepository(name)
+	if err = r.Get(ctx, types.NamespacedName{Namespace: defaultNamespace, Name: name}, repo); err != nil {
+		if apierrors.IsNotFound(err) {
+			repo = newHelmRepository(name)
+			_ = unstructured.SetNestedField(repo.Object, url, "spec", "url")
+			_ = unstructured.SetNestedField(repo.Object, helmInterval.String(), "spec", "interval")
+			err = r.Create(ctx, repo)
+		}
+		return
+	}
+
+	if existingURL, _, _ := unstructured.NestedString(repo.Object, "spec", "url"); existingURL != url {
+		_ = unstructured.SetNestedField(repo.Object, url, "spec", "url")
+		err = r.Update(ctx, repo)
+	}
+	return
+}
+
+func newHelmRepository(name string) (repo *unstructured.Unstructured) {
+	repo = &unstructured.Unstructured{}
+	repo.SetGroupVersionKind(schema.GroupVersionKind{
+		Group:   "source.toolkit.fluxcd.io",
+		Version: "v1beta2",
+		Kind:    "HelmRepository",
+	})
+	repo.SetNamespace(defaultNamespace)
+	repo.SetName(name)
+	return
+}
+
+// isHelmReleaseReady checks the Ready condition of the HelmRelease, returns an error if it failed
+func isHelmReleaseReady(release *helmv2.HelmRelease) (ready bool, err error) {
+	condition := meta.FindStatusCondition(release.Status.Conditions, fluxmeta.ReadyCondition)
+	switch {
+	case condition == nil || release.Status.ObservedGeneration < release.Generation:
+	case condition.Status == metav1.ConditionTrue:
+		ready = true
+	case strings.HasSuffix(condition.Reason, "Failed"):
+		err = fmt.Errorf("%s: %s", condition.Reason, condition.Message)
+	}
+	return
+}
+
+// parametersToValues converts the flat parameters into the Helm values,
+// a key like 'image.tag' represents a nested value
+func parametersToValues(parameters map[string]string) (values map[string]interface{}) {
+	keys := make([]string, 0, len(parameters))
+	for key := range parameters {
+		keys = append(keys, key)
+	}
+	// make sure the result is stable when a key conflicts with the nested ones
+	sort.Strings(keys)
+
+	values = map[string]interface{}{}
+	for _, key := range keys {
+		val := parameters[key]
+		fields := strings.Split(key, ".")
+		current := values
+		for _, field := range fields[:len(fields)-1] {
+			next, ok := current[field].(map[string]interface{})
+			if !ok {
+				next = map[string]interface{}{}
+				current[field] = next
+			}
+			current = next
+		}
+		current[fields[len(fields)-1]] = parseValue(val)
+	}
+	return
+}
+
+func parseValue(val string) interface{} {
+	switch val {
+	case "true":
+		return true
+	case "false":
+		return false
+	}
+	if intVal, err := strconv.ParseInt(val, 10, 64); err == nil {
+		return intVal
+	}
+	return val
+}
@@ -0,0 +1,218 @@
+/*
+Copyright 2022 The KubeSphere Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package addon
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/go-logr/logr"
+	"github.com/kubesphere/ks-devops/pkg/api/devops/v1alpha3"
+	helmv2 "github.com/kubesphere/ks-devops/pkg/external/fluxcd/helm/v2beta1"
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestReconciler_helm(t *testing.T) {
+	schema, err := v1alpha3.SchemeBuilder.Register().Build()
+	assert.Nil(t, err)
+	err = helmv2.AddToScheme(schema)
+	assert.Nil(t, err)
+
+	strategy := &v1alpha3.AddonStrategy{
+		ObjectMeta: metav1.ObjectMeta{Name: "helm-sonarqube"},
+		Spec: v1alpha3.AddStrategySpec{
+			Type:     v1alpha3.AddonInstallStrategyHelm,
+			HelmRepo: "https://sonarsource.github.io/helm-chart-sonarqube",
+			Chart:    "sonarqube",
+			Parameters: map[string]string{
+				"persistence.enabled": "true",
+				"replicaCount":        "1",
+			},
+		},
+	}
+	noRepoStrategy := strategy.DeepCopy()
+	noRepoStrategy.Spec.HelmRepo = ""
+	addon := &v1alpha3.Addon{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: "default",
+			Name:      "sonarqube",
+		},
+		Spec: v1alpha3.AddonSpec{
+			Version:    "4.0.0",
+			Strategy:   v1.LocalObjectReference{Name: "helm-sonarqube"},
+			Parameters: map[string]string{"replicaCount": "2"},
+		},
+	}
+	deletingAddon := addon.DeepCopy()
+	deletingAddon.Finalizers = []string{v1alpha3.AddonFinalizerName}
+	now := metav1.Now()
+	deletingAddon.DeletionTimestamp = &now
+	readyRelease := &helmv2.HelmRelease{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "sonarqube"},
+		Status: helmv2.HelmReleaseStatus{
+			LastAppliedRevision: "4.0.0",
+			Conditions: []metav1.Condition{{
+				Type:   "Ready",
+				Status: metav1.ConditionTrue,
+				Reason: helmv2.ReconciliationSucceededReason,
+			}},
+		},
+	}
+	failedRelease := readyRelease.DeepCopy()
+	failedRelease.Status.Conditions[0].Status = metav1.ConditionFalse
+	failedRelease.Status.Conditions[0].Reason = helmv2.InstallFailedReason
+	failedRelease.Status.Conditions[0].Message = "timeout"
+
+	getAddon := func(c client.Client) (result *v1alpha3.Addon) {
+		result = &v1alpha3.Addon{}
+		assert.Nil(t, c.Get(context.Background(), types.NamespacedName{Namespace: "default", Name: "sonarqube"}, result))
+		return
+	}
+
+	tests := []struct {
+		name       string
+		objects    []client.Object
+		wantResult ctrl.Result
+		wantErr    bool
+		verify     func(*testing.T, client.Client)
+	}{{
+		name:       "install a chart",
+		objects:    []client.Object{strategy.DeepCopy(), addon.DeepCopy()},
+		wantResult: ctrl.Result{RequeueAfter: installingRequeuePeriod},
+		verify: func(t *testing.T, c client.Client) {
+			release := &helmv2.HelmRelease{}
+			err := c.Get(context.Background(), types.NamespacedName{Namespace: "default", Name: "sonarqube"}, release)
+			assert.Nil(t, err)
+			assert.Equal(t, "sonarqube", release.Spec.Chart.Spec.Chart)
+			assert.Equal(t, "4.0.0", release.Spec.Chart.Spec.Version)
+			assert.Equal(t, "HelmRepository", release.Spec.Chart.Spec.SourceRef.Kind)
+			if assert.NotNil(t, release.Spec.Values) {
+				assert.JSONEq(t, `{"persistence":{"enabled":true},"replicaCount":2}`, string(release.Spec.Values.Raw))
+			}
+
+			repo := newHelmRepository("sonarqube")
+			err = c.Get(context.Background(), types.NamespacedName{Namespace: "default", Name: "sonarqube"}, repo)
+			assert.Nil(t, err)
+			url, _, _ := unstructured.NestedString(repo.Object, "spec", "url")
+			assert.Equal(t, "https://sonarsource.github.io/helm-chart-sonarqube", url)
+
+			result := getAddon(c)
+			assert.Equal(t, v1alpha3.AddonPhaseInstalling, result.Status.Phase)
+			assert.Equal(t, []string{v1alpha3.AddonFinalizerName}, result.Finalizers)
+		},
+	}, {
+		name:    "the chart was installed",
+		objects: []client.Object{strategy.DeepCopy(), addon.DeepCopy(), readyRelease.DeepCopy()},
+		verify: func(t *testing.T, c client.Client) {
+			result := getAddon(c)
+			assert.Equal(t, v1alpha3.AddonPhaseInstalled, result.Status.Phase)
+			assert.Equal(t, "4.0.0", result.Status.Version)
+			if assert.Equal(t, 1, len(result.Status.Conditions)) {
+				assert.Equal(t, metav1.ConditionTrue, result.Status.Conditions[0].Status)
+			}
+		},
+	}, {
+		name:    "failed to install the chart",
+		objects: []client.Object{strategy.DeepCopy(), addon.DeepCopy(), failedRelease.DeepCopy()},
+		wantErr: true,
+		verify: func(t *testing.T, c client.Client) {
+			result := getAddon(c)
+			assert.Equal(t, v1alpha3.AddonPhaseFailed, result.Status.Phase)
+			if assert.Equal(t, 1, len(result.Status.Conditions)) {
+				assert.Equal(t, EventReasonFailed, result.Status.Conditions[0].Reason)
+				assert.Equal(t, "InstallFailed: timeout", result.Status.Conditions[0].Message)
+			}
+		},
+	}, {
+		name:    "no helm repository",
+		objects: []client.Object{noRepoStrategy, addon.DeepCopy()},
+		wantErr: true,
+		verify: func(t *testing.T, c client.Client) {
+			result := getAddon(c)
+			assert.Equal(t, v1alpha3.AddonPhaseFailed, result.Status.Phase)
+		},
+	}, {
+		name:    "uninstall the chart",
+		objects: []client.Object{strategy.DeepCopy(), deletingAddon, readyRelease.DeepCopy()},
+		verify: func(t *testing.T, c client.Client) {
+			release := &helmv2.HelmRelease{}
+			err := c.Get(context.Background(), types.NamespacedName{Namespace: "default", Name: "sonarqube"}, release)
+			assert.True(t, client.IgnoreNotFound(err) == nil && err != nil)
+		},
+	}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := fake.NewClientBuilder().WithScheme(schema).WithObjects(tt.objects...).Build()
+			r := &Reconciler{
+				Client:   c,
+				log:      logr.Discard(),
+				recorder: record.NewFakeRecorder(100),
+			}
+			result, err := r.Reconcile(context.Background(), ctrl.Request{
+				NamespacedName: types.NamespacedName{Namespace: "default", Name: "sonarqube"},
+			})
+			assert.Equal(t, tt.wantErr, err != nil, err)
+			assert.Equal(t, tt.wantResult, result)
+			if tt.verify != nil {
+				tt.verify(t, c)
+			}
+		})
+	}
+}
+
+func Test_parametersToValues(t *testing.T) {
+	tests := []struct {
+		name       string
+		parameters map[string]string
+		want       string
+	}{{
+		name: "nil parameters",
+		want: `{}`,
+	}, {
+		name: "flat parameters",
+		parameters: map[string]string{
+			"name":    "sonarqube",
+			"enabled": "false",
+			"port":    "9000",
+		},
+		want: `{"name":"sonarqube","enabled":false,"port":9000}`,
+	}, {
+		name: "nested parameters",
+		parameters: map[string]string{
+			"image.repository": "sonarqube",
+			"image.tag":        "9.0",
+			"service.type":     "NodePort",
+		},
+		want: `{"image":{"repository":"sonarqube","tag":"9.0"},"service":{"type":"NodePort"}}`,
+	}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := json.Marshal(parametersToValues(tt.parameters))
+			assert.Nil(t, err)
+			assert.JSONEq(t, tt.want, string(data))
+		})
+	}
+}
@@ -0,0 +1,155 @@
+/*
+Copyright 2022 The KubeSphere Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package addon
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/kubesphere/ks-devops/pkg/api/devops/v1alpha3"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	utilyaml "k8s.io/apimachinery/pkg/util/yaml"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// applySimple applies the resources which come from the YAML of the strategy, then checks if all of them are ready
+func (r *Reconciler) applySimple(ctx context.Context, addon *v1alpha3.Addon, strategy *v1alpha3.AddonStrategy) (ready bool, err error) {
+	if strategy.Spec.YAML == "" {
+		err = fmt.Errorf("no YAML found from %s", strategy.Name)
+		return
+	}
+
+	var objects []*unstructured.Unstructured
+	if objects, err = r.renderObjects(addon, strategy, strategy.Spec.YAML); err == nil {
+		if err = r.applyObjects(ctx, objects, true); err == nil {
+			ready, err = r.objectsReady(ctx, objects)
+		}
+	}
+	return
+}
+
+// deleteSimple deletes the resources which come from the YAML of the strategy
+func (r *Reconciler) deleteSimple(ctx context.Context, addon *v1alpha3.Addon, strategy *v1alpha3.AddonStrategy) (err error) {
+	var objects []*unstructured.Unstructured
+	if objects, err = r.renderObjects(addon, strategy, strategy.Spec.YAML); err != nil {
+		return
+	}
+
+	for _, obj := range objects {
+		if err = client.IgnoreNotFound(r.Delete(ctx, obj)); err != nil {
+			return
+		}
+	}
+	return
+}
+
+// applyObjects creates the objects which do not exist, and updates the changed ones if update is true
+func (r *Reconciler) applyObjects(ctx context.Context, objects []*unstructured.Unstructured, update bool) (err error) {
+	for _, obj := range objects {
+		existing := &unstructured.Unstructured{}
+		existing.SetGroupVersionKind(obj.GroupVersionKind())
+		if err = r.Get(ctx, client.ObjectKeyFromObject(obj), existing); err == nil {
+			if update && needsUpdate(obj, existing) {
+				obj.SetResourceVersion(existing.GetResourceVersion())
+				err = r.Update(ctx, obj)
+			}
+		} else if client.IgnoreNotFound(err) == nil {
+			err = r.Create(ctx, obj)
+		}
+
+		if err != nil {
+			err = fmt.Errorf("failed to apply %s %s, error: %v", obj.GetKind(), obj.GetName(), err)
+			return
+		}
+	}
+	return
+}
+
+// objectsReady checks if all the objects are ready
+func (r *Reconciler) objectsReady(ctx context.Context, objects []*unstructured.Unstructured) (ready bool, err error) {
+	for _, obj := range objects {
+		current := &unstructured.Unstructured{}
+		current.SetGroupVersionKind(obj.GroupVersionKind())
+		if err = r.Get(ctx, client.ObjectKeyFromObject(obj), current); err != nil {
+			return
+		}
+
+		if ready, err = isObjectReady(current, nil); err != nil || !ready {
+			return
+		}
+	}
+	ready = true
+	return
+}
+
+// renderObjects renders the template, then parses it to be the objects of the addon
+func (r *Reconciler) renderObjects(addon *v1alpha3.Addon, strategy *v1alpha3.AddonStrategy, tpl string) (objects []*unstructured.Unstructured, err error) {
+	var content string
+	if content, err = getTemplate(tpl, withParameters(addon, strategy)); err != nil {
+		err = fmt.Errorf("failed to render the YAML of %s, error: %v", strategy.Name, err)
+		return
+	}
+
+	decoder := utilyaml.NewYAMLOrJSONDecoder(bytes.NewBufferString(content), 4096)
+	for {
+		raw := runtime.RawExtension{}
+		if err = decoder.Decode(&raw); err != nil {
+			if errors.Is(err, io.EOF) {
+				err = nil
+			} else {
+				err = fmt.Errorf("failed to parse the YAML of %s, error: %v", strategy.Name, err)
+			}
+			break
+		}
+		if raw = trimRaw(raw); len(raw.Raw) == 0 {
+			// skip the empty documents
+			continue
+		}
+
+		obj := &unstructured.Unstructured{}
+		if err = obj.UnmarshalJSON(raw.Raw); err != nil {
+			err = fmt.Errorf("failed to parse the YAML of %s, error: %v", strategy.Name, err)
+			return
+		}
+
+		if obj.GetNamespace() == "" {
+			if namespaced, nsErr := r.IsObjectNamespaced(obj); nsErr == nil && namespaced {
+				obj.SetNamespace(defaultNamespace)
+			}
+		}
+		labels := obj.GetLabels()
+		if labels == nil {
+			labels = map[string]string{}
+		}
+		labels[v1alpha3.AddonLabelKey] = addon.Name
+		obj.SetLabels(labels)
+		objects = append(objects, obj)
+	}
+	return
+}
+
+func trimRaw(raw runtime.RawExtension) runtime.RawExtension {
+	raw.Raw = bytes.TrimSpace(raw.Raw)
+	if string(raw.Raw) == "null" {
+		raw.Raw = nil
+	}
+	return raw
+}
@@ -0,0 +1,165 @@
+/*
+Copyright 2022 The KubeSphere Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package addon
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-logr/logr"
+	"github.com/kubesphere/ks-devops/pkg/api/devops/v1alpha3"
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta/testrestmapper"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestReconciler_simple(t *testing.T) {
+	schema, err := v1alpha3.SchemeBuilder.Register().Build()
+	assert.Nil(t, err)
+	err = v1.AddToScheme(schema)
+	assert.Nil(t, err)
+
+	strategy := &v1alpha3.AddonStrategy{
+		ObjectMeta: metav1.ObjectMeta{Name: "simple-demo"},
+		Spec: v1alpha3.AddStrategySpec{
+			Type:       v1alpha3.AddonInstallStrategySimple,
+			Parameters: map[string]string{"message": "hello", "mode": "dev"},
+			YAML: `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: demo-config
+data:
+  message: "{{.Spec.Parameters.message}}"
+  mode: {{.Spec.Parameters.mode}}
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: demo-version
+  namespace: devops
+data:
+  version: {{.Spec.Version}}
+`,
+		},
+	}
+	invalidStrategy := strategy.DeepCopy()
+	invalidStrategy.Spec.YAML = "{{.Spec.Fake"
+	addon := &v1alpha3.Addon{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: "default",
+			Name:      "demo",
+		},
+		Spec: v1alpha3.AddonSpec{
+			Version:    "v1.0.0",
+			Strategy:   v1.LocalObjectReference{Name: "simple-demo"},
+			Parameters: map[string]string{"mode": "prod"},
+		},
+	}
+	existingConfig := &v1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "demo-config"},
+		Data:       map[string]string{"message": "old"},
+	}
+	deletingAddon := addon.DeepCopy()
+	deletingAddon.Finalizers = []string{v1alpha3.AddonFinalizerName}
+	now := metav1.Now()
+	deletingAddon.DeletionTimestamp = &now
+
+	getConfigMap := func(c client.Client, namespace, name string) (cm *v1.ConfigMap, err error) {
+		cm = &v1.ConfigMap{}
+		err = c.Get(context.Background(), types.NamespacedName{Namespace: namespace, Name: name}, cm)
+		return
+	}
+	getAddon := func(c client.Client) (result *v1alpha3.Addon) {
+		result = &v1alpha3.Addon{}
+		assert.Nil(t, c.Get(context.Background(), types.NamespacedName{Namespace: "default", Name: "demo"}, result))
+		return
+	}
+
+	tests := []struct {
+		name    string
+		objects []client.Object
+		wantErr bool
+		verify  func(*testing.T, client.Client)
+	}{{
+		name:    "apply the YAML",
+		objects: []client.Object{strategy.DeepCopy(), addon.DeepCopy()},
+		verify: func(t *testing.T, c client.Client) {
+			cm, err := getConfigMap(c, "default", "demo-config")
+			assert.Nil(t, err)
+			assert.Equal(t, map[string]string{"message": "hello", "mode": "prod"}, cm.Data)
+			assert.Equal(t, "demo", cm.Labels[v1alpha3.AddonLabelKey])
+
+			cm, err = getConfigMap(c, "devops", "demo-version")
+			assert.Nil(t, err)
+			assert.Equal(t, "v1.0.0", cm.Data["version"])
+
+			result := getAddon(c)
+			assert.Equal(t, v1alpha3.AddonPhaseInstalled, result.Status.Phase)
+			assert.Equal(t, "v1.0.0", result.Status.Version)
+		},
+	}, {
+		name:    "update the existing resources",
+		objects: []client.Object{strategy.DeepCopy(), addon.DeepCopy(), existingConfig},
+		verify: func(t *testing.T, c client.Client) {
+			cm, err := getConfigMap(c, "default", "demo-config")
+			assert.Nil(t, err)
+			assert.Equal(t, "hello", cm.Data["message"])
+		},
+	}, {
+		name:    "invalid YAML",
+		objects: []client.Object{invalidStrategy, addon.DeepCopy()},
+		wantErr: true,
+		verify: func(t *testing.T, c client.Client) {
+			result := getAddon(c)
+			assert.Equal(t, v1alpha3.AddonPhaseFailed, result.Status.Phase)
+			if assert.Equal(t, 1, len(result.Status.Conditions)) {
+				assert.Equal(t, metav1.ConditionFalse, result.Status.Conditions[0].Status)
+			}
+		},
+	}, {
+		name:    "delete the resources",
+		objects: []client.Object{strategy.DeepCopy(), deletingAddon, existingConfig.DeepCopy()},
+		verify: func(t *testing.T, c client.Client) {
+			_, err := getConfigMap(c, "default", "demo-config")
+			assert.True(t, client.IgnoreNotFound(err) == nil && err != nil)
+		},
+	}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := fake.NewClientBuilder().WithScheme(schema).WithObjects(tt.objects...).
+				WithRESTMapper(testrestmapper.TestOnlyStaticRESTMapper(schema)).Build()
+			r := &Reconciler{
+				Client:   c,
+				log:      logr.Discard(),
+				recorder: record.NewFakeRecorder(100),
+			}
+			_, err := r.Reconcile(context.Background(), ctrl.Request{
+				NamespacedName: types.NamespacedName{Namespace: "default", Name: "demo"},
+			})
+			assert.Equal(t, tt.wantErr, err != nil, err)
+			if tt.verify != nil {
+				tt.verify(t, c)
+			}
+		})
+	}
+}
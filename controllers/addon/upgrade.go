@@ -0,0 +1,111 @@
+/*
+Copyright 2022 The KubeSphere Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package addon
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/blang/semver/v4"
+	"github.com/kubesphere/ks-devops/pkg/api/devops/v1alpha3"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// isUpgrading checks if the desired version is different from the installed one
+func isUpgrading(addon *v1alpha3.Addon) bool {
+	return addon.Status.Version != "" && addon.Status.Version != addon.Spec.Version
+}
+
+// findUpgradePath checks if the desired version is compatible, and returns the upgrade path if it's upgrading.
+// The upgrade path is nil if there is no need to upgrade, or there is no declared upgrade path.
+func findUpgradePath(addon *v1alpha3.Addon, strategy *v1alpha3.AddonStrategy) (path *v1alpha3.AddonUpgradePath, err error) {
+	compatibility := strategy.Spec.Compatibility
+	if compatibility == nil {
+		return
+	}
+
+	if compatibility.Versions != "" && !matchVersion(compatibility.Versions, addon.Spec.Version) {
+		err = fmt.Errorf("version %s is not compatible with the strategy %s, the supported versions are %s",
+			addon.Spec.Version, strategy.Name, compatibility.Versions)
+		return
+	}
+
+	if !isUpgrading(addon) || len(compatibility.Upgrades) == 0 {
+		return
+	}
+
+	for i := range compatibility.Upgrades {
+		upgrade := &compatibility.Upgrades[i]
+		if matchVersion(upgrade.From, addon.Status.Version) && matchVersion(upgrade.To, addon.Spec.Version) {
+			path = upgrade
+			return
+		}
+	}
+	err = fmt.Errorf("there is no upgrade path from %s to %s", addon.Status.Version, addon.Spec.Version)
+	return
+}
+
+// matchVersion checks if the version is in the range, an invalid range or version never matches
+func matchVersion(versionRange, version string) bool {
+	expectedRange, err := semver.ParseRange(versionRange)
+	if err != nil {
+		return false
+	}
+
+	ver, err := semver.ParseTolerant(version)
+	return err == nil && expectedRange(ver)
+}
+
+// runHook creates the resources of an upgrade hook, then checks if all of them are completed
+func (r *Reconciler) runHook(ctx context.Context, addon *v1alpha3.Addon, strategy *v1alpha3.AddonStrategy, hook string) (
+	done bool, err error) {
+	var objects []*unstructured.Unstructured
+	if objects, err = r.renderObjects(addon, strategy, hook); err == nil {
+		// the hooks are usually Jobs, which are not allowed to be updated
+		if err = r.applyObjects(ctx, objects, false); err == nil {
+			done, err = r.objectsReady(ctx, objects)
+		}
+	}
+	return
+}
+
+// deleteHooks deletes the resources of the upgrade hooks once the upgrade is done,
+// otherwise the completed Jobs would be taken as the results of the next upgrade which has the same names
+func (r *Reconciler) deleteHooks(ctx context.Context, addon *v1alpha3.Addon, strategy *v1alpha3.AddonStrategy,
+	upgrade *v1alpha3.AddonUpgradePath) (err error) {
+	for _, hook := range []string{upgrade.PreUpgrade, upgrade.PostUpgrade} {
+		if hook == "" {
+			continue
+		}
+
+		var objects []*unstructured.Unstructured
+		if objects, err = r.renderObjects(addon, strategy, hook); err != nil {
+			return
+		}
+		for _, obj := range objects {
+			// the Pods of the Jobs are orphaned without the propagation policy
+			if err = client.IgnoreNotFound(r.Delete(ctx, obj,
+				client.PropagationPolicy(metav1.DeletePropagationBackground))); err != nil {
+				err = fmt.Errorf("failed to delete the hook %s %s, error: %v", obj.GetKind(), obj.GetName(), err)
+				return
+			}
+		}
+	}
+	return
+}
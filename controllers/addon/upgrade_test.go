@@ -0,0 +1,244 @@
+/*
+Copyright 2022 The KubeSphere Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package addon
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-logr/logr"
+	"github.com/kubesphere/ks-devops/pkg/api/devops/v1alpha3"
+	"github.com/stretchr/testify/assert"
+	batchv1 "k8s.io/api/batch/v1"
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta/testrestmapper"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func Test_findUpgradePath(t *testing.T) {
+	upgrades := []v1alpha3.AddonUpgradePath{{
+		From: ">=2.3.0 <2.4.0",
+		To:   ">=2.4.0 <2.5.0",
+	}}
+	tests := []struct {
+		name          string
+		compatibility *v1alpha3.AddonCompatibility
+		version       string
+		installed     string
+		wantPath      bool
+		wantErr       bool
+	}{{
+		name:    "no compatibility metadata",
+		version: "v2.4.0",
+	}, {
+		name:          "compatible version",
+		compatibility: &v1alpha3.AddonCompatibility{Versions: ">=2.3.0 <3.0.0"},
+		version:       "v2.3.1",
+	}, {
+		name:          "incompatible version",
+		compatibility: &v1alpha3.AddonCompatibility{Versions: ">=2.3.0 <3.0.0"},
+		version:       "v3.0.0",
+		wantErr:       true,
+	}, {
+		name:          "invalid version",
+		compatibility: &v1alpha3.AddonCompatibility{Versions: ">=2.3.0 <3.0.0"},
+		version:       "latest",
+		wantErr:       true,
+	}, {
+		name:          "not upgrading",
+		compatibility: &v1alpha3.AddonCompatibility{Upgrades: upgrades},
+		version:       "v2.3.1",
+		installed:     "v2.3.1",
+	}, {
+		name:          "any upgrade is allowed without the upgrade paths",
+		compatibility: &v1alpha3.AddonCompatibility{Versions: ">=2.3.0"},
+		version:       "v2.5.0",
+		installed:     "v2.3.1",
+	}, {
+		name:          "found the upgrade path",
+		compatibility: &v1alpha3.AddonCompatibility{Upgrades: upgrades},
+		version:       "v2.4.2",
+		installed:     "v2.3.1",
+		wantPath:      true,
+	}, {
+		name:          "no upgrade path",
+		compatibility: &v1alpha3.AddonCompatibility{Upgrades: upgrades},
+		version:       "v2.5.0",
+		installed:     "v2.3.1",
+		wantErr:       true,
+	}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			addon := &v1alpha3.Addon{
+				Spec:   v1alpha3.AddonSpec{Version: tt.version},
+				Status: v1alpha3.AddonStatus{Version: tt.installed},
+			}
+			strategy := &v1alpha3.AddonStrategy{Spec: v1alpha3.AddStrategySpec{Compatibility: tt.compatibility}}
+			path, err := findUpgradePath(addon, strategy)
+			assert.Equal(t, tt.wantErr, err != nil, err)
+			assert.Equal(t, tt.wantPath, path != nil)
+		})
+	}
+}
+
+func TestReconciler_upgrade(t *testing.T) {
+	schema, err := v1alpha3.SchemeBuilder.Register().Build()
+	assert.Nil(t, err)
+	err = v1.AddToScheme(schema)
+	assert.Nil(t, err)
+	err = batchv1.AddToScheme(schema)
+	assert.Nil(t, err)
+
+	strategy := &v1alpha3.AddonStrategy{
+		ObjectMeta: metav1.ObjectMeta{Name: "simple-demo"},
+		Spec: v1alpha3.AddStrategySpec{
+			Type: v1alpha3.AddonInstallStrategySimple,
+			YAML: `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: demo
+data:
+  version: {{.Spec.Version}}`,
+			Compatibility: &v1alpha3.AddonCompatibility{
+				Versions: ">=1.0.0 <2.0.0",
+				Upgrades: []v1alpha3.AddonUpgradePath{{
+					From: "<1.1.0",
+					To:   ">=1.1.0",
+					PreUpgrade: `apiVersion: batch/v1
+kind: Job
+metadata:
+  name: demo-pre-upgrade-{{.Spec.Version}}`,
+				}},
+			},
+		},
+	}
+	addon := &v1alpha3.Addon{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "demo"},
+		Spec: v1alpha3.AddonSpec{
+			Version:  "1.1.0",
+			Strategy: v1.LocalObjectReference{Name: "simple-demo"},
+		},
+		Status: v1alpha3.AddonStatus{
+			Phase:   v1alpha3.AddonPhaseInstalled,
+			Version: "1.0.0",
+		},
+	}
+	incompatibleAddon := addon.DeepCopy()
+	incompatibleAddon.Spec.Version = "2.0.0"
+	installedConfig := &v1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "demo"},
+		Data:       map[string]string{"version": "1.0.0"},
+	}
+	completedJob := &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "demo-pre-upgrade-1.1.0"},
+		Status: batchv1.JobStatus{Conditions: []batchv1.JobCondition{{
+			Type:   batchv1.JobComplete,
+			Status: v1.ConditionTrue,
+		}}},
+	}
+	failedJob := completedJob.DeepCopy()
+	failedJob.Status.Conditions[0].Type = batchv1.JobFailed
+
+	getVersion := func(c client.Client) string {
+		cm := &v1.ConfigMap{}
+		assert.Nil(t, c.Get(context.Background(), types.NamespacedName{Namespace: "default", Name: "demo"}, cm))
+		return cm.Data["version"]
+	}
+
+	tests := []struct {
+		name       string
+		objects    []client.Object
+		wantResult ctrl.Result
+		wantErr    bool
+		wantPhase  v1alpha3.AddonPhase
+		wantVer    string
+		verify     func(*testing.T, client.Client)
+	}{{
+		name:      "refuse the incompatible version",
+		objects:   []client.Object{strategy.DeepCopy(), incompatibleAddon, installedConfig.DeepCopy()},
+		wantPhase: v1alpha3.AddonPhaseFailed,
+		wantVer:   "1.0.0",
+		verify: func(t *testing.T, c client.Client) {
+			assert.Equal(t, "1.0.0", getVersion(c))
+		},
+	}, {
+		name:       "waiting for the pre-upgrade hook",
+		objects:    []client.Object{strategy.DeepCopy(), addon.DeepCopy(), installedConfig.DeepCopy()},
+		wantResult: ctrl.Result{RequeueAfter: installingRequeuePeriod},
+		wantPhase:  v1alpha3.AddonPhaseUpgrading,
+		wantVer:    "1.0.0",
+		verify: func(t *testing.T, c client.Client) {
+			assert.Equal(t, "1.0.0", getVersion(c))
+
+			job := &batchv1.Job{}
+			err := c.Get(context.Background(), types.NamespacedName{Namespace: "default", Name: "demo-pre-upgrade-1.1.0"}, job)
+			assert.Nil(t, err)
+			assert.Equal(t, "demo", job.Labels[v1alpha3.AddonLabelKey])
+		},
+	}, {
+		name:      "upgrade after the pre-upgrade hook",
+		objects:   []client.Object{strategy.DeepCopy(), addon.DeepCopy(), installedConfig.DeepCopy(), completedJob.DeepCopy()},
+		wantPhase: v1alpha3.AddonPhaseInstalled,
+		wantVer:   "1.1.0",
+		verify: func(t *testing.T, c client.Client) {
+			assert.Equal(t, "1.1.0", getVersion(c))
+
+			job := &batchv1.Job{}
+			err := c.Get(context.Background(), types.NamespacedName{Namespace: "default", Name: "demo-pre-upgrade-1.1.0"}, job)
+			assert.True(t, apierrors.IsNotFound(err), err)
+		},
+	}, {
+		name:      "the pre-upgrade hook failed",
+		objects:   []client.Object{strategy.DeepCopy(), addon.DeepCopy(), installedConfig.DeepCopy(), failedJob},
+		wantErr:   true,
+		wantPhase: v1alpha3.AddonPhaseFailed,
+		wantVer:   "1.0.0",
+		verify: func(t *testing.T, c client.Client) {
+			assert.Equal(t, "1.0.0", getVersion(c))
+		},
+	}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := fake.NewClientBuilder().WithScheme(schema).WithObjects(tt.objects...).
+				WithRESTMapper(testrestmapper.TestOnlyStaticRESTMapper(schema)).Build()
+			r := &Reconciler{
+				Client:   c,
+				log:      logr.Discard(),
+				recorder: record.NewFakeRecorder(100),
+			}
+			result, err := r.Reconcile(context.Background(), ctrl.Request{
+				NamespacedName: types.NamespacedName{Namespace: "default", Name: "demo"},
+			})
+			assert.Equal(t, tt.wantErr, err != nil, err)
+			assert.Equal(t, tt.wantResult, result)
+
+			current := &v1alpha3.Addon{}
+			assert.Nil(t, c.Get(context.Background(), types.NamespacedName{Namespace: "default", Name: "demo"}, current))
+			assert.Equal(t, tt.wantPhase, current.Status.Phase)
+			assert.Equal(t, tt.wantVer, current.Status.Version)
+			if tt.verify != nil {
+				tt.verify(t, c)
+			}
+		})
+	}
+}
@@ -149,6 +149,18 @@ func (c *GitRepositoryController) setArgoGitRepoAuth(secret *v1.Secret, ref *v1.
 		case v1.SecretTypeBasicAuth, v1alpha3.SecretTypeBasicAuth:
 			secret.Data["username"] = authSecret.Data[v1.BasicAuthUsernameKey]
 			secret.Data["password"] = authSecret.Data[v1.BasicAuthPasswordKey]
+		case v1.SecretTypeSSHAuth:
+			secret.Data["sshPrivateKey"] = authSecret.Data[v1.SSHAuthPrivateKey]
+		case v1alpha3.SecretTypeSSHAuth:
+			// Argo CD checks the host key against its own known hosts
+			secret.Data["sshPrivateKey"] = authSecret.Data[v1alpha3.SSHAuthPrivateKey]
+		case v1alpha3.SecretTypeGitHubApp:
+			secret.Data["githubAppID"] = authSecret.Data[v1alpha3.GitHubAppIDKey]
+			secret.Data["githubAppInstallationID"] = authSecret.Data[v1alpha3.GitHubAppInstallationIDKey]
+			secret.Data["githubAppPrivateKey"] = authSecret.Data[v1alpha3.GitHubAppPrivateKey]
+			if apiURL, ok := authSecret.Data[v1alpha3.GitHubAppAPIURLKey]; ok {
+				secret.Data["githubAppEnterpriseBaseUrl"] = apiURL
+			}
 		default:
 			c.log.V(4).Info("not support auth secret", "type", authSecret.Type)
 		}
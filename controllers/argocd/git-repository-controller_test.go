@@ -210,6 +210,64 @@ func TestGitRepositoryController_Reconcile(t *testing.T) {
 	}
 }
 
+func TestGitRepositoryController_setArgoGitRepoAuth(t *testing.T) {
+	schema, err := v1alpha3.SchemeBuilder.Register().Build()
+	assert.Nil(t, err)
+	err = v1.SchemeBuilder.AddToScheme(schema)
+	assert.Nil(t, err)
+
+	newSecret := func(secretType v1.SecretType, data map[string][]byte) *v1.Secret {
+		return &v1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "auth"},
+			Type:       secretType,
+			Data:       data,
+		}
+	}
+
+	tests := []struct {
+		name       string
+		authSecret *v1.Secret
+		want       map[string][]byte
+	}{{
+		name:       "basic auth",
+		authSecret: newSecret(v1alpha3.SecretTypeBasicAuth, map[string][]byte{"username": []byte("admin"), "password": []byte("token")}),
+		want:       map[string][]byte{"username": []byte("admin"), "password": []byte("token")},
+	}, {
+		name:       "devops ssh auth",
+		authSecret: newSecret(v1alpha3.SecretTypeSSHAuth, map[string][]byte{"username": []byte("git"), "private_key": []byte("key")}),
+		want:       map[string][]byte{"sshPrivateKey": []byte("key")},
+	}, {
+		name:       "kubernetes ssh auth",
+		authSecret: newSecret(v1.SecretTypeSSHAuth, map[string][]byte{"ssh-privatekey": []byte("key")}),
+		want:       map[string][]byte{"sshPrivateKey": []byte("key")},
+	}, {
+		name: "github app",
+		authSecret: newSecret(v1alpha3.SecretTypeGitHubApp, map[string][]byte{
+			"app_id": []byte("1"), "installation_id": []byte("2"), "private_key": []byte("key"),
+			"api_url": []byte("https://github.example.com/api/v3"),
+		}),
+		want: map[string][]byte{
+			"githubAppID": []byte("1"), "githubAppInstallationID": []byte("2"), "githubAppPrivateKey": []byte("key"),
+			"githubAppEnterpriseBaseUrl": []byte("https://github.example.com/api/v3"),
+		},
+	}, {
+		name:       "not supported",
+		authSecret: newSecret(v1alpha3.SecretTypeKubeConfig, map[string][]byte{"content": []byte("config")}),
+		want:       map[string][]byte{},
+	}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &GitRepositoryController{
+				Client: fake.NewClientBuilder().WithScheme(schema).WithObjects(tt.authSecret).Build(),
+				log:    logr.New(log.NullLogSink{}),
+			}
+			secret := &v1.Secret{Data: map[string][]byte{}}
+			c.setArgoGitRepoAuth(secret, &v1.SecretReference{Namespace: "ns", Name: "auth"})
+			assert.Equal(t, tt.want, secret.Data)
+		})
+	}
+}
+
 func TestGitRepositoryController_SetupWithManager(t *testing.T) {
 	schema, err := v1alpha3.SchemeBuilder.Register().Build()
 	assert.Nil(t, err)
@@ -0,0 +1,355 @@
+/*
+Copyright 2022 The KubeSphere Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package deploytarget
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/kubesphere/ks-devops/pkg/api/devops/v1alpha3"
+	"github.com/kubesphere/ks-devops/pkg/constants"
+	"github.com/kubesphere/ks-devops/pkg/utils/k8sutil"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// DefaultTTL is the default time-to-live of the deployment credentials
+	DefaultTTL = time.Hour
+
+	hostClusterLabelKey = "cluster-role.kubesphere.io/host"
+)
+
+//+kubebuilder:rbac:groups=devops.kubesphere.io,resources=pipelineruns,verbs=get;list;watch
+//+kubebuilder:rbac:groups=devops.kubesphere.io,resources=pipelines,verbs=get;list;watch
+//+kubebuilder:rbac:groups=devops.kubesphere.io,resources=devopsprojects,verbs=get
+//+kubebuilder:rbac:groups=cluster.kubesphere.io,resources=clusters,verbs=get;list;watch
+//+kubebuilder:rbac:groups="",resources=namespaces,verbs=get
+//+kubebuilder:rbac:groups="",resources=secrets,verbs=get;list;create;update;delete
+//+kubebuilder:rbac:groups="",resources=events,verbs=create;patch
+
+// Reconciler issues the short-lived kubeconfig credentials of the deployment targets for the running PipelineRuns.
+// The credentials are stored as DevOps credentials, so they are able to be injected into the agent by Jenkins.
+type Reconciler struct {
+	client.Client
+	Issuer CredentialIssuer
+	// HostConfig is the config of the host cluster, it's used when the Cluster object does not have a kubeconfig
+	HostConfig *rest.Config
+	// TTL is the time-to-live of the credentials, the default value is DefaultTTL
+	TTL time.Duration
+
+	log      logr.Logger
+	recorder record.EventRecorder
+	// newClientset creates the clientset of a cluster, it's replaced in the tests
+	newClientset func(config *rest.Config) (kubernetes.Interface, error)
+}
+
+// Reconcile issues the credentials when a PipelineRun is running, and releases them once it's completed
+func (r *Reconciler) Reconcile(ctx context.Context, req ctrl.Request) (result ctrl.Result, err error) {
+	r.log.V(6).Info(fmt.Sprintf("start to reconcile PipelineRun: %s", req.String()))
+
+	pipelineRun := &v1alpha3.PipelineRun{}
+	if err = r.Get(ctx, req.NamespacedName, pipelineRun); err != nil {
+		err = client.IgnoreNotFound(err)
+		return
+	}
+
+	if pipelineRun.Spec.PipelineRef == nil || pipelineRun.Spec.PipelineRef.Name == "" {
+		return
+	}
+
+	pipeline := &v1alpha3.Pipeline{}
+	if err = r.Get(ctx, types.NamespacedName{
+		Namespace: pipelineRun.Namespace,
+		Name:      pipelineRun.Spec.PipelineRef.Name,
+	}, pipeline); err != nil {
+		err = client.IgnoreNotFound(err)
+		return
+	}
+
+	targets := pipeline.GetDeployTargets()
+	if len(targets) == 0 {
+		return
+	}
+
+	if !pipelineRun.DeletionTimestamp.IsZero() || pipelineRun.HasCompleted() {
+		for _, target := range targets {
+			if err = r.releaseCredential(ctx, pipelineRun, target); err != nil {
+				return
+			}
+		}
+		return
+	}
+
+	var workspace string
+	if workspace, err = r.getWorkspace(ctx, pipelineRun.Namespace); err != nil {
+		return
+	}
+
+	ttl := r.getTTL()
+	for _, target := range targets {
+		var config *rest.Config
+		var authorized bool
+		if config, err = r.getClusterConfig(ctx, target.Cluster); err == nil {
+			authorized, err = r.authorize(ctx, config, workspace, target)
+		}
+		if err == nil && !authorized {
+			r.recorder.Eventf(pipelineRun, corev1.EventTypeWarning, "UnauthorizedDeployTarget",
+				"deployment target %s/%s does not belong to workspace %q of the DevOps project",
+				target.Cluster, target.Namespace, workspace)
+			continue
+		}
+		if err == nil {
+			err = r.ensureCredential(ctx, pipelineRun, target, config, ttl)
+		}
+		if err != nil {
+			r.recorder.Eventf(pipelineRun, corev1.EventTypeWarning, "FailedToIssueCredential",
+				"failed to issue the credential for deployment target %s/%s, error is: %v",
+				target.Cluster, target.Namespace, err)
+			return
+		}
+	}
+	// renew the credentials before they expire
+	result = ctrl.Result{RequeueAfter: ttl / 2}
+	return
+}
+
+func (r *Reconciler) getTTL() time.Duration {
+	if r.TTL <= 0 {
+		return DefaultTTL
+	}
+	return r.TTL
+}
+
+// getWorkspace returns the workspace of the DevOps project which the namespace belongs to
+func (r *Reconciler) getWorkspace(ctx context.Context, namespace string) (workspace string, err error) {
+	ns := &corev1.Namespace{}
+	if err = r.Get(ctx, types.NamespacedName{Name: namespace}, ns); err != nil {
+		err = client.IgnoreNotFound(err)
+		return
+	}
+
+	if projectName := ns.Labels[constants.DevOpsProjectLabelKey]; projectName != "" {
+		project := &v1alpha3.DevOpsProject{}
+		if err = r.Get(ctx, types.NamespacedName{Name: projectName}, project); err != nil {
+			err = client.IgnoreNotFound(err)
+			return
+		}
+		workspace = project.Labels[constants.WorkspaceLabelKey]
+	}
+	if workspace == "" {
+		workspace = ns.Labels[constants.WorkspaceLabelKey]
+	}
+	return
+}
+
+// authorize checks if the target namespace belongs to the workspace of the DevOps project.
+// The controller is the admin of the clusters, so the namespaces of the other workspaces must be rejected.
+func (r *Reconciler) authorize(ctx context.Context, config *rest.Config, workspace string,
+	target v1alpha3.DeployTarget) (authorized bool, err error) {
+	if workspace == "" {
+		return
+	}
+
+	var clientset kubernetes.Interface
+	if clientset, err = r.getClientset(config); err != nil {
+		return
+	}
+	var ns *corev1.Namespace
+	if ns, err = clientset.CoreV1().Namespaces().Get(ctx, target.Namespace, metav1.GetOptions{}); err != nil {
+		if apierrors.IsNotFound(err) {
+			err = nil
+		}
+		return
+	}
+	authorized = ns.Labels[constants.WorkspaceLabelKey] == workspace
+	return
+}
+
+func (r *Reconciler) getClientset(config *rest.Config) (kubernetes.Interface, error) {
+	if r.newClientset != nil {
+		return r.newClientset(config)
+	}
+	return kubernetes.NewForConfig(config)
+}
+
+// ensureCredential makes sure the credential is valid, and the PipelineRun is one of its owners
+func (r *Reconciler) ensureCredential(ctx context.Context, pipelineRun *v1alpha3.PipelineRun,
+	target v1alpha3.DeployTarget, config *rest.Config, ttl time.Duration) (err error) {
+	secret := &corev1.Secret{}
+	exists := true
+	if err = r.Get(ctx, types.NamespacedName{
+		Namespace: pipelineRun.Namespace,
+		Name:      target.GetCredentialName(),
+	}, secret); err != nil {
+		if !apierrors.IsNotFound(err) {
+			return
+		}
+		exists = false
+		secret = &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: pipelineRun.Namespace,
+				Name:      target.GetCredentialName(),
+				Labels: map[string]string{
+					v1alpha3.DeployTargetClusterLabelKey:   target.Cluster,
+					v1alpha3.DeployTargetNamespaceLabelKey: target.Namespace,
+				},
+			},
+			Type: v1alpha3.SecretTypeKubeConfig,
+		}
+	}
+
+	if needsRenewal(secret, ttl) {
+		var kubeconfig []byte
+		var expiration time.Time
+		if kubeconfig, expiration, err = r.Issuer.Issue(ctx, config, target.Namespace, ttl); err != nil {
+			return
+		}
+		secret.Data = map[string][]byte{
+			v1alpha3.KubeConfigSecretKey: kubeconfig,
+		}
+		if secret.Annotations == nil {
+			secret.Annotations = map[string]string{}
+		}
+		secret.Annotations[v1alpha3.DeployTargetExpirationAnnoKey] = expiration.UTC().Format(time.RFC3339)
+		r.recorder.Eventf(pipelineRun, corev1.EventTypeNormal, "CredentialIssued",
+			"issued credential %s for deployment target %s/%s", secret.Name, target.Cluster, target.Namespace)
+	}
+
+	k8sutil.SetOwnerReference(secret, getOwnerReference(pipelineRun))
+	if exists {
+		err = r.Update(ctx, secret)
+	} else {
+		err = r.Create(ctx, secret)
+	}
+	return
+}
+
+// releaseCredential removes the PipelineRun from the owners of the credential,
+// the credential will be deleted if there are no other owners
+func (r *Reconciler) releaseCredential(ctx context.Context, pipelineRun *v1alpha3.PipelineRun,
+	target v1alpha3.DeployTarget) (err error) {
+	secret := &corev1.Secret{}
+	if err = r.Get(ctx, types.NamespacedName{
+		Namespace: pipelineRun.Namespace,
+		Name:      target.GetCredentialName(),
+	}, secret); err != nil {
+		err = client.IgnoreNotFound(err)
+		return
+	}
+
+	var refs []metav1.OwnerReference
+	for _, ref := range secret.OwnerReferences {
+		if ref.UID != pipelineRun.UID {
+			refs = append(refs, ref)
+		}
+	}
+	if len(refs) == len(secret.OwnerReferences) {
+		return
+	}
+
+	if len(refs) == 0 {
+		err = client.IgnoreNotFound(r.Delete(ctx, secret))
+		return
+	}
+	secret.OwnerReferences = refs
+	err = r.Update(ctx, secret)
+	return
+}
+
+// needsRenewal checks if the credential is going to expire in half of the TTL
+func needsRenewal(secret *corev1.Secret, ttl time.Duration) bool {
+	if len(secret.Data[v1alpha3.KubeConfigSecretKey]) == 0 {
+		return true
+	}
+	expiration, err := time.Parse(time.RFC3339, secret.Annotations[v1alpha3.DeployTargetExpirationAnnoKey])
+	return err != nil || time.Now().Add(ttl/2).After(expiration)
+}
+
+// getClusterConfig returns the config of a KubeSphere cluster
+func (r *Reconciler) getClusterConfig(ctx context.Context, name string) (config *rest.Config, err error) {
+	cluster := createBareClusterObject()
+	if err = r.Get(ctx, types.NamespacedName{Name: name}, cluster); err != nil {
+		return
+	}
+
+	kubeconfig, _, _ := unstructured.NestedString(cluster.Object, "spec", "connection", "kubeconfig")
+	if kubeconfig == "" {
+		if _, isHost := cluster.GetLabels()[hostClusterLabelKey]; isHost && r.HostConfig != nil {
+			config = r.HostConfig
+			return
+		}
+		err = fmt.Errorf("no kubeconfig found in cluster %s", name)
+		return
+	}
+
+	var data []byte
+	if data, err = base64.StdEncoding.DecodeString(kubeconfig); err == nil {
+		config, err = clientcmd.RESTConfigFromKubeConfig(data)
+	}
+	return
+}
+
+func getOwnerReference(pipelineRun *v1alpha3.PipelineRun) metav1.OwnerReference {
+	return metav1.OwnerReference{
+		APIVersion: v1alpha3.GroupVersion.String(),
+		Kind:       "PipelineRun",
+		Name:       pipelineRun.Name,
+		UID:        pipelineRun.UID,
+	}
+}
+
+func createBareClusterObject() *unstructured.Unstructured {
+	cluster := &unstructured.Unstructured{}
+	cluster.SetGroupVersionKind(schema.GroupVersionKind{
+		Group:   "cluster.kubesphere.io",
+		Version: "v1alpha1",
+		Kind:    "Cluster",
+	})
+	return cluster
+}
+
+// GetName returns the name of this controller
+func (r *Reconciler) GetName() string {
+	return "DeployTargetReconciler"
+}
+
+// SetupWithManager setups the log and recorder
+func (r *Reconciler) SetupWithManager(mgr ctrl.Manager) error {
+	r.log = ctrl.Log.WithName(r.GetName())
+	r.recorder = mgr.GetEventRecorderFor(r.GetName())
+	if r.Issuer == nil {
+		r.Issuer = NewServiceAccountIssuer(DefaultClusterRole)
+	}
+	return ctrl.NewControllerManagedBy(mgr).
+		Named("deploy_target_controller").
+		For(&v1alpha3.PipelineRun{}).
+		Complete(r)
+}
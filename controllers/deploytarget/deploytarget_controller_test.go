@@ -0,0 +1,317 @@
+/*
+Copyright 2022 The KubeSphere Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package deploytarget
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/kubesphere/ks-devops/controllers/core"
+	"github.com/kubesphere/ks-devops/pkg/api/devops/v1alpha3"
+	"github.com/kubesphere/ks-devops/pkg/constants"
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+type fakeIssuer struct {
+	hosts []string
+	err   error
+}
+
+func (f *fakeIssuer) Issue(ctx context.Context, config *rest.Config, namespace string, ttl time.Duration) (
+	kubeconfig []byte, expiration time.Time, err error) {
+	f.hosts = append(f.hosts, config.Host)
+	if err = f.err; err == nil {
+		kubeconfig = []byte("kubeconfig of " + namespace)
+		expiration = time.Now().Add(ttl)
+	}
+	return
+}
+
+const memberKubeConfig = `apiVersion: v1
+kind: Config
+clusters:
+- name: member
+  cluster:
+    server: https://member:6443
+contexts:
+- name: member
+  context:
+    cluster: member
+    user: admin
+current-context: member
+users:
+- name: admin
+  user:
+    token: token
+`
+
+func TestReconciler_Reconcile(t *testing.T) {
+	schema, err := v1alpha3.SchemeBuilder.Register().Build()
+	assert.Nil(t, err)
+	err = v1.SchemeBuilder.AddToScheme(schema)
+	assert.Nil(t, err)
+
+	pipeline := &v1alpha3.Pipeline{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: "devops",
+			Name:      "pipeline",
+			Annotations: map[string]string{
+				v1alpha3.PipelineDeployTargetsAnnoKey: "host/demo,member/prod",
+			},
+		},
+	}
+	pipelineRun := &v1alpha3.PipelineRun{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: "devops",
+			Name:      "run",
+			UID:       "uid",
+		},
+		Spec: v1alpha3.PipelineRunSpec{
+			PipelineRef: &v1.ObjectReference{Name: "pipeline"},
+		},
+	}
+	completedRun := pipelineRun.DeepCopy()
+	completedRun.Status.CompletionTime = &metav1.Time{Time: time.Now()}
+	noTargetPipeline := pipeline.DeepCopy()
+	noTargetPipeline.Annotations = nil
+	unauthorizedPipeline := pipeline.DeepCopy()
+	unauthorizedPipeline.Annotations[v1alpha3.PipelineDeployTargetsAnnoKey] = "host/kube-system,member/other,host/missing"
+
+	devopsNamespace := &v1.Namespace{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   "devops",
+			Labels: map[string]string{constants.DevOpsProjectLabelKey: "devops"},
+		},
+	}
+	devopsProject := &v1alpha3.DevOpsProject{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   "devops",
+			Labels: map[string]string{constants.WorkspaceLabelKey: "ws"},
+		},
+	}
+	newWorkspaceNamespace := func(name, workspace string) *v1.Namespace {
+		return &v1.Namespace{ObjectMeta: metav1.ObjectMeta{
+			Name:   name,
+			Labels: map[string]string{constants.WorkspaceLabelKey: workspace},
+		}}
+	}
+	clusterNamespaces := []runtime.Object{newWorkspaceNamespace("demo", "ws"), newWorkspaceNamespace("prod", "ws"),
+		newWorkspaceNamespace("other", "other-ws"), &v1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "kube-system"}}}
+
+	hostCluster := createBareClusterObject()
+	hostCluster.SetName("host")
+	hostCluster.SetLabels(map[string]string{hostClusterLabelKey: ""})
+	memberCluster := createBareClusterObject()
+	memberCluster.SetName("member")
+	_ = unstructured.SetNestedField(memberCluster.Object, base64.StdEncoding.EncodeToString([]byte(memberKubeConfig)),
+		"spec", "connection", "kubeconfig")
+
+	validCredential := &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: "devops",
+			Name:      "deploy-host.demo",
+			Annotations: map[string]string{
+				v1alpha3.DeployTargetExpirationAnnoKey: time.Now().Add(time.Hour).UTC().Format(time.RFC3339),
+			},
+			OwnerReferences: []metav1.OwnerReference{{Name: "other", UID: "other"}},
+		},
+		Type: v1alpha3.SecretTypeKubeConfig,
+		Data: map[string][]byte{v1alpha3.KubeConfigSecretKey: []byte("valid")},
+	}
+	expiredCredential := validCredential.DeepCopy()
+	expiredCredential.Annotations[v1alpha3.DeployTargetExpirationAnnoKey] = time.Now().Add(time.Minute).UTC().Format(time.RFC3339)
+	ownedCredential := validCredential.DeepCopy()
+	ownedCredential.OwnerReferences = []metav1.OwnerReference{{Name: "run", UID: "uid"}}
+	sharedCredential := validCredential.DeepCopy()
+	sharedCredential.Name = "deploy-member.prod"
+	sharedCredential.OwnerReferences = append(sharedCredential.OwnerReferences, metav1.OwnerReference{Name: "run", UID: "uid"})
+
+	getSecret := func(c client.Client, name string) (secret *v1.Secret, err error) {
+		secret = &v1.Secret{}
+		err = c.Get(context.Background(), types.NamespacedName{Namespace: "devops", Name: name}, secret)
+		return
+	}
+
+	tests := []struct {
+		name    string
+		objects []runtime.Object
+		issuer  *fakeIssuer
+		// withoutWorkspace indicates the DevOps project does not belong to any workspace
+		withoutWorkspace bool
+		wantResult       ctrl.Result
+		wantErr          bool
+		verify           func(*testing.T, client.Client, *fakeIssuer)
+	}{{
+		name: "not found",
+	}, {
+		name:    "pipeline not found",
+		objects: []runtime.Object{pipelineRun.DeepCopy()},
+	}, {
+		name:    "no deployment targets",
+		objects: []runtime.Object{pipelineRun.DeepCopy(), noTargetPipeline},
+	}, {
+		name: "issue credentials",
+		objects: []runtime.Object{pipelineRun.DeepCopy(), pipeline.DeepCopy(),
+			hostCluster.DeepCopy(), memberCluster.DeepCopy()},
+		issuer:     &fakeIssuer{},
+		wantResult: ctrl.Result{RequeueAfter: DefaultTTL / 2},
+		verify: func(t *testing.T, c client.Client, issuer *fakeIssuer) {
+			assert.Equal(t, []string{"https://host:6443", "https://member:6443"}, issuer.hosts)
+
+			secret, err := getSecret(c, "deploy-member.prod")
+			assert.Nil(t, err)
+			assert.Equal(t, v1alpha3.SecretTypeKubeConfig, secret.Type)
+			assert.Equal(t, "kubeconfig of prod", string(secret.Data[v1alpha3.KubeConfigSecretKey]))
+			assert.Equal(t, "member", secret.Labels[v1alpha3.DeployTargetClusterLabelKey])
+			assert.NotEmpty(t, secret.Annotations[v1alpha3.DeployTargetExpirationAnnoKey])
+			if assert.Equal(t, 1, len(secret.OwnerReferences)) {
+				assert.Equal(t, types.UID("uid"), secret.OwnerReferences[0].UID)
+			}
+		},
+	}, {
+		name: "reuse the valid credential",
+		objects: []runtime.Object{pipelineRun.DeepCopy(), pipeline.DeepCopy(),
+			hostCluster.DeepCopy(), memberCluster.DeepCopy(), validCredential.DeepCopy()},
+		issuer:     &fakeIssuer{},
+		wantResult: ctrl.Result{RequeueAfter: DefaultTTL / 2},
+		verify: func(t *testing.T, c client.Client, issuer *fakeIssuer) {
+			assert.Equal(t, []string{"https://member:6443"}, issuer.hosts)
+
+			secret, err := getSecret(c, "deploy-host.demo")
+			assert.Nil(t, err)
+			assert.Equal(t, "valid", string(secret.Data[v1alpha3.KubeConfigSecretKey]))
+			assert.Equal(t, 2, len(secret.OwnerReferences))
+		},
+	}, {
+		name: "renew the credential which is going to expire",
+		objects: []runtime.Object{pipelineRun.DeepCopy(), pipeline.DeepCopy(),
+			hostCluster.DeepCopy(), memberCluster.DeepCopy(), expiredCredential.DeepCopy()},
+		issuer:     &fakeIssuer{},
+		wantResult: ctrl.Result{RequeueAfter: DefaultTTL / 2},
+		verify: func(t *testing.T, c client.Client, issuer *fakeIssuer) {
+			secret, err := getSecret(c, "deploy-host.demo")
+			assert.Nil(t, err)
+			assert.Equal(t, "kubeconfig of demo", string(secret.Data[v1alpha3.KubeConfigSecretKey]))
+		},
+	}, {
+		name: "reject the namespaces outside the workspace",
+		objects: []runtime.Object{pipelineRun.DeepCopy(), unauthorizedPipeline,
+			hostCluster.DeepCopy(), memberCluster.DeepCopy()},
+		issuer:     &fakeIssuer{},
+		wantResult: ctrl.Result{RequeueAfter: DefaultTTL / 2},
+		verify: func(t *testing.T, c client.Client, issuer *fakeIssuer) {
+			assert.Empty(t, issuer.hosts)
+
+			secrets := &v1.SecretList{}
+			assert.Nil(t, c.List(context.Background(), secrets))
+			assert.Empty(t, secrets.Items)
+		},
+	}, {
+		name: "reject all targets of the DevOps project without workspace",
+		objects: []runtime.Object{pipelineRun.DeepCopy(), pipeline.DeepCopy(),
+			hostCluster.DeepCopy(), memberCluster.DeepCopy()},
+		withoutWorkspace: true,
+		issuer:           &fakeIssuer{},
+		wantResult:       ctrl.Result{RequeueAfter: DefaultTTL / 2},
+		verify: func(t *testing.T, c client.Client, issuer *fakeIssuer) {
+			assert.Empty(t, issuer.hosts)
+		},
+	}, {
+		name:    "cluster not found",
+		objects: []runtime.Object{pipelineRun.DeepCopy(), pipeline.DeepCopy()},
+		issuer:  &fakeIssuer{},
+		wantErr: true,
+	}, {
+		name: "failed to issue",
+		objects: []runtime.Object{pipelineRun.DeepCopy(), pipeline.DeepCopy(),
+			hostCluster.DeepCopy(), memberCluster.DeepCopy()},
+		issuer:  &fakeIssuer{err: errors.New("fake")},
+		wantErr: true,
+	}, {
+		name: "release the credentials",
+		objects: []runtime.Object{completedRun, pipeline.DeepCopy(),
+			ownedCredential.DeepCopy(), sharedCredential.DeepCopy()},
+		verify: func(t *testing.T, c client.Client, issuer *fakeIssuer) {
+			_, err := getSecret(c, "deploy-host.demo")
+			assert.True(t, client.IgnoreNotFound(err) == nil && err != nil)
+
+			secret, err := getSecret(c, "deploy-member.prod")
+			assert.Nil(t, err)
+			assert.Equal(t, []metav1.OwnerReference{{Name: "other", UID: "other"}}, secret.OwnerReferences)
+		},
+	}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			objects := append([]runtime.Object{devopsNamespace.DeepCopy()}, tt.objects...)
+			if !tt.withoutWorkspace {
+				objects = append(objects, devopsProject.DeepCopy())
+			}
+			c := fake.NewClientBuilder().WithScheme(schema).WithRuntimeObjects(objects...).Build()
+			r := &Reconciler{
+				Client:     c,
+				HostConfig: &rest.Config{Host: "https://host:6443"},
+				log:        logr.New(log.NullLogSink{}),
+				recorder:   &record.FakeRecorder{},
+				newClientset: func(*rest.Config) (kubernetes.Interface, error) {
+					return kubefake.NewSimpleClientset(clusterNamespaces...), nil
+				},
+			}
+			if tt.issuer != nil {
+				r.Issuer = tt.issuer
+			}
+			result, err := r.Reconcile(context.Background(), ctrl.Request{
+				NamespacedName: types.NamespacedName{Namespace: "devops", Name: "run"},
+			})
+			assert.Equal(t, tt.wantErr, err != nil, err)
+			assert.Equal(t, tt.wantResult, result)
+			if tt.verify != nil {
+				tt.verify(t, c, tt.issuer)
+			}
+		})
+	}
+}
+
+func TestReconciler_SetupWithManager(t *testing.T) {
+	schema, err := v1alpha3.SchemeBuilder.Register().Build()
+	assert.Nil(t, err)
+
+	r := &Reconciler{}
+	err = r.SetupWithManager(&core.FakeManager{
+		Client: fake.NewClientBuilder().WithScheme(schema).Build(),
+		Scheme: schema,
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, "DeployTargetReconciler", r.GetName())
+	assert.NotNil(t, r.Issuer)
+}
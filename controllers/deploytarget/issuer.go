@@ -0,0 +1,163 @@
+/*
+Copyright 2022 The KubeSphere Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package deploytarget
+
+import (
+	"context"
+	"os"
+	"time"
+
+	authenticationv1 "k8s.io/api/authentication/v1"
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+const (
+	// DeployerName is the name of the ServiceAccount and RoleBinding which are created in the target namespace
+	DeployerName = "ks-devops-deployer"
+	// DefaultClusterRole is the default ClusterRole which is bound to the deployer in the target namespace
+	DefaultClusterRole = "edit"
+)
+
+// CredentialIssuer issues a short-lived kubeconfig which is scoped to a namespace of a cluster
+type CredentialIssuer interface {
+	Issue(ctx context.Context, config *rest.Config, namespace string, ttl time.Duration) (
+		kubeconfig []byte, expiration time.Time, err error)
+}
+
+// serviceAccountIssuer issues the kubeconfig with a bound ServiceAccount token
+type serviceAccountIssuer struct {
+	clusterRole  string
+	newClientset func(config *rest.Config) (kubernetes.Interface, error)
+}
+
+// NewServiceAccountIssuer creates a CredentialIssuer which binds the clusterRole to a ServiceAccount
+// in the target namespace, then requests a token with the expected TTL
+func NewServiceAccountIssuer(clusterRole string) CredentialIssuer {
+	if clusterRole == "" {
+		clusterRole = DefaultClusterRole
+	}
+	return &serviceAccountIssuer{
+		clusterRole: clusterRole,
+		newClientset: func(config *rest.Config) (kubernetes.Interface, error) {
+			return kubernetes.NewForConfig(config)
+		},
+	}
+}
+
+// Issue issues a kubeconfig which is scoped to the namespace
+func (i *serviceAccountIssuer) Issue(ctx context.Context, config *rest.Config, namespace string, ttl time.Duration) (
+	kubeconfig []byte, expiration time.Time, err error) {
+	var clientset kubernetes.Interface
+	if clientset, err = i.newClientset(config); err != nil {
+		return
+	}
+
+	if err = i.ensureServiceAccount(ctx, clientset, namespace); err != nil {
+		return
+	}
+	if err = i.ensureRoleBinding(ctx, clientset, namespace); err != nil {
+		return
+	}
+
+	expirationSeconds := int64(ttl.Seconds())
+	var token *authenticationv1.TokenRequest
+	if token, err = clientset.CoreV1().ServiceAccounts(namespace).CreateToken(ctx, DeployerName,
+		&authenticationv1.TokenRequest{
+			Spec: authenticationv1.TokenRequestSpec{
+				ExpirationSeconds: &expirationSeconds,
+			},
+		}, metav1.CreateOptions{}); err != nil {
+		return
+	}
+
+	expiration = token.Status.ExpirationTimestamp.Time
+	kubeconfig, err = buildKubeConfig(config, namespace, token.Status.Token)
+	return
+}
+
+func (i *serviceAccountIssuer) ensureServiceAccount(ctx context.Context, clientset kubernetes.Interface, namespace string) (err error) {
+	if _, err = clientset.CoreV1().ServiceAccounts(namespace).Get(ctx, DeployerName, metav1.GetOptions{}); apierrors.IsNotFound(err) {
+		_, err = clientset.CoreV1().ServiceAccounts(namespace).Create(ctx, &corev1.ServiceAccount{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      DeployerName,
+				Namespace: namespace,
+				Labels:    map[string]string{"app.kubernetes.io/managed-by": "ks-devops"},
+			},
+		}, metav1.CreateOptions{})
+	}
+	return
+}
+
+func (i *serviceAccountIssuer) ensureRoleBinding(ctx context.Context, clientset kubernetes.Interface, namespace string) (err error) {
+	if _, err = clientset.RbacV1().RoleBindings(namespace).Get(ctx, DeployerName, metav1.GetOptions{}); apierrors.IsNotFound(err) {
+		_, err = clientset.RbacV1().RoleBindings(namespace).Create(ctx, &rbacv1.RoleBinding{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      DeployerName,
+				Namespace: namespace,
+				Labels:    map[string]string{"app.kubernetes.io/managed-by": "ks-devops"},
+			},
+			RoleRef: rbacv1.RoleRef{
+				APIGroup: rbacv1.GroupName,
+				Kind:     "ClusterRole",
+				Name:     i.clusterRole,
+			},
+			Subjects: []rbacv1.Subject{{
+				Kind:      rbacv1.ServiceAccountKind,
+				Name:      DeployerName,
+				Namespace: namespace,
+			}},
+		}, metav1.CreateOptions{})
+	}
+	return
+}
+
+// buildKubeConfig builds a kubeconfig which only contains the server information of the config and the token
+func buildKubeConfig(config *rest.Config, namespace, token string) (data []byte, err error) {
+	caData := config.CAData
+	if len(caData) == 0 && config.CAFile != "" {
+		// the in-cluster config only has the CA file
+		if caData, err = os.ReadFile(config.CAFile); err != nil {
+			return
+		}
+	}
+
+	const name = "deploy-target"
+	kubeconfig := clientcmdapi.NewConfig()
+	kubeconfig.Clusters[name] = &clientcmdapi.Cluster{
+		Server:                   config.Host,
+		CertificateAuthorityData: caData,
+		InsecureSkipTLSVerify:    config.Insecure,
+	}
+	kubeconfig.AuthInfos[name] = &clientcmdapi.AuthInfo{
+		Token: token,
+	}
+	kubeconfig.Contexts[name] = &clientcmdapi.Context{
+		Cluster:   name,
+		AuthInfo:  name,
+		Namespace: namespace,
+	}
+	kubeconfig.CurrentContext = name
+	data, err = clientcmd.Write(*kubeconfig)
+	return
+}
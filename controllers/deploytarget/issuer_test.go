@@ -0,0 +1,81 @@
+/*
+Copyright 2022 The KubeSphere Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package deploytarget
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/rest"
+	k8stesting "k8s.io/client-go/testing"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+func TestServiceAccountIssuer_Issue(t *testing.T) {
+	expiration := time.Date(2022, 8, 1, 0, 0, 0, 0, time.UTC)
+	clientset := fake.NewSimpleClientset()
+	var requestedSeconds int64
+	clientset.PrependReactor("create", "serviceaccounts", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		if action.GetSubresource() != "token" {
+			return false, nil, nil
+		}
+		request := action.(k8stesting.CreateAction).GetObject().(*authenticationv1.TokenRequest)
+		requestedSeconds = *request.Spec.ExpirationSeconds
+		request.Status = authenticationv1.TokenRequestStatus{
+			Token:               "fake-token",
+			ExpirationTimestamp: metav1.NewTime(expiration),
+		}
+		return true, request, nil
+	})
+
+	issuer := NewServiceAccountIssuer("").(*serviceAccountIssuer)
+	assert.Equal(t, DefaultClusterRole, issuer.clusterRole)
+	issuer.newClientset = func(config *rest.Config) (kubernetes.Interface, error) {
+		return clientset, nil
+	}
+
+	config := &rest.Config{Host: "https://member:6443", TLSClientConfig: rest.TLSClientConfig{CAData: []byte("ca")}}
+	data, gotExpiration, err := issuer.Issue(context.Background(), config, "demo", time.Hour)
+	assert.Nil(t, err)
+	assert.Equal(t, expiration, gotExpiration)
+	assert.Equal(t, int64(3600), requestedSeconds)
+
+	kubeconfig, err := clientcmd.Load(data)
+	assert.Nil(t, err)
+	assert.Equal(t, "https://member:6443", kubeconfig.Clusters[kubeconfig.CurrentContext].Server)
+	assert.Equal(t, []byte("ca"), kubeconfig.Clusters[kubeconfig.CurrentContext].CertificateAuthorityData)
+	assert.Equal(t, "fake-token", kubeconfig.AuthInfos[kubeconfig.CurrentContext].Token)
+	assert.Equal(t, "demo", kubeconfig.Contexts[kubeconfig.CurrentContext].Namespace)
+
+	// the ServiceAccount and RoleBinding should be created
+	_, err = clientset.CoreV1().ServiceAccounts("demo").Get(context.Background(), DeployerName, metav1.GetOptions{})
+	assert.Nil(t, err)
+	binding, err := clientset.RbacV1().RoleBindings("demo").Get(context.Background(), DeployerName, metav1.GetOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, DefaultClusterRole, binding.RoleRef.Name)
+
+	// issue again with the existing ServiceAccount and RoleBinding
+	_, _, err = issuer.Issue(context.Background(), config, "demo", time.Hour)
+	assert.Nil(t, err)
+}
@@ -43,4 +43,7 @@ const (
 
 	// FluxAppLastRevision is the revision of the last successfully applied source.
 	FluxAppLastRevision = "gitops.kubesphere.io/last-revision"
+
+	// FluxImageUpdaterLabelKey indicates which ImageUpdater the FluxCD image automation object belongs to
+	FluxImageUpdaterLabelKey = "gitops.kubesphere.io/image-updater"
 )
@@ -0,0 +1,334 @@
+/*
+Copyright 2022 The KubeSphere Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package fluxcd
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/kubesphere/ks-devops/pkg/api/devops/v1alpha3"
+	"github.com/kubesphere/ks-devops/pkg/api/gitops/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+//+kubebuilder:rbac:groups=gitops.kubesphere.io,resources=imageupdaters,verbs=get;list;watch
+//+kubebuilder:rbac:groups=devops.kubesphere.io,resources=gitrepositories,verbs=get;list;watch
+//+kubebuilder:rbac:groups="image.toolkit.fluxcd.io",resources=imagerepositories;imagepolicies;imageupdateautomations,verbs=get;list;create;update;delete
+//+kubebuilder:rbac:groups="",resources=events,verbs=create;patch
+
+// ImageUpdaterReconciler is the reconciler of the ImageUpdater which kind is fluxcd
+type ImageUpdaterReconciler struct {
+	client.Client
+	log      logr.Logger
+	recorder record.EventRecorder
+}
+
+// Reconcile makes sure the FluxCD image automation objects are consistent with the ImageUpdater
+func (r *ImageUpdaterReconciler) Reconcile(ctx context.Context, req ctrl.Request) (result ctrl.Result, err error) {
+	r.log.Info(fmt.Sprintf("start to reconcile imageUpdater: %s", req.String()))
+
+	updater := &v1alpha1.ImageUpdater{}
+	if err = r.Get(ctx, req.NamespacedName, updater); err != nil {
+		err = client.IgnoreNotFound(err)
+		return
+	}
+
+	// skip if kind is not fluxcd
+	if updater.Spec.Kind != string(v1alpha1.FluxCD) {
+		r.log.V(7).Info(fmt.Sprintf("skip %s due to the spec.kind value is not fluxcd", req.String()))
+		return
+	}
+
+	flux := updater.Spec.Flux
+	if flux == nil {
+		r.log.V(7).Info(fmt.Sprintf("skip %s due to the Flux is nil", req.String()))
+		return
+	}
+
+	if flux.Repo.Name == "" {
+		r.recorder.Eventf(updater, corev1.EventTypeWarning, "Missing", "git repository name is required")
+		return
+	}
+
+	repo := &v1alpha3.GitRepository{}
+	if err = r.Get(ctx, types.NamespacedName{
+		Namespace: req.Namespace,
+		Name:      flux.Repo.Name,
+	}, repo); err != nil {
+		err = nil
+		result = ctrl.Result{RequeueAfter: time.Minute}
+		return
+	}
+	if !isArtifactRepo(repo) {
+		r.recorder.Eventf(updater, corev1.EventTypeWarning, "InvalidRepository",
+			"git repository %s is not an artifact repository", repo.GetName())
+		result = ctrl.Result{RequeueAfter: time.Minute}
+		return
+	}
+
+	var images []v1alpha1.ImageItem
+	if images, err = v1alpha1.ParseImages(updater.Spec.Images); err != nil {
+		r.recorder.Eventf(updater, corev1.EventTypeWarning, "InvalidImage", err.Error())
+		err = nil
+		return
+	}
+
+	expected := make(map[string]bool, len(images))
+	for _, image := range images {
+		var policy map[string]interface{}
+		if policy, err = getFluxImagePolicy(flux.UpdateStrategy[image.Alias], image.Constraint); err != nil {
+			r.recorder.Eventf(updater, corev1.EventTypeWarning, "InvalidUpdateStrategy",
+				"skip image %s, error is: %v", image.Alias, err)
+			err = nil
+			continue
+		}
+		// the same as Argo CD Image Updater, allowTags is a regular expression
+		if allowTags := flux.AllowTags[image.Alias]; allowTags != "" {
+			if _, err = regexp.Compile(allowTags); err != nil {
+				r.recorder.Eventf(updater, corev1.EventTypeWarning, "InvalidAllowTags",
+					"skip image %s, error is: %v", image.Alias, err)
+				err = nil
+				continue
+			}
+		}
+
+		imageRepo := createFluxImageRepository(updater, image)
+		if err = r.createOrUpdate(ctx, imageRepo); err != nil {
+			return
+		}
+		if err = r.createOrUpdate(ctx, createFluxImagePolicy(updater, image, policy)); err != nil {
+			return
+		}
+		expected[imageRepo.GetName()] = true
+	}
+
+	if err = r.cleanUnexpectedObjects(ctx, updater, expected); err != nil {
+		return
+	}
+	err = r.createOrUpdate(ctx, createFluxImageUpdateAutomation(updater, getFluxRepoName(repo.GetName())))
+	return
+}
+
+// createOrUpdate creates the object if it does not exist, or update the spec of it
+func (r *ImageUpdaterReconciler) createOrUpdate(ctx context.Context, obj *unstructured.Unstructured) (err error) {
+	existing := &unstructured.Unstructured{}
+	existing.SetGroupVersionKind(obj.GroupVersionKind())
+	if err = r.Get(ctx, types.NamespacedName{
+		Namespace: obj.GetNamespace(),
+		Name:      obj.GetName(),
+	}, existing); err != nil {
+		if !apierrors.IsNotFound(err) {
+			return
+		}
+		r.log.Info(fmt.Sprintf("create FluxCD %s", obj.GetKind()), "name", obj.GetName())
+		err = r.Create(ctx, obj)
+		return
+	}
+
+	existing.Object["spec"] = obj.Object["spec"]
+	existing.SetLabels(obj.GetLabels())
+	existing.SetOwnerReferences(obj.GetOwnerReferences())
+	r.log.Info(fmt.Sprintf("update FluxCD %s", obj.GetKind()), "name", obj.GetName())
+	err = r.Update(ctx, existing)
+	return
+}
+
+// cleanUnexpectedObjects removes the ImageRepositories and ImagePolicies which were removed from the ImageUpdater
+func (r *ImageUpdaterReconciler) cleanUnexpectedObjects(ctx context.Context, updater *v1alpha1.ImageUpdater, expected map[string]bool) (err error) {
+	for _, kind := range []string{"ImageRepository", "ImagePolicy"} {
+		list := &unstructured.UnstructuredList{}
+		list.SetGroupVersionKind(fluxImageGroupVersion.WithKind(kind + "List"))
+		if err = r.List(ctx, list, client.InNamespace(updater.GetNamespace()), client.MatchingLabels{
+			FluxImageUpdaterLabelKey: updater.GetName(),
+		}); err != nil {
+			return
+		}
+
+		for i := range list.Items {
+			item := &list.Items[i]
+			if expected[item.GetName()] {
+				continue
+			}
+			r.log.Info(fmt.Sprintf("delete FluxCD %s", kind), "name", item.GetName())
+			if err = r.Delete(ctx, item); err != nil {
+				if err = client.IgnoreNotFound(err); err != nil {
+					return
+				}
+			}
+		}
+	}
+	return
+}
+
+var fluxImageGroupVersion = schema.GroupVersion{
+	Group:   "image.toolkit.fluxcd.io",
+	Version: "v1beta1",
+}
+
+var invalidNameChars = regexp.MustCompile(`[^a-z0-9-]+`)
+
+// getFluxImagePolicy converts the update strategy of Argo CD Image Updater to the policy of FluxCD ImagePolicy
+func getFluxImagePolicy(strategy, constraint string) (policy map[string]interface{}, err error) {
+	switch strategy {
+	case "", "semver":
+		if constraint == "" {
+			constraint = ">=0.0.0"
+		}
+		policy = map[string]interface{}{
+			"semver": map[string]interface{}{"range": constraint},
+		}
+	case "name", "alphabetical":
+		policy = map[string]interface{}{
+			"alphabetical": map[string]interface{}{"order": "asc"},
+		}
+	case "numerical":
+		policy = map[string]interface{}{
+			"numerical": map[string]interface{}{"order": "asc"},
+		}
+	default:
+		err = fmt.Errorf("update strategy %q is not supported by FluxCD", strategy)
+	}
+	return
+}
+
+func getFluxImageObjectName(updater *v1alpha1.ImageUpdater, image v1alpha1.ImageItem) string {
+	name := invalidNameChars.ReplaceAllString(strings.ToLower(image.Alias), "-")
+	return fmt.Sprintf("%s-%s", updater.GetName(), strings.Trim(name, "-"))
+}
+
+func createBareFluxImageObject(updater *v1alpha1.ImageUpdater, kind, name string) *unstructured.Unstructured {
+	obj := &unstructured.Unstructured{}
+	obj.SetGroupVersionKind(fluxImageGroupVersion.WithKind(kind))
+	obj.SetNamespace(updater.GetNamespace())
+	obj.SetName(name)
+	obj.SetLabels(map[string]string{
+		"app.kubernetes.io/managed-by": v1alpha1.GroupName,
+		FluxImageUpdaterLabelKey:       updater.GetName(),
+	})
+	obj.SetOwnerReferences([]metav1.OwnerReference{{
+		APIVersion: v1alpha1.GroupVersion.String(),
+		Kind:       "ImageUpdater",
+		Name:       updater.GetName(),
+		UID:        updater.GetUID(),
+	}})
+	return obj
+}
+
+func createFluxImageRepository(updater *v1alpha1.ImageUpdater, image v1alpha1.ImageItem) *unstructured.Unstructured {
+	flux := updater.Spec.Flux
+	imageRepo := createBareFluxImageObject(updater, "ImageRepository", getFluxImageObjectName(updater, image))
+
+	_ = unstructured.SetNestedField(imageRepo.Object, image.Image, "spec", "image")
+	_ = unstructured.SetNestedField(imageRepo.Object, getFluxInterval(flux), "spec", "interval")
+	if secret := flux.Secrets[image.Alias]; secret != "" {
+		// the secret must be in the same namespace, ignore the namespace part if it exists
+		_ = unstructured.SetNestedField(imageRepo.Object, secret[strings.LastIndex(secret, "/")+1:], "spec", "secretRef", "name")
+	}
+	if ignoreTags := flux.IgnoreTags[image.Alias]; ignoreTags != "" {
+		var exclusionList []interface{}
+		for _, pattern := range v1alpha1.ParseIgnoreTags(ignoreTags) {
+			exclusionList = append(exclusionList, pattern)
+		}
+		_ = unstructured.SetNestedSlice(imageRepo.Object, exclusionList, "spec", "exclusionList")
+	}
+	return imageRepo
+}
+
+func createFluxImagePolicy(updater *v1alpha1.ImageUpdater, image v1alpha1.ImageItem, policy map[string]interface{}) *unstructured.Unstructured {
+	name := getFluxImageObjectName(updater, image)
+	imagePolicy := createBareFluxImageObject(updater, "ImagePolicy", name)
+
+	_ = unstructured.SetNestedField(imagePolicy.Object, name, "spec", "imageRepositoryRef", "name")
+	_ = unstructured.SetNestedMap(imagePolicy.Object, policy, "spec", "policy")
+	if allowTags := updater.Spec.Flux.AllowTags[image.Alias]; allowTags != "" {
+		_ = unstructured.SetNestedField(imagePolicy.Object, allowTags, "spec", "filterTags", "pattern")
+	}
+	return imagePolicy
+}
+
+func createFluxImageUpdateAutomation(updater *v1alpha1.ImageUpdater, fluxRepoName string) *unstructured.Unstructured {
+	flux := updater.Spec.Flux
+	automation := createBareFluxImageObject(updater, "ImageUpdateAutomation", updater.GetName())
+
+	branch := flux.Branch
+	if branch == "" {
+		branch = "main"
+	}
+	pushBranch := flux.PushBranch
+	if pushBranch == "" {
+		pushBranch = branch
+	}
+	path := flux.Path
+	if path == "" {
+		path = "./"
+	}
+	authorName, authorEmail := flux.Author.Name, flux.Author.Email
+	if authorName == "" {
+		authorName = "fluxcdbot"
+	}
+	if authorEmail == "" {
+		authorEmail = "fluxcdbot@users.noreply.github.com"
+	}
+
+	_ = unstructured.SetNestedField(automation.Object, getFluxInterval(flux), "spec", "interval")
+	_ = unstructured.SetNestedField(automation.Object, "GitRepository", "spec", "sourceRef", "kind")
+	_ = unstructured.SetNestedField(automation.Object, fluxRepoName, "spec", "sourceRef", "name")
+	_ = unstructured.SetNestedField(automation.Object, branch, "spec", "git", "checkout", "ref", "branch")
+	_ = unstructured.SetNestedField(automation.Object, authorName, "spec", "git", "commit", "author", "name")
+	_ = unstructured.SetNestedField(automation.Object, authorEmail, "spec", "git", "commit", "author", "email")
+	if flux.MessageTemplate != "" {
+		_ = unstructured.SetNestedField(automation.Object, flux.MessageTemplate, "spec", "git", "commit", "messageTemplate")
+	}
+	_ = unstructured.SetNestedField(automation.Object, pushBranch, "spec", "git", "push", "branch")
+	_ = unstructured.SetNestedField(automation.Object, path, "spec", "update", "path")
+	_ = unstructured.SetNestedField(automation.Object, "Setters", "spec", "update", "strategy")
+	return automation
+}
+
+func getFluxInterval(flux *v1alpha1.FluxImageUpdater) string {
+	if flux.Interval == "" {
+		return "1m"
+	}
+	return flux.Interval
+}
+
+// GetName returns the name of this controller
+func (r *ImageUpdaterReconciler) GetName() string {
+	return "FluxImageUpdaterReconciler"
+}
+
+// SetupWithManager setups the log and recorder
+func (r *ImageUpdaterReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	r.log = ctrl.Log.WithName(r.GetName())
+	r.recorder = mgr.GetEventRecorderFor(r.GetName())
+	return ctrl.NewControllerManagedBy(mgr).
+		Named("fluxcd_image_updater_controller").
+		For(&v1alpha1.ImageUpdater{}).
+		Complete(r)
+}
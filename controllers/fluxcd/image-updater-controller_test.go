@@ -0,0 +1,263 @@
+/*
+Copyright 2022 The KubeSphere Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package fluxcd
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/kubesphere/ks-devops/controllers/core"
+	"github.com/kubesphere/ks-devops/pkg/api/devops/v1alpha3"
+	"github.com/kubesphere/ks-devops/pkg/api/gitops/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+func Test_getFluxImagePolicy(t *testing.T) {
+	tests := []struct {
+		name       string
+		strategy   string
+		constraint string
+		want       map[string]interface{}
+		wantErr    bool
+	}{{
+		name: "default strategy without constraint",
+		want: map[string]interface{}{
+			"semver": map[string]interface{}{"range": ">=0.0.0"},
+		},
+	}, {
+		name:       "semver with constraint",
+		strategy:   "semver",
+		constraint: "5.0.x",
+		want: map[string]interface{}{
+			"semver": map[string]interface{}{"range": "5.0.x"},
+		},
+	}, {
+		name:     "name",
+		strategy: "name",
+		want: map[string]interface{}{
+			"alphabetical": map[string]interface{}{"order": "asc"},
+		},
+	}, {
+		name:     "numerical",
+		strategy: "numerical",
+		want: map[string]interface{}{
+			"numerical": map[string]interface{}{"order": "asc"},
+		},
+	}, {
+		name:     "digest is not supported",
+		strategy: "digest",
+		wantErr:  true,
+	}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := getFluxImagePolicy(tt.strategy, tt.constraint)
+			assert.Equal(t, tt.wantErr, err != nil)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestImageUpdaterReconciler_Reconcile(t *testing.T) {
+	schema, err := v1alpha1.SchemeBuilder.Register().Build()
+	assert.Nil(t, err)
+	err = v1alpha3.AddToScheme(schema)
+	assert.Nil(t, err)
+	for _, kind := range []string{"ImageRepository", "ImagePolicy", "ImageUpdateAutomation"} {
+		schema.AddKnownTypeWithName(fluxImageGroupVersion.WithKind(kind), &unstructured.Unstructured{})
+		schema.AddKnownTypeWithName(fluxImageGroupVersion.WithKind(kind+"List"), &unstructured.UnstructuredList{})
+	}
+
+	repo := &v1alpha3.GitRepository{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "repo",
+			Namespace: "fake",
+			Labels: map[string]string{
+				v1alpha1.ArtifactRepoLabelKey: "true",
+			},
+		},
+	}
+	nonArtifactRepo := repo.DeepCopy()
+	nonArtifactRepo.Labels = nil
+
+	updater := &v1alpha1.ImageUpdater{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "updater",
+			Namespace: "fake",
+		},
+		Spec: v1alpha1.ImageUpdaterSpec{
+			Kind:   "fluxcd",
+			Images: []string{"nginx:1.x", "tomcat"},
+			Flux: &v1alpha1.FluxImageUpdater{
+				Repo:   v1.LocalObjectReference{Name: "repo"},
+				Branch: "master",
+				UpdateStrategy: map[string]string{
+					"tomcat": "digest",
+				},
+				AllowTags: map[string]string{
+					"nginx": "^1",
+				},
+				IgnoreTags: map[string]string{
+					"nginx": "latest, *-rc",
+				},
+				Secrets: map[string]string{
+					"nginx": "fake/docker-hub",
+				},
+			},
+		},
+	}
+	argoUpdater := updater.DeepCopy()
+	argoUpdater.Spec.Kind = "argocd"
+	noFluxUpdater := updater.DeepCopy()
+	noFluxUpdater.Spec.Flux = nil
+	emptyRepoUpdater := updater.DeepCopy()
+	emptyRepoUpdater.Spec.Flux.Repo.Name = ""
+	invalidAllowTagsUpdater := updater.DeepCopy()
+	invalidAllowTagsUpdater.Spec.Flux.AllowTags["nginx"] = "[1"
+
+	staleImagePolicy := createBareFluxImageObject(updater, "ImagePolicy", "updater-alpine")
+
+	defaultReq := ctrl.Request{
+		NamespacedName: types.NamespacedName{
+			Namespace: "fake",
+			Name:      "updater",
+		},
+	}
+
+	getObject := func(c client.Client, kind, name string) (*unstructured.Unstructured, error) {
+		obj := &unstructured.Unstructured{}
+		obj.SetGroupVersionKind(fluxImageGroupVersion.WithKind(kind))
+		err := c.Get(context.Background(), types.NamespacedName{Namespace: "fake", Name: name}, obj)
+		return obj, err
+	}
+
+	tests := []struct {
+		name       string
+		objects    []runtime.Object
+		wantResult ctrl.Result
+		verify     func(*testing.T, client.Client)
+	}{{
+		name:    "not found",
+		objects: []runtime.Object{},
+	}, {
+		name:    "kind is not fluxcd",
+		objects: []runtime.Object{argoUpdater, repo.DeepCopy()},
+		verify: func(t *testing.T, c client.Client) {
+			_, err := getObject(c, "ImageUpdateAutomation", "updater")
+			assert.True(t, apierrors.IsNotFound(err))
+		},
+	}, {
+		name:    "flux setting is nil",
+		objects: []runtime.Object{noFluxUpdater, repo.DeepCopy()},
+	}, {
+		name:    "repo name is empty",
+		objects: []runtime.Object{emptyRepoUpdater, repo.DeepCopy()},
+	}, {
+		name:       "repo not found",
+		objects:    []runtime.Object{updater.DeepCopy()},
+		wantResult: ctrl.Result{RequeueAfter: time.Minute},
+	}, {
+		name:       "repo is not an artifact repository",
+		objects:    []runtime.Object{updater.DeepCopy(), nonArtifactRepo},
+		wantResult: ctrl.Result{RequeueAfter: time.Minute},
+	}, {
+		name:    "allowTags is not a valid regular expression",
+		objects: []runtime.Object{invalidAllowTagsUpdater, repo.DeepCopy()},
+		verify: func(t *testing.T, c client.Client) {
+			_, err := getObject(c, "ImageRepository", "updater-nginx")
+			assert.True(t, apierrors.IsNotFound(err))
+			_, err = getObject(c, "ImageUpdateAutomation", "updater")
+			assert.Nil(t, err)
+		},
+	}, {
+		name:    "normal case",
+		objects: []runtime.Object{updater.DeepCopy(), repo.DeepCopy(), staleImagePolicy.DeepCopy()},
+		verify: func(t *testing.T, c client.Client) {
+			imageRepo, err := getObject(c, "ImageRepository", "updater-nginx")
+			assert.Nil(t, err)
+			assert.Equal(t, "nginx", imageRepo.Object["spec"].(map[string]interface{})["image"])
+			exclusionList, _, _ := unstructured.NestedStringSlice(imageRepo.Object, "spec", "exclusionList")
+			assert.Equal(t, []string{"^latest$", "^.*-rc$"}, exclusionList)
+			secret, _, _ := unstructured.NestedString(imageRepo.Object, "spec", "secretRef", "name")
+			assert.Equal(t, "docker-hub", secret)
+
+			imagePolicy, err := getObject(c, "ImagePolicy", "updater-nginx")
+			assert.Nil(t, err)
+			semverRange, _, _ := unstructured.NestedString(imagePolicy.Object, "spec", "policy", "semver", "range")
+			assert.Equal(t, "1.x", semverRange)
+			pattern, _, _ := unstructured.NestedString(imagePolicy.Object, "spec", "filterTags", "pattern")
+			assert.Equal(t, "^1", pattern)
+
+			// unsupported update strategy
+			_, err = getObject(c, "ImageRepository", "updater-tomcat")
+			assert.True(t, apierrors.IsNotFound(err))
+			// the stale one should be removed
+			_, err = getObject(c, "ImagePolicy", "updater-alpine")
+			assert.True(t, apierrors.IsNotFound(err))
+
+			automation, err := getObject(c, "ImageUpdateAutomation", "updater")
+			assert.Nil(t, err)
+			sourceRef, _, _ := unstructured.NestedString(automation.Object, "spec", "sourceRef", "name")
+			assert.Equal(t, "fluxcd-repo", sourceRef)
+			branch, _, _ := unstructured.NestedString(automation.Object, "spec", "git", "push", "branch")
+			assert.Equal(t, "master", branch)
+			path, _, _ := unstructured.NestedString(automation.Object, "spec", "update", "path")
+			assert.Equal(t, "./", path)
+		},
+	}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := fake.NewClientBuilder().WithScheme(schema).WithRuntimeObjects(tt.objects...).Build()
+			r := &ImageUpdaterReconciler{
+				Client:   c,
+				log:      logr.New(log.NullLogSink{}),
+				recorder: &record.FakeRecorder{},
+			}
+			result, err := r.Reconcile(context.Background(), defaultReq)
+			assert.Nil(t, err)
+			assert.Equal(t, tt.wantResult, result)
+			if tt.verify != nil {
+				tt.verify(t, c)
+			}
+		})
+	}
+}
+
+func TestImageUpdaterReconciler_SetupWithManager(t *testing.T) {
+	schema, err := v1alpha1.SchemeBuilder.Register().Build()
+	assert.Nil(t, err)
+
+	r := &ImageUpdaterReconciler{}
+	err = r.SetupWithManager(&core.FakeManager{
+		Client: fake.NewClientBuilder().WithScheme(schema).Build(),
+		Scheme: schema,
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, "FluxImageUpdaterReconciler", r.GetName())
+}
@@ -16,3 +16,16 @@ package gitrepository
 const (
 	groupName = "gitrepository"
 )
+
+const (
+	// EventReasonWebhookDeleted indicates the webhook was deleted on the git provider
+	EventReasonWebhookDeleted = "WebhookDeleted"
+	// EventReasonConnectionFailed indicates the git repository is not accessible
+	EventReasonConnectionFailed = "ConnectionFailed"
+	// EventReasonSettingsDrift indicates the settings on the git provider are different from the desired ones
+	EventReasonSettingsDrift = "SettingsDrift"
+	// EventReasonSettingsApplied indicates the desired settings were applied to the git provider
+	EventReasonSettingsApplied = "SettingsApplied"
+	// EventReasonSettingsUnsupported indicates the git provider does not support managing the settings
+	EventReasonSettingsUnsupported = "Unsupported"
+)
@@ -16,6 +16,7 @@ package gitrepository
 import (
 	"context"
 	"fmt"
+	"net/url"
 	"strings"
 
 	"github.com/go-logr/logr"
@@ -63,6 +64,8 @@ func init() {
 		&gitlabPublicAmend{},
 		&githubPublicAmend{},
 		&bitbucketPublicAmend{},
+		&bitbucketCloudAmend{},
+		&giteaAmend{},
 	}
 }
 
@@ -126,6 +129,45 @@ func (a *bitbucketPublicAmend) Amend(repo *v1alpha3.GitRepository) (changed bool
 	return
 }
 
+type bitbucketCloudAmend struct {
+}
+
+func (a *bitbucketCloudAmend) Match(repo *v1alpha3.GitRepository) bool {
+	return strings.ToLower(repo.Spec.Provider) == v1alpha3.SourceTypeBitbucketCloud
+}
+
+func (a *bitbucketCloudAmend) Amend(repo *v1alpha3.GitRepository) (changed bool) {
+	if repo.Spec.URL == "" {
+		repo.Spec.URL = fmt.Sprintf("https://bitbucket.org/%s/%s",
+			repo.Spec.Owner, repo.Spec.Repo)
+		changed = true
+	}
+	return
+}
+
+// giteaAmend completes the URL or server, because there is no public Gitea server as the default one
+type giteaAmend struct {
+}
+
+func (a *giteaAmend) Match(repo *v1alpha3.GitRepository) bool {
+	return strings.ToLower(repo.Spec.Provider) == v1alpha3.SourceTypeGitea
+}
+
+func (a *giteaAmend) Amend(repo *v1alpha3.GitRepository) (changed bool) {
+	if repo.Spec.URL == "" && repo.Spec.Server != "" {
+		repo.Spec.URL = fmt.Sprintf("%s/%s/%s", strings.TrimSuffix(repo.Spec.Server, "/"),
+			repo.Spec.Owner, repo.Spec.Repo)
+		changed = true
+	}
+	if repo.Spec.Server == "" && repo.Spec.URL != "" {
+		if gitURL, err := url.Parse(repo.Spec.URL); err == nil && gitURL.Host != "" {
+			repo.Spec.Server = fmt.Sprintf("%s://%s", gitURL.Scheme, gitURL.Host)
+			changed = true
+		}
+	}
+	return
+}
+
 func (r *AmendReconciler) GetName() string {
 	return "git-repository-amend"
 }
@@ -95,6 +95,65 @@ func Test_amendGitlabURL(t *testing.T) {
 	}
 }
 
+func Test_amendGiteaAndBitbucketCloud(t *testing.T) {
+	tests := []struct {
+		name        string
+		spec        v1alpha3.GitRepositorySpec
+		wantChanged bool
+		wantSpec    v1alpha3.GitRepositorySpec
+	}{{
+		name: "gitea, have server, owner and repo, but without URL",
+		spec: v1alpha3.GitRepositorySpec{
+			Provider: "gitea", Server: "https://gitea.example.com/", Owner: "devops", Repo: "test",
+		},
+		wantChanged: true,
+		wantSpec: v1alpha3.GitRepositorySpec{
+			Provider: "gitea", Server: "https://gitea.example.com/", Owner: "devops", Repo: "test",
+			URL: "https://gitea.example.com/devops/test",
+		},
+	}, {
+		name: "gitea, have URL, but without server",
+		spec: v1alpha3.GitRepositorySpec{
+			Provider: "Gitea", URL: "https://gitea.example.com/devops/test.git",
+		},
+		wantChanged: true,
+		wantSpec: v1alpha3.GitRepositorySpec{
+			Provider: "Gitea", URL: "https://gitea.example.com/devops/test.git", Server: "https://gitea.example.com",
+		},
+	}, {
+		name: "gitea, nothing to amend",
+		spec: v1alpha3.GitRepositorySpec{
+			Provider: "gitea", Owner: "devops", Repo: "test",
+		},
+		wantSpec: v1alpha3.GitRepositorySpec{
+			Provider: "gitea", Owner: "devops", Repo: "test",
+		},
+	}, {
+		name: "bitbucket cloud, without URL",
+		spec: v1alpha3.GitRepositorySpec{
+			Provider: "bitbucket_cloud", Owner: "devops", Repo: "test",
+		},
+		wantChanged: true,
+		wantSpec: v1alpha3.GitRepositorySpec{
+			Provider: "bitbucket_cloud", Owner: "devops", Repo: "test", URL: "https://bitbucket.org/devops/test",
+		},
+	}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &v1alpha3.GitRepository{Spec: tt.spec}
+			changed := false
+			for _, amend := range gitProviderAmends {
+				if amend.Match(repo) {
+					changed = amend.Amend(repo)
+					break
+				}
+			}
+			assert.Equal(t, tt.wantChanged, changed)
+			assert.Equal(t, tt.wantSpec, repo.Spec)
+		})
+	}
+}
+
 func TestAmendReconciler_SetupWithManager(t *testing.T) {
 	schema, err := v1alpha3.SchemeBuilder.Register().Build()
 	assert.Nil(t, err)
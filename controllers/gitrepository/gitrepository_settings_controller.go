@@ -0,0 +1,330 @@
+/*
+Copyright 2022 The KubeSphere Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gitrepository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/jenkins-x/go-scm/scm"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+
+	"github.com/kubesphere/ks-devops/pkg/api/devops/v1alpha3"
+)
+
+// SettingsReconciler applies the desired settings, such as the branch protections and labels, to the git repositories.
+// It checks the settings periodically in case they were changed on the git provider.
+type SettingsReconciler struct {
+	client.Client
+	// Interval is the period of checking the settings, the default value is 10 minutes
+	Interval time.Duration
+	log      logr.Logger
+	recorder record.EventRecorder
+}
+
+//+kubebuilder:rbac:groups=devops.kubesphere.io,resources=gitrepositories,verbs=get;list;watch
+//+kubebuilder:rbac:groups=devops.kubesphere.io,resources=gitrepositories/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups="",resources=secrets,verbs=get
+//+kubebuilder:rbac:groups="",resources=events,verbs=create;patch
+
+// Reconcile compares the desired settings with the actual ones, then applies or reports the drift
+func (r *SettingsReconciler) Reconcile(ctx context.Context, req ctrl.Request) (result ctrl.Result, err error) {
+	log := r.log.WithValues("GitRepository", req.NamespacedName)
+
+	repo := &v1alpha3.GitRepository{}
+	if err = r.Get(ctx, req.NamespacedName, repo); err != nil {
+		err = client.IgnoreNotFound(err)
+		return
+	}
+	if !repo.DeletionTimestamp.IsZero() {
+		return
+	}
+
+	status := repo.Status.DeepCopy()
+	if repo.Spec.Settings == nil {
+		status.SettingsDrift = nil
+		meta.RemoveStatusCondition(&status.Conditions, v1alpha3.GitRepositoryConditionSettingsSynced)
+	} else if r.syncSettings(ctx, repo, status) {
+		result = ctrl.Result{RequeueAfter: r.getInterval()}
+	}
+
+	if !equality.Semantic.DeepEqual(status, &repo.Status) {
+		repo.Status = *status
+		if err = r.Status().Update(ctx, repo); err != nil {
+			log.Error(err, "failed to update the status")
+		}
+	}
+	return
+}
+
+func (r *SettingsReconciler) getInterval() time.Duration {
+	if r.Interval > 0 {
+		return r.Interval
+	}
+	return defaultStatusCheckInterval
+}
+
+// syncSettings finds the drift of the settings, then applies the desired settings if the policy is Apply.
+// There is no need to check the settings again if the git provider does not support managing them.
+func (r *SettingsReconciler) syncSettings(ctx context.Context, repo *v1alpha3.GitRepository,
+	status *v1alpha3.GitRepositoryStatus) (requeue bool) {
+	settings := repo.Spec.Settings
+	changes, err := r.getSettingsChanges(ctx, repo)
+	if errors.Is(err, errSettingsUnsupported) {
+		status.SettingsDrift = nil
+		if !isConditionReason(status, v1alpha3.GitRepositoryConditionSettingsSynced, EventReasonSettingsUnsupported) {
+			r.recorder.Event(repo, v1.EventTypeWarning, EventReasonSettingsUnsupported, err.Error())
+		}
+		setGitRepositoryCondition(repo, status, v1alpha3.GitRepositoryConditionSettingsSynced, metav1.ConditionFalse,
+			EventReasonSettingsUnsupported, err.Error())
+		return
+	}
+
+	requeue = true
+	if err != nil {
+		status.SettingsDrift = nil
+		setGitRepositoryCondition(repo, status, v1alpha3.GitRepositoryConditionSettingsSynced, metav1.ConditionUnknown,
+			"CheckFailed", err.Error())
+		return
+	}
+
+	if settings.GetPolicy() == v1alpha3.GitRepositorySettingsPolicyReport {
+		status.SettingsDrift = getSettingsDrift(changes)
+		if len(changes) == 0 {
+			setGitRepositoryCondition(repo, status, v1alpha3.GitRepositoryConditionSettingsSynced, metav1.ConditionTrue,
+				v1alpha3.GitRepositoryConditionSettingsSynced, "")
+		} else {
+			setGitRepositoryCondition(repo, status, v1alpha3.GitRepositoryConditionSettingsSynced, metav1.ConditionFalse,
+				EventReasonSettingsDrift, strings.Join(status.SettingsDrift, "; "))
+		}
+		return
+	}
+
+	var failed []settingsChange
+	var errs []string
+	for _, change := range changes {
+		if applyErr := change.apply(ctx); applyErr != nil {
+			failed = append(failed, change)
+			errs = append(errs, fmt.Sprintf("%s: %v", change.drift, applyErr))
+		}
+	}
+	status.SettingsDrift = getSettingsDrift(failed)
+	if len(changes) > len(failed) {
+		r.recorder.Event(repo, v1.EventTypeNormal, EventReasonSettingsApplied,
+			fmt.Sprintf("corrected the settings drift: %s", strings.Join(getSettingsDrift(changes), "; ")))
+	}
+	if len(errs) == 0 {
+		setGitRepositoryCondition(repo, status, v1alpha3.GitRepositoryConditionSettingsSynced, metav1.ConditionTrue,
+			v1alpha3.GitRepositoryConditionSettingsSynced, "")
+	} else {
+		message := strings.Join(errs, "; ")
+		setGitRepositoryCondition(repo, status, v1alpha3.GitRepositoryConditionSettingsSynced, metav1.ConditionFalse,
+			"ApplyFailed", message)
+		r.recorder.Event(repo, v1.EventTypeWarning, "ApplyFailed", message)
+	}
+	return
+}
+
+// settingsChange is a drift of the settings, and the way to correct it
+type settingsChange struct {
+	drift string
+	apply func(ctx context.Context) error
+}
+
+func getSettingsDrift(changes []settingsChange) (drift []string) {
+	for _, change := range changes {
+		drift = append(drift, change.drift)
+	}
+	return
+}
+
+// getSettingsChanges compares the desired settings with the actual ones on the git provider
+func (r *SettingsReconciler) getSettingsChanges(ctx context.Context, repo *v1alpha3.GitRepository) (changes []settingsChange, err error) {
+	repoPath := repo.GetRepoPath()
+	if repoPath == "" {
+		err = fmt.Errorf("cannot find the owner and name of the repository")
+		return
+	}
+
+	var gitClient *scm.Client
+	if gitClient, err = newGitClient(r.Client, repo); err != nil {
+		return
+	}
+	newManager, ok := settingsManagers[gitClient.Driver]
+	if !ok {
+		err = fmt.Errorf("%w: %s", errSettingsUnsupported, repo.Spec.Provider)
+		return
+	}
+	changes, err = diffSettings(ctx, newManager(gitClient), repoPath, repo.Spec.Settings)
+	return
+}
+
+// errSettingsUnsupported indicates the git provider has no settingsManager
+var errSettingsUnsupported = errors.New("managing the settings is not supported by git provider")
+
+func isConditionReason(status *v1alpha3.GitRepositoryStatus, conditionType, reason string) bool {
+	condition := meta.FindStatusCondition(status.Conditions, conditionType)
+	return condition != nil && condition.Reason == reason
+}
+
+// settingsManager reads and writes the settings of the repositories on a git provider
+type settingsManager interface {
+	GetDefaultBranch(ctx context.Context, repo string) (string, error)
+	SetDefaultBranch(ctx context.Context, repo, branch string) error
+	ListLabels(ctx context.Context, repo string) ([]v1alpha3.GitLabel, error)
+	CreateLabel(ctx context.Context, repo string, label v1alpha3.GitLabel) error
+	UpdateLabel(ctx context.Context, repo string, label v1alpha3.GitLabel) error
+	// GetBranchProtection returns nil if the branch is not protected
+	GetBranchProtection(ctx context.Context, repo, branch string) (*v1alpha3.BranchProtection, error)
+	SetBranchProtection(ctx context.Context, repo string, protection v1alpha3.BranchProtection) error
+}
+
+// settingsManagers are the providers which support managing the settings, go-scm does not support it yet
+var settingsManagers = map[scm.Driver]func(gitClient *scm.Client) settingsManager{
+	scm.DriverGithub: newGitHubSettingsManager,
+}
+
+// diffSettings returns the changes which make the actual settings be the desired ones
+func diffSettings(ctx context.Context, manager settingsManager, repo string, settings *v1alpha3.GitRepositorySettings) (
+	changes []settingsChange, err error) {
+	if settings.DefaultBranch != "" {
+		var defaultBranch string
+		if defaultBranch, err = manager.GetDefaultBranch(ctx, repo); err != nil {
+			err = fmt.Errorf("failed to get the default branch, error: %v", err)
+			return
+		}
+		if defaultBranch != settings.DefaultBranch {
+			changes = append(changes, settingsChange{
+				drift: fmt.Sprintf("the default branch is %s, expected %s", defaultBranch, settings.DefaultBranch),
+				apply: func(ctx context.Context) error {
+					return manager.SetDefaultBranch(ctx, repo, settings.DefaultBranch)
+				},
+			})
+		}
+	}
+
+	if len(settings.Labels) > 0 {
+		var labels []v1alpha3.GitLabel
+		if labels, err = manager.ListLabels(ctx, repo); err != nil {
+			err = fmt.Errorf("failed to list the labels, error: %v", err)
+			return
+		}
+		changes = append(changes, diffLabels(manager, repo, settings.Labels, labels)...)
+	}
+
+	for i := range settings.BranchProtections {
+		desired := settings.BranchProtections[i]
+		var protection *v1alpha3.BranchProtection
+		if protection, err = manager.GetBranchProtection(ctx, repo, desired.Branch); err != nil {
+			err = fmt.Errorf("failed to get the protection of branch %s, error: %v", desired.Branch, err)
+			return
+		}
+
+		var drift string
+		if protection == nil {
+			drift = fmt.Sprintf("branch %s is not protected", desired.Branch)
+		} else if !isSameBranchProtection(desired, *protection) {
+			drift = fmt.Sprintf("the protection of branch %s is different", desired.Branch)
+		} else {
+			continue
+		}
+		changes = append(changes, settingsChange{
+			drift: drift,
+			apply: func(ctx context.Context) error {
+				return manager.SetBranchProtection(ctx, repo, desired)
+			},
+		})
+	}
+	return
+}
+
+// diffLabels returns the changes of the missing or different labels, the label names are case-insensitive
+func diffLabels(manager settingsManager, repo string, desired, actual []v1alpha3.GitLabel) (changes []settingsChange) {
+	actualLabels := map[string]v1alpha3.GitLabel{}
+	for _, label := range actual {
+		actualLabels[strings.ToLower(label.Name)] = label
+	}
+
+	for i := range desired {
+		label := desired[i]
+		existing, ok := actualLabels[strings.ToLower(label.Name)]
+		switch {
+		case !ok:
+			changes = append(changes, settingsChange{
+				drift: fmt.Sprintf("label %s is missing", label.Name),
+				apply: func(ctx context.Context) error {
+					return manager.CreateLabel(ctx, repo, label)
+				},
+			})
+		case (label.Color != "" && !strings.EqualFold(label.Color, existing.Color)) ||
+			(label.Description != "" && label.Description != existing.Description):
+			changes = append(changes, settingsChange{
+				drift: fmt.Sprintf("label %s is different", label.Name),
+				apply: func(ctx context.Context) error {
+					return manager.UpdateLabel(ctx, repo, label)
+				},
+			})
+		}
+	}
+	return
+}
+
+func isSameBranchProtection(desired, actual v1alpha3.BranchProtection) bool {
+	desiredChecks := append([]string{}, desired.RequiredStatusChecks...)
+	actualChecks := append([]string{}, actual.RequiredStatusChecks...)
+	sort.Strings(desiredChecks)
+	sort.Strings(actualChecks)
+	return strings.Join(desiredChecks, "\n") == strings.Join(actualChecks, "\n") &&
+		desired.StrictStatusChecks == actual.StrictStatusChecks &&
+		desired.RequiredApprovingReviewCount == actual.RequiredApprovingReviewCount &&
+		desired.DismissStaleReviews == actual.DismissStaleReviews &&
+		desired.EnforceAdmins == actual.EnforceAdmins
+}
+
+// GetName returns the name of this reconciler
+func (r *SettingsReconciler) GetName() string {
+	return "git-repository-settings"
+}
+
+// GetGroupName returns the group name of this reconciler
+func (r *SettingsReconciler) GetGroupName() string {
+	return groupName
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *SettingsReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	r.recorder = mgr.GetEventRecorderFor(r.GetName())
+	r.log = ctrl.Log.WithName(r.GetName())
+	return ctrl.NewControllerManagedBy(mgr).
+		Named("git_repository_settings_controller").
+		For(&v1alpha3.GitRepository{}).
+		// the status updates should not trigger it again, it requeues by itself periodically
+		WithEventFilter(predicate.GenerationChangedPredicate{}).
+		Complete(r)
+}
@@ -0,0 +1,395 @@
+/*
+Copyright 2022 The KubeSphere Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gitrepository
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-logr/logr"
+	"github.com/h2non/gock"
+	"github.com/jenkins-x/go-scm/scm"
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	mgrcore "github.com/kubesphere/ks-devops/controllers/core"
+	"github.com/kubesphere/ks-devops/pkg/api/devops/v1alpha3"
+)
+
+func Test_isSameBranchProtection(t *testing.T) {
+	tests := []struct {
+		name    string
+		desired v1alpha3.BranchProtection
+		actual  v1alpha3.BranchProtection
+		want    bool
+	}{{
+		name:    "empty",
+		desired: v1alpha3.BranchProtection{Branch: "master"},
+		actual:  v1alpha3.BranchProtection{Branch: "master"},
+		want:    true,
+	}, {
+		name: "status checks in different order",
+		desired: v1alpha3.BranchProtection{
+			RequiredStatusChecks: []string{"KubeSphere DevOps", "lint"}, StrictStatusChecks: true, RequiredApprovingReviewCount: 1,
+		},
+		actual: v1alpha3.BranchProtection{
+			RequiredStatusChecks: []string{"lint", "KubeSphere DevOps"}, StrictStatusChecks: true, RequiredApprovingReviewCount: 1,
+		},
+		want: true,
+	}, {
+		name:    "missing status checks",
+		desired: v1alpha3.BranchProtection{RequiredStatusChecks: []string{"KubeSphere DevOps"}},
+		actual:  v1alpha3.BranchProtection{},
+		want:    false,
+	}, {
+		name:    "different review count",
+		desired: v1alpha3.BranchProtection{RequiredApprovingReviewCount: 2},
+		actual:  v1alpha3.BranchProtection{RequiredApprovingReviewCount: 1},
+		want:    false,
+	}, {
+		name:    "different admin enforcement",
+		desired: v1alpha3.BranchProtection{EnforceAdmins: true},
+		actual:  v1alpha3.BranchProtection{},
+		want:    false,
+	}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, isSameBranchProtection(tt.desired, tt.actual))
+		})
+	}
+}
+
+func Test_gitHubSettingsManager_GetBranchProtection(t *testing.T) {
+	defer gock.Off()
+	gock.New("https://api.github.com").
+		Get("/repos/linuxsuren/test/branches/master/protection").
+		Reply(200).
+		Type("application/json").
+		SetHeaders(mockHeaders).
+		File("testdata/protection.json")
+	gock.New("https://api.github.com").
+		Get("/repos/linuxsuren/test/branches/dev/protection").
+		Reply(404).
+		Type("application/json").
+		SetHeaders(mockHeaders).
+		JSON(map[string]string{"message": "Branch not protected"})
+
+	manager := newGitHubSettingsManager(newGitHubTestClient(t))
+	protection, err := manager.GetBranchProtection(context.Background(), "linuxsuren/test", "master")
+	assert.Nil(t, err)
+	assert.Equal(t, &v1alpha3.BranchProtection{
+		Branch:                       "master",
+		RequiredStatusChecks:         []string{"KubeSphere DevOps"},
+		StrictStatusChecks:           true,
+		RequiredApprovingReviewCount: 1,
+		DismissStaleReviews:          true,
+	}, protection)
+
+	protection, err = manager.GetBranchProtection(context.Background(), "linuxsuren/test", "dev")
+	assert.Nil(t, err)
+	assert.Nil(t, protection)
+	assert.True(t, gock.IsDone())
+}
+
+func newGitHubTestClient(t *testing.T) *scm.Client {
+	schema, err := v1alpha3.SchemeBuilder.Register().Build()
+	assert.Nil(t, err)
+	err = v1.SchemeBuilder.AddToScheme(schema)
+	assert.Nil(t, err)
+
+	repo := &v1alpha3.GitRepository{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "test"},
+		Spec: v1alpha3.GitRepositorySpec{
+			Provider: "github",
+			Secret:   &v1.SecretReference{Name: "secret", Namespace: "ns"},
+		},
+	}
+	secret := &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "secret"},
+		Type:       v1.SecretTypeBasicAuth,
+		Data:       map[string][]byte{v1.BasicAuthPasswordKey: []byte("token")},
+	}
+	gitClient, err := newGitClient(fake.NewClientBuilder().WithScheme(schema).WithObjects(secret).Build(), repo)
+	assert.Nil(t, err)
+	return gitClient
+}
+
+func TestSettingsReconciler_Reconcile(t *testing.T) {
+	schema, err := v1alpha3.SchemeBuilder.Register().Build()
+	assert.Nil(t, err)
+	err = v1.SchemeBuilder.AddToScheme(schema)
+	assert.Nil(t, err)
+
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Namespace: "ns", Name: "test"}}
+	settings := &v1alpha3.GitRepositorySettings{
+		DefaultBranch: "main",
+		Labels: []v1alpha3.GitLabel{
+			{Name: "Bug", Color: "d73a4a"},
+			{Name: "enhancement", Color: "A2EEEF"},
+			{Name: "ks-devops", Color: "0e8a16", Description: "Managed by KubeSphere DevOps"},
+		},
+		BranchProtections: []v1alpha3.BranchProtection{{
+			Branch:               "master",
+			RequiredStatusChecks: []string{"KubeSphere DevOps"},
+			StrictStatusChecks:   true,
+		}, {
+			Branch:                       "dev",
+			RequiredApprovingReviewCount: 1,
+		}},
+	}
+	repo := &v1alpha3.GitRepository{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "test"},
+		Spec: v1alpha3.GitRepositorySpec{
+			Provider: "github",
+			Owner:    "linuxsuren",
+			Repo:     "test",
+			Secret:   &v1.SecretReference{Name: "secret"},
+			Settings: settings,
+		},
+	}
+	reportRepo := repo.DeepCopy()
+	reportRepo.Spec.Settings.Policy = v1alpha3.GitRepositorySettingsPolicyReport
+	withoutSettings := repo.DeepCopy()
+	withoutSettings.Spec.Settings = nil
+	withoutSettings.Status.SettingsDrift = []string{"label bug is missing"}
+	withoutSettings.Status.Conditions = []metav1.Condition{{
+		Type:   v1alpha3.GitRepositoryConditionSettingsSynced,
+		Status: metav1.ConditionFalse,
+		Reason: EventReasonSettingsDrift,
+	}}
+	unsupported := repo.DeepCopy()
+	unsupported.Spec.Provider = "gitlab"
+
+	secret := &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "secret"},
+		Type:       v1.SecretTypeBasicAuth,
+		Data:       map[string][]byte{v1.BasicAuthPasswordKey: []byte("token")},
+	}
+
+	mockCurrentSettings := func() {
+		gock.New("https://api.github.com").
+			Get("/repos/linuxsuren/test").
+			Reply(200).
+			Type("application/json").
+			SetHeaders(mockHeaders).
+			File("testdata/repo.json")
+		gock.New("https://api.github.com").
+			Get("/repos/linuxsuren/test/labels").
+			Reply(200).
+			Type("application/json").
+			SetHeaders(mockHeaders).
+			File("testdata/labels.json")
+		gock.New("https://api.github.com").
+			Get("/repos/linuxsuren/test/branches/master/protection").
+			Reply(200).
+			Type("application/json").
+			SetHeaders(mockHeaders).
+			File("testdata/protection.json")
+		gock.New("https://api.github.com").
+			Get("/repos/linuxsuren/test/branches/dev/protection").
+			Reply(404).
+			Type("application/json").
+			SetHeaders(mockHeaders).
+			JSON(map[string]string{"message": "Branch not protected"})
+	}
+	expectedDrift := []string{
+		"the default branch is master, expected main",
+		"label Bug is different",
+		"label ks-devops is missing",
+		"the protection of branch master is different",
+		"branch dev is not protected",
+	}
+
+	tests := []struct {
+		name    string
+		objects []v1alpha3.GitRepository
+		prepare func()
+		verify  func(t *testing.T, repo *v1alpha3.GitRepository, result ctrl.Result, recorder *record.FakeRecorder)
+	}{{
+		name: "not found",
+		verify: func(t *testing.T, repo *v1alpha3.GitRepository, result ctrl.Result, recorder *record.FakeRecorder) {
+			assert.Equal(t, ctrl.Result{}, result)
+		},
+	}, {
+		name:    "settings are not managed",
+		objects: []v1alpha3.GitRepository{*withoutSettings},
+		verify: func(t *testing.T, repo *v1alpha3.GitRepository, result ctrl.Result, recorder *record.FakeRecorder) {
+			assert.Equal(t, ctrl.Result{}, result)
+			assert.Empty(t, repo.Status.SettingsDrift)
+			assert.Nil(t, meta.FindStatusCondition(repo.Status.Conditions, v1alpha3.GitRepositoryConditionSettingsSynced))
+		},
+	}, {
+		name:    "unsupported git provider",
+		objects: []v1alpha3.GitRepository{*unsupported},
+		verify: func(t *testing.T, repo *v1alpha3.GitRepository, result ctrl.Result, recorder *record.FakeRecorder) {
+			assert.Equal(t, ctrl.Result{}, result)
+			condition := meta.FindStatusCondition(repo.Status.Conditions, v1alpha3.GitRepositoryConditionSettingsSynced)
+			if assert.NotNil(t, condition) {
+				assert.Equal(t, metav1.ConditionFalse, condition.Status)
+				assert.Equal(t, EventReasonSettingsUnsupported, condition.Reason)
+				assert.Equal(t, "managing the settings is not supported by git provider: gitlab", condition.Message)
+			}
+			assert.Len(t, recorder.Events, 1)
+		},
+	}, {
+		name:    "report the drift",
+		objects: []v1alpha3.GitRepository{*reportRepo},
+		prepare: mockCurrentSettings,
+		verify: func(t *testing.T, repo *v1alpha3.GitRepository, result ctrl.Result, recorder *record.FakeRecorder) {
+			assert.Equal(t, ctrl.Result{RequeueAfter: defaultStatusCheckInterval}, result)
+			assert.Equal(t, expectedDrift, repo.Status.SettingsDrift)
+			condition := meta.FindStatusCondition(repo.Status.Conditions, v1alpha3.GitRepositoryConditionSettingsSynced)
+			if assert.NotNil(t, condition) {
+				assert.Equal(t, metav1.ConditionFalse, condition.Status)
+				assert.Equal(t, EventReasonSettingsDrift, condition.Reason)
+			}
+			assert.Empty(t, recorder.Events)
+		},
+	}, {
+		name:    "apply the settings",
+		objects: []v1alpha3.GitRepository{*repo},
+		prepare: func() {
+			mockCurrentSettings()
+			gock.New("https://api.github.com").
+				Patch("/repos/linuxsuren/test").
+				JSON(map[string]string{"default_branch": "main"}).
+				Reply(200).
+				SetHeaders(mockHeaders).
+				File("testdata/repo.json")
+			gock.New("https://api.github.com").
+				Patch("/repos/linuxsuren/test/labels/Bug").
+				JSON(map[string]string{"name": "Bug", "color": "d73a4a"}).
+				Reply(200).
+				SetHeaders(mockHeaders)
+			gock.New("https://api.github.com").
+				Post("/repos/linuxsuren/test/labels").
+				JSON(map[string]string{"name": "ks-devops", "color": "0e8a16", "description": "Managed by KubeSphere DevOps"}).
+				Reply(201).
+				SetHeaders(mockHeaders)
+			gock.New("https://api.github.com").
+				Put("/repos/linuxsuren/test/branches/master/protection").
+				JSON(map[string]interface{}{
+					"required_status_checks":        map[string]interface{}{"strict": true, "contexts": []string{"KubeSphere DevOps"}},
+					"enforce_admins":                false,
+					"required_pull_request_reviews": nil,
+					"restrictions":                  nil,
+				}).
+				Reply(200).
+				SetHeaders(mockHeaders)
+			gock.New("https://api.github.com").
+				Put("/repos/linuxsuren/test/branches/dev/protection").
+				JSON(map[string]interface{}{
+					"required_status_checks":        nil,
+					"enforce_admins":                false,
+					"required_pull_request_reviews": map[string]interface{}{"dismiss_stale_reviews": false, "required_approving_review_count": 1},
+					"restrictions":                  nil,
+				}).
+				Reply(200).
+				SetHeaders(mockHeaders)
+		},
+		verify: func(t *testing.T, repo *v1alpha3.GitRepository, result ctrl.Result, recorder *record.FakeRecorder) {
+			assert.Equal(t, ctrl.Result{RequeueAfter: defaultStatusCheckInterval}, result)
+			assert.Empty(t, repo.Status.SettingsDrift)
+			assert.True(t, meta.IsStatusConditionTrue(repo.Status.Conditions, v1alpha3.GitRepositoryConditionSettingsSynced))
+			if assert.Len(t, recorder.Events, 1) {
+				assert.Contains(t, <-recorder.Events, "Normal SettingsApplied corrected the settings drift")
+			}
+		},
+	}, {
+		name:    "failed to apply some settings",
+		objects: []v1alpha3.GitRepository{*repo},
+		prepare: func() {
+			mockCurrentSettings()
+			gock.New("https://api.github.com").
+				Patch("/repos/linuxsuren/test").
+				Reply(422).
+				SetHeaders(mockHeaders)
+			gock.New("https://api.github.com").
+				Patch("/repos/linuxsuren/test/labels/Bug").
+				Reply(200).
+				SetHeaders(mockHeaders)
+			gock.New("https://api.github.com").
+				Post("/repos/linuxsuren/test/labels").
+				Reply(201).
+				SetHeaders(mockHeaders)
+			gock.New("https://api.github.com").
+				Put("/repos/linuxsuren/test/branches/master/protection").
+				Reply(200).
+				SetHeaders(mockHeaders)
+			gock.New("https://api.github.com").
+				Put("/repos/linuxsuren/test/branches/dev/protection").
+				Reply(403).
+				SetHeaders(mockHeaders)
+		},
+		verify: func(t *testing.T, repo *v1alpha3.GitRepository, result ctrl.Result, recorder *record.FakeRecorder) {
+			assert.Equal(t, []string{
+				"the default branch is master, expected main",
+				"branch dev is not protected",
+			}, repo.Status.SettingsDrift)
+			condition := meta.FindStatusCondition(repo.Status.Conditions, v1alpha3.GitRepositoryConditionSettingsSynced)
+			if assert.NotNil(t, condition) {
+				assert.Equal(t, metav1.ConditionFalse, condition.Status)
+				assert.Equal(t, "ApplyFailed", condition.Reason)
+				assert.Contains(t, condition.Message, "unexpected status code 403")
+			}
+			assert.Len(t, recorder.Events, 2)
+		},
+	}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer gock.Off()
+			if tt.prepare != nil {
+				tt.prepare()
+			}
+
+			builder := fake.NewClientBuilder().WithScheme(schema).WithStatusSubresource(&v1alpha3.GitRepository{}).
+				WithObjects(secret.DeepCopy())
+			for i := range tt.objects {
+				builder.WithObjects(tt.objects[i].DeepCopy())
+			}
+			recorder := record.NewFakeRecorder(10)
+			r := &SettingsReconciler{
+				Client:   builder.Build(),
+				log:      logr.New(log.NullLogSink{}),
+				recorder: recorder,
+			}
+			result, err := r.Reconcile(context.Background(), req)
+			assert.Nil(t, err)
+
+			gotRepo := &v1alpha3.GitRepository{}
+			_ = r.Get(context.Background(), req.NamespacedName, gotRepo)
+			tt.verify(t, gotRepo, result, recorder)
+			assert.True(t, gock.IsDone())
+		})
+	}
+}
+
+func TestSettingsReconciler_SetupWithManager(t *testing.T) {
+	schema, err := v1alpha3.SchemeBuilder.Register().Build()
+	assert.Nil(t, err)
+
+	r := &SettingsReconciler{}
+	assert.Nil(t, r.SetupWithManager(&mgrcore.FakeManager{Scheme: schema}))
+	assert.Equal(t, defaultStatusCheckInterval, r.getInterval())
+}
@@ -0,0 +1,188 @@
+/*
+Copyright 2022 The KubeSphere Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gitrepository
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/jenkins-x/go-scm/scm"
+
+	"github.com/kubesphere/ks-devops/pkg/api/devops/v1alpha3"
+)
+
+// gitHubSettingsManager manages the settings via the GitHub REST API, see also
+// https://docs.github.com/en/rest/branches/branch-protection
+type gitHubSettingsManager struct {
+	client *scm.Client
+}
+
+func newGitHubSettingsManager(gitClient *scm.Client) settingsManager {
+	return &gitHubSettingsManager{client: gitClient}
+}
+
+// GetDefaultBranch returns the default branch of the repository
+func (m *gitHubSettingsManager) GetDefaultBranch(ctx context.Context, repo string) (branch string, err error) {
+	var gitRepo *scm.Repository
+	if gitRepo, _, err = m.client.Repositories.Find(ctx, repo); err == nil {
+		branch = gitRepo.Branch
+	}
+	return
+}
+
+// SetDefaultBranch changes the default branch of the repository
+func (m *gitHubSettingsManager) SetDefaultBranch(ctx context.Context, repo, branch string) error {
+	return m.do(ctx, http.MethodPatch, fmt.Sprintf("repos/%s", repo), map[string]string{
+		"default_branch": branch,
+	}, nil)
+}
+
+// ListLabels returns the labels of the repository
+func (m *gitHubSettingsManager) ListLabels(ctx context.Context, repo string) (labels []v1alpha3.GitLabel, err error) {
+	var scmLabels []*scm.Label
+	if scmLabels, _, err = m.client.Repositories.ListLabels(ctx, repo, &scm.ListOptions{Page: 1, Size: 100}); err != nil {
+		return
+	}
+	for _, label := range scmLabels {
+		labels = append(labels, v1alpha3.GitLabel{
+			Name:        label.Name,
+			Color:       label.Color,
+			Description: label.Description,
+		})
+	}
+	return
+}
+
+// CreateLabel creates a label in the repository
+func (m *gitHubSettingsManager) CreateLabel(ctx context.Context, repo string, label v1alpha3.GitLabel) error {
+	return m.do(ctx, http.MethodPost, fmt.Sprintf("repos/%s/labels", repo), label, nil)
+}
+
+// UpdateLabel updates the color and description of a label
+func (m *gitHubSettingsManager) UpdateLabel(ctx context.Context, repo string, label v1alpha3.GitLabel) error {
+	return m.do(ctx, http.MethodPatch, fmt.Sprintf("repos/%s/labels/%s", repo, url.PathEscape(label.Name)), label, nil)
+}
+
+type gitHubBranchProtection struct {
+	RequiredStatusChecks *struct {
+		Strict   bool     `json:"strict"`
+		Contexts []string `json:"contexts"`
+	} `json:"required_status_checks"`
+	EnforceAdmins *struct {
+		Enabled bool `json:"enabled"`
+	} `json:"enforce_admins"`
+	RequiredPullRequestReviews *struct {
+		DismissStaleReviews          bool `json:"dismiss_stale_reviews"`
+		RequiredApprovingReviewCount int  `json:"required_approving_review_count"`
+	} `json:"required_pull_request_reviews"`
+}
+
+// GetBranchProtection returns the protection of a branch, it returns nil if the branch is not protected
+func (m *gitHubSettingsManager) GetBranchProtection(ctx context.Context, repo, branch string) (
+	protection *v1alpha3.BranchProtection, err error) {
+	ghProtection := &gitHubBranchProtection{}
+	if err = m.do(ctx, http.MethodGet, getGitHubBranchProtectionPath(repo, branch), nil, ghProtection); err != nil {
+		if isNotFound(err) {
+			err = nil
+		}
+		return
+	}
+
+	protection = &v1alpha3.BranchProtection{Branch: branch}
+	if checks := ghProtection.RequiredStatusChecks; checks != nil {
+		protection.RequiredStatusChecks = checks.Contexts
+		protection.StrictStatusChecks = checks.Strict
+	}
+	if ghProtection.EnforceAdmins != nil {
+		protection.EnforceAdmins = ghProtection.EnforceAdmins.Enabled
+	}
+	if reviews := ghProtection.RequiredPullRequestReviews; reviews != nil {
+		protection.DismissStaleReviews = reviews.DismissStaleReviews
+		protection.RequiredApprovingReviewCount = reviews.RequiredApprovingReviewCount
+	}
+	return
+}
+
+// SetBranchProtection replaces the protection of a branch, the push restrictions are removed
+func (m *gitHubSettingsManager) SetBranchProtection(ctx context.Context, repo string, protection v1alpha3.BranchProtection) error {
+	payload := map[string]interface{}{
+		"required_status_checks":        nil,
+		"enforce_admins":                protection.EnforceAdmins,
+		"required_pull_request_reviews": nil,
+		"restrictions":                  nil,
+	}
+	if len(protection.RequiredStatusChecks) > 0 {
+		payload["required_status_checks"] = map[string]interface{}{
+			"strict":   protection.StrictStatusChecks,
+			"contexts": protection.RequiredStatusChecks,
+		}
+	}
+	if protection.RequiredApprovingReviewCount > 0 || protection.DismissStaleReviews {
+		payload["required_pull_request_reviews"] = map[string]interface{}{
+			"dismiss_stale_reviews":           protection.DismissStaleReviews,
+			"required_approving_review_count": protection.RequiredApprovingReviewCount,
+		}
+	}
+	return m.do(ctx, http.MethodPut, getGitHubBranchProtectionPath(repo, protection.Branch), payload, nil)
+}
+
+func getGitHubBranchProtectionPath(repo, branch string) string {
+	return fmt.Sprintf("repos/%s/branches/%s/protection", repo, url.PathEscape(branch))
+}
+
+// do sends a JSON request, then decodes the response into the result if it's not nil
+func (m *gitHubSettingsManager) do(ctx context.Context, method, path string, payload, result interface{}) (err error) {
+	req := &scm.Request{
+		Method: method,
+		Path:   path,
+		Header: http.Header{"Accept": []string{"application/vnd.github+json"}},
+	}
+	if payload != nil {
+		var data []byte
+		if data, err = json.Marshal(payload); err != nil {
+			return
+		}
+		req.Body = bytes.NewReader(data)
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	var resp *scm.Response
+	if resp, err = m.client.Do(ctx, req); err != nil {
+		return
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	switch {
+	case resp.Status == http.StatusNotFound:
+		err = scm.ErrNotFound
+	case resp.Status >= http.StatusBadRequest:
+		err = fmt.Errorf("unexpected status code %d when requesting %s %s", resp.Status, method, path)
+	case result != nil:
+		err = json.NewDecoder(resp.Body).Decode(result)
+	}
+	return
+}
+
+func isNotFound(err error) bool {
+	return err == scm.ErrNotFound
+}
@@ -0,0 +1,318 @@
+/*
+Copyright 2022 The KubeSphere Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gitrepository
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/jenkins-x/go-scm/scm"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apiserver/pkg/storage/names"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+
+	"github.com/kubesphere/ks-devops/pkg/api/devops/v1alpha3"
+)
+
+// defaultStatusCheckInterval is the default period of checking a git repository
+const defaultStatusCheckInterval = 10 * time.Minute
+
+// StatusReconciler checks the git repositories periodically, then records the results into their status
+type StatusReconciler struct {
+	client.Client
+	// Interval is the period of checking a git repository, the default value is 10 minutes
+	Interval time.Duration
+	log      logr.Logger
+	recorder record.EventRecorder
+}
+
+//+kubebuilder:rbac:groups=devops.kubesphere.io,resources=gitrepositories,verbs=get;list;watch;update
+//+kubebuilder:rbac:groups=devops.kubesphere.io,resources=gitrepositories/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups=devops.kubesphere.io,resources=webhooks,verbs=get;list
+//+kubebuilder:rbac:groups="",resources=secrets,verbs=get
+//+kubebuilder:rbac:groups="",resources=events,verbs=create;patch
+
+// Reconcile verifies the credential, then records the repository information and the webhook health
+func (r *StatusReconciler) Reconcile(ctx context.Context, req ctrl.Request) (result ctrl.Result, err error) {
+	log := r.log.WithValues("GitRepository", req.NamespacedName)
+
+	repo := &v1alpha3.GitRepository{}
+	if err = r.Get(ctx, req.NamespacedName, repo); err != nil {
+		err = client.IgnoreNotFound(err)
+		return
+	}
+	if !repo.DeletionTimestamp.IsZero() {
+		return
+	}
+
+	status := repo.Status.DeepCopy()
+	deletedWebhooks := r.checkStatus(ctx, repo, status)
+	repo.Status = *status
+	if err = r.Status().Update(ctx, repo); err != nil {
+		log.Error(err, "failed to update the status")
+		return
+	}
+
+	if len(deletedWebhooks) > 0 {
+		log.Info("found deleted webhooks", "webhooks", deletedWebhooks)
+		// notify the webhook controller to recreate them
+		if repo.Annotations == nil {
+			repo.Annotations = map[string]string{}
+		}
+		repo.Annotations[v1alpha3.AnnotationKeyWebhookUpdates] = names.SimpleNameGenerator.GenerateName("")
+		if err = r.Update(ctx, repo); err != nil {
+			return
+		}
+	}
+	result = ctrl.Result{RequeueAfter: r.getInterval()}
+	return
+}
+
+func (r *StatusReconciler) getInterval() time.Duration {
+	if r.Interval > 0 {
+		return r.Interval
+	}
+	return defaultStatusCheckInterval
+}
+
+// checkStatus fills the status by the git provider API, it returns the webhooks which were deleted on the git provider
+func (r *StatusReconciler) checkStatus(ctx context.Context, repo *v1alpha3.GitRepository, status *v1alpha3.GitRepositoryStatus) (deletedWebhooks []string) {
+	now := metav1.Now()
+	status.LastProbeTime = &now
+
+	gitClient, repoPath, err := r.connect(ctx, repo, status)
+	if err != nil {
+		status.Connection = string(metav1.ConditionFalse)
+		status.Message = err.Error()
+		setGitRepositoryCondition(repo, status, v1alpha3.GitRepositoryConditionConnected, metav1.ConditionFalse, EventReasonConnectionFailed, err.Error())
+		r.recorder.Event(repo, v1.EventTypeWarning, EventReasonConnectionFailed, err.Error())
+		return
+	}
+	status.Connection = string(metav1.ConditionTrue)
+	status.Message = ""
+	setGitRepositoryCondition(repo, status, v1alpha3.GitRepositoryConditionConnected, metav1.ConditionTrue, v1alpha3.GitRepositoryConditionConnected, "")
+
+	if len(repo.Spec.Webhooks) == 0 {
+		status.Webhooks = nil
+		meta.RemoveStatusCondition(&status.Conditions, v1alpha3.GitRepositoryConditionWebhooksReady)
+		return
+	}
+	deletedWebhooks = r.checkWebhooks(ctx, gitClient, repo, repoPath, status)
+	return
+}
+
+// connect verifies the credential by finding the repository, then records the repository information
+func (r *StatusReconciler) connect(ctx context.Context, repo *v1alpha3.GitRepository, status *v1alpha3.GitRepositoryStatus) (
+	gitClient *scm.Client, repoPath string, err error) {
+	if repoPath = repo.GetRepoPath(); repoPath == "" {
+		err = fmt.Errorf("cannot find the owner and name of the repository")
+		return
+	}
+	if gitClient, err = newGitClient(r.Client, repo); err != nil {
+		return
+	}
+
+	var gitRepo *scm.Repository
+	if gitRepo, _, err = gitClient.Repositories.Find(ctx, repoPath); err != nil {
+		err = fmt.Errorf("failed to find repository %s, error: %v", repoPath, err)
+		return
+	}
+
+	status.DefaultBranch = gitRepo.Branch
+	status.Visibility = v1alpha3.GitRepositoryVisibilityPublic
+	if gitRepo.Private {
+		status.Visibility = v1alpha3.GitRepositoryVisibilityPrivate
+	}
+
+	if gitRepo.Branch != "" {
+		// the last commit is optional, it's possible that the repository is empty
+		if commit, _, commitErr := gitClient.Git.FindCommit(ctx, repoPath, gitRepo.Branch); commitErr == nil {
+			status.LastCommit = convertCommit(commit)
+		} else {
+			r.log.V(6).Info("failed to find the last commit", "repo", repoPath, "error", commitErr)
+		}
+	}
+	return
+}
+
+// checkWebhooks records the registration states of the webhooks, and finds the ones which were deleted on the git provider
+func (r *StatusReconciler) checkWebhooks(ctx context.Context, gitClient *scm.Client, repo *v1alpha3.GitRepository,
+	repoPath string, status *v1alpha3.GitRepositoryStatus) (deletedWebhooks []string) {
+	hooks, _, err := gitClient.Repositories.ListHooks(ctx, repoPath, &scm.ListOptions{Page: 1, Size: 100})
+	if err != nil {
+		setGitRepositoryCondition(repo, status, v1alpha3.GitRepositoryConditionWebhooksReady, metav1.ConditionUnknown,
+			"ListFailed", fmt.Sprintf("failed to list the webhooks, error: %v", err))
+		return
+	}
+
+	var webhookStatuses []v1alpha3.GitRepositoryWebhookStatus
+	var problems []string
+	for _, webhookRef := range repo.Spec.Webhooks {
+		webhookStatus := v1alpha3.GitRepositoryWebhookStatus{Name: webhookRef.Name}
+		if previous := status.GetWebhookStatus(webhookRef.Name); previous != nil {
+			webhookStatus = *previous
+		}
+
+		webhook := &v1alpha3.Webhook{}
+		if err = r.Get(ctx, types.NamespacedName{Namespace: repo.Namespace, Name: webhookRef.Name}, webhook); err != nil {
+			problems = append(problems, fmt.Sprintf("cannot find webhook %s", webhookRef.Name))
+			webhookStatuses = append(webhookStatuses, webhookStatus)
+			continue
+		}
+
+		if ok, id := exist(webhook.Spec.Server, hooks); ok {
+			webhookStatus.ID = id
+			webhookStatus.Registered = true
+			if getter, found := webhookDeliveryGetters[gitClient.Driver]; found {
+				if lastDelivery, failures, deliveryErr := getter(ctx, gitClient, repoPath, id); deliveryErr == nil {
+					webhookStatus.LastDelivery = lastDelivery
+					webhookStatus.FailureCount = failures
+				}
+			}
+			if webhookStatus.FailureCount > 0 {
+				problems = append(problems, fmt.Sprintf("webhook %s has %d failed deliveries", webhookRef.Name, webhookStatus.FailureCount))
+			}
+		} else {
+			if webhookStatus.Registered {
+				deletedWebhooks = append(deletedWebhooks, webhookRef.Name)
+				r.recorder.Eventf(repo, v1.EventTypeWarning, EventReasonWebhookDeleted,
+					"the webhook %s (id: %s) was deleted on the git provider", webhookRef.Name, webhookStatus.ID)
+			}
+			webhookStatus.Registered = false
+			webhookStatus.ID = ""
+			problems = append(problems, fmt.Sprintf("webhook %s is not registered", webhookRef.Name))
+		}
+		webhookStatuses = append(webhookStatuses, webhookStatus)
+	}
+	status.Webhooks = webhookStatuses
+
+	if len(problems) == 0 {
+		setGitRepositoryCondition(repo, status, v1alpha3.GitRepositoryConditionWebhooksReady, metav1.ConditionTrue,
+			v1alpha3.GitRepositoryConditionWebhooksReady, "")
+	} else {
+		setGitRepositoryCondition(repo, status, v1alpha3.GitRepositoryConditionWebhooksReady, metav1.ConditionFalse,
+			"Unhealthy", strings.Join(problems, "; "))
+	}
+	return
+}
+
+func setGitRepositoryCondition(repo *v1alpha3.GitRepository, status *v1alpha3.GitRepositoryStatus,
+	conditionType string, conditionStatus metav1.ConditionStatus, reason, message string) {
+	meta.SetStatusCondition(&status.Conditions, metav1.Condition{
+		Type:               conditionType,
+		Status:             conditionStatus,
+		ObservedGeneration: repo.Generation,
+		Reason:             reason,
+		Message:            message,
+	})
+}
+
+func convertCommit(commit *scm.Commit) (result *v1alpha3.GitCommit) {
+	result = &v1alpha3.GitCommit{
+		SHA:     commit.Sha,
+		Message: strings.SplitN(commit.Message, "\n", 2)[0],
+		Author:  commit.Author.Name,
+	}
+	if result.Author == "" {
+		result.Author = commit.Author.Login
+	}
+	if !commit.Author.Date.IsZero() {
+		commitTime := metav1.NewTime(commit.Author.Date)
+		result.Time = &commitTime
+	}
+	return
+}
+
+// webhookDeliveryGetter returns the latest delivery time and the count of the failed deliveries in the recent ones
+type webhookDeliveryGetter func(ctx context.Context, gitClient *scm.Client, repo, hookID string) (lastDelivery *metav1.Time, failures int, err error)
+
+// webhookDeliveryGetters are the providers which have the delivery API, go-scm does not support it yet
+var webhookDeliveryGetters = map[scm.Driver]webhookDeliveryGetter{
+	scm.DriverGithub: getGitHubWebhookDeliveries,
+}
+
+type gitHubHookDelivery struct {
+	DeliveredAt time.Time `json:"delivered_at"`
+	StatusCode  int       `json:"status_code"`
+}
+
+// getGitHubWebhookDeliveries gets the recent deliveries, see also
+// https://docs.github.com/en/rest/webhooks/repo-deliveries#list-deliveries-for-a-repository-webhook
+func getGitHubWebhookDeliveries(ctx context.Context, gitClient *scm.Client, repo, hookID string) (lastDelivery *metav1.Time, failures int, err error) {
+	var resp *scm.Response
+	if resp, err = gitClient.Do(ctx, &scm.Request{
+		Method: http.MethodGet,
+		Path:   fmt.Sprintf("repos/%s/hooks/%s/deliveries?per_page=30", repo, hookID),
+	}); err != nil {
+		return
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+	if resp.Status >= http.StatusBadRequest {
+		err = fmt.Errorf("unexpected status code %d when getting the deliveries", resp.Status)
+		return
+	}
+
+	var deliveries []gitHubHookDelivery
+	if err = json.NewDecoder(resp.Body).Decode(&deliveries); err != nil {
+		return
+	}
+	for _, delivery := range deliveries {
+		if lastDelivery == nil || delivery.DeliveredAt.After(lastDelivery.Time) {
+			deliveredAt := metav1.NewTime(delivery.DeliveredAt)
+			lastDelivery = &deliveredAt
+		}
+		if delivery.StatusCode < http.StatusOK || delivery.StatusCode >= http.StatusMultipleChoices {
+			failures++
+		}
+	}
+	return
+}
+
+// GetName returns the name of this reconciler
+func (r *StatusReconciler) GetName() string {
+	return "git-repository-status"
+}
+
+// GetGroupName returns the group name of this reconciler
+func (r *StatusReconciler) GetGroupName() string {
+	return groupName
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *StatusReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	r.recorder = mgr.GetEventRecorderFor(r.GetName())
+	r.log = ctrl.Log.WithName(r.GetName())
+	return ctrl.NewControllerManagedBy(mgr).
+		Named("git_repository_status_controller").
+		For(&v1alpha3.GitRepository{}).
+		// the status updates should not trigger it again, it requeues by itself periodically
+		WithEventFilter(predicate.GenerationChangedPredicate{}).
+		Complete(r)
+}
@@ -0,0 +1,252 @@
+/*
+Copyright 2022 The KubeSphere Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gitrepository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/h2non/gock"
+	"github.com/jenkins-x/go-scm/scm"
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	mgrcore "github.com/kubesphere/ks-devops/controllers/core"
+	"github.com/kubesphere/ks-devops/pkg/api/devops/v1alpha3"
+)
+
+func Test_convertCommit(t *testing.T) {
+	date := time.Date(2011, 4, 14, 16, 0, 49, 0, time.UTC)
+	commitTime := metav1.NewTime(date)
+
+	assert.Equal(t, &v1alpha3.GitCommit{
+		SHA:     "sha",
+		Message: "Fix all the bugs",
+		Author:  "Rick",
+		Time:    &commitTime,
+	}, convertCommit(&scm.Commit{
+		Sha:     "sha",
+		Message: "Fix all the bugs\n\ndetails",
+		Author:  scm.Signature{Name: "Rick", Login: "linuxsuren", Date: date},
+	}))
+	assert.Equal(t, &v1alpha3.GitCommit{
+		SHA:    "sha",
+		Author: "linuxsuren",
+	}, convertCommit(&scm.Commit{
+		Sha:    "sha",
+		Author: scm.Signature{Login: "linuxsuren"},
+	}))
+}
+
+func TestStatusReconciler_Reconcile(t *testing.T) {
+	schema, err := v1alpha3.SchemeBuilder.Register().Build()
+	assert.Nil(t, err)
+	err = v1.SchemeBuilder.AddToScheme(schema)
+	assert.Nil(t, err)
+
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Namespace: "ns", Name: "test"}}
+	repo := &v1alpha3.GitRepository{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "test"},
+		Spec: v1alpha3.GitRepositorySpec{
+			Provider: "github",
+			Owner:    "linuxsuren",
+			Repo:     "test",
+			Secret:   &v1.SecretReference{Name: "secret"},
+		},
+	}
+	repoWithWebhook := repo.DeepCopy()
+	repoWithWebhook.Spec.Webhooks = []v1.LocalObjectReference{{Name: "webhook"}}
+	repoWithDeletedWebhook := repoWithWebhook.DeepCopy()
+	repoWithDeletedWebhook.Spec.Webhooks = []v1.LocalObjectReference{{Name: "deleted"}}
+	repoWithDeletedWebhook.Status.Webhooks = []v1alpha3.GitRepositoryWebhookStatus{{Name: "deleted", ID: "2", Registered: true}}
+
+	secret := &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "secret"},
+		Type:       v1.SecretTypeBasicAuth,
+		Data:       map[string][]byte{v1.BasicAuthPasswordKey: []byte("token")},
+	}
+	webhook := &v1alpha3.Webhook{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "webhook"},
+		Spec:       v1alpha3.WebhookSpec{Server: "http://example.com/webhook"},
+	}
+	deletedWebhook := &v1alpha3.Webhook{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "deleted"},
+		Spec:       v1alpha3.WebhookSpec{Server: "http://example.com/deleted"},
+	}
+
+	mockRepo := func() {
+		gock.New("https://api.github.com").
+			Get("/repos/linuxsuren/test").
+			Reply(200).
+			Type("application/json").
+			SetHeaders(mockHeaders).
+			File("testdata/repo.json")
+		gock.New("https://api.github.com").
+			Get("/repos/linuxsuren/test/commits/master").
+			Reply(200).
+			Type("application/json").
+			SetHeaders(mockHeaders).
+			File("testdata/commit.json")
+	}
+	mockHooks := func() {
+		gock.New("https://api.github.com").
+			Get("/repos/linuxsuren/test/hooks").
+			Reply(200).
+			Type("application/json").
+			SetHeaders(mockHeaders).
+			File("testdata/hooks.json")
+	}
+
+	tests := []struct {
+		name    string
+		objects []v1alpha3.GitRepository
+		prepare func()
+		verify  func(t *testing.T, repo *v1alpha3.GitRepository, result ctrl.Result, err error)
+	}{{
+		name: "not found",
+		verify: func(t *testing.T, repo *v1alpha3.GitRepository, result ctrl.Result, err error) {
+			assert.Nil(t, err)
+			assert.Equal(t, ctrl.Result{}, result)
+		},
+	}, {
+		name:    "failed to connect",
+		objects: []v1alpha3.GitRepository{*repo},
+		prepare: func() {
+			gock.New("https://api.github.com").
+				Get("/repos/linuxsuren/test").
+				Reply(401).
+				Type("application/json").
+				SetHeaders(mockHeaders).
+				JSON(map[string]string{"message": "Bad credentials"})
+		},
+		verify: func(t *testing.T, repo *v1alpha3.GitRepository, result ctrl.Result, err error) {
+			assert.Nil(t, err)
+			assert.Equal(t, ctrl.Result{RequeueAfter: defaultStatusCheckInterval}, result)
+			assert.Equal(t, "False", repo.Status.Connection)
+			assert.Contains(t, repo.Status.Message, "failed to find repository linuxsuren/test")
+			assert.NotNil(t, repo.Status.LastProbeTime)
+			assert.True(t, meta.IsStatusConditionFalse(repo.Status.Conditions, v1alpha3.GitRepositoryConditionConnected))
+		},
+	}, {
+		name:    "connected without webhooks",
+		objects: []v1alpha3.GitRepository{*repo},
+		prepare: mockRepo,
+		verify: func(t *testing.T, repo *v1alpha3.GitRepository, result ctrl.Result, err error) {
+			assert.Nil(t, err)
+			assert.Equal(t, "True", repo.Status.Connection)
+			assert.Equal(t, "master", repo.Status.DefaultBranch)
+			assert.Equal(t, v1alpha3.GitRepositoryVisibilityPrivate, repo.Status.Visibility)
+			if assert.NotNil(t, repo.Status.LastCommit) {
+				assert.Equal(t, "6dcb09b5b57875f334f61aebed695e2e4193db5e", repo.Status.LastCommit.SHA)
+				assert.Equal(t, "Fix all the bugs", repo.Status.LastCommit.Message)
+				assert.Equal(t, "Rick", repo.Status.LastCommit.Author)
+			}
+			assert.True(t, meta.IsStatusConditionTrue(repo.Status.Conditions, v1alpha3.GitRepositoryConditionConnected))
+			assert.Nil(t, meta.FindStatusCondition(repo.Status.Conditions, v1alpha3.GitRepositoryConditionWebhooksReady))
+		},
+	}, {
+		name:    "webhook with failed deliveries",
+		objects: []v1alpha3.GitRepository{*repoWithWebhook},
+		prepare: func() {
+			mockRepo()
+			mockHooks()
+			gock.New("https://api.github.com").
+				Get("/repos/linuxsuren/test/hooks/1/deliveries").
+				Reply(200).
+				Type("application/json").
+				SetHeaders(mockHeaders).
+				File("testdata/deliveries.json")
+		},
+		verify: func(t *testing.T, repo *v1alpha3.GitRepository, result ctrl.Result, err error) {
+			assert.Nil(t, err)
+			if assert.Len(t, repo.Status.Webhooks, 1) {
+				webhookStatus := repo.Status.Webhooks[0]
+				assert.Equal(t, "webhook", webhookStatus.Name)
+				assert.Equal(t, "1", webhookStatus.ID)
+				assert.True(t, webhookStatus.Registered)
+				assert.Equal(t, 1, webhookStatus.FailureCount)
+				if assert.NotNil(t, webhookStatus.LastDelivery) {
+					assert.Equal(t, time.Date(2019, 6, 4, 0, 57, 16, 0, time.UTC), webhookStatus.LastDelivery.UTC())
+				}
+			}
+			condition := meta.FindStatusCondition(repo.Status.Conditions, v1alpha3.GitRepositoryConditionWebhooksReady)
+			if assert.NotNil(t, condition) {
+				assert.Equal(t, metav1.ConditionFalse, condition.Status)
+				assert.Equal(t, "webhook webhook has 1 failed deliveries", condition.Message)
+			}
+			assert.Empty(t, repo.Annotations[v1alpha3.AnnotationKeyWebhookUpdates])
+		},
+	}, {
+		name:    "webhook was deleted on the git provider",
+		objects: []v1alpha3.GitRepository{*repoWithDeletedWebhook},
+		prepare: func() {
+			mockRepo()
+			mockHooks()
+		},
+		verify: func(t *testing.T, repo *v1alpha3.GitRepository, result ctrl.Result, err error) {
+			assert.Nil(t, err)
+			assert.Equal(t, []v1alpha3.GitRepositoryWebhookStatus{{Name: "deleted"}}, repo.Status.Webhooks)
+			assert.True(t, meta.IsStatusConditionFalse(repo.Status.Conditions, v1alpha3.GitRepositoryConditionWebhooksReady))
+			assert.NotEmpty(t, repo.Annotations[v1alpha3.AnnotationKeyWebhookUpdates])
+		},
+	}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer gock.Off()
+			if tt.prepare != nil {
+				tt.prepare()
+			}
+
+			builder := fake.NewClientBuilder().WithScheme(schema).WithStatusSubresource(&v1alpha3.GitRepository{}).
+				WithObjects(secret.DeepCopy(), webhook.DeepCopy(), deletedWebhook.DeepCopy())
+			for i := range tt.objects {
+				builder.WithObjects(tt.objects[i].DeepCopy())
+			}
+			r := &StatusReconciler{
+				Client:   builder.Build(),
+				log:      logr.New(log.NullLogSink{}),
+				recorder: record.NewFakeRecorder(10),
+			}
+			result, err := r.Reconcile(context.Background(), req)
+
+			gotRepo := &v1alpha3.GitRepository{}
+			_ = r.Get(context.Background(), req.NamespacedName, gotRepo)
+			tt.verify(t, gotRepo, result, err)
+			assert.True(t, gock.IsDone())
+		})
+	}
+}
+
+func TestStatusReconciler_SetupWithManager(t *testing.T) {
+	schema, err := v1alpha3.SchemeBuilder.Register().Build()
+	assert.Nil(t, err)
+
+	r := &StatusReconciler{}
+	assert.Nil(t, r.SetupWithManager(&mgrcore.FakeManager{Scheme: schema}))
+	assert.Equal(t, defaultStatusCheckInterval, r.getInterval())
+	r.Interval = time.Minute
+	assert.Equal(t, time.Minute, r.getInterval())
+}
@@ -23,12 +23,14 @@ import (
 	"github.com/kubesphere/ks-devops/pkg/api/devops/v1alpha3"
 	"github.com/kubesphere/ks-devops/pkg/client/git"
 	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
 	"k8s.io/apimachinery/pkg/types"
 
 	"github.com/go-logr/logr"
 	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
 )
 
 // Reconciler reconciles a GitRepository object
@@ -41,6 +43,8 @@ type Reconciler struct {
 //+kubebuilder:rbac:groups=devops.kubesphere.io,resources=webhooks,verbs=get;list;update;patch
 //+kubebuilder:rbac:groups=devops.kubesphere.io,resources=secrets,verbs=get
 //+kubebuilder:rbac:groups=devops.kubesphere.io,resources=gitrepositories,verbs=get;list;watch
+//+kubebuilder:rbac:groups=devops.kubesphere.io,resources=gitrepositories/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups="",resources=events,verbs=create;patch
 
 // Reconcile is part of the main kubernetes reconciliation loop which aims to
 // move the current state of the cluster closer to the desired state.
@@ -98,6 +102,7 @@ func (r *Reconciler) createOrUpdateWebhook(repo *v1alpha3.GitRepository) (err er
 		return
 	}
 
+	status := repo.Status.DeepCopy()
 	for index := range repo.Spec.Webhooks {
 		webhookRef := repo.Spec.Webhooks[index]
 		webhook := &v1alpha3.Webhook{}
@@ -123,12 +128,36 @@ func (r *Reconciler) createOrUpdateWebhook(repo *v1alpha3.GitRepository) (err er
 			NativeEvents: webhook.Spec.Events,
 		}
 
-		if ok, _ := exist(webhook.Spec.Server, hooks); ok {
+		var hook *scm.Hook
+		webhookStatus := v1alpha3.GitRepositoryWebhookStatus{Name: webhookRef.Name}
+		if previous := status.GetWebhookStatus(webhookRef.Name); previous != nil {
+			webhookStatus = *previous
+		}
+
+		if ok, id := exist(webhook.Spec.Server, hooks); ok {
 			// update the existing webhooks
-			_, _, err = gitClient.Repositories.UpdateHook(context.TODO(), repoAddress, hookInput)
+			webhookStatus.ID = id
+			hook, _, err = gitClient.Repositories.UpdateHook(context.TODO(), repoAddress, hookInput)
 		} else {
+			if webhookStatus.Registered {
+				r.recorder.Eventf(repo, v1.EventTypeWarning, EventReasonWebhookDeleted,
+					"the webhook %s (id: %s) was deleted on the git provider, recreating it", webhookRef.Name, webhookStatus.ID)
+			}
 			// create the webhook
-			_, _, err = gitClient.Repositories.CreateHook(context.TODO(), repoAddress, hookInput)
+			hook, _, err = gitClient.Repositories.CreateHook(context.TODO(), repoAddress, hookInput)
+		}
+
+		webhookStatus.Registered = err == nil
+		if hook != nil && hook.ID != "" {
+			webhookStatus.ID = hook.ID
+		}
+		status.SetWebhookStatus(webhookStatus)
+	}
+
+	if !equality.Semantic.DeepEqual(status.Webhooks, repo.Status.Webhooks) {
+		repo.Status = *status
+		if statusErr := r.Status().Update(context.TODO(), repo); statusErr != nil && err == nil {
+			err = fmt.Errorf("failed to update the status of the webhooks, error: %v", statusErr)
 		}
 	}
 	return
@@ -146,6 +175,11 @@ func exist(server string, hooks []*scm.Hook) (exist bool, id string) {
 }
 
 func (r *Reconciler) getGitClient(repo *v1alpha3.GitRepository) (client *scm.Client, err error) {
+	return newGitClient(r.Client, repo)
+}
+
+// newGitClient creates a git client with the secret of the GitRepository
+func newGitClient(k8sClient client.Client, repo *v1alpha3.GitRepository) (*scm.Client, error) {
 	spec := repo.Spec.DeepCopy()
 	provider := spec.Provider
 
@@ -153,7 +187,9 @@ func (r *Reconciler) getGitClient(repo *v1alpha3.GitRepository) (client *scm.Cli
 	if spec.Secret != nil && spec.Secret.Namespace == "" {
 		spec.Secret.Namespace = repo.Namespace
 	}
-	return git.NewClientFactory(provider, spec.Secret, r.Client).GetClient()
+	factory := git.NewClientFactory(provider, spec.Secret, k8sClient)
+	factory.Server = spec.Server
+	return factory.GetClient()
 }
 
 func (r *Reconciler) getTokenFromSecret(secretRef *v1.SecretReference, defaultNamespace string) (token string, err error) {
@@ -263,5 +299,7 @@ func (r *Reconciler) SetupWithManager(mgr ctrl.Manager) error {
 	return ctrl.NewControllerManagedBy(mgr).
 		Named("git_repository_webhook_controller").
 		For(&v1alpha3.GitRepository{}).
+		// the status is updated by itself and the status controller, only the changes of the spec and the signal are concerned
+		WithEventFilter(predicate.Or(predicate.GenerationChangedPredicate{}, predicate.AnnotationChangedPredicate{})).
 		Complete(r)
 }
@@ -832,7 +832,7 @@ func TestReconciler_Reconcile(t *testing.T) {
 	}, {
 		name: "normal case",
 		fields: fields{
-			Client: fake.NewClientBuilder().WithScheme(schema).WithObjects(repoWithSecret.DeepCopy(), secret.DeepCopy(), webhook.DeepCopy()).Build(),
+			Client: fake.NewClientBuilder().WithScheme(schema).WithStatusSubresource(&v1alpha3.GitRepository{}).WithObjects(repoWithSecret.DeepCopy(), secret.DeepCopy(), webhook.DeepCopy()).Build(),
 		},
 		args: args{req: req},
 		prepare: func() {
@@ -877,8 +877,9 @@ func TestReconciler_Reconcile(t *testing.T) {
 			defer gock.Off()
 
 			r := &Reconciler{
-				Client: tt.fields.Client,
-				log:    logr.New(log.NullLogSink{}),
+				Client:   tt.fields.Client,
+				log:      logr.New(log.NullLogSink{}),
+				recorder: record.NewFakeRecorder(10),
 			}
 			if tt.prepare != nil {
 				tt.prepare()
@@ -50,6 +50,18 @@ func TestInterfaceImplement(t *testing.T) {
 			NamedReconciler: &WebhookReconciler{},
 			GroupReconciler: &WebhookReconciler{},
 		},
+	}, {
+		name: "StatusReconciler",
+		instance: interInstance{
+			NamedReconciler: &StatusReconciler{},
+			GroupReconciler: &StatusReconciler{},
+		},
+	}, {
+		name: "SettingsReconciler",
+		instance: interInstance{
+			NamedReconciler: &SettingsReconciler{},
+			GroupReconciler: &SettingsReconciler{},
+		},
 	}, {
 		name: "PullRequestStatusReconciler",
 		instance: interInstance{
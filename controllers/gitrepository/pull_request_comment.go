@@ -0,0 +1,281 @@
+/*
+Copyright 2022 The KubeSphere Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gitrepository
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/jenkins-x/go-scm/scm"
+	"github.com/jenkins-x/go-scm/scm/factory"
+	"github.com/kubesphere/ks-devops/pkg/api/devops/v1alpha3"
+	"github.com/kubesphere/ks-devops/pkg/models/pipelinerun"
+	"github.com/kubesphere/ks-devops/pkg/utils/stringutils"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// logTailLines is the number of lines of the failed step log in the comment
+const logTailLines = 30
+
+// TestSummary is the test counts of a build
+type TestSummary struct {
+	Total   int64
+	Passed  int64
+	Failed  int64
+	Skipped int64
+}
+
+// QualityGate is the result of a SonarQube analysis
+type QualityGate struct {
+	// Status is the status of the quality gate, such as OK and ERROR. It is empty if unknown
+	Status       string
+	DashboardURL string
+}
+
+// BuildDetails contains the details of a build which are not stored in the PipelineRun
+type BuildDetails struct {
+	Tests        *TestSummary
+	QualityGates []QualityGate
+}
+
+// BuildDetailGetter gets the details of a build from the CI server
+type BuildDetailGetter interface {
+	// GetBuildDetails returns the test counts and quality gates of a PipelineRun
+	GetBuildDetails(run *v1alpha3.PipelineRun) (*BuildDetails, error)
+	// GetStepLog returns the log of a step
+	GetStepLog(run *v1alpha3.PipelineRun, nodeID, stepID string) (string, error)
+}
+
+// failedStep is a failed step and the tail of its log
+type failedStep struct {
+	stage string
+	step  string
+	log   string
+}
+
+// getPipelineFullName returns the namespace and name of the Pipeline of a PipelineRun
+func getPipelineFullName(run *v1alpha3.PipelineRun) string {
+	pipelineName := run.GetLabels()[v1alpha3.PipelineNameLabelKey]
+	if pipelineName == "" && run.Spec.PipelineRef != nil {
+		pipelineName = run.Spec.PipelineRef.Name
+	}
+	return fmt.Sprintf("%s/%s", run.Namespace, pipelineName)
+}
+
+// commentMarker returns the hidden mark of the comment of a Pipeline, there is only one comment per Pipeline
+func commentMarker(run *v1alpha3.PipelineRun) string {
+	return fmt.Sprintf("<!-- ks-devops/pipeline: %s -->", getPipelineFullName(run))
+}
+
+// buildComment renders the summary of a PipelineRun as Markdown
+func buildComment(run *v1alpha3.PipelineRun, target string, stages []pipelinerun.NodeDetail,
+	details *BuildDetails, failed *failedStep) string {
+	builder := &strings.Builder{}
+	builder.WriteString(commentMarker(run) + "\n")
+	builder.WriteString(fmt.Sprintf("### %s %s: `%s`\n\n", phaseEmoji(run.Status.Phase), statusLabel, getPipelineFullName(run)))
+	builder.WriteString(fmt.Sprintf("**%s**", run.Status.Phase))
+	if duration := getRunDuration(run); duration > 0 {
+		builder.WriteString(fmt.Sprintf(" in %s", duration.String()))
+	}
+	builder.WriteString(fmt.Sprintf(" · [Details](%s)\n", target))
+
+	if len(stages) > 0 {
+		builder.WriteString("\n| Stage | Result | Duration |\n|---|---|---|\n")
+		for _, stage := range stages {
+			result := strings.ToLower(stage.Result)
+			if stage.State != "FINISHED" {
+				result = strings.ToLower(stage.State)
+			}
+			duration := time.Duration(stage.DurationInMillis) * time.Millisecond
+			builder.WriteString(fmt.Sprintf("| %s | %s %s | %s |\n", stage.DisplayName,
+				stageEmoji(convertStageToSCMStatus(stage.State, stage.Result)), result, duration.String()))
+		}
+	}
+
+	if details != nil {
+		if details.Tests != nil {
+			builder.WriteString(fmt.Sprintf("\n**Tests**: %d total, %d passed, %d failed, %d skipped\n",
+				details.Tests.Total, details.Tests.Passed, details.Tests.Failed, details.Tests.Skipped))
+		}
+		for _, gate := range details.QualityGates {
+			status := gate.Status
+			if status == "" {
+				status = "unknown"
+			}
+			builder.WriteString(fmt.Sprintf("\n**SonarQube quality gate**: %s", status))
+			if gate.DashboardURL != "" {
+				builder.WriteString(fmt.Sprintf(" ([dashboard](%s))", gate.DashboardURL))
+			}
+			builder.WriteString("\n")
+		}
+	}
+
+	if failed != nil && failed.log != "" {
+		builder.WriteString(fmt.Sprintf("\n<details><summary>The log of the failed step <code>%s</code> in stage <code>%s</code></summary>\n\n",
+			failed.step, failed.stage))
+		builder.WriteString("```\n" + tailLines(failed.log, logTailLines) + "\n```\n</details>\n")
+	}
+	return builder.String()
+}
+
+// getFailedStep returns the first failed step of the stages
+func getFailedStep(stages []pipelinerun.NodeDetail) (nodeID, stepID string, failed *failedStep) {
+	for _, stage := range stages {
+		if stage.Result != "FAILURE" {
+			continue
+		}
+		for _, step := range stage.Steps {
+			if step.Result == "FAILURE" {
+				return stage.ID, step.ID, &failedStep{
+					stage: stage.DisplayName,
+					step:  stringutils.SetOrDefault(step.DisplayDescription, step.DisplayName),
+				}
+			}
+		}
+	}
+	return
+}
+
+func getRunDuration(run *v1alpha3.PipelineRun) (duration time.Duration) {
+	if run.Status.StartTime != nil && run.Status.CompletionTime != nil {
+		duration = run.Status.CompletionTime.Sub(run.Status.StartTime.Time).Round(time.Second)
+	}
+	return
+}
+
+func tailLines(text string, count int) string {
+	lines := strings.Split(strings.TrimRight(text, "\n"), "\n")
+	if len(lines) > count {
+		lines = lines[len(lines)-count:]
+	}
+	return strings.Join(lines, "\n")
+}
+
+func phaseEmoji(phase v1alpha3.RunPhase) string {
+	return stageEmoji(convertPipelineRunPhaseToSCMStatus(phase))
+}
+
+func stageEmoji(state scm.State) string {
+	switch state {
+	case scm.StateSuccess:
+		return ":white_check_mark:"
+	case scm.StateFailure, scm.StateError:
+		return ":x:"
+	case scm.StateCanceled:
+		return ":no_entry_sign:"
+	case scm.StateRunning, scm.StatePending:
+		return ":hourglass:"
+	default:
+		return ":grey_question:"
+	}
+}
+
+// needComment returns true if the GitRepository of the Pipeline opts in the pull request comment
+func (r *PullRequestStatusReconciler) needComment(ctx context.Context, run *v1alpha3.PipelineRun,
+	gitLabServers v1alpha3.GitLabServers) (need bool, err error) {
+	repoURL := run.Spec.PipelineSpec.MultiBranchPipeline.ResolveGitURL(gitLabServers)
+	repoList := &v1alpha3.GitRepositoryList{}
+	if err = r.List(ctx, repoList, client.InNamespace(run.Namespace)); err != nil {
+		return
+	}
+	for _, repo := range repoList.Items {
+		if repo.Spec.PullRequestComment &&
+			v1alpha3.GitURLMatch(repo.Spec.URL, repoURL.Web, repoURL.Clone, repoURL.CloneSSH) {
+			need = true
+			break
+		}
+	}
+	return
+}
+
+// comment creates or updates the summary comment of the PipelineRun
+func (r *PullRequestStatusReconciler) comment(ctx context.Context, maker *StatusMaker, run *v1alpha3.PipelineRun,
+	target string, stages []pipelinerun.NodeDetail) (err error) {
+	var details *BuildDetails
+	var failed *failedStep
+	if r.BuildDetailGetter != nil && run.HasCompleted() {
+		if details, err = r.BuildDetailGetter.GetBuildDetails(run); err != nil {
+			r.log.Error(err, "failed to get the build details")
+		}
+
+		var nodeID, stepID string
+		if nodeID, stepID, failed = getFailedStep(stages); failed != nil {
+			if failed.log, err = r.BuildDetailGetter.GetStepLog(run, nodeID, stepID); err != nil {
+				r.log.Error(err, "failed to get the log of the failed step")
+			}
+		}
+	}
+	return maker.Comment(ctx, commentMarker(run), buildComment(run, target, stages, details, failed))
+}
+
+var commentTargetReg = regexp.MustCompile(`\[Details\]\(([^)]+)\)`)
+
+// Comment creates a comment on the pull request, or updates the existing one which has the same marker
+func (s *StatusMaker) Comment(ctx context.Context, marker, body string) (err error) {
+	var scmClient *scm.Client
+	if scmClient, err = factory.NewClient(s.provider, s.server, s.token, func(c *scm.Client) {
+		c.Username = s.username
+	}); err != nil {
+		return
+	}
+
+	var existing *scm.Comment
+	if existing, err = findComment(ctx, scmClient, s.repo, s.pr, marker); err != nil {
+		return
+	}
+
+	input := &scm.CommentInput{Body: body}
+	if existing != nil {
+		// avoid the comment of a previous PipelineRun overriding the newer one
+		if matches := commentTargetReg.FindStringSubmatch(existing.Body); len(matches) == 2 &&
+			s.expirationCheck(&scm.Status{Target: matches[1]}, &scm.StatusInput{Target: s.target}) {
+			return
+		}
+		if existing.Body != body {
+			_, _, err = scmClient.PullRequests.EditComment(ctx, s.repo, s.pr, existing.ID, input)
+		}
+		return
+	}
+	_, _, err = scmClient.PullRequests.CreateComment(ctx, s.repo, s.pr, input)
+	return
+}
+
+// findComment walks through all the comments of the pull request, and returns the one which has the marker
+func findComment(ctx context.Context, scmClient *scm.Client, repo string, pr int, marker string) (comment *scm.Comment, err error) {
+	opts := &scm.ListOptions{Page: 1, Size: 100}
+	for {
+		var comments []*scm.Comment
+		var resp *scm.Response
+		if comments, resp, err = scmClient.PullRequests.ListComments(ctx, repo, pr, opts); err != nil {
+			err = fmt.Errorf("failed to list the comments, error: %v", err)
+			return
+		}
+		for _, item := range comments {
+			if strings.Contains(item.Body, marker) {
+				comment = item
+				return
+			}
+		}
+		if resp == nil || resp.Page.Next == 0 || len(comments) == 0 {
+			return
+		}
+		opts.Page = resp.Page.Next
+	}
+}
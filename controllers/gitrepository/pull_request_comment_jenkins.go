@@ -0,0 +1,163 @@
+/*
+Copyright 2022 The KubeSphere Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gitrepository
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/jenkins-zh/jenkins-client/pkg/core"
+	"github.com/kubesphere/ks-devops/pkg/api/devops/v1alpha3"
+	"github.com/kubesphere/ks-devops/pkg/client/devops/sharding"
+	"github.com/kubesphere/ks-devops/pkg/client/sonarqube"
+)
+
+const (
+	// testResultActionClass is the class of the JUnit test result in Jenkins
+	testResultActionClass = "hudson.tasks.junit.TestResultAction"
+	// sonarQualityGateMetric is the metric key of the quality gate status in SonarQube
+	sonarQualityGateMetric = "alert_status"
+)
+
+// JenkinsBuildDetailGetter gets the build details from Jenkins
+type JenkinsBuildDetailGetter struct {
+	JenkinsCore core.JenkinsCore
+	// JenkinsCores is optional, it finds the Jenkins instance of the DevOps projects
+	JenkinsCores sharding.CoreGetter
+	// Sonar is optional, the status of the quality gates is unknown without it
+	Sonar sonarqube.SonarInterface
+}
+
+// jenkinsBuild is the part of a Jenkins build which is about the tests and SonarQube
+type jenkinsBuild struct {
+	Actions []struct {
+		Class             string `json:"_class"`
+		FailCount         int64  `json:"failCount"`
+		SkipCount         int64  `json:"skipCount"`
+		TotalCount        int64  `json:"totalCount"`
+		SonarTaskID       string `json:"ceTaskId"`
+		SonarDashboardURL string `json:"sonarqubeDashboardUrl"`
+	} `json:"actions"`
+}
+
+// GetBuildDetails returns the test counts and quality gates of a PipelineRun
+func (g *JenkinsBuildDetailGetter) GetBuildDetails(run *v1alpha3.PipelineRun) (details *BuildDetails, err error) {
+	var api string
+	if api, err = getJenkinsBuildPath(run); err != nil {
+		return
+	}
+
+	var jenkinsCore core.JenkinsCore
+	if jenkinsCore, err = sharding.GetJenkinsCore(g.JenkinsCores, run.Namespace, g.JenkinsCore); err != nil {
+		return
+	}
+
+	build := &jenkinsBuild{}
+	if err = jenkinsCore.RequestWithData(http.MethodGet, api+"api/json", nil, nil, http.StatusOK, build); err != nil {
+		return
+	}
+
+	details = &BuildDetails{}
+	for _, action := range build.Actions {
+		switch {
+		case action.Class == testResultActionClass:
+			details.Tests = &TestSummary{
+				Total:   action.TotalCount,
+				Failed:  action.FailCount,
+				Skipped: action.SkipCount,
+				Passed:  action.TotalCount - action.FailCount - action.SkipCount,
+			}
+		case action.Class == sonarqube.SonarAnalysisActionClass && action.SonarTaskID != "":
+			details.QualityGates = append(details.QualityGates, QualityGate{
+				Status:       g.getQualityGateStatus(action.SonarTaskID),
+				DashboardURL: action.SonarDashboardURL,
+			})
+		}
+	}
+	return
+}
+
+// GetStepLog returns the log of a step
+func (g *JenkinsBuildDetailGetter) GetStepLog(run *v1alpha3.PipelineRun, nodeID, stepID string) (log string, err error) {
+	var api string
+	if api, err = getJenkinsBuildPath(run); err != nil {
+		return
+	}
+
+	var jenkinsCore core.JenkinsCore
+	if jenkinsCore, err = sharding.GetJenkinsCore(g.JenkinsCores, run.Namespace, g.JenkinsCore); err != nil {
+		return
+	}
+
+	var data []byte
+	var statusCode int
+	if statusCode, data, err = jenkinsCore.Request(http.MethodGet,
+		fmt.Sprintf("%sexecution/node/%s/wfapi/log", api, url.PathEscape(stepID)), nil, nil); err == nil {
+		if statusCode != http.StatusOK {
+			err = fmt.Errorf("unexpected status code %d when getting the log of step %s in node %s", statusCode, stepID, nodeID)
+			return
+		}
+		stepLog := struct {
+			Text string `json:"text"`
+		}{}
+		if err = json.Unmarshal(data, &stepLog); err == nil {
+			log = stepLog.Text
+		}
+	}
+	return
+}
+
+func (g *JenkinsBuildDetailGetter) getQualityGateStatus(taskID string) (status string) {
+	if g.Sonar == nil {
+		return
+	}
+
+	results, err := g.Sonar.GetSonarResultsByTaskIds(taskID)
+	if err != nil {
+		return
+	}
+	for _, result := range results {
+		if result.Measures == nil || result.Measures.Component == nil {
+			continue
+		}
+		for _, measure := range result.Measures.Component.Measures {
+			if measure.Metric == sonarQualityGateMetric {
+				status = measure.Value
+				return
+			}
+		}
+	}
+	return
+}
+
+// getJenkinsBuildPath returns the path of the Jenkins build of a PipelineRun, such as: /job/ns/job/pipeline/job/master/1/
+func getJenkinsBuildPath(run *v1alpha3.PipelineRun) (api string, err error) {
+	runID, ok := run.GetPipelineRunID()
+	if !ok || run.Spec.PipelineRef == nil {
+		err = fmt.Errorf("no Jenkins build found for PipelineRun %s/%s", run.Namespace, run.Name)
+		return
+	}
+
+	api = fmt.Sprintf("/job/%s/job/%s/", url.PathEscape(run.Namespace), url.PathEscape(run.Spec.PipelineRef.Name))
+	if run.Spec.IsMultiBranchPipeline() && run.Spec.SCM != nil {
+		api += fmt.Sprintf("job/%s/", url.PathEscape(url.PathEscape(run.Spec.SCM.RefName)))
+	}
+	api += fmt.Sprintf("%s/", runID)
+	return
+}
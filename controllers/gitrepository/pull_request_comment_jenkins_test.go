@@ -0,0 +1,161 @@
+/*
+Copyright 2022 The KubeSphere Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gitrepository
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/jenkins-zh/jenkins-client/pkg/core"
+	"github.com/kubesphere/ks-devops/pkg/api/devops/v1alpha3"
+	"github.com/kubesphere/ks-devops/pkg/client/sonarqube"
+	sonargo "github.com/kubesphere/sonargo/sonar"
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeSonar struct {
+	status string
+	err    error
+}
+
+func (s *fakeSonar) GetSonarResultsByTaskIds(taskIDs ...string) ([]*sonarqube.SonarStatus, error) {
+	return []*sonarqube.SonarStatus{{}, {
+		Measures: &sonargo.MeasuresComponentObject{Component: &sonargo.Component{
+			Measures: []*sonargo.SonarMeasure{{Metric: "bugs", Value: "0"}, {Metric: "alert_status", Value: s.status}},
+		}},
+	}}, s.err
+}
+
+func newJenkinsBuildTestRun(multiBranch bool) *v1alpha3.PipelineRun {
+	run := newCommentTestRun()
+	run.SetAnnotations(map[string]string{v1alpha3.JenkinsPipelineRunIDAnnoKey: "3"})
+	if multiBranch {
+		run.Spec.PipelineSpec = &v1alpha3.PipelineSpec{Type: v1alpha3.MultiBranchPipelineType}
+		run.Spec.SCM = &v1alpha3.SCM{RefName: "feature/login"}
+	}
+	return run
+}
+
+func Test_getJenkinsBuildPath(t *testing.T) {
+	api, err := getJenkinsBuildPath(newJenkinsBuildTestRun(false))
+	assert.Nil(t, err)
+	assert.Equal(t, "/job/ns/job/demo/3/", api)
+
+	api, err = getJenkinsBuildPath(newJenkinsBuildTestRun(true))
+	assert.Nil(t, err)
+	assert.Equal(t, "/job/ns/job/demo/job/feature%252Flogin/3/", api)
+
+	_, err = getJenkinsBuildPath(newCommentTestRun())
+	assert.NotNil(t, err)
+}
+
+func TestJenkinsBuildDetailGetter(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.EscapedPath() {
+		case "/job/ns/job/demo/3/api/json":
+			_, _ = fmt.Fprint(w, `{"actions": [{}, {
+"_class": "hudson.tasks.junit.TestResultAction", "failCount": 1, "skipCount": 2, "totalCount": 10}, {
+"_class": "hudson.plugins.sonar.action.SonarAnalysisAction", "ceTaskId": "task",
+"sonarqubeDashboardUrl": "https://sonar.example.com/dashboard?id=demo"}]}`)
+		case "/job/ns/job/demo/3/execution/node/4/wfapi/log":
+			_, _ = fmt.Fprint(w, `{"nodeId": "4", "text": "make: *** [test] Error 1"}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	getter := &JenkinsBuildDetailGetter{JenkinsCore: core.JenkinsCore{URL: server.URL}}
+	run := newJenkinsBuildTestRun(false)
+
+	details, err := getter.GetBuildDetails(run)
+	assert.Nil(t, err)
+	assert.Equal(t, &BuildDetails{
+		Tests:        &TestSummary{Total: 10, Passed: 7, Failed: 1, Skipped: 2},
+		QualityGates: []QualityGate{{DashboardURL: "https://sonar.example.com/dashboard?id=demo"}},
+	}, details)
+
+	getter.Sonar = &fakeSonar{status: "OK"}
+	details, err = getter.GetBuildDetails(run)
+	assert.Nil(t, err)
+	assert.Equal(t, []QualityGate{{Status: "OK", DashboardURL: "https://sonar.example.com/dashboard?id=demo"}},
+		details.QualityGates)
+
+	getter.Sonar = &fakeSonar{err: fmt.Errorf("fake")}
+	details, err = getter.GetBuildDetails(run)
+	assert.Nil(t, err)
+	assert.Empty(t, details.QualityGates[0].Status)
+
+	stepLog, err := getter.GetStepLog(run, "2", "4")
+	assert.Nil(t, err)
+	assert.Equal(t, "make: *** [test] Error 1", stepLog)
+
+	_, err = getter.GetStepLog(run, "2", "5")
+	assert.NotNil(t, err)
+
+	_, err = getter.GetBuildDetails(newJenkinsBuildTestRun(true))
+	assert.NotNil(t, err)
+}
+
+type fakeCoreGetter struct {
+	cores map[string]core.JenkinsCore
+}
+
+func (g *fakeCoreGetter) GetJenkinsCore(namespace string) (jenkinsCore core.JenkinsCore, err error) {
+	var ok bool
+	if jenkinsCore, ok = g.cores[namespace]; !ok {
+		err = fmt.Errorf("no Jenkins instance found for namespace %s", namespace)
+	}
+	return
+}
+
+func TestJenkinsBuildDetailGetter_sharding(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.EscapedPath() {
+		case "/job/ns/job/demo/3/api/json":
+			_, _ = fmt.Fprint(w, `{"actions": []}`)
+		case "/job/ns/job/demo/3/execution/node/4/wfapi/log":
+			_, _ = fmt.Fprint(w, `{"nodeId": "4", "text": "done"}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	// the default Jenkins is not accessible, the builds are on the instance of the DevOps project
+	getter := &JenkinsBuildDetailGetter{
+		JenkinsCore:  core.JenkinsCore{URL: "http://127.0.0.1:0"},
+		JenkinsCores: &fakeCoreGetter{cores: map[string]core.JenkinsCore{"ns": {URL: server.URL}}},
+	}
+	run := newJenkinsBuildTestRun(false)
+
+	details, err := getter.GetBuildDetails(run)
+	assert.Nil(t, err)
+	assert.Equal(t, &BuildDetails{}, details)
+
+	stepLog, err := getter.GetStepLog(run, "2", "4")
+	assert.Nil(t, err)
+	assert.Equal(t, "done", stepLog)
+
+	run.Namespace = "unknown"
+	_, err = getter.GetBuildDetails(run)
+	assert.NotNil(t, err)
+	_, err = getter.GetStepLog(run, "2", "4")
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,273 @@
+/*
+Copyright 2022 The KubeSphere Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gitrepository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/h2non/gock"
+	"github.com/jenkins-x/go-scm/scm"
+	"github.com/jenkins-zh/jenkins-client/pkg/job"
+	"github.com/kubesphere/ks-devops/pkg/api/devops/v1alpha3"
+	"github.com/kubesphere/ks-devops/pkg/models/pipelinerun"
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+func newCommentTestRun() *v1alpha3.PipelineRun {
+	start := metav1.NewTime(time.Date(2022, 10, 16, 15, 0, 0, 0, time.UTC))
+	end := metav1.NewTime(start.Add(3*time.Minute + 20*time.Second))
+	run := &v1alpha3.PipelineRun{}
+	run.SetName("demo-abcde")
+	run.SetNamespace("ns")
+	run.SetLabels(map[string]string{v1alpha3.PipelineNameLabelKey: "demo"})
+	run.Spec.PipelineRef = &v1.ObjectReference{Name: "demo"}
+	run.Status.Phase = v1alpha3.Failed
+	run.Status.StartTime = &start
+	run.Status.CompletionTime = &end
+	return run
+}
+
+var commentTestStages = []pipelinerun.NodeDetail{{
+	Node: job.Node{ID: "1", DisplayName: "build", State: "FINISHED", Result: "SUCCESS", DurationInMillis: 62000},
+}, {
+	Node: job.Node{ID: "2", DisplayName: "test", State: "FINISHED", Result: "FAILURE", DurationInMillis: 1500},
+	Steps: []pipelinerun.Step{
+		{Step: job.Step{ID: "3", DisplayName: "Shell Script", Result: "SUCCESS"}},
+		{Step: job.Step{ID: "4", DisplayName: "Shell Script", DisplayDescription: "make test", Result: "FAILURE"}},
+	},
+}, {
+	Node: job.Node{ID: "5", DisplayName: "deploy", State: "NOT_BUILT", Result: "NOT_BUILT"},
+}}
+
+func Test_buildComment(t *testing.T) {
+	run := newCommentTestRun()
+
+	comment := buildComment(run, "https://ks.example.com/run", commentTestStages, &BuildDetails{
+		Tests: &TestSummary{Total: 10, Passed: 8, Failed: 1, Skipped: 1},
+		QualityGates: []QualityGate{{Status: "ERROR", DashboardURL: "https://sonar.example.com/dashboard?id=demo"},
+			{}},
+	}, &failedStep{stage: "test", step: "make test", log: "line1\nline2\n"})
+	assert.Equal(t, "<!-- ks-devops/pipeline: ns/demo -->\n"+
+		"### :x: KubeSphere DevOps: `ns/demo`\n\n"+
+		"**Failed** in 3m20s · [Details](https://ks.example.com/run)\n"+
+		"\n| Stage | Result | Duration |\n|---|---|---|\n"+
+		"| build | :white_check_mark: success | 1m2s |\n"+
+		"| test | :x: failure | 1.5s |\n"+
+		"| deploy | :grey_question: not_built | 0s |\n"+
+		"\n**Tests**: 10 total, 8 passed, 1 failed, 1 skipped\n"+
+		"\n**SonarQube quality gate**: ERROR ([dashboard](https://sonar.example.com/dashboard?id=demo))\n"+
+		"\n**SonarQube quality gate**: unknown\n"+
+		"\n<details><summary>The log of the failed step <code>make test</code> in stage <code>test</code></summary>\n\n"+
+		"```\nline1\nline2\n```\n</details>\n", comment)
+
+	// without any details
+	run.Status.Phase = v1alpha3.Running
+	run.Status.CompletionTime = nil
+	assert.Equal(t, "<!-- ks-devops/pipeline: ns/demo -->\n"+
+		"### :hourglass: KubeSphere DevOps: `ns/demo`\n\n"+
+		"**Running** · [Details](target)\n", buildComment(run, "target", nil, nil, nil))
+}
+
+func Test_getFailedStep(t *testing.T) {
+	nodeID, stepID, failed := getFailedStep(commentTestStages)
+	assert.Equal(t, "2", nodeID)
+	assert.Equal(t, "4", stepID)
+	assert.Equal(t, &failedStep{stage: "test", step: "make test"}, failed)
+
+	_, _, failed = getFailedStep(commentTestStages[:1])
+	assert.Nil(t, failed)
+}
+
+func Test_tailLines(t *testing.T) {
+	assert.Equal(t, "c\nd", tailLines("a\nb\nc\nd\n", 2))
+	assert.Equal(t, "a\nb", tailLines("a\nb", 5))
+}
+
+func TestStatusMaker_Comment(t *testing.T) {
+	const marker = "<!-- ks-devops/pipeline: ns/demo -->"
+	body := marker + "\n**Succeeded** · [Details](https://ks.example.com/new)"
+	tests := []struct {
+		name    string
+		prepare func()
+		maker   func() *StatusMaker
+		wantErr bool
+	}{{
+		name: "create a new comment",
+		prepare: func() {
+			gock.New("https://api.github.com").
+				Get("/repos/octocat/hello-world/issues/1347/comments").
+				Reply(200).
+				Type("application/json").
+				SetHeaders(mockHeaders).
+				BodyString(`[{"id": 1, "body": "LGTM"}]`)
+			gock.New("https://api.github.com").
+				Post("/repos/octocat/hello-world/issues/1347/comments").
+				Reply(201).
+				Type("application/json").
+				SetHeaders(mockHeaders).
+				BodyString(`{"id": 2}`)
+		},
+	}, {
+		name: "update the existing comment",
+		prepare: func() {
+			gock.New("https://api.github.com").
+				Get("/repos/octocat/hello-world/issues/1347/comments").
+				Reply(200).
+				Type("application/json").
+				SetHeaders(mockHeaders).
+				BodyString(`[{"id": 2, "body": "` + marker + `\n**Running** · [Details](https://ks.example.com/new)"}]`)
+			gock.New("https://api.github.com").
+				Patch("/repos/octocat/hello-world/issues/comments/2").
+				Reply(200).
+				Type("application/json").
+				SetHeaders(mockHeaders).
+				BodyString(`{"id": 2}`)
+		},
+	}, {
+		name: "update the existing comment on the second page",
+		prepare: func() {
+			gock.New("https://api.github.com").
+				Get("/repos/octocat/hello-world/issues/1347/comments").
+				MatchParam("page", "1").
+				Reply(200).
+				Type("application/json").
+				SetHeaders(mockHeaders).
+				SetHeader("Link", `<https://api.github.com/repos/octocat/hello-world/issues/1347/comments?page=2&per_page=100>; rel="next"`).
+				BodyString(`[{"id": 1, "body": "LGTM"}]`)
+			gock.New("https://api.github.com").
+				Get("/repos/octocat/hello-world/issues/1347/comments").
+				MatchParam("page", "2").
+				Reply(200).
+				Type("application/json").
+				SetHeaders(mockHeaders).
+				BodyString(`[{"id": 2, "body": "` + marker + `\n**Running** · [Details](https://ks.example.com/new)"}]`)
+			gock.New("https://api.github.com").
+				Patch("/repos/octocat/hello-world/issues/comments/2").
+				Reply(200).
+				Type("application/json").
+				SetHeaders(mockHeaders).
+				BodyString(`{"id": 2}`)
+		},
+	}, {
+		name: "the comment is up to date",
+		prepare: func() {
+			gock.New("https://api.github.com").
+				Get("/repos/octocat/hello-world/issues/1347/comments").
+				Reply(200).
+				Type("application/json").
+				SetHeaders(mockHeaders).
+				BodyString(`[{"id": 2, "body": "` + marker + `\n**Succeeded** · [Details](https://ks.example.com/new)"}]`)
+		},
+	}, {
+		name: "the comment belongs to a newer PipelineRun",
+		prepare: func() {
+			gock.New("https://api.github.com").
+				Get("/repos/octocat/hello-world/issues/1347/comments").
+				Reply(200).
+				Type("application/json").
+				SetHeaders(mockHeaders).
+				BodyString(`[{"id": 2, "body": "` + marker + `\n**Running** · [Details](https://ks.example.com/newer)"}]`)
+		},
+		maker: func() *StatusMaker {
+			maker := NewStatusMaker("octocat/hello-world", "").WithPR(1347).WithTarget("https://ks.example.com/new")
+			maker.WithExpirationCheck(func(previousStatus *scm.Status, _ *scm.StatusInput) bool {
+				return previousStatus.Target == "https://ks.example.com/newer"
+			})
+			return maker
+		},
+	}, {
+		name: "failed to list the comments",
+		prepare: func() {
+			gock.New("https://api.github.com").
+				Get("/repos/octocat/hello-world/issues/1347/comments").
+				Reply(500)
+		},
+		wantErr: true,
+	}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer gock.Off()
+			tt.prepare()
+
+			maker := NewStatusMaker("octocat/hello-world", "").WithPR(1347).WithTarget("https://ks.example.com/new")
+			if tt.maker != nil {
+				maker = tt.maker()
+			}
+			err := maker.Comment(context.Background(), marker, body)
+			if tt.wantErr {
+				assert.NotNil(t, err)
+			} else {
+				assert.Nil(t, err)
+				assert.True(t, gock.IsDone())
+			}
+		})
+	}
+}
+
+func TestPullRequestStatusReconciler_needComment(t *testing.T) {
+	schema, err := v1alpha3.SchemeBuilder.Register().Build()
+	assert.Nil(t, err)
+
+	run := newCommentTestRun()
+	run.Spec.PipelineSpec = &v1alpha3.PipelineSpec{
+		Type: v1alpha3.MultiBranchPipelineType,
+		MultiBranchPipeline: &v1alpha3.MultiBranchPipeline{
+			SourceType:   v1alpha3.SourceTypeGithub,
+			GitHubSource: &v1alpha3.GithubSource{Owner: "octocat", Repo: "hello-world"},
+		},
+	}
+
+	optIn := &v1alpha3.GitRepository{}
+	optIn.SetName("hello-world")
+	optIn.SetNamespace("ns")
+	optIn.Spec.URL = "https://github.com/octocat/hello-world.git"
+	optIn.Spec.PullRequestComment = true
+
+	optOut := optIn.DeepCopy()
+	optOut.Spec.PullRequestComment = false
+
+	otherNamespace := optIn.DeepCopy()
+	otherNamespace.SetNamespace("other")
+
+	tests := []struct {
+		name string
+		repo *v1alpha3.GitRepository
+		want bool
+	}{
+		{name: "opt in", repo: optIn, want: true},
+		{name: "opt out", repo: optOut, want: false},
+		{name: "in another namespace", repo: otherNamespace, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &PullRequestStatusReconciler{
+				Client: fake.NewClientBuilder().WithScheme(schema).WithObjects(tt.repo.DeepCopy()).Build(),
+				log:    logr.New(log.NullLogSink{}),
+			}
+			need, err := r.needComment(context.Background(), run, nil)
+			assert.Nil(t, err)
+			assert.Equal(t, tt.want, need)
+		})
+	}
+}
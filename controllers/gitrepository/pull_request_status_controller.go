@@ -26,6 +26,8 @@ import (
 	"github.com/jenkins-x/go-scm/scm"
 	"github.com/jenkins-x/go-scm/scm/factory"
 	"github.com/kubesphere/ks-devops/pkg/api/devops/v1alpha3"
+	"github.com/kubesphere/ks-devops/pkg/client/devops/jenkins"
+	"github.com/kubesphere/ks-devops/pkg/models/pipelinerun"
 	"github.com/kubesphere/ks-devops/pkg/utils/net"
 	v1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -35,11 +37,17 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
-// PullRequestStatusReconciler reconciles a Pipeline build status to the Pull Requests
+// PullRequestStatusReconciler reconciles a Pipeline build status to the Pull Requests and commits
 type PullRequestStatusReconciler struct {
 	client.Client
 	ExternalAddress string
 	ClusterName     string
+	// StageStatus indicates whether to report the status of each stage besides the whole PipelineRun
+	StageStatus bool
+	// BuildDetailGetter is optional, it provides the test counts, quality gates and logs for the pull request comment
+	BuildDetailGetter BuildDetailGetter
+	// JenkinsNamespace is the namespace of Jenkins, the GitLab servers are read from its configuration-as-code
+	JenkinsNamespace string
 
 	log      logr.Logger
 	recorder record.EventRecorder
@@ -47,6 +55,8 @@ type PullRequestStatusReconciler struct {
 
 //+kubebuilder:rbac:groups=devops.kubesphere.io,resources=webhooks,verbs=get;list;update;patch
 //+kubebuilder:rbac:groups=devops.kubesphere.io,resources=secrets,verbs=get
+//+kubebuilder:rbac:groups=devops.kubesphere.io,resources=gitrepositories,verbs=get;list
+//+kubebuilder:rbac:groups="",resources=configmaps,verbs=get;list;watch
 
 // Reconcile is the main entry of this reconciler
 func (r *PullRequestStatusReconciler) Reconcile(ctx context.Context, req ctrl.Request) (
@@ -57,22 +67,33 @@ func (r *PullRequestStatusReconciler) Reconcile(ctx context.Context, req ctrl.Re
 		return
 	}
 
-	if !pipelinerun.Spec.IsMultiBranchPipeline() || pipelinerun.Spec.SCM == nil || pipelinerun.Status.Phase == "" {
+	if pipelinerun.Status.Phase == "" {
 		return
 	}
 
-	r.log.Info(fmt.Sprintf("start to reconcile %s", req.NamespacedName))
+	// the status of a pull request goes to its head commit, branch and tag builds go to the commit which was built
 	var prNumber int
-	if prNumber, err = getPRNumber(pipelinerun.Spec.SCM.RefName); err != nil {
-		err = nil
+	if pipelinerun.Spec.IsMultiBranchPipeline() && pipelinerun.Spec.SCM != nil {
+		prNumber, _ = getPRNumber(pipelinerun.Spec.SCM.RefName)
+	}
+	sha := pipelinerun.GetAnnotations()[v1alpha3.PipelineRunCommitAnnoKey]
+	if prNumber <= 0 && sha == "" {
 		return
 	}
 
-	repoInfo := getRepoInfo(pipelinerun.Spec.PipelineSpec.MultiBranchPipeline)
+	var repoInfo repoInformation
+	var gitLabServers v1alpha3.GitLabServers
+	if pipelinerun.Spec.IsMultiBranchPipeline() {
+		gitLabServers = jenkins.GetGitLabServers(ctx, r.Client, r.JenkinsNamespace)
+		repoInfo = getRepoInfo(pipelinerun.Spec.PipelineSpec.MultiBranchPipeline, gitLabServers)
+	} else {
+		repoInfo = getRepoInfoFromAnnotations(pipelinerun.GetAnnotations())
+	}
 	if repoInfo.isInvalid() {
 		return
 	}
 
+	r.log.Info(fmt.Sprintf("start to reconcile %s", req.NamespacedName))
 	var (
 		token    string
 		username string
@@ -86,7 +107,11 @@ func (r *PullRequestStatusReconciler) Reconcile(ctx context.Context, req ctrl.Re
 	}
 
 	repo := repoInfo.getRepoPath()
-	r.log.Info(fmt.Sprintf("start sending status to %s with pr %d", repo, prNumber))
+	if prNumber > 0 {
+		r.log.Info(fmt.Sprintf("start sending status to %s with pr %d", repo, prNumber))
+	} else {
+		r.log.Info(fmt.Sprintf("start sending status to %s with commit %s", repo, sha))
+	}
 
 	var target string
 	if target, err = r.getExternalPipelineRunAddress(ctx, pipelinerun); err != nil {
@@ -94,7 +119,8 @@ func (r *PullRequestStatusReconciler) Reconcile(ctx context.Context, req ctrl.Re
 	}
 
 	maker := NewStatusMaker(repo, token)
-	maker.WithTarget(target).WithPR(prNumber).WithProvider(repoInfo.provider).WithUsername(username)
+	maker.WithTarget(target).WithPR(prNumber).WithSHA(sha).WithProvider(repoInfo.provider).WithServer(repoInfo.server).
+		WithUsername(username)
 	maker.WithExpirationCheck(createExpirationCheckFunc(ctx, r, pipelinerun.DeepCopy()))
 
 	var desc string
@@ -108,13 +134,82 @@ func (r *PullRequestStatusReconciler) Reconcile(ctx context.Context, req ctrl.Re
 		desc = string(pipelinerun.Status.Phase)
 	}
 
-	err = maker.CreateWithPipelinePhase(ctx, pipelinerun.Status.Phase, "KubeSphere DevOps", desc)
-	if err != nil {
+	statuses := []*scm.StatusInput{{
+		Label:  statusLabel,
+		State:  convertPipelineRunPhaseToSCMStatus(pipelinerun.Status.Phase),
+		Desc:   desc,
+		Target: target,
+	}}
+	stages := r.getStages(ctx, pipelinerun)
+	if r.StageStatus {
+		statuses = append(statuses, getStageStatuses(stages, target)...)
+	}
+
+	if err = maker.CreateAll(ctx, statuses); err != nil {
 		r.log.Error(err, "failed to send status")
 	}
+
+	if prNumber > 0 && pipelinerun.Spec.IsMultiBranchPipeline() {
+		needComment, commentErr := r.needComment(ctx, pipelinerun, gitLabServers)
+		if commentErr == nil && needComment {
+			commentErr = r.comment(ctx, maker, pipelinerun, target, stages)
+		}
+		if commentErr != nil {
+			r.log.Error(commentErr, "failed to comment the pull request")
+		}
+	}
 	return
 }
 
+// statusLabel is the label (context) of the status of a whole PipelineRun
+const statusLabel = "KubeSphere DevOps"
+
+// getStages returns the stages of the PipelineRun from its annotation or the data store
+func (r *PullRequestStatusReconciler) getStages(ctx context.Context, run *v1alpha3.PipelineRun) []pipelinerun.NodeDetail {
+	return pipelinerun.GetStages(ctx, r.Client, run)
+}
+
+// getStageStatuses converts the stages to the statuses, the stages which were not built are ignored
+func getStageStatuses(stages []pipelinerun.NodeDetail, target string) (statuses []*scm.StatusInput) {
+	for _, stage := range stages {
+		state := convertStageToSCMStatus(stage.State, stage.Result)
+		if state == scm.StateUnknown || stage.DisplayName == "" {
+			continue
+		}
+		statuses = append(statuses, &scm.StatusInput{
+			Label:  fmt.Sprintf("%s / %s", statusLabel, stage.DisplayName),
+			State:  state,
+			Desc:   strings.ToLower(stringutils.SetOrDefault(stage.Result, stage.State)),
+			Target: target,
+		})
+	}
+	return
+}
+
+// convertStageToSCMStatus converts the state and result of a Jenkins stage to the status
+func convertStageToSCMStatus(state, result string) scm.State {
+	switch state {
+	case "RUNNING", "PAUSED":
+		return scm.StateRunning
+	case "QUEUED":
+		return scm.StatePending
+	case "SKIPPED", "NOT_BUILT", "":
+		return scm.StateUnknown
+	}
+
+	switch result {
+	case "SUCCESS":
+		return scm.StateSuccess
+	case "FAILURE", "UNSTABLE":
+		return scm.StateFailure
+	case "ABORTED":
+		return scm.StateCanceled
+	case "UNKNOWN", "":
+		return scm.StatePending
+	}
+	return scm.StateUnknown
+}
+
 // createExpirationCheckFunc checks the start time of the PipelineRun
 func createExpirationCheckFunc(ctx context.Context, k8sClient client.Client, currentPipelineRun *v1alpha3.PipelineRun) expirationCheckFunc {
 	return func(previousStatus *scm.Status, currentStatus *scm.StatusInput) bool {
@@ -161,6 +256,7 @@ func getPipelineRunNameAndNsFromURL(link string) (name, ns string, err error) {
 
 type repoInformation struct {
 	provider string
+	server   string
 	owner    string
 	repo     string
 	tokenId  string
@@ -174,27 +270,46 @@ func (r repoInformation) isInvalid() bool {
 	return r.provider == "" || r.owner == "" || r.repo == "" || r.tokenId == ""
 }
 
-func getRepoInfo(repo *v1alpha3.MultiBranchPipeline) (info repoInformation) {
+func getRepoInfo(repo *v1alpha3.MultiBranchPipeline, gitLabServers v1alpha3.GitLabServers) (info repoInformation) {
 	if repo == nil {
 		return
 	}
 	switch repo.SourceType {
 	case v1alpha3.SourceTypeBitbucket:
 		if repo.BitbucketServerSource != nil {
-			info.provider = "bitbucket"
+			info.provider = "bitbucketcloud"
 			info.owner = repo.BitbucketServerSource.Owner
 			info.repo = repo.BitbucketServerSource.Repo
 			info.tokenId = repo.BitbucketServerSource.CredentialId
-			if repo.BitbucketServerSource.ApiUri == "https://bitbucket.org" {
-				info.provider = "bitbucketcloud"
+			if !repo.BitbucketServerSource.IsCloud() {
+				info.provider = "bitbucketserver"
+				info.server = repo.BitbucketServerSource.GetServerURL()
 			}
 		}
+	case v1alpha3.SourceTypeBitbucketCloud:
+		if repo.BitbucketCloudSource != nil {
+			info.provider = "bitbucketcloud"
+			info.owner = repo.BitbucketCloudSource.Owner
+			info.repo = repo.BitbucketCloudSource.Repo
+			info.tokenId = repo.BitbucketCloudSource.CredentialId
+		}
+	case v1alpha3.SourceTypeGitea:
+		if repo.GiteaSource != nil {
+			info.provider = "gitea"
+			info.server = repo.GiteaSource.ServerUrl
+			info.owner = repo.GiteaSource.Owner
+			info.repo = repo.GiteaSource.Repo
+			info.tokenId = repo.GiteaSource.CredentialId
+		}
 	case v1alpha3.SourceTypeGithub:
 		if repo.GitHubSource != nil {
 			info.provider = "github"
 			info.owner = repo.GitHubSource.Owner
 			info.repo = repo.GitHubSource.Repo
 			info.tokenId = repo.GitHubSource.CredentialId
+			if server := v1alpha3.GitHubServerURLFromAPI(repo.GitHubSource.ApiUri); server != v1alpha3.GitHubServerURL {
+				info.server = server
+			}
 		}
 	case v1alpha3.SourceTypeGitlab:
 		if repo.GitlabSource != nil {
@@ -203,17 +318,37 @@ func getRepoInfo(repo *v1alpha3.MultiBranchPipeline) (info repoInformation) {
 			// the repo format of Gitlab is: owner/repo
 			info.repo = strings.TrimPrefix(repo.GitlabSource.Repo, repo.GitlabSource.Owner+"/")
 			info.tokenId = repo.GitlabSource.CredentialId
+			if server := repo.GitlabSource.GetServerURL(gitLabServers); server != v1alpha3.GitLabServerURL {
+				info.server = server
+			}
 		}
 	}
 	return
 }
 
+// getRepoInfoFromAnnotations returns the repository of a PipelineRun which was triggered by a webhook
+func getRepoInfoFromAnnotations(annotations map[string]string) (info repoInformation) {
+	info.provider = annotations[v1alpha3.PipelineRunSCMProviderAnnoKey]
+	info.server = annotations[v1alpha3.PipelineRunSCMServerAnnoKey]
+	info.tokenId = annotations[v1alpha3.PipelineRunSCMCredentialAnnoKey]
+	if ownerAndRepo := strings.SplitN(annotations[v1alpha3.PipelineRunSCMRepoAnnoKey], "/", 2); len(ownerAndRepo) == 2 {
+		info.owner, info.repo = ownerAndRepo[0], ownerAndRepo[1]
+	}
+	return
+}
+
 func (r *PullRequestStatusReconciler) getExternalPipelineRunAddress(ctx context.Context, pipelineRun *v1alpha3.PipelineRun) (target string, err error) {
 	var ws string
 	if ws, err = r.getWorkspace(ctx, pipelineRun.GetNamespace()); err == nil {
-		target = fmt.Sprintf("%s/%s/clusters/%s/devops/%s/pipelines/%s/branch/%s/run/%s/task-status",
-			net.ParseURL(r.ExternalAddress), ws, r.ClusterName,
-			pipelineRun.Namespace, pipelineRun.Spec.PipelineRef.Name, pipelineRun.Spec.SCM.RefName, pipelineRun.Name)
+		if pipelineRun.Spec.SCM == nil {
+			target = fmt.Sprintf("%s/%s/clusters/%s/devops/%s/pipelines/%s/run/%s/task-status",
+				net.ParseURL(r.ExternalAddress), ws, r.ClusterName,
+				pipelineRun.Namespace, pipelineRun.Spec.PipelineRef.Name, pipelineRun.Name)
+		} else {
+			target = fmt.Sprintf("%s/%s/clusters/%s/devops/%s/pipelines/%s/branch/%s/run/%s/task-status",
+				net.ParseURL(r.ExternalAddress), ws, r.ClusterName,
+				pipelineRun.Namespace, pipelineRun.Spec.PipelineRef.Name, pipelineRun.Spec.SCM.RefName, pipelineRun.Name)
+		}
 	}
 	return
 }
@@ -309,6 +444,7 @@ type StatusMaker struct {
 	server   string
 	repo     string
 	pr       int
+	sha      string
 	token    string
 	username string
 	target   string
@@ -366,8 +502,24 @@ func (s *StatusMaker) WithPR(pr int) *StatusMaker {
 	return s
 }
 
+// WithSHA sets the commit SHA, it is used when there is no pull request
+func (s *StatusMaker) WithSHA(sha string) *StatusMaker {
+	s.sha = sha
+	return s
+}
+
 // Create creates a generic status
 func (s *StatusMaker) Create(ctx context.Context, status scm.State, label, desc string) (err error) {
+	return s.CreateAll(ctx, []*scm.StatusInput{{
+		Desc:   desc,
+		Label:  label,
+		State:  status,
+		Target: s.target,
+	}})
+}
+
+// CreateAll creates a set of statuses for the head commit of the pull request, or the commit SHA if there is no pull request
+func (s *StatusMaker) CreateAll(ctx context.Context, statuses []*scm.StatusInput) (err error) {
 	var scmClient *scm.Client
 	scmClient, err = factory.NewClient(s.provider, s.server, s.token, func(c *scm.Client) {
 		c.Username = s.username
@@ -376,22 +528,30 @@ func (s *StatusMaker) Create(ctx context.Context, status scm.State, label, desc
 		return
 	}
 
-	var pullRequest *scm.PullRequest
-	if pullRequest, _, err = scmClient.PullRequests.Find(ctx, s.repo, s.pr); err == nil {
-		var previousStatus *scm.Status
-		if previousStatus, err = s.FindPreviousStatus(ctx, scmClient, pullRequest.Sha, label); err != nil {
+	sha := s.sha
+	if s.pr > 0 {
+		var pullRequest *scm.PullRequest
+		if pullRequest, _, err = scmClient.PullRequests.Find(ctx, s.repo, s.pr); err != nil {
 			return
 		}
+		sha = pullRequest.Sha
+	}
+	if sha == "" {
+		err = fmt.Errorf("no commit found for the status of %s", s.repo)
+		return
+	}
 
-		currentStatus := &scm.StatusInput{
-			Desc:   desc,
-			Label:  label,
-			State:  status,
-			Target: s.target,
+	for _, currentStatus := range statuses {
+		var previousStatus *scm.Status
+		if previousStatus, err = s.FindPreviousStatus(ctx, scmClient, sha, currentStatus.Label); err != nil {
+			return
 		}
+
 		// avoid the previous building status override newer one
 		if !s.expirationCheck(previousStatus, currentStatus) {
-			_, _, err = scmClient.Repositories.CreateStatus(ctx, s.repo, pullRequest.Sha, currentStatus)
+			if _, _, err = scmClient.Repositories.CreateStatus(ctx, s.repo, sha, currentStatus); err != nil {
+				return
+			}
 		}
 	}
 	return
@@ -24,8 +24,10 @@ import (
 	"github.com/go-logr/logr"
 	"github.com/h2non/gock"
 	"github.com/jenkins-x/go-scm/scm"
+	"github.com/jenkins-zh/jenkins-client/pkg/job"
 	mgrcore "github.com/kubesphere/ks-devops/controllers/core"
 	"github.com/kubesphere/ks-devops/pkg/api/devops/v1alpha3"
+	"github.com/kubesphere/ks-devops/pkg/models/pipelinerun"
 	"github.com/stretchr/testify/assert"
 	v1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/types"
@@ -109,6 +111,37 @@ func TestCreatePullRequestStatus(t *testing.T) {
 			return maker
 		},
 		wantErr: true,
+	}, {
+		name: "commit without pull request",
+		createStatusMaker: func() *StatusMaker {
+			gock.New("https://api.github.com").
+				Post("/repos/octocat/hello-world/statuses/6dcb09b5b57875f334f61aebed695e2e4193db5e").
+				Reply(201).
+				Type("application/json").
+				SetHeaders(mockHeaders).
+				File("testdata/status.json")
+
+			gock.New("https://api.github.com").
+				Get("/repos/octocat/hello-world/statuses/6dcb09b5b57875f334f61aebed695e2e4193db5e").
+				MatchParam("page", "1").
+				MatchParam("per_page", "100").
+				Reply(200).
+				Type("application/json").
+				SetHeaders(mockHeaders).
+				SetHeaders(mockPageHeaders).
+				File("testdata/statuses.json")
+
+			maker := NewStatusMaker("octocat/hello-world", "")
+			maker.WithTarget("https://ci.example.com/1000/output").WithSHA("6dcb09b5b57875f334f61aebed695e2e4193db5e")
+			return maker
+		},
+		wantErr: false,
+	}, {
+		name: "neither pull request nor commit",
+		createStatusMaker: func() *StatusMaker {
+			return NewStatusMaker("octocat/hello-world", "")
+		},
+		wantErr: true,
 	}, {
 		name: "invalid go scm provider",
 		createStatusMaker: func() *StatusMaker {
@@ -240,13 +273,34 @@ func TestPullRequestStatusReconciler(t *testing.T) {
 		"kubesphere.io/workspace": "ws",
 	}
 
+	branchRun := pipRun.DeepCopy()
+	branchRun.Spec.SCM.RefName = "master"
+
+	commitRun := branchRun.DeepCopy()
+	commitRun.Annotations = map[string]string{
+		v1alpha3.PipelineRunCommitAnnoKey: "6dcb09b5b57875f334f61aebed695e2e4193db5e",
+		v1alpha3.JenkinsPipelineRunStagesStatusAnnoKey: `[{"displayName":"build","state":"FINISHED","result":"SUCCESS"},` +
+			`{"displayName":"test","state":"RUNNING"},{"displayName":"deploy","state":"NOT_BUILT"}]`,
+	}
+
+	webhookRun := pipRun.DeepCopy()
+	webhookRun.Spec.SCM = nil
+	webhookRun.Spec.PipelineSpec = &v1alpha3.PipelineSpec{Type: v1alpha3.NoScmPipelineType}
+	webhookRun.Annotations = map[string]string{
+		v1alpha3.PipelineRunCommitAnnoKey:        "6dcb09b5b57875f334f61aebed695e2e4193db5e",
+		v1alpha3.PipelineRunSCMProviderAnnoKey:   "github",
+		v1alpha3.PipelineRunSCMRepoAnnoKey:       "octocat/hello-world",
+		v1alpha3.PipelineRunSCMCredentialAnnoKey: "token",
+	}
+
 	tests := []struct {
-		name       string
-		request    request
-		prepare    func(*testing.T)
-		k8sClient  client.Client
-		wantResult ctrl.Result
-		wantErr    bool
+		name        string
+		request     request
+		prepare     func(*testing.T)
+		k8sClient   client.Client
+		stageStatus bool
+		wantResult  ctrl.Result
+		wantErr     bool
 	}{{
 		name:      "not found pipelinerun",
 		request:   defaultReq,
@@ -283,6 +337,60 @@ func TestPullRequestStatusReconciler(t *testing.T) {
 		},
 		k8sClient: fake.NewClientBuilder().WithScheme(schema).WithRuntimeObjects(pipRun.DeepCopy(), secret.DeepCopy(), project.DeepCopy()).Build(),
 		wantErr:   false,
+	}, {
+		name:      "branch without commit",
+		request:   defaultReq,
+		k8sClient: fake.NewClientBuilder().WithScheme(schema).WithRuntimeObjects(branchRun.DeepCopy(), secret.DeepCopy(), project.DeepCopy()).Build(),
+		wantErr:   false,
+	}, {
+		name:    "branch with commit and stages",
+		request: defaultReq,
+		prepare: func(t *testing.T) {
+			gock.New("https://api.github.com").
+				Post("/repos/octocat/hello-world/statuses/6dcb09b5b57875f334f61aebed695e2e4193db5e").
+				Times(3).
+				Reply(201).
+				Type("application/json").
+				SetHeaders(mockHeaders).
+				File("testdata/status.json")
+
+			gock.New("https://api.github.com").
+				Get("/repos/octocat/hello-world/statuses/6dcb09b5b57875f334f61aebed695e2e4193db5e").
+				Times(3).
+				MatchParam("page", "1").
+				MatchParam("per_page", "100").
+				Reply(200).
+				Type("application/json").
+				SetHeaders(mockHeaders).
+				SetHeaders(mockPageHeaders).
+				File("testdata/statuses.json")
+		},
+		k8sClient:   fake.NewClientBuilder().WithScheme(schema).WithRuntimeObjects(commitRun.DeepCopy(), secret.DeepCopy(), project.DeepCopy()).Build(),
+		stageStatus: true,
+		wantErr:     false,
+	}, {
+		name:    "webhook-triggered Pipeline",
+		request: defaultReq,
+		prepare: func(t *testing.T) {
+			gock.New("https://api.github.com").
+				Post("/repos/octocat/hello-world/statuses/6dcb09b5b57875f334f61aebed695e2e4193db5e").
+				Reply(201).
+				Type("application/json").
+				SetHeaders(mockHeaders).
+				File("testdata/status.json")
+
+			gock.New("https://api.github.com").
+				Get("/repos/octocat/hello-world/statuses/6dcb09b5b57875f334f61aebed695e2e4193db5e").
+				MatchParam("page", "1").
+				MatchParam("per_page", "100").
+				Reply(200).
+				Type("application/json").
+				SetHeaders(mockHeaders).
+				SetHeaders(mockPageHeaders).
+				File("testdata/statuses.json")
+		},
+		k8sClient: fake.NewClientBuilder().WithScheme(schema).WithRuntimeObjects(webhookRun.DeepCopy(), secret.DeepCopy(), project.DeepCopy()).Build(),
+		wantErr:   false,
 	}}
 	for i, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -292,8 +400,9 @@ func TestPullRequestStatusReconciler(t *testing.T) {
 				tt.prepare(t)
 			}
 			recon := &PullRequestStatusReconciler{
-				log:    logr.New(log.NullLogSink{}),
-				Client: tt.k8sClient,
+				log:         logr.New(log.NullLogSink{}),
+				Client:      tt.k8sClient,
+				StageStatus: tt.stageStatus,
 			}
 
 			result, err := recon.Reconcile(context.Background(), ctrl.Request{
@@ -309,6 +418,7 @@ func TestPullRequestStatusReconciler(t *testing.T) {
 			} else {
 				assert.Nil(t, err, "should not have error in case [%s]-[%d]", tt.name, i)
 			}
+			assert.True(t, gock.IsDone(), "not all the expected requests were sent in case [%s]", tt.name)
 		})
 	}
 }
@@ -436,10 +546,92 @@ func TestGetRepoInfo(t *testing.T) {
 			},
 		},
 		wantInfo: repoInformation{owner: "owner", repo: "repo", tokenId: "token", provider: "bitbucketcloud"},
+	}, {
+		name: "bitbucket server",
+		repo: &v1alpha3.MultiBranchPipeline{
+			SourceType: v1alpha3.SourceTypeBitbucket,
+			BitbucketServerSource: &v1alpha3.BitbucketServerSource{
+				ApiUri:       "https://bitbucket.example.com/",
+				Owner:        "PRJ",
+				Repo:         "repo",
+				CredentialId: "token",
+			},
+		},
+		wantInfo: repoInformation{owner: "PRJ", repo: "repo", tokenId: "token", provider: "bitbucketserver",
+			server: "https://bitbucket.example.com"},
+	}, {
+		name: "github enterprise",
+		repo: &v1alpha3.MultiBranchPipeline{
+			SourceType: v1alpha3.SourceTypeGithub,
+			GitHubSource: &v1alpha3.GithubSource{
+				ApiUri:       "https://ghe.example.com/api/v3",
+				Owner:        "owner",
+				Repo:         "repo",
+				CredentialId: "token",
+			},
+		},
+		wantInfo: repoInformation{owner: "owner", repo: "repo", tokenId: "token", provider: "github",
+			server: "https://ghe.example.com"},
+	}, {
+		name: "self-hosted gitlab",
+		repo: &v1alpha3.MultiBranchPipeline{
+			SourceType: v1alpha3.SourceTypeGitlab,
+			GitlabSource: &v1alpha3.GitlabSource{
+				ServerName:   "https://gitlab.example.com",
+				Owner:        "owner",
+				Repo:         "owner/repo",
+				CredentialId: "token",
+			},
+		},
+		wantInfo: repoInformation{owner: "owner", repo: "repo", tokenId: "token", provider: "gitlab",
+			server: "https://gitlab.example.com"},
+	}, {
+		name: "gitlab server configured in Jenkins",
+		repo: &v1alpha3.MultiBranchPipeline{
+			SourceType: v1alpha3.SourceTypeGitlab,
+			GitlabSource: &v1alpha3.GitlabSource{
+				ServerName:   "internal",
+				Owner:        "owner",
+				Repo:         "owner/repo",
+				CredentialId: "token",
+			},
+		},
+		wantInfo: repoInformation{owner: "owner", repo: "repo", tokenId: "token", provider: "gitlab",
+			server: "https://gitlab.internal.com"},
+	}, {
+		name: "bitbucket cloud",
+		repo: &v1alpha3.MultiBranchPipeline{
+			SourceType: v1alpha3.SourceTypeBitbucketCloud,
+			BitbucketCloudSource: &v1alpha3.BitbucketCloudSource{
+				Owner:        "owner",
+				Repo:         "repo",
+				CredentialId: "token",
+			},
+		},
+		wantInfo: repoInformation{owner: "owner", repo: "repo", tokenId: "token", provider: "bitbucketcloud"},
+	}, {
+		name: "gitea",
+		repo: &v1alpha3.MultiBranchPipeline{
+			SourceType: v1alpha3.SourceTypeGitea,
+			GiteaSource: &v1alpha3.GiteaSource{
+				ServerUrl:    "https://gitea.example.com",
+				Owner:        "owner",
+				Repo:         "repo",
+				CredentialId: "token",
+			},
+		},
+		wantInfo: repoInformation{owner: "owner", repo: "repo", tokenId: "token", provider: "gitea",
+			server: "https://gitea.example.com"},
+	}, {
+		name: "gitea without source",
+		repo: &v1alpha3.MultiBranchPipeline{
+			SourceType: v1alpha3.SourceTypeGitea,
+		},
+		wantInfo: emptyRepoInfo,
 	}}
 	for i, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			info := getRepoInfo(tt.repo)
+			info := getRepoInfo(tt.repo, v1alpha3.GitLabServers{"internal": "https://gitlab.internal.com"})
 			assert.Equal(t, tt.wantInfo, info, "failed in case [%d]", i)
 		})
 	}
@@ -534,3 +726,39 @@ func TestCreateExpirationCheckFunc(t *testing.T) {
 		})
 	}
 }
+
+func Test_getStageStatuses(t *testing.T) {
+	stages := []pipelinerun.NodeDetail{
+		{Node: job.Node{DisplayName: "build", State: "FINISHED", Result: "SUCCESS"}},
+		{Node: job.Node{DisplayName: "test", State: "FINISHED", Result: "UNSTABLE"}},
+		{Node: job.Node{DisplayName: "approve", State: "PAUSED", Result: "UNKNOWN"}},
+		{Node: job.Node{DisplayName: "release", State: "QUEUED"}},
+		{Node: job.Node{DisplayName: "cleanup", State: "FINISHED", Result: "ABORTED"}},
+		{Node: job.Node{DisplayName: "deploy", State: "SKIPPED", Result: "NOT_BUILT"}},
+		{Node: job.Node{State: "FINISHED", Result: "SUCCESS"}},
+	}
+	assert.Equal(t, []*scm.StatusInput{
+		{Label: "KubeSphere DevOps / build", State: scm.StateSuccess, Desc: "success", Target: "target"},
+		{Label: "KubeSphere DevOps / test", State: scm.StateFailure, Desc: "unstable", Target: "target"},
+		{Label: "KubeSphere DevOps / approve", State: scm.StateRunning, Desc: "unknown", Target: "target"},
+		{Label: "KubeSphere DevOps / release", State: scm.StatePending, Desc: "queued", Target: "target"},
+		{Label: "KubeSphere DevOps / cleanup", State: scm.StateCanceled, Desc: "aborted", Target: "target"},
+	}, getStageStatuses(stages, "target"))
+	assert.Empty(t, getStageStatuses(nil, "target"))
+}
+
+func Test_getRepoInfoFromAnnotations(t *testing.T) {
+	assert.Equal(t, repoInformation{
+		provider: "gitlab",
+		server:   "https://gitlab.example.com",
+		owner:    "group",
+		repo:     "sub/repo",
+		tokenId:  "token",
+	}, getRepoInfoFromAnnotations(map[string]string{
+		v1alpha3.PipelineRunSCMProviderAnnoKey:   "gitlab",
+		v1alpha3.PipelineRunSCMServerAnnoKey:     "https://gitlab.example.com",
+		v1alpha3.PipelineRunSCMRepoAnnoKey:       "group/sub/repo",
+		v1alpha3.PipelineRunSCMCredentialAnnoKey: "token",
+	}))
+	assert.True(t, getRepoInfoFromAnnotations(nil).isInvalid())
+}
@@ -23,5 +23,11 @@ func GetReconcilers(k8s client.Client) core.GroupedReconcilers {
 		&AmendReconciler{
 			Client: k8s,
 		},
+		&StatusReconciler{
+			Client: k8s,
+		},
+		&SettingsReconciler{
+			Client: k8s,
+		},
 	}
 }
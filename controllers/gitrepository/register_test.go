@@ -24,4 +24,12 @@ func TestGetReconcilers(t *testing.T) {
 		item := reconcilers[i]
 		assert.Equal(t, groupName, item.GetGroupName())
 	}
+
+	// the webhook controllers are not registered by default
+	for i := range reconcilers {
+		_, isWebhookReconciler := reconcilers[i].(*Reconciler)
+		assert.False(t, isWebhookReconciler)
+		_, isWebhookNotifier := reconcilers[i].(*WebhookReconciler)
+		assert.False(t, isWebhookNotifier)
+	}
 }
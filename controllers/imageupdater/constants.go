@@ -0,0 +1,27 @@
+/*
+Copyright 2022 The KubeSphere Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package imageupdater
+
+import "time"
+
+const (
+	// KindNative is the kind of the ImageUpdater which is handled by the built-in image updater
+	KindNative = "native"
+
+	// defaultInterval is the interval of checking the images if it's not specified
+	defaultInterval = 5 * time.Minute
+)
@@ -0,0 +1,241 @@
+/*
+Copyright 2022 The KubeSphere Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package imageupdater
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/kubesphere/ks-devops/pkg/api/devops/v1alpha3"
+	"github.com/kubesphere/ks-devops/pkg/api/gitops/v1alpha1"
+	"github.com/kubesphere/ks-devops/pkg/client/registry"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+)
+
+//+kubebuilder:rbac:groups=gitops.kubesphere.io,resources=imageupdaters,verbs=get;list;watch
+//+kubebuilder:rbac:groups=gitops.kubesphere.io,resources=imageupdaters/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups=devops.kubesphere.io,resources=gitrepositories,verbs=get;list;watch
+//+kubebuilder:rbac:groups="",resources=secrets,verbs=get
+//+kubebuilder:rbac:groups="",resources=events,verbs=create;patch
+
+// Reconciler scans the image registries and writes the new tags back to the git repository
+// for the ImageUpdater which kind is native, it does not depend on any external image updater
+type Reconciler struct {
+	client.Client
+	GitWriter GitWriter
+	// NewRegistryClient creates the registry client with the credential, the default one is registry.NewClient
+	NewRegistryClient func(username, password string) registry.Interface
+
+	log      logr.Logger
+	recorder record.EventRecorder
+}
+
+// Reconcile checks the latest version of images, and commits them to the git repository
+func (r *Reconciler) Reconcile(ctx context.Context, req ctrl.Request) (result ctrl.Result, err error) {
+	r.log.Info(fmt.Sprintf("start to reconcile imageUpdater: %s", req.String()))
+
+	updater := &v1alpha1.ImageUpdater{}
+	if err = r.Get(ctx, req.NamespacedName, updater); err != nil {
+		err = client.IgnoreNotFound(err)
+		return
+	}
+
+	// skip if kind is not native
+	if updater.Spec.Kind != KindNative {
+		r.log.V(7).Info(fmt.Sprintf("skip %s due to the spec.kind value is not %s", req.String(), KindNative))
+		return
+	}
+
+	native := updater.Spec.Native
+	if native == nil {
+		r.log.V(7).Info(fmt.Sprintf("skip %s due to the Native is nil", req.String()))
+		return
+	}
+	if native.Repo.Name == "" {
+		r.recorder.Eventf(updater, corev1.EventTypeWarning, "Missing", "git repository name is required")
+		return
+	}
+
+	interval := defaultInterval
+	if native.Interval != "" {
+		if interval, err = time.ParseDuration(native.Interval); err != nil {
+			r.recorder.Eventf(updater, corev1.EventTypeWarning, "InvalidInterval", "invalid interval: %v", err)
+			err = nil
+			return
+		}
+	}
+	result = ctrl.Result{RequeueAfter: interval}
+
+	var images []v1alpha1.ImageItem
+	if images, err = v1alpha1.ParseImages(updater.Spec.Images); err != nil {
+		r.recorder.Eventf(updater, corev1.EventTypeWarning, "InvalidImage", err.Error())
+		err = nil
+		return
+	}
+
+	status := updater.Status.DeepCopy()
+	refs := map[string]string{}
+	var messages []string
+	for _, image := range images {
+		var version imageVersion
+		if version, err = r.getImageVersion(ctx, updater, image); err != nil {
+			r.recorder.Eventf(updater, corev1.EventTypeWarning, "FailedToCheckImage",
+				"failed to check image %s, error is: %v", image.Image, err)
+			messages = append(messages, fmt.Sprintf("%s: %v", image.Alias, err))
+			err = nil
+			continue
+		}
+		refs[image.Image] = version.getReference(image.Image)
+		setImageStatus(status, image, version)
+	}
+
+	if len(refs) > 0 {
+		var commit string
+		commit, err = r.GitWriter.Write(ctx, types.NamespacedName{
+			Namespace: updater.GetNamespace(),
+			Name:      native.Repo.Name,
+		}, getBranch(native), getCommitMessage(refs), func(root string) (bool, error) {
+			return updateImagesInPath(root, native.Path, refs)
+		})
+		if err != nil {
+			r.recorder.Eventf(updater, corev1.EventTypeWarning, "FailedToWriteBack",
+				"failed to write the images back to git repository, error is: %v", err)
+			messages = append(messages, err.Error())
+			err = nil
+		} else if commit != "" {
+			status.LastCommit = commit
+			r.recorder.Eventf(updater, corev1.EventTypeNormal, "Updated", "images were updated in commit %s", commit)
+		}
+	}
+
+	now := metav1.Now()
+	status.LastCheckTime = &now
+	status.Message = strings.Join(messages, "; ")
+	updater.Status = *status
+	err = r.Status().Update(ctx, updater)
+	return
+}
+
+func (r *Reconciler) getImageVersion(ctx context.Context, updater *v1alpha1.ImageUpdater, image v1alpha1.ImageItem) (
+	version imageVersion, err error) {
+	native := updater.Spec.Native
+	var filter *tagFilter
+	if filter, err = newTagFilter(native.AllowTags[image.Alias], native.IgnoreTags[image.Alias]); err != nil {
+		return
+	}
+
+	var username, password string
+	if secretName := native.Secrets[image.Alias]; secretName != "" {
+		if username, password, err = r.getCredential(ctx, updater.GetNamespace(), secretName); err != nil {
+			return
+		}
+	}
+
+	newRegistryClient := r.NewRegistryClient
+	if newRegistryClient == nil {
+		newRegistryClient = func(username, password string) registry.Interface {
+			return registry.NewClient(username, password, nil)
+		}
+	}
+	version, err = selectVersion(ctx, newRegistryClient(username, password), image.Image, image.Constraint,
+		native.UpdateStrategy[image.Alias], filter)
+	return
+}
+
+// getCredential returns the username and password from a DevOps credential
+func (r *Reconciler) getCredential(ctx context.Context, namespace, name string) (username, password string, err error) {
+	secret := &corev1.Secret{}
+	if err = r.Get(ctx, types.NamespacedName{Namespace: namespace, Name: name}, secret); err != nil {
+		return
+	}
+
+	switch secret.Type {
+	case v1alpha3.SecretTypeBasicAuth, corev1.SecretTypeBasicAuth:
+		username = string(secret.Data[v1alpha3.BasicAuthUsernameKey])
+		password = string(secret.Data[v1alpha3.BasicAuthPasswordKey])
+	case v1alpha3.SecretTypeSecretText:
+		password = string(secret.Data[v1alpha3.SecretTextSecretKey])
+	default:
+		err = fmt.Errorf("unsupported credential type %q of %s/%s", secret.Type, namespace, name)
+	}
+	return
+}
+
+func setImageStatus(status *v1alpha1.ImageUpdaterStatus, image v1alpha1.ImageItem, version imageVersion) {
+	now := metav1.Now()
+	for i := range status.Images {
+		item := &status.Images[i]
+		if item.Alias != image.Alias {
+			continue
+		}
+		if item.Image != image.Image || item.Tag != version.tag || item.Digest != version.digest {
+			item.Image, item.Tag, item.Digest = image.Image, version.tag, version.digest
+			item.LastUpdateTime = &now
+		}
+		return
+	}
+	status.Images = append(status.Images, v1alpha1.ImageStatus{
+		Alias:          image.Alias,
+		Image:          image.Image,
+		Tag:            version.tag,
+		Digest:         version.digest,
+		LastUpdateTime: &now,
+	})
+}
+
+func getBranch(native *v1alpha1.NativeImageUpdater) string {
+	if native.Branch == "" {
+		return "main"
+	}
+	return native.Branch
+}
+
+func getCommitMessage(refs map[string]string) string {
+	var lines []string
+	for _, ref := range refs {
+		lines = append(lines, "- "+ref)
+	}
+	sort.Strings(lines)
+	return fmt.Sprintf("Update images\n\n%s", strings.Join(lines, "\n"))
+}
+
+// GetName returns the name of this controller
+func (r *Reconciler) GetName() string {
+	return "NativeImageUpdaterReconciler"
+}
+
+// SetupWithManager setups the log and recorder.
+// The status updates are ignored, the images are checked periodically via RequeueAfter.
+func (r *Reconciler) SetupWithManager(mgr ctrl.Manager) error {
+	r.log = ctrl.Log.WithName(r.GetName())
+	r.recorder = mgr.GetEventRecorderFor(r.GetName())
+	return ctrl.NewControllerManagedBy(mgr).
+		Named("native_image_updater_controller").
+		For(&v1alpha1.ImageUpdater{}).
+		WithEventFilter(predicate.GenerationChangedPredicate{}).
+		Complete(r)
+}
@@ -0,0 +1,259 @@
+/*
+Copyright 2022 The KubeSphere Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package imageupdater
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-logr/logr"
+	"github.com/kubesphere/ks-devops/controllers/core"
+	"github.com/kubesphere/ks-devops/pkg/api/devops/v1alpha3"
+	"github.com/kubesphere/ks-devops/pkg/api/gitops/v1alpha1"
+	"github.com/kubesphere/ks-devops/pkg/client/registry"
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+type fakeGitWriter struct {
+	root   string
+	repo   types.NamespacedName
+	branch string
+	err    error
+}
+
+func (f *fakeGitWriter) Write(ctx context.Context, repo types.NamespacedName, branch, message string,
+	update func(root string) (bool, error)) (commit string, err error) {
+	f.repo, f.branch = repo, branch
+	if f.err != nil {
+		err = f.err
+		return
+	}
+	var changed bool
+	if changed, err = update(f.root); err == nil && changed {
+		commit = "fake-commit"
+	}
+	return
+}
+
+func TestReconciler_Reconcile(t *testing.T) {
+	schema, err := v1alpha1.SchemeBuilder.Register().Build()
+	assert.Nil(t, err)
+	err = v1.SchemeBuilder.AddToScheme(schema)
+	assert.Nil(t, err)
+
+	updater := &v1alpha1.ImageUpdater{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "updater",
+			Namespace: "fake",
+		},
+		Spec: v1alpha1.ImageUpdaterSpec{
+			Kind:   KindNative,
+			Images: []string{"nginx:>=1.0.0", "web=ghcr.io/demo/web"},
+			Native: &v1alpha1.NativeImageUpdater{
+				Repo:   v1.LocalObjectReference{Name: "repo"},
+				Branch: "master",
+				UpdateStrategy: map[string]string{
+					"web": StrategyDigest,
+				},
+				Secrets: map[string]string{
+					"nginx": "registry",
+				},
+			},
+		},
+	}
+	secret := &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "registry",
+			Namespace: "fake",
+		},
+		Type: v1alpha3.SecretTypeBasicAuth,
+		Data: map[string][]byte{
+			v1alpha3.BasicAuthUsernameKey: []byte("admin"),
+			v1alpha3.BasicAuthPasswordKey: []byte("password"),
+		},
+	}
+	argoUpdater := updater.DeepCopy()
+	argoUpdater.Spec.Kind = "argocd"
+	noNativeUpdater := updater.DeepCopy()
+	noNativeUpdater.Spec.Native = nil
+	emptyRepoUpdater := updater.DeepCopy()
+	emptyRepoUpdater.Spec.Native.Repo.Name = ""
+	invalidIntervalUpdater := updater.DeepCopy()
+	invalidIntervalUpdater.Spec.Native.Interval = "invalid"
+
+	stubRegistry := &fakeRegistry{
+		tags:    []string{"0.9.0", "1.20.0", "1.21.0"},
+		digests: map[string]string{"latest": "sha256:web"},
+	}
+
+	tests := []struct {
+		name       string
+		objects    []runtime.Object
+		writer     *fakeGitWriter
+		registry   *fakeRegistry
+		wantResult ctrl.Result
+		verify     func(*testing.T, client.Client, *fakeGitWriter)
+	}{{
+		name:    "not found",
+		objects: []runtime.Object{},
+	}, {
+		name:    "kind is not native",
+		objects: []runtime.Object{argoUpdater},
+	}, {
+		name:    "native setting is nil",
+		objects: []runtime.Object{noNativeUpdater},
+	}, {
+		name:    "repo name is empty",
+		objects: []runtime.Object{emptyRepoUpdater},
+	}, {
+		name:    "invalid interval",
+		objects: []runtime.Object{invalidIntervalUpdater},
+	}, {
+		name:       "normal case",
+		objects:    []runtime.Object{updater.DeepCopy(), secret.DeepCopy()},
+		writer:     &fakeGitWriter{},
+		registry:   stubRegistry,
+		wantResult: ctrl.Result{RequeueAfter: defaultInterval},
+		verify: func(t *testing.T, c client.Client, writer *fakeGitWriter) {
+			assert.Equal(t, types.NamespacedName{Namespace: "fake", Name: "repo"}, writer.repo)
+			assert.Equal(t, "master", writer.branch)
+
+			data, err := os.ReadFile(filepath.Join(writer.root, "app.yaml"))
+			assert.Nil(t, err)
+			assert.Equal(t, "image: nginx:1.21.0\nimage: ghcr.io/demo/web:latest@sha256:web\n", string(data))
+
+			result := &v1alpha1.ImageUpdater{}
+			err = c.Get(context.Background(), types.NamespacedName{Namespace: "fake", Name: "updater"}, result)
+			assert.Nil(t, err)
+			assert.Equal(t, "fake-commit", result.Status.LastCommit)
+			assert.Empty(t, result.Status.Message)
+			assert.NotNil(t, result.Status.LastCheckTime)
+			if assert.Equal(t, 2, len(result.Status.Images)) {
+				assert.Equal(t, "1.21.0", result.Status.Images[0].Tag)
+				assert.Equal(t, "sha256:web", result.Status.Images[1].Digest)
+			}
+		},
+	}, {
+		name:       "credential not found",
+		objects:    []runtime.Object{updater.DeepCopy()},
+		writer:     &fakeGitWriter{},
+		registry:   stubRegistry,
+		wantResult: ctrl.Result{RequeueAfter: defaultInterval},
+		verify: func(t *testing.T, c client.Client, writer *fakeGitWriter) {
+			data, err := os.ReadFile(filepath.Join(writer.root, "app.yaml"))
+			assert.Nil(t, err)
+			assert.Equal(t, "image: nginx:1.19\nimage: ghcr.io/demo/web:latest@sha256:web\n", string(data))
+
+			result := &v1alpha1.ImageUpdater{}
+			err = c.Get(context.Background(), types.NamespacedName{Namespace: "fake", Name: "updater"}, result)
+			assert.Nil(t, err)
+			assert.Contains(t, result.Status.Message, "nginx")
+		},
+	}, {
+		name:       "failed to write back",
+		objects:    []runtime.Object{updater.DeepCopy(), secret.DeepCopy()},
+		writer:     &fakeGitWriter{err: errors.New("fake error")},
+		registry:   stubRegistry,
+		wantResult: ctrl.Result{RequeueAfter: defaultInterval},
+		verify: func(t *testing.T, c client.Client, writer *fakeGitWriter) {
+			result := &v1alpha1.ImageUpdater{}
+			err := c.Get(context.Background(), types.NamespacedName{Namespace: "fake", Name: "updater"}, result)
+			assert.Nil(t, err)
+			assert.Equal(t, "fake error", result.Status.Message)
+			assert.Empty(t, result.Status.LastCommit)
+		},
+	}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := fake.NewClientBuilder().WithScheme(schema).WithRuntimeObjects(tt.objects...).
+				WithStatusSubresource(&v1alpha1.ImageUpdater{}).Build()
+			writer := tt.writer
+			if writer != nil {
+				writer.root = t.TempDir()
+				assert.Nil(t, os.WriteFile(filepath.Join(writer.root, "app.yaml"),
+					[]byte("image: nginx:1.19\nimage: ghcr.io/demo/web\n"), 0644))
+			}
+			r := &Reconciler{
+				Client:    c,
+				GitWriter: writer,
+				NewRegistryClient: func(username, password string) registry.Interface {
+					return tt.registry
+				},
+				log:      logr.New(log.NullLogSink{}),
+				recorder: &record.FakeRecorder{},
+			}
+			result, err := r.Reconcile(context.Background(), ctrl.Request{
+				NamespacedName: types.NamespacedName{Namespace: "fake", Name: "updater"},
+			})
+			assert.Nil(t, err)
+			assert.Equal(t, tt.wantResult, result)
+			if tt.verify != nil {
+				tt.verify(t, c, writer)
+			}
+		})
+	}
+}
+
+func TestReconciler_getCredential(t *testing.T) {
+	schema, err := v1alpha1.SchemeBuilder.Register().Build()
+	assert.Nil(t, err)
+	err = v1.SchemeBuilder.AddToScheme(schema)
+	assert.Nil(t, err)
+
+	c := fake.NewClientBuilder().WithScheme(schema).WithObjects(&v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "text", Namespace: "fake"},
+		Type:       v1alpha3.SecretTypeSecretText,
+		Data:       map[string][]byte{v1alpha3.SecretTextSecretKey: []byte("token")},
+	}, &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "ssh", Namespace: "fake"},
+		Type:       v1alpha3.SecretTypeSSHAuth,
+	}).Build()
+	r := &Reconciler{Client: c}
+
+	username, password, err := r.getCredential(context.Background(), "fake", "text")
+	assert.Nil(t, err)
+	assert.Equal(t, "", username)
+	assert.Equal(t, "token", password)
+
+	_, _, err = r.getCredential(context.Background(), "fake", "ssh")
+	assert.NotNil(t, err)
+}
+
+func TestReconciler_SetupWithManager(t *testing.T) {
+	schema, err := v1alpha1.SchemeBuilder.Register().Build()
+	assert.Nil(t, err)
+
+	r := &Reconciler{}
+	err = r.SetupWithManager(&core.FakeManager{
+		Client: fake.NewClientBuilder().WithScheme(schema).Build(),
+		Scheme: schema,
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, "NativeImageUpdaterReconciler", r.GetName())
+}
@@ -0,0 +1,175 @@
+/*
+Copyright 2022 The KubeSphere Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package imageupdater
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/blang/semver/v4"
+	"github.com/kubesphere/ks-devops/pkg/api/gitops/v1alpha1"
+	"github.com/kubesphere/ks-devops/pkg/client/registry"
+)
+
+const (
+	// StrategySemver picks the highest version which matches the constraint
+	StrategySemver = "semver"
+	// StrategyLatest picks the most recently built image
+	StrategyLatest = "latest"
+	// StrategyName picks the last tag in alphabetical order
+	StrategyName = "name"
+	// StrategyDigest tracks the digest of a mutable tag
+	StrategyDigest = "digest"
+)
+
+// tagFilter filters the tags with the allow regular expression and the ignore glob patterns
+type tagFilter struct {
+	allow  *regexp.Regexp
+	ignore []*regexp.Regexp
+}
+
+// newTagFilter creates a tagFilter, the ignore glob patterns are separated by comma, the same as the other engines
+func newTagFilter(allow, ignore string) (filter *tagFilter, err error) {
+	filter = &tagFilter{}
+	if allow != "" {
+		if filter.allow, err = regexp.Compile(allow); err != nil {
+			return
+		}
+	}
+	for _, pattern := range v1alpha1.ParseIgnoreTags(ignore) {
+		var reg *regexp.Regexp
+		if reg, err = regexp.Compile(pattern); err != nil {
+			return
+		}
+		filter.ignore = append(filter.ignore, reg)
+	}
+	return
+}
+
+func (f *tagFilter) filter(tags []string) (result []string) {
+	for _, tag := range tags {
+		if f.allow != nil && !f.allow.MatchString(tag) {
+			continue
+		}
+		ignored := false
+		for _, reg := range f.ignore {
+			if ignored = reg.MatchString(tag); ignored {
+				break
+			}
+		}
+		if !ignored {
+			result = append(result, tag)
+		}
+	}
+	return
+}
+
+// imageVersion is the result of the update strategy
+type imageVersion struct {
+	tag    string
+	digest string
+}
+
+// getReference returns the full image reference with the tag and digest
+func (v imageVersion) getReference(image string) string {
+	ref := image
+	if v.tag != "" {
+		ref = fmt.Sprintf("%s:%s", ref, v.tag)
+	}
+	if v.digest != "" {
+		ref = fmt.Sprintf("%s@%s", ref, v.digest)
+	}
+	return ref
+}
+
+// selectVersion finds the expected version of an image according to the update strategy
+func selectVersion(ctx context.Context, client registry.Interface, image, constraint, strategy string,
+	filter *tagFilter) (version imageVersion, err error) {
+	if strategy == StrategyDigest {
+		version.tag = constraint
+		if version.tag == "" {
+			version.tag = "latest"
+		}
+		version.digest, err = client.GetDigest(ctx, image, version.tag)
+		return
+	}
+
+	var tags []string
+	if tags, err = client.ListTags(ctx, image); err != nil {
+		return
+	}
+	tags = filter.filter(tags)
+
+	switch strategy {
+	case "", StrategySemver:
+		version.tag, err = selectSemverTag(tags, constraint)
+	case StrategyName:
+		if len(tags) > 0 {
+			sort.Strings(tags)
+			version.tag = tags[len(tags)-1]
+		}
+	case StrategyLatest:
+		var latest time.Time
+		for _, tag := range tags {
+			var created time.Time
+			if created, err = client.GetCreatedTime(ctx, image, tag); err != nil {
+				return
+			}
+			if version.tag == "" || created.After(latest) {
+				version.tag, latest = tag, created
+			}
+		}
+	default:
+		err = fmt.Errorf("unsupported update strategy: %q", strategy)
+		return
+	}
+
+	if err == nil && version.tag == "" {
+		err = fmt.Errorf("no matched tag found for image %s", image)
+	}
+	return
+}
+
+// selectSemverTag returns the highest semantic version, the tags are allowed to have the prefix 'v'
+func selectSemverTag(tags []string, constraint string) (tag string, err error) {
+	var versionRange semver.Range
+	if constraint != "" {
+		if versionRange, err = semver.ParseRange(constraint); err != nil {
+			err = fmt.Errorf("invalid version constraint %q: %v", constraint, err)
+			return
+		}
+	}
+
+	var highest semver.Version
+	for _, item := range tags {
+		version, parseErr := semver.Parse(strings.TrimPrefix(item, "v"))
+		if parseErr != nil {
+			continue
+		}
+		if versionRange != nil && !versionRange(version) {
+			continue
+		}
+		if tag == "" || version.GT(highest) {
+			tag, highest = item, version
+		}
+	}
+	return
+}
@@ -0,0 +1,164 @@
+/*
+Copyright 2022 The KubeSphere Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package imageupdater
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeRegistry struct {
+	tags    []string
+	digests map[string]string
+	created map[string]time.Time
+	err     error
+}
+
+func (f *fakeRegistry) ListTags(ctx context.Context, image string) ([]string, error) {
+	return f.tags, f.err
+}
+
+func (f *fakeRegistry) GetDigest(ctx context.Context, image, tag string) (string, error) {
+	return f.digests[tag], f.err
+}
+
+func (f *fakeRegistry) GetCreatedTime(ctx context.Context, image, tag string) (time.Time, error) {
+	return f.created[tag], f.err
+}
+
+func Test_tagFilter(t *testing.T) {
+	filter, err := newTagFilter(`^v\d`, "latest, *-rc")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"v1.0.0", "v1.1.0"}, filter.filter([]string{"latest", "v1.0.0", "v1.1.0", "v1.2.0-rc", "dev"}))
+
+	filter, err = newTagFilter("", "")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"latest", "dev"}, filter.filter([]string{"latest", "dev"}))
+
+	_, err = newTagFilter("(", "")
+	assert.NotNil(t, err)
+	_, err = newTagFilter("", "a,[z-a]")
+	assert.NotNil(t, err)
+
+	// the ignored tags are glob patterns, and they match the whole tag
+	filter, err = newTagFilter("", "v1.?.0")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"v1.0.0-rc", "v1x0x0"}, filter.filter([]string{"v1.0.0", "v1.0.0-rc", "v1x0x0"}))
+}
+
+func Test_selectVersion(t *testing.T) {
+	registry := &fakeRegistry{
+		tags:    []string{"v0.9.0", "1.0.0", "v1.1.0", "v2.0.0", "latest", "zeta"},
+		digests: map[string]string{"latest": "sha256:latest", "stable": "sha256:stable"},
+		created: map[string]time.Time{
+			"v0.9.0": time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC),
+			"1.0.0":  time.Date(2022, 3, 1, 0, 0, 0, 0, time.UTC),
+			"v1.1.0": time.Date(2022, 2, 1, 0, 0, 0, 0, time.UTC),
+		},
+	}
+	noFilter, _ := newTagFilter("", "")
+	semverOnly, _ := newTagFilter(`^v?\d+\.\d+\.\d+$`, "")
+	belowTwo, _ := newTagFilter("", "v2*")
+
+	tests := []struct {
+		name       string
+		registry   *fakeRegistry
+		constraint string
+		strategy   string
+		filter     *tagFilter
+		want       imageVersion
+		wantErr    bool
+	}{{
+		name:     "semver is the default strategy",
+		registry: registry,
+		filter:   noFilter,
+		want:     imageVersion{tag: "v2.0.0"},
+	}, {
+		name:       "semver with constraint",
+		registry:   registry,
+		strategy:   StrategySemver,
+		constraint: ">=1.0.0 <2.0.0",
+		filter:     noFilter,
+		want:       imageVersion{tag: "v1.1.0"},
+	}, {
+		name:       "invalid semver constraint",
+		registry:   registry,
+		strategy:   StrategySemver,
+		constraint: "invalid",
+		filter:     noFilter,
+		wantErr:    true,
+	}, {
+		name:     "name",
+		registry: registry,
+		strategy: StrategyName,
+		filter:   noFilter,
+		want:     imageVersion{tag: "zeta"},
+	}, {
+		name:     "latest",
+		registry: registry,
+		strategy: StrategyLatest,
+		filter:   belowTwo,
+		want:     imageVersion{tag: "1.0.0"},
+	}, {
+		name:     "digest without constraint",
+		registry: registry,
+		strategy: StrategyDigest,
+		filter:   noFilter,
+		want:     imageVersion{tag: "latest", digest: "sha256:latest"},
+	}, {
+		name:       "digest with constraint",
+		registry:   registry,
+		strategy:   StrategyDigest,
+		constraint: "stable",
+		filter:     noFilter,
+		want:       imageVersion{tag: "stable", digest: "sha256:stable"},
+	}, {
+		name:     "unsupported strategy",
+		registry: registry,
+		strategy: "unknown",
+		filter:   noFilter,
+		wantErr:  true,
+	}, {
+		name:     "no matched tags",
+		registry: &fakeRegistry{tags: []string{"latest"}},
+		filter:   semverOnly,
+		wantErr:  true,
+	}, {
+		name:     "registry error",
+		registry: &fakeRegistry{err: errors.New("fake")},
+		filter:   noFilter,
+		wantErr:  true,
+	}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := selectVersion(context.Background(), tt.registry, "nginx", tt.constraint, tt.strategy, tt.filter)
+			assert.Equal(t, tt.wantErr, err != nil, err)
+			if !tt.wantErr {
+				assert.Equal(t, tt.want, got)
+			}
+		})
+	}
+}
+
+func Test_imageVersion_getReference(t *testing.T) {
+	assert.Equal(t, "nginx:v1", imageVersion{tag: "v1"}.getReference("nginx"))
+	assert.Equal(t, "nginx:latest@sha256:a", imageVersion{tag: "latest", digest: "sha256:a"}.getReference("nginx"))
+}
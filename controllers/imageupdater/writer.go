@@ -0,0 +1,99 @@
+/*
+Copyright 2022 The KubeSphere Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package imageupdater
+
+import (
+	"context"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// GitWriter writes the changes back to a git repository
+type GitWriter interface {
+	// Write checks out the branch, calls the update function with the root directory of the work tree,
+	// then commits and pushes the changes. The commit hash is empty if there is nothing changed.
+	Write(ctx context.Context, repo types.NamespacedName, branch, message string,
+		update func(root string) (changed bool, err error)) (commit string, err error)
+}
+
+// replaceImage replaces the references of an image with the new reference in the content.
+// Only the values of the image fields are replaced, such as the names of the containers are never changed.
+func replaceImage(content, image, newRef string) (string, bool) {
+	pattern := regexp.MustCompile(`(?m)((?:^|[\s{,-])["']?image["']?\s*:\s*["']?)` + regexp.QuoteMeta(image) +
+		`(:[\w][\w.-]{0,127})?(@sha256:[a-fA-F0-9]{64})?(["',}\s]|$)`)
+	result := pattern.ReplaceAllString(content, "${1}"+strings.ReplaceAll(newRef, "$", "$$")+"${4}")
+	return result, result != content
+}
+
+// isManifestFile checks if the file might contain the image references
+func isManifestFile(name string) bool {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".yaml", ".yml", ".json":
+		return true
+	}
+	return false
+}
+
+// updateImagesInPath replaces the image references in all manifest files under the path,
+// the key of refs is the image name, and the value is the new reference
+func updateImagesInPath(root, path string, refs map[string]string) (changed bool, err error) {
+	// the path is cleaned as an absolute one to make sure it's not out of the root
+	target := filepath.Join(root, filepath.Clean("/"+path))
+
+	err = filepath.WalkDir(target, func(file string, d fs.DirEntry, walkErr error) error {
+		if walkErr != nil {
+			return walkErr
+		}
+		if d.IsDir() {
+			if d.Name() == ".git" {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if file != target && !isManifestFile(file) {
+			return nil
+		}
+
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return err
+		}
+		content, fileChanged := string(data), false
+		for image, ref := range refs {
+			var replaced bool
+			if content, replaced = replaceImage(content, image, ref); replaced {
+				fileChanged = true
+			}
+		}
+		if !fileChanged {
+			return nil
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		changed = true
+		return os.WriteFile(file, []byte(content), info.Mode())
+	})
+	return
+}
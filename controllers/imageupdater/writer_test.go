@@ -0,0 +1,133 @@
+/*
+Copyright 2022 The KubeSphere Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package imageupdater
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_replaceImage(t *testing.T) {
+	digest := "sha256:" + strings.Repeat("a", 64)
+	tests := []struct {
+		name        string
+		content     string
+		image       string
+		newRef      string
+		want        string
+		wantChanged bool
+	}{{
+		name:        "with tag",
+		content:     "image: nginx:1.19\n",
+		image:       "nginx",
+		newRef:      "nginx:1.20",
+		want:        "image: nginx:1.20\n",
+		wantChanged: true,
+	}, {
+		name:        "without tag and quoted",
+		content:     `"image": "nginx"`,
+		image:       "nginx",
+		newRef:      "nginx:1.20",
+		want:        `"image": "nginx:1.20"`,
+		wantChanged: true,
+	}, {
+		name:        "with digest",
+		content:     "image: nginx:latest@" + digest,
+		image:       "nginx",
+		newRef:      "nginx:latest@sha256:bbb",
+		want:        "image: nginx:latest@sha256:bbb",
+		wantChanged: true,
+	}, {
+		name:    "different images with the same suffix or prefix",
+		content: "image: ghcr.io/nginx:1.19\nimage: nginx-ingress:1.0",
+		image:   "nginx",
+		newRef:  "nginx:1.20",
+		want:    "image: ghcr.io/nginx:1.19\nimage: nginx-ingress:1.0",
+	}, {
+		name:        "container named after its image",
+		content:     "containers:\n- name: nginx\n  image: nginx:1.19\n- image: nginx\n  name: nginx\n",
+		image:       "nginx",
+		newRef:      "nginx:1.20",
+		want:        "containers:\n- name: nginx\n  image: nginx:1.20\n- image: nginx:1.20\n  name: nginx\n",
+		wantChanged: true,
+	}, {
+		name:        "JSON",
+		content:     `{"name":"nginx","image":"nginx:1.19"}`,
+		image:       "nginx",
+		newRef:      "nginx:1.20",
+		want:        `{"name":"nginx","image":"nginx:1.20"}`,
+		wantChanged: true,
+	}, {
+		name:    "not an image field",
+		content: "args: [nginx]\nenv:\n- name: IMAGE\n  value: nginx:1.19\n",
+		image:   "nginx",
+		newRef:  "nginx:1.20",
+		want:    "args: [nginx]\nenv:\n- name: IMAGE\n  value: nginx:1.19\n",
+	}, {
+		name:    "same version",
+		content: "image: nginx:1.20",
+		image:   "nginx",
+		newRef:  "nginx:1.20",
+		want:    "image: nginx:1.20",
+	}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, changed := replaceImage(tt.content, tt.image, tt.newRef)
+			assert.Equal(t, tt.want, got)
+			assert.Equal(t, tt.wantChanged, changed)
+		})
+	}
+}
+
+func Test_updateImagesInPath(t *testing.T) {
+	root := t.TempDir()
+	assert.Nil(t, os.MkdirAll(filepath.Join(root, "deploy"), 0755))
+	assert.Nil(t, os.WriteFile(filepath.Join(root, "deploy", "app.yaml"), []byte("image: nginx:1.19"), 0644))
+	assert.Nil(t, os.WriteFile(filepath.Join(root, "deploy", "README.md"), []byte("image: nginx:1.19"), 0644))
+	assert.Nil(t, os.WriteFile(filepath.Join(root, "other.yaml"), []byte("image: nginx:1.19"), 0644))
+
+	refs := map[string]string{"nginx": "nginx:1.20"}
+	changed, err := updateImagesInPath(root, "deploy", refs)
+	assert.Nil(t, err)
+	assert.True(t, changed)
+
+	data, _ := os.ReadFile(filepath.Join(root, "deploy", "app.yaml"))
+	assert.Equal(t, "image: nginx:1.20", string(data))
+	data, _ = os.ReadFile(filepath.Join(root, "deploy", "README.md"))
+	assert.Equal(t, "image: nginx:1.19", string(data))
+	data, _ = os.ReadFile(filepath.Join(root, "other.yaml"))
+	assert.Equal(t, "image: nginx:1.19", string(data))
+
+	// nothing changed in the second time
+	changed, err = updateImagesInPath(root, "deploy", refs)
+	assert.Nil(t, err)
+	assert.False(t, changed)
+
+	// the path cannot be out of the root
+	changed, err = updateImagesInPath(root, "../../", refs)
+	assert.Nil(t, err)
+	assert.True(t, changed)
+	data, _ = os.ReadFile(filepath.Join(root, "other.yaml"))
+	assert.Equal(t, "image: nginx:1.20", string(data))
+
+	_, err = updateImagesInPath(root, "not-exist", refs)
+	assert.NotNil(t, err)
+}
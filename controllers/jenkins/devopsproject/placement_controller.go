@@ -0,0 +1,106 @@
+/*
+Copyright 2022 The KubeSphere Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package devopsproject
+
+import (
+	"context"
+
+	"github.com/go-logr/logr"
+	"github.com/kubesphere/ks-devops/pkg/api/devops/v1alpha3"
+	"github.com/kubesphere/ks-devops/pkg/client/devops/sharding"
+	"github.com/kubesphere/ks-devops/pkg/constants"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+)
+
+//+kubebuilder:rbac:groups=devops.kubesphere.io,resources=devopsprojects,verbs=get;list;watch;update
+//+kubebuilder:rbac:groups="",resources=events,verbs=create;patch
+
+// PlacementReconciler places the DevOps projects on the Jenkins instances, the result is recorded in the status.
+type PlacementReconciler struct {
+	client.Client
+	Registry *sharding.Registry
+	// Policy decides the Jenkins instance of a new DevOps project, the default one is sharding.LeastProjectsPolicy
+	Policy sharding.Policy
+
+	log      logr.Logger
+	recorder record.EventRecorder
+}
+
+// Reconcile places the DevOps project which does not have a Jenkins instance
+func (r *PlacementReconciler) Reconcile(ctx context.Context, req ctrl.Request) (result ctrl.Result, err error) {
+	project := &v1alpha3.DevOpsProject{}
+	if err = r.Get(ctx, req.NamespacedName, project); err != nil {
+		err = client.IgnoreNotFound(err)
+		return
+	}
+	if project.Status.JenkinsInstance != "" || !project.DeletionTimestamp.IsZero() {
+		return
+	}
+
+	var instance string
+	if project.Annotations[v1alpha3.DevOpeProjectSyncStatusAnnoKey] == constants.StatusSuccessful {
+		// the project was synchronized before the sharding, its Jenkins folder is in the default instance
+		instance = r.Registry.Default()
+	} else {
+		projects := &v1alpha3.DevOpsProjectList{}
+		if err = r.List(ctx, projects); err != nil {
+			return
+		}
+		if instance, err = r.getPolicy().Place(project, projects.Items); err != nil {
+			r.recorder.Eventf(project, v1.EventTypeWarning, "FailedPlacement",
+				"failed to place the DevOps project on a Jenkins instance, error is: %v", err)
+			return
+		}
+	}
+
+	project.Status.JenkinsInstance = instance
+	if err = r.Update(ctx, project); err == nil {
+		r.log.Info("placed the DevOps project", "project", project.Name, "instance", instance)
+		r.recorder.Eventf(project, v1.EventTypeNormal, "Placed", "placed on Jenkins instance %s", instance)
+	}
+	return
+}
+
+func (r *PlacementReconciler) getPolicy() sharding.Policy {
+	if r.Policy == nil {
+		return &sharding.LeastProjectsPolicy{Registry: r.Registry}
+	}
+	return r.Policy
+}
+
+// GetName returns the name of this controller
+func (r *PlacementReconciler) GetName() string {
+	return "JenkinsPlacementController"
+}
+
+// SetupWithManager setups the log and recorder
+func (r *PlacementReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	r.log = ctrl.Log.WithName(r.GetName())
+	r.recorder = mgr.GetEventRecorderFor(r.GetName())
+	return ctrl.NewControllerManagedBy(mgr).
+		Named("jenkins_placement_controller").
+		For(&v1alpha3.DevOpsProject{}).
+		WithEventFilter(predicate.NewPredicateFuncs(func(object client.Object) bool {
+			project, ok := object.(*v1alpha3.DevOpsProject)
+			return ok && project.Status.JenkinsInstance == ""
+		})).
+		Complete(r)
+}
@@ -0,0 +1,116 @@
+/*
+Copyright 2022 The KubeSphere Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package devopsproject
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-logr/logr"
+	"github.com/kubesphere/ks-devops/controllers/core"
+	"github.com/kubesphere/ks-devops/pkg/api/devops/v1alpha3"
+	fakedevops "github.com/kubesphere/ks-devops/pkg/client/devops/fake"
+	"github.com/kubesphere/ks-devops/pkg/client/devops/sharding"
+	"github.com/kubesphere/ks-devops/pkg/constants"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+func TestPlacementReconciler_Reconcile(t *testing.T) {
+	schema, err := v1alpha3.SchemeBuilder.Register().Build()
+	assert.Nil(t, err)
+
+	registry := sharding.NewRegistry(
+		&sharding.Instance{Name: "default", Client: fakedevops.New()},
+		&sharding.Instance{Name: "b", Client: fakedevops.New()})
+	newProject := func(name, instance string, annotations map[string]string) *v1alpha3.DevOpsProject {
+		return &v1alpha3.DevOpsProject{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Annotations: annotations},
+			Status:     v1alpha3.DevOpsProjectStatus{JenkinsInstance: instance},
+		}
+	}
+	getInstance := func(c client.Client) string {
+		project := &v1alpha3.DevOpsProject{}
+		_ = c.Get(context.Background(), types.NamespacedName{Name: "team"}, project)
+		return project.Status.JenkinsInstance
+	}
+
+	tests := []struct {
+		name         string
+		objects      []runtime.Object
+		wantInstance string
+		wantErr      bool
+	}{{
+		name: "not found",
+	}, {
+		name:         "placed already",
+		objects:      []runtime.Object{newProject("team", "b", nil)},
+		wantInstance: "b",
+	}, {
+		name:         "place on the instance which has the least projects",
+		objects:      []runtime.Object{newProject("team", "", nil), newProject("other", "default", nil)},
+		wantInstance: "b",
+	}, {
+		name: "the synchronized project stays in the default instance",
+		objects: []runtime.Object{newProject("team", "", map[string]string{
+			v1alpha3.DevOpeProjectSyncStatusAnnoKey: constants.StatusSuccessful,
+		}), newProject("other", "default", nil)},
+		wantInstance: "default",
+	}, {
+		name: "the desired instance is not registered",
+		objects: []runtime.Object{newProject("team", "", map[string]string{
+			v1alpha3.DevOpsProjectJenkinsInstanceAnnoKey: "c",
+		})},
+		wantErr: true,
+	}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := fake.NewClientBuilder().WithScheme(schema).WithRuntimeObjects(tt.objects...).Build()
+			r := &PlacementReconciler{
+				Client:   c,
+				Registry: registry,
+				log:      logr.New(log.NullLogSink{}),
+				recorder: &record.FakeRecorder{},
+			}
+			_, err := r.Reconcile(context.Background(), ctrl.Request{
+				NamespacedName: types.NamespacedName{Name: "team"},
+			})
+			assert.Equal(t, tt.wantErr, err != nil, err)
+			assert.Equal(t, tt.wantInstance, getInstance(c))
+		})
+	}
+}
+
+func TestPlacementReconciler_SetupWithManager(t *testing.T) {
+	schema, err := v1alpha3.SchemeBuilder.Register().Build()
+	assert.Nil(t, err)
+
+	r := &PlacementReconciler{}
+	err = r.SetupWithManager(&core.FakeManager{
+		Client: fake.NewClientBuilder().WithScheme(schema).Build(),
+		Scheme: schema,
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, "JenkinsPlacementController", r.GetName())
+}
@@ -0,0 +1,145 @@
+/*
+Copyright 2022 The KubeSphere Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package health
+
+import (
+	"context"
+	"reflect"
+	"strconv"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+
+	"github.com/kubesphere/ks-devops/pkg/client/devops/jenkins"
+)
+
+const (
+	// ConfigMapName is the name of the ConfigMap which holds the cluster-wide condition of the default Jenkins
+	ConfigMapName = jenkins.HealthConfigMapName
+
+	// ReasonAvailable indicates that the requests are sent to Jenkins as usual
+	ReasonAvailable = "Available"
+	// ReasonUnavailable indicates that the requests to Jenkins are rejected, and the PipelineRuns are held
+	ReasonUnavailable = "Unavailable"
+	// ReasonRecovering indicates that a trial request is being sent to Jenkins
+	ReasonRecovering = "Recovering"
+)
+
+//+kubebuilder:rbac:groups="",resources=configmaps,verbs=get;create;update
+
+// Reporter writes the health of a Jenkins instance into a ConfigMap, it's the cluster-wide condition of the instance
+type Reporter struct {
+	client.Client
+	Namespace string
+	// Instance is the name of the Jenkins instance, it's the default one if it's empty
+	Instance string
+	Monitor  *jenkins.HealthMonitor
+
+	changed chan struct{}
+}
+
+// SetupWithManager adds the health monitor and the reporter into the manager
+func (r *Reporter) SetupWithManager(mgr manager.Manager) (err error) {
+	r.changed = make(chan struct{}, 1)
+	r.Monitor.OnStateChange(func(jenkins.HealthStatus) {
+		select {
+		case r.changed <- struct{}{}:
+		default:
+			// there is a pending report already
+		}
+	})
+	if err = mgr.Add(r.Monitor); err == nil {
+		err = mgr.Add(r)
+	}
+	return
+}
+
+// Start reports the health whenever it changes, and every probe interval to renew the last probe time, which also
+// retries the failed reports.
+// It only runs in the leader.
+func (r *Reporter) Start(ctx context.Context) error {
+	ticker := time.NewTicker(r.Monitor.RetryAfter())
+	defer ticker.Stop()
+	for {
+		if err := r.report(ctx, r.Monitor.Status()); err != nil {
+			klog.Errorf("failed to report the health of Jenkins instance %q, error: %v", r.Instance, err)
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-r.changed:
+		case <-ticker.C:
+		}
+	}
+}
+
+func (r *Reporter) report(ctx context.Context, status jenkins.HealthStatus) (err error) {
+	interval := jenkins.DefaultHealthProbeInterval
+	if r.Monitor != nil {
+		interval = r.Monitor.RetryAfter()
+	}
+	data := toConditionData(status, interval)
+
+	name := jenkins.GetHealthConfigMapName(r.Instance)
+	cm := &v1.ConfigMap{}
+	if err = r.Get(ctx, types.NamespacedName{Namespace: r.Namespace, Name: name}, cm); err != nil {
+		if apierrors.IsNotFound(err) {
+			cm = &v1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{Namespace: r.Namespace, Name: name},
+				Data:       data,
+			}
+			err = r.Create(ctx, cm)
+		}
+		return
+	}
+
+	if !reflect.DeepEqual(cm.Data, data) {
+		cm.Data = data
+		err = r.Update(ctx, cm)
+	}
+	return
+}
+
+// toConditionData converts the health into the data of the ConfigMap. The last probe time is the heartbeat of the
+// report, the readers treat the report as unknown once it's older than a few probe intervals.
+func toConditionData(status jenkins.HealthStatus, interval time.Duration) map[string]string {
+	reason, message := ReasonAvailable, ""
+	switch status.State {
+	case jenkins.BreakerOpen:
+		reason, message = ReasonUnavailable, status.LastError
+	case jenkins.BreakerHalfOpen:
+		reason, message = ReasonRecovering, status.LastError
+	}
+	data := map[string]string{
+		"available":          strconv.FormatBool(status.Available()),
+		"state":              string(status.State),
+		"reason":             reason,
+		"message":            message,
+		"lastTransitionTime": status.LastTransitionTime.UTC().Format(time.RFC3339),
+		"probeInterval":      interval.String(),
+	}
+	if !status.LastProbeTime.IsZero() {
+		data["lastProbeTime"] = status.LastProbeTime.UTC().Format(time.RFC3339)
+	}
+	return data
+}
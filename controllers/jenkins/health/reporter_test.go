@@ -0,0 +1,178 @@
+/*
+Copyright 2022 The KubeSphere Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package health
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/kubesphere/ks-devops/controllers/core"
+	"github.com/kubesphere/ks-devops/pkg/client/devops/jenkins"
+)
+
+func Test_toConditionData(t *testing.T) {
+	transitionTime := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name   string
+		status jenkins.HealthStatus
+		expect map[string]string
+	}{{
+		name: "available",
+		status: jenkins.HealthStatus{
+			State:               jenkins.BreakerClosed,
+			ConsecutiveFailures: 1,
+			LastError:           "connection refused",
+			LastTransitionTime:  transitionTime,
+			LastProbeTime:       transitionTime.Add(time.Minute),
+		},
+		expect: map[string]string{
+			"available":          "true",
+			"state":              "Closed",
+			"reason":             ReasonAvailable,
+			"message":            "",
+			"lastTransitionTime": "2022-01-01T00:00:00Z",
+			"probeInterval":      "10s",
+			"lastProbeTime":      "2022-01-01T00:01:00Z",
+		},
+	}, {
+		name: "unavailable",
+		status: jenkins.HealthStatus{
+			State:              jenkins.BreakerOpen,
+			LastError:          "connection refused",
+			LastTransitionTime: transitionTime,
+		},
+		expect: map[string]string{
+			"available":          "false",
+			"state":              "Open",
+			"reason":             ReasonUnavailable,
+			"message":            "connection refused",
+			"lastTransitionTime": "2022-01-01T00:00:00Z",
+			"probeInterval":      "10s",
+		},
+	}, {
+		name: "recovering",
+		status: jenkins.HealthStatus{
+			State:              jenkins.BreakerHalfOpen,
+			LastError:          "connection refused",
+			LastTransitionTime: transitionTime,
+		},
+		expect: map[string]string{
+			"available":          "true",
+			"state":              "HalfOpen",
+			"reason":             ReasonRecovering,
+			"message":            "connection refused",
+			"lastTransitionTime": "2022-01-01T00:00:00Z",
+			"probeInterval":      "10s",
+		},
+	}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expect, toConditionData(tt.status, 10*time.Second))
+		})
+	}
+}
+
+func TestReporter_report(t *testing.T) {
+	schema := runtime.NewScheme()
+	assert.Nil(t, v1.AddToScheme(schema))
+
+	tests := []struct {
+		name       string
+		instance   string
+		objects    []runtime.Object
+		expectName string
+	}{{
+		name:       "create the ConfigMap",
+		expectName: ConfigMapName,
+	}, {
+		name: "update the ConfigMap",
+		objects: []runtime.Object{&v1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: ConfigMapName},
+			Data:       map[string]string{"available": "true"},
+		}},
+		expectName: ConfigMapName,
+	}, {
+		name:     "another Jenkins instance",
+		instance: "b",
+		objects: []runtime.Object{&v1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: ConfigMapName},
+			Data:       map[string]string{"available": "true"},
+		}},
+		expectName: "jenkins-health-b",
+	}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &Reporter{
+				Client:    fake.NewClientBuilder().WithScheme(schema).WithRuntimeObjects(tt.objects...).Build(),
+				Namespace: "ns",
+				Instance:  tt.instance,
+			}
+			status := jenkins.HealthStatus{State: jenkins.BreakerOpen, LastError: "connection refused"}
+			assert.Nil(t, r.report(context.Background(), status))
+			// nothing changes if the health is the same
+			assert.Nil(t, r.report(context.Background(), status))
+
+			cm := &v1.ConfigMap{}
+			assert.Nil(t, r.Get(context.Background(), types.NamespacedName{Namespace: "ns", Name: tt.expectName}, cm))
+			assert.Equal(t, toConditionData(status, jenkins.DefaultHealthProbeInterval), cm.Data)
+		})
+	}
+}
+
+func TestReporter_Start(t *testing.T) {
+	schema := runtime.NewScheme()
+	assert.Nil(t, v1.AddToScheme(schema))
+	monitor := jenkins.NewHealthMonitor(&jenkins.Options{Host: "http://jenkins", HealthFailureThreshold: 1})
+	r := &Reporter{
+		Client:    fake.NewClientBuilder().WithScheme(schema).Build(),
+		Namespace: "ns",
+		Monitor:   monitor,
+	}
+	assert.Nil(t, r.SetupWithManager(&core.FakeManager{Client: r.Client, Scheme: schema}))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- r.Start(ctx)
+	}()
+
+	getAvailable := func() string {
+		cm := &v1.ConfigMap{}
+		_ = r.Get(context.Background(), types.NamespacedName{Namespace: "ns", Name: ConfigMapName}, cm)
+		return cm.Data["available"]
+	}
+	assert.Eventually(t, func() bool {
+		return getAvailable() == "true"
+	}, 10*time.Second, 10*time.Millisecond)
+
+	monitor.RecordFailure(errors.New("connection refused"))
+	assert.Eventually(t, func() bool {
+		return getAvailable() == "false"
+	}, 10*time.Second, 10*time.Millisecond)
+
+	cancel()
+	assert.Nil(t, <-done)
+}
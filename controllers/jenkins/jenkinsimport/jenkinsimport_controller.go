@@ -0,0 +1,164 @@
+/*
+Copyright 2022 The KubeSphere Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package jenkinsimport
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/kubesphere/ks-devops/pkg/api/devops/v1alpha3"
+	"github.com/kubesphere/ks-devops/pkg/jenkinsimport"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+)
+
+const (
+	// FolderAnnoKey is the annotation key of the Jenkins folder which the DevOps project imports the jobs from
+	FolderAnnoKey = v1alpha3.DevOpsProjectPrefix + "jenkins-import-folder"
+	// DryRunAnnoKey only reports what is going to be imported if its value is true
+	DryRunAnnoKey = v1alpha3.DevOpsProjectPrefix + "jenkins-import-dry-run"
+	// ForceAnnoKey imports the jobs with the unsupported settings if its value is true
+	ForceAnnoKey = v1alpha3.DevOpsProjectPrefix + "jenkins-import-force"
+	// ReportAnnoKey is the annotation key of the latest import report in JSON format
+	ReportAnnoKey = v1alpha3.DevOpsProjectPrefix + "jenkins-import-report"
+
+	// DefaultInterval is the default interval of discovering the new jobs
+	DefaultInterval = 10 * time.Minute
+)
+
+//+kubebuilder:rbac:groups=devops.kubesphere.io,resources=devopsprojects,verbs=get;list;watch;update
+//+kubebuilder:rbac:groups=devops.kubesphere.io,resources=pipelines,verbs=get;list;watch;create
+//+kubebuilder:rbac:groups="",resources=events,verbs=create;patch
+
+// Reconciler imports the jobs of a Jenkins folder as the Pipelines of a DevOps project.
+// The DevOps projects which have the folder annotation are handled only. A project is only able to import
+// its own folder, the jobs of the other folders are imported by the administrators via the command line.
+type Reconciler struct {
+	client.Client
+	Source jenkinsimport.JobSource
+	// Interval is the interval of discovering the new jobs, the default value is DefaultInterval
+	Interval time.Duration
+
+	log      logr.Logger
+	recorder record.EventRecorder
+}
+
+// Reconcile imports the jobs, and writes the report into the annotations of the DevOps project
+func (r *Reconciler) Reconcile(ctx context.Context, req ctrl.Request) (result ctrl.Result, err error) {
+	r.log.V(6).Info(fmt.Sprintf("start to reconcile DevOpsProject: %s", req.String()))
+
+	project := &v1alpha3.DevOpsProject{}
+	if err = r.Get(ctx, req.NamespacedName, project); err != nil {
+		err = client.IgnoreNotFound(err)
+		return
+	}
+
+	folder := project.Annotations[FolderAnnoKey]
+	if folder == "" || !project.DeletionTimestamp.IsZero() {
+		return
+	}
+	if project.Status.AdminNamespace == "" {
+		// wait for the namespace which is created by the DevOps project controller
+		result = ctrl.Result{RequeueAfter: 5 * time.Second}
+		return
+	}
+	if !isOwnFolder(project, folder) {
+		r.recorder.Eventf(project, corev1.EventTypeWarning, "ForbiddenFolder",
+			"DevOps project is not allowed to import the jobs of Jenkins folder %s, only folder %s is allowed",
+			folder, project.Status.AdminNamespace)
+		return
+	}
+
+	importer := &jenkinsimport.Importer{Client: r.Client, Source: r.Source}
+	var report *jenkinsimport.Report
+	if report, err = importer.Import(ctx, jenkinsimport.Options{
+		Folder:    folder,
+		Namespace: project.Status.AdminNamespace,
+		DryRun:    isTrue(project.Annotations[DryRunAnnoKey]),
+		Force:     isTrue(project.Annotations[ForceAnnoKey]),
+	}); err != nil {
+		r.recorder.Eventf(project, corev1.EventTypeWarning, "FailedToImport",
+			"failed to import the jobs of Jenkins folder %s, error is: %v", folder, err)
+		return
+	}
+
+	if created := report.Count(jenkinsimport.ActionCreate); created > 0 && !report.DryRun {
+		r.recorder.Eventf(project, corev1.EventTypeNormal, "Imported",
+			"imported %d jobs from Jenkins folder %s", created, folder)
+	}
+
+	var data []byte
+	if data, err = json.Marshal(report); err != nil {
+		return
+	}
+	if project.Annotations[ReportAnnoKey] != string(data) {
+		project.Annotations[ReportAnnoKey] = string(data)
+		if err = r.Update(ctx, project); err != nil {
+			return
+		}
+	}
+	// discover the jobs which are created in Jenkins later
+	result = ctrl.Result{RequeueAfter: r.getInterval()}
+	return
+}
+
+func (r *Reconciler) getInterval() time.Duration {
+	if r.Interval <= 0 {
+		return DefaultInterval
+	}
+	return r.Interval
+}
+
+// isOwnFolder checks if the folder is the Jenkins folder of the DevOps project or one of its sub-folders.
+// The folder of a DevOps project is named after its admin namespace.
+func isOwnFolder(project *v1alpha3.DevOpsProject, folder string) bool {
+	folder = strings.Trim(folder, "/")
+	own := project.Status.AdminNamespace
+	return folder == own || strings.HasPrefix(folder, own+"/")
+}
+
+func isTrue(value string) bool {
+	ok, _ := strconv.ParseBool(value)
+	return ok
+}
+
+// GetName returns the name of this controller
+func (r *Reconciler) GetName() string {
+	return "JenkinsImportReconciler"
+}
+
+// SetupWithManager setups the log and recorder
+func (r *Reconciler) SetupWithManager(mgr ctrl.Manager) error {
+	r.log = ctrl.Log.WithName(r.GetName())
+	r.recorder = mgr.GetEventRecorderFor(r.GetName())
+	return ctrl.NewControllerManagedBy(mgr).
+		Named("jenkins_import_controller").
+		For(&v1alpha3.DevOpsProject{}).
+		WithEventFilter(predicate.NewPredicateFuncs(func(object client.Object) bool {
+			_, ok := object.GetAnnotations()[FolderAnnoKey]
+			return ok
+		})).
+		Complete(r)
+}
@@ -0,0 +1,203 @@
+/*
+Copyright 2022 The KubeSphere Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package jenkinsimport
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/kubesphere/ks-devops/controllers/core"
+	"github.com/kubesphere/ks-devops/pkg/api/devops/v1alpha3"
+	"github.com/kubesphere/ks-devops/pkg/client/devops/jenkins"
+	"github.com/kubesphere/ks-devops/pkg/jenkinsimport"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+const pipelineConfig = `<?xml version='1.1' encoding='UTF-8'?>
+<flow-definition plugin="workflow-job">
+  <keepDependencies>false</keepDependencies>
+  <properties/>
+  <definition class="org.jenkinsci.plugins.workflow.cps.CpsFlowDefinition" plugin="workflow-cps">
+    <script>node{echo 'hello'}</script>
+    <sandbox>true</sandbox>
+  </definition>
+  <triggers/>
+  <disabled>false</disabled>
+</flow-definition>`
+
+type fakeSource struct {
+	err error
+}
+
+func (f *fakeSource) ListJobs(folder string) ([]jenkins.JobSummary, error) {
+	return []jenkins.JobSummary{{Name: "build", Class: jenkins.WorkflowJobClass}}, f.err
+}
+
+func (f *fakeSource) GetJobConfig(folder, name string) (string, error) {
+	return pipelineConfig, nil
+}
+
+func TestReconciler_Reconcile(t *testing.T) {
+	schema, err := v1alpha3.SchemeBuilder.Register().Build()
+	assert.Nil(t, err)
+
+	project := &v1alpha3.DevOpsProject{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "team",
+			Annotations: map[string]string{
+				FolderAnnoKey: "team",
+			},
+		},
+		Status: v1alpha3.DevOpsProjectStatus{AdminNamespace: "team"},
+	}
+	dryRunProject := project.DeepCopy()
+	dryRunProject.Annotations[DryRunAnnoKey] = "true"
+	notReadyProject := project.DeepCopy()
+	notReadyProject.Status.AdminNamespace = ""
+	noFolderProject := project.DeepCopy()
+	noFolderProject.Annotations = nil
+	otherFolderProject := project.DeepCopy()
+	otherFolderProject.Annotations[FolderAnnoKey] = "team-other"
+
+	getReport := func(c client.Client) (report *jenkinsimport.Report) {
+		obj := &v1alpha3.DevOpsProject{}
+		if err := c.Get(context.Background(), types.NamespacedName{Name: "team"}, obj); err == nil {
+			if data, ok := obj.Annotations[ReportAnnoKey]; ok {
+				report = &jenkinsimport.Report{}
+				_ = json.Unmarshal([]byte(data), report)
+			}
+		}
+		return
+	}
+	pipelineExists := func(c client.Client) bool {
+		return c.Get(context.Background(), types.NamespacedName{Namespace: "team", Name: "build"},
+			&v1alpha3.Pipeline{}) == nil
+	}
+
+	tests := []struct {
+		name       string
+		objects    []runtime.Object
+		source     *fakeSource
+		wantResult ctrl.Result
+		wantErr    bool
+		verify     func(*testing.T, client.Client)
+	}{{
+		name: "not found",
+	}, {
+		name:    "without the folder annotation",
+		objects: []runtime.Object{noFolderProject},
+		verify: func(t *testing.T, c client.Client) {
+			assert.Nil(t, getReport(c))
+		},
+	}, {
+		name:       "the namespace is not ready",
+		objects:    []runtime.Object{notReadyProject},
+		wantResult: ctrl.Result{RequeueAfter: 5 * time.Second},
+	}, {
+		name:       "import the jobs",
+		objects:    []runtime.Object{project.DeepCopy()},
+		wantResult: ctrl.Result{RequeueAfter: DefaultInterval},
+		verify: func(t *testing.T, c client.Client) {
+			assert.True(t, pipelineExists(c))
+			report := getReport(c)
+			if assert.NotNil(t, report) {
+				assert.Equal(t, "team", report.Folder)
+				assert.Equal(t, 1, report.Count(jenkinsimport.ActionCreate))
+			}
+		},
+	}, {
+		name:    "the folder of another project",
+		objects: []runtime.Object{otherFolderProject},
+		verify: func(t *testing.T, c client.Client) {
+			assert.False(t, pipelineExists(c))
+			assert.Nil(t, getReport(c))
+		},
+	}, {
+		name:       "dry run",
+		objects:    []runtime.Object{dryRunProject},
+		wantResult: ctrl.Result{RequeueAfter: DefaultInterval},
+		verify: func(t *testing.T, c client.Client) {
+			assert.False(t, pipelineExists(c))
+			report := getReport(c)
+			if assert.NotNil(t, report) {
+				assert.True(t, report.DryRun)
+			}
+		},
+	}, {
+		name:    "failed to list the jobs",
+		objects: []runtime.Object{project.DeepCopy()},
+		source:  &fakeSource{err: errors.New("fake")},
+		wantErr: true,
+	}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.source == nil {
+				tt.source = &fakeSource{}
+			}
+			c := fake.NewClientBuilder().WithScheme(schema).WithRuntimeObjects(tt.objects...).Build()
+			r := &Reconciler{
+				Client:   c,
+				Source:   tt.source,
+				log:      logr.New(log.NullLogSink{}),
+				recorder: &record.FakeRecorder{},
+			}
+			result, err := r.Reconcile(context.Background(), ctrl.Request{
+				NamespacedName: types.NamespacedName{Name: "team"},
+			})
+			assert.Equal(t, tt.wantErr, err != nil, err)
+			assert.Equal(t, tt.wantResult, result)
+			if tt.verify != nil {
+				tt.verify(t, c)
+			}
+		})
+	}
+}
+
+func TestIsOwnFolder(t *testing.T) {
+	project := &v1alpha3.DevOpsProject{Status: v1alpha3.DevOpsProjectStatus{AdminNamespace: "team"}}
+	assert.True(t, isOwnFolder(project, "team"))
+	assert.True(t, isOwnFolder(project, "/team/"))
+	assert.True(t, isOwnFolder(project, "team/child"))
+	assert.False(t, isOwnFolder(project, "team-other"))
+	assert.False(t, isOwnFolder(project, "other/team"))
+	assert.False(t, isOwnFolder(project, ""))
+}
+
+func TestReconciler_SetupWithManager(t *testing.T) {
+	schema, err := v1alpha3.SchemeBuilder.Register().Build()
+	assert.Nil(t, err)
+
+	r := &Reconciler{}
+	err = r.SetupWithManager(&core.FakeManager{
+		Client: fake.NewClientBuilder().WithScheme(schema).Build(),
+		Scheme: schema,
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, "JenkinsImportReconciler", r.GetName())
+}
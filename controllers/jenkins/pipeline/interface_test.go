@@ -37,6 +37,12 @@ func TestInterfaceImplement(t *testing.T) {
 			NamedReconciler: &JenkinsfileReconciler{},
 			GroupReconciler: &JenkinsfileReconciler{},
 		},
+	}, {
+		name: "DriftReconciler",
+		instance: interInstance{
+			NamedReconciler: &DriftReconciler{},
+			GroupReconciler: &DriftReconciler{},
+		},
 	}}
 	for i := range tests {
 		tt := tests[i]
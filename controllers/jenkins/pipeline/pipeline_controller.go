@@ -47,6 +47,8 @@ import (
 
 	kubesphereclient "github.com/kubesphere/ks-devops/pkg/client/clientset/versioned"
 	devopsClient "github.com/kubesphere/ks-devops/pkg/client/devops"
+	"github.com/kubesphere/ks-devops/pkg/client/devops/jenkins"
+	"github.com/kubesphere/ks-devops/pkg/client/devops/sharding"
 	devopsinformers "github.com/kubesphere/ks-devops/pkg/client/informers/externalversions/devops/v1alpha3"
 	devopslisters "github.com/kubesphere/ks-devops/pkg/client/listers/devops/v1alpha3"
 	"github.com/kubesphere/ks-devops/pkg/constants"
@@ -70,6 +72,8 @@ type Controller struct {
 
 	workerLoopPeriod time.Duration
 	devopsClient     devopsClient.Interface
+	health           jenkins.HealthChecker
+	healths          sharding.HealthGetter
 }
 
 // NewController creates the controller instance
@@ -111,8 +115,9 @@ func NewController(client clientset.Interface,
 
 			_, requestSync := newPipeline.Annotations[devopsv1alpha3.PipelineRequestToSyncRunsAnnoKey]
 
+			// the status-only updates, such as the schedules, do not need to sync the Pipeline to Jenkins
 			if !reflect.DeepEqual(oldPipeline.Spec, newPipeline.Spec) ||
-				(!reflect.DeepEqual(oldPipeline.ObjectMeta, newPipeline.ObjectMeta) && !requestSync) {
+				(isMetadataChanged(oldPipeline.ObjectMeta, newPipeline.ObjectMeta) && !requestSync) {
 				v.enqueuePipeline(newObj)
 			}
 		},
@@ -121,6 +126,30 @@ func NewController(client clientset.Interface,
 	return v
 }
 
+// isMetadataChanged compares the metadata without the fields which change with every update, including the status ones
+func isMetadataChanged(oldMeta, newMeta metav1.ObjectMeta) bool {
+	oldMeta.ResourceVersion, newMeta.ResourceVersion = "", ""
+	oldMeta.ManagedFields, newMeta.ManagedFields = nil, nil
+	return !reflect.DeepEqual(oldMeta, newMeta)
+}
+
+// WithHealthChecker makes the controller wait for Jenkins to recover when it's unavailable.
+// The healths is optional, it returns the health of the Jenkins instance of a namespace if there are multiple instances.
+func (c *Controller) WithHealthChecker(health jenkins.HealthChecker, healths sharding.HealthGetter) *Controller {
+	c.health = health
+	c.healths = healths
+	return c
+}
+
+// getHealth returns the health of the Jenkins instance which hosts the Pipeline, it's nil if it's unknown
+func (c *Controller) getHealth(key string) (health jenkins.HealthChecker) {
+	namespace, _, err := cache.SplitMetaNamespaceKey(key)
+	if err == nil {
+		health, _ = sharding.GetHealth(c.healths, namespace, c.health)
+	}
+	return
+}
+
 // enqueuePipeline takes a Foo resource and converts it into a namespace/name
 // string which is then put onto the work workqueue. This method should *not* be
 // passed resources of any type other than DevOpsProject.
@@ -153,6 +182,13 @@ func (c *Controller) processNextWorkItem() bool {
 			return nil
 		}
 		if err := c.syncHandler(key); err != nil {
+			if health := c.getHealth(key); health != nil && !health.Status().Available() {
+				// retry after Jenkins recovers instead of in a tight loop
+				c.workqueue.Forget(obj)
+				c.workqueue.AddAfter(key, health.RetryAfter())
+				return fmt.Errorf("error syncing '%s': %s, Jenkins is unavailable, requeuing after %s",
+					key, err.Error(), health.RetryAfter())
+			}
 			c.workqueue.AddRateLimited(key)
 			return fmt.Errorf("error syncing '%s': %s, requeuing", key, err.Error())
 		}
@@ -26,6 +26,9 @@ import (
 	"github.com/golang/mock/gomock"
 	fakeDevOps "github.com/kubesphere/ks-devops/pkg/client/devops/fake"
 	"github.com/kubesphere/ks-devops/pkg/client/devops/jclient"
+	"github.com/kubesphere/ks-devops/pkg/client/devops/jenkins"
+	"github.com/kubesphere/ks-devops/pkg/client/devops/sharding"
+	"github.com/stretchr/testify/assert"
 
 	"github.com/kubesphere/ks-devops/pkg/constants"
 
@@ -438,3 +441,117 @@ func TestUpdatePipelineConfig(t *testing.T) {
 	f.expectPipeline = []*devops.Pipeline{expectPipeline}
 	f.run(getKey(modifiedPipeline, t))
 }
+
+type fakeHealthChecker struct {
+	status jenkins.HealthStatus
+}
+
+func (f *fakeHealthChecker) Status() jenkins.HealthStatus {
+	return f.status
+}
+
+func (f *fakeHealthChecker) RetryAfter() time.Duration {
+	return time.Hour
+}
+
+type fakeHealthGetter struct {
+	healths map[string]jenkins.HealthChecker
+}
+
+func (f *fakeHealthGetter) GetHealth(namespace string) (jenkins.HealthChecker, error) {
+	return f.healths[namespace], nil
+}
+
+func TestProcessNextWorkItemWhenJenkinsUnavailable(t *testing.T) {
+	tests := []struct {
+		name          string
+		health        jenkins.HealthChecker
+		healths       sharding.HealthGetter
+		expectRequeue int
+	}{{
+		name:          "without health checker",
+		expectRequeue: 1,
+	}, {
+		name:          "Jenkins is available",
+		health:        &fakeHealthChecker{status: jenkins.HealthStatus{State: jenkins.BreakerClosed}},
+		expectRequeue: 1,
+	}, {
+		name:          "Jenkins is unavailable",
+		health:        &fakeHealthChecker{status: jenkins.HealthStatus{State: jenkins.BreakerOpen}},
+		expectRequeue: 0,
+	}, {
+		name:   "the Jenkins instance of the Pipeline is unavailable",
+		health: &fakeHealthChecker{status: jenkins.HealthStatus{State: jenkins.BreakerClosed}},
+		healths: &fakeHealthGetter{healths: map[string]jenkins.HealthChecker{
+			"test-123": &fakeHealthChecker{status: jenkins.HealthStatus{State: jenkins.BreakerOpen}},
+		}},
+		expectRequeue: 0,
+	}, {
+		name:   "only another Jenkins instance is unavailable",
+		health: &fakeHealthChecker{status: jenkins.HealthStatus{State: jenkins.BreakerOpen}},
+		healths: &fakeHealthGetter{healths: map[string]jenkins.HealthChecker{
+			"test-123": &fakeHealthChecker{status: jenkins.HealthStatus{State: jenkins.BreakerClosed}},
+		}},
+		expectRequeue: 1,
+	}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newFixture(t)
+			// the Pipeline does not exist in the API server, so the sync fails
+			pipeline := newPipeline("test-123", "test", devops.PipelineSpec{}, false, false)
+			f.pipelineLister = append(f.pipelineLister, pipeline)
+			f.initDevOpsProject = "test-123"
+
+			c, _, _, _ := f.newController()
+			c.WithHealthChecker(tt.health, tt.healths)
+			key := getKey(pipeline, t)
+			c.workqueue.Add(key)
+
+			assert.True(t, c.processNextWorkItem())
+			assert.Equal(t, tt.expectRequeue, c.workqueue.NumRequeues(key))
+			c.workqueue.ShutDown()
+		})
+	}
+}
+
+func Test_isMetadataChanged(t *testing.T) {
+	oldMeta := metav1.ObjectMeta{
+		Name:            "test",
+		ResourceVersion: "1",
+		Annotations:     map[string]string{"a": "b"},
+		ManagedFields:   []metav1.ManagedFieldsEntry{{Manager: "controller"}},
+	}
+	tests := []struct {
+		name   string
+		update func(meta *metav1.ObjectMeta)
+		expect bool
+	}{{
+		name: "status-only update",
+		update: func(meta *metav1.ObjectMeta) {
+			meta.ResourceVersion = "2"
+			meta.ManagedFields = append(meta.ManagedFields, metav1.ManagedFieldsEntry{Manager: "scheduler", Subresource: "status"})
+		},
+		expect: false,
+	}, {
+		name: "the annotations changed",
+		update: func(meta *metav1.ObjectMeta) {
+			meta.ResourceVersion = "2"
+			meta.Annotations = map[string]string{"a": "c"}
+		},
+		expect: true,
+	}, {
+		name: "the finalizers changed",
+		update: func(meta *metav1.ObjectMeta) {
+			meta.Finalizers = []string{devops.PipelineFinalizerName}
+		},
+		expect: true,
+	}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			newMeta := *oldMeta.DeepCopy()
+			tt.update(&newMeta)
+			assert.Equal(t, tt.expect, isMetadataChanged(oldMeta, newMeta))
+			assert.Equal(t, "1", oldMeta.ResourceVersion)
+		})
+	}
+}
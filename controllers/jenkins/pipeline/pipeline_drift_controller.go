@@ -0,0 +1,348 @@
+/*
+Copyright 2022 The KubeSphere Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pipeline
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/kubesphere/ks-devops/pkg/api/devops/v1alpha3"
+	devopsClient "github.com/kubesphere/ks-devops/pkg/client/devops"
+	"github.com/kubesphere/ks-devops/pkg/constants"
+	"github.com/kubesphere/ks-devops/pkg/utils"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+)
+
+const (
+	// DriftPolicyAnnoKey is the annotation key of a DevOps project which decides how to handle the drifted Pipelines
+	DriftPolicyAnnoKey = v1alpha3.DevOpsProjectPrefix + "pipeline-drift-policy"
+	// DriftAnnoKey is the annotation key of the field-level differences between a Pipeline and its Jenkins job in JSON format
+	DriftAnnoKey = v1alpha3.PipelinePrefix + "drift"
+
+	// DriftPolicyIgnore skips the drift detection
+	DriftPolicyIgnore = "ignore"
+	// DriftPolicyReport records the drift without touching the Jenkins job, it is the default policy
+	DriftPolicyReport = "report"
+	// DriftPolicyApply records the drift, then re-applies the Pipeline as the source of truth
+	DriftPolicyApply = "apply"
+
+	// Drifted indicates the Jenkins job config is different from the Pipeline
+	Drifted = "Drifted"
+	// DriftReconciled indicates the Pipeline has been re-applied to the Jenkins job
+	DriftReconciled = "DriftReconciled"
+	// FailedDriftCheck indicates the controller fails to check or reconcile the drift
+	FailedDriftCheck = "FailedDriftCheck"
+
+	// DefaultDriftInterval is the default interval of the drift detection
+	DefaultDriftInterval = 5 * time.Minute
+
+	// maxDriftValueLength is the max length of a value in the drift report, the Jenkinsfile could be very long
+	maxDriftValueLength = 64
+)
+
+//+kubebuilder:rbac:groups=devops.kubesphere.io,resources=devopsprojects,verbs=get;list;watch
+//+kubebuilder:rbac:groups=devops.kubesphere.io,resources=pipelines,verbs=get;list;watch;update
+//+kubebuilder:rbac:groups="",resources=events,verbs=create;patch
+
+// DriftReconciler detects the drift between the Pipelines and the Jenkins job configs periodically.
+// The Jenkins job might be changed via the Jenkins UI, or restored from a backup,
+// those changes cannot be found by the Pipeline controller because it only syncs the changed spec.
+type DriftReconciler struct {
+	client.Client
+	DevOpsClient devopsClient.Interface
+	// Interval is the interval of the drift detection, the default value is DefaultDriftInterval
+	Interval time.Duration
+
+	log      logr.Logger
+	recorder record.EventRecorder
+}
+
+// FieldDiff represents a field which is different between a Pipeline and its Jenkins job
+type FieldDiff struct {
+	Path    string `json:"path"`
+	Desired string `json:"desired,omitempty"`
+	Actual  string `json:"actual,omitempty"`
+}
+
+// Reconcile compares the Pipeline with its Jenkins job config, then handles the drift according to the project policy
+func (r *DriftReconciler) Reconcile(ctx context.Context, req ctrl.Request) (result ctrl.Result, err error) {
+	pipeline := &v1alpha3.Pipeline{}
+	if err = r.Get(ctx, req.NamespacedName, pipeline); err != nil {
+		err = client.IgnoreNotFound(err)
+		return
+	}
+	if !pipeline.DeletionTimestamp.IsZero() {
+		return
+	}
+	result = ctrl.Result{RequeueAfter: r.getInterval()}
+
+	policy := r.getPolicy(ctx, pipeline.Namespace)
+	if policy == DriftPolicyIgnore || !isSynced(pipeline) {
+		// the unsynced Pipeline belongs to the Pipeline controller
+		return
+	}
+
+	var diffs []FieldDiff
+	var jobMissing bool
+	if diffs, jobMissing, err = r.detectDrift(pipeline); err != nil {
+		r.recorder.Eventf(pipeline, v1.EventTypeWarning, FailedDriftCheck,
+			"failed to get the Jenkins job config of Pipeline, error is: %v", err)
+		return
+	}
+
+	if len(diffs) > 0 {
+		r.recorder.Eventf(pipeline, v1.EventTypeWarning, Drifted,
+			"the Jenkins job config is different from the Pipeline, fields: %s", joinPaths(diffs))
+
+		if policy == DriftPolicyApply {
+			if jobMissing {
+				_, err = r.DevOpsClient.CreateProjectPipeline(pipeline.Namespace, pipeline)
+			} else {
+				_, err = r.DevOpsClient.UpdateProjectPipeline(pipeline.Namespace, pipeline)
+			}
+			if err != nil {
+				r.recorder.Eventf(pipeline, v1.EventTypeWarning, FailedDriftCheck,
+					"failed to re-apply the Pipeline to Jenkins, error is: %v", err)
+				return
+			}
+			r.recorder.Eventf(pipeline, v1.EventTypeNormal, DriftReconciled,
+				"the Pipeline has been re-applied to Jenkins")
+			diffs = nil
+		}
+	}
+	err = r.updateDriftReport(ctx, pipeline, diffs)
+	return
+}
+
+// detectDrift returns the field-level differences, and whether the Jenkins job is missing
+func (r *DriftReconciler) detectDrift(pipeline *v1alpha3.Pipeline) (diffs []FieldDiff, jobMissing bool, err error) {
+	var jenkinsPipeline *v1alpha3.Pipeline
+	if jenkinsPipeline, err = r.DevOpsClient.GetProjectPipelineConfig(pipeline.Namespace, pipeline.Name); err != nil {
+		if devopsClient.GetDevOpsStatusCode(err) == http.StatusNotFound {
+			jobMissing = true
+			diffs = []FieldDiff{{Path: ".", Desired: pipeline.Name}}
+			err = nil
+		}
+		return
+	}
+	diffs, err = diffPipelineSpec(pipeline.Spec, jenkinsPipeline.Spec)
+	return
+}
+
+func (r *DriftReconciler) updateDriftReport(ctx context.Context, pipeline *v1alpha3.Pipeline, diffs []FieldDiff) (err error) {
+	var report string
+	if len(diffs) > 0 {
+		var data []byte
+		if data, err = json.Marshal(diffs); err != nil {
+			return
+		}
+		report = string(data)
+	}
+
+	if pipeline.Annotations[DriftAnnoKey] == report {
+		return
+	}
+	if report == "" {
+		delete(pipeline.Annotations, DriftAnnoKey)
+	} else {
+		if pipeline.Annotations == nil {
+			pipeline.Annotations = map[string]string{}
+		}
+		pipeline.Annotations[DriftAnnoKey] = report
+	}
+	err = r.Update(ctx, pipeline)
+	return
+}
+
+// getPolicy returns the drift policy of the DevOps project, the name of a DevOps project is the name of its namespace
+func (r *DriftReconciler) getPolicy(ctx context.Context, namespace string) (policy string) {
+	policy = DriftPolicyReport
+	project := &v1alpha3.DevOpsProject{}
+	if err := r.Get(ctx, types.NamespacedName{Name: namespace}, project); err != nil {
+		r.log.V(6).Info(fmt.Sprintf("cannot get the DevOps project %s, use the default drift policy: %v", namespace, err))
+		return
+	}
+	switch value := strings.ToLower(project.Annotations[DriftPolicyAnnoKey]); value {
+	case DriftPolicyIgnore, DriftPolicyApply:
+		policy = value
+	}
+	return
+}
+
+func (r *DriftReconciler) getInterval() time.Duration {
+	if r.Interval <= 0 {
+		return DefaultDriftInterval
+	}
+	return r.Interval
+}
+
+// isSynced returns true if the current spec of the Pipeline has been synced to Jenkins
+func isSynced(pipeline *v1alpha3.Pipeline) bool {
+	annotations := pipeline.GetAnnotations()
+	if annotations[v1alpha3.PipelineSyncStatusAnnoKey] != constants.StatusSuccessful {
+		return false
+	}
+	specHash, ok := annotations[v1alpha3.PipelineSpecHash]
+	return !ok || specHash == utils.ComputeHash(pipeline.Spec)
+}
+
+// diffPipelineSpec compares the specs field by field, the paths are made of the JSON names
+func diffPipelineSpec(desired, actual v1alpha3.PipelineSpec) (diffs []FieldDiff, err error) {
+	// the triggers and schedules are handled by the controllers, they are never stored in the Jenkins job
+	desired.Triggers, actual.Triggers = nil, nil
+	desired.Schedules, actual.Schedules = nil, nil
+
+	var desiredObj, actualObj interface{}
+	if desiredObj, err = toUnstructured(desired); err != nil {
+		return
+	}
+	if actualObj, err = toUnstructured(actual); err != nil {
+		return
+	}
+	diffs = diffValue("", desiredObj, actualObj, diffs)
+	return
+}
+
+func toUnstructured(spec v1alpha3.PipelineSpec) (obj interface{}, err error) {
+	var data []byte
+	if data, err = json.Marshal(spec); err == nil {
+		err = json.Unmarshal(data, &obj)
+	}
+	return
+}
+
+func diffValue(path string, desired, actual interface{}, diffs []FieldDiff) []FieldDiff {
+	switch desiredVal := desired.(type) {
+	case map[string]interface{}:
+		if actualVal, ok := actual.(map[string]interface{}); ok {
+			keys := make([]string, 0, len(desiredVal)+len(actualVal))
+			for key := range desiredVal {
+				keys = append(keys, key)
+			}
+			for key := range actualVal {
+				if _, exist := desiredVal[key]; !exist {
+					keys = append(keys, key)
+				}
+			}
+			sort.Strings(keys)
+			for _, key := range keys {
+				diffs = diffValue(joinPath(path, key), desiredVal[key], actualVal[key], diffs)
+			}
+			return diffs
+		}
+	case []interface{}:
+		if actualVal, ok := actual.([]interface{}); ok && len(desiredVal) == len(actualVal) {
+			for i := range desiredVal {
+				diffs = diffValue(fmt.Sprintf("%s[%d]", path, i), desiredVal[i], actualVal[i], diffs)
+			}
+			return diffs
+		}
+	}
+
+	desiredText, actualText := toText(desired), toText(actual)
+	if desiredText != actualText {
+		diffs = append(diffs, FieldDiff{
+			Path:    path,
+			Desired: truncate(desiredText),
+			Actual:  truncate(actualText),
+		})
+	}
+	return diffs
+}
+
+func joinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+func toText(value interface{}) string {
+	switch val := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return val
+	default:
+		data, _ := json.Marshal(val)
+		return string(data)
+	}
+}
+
+func truncate(text string) string {
+	if len(text) > maxDriftValueLength {
+		return text[:maxDriftValueLength] + "..."
+	}
+	return text
+}
+
+func joinPaths(diffs []FieldDiff) string {
+	paths := make([]string, len(diffs))
+	for i := range diffs {
+		paths[i] = diffs[i].Path
+	}
+	return strings.Join(paths, ", ")
+}
+
+// driftPredicate only cares about the creation, the reconciler requeues the Pipeline itself
+var driftPredicate = predicate.Funcs{
+	CreateFunc: func(ce event.CreateEvent) bool {
+		return true
+	},
+	UpdateFunc: func(ue event.UpdateEvent) bool {
+		return false
+	},
+	DeleteFunc: func(de event.DeleteEvent) bool {
+		return false
+	},
+	GenericFunc: func(ge event.GenericEvent) bool {
+		return false
+	},
+}
+
+// GetName returns the name of this controller
+func (r *DriftReconciler) GetName() string {
+	return "PipelineDriftController"
+}
+
+// GetGroupName returns the group name of this controller
+func (r *DriftReconciler) GetGroupName() string {
+	return ControllerGroupName
+}
+
+// SetupWithManager setups the log and recorder
+func (r *DriftReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	r.log = ctrl.Log.WithName(r.GetName())
+	r.recorder = mgr.GetEventRecorderFor(r.GetName())
+	return ctrl.NewControllerManagedBy(mgr).
+		Named("jenkins_pipeline_drift_controller").
+		WithEventFilter(driftPredicate).
+		For(&v1alpha3.Pipeline{}).
+		Complete(r)
+}
@@ -0,0 +1,258 @@
+/*
+Copyright 2022 The KubeSphere Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pipeline
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/go-logr/logr"
+	"github.com/kubesphere/ks-devops/controllers/core"
+	"github.com/kubesphere/ks-devops/pkg/api/devops/v1alpha3"
+	fakeDevOps "github.com/kubesphere/ks-devops/pkg/client/devops/fake"
+	"github.com/kubesphere/ks-devops/pkg/constants"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+func TestDriftReconciler_Reconcile(t *testing.T) {
+	schema, err := v1alpha3.SchemeBuilder.Register().Build()
+	assert.Nil(t, err)
+
+	newPipeline := func(jenkinsfile string, annotations map[string]string) *v1alpha3.Pipeline {
+		return &v1alpha3.Pipeline{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace:   "team",
+				Name:        "build",
+				Annotations: annotations,
+			},
+			Spec: v1alpha3.PipelineSpec{
+				Type: v1alpha3.NoScmPipelineType,
+				Pipeline: &v1alpha3.NoScmPipeline{
+					Name:        "build",
+					Jenkinsfile: jenkinsfile,
+				},
+			},
+		}
+	}
+	synced := map[string]string{v1alpha3.PipelineSyncStatusAnnoKey: constants.StatusSuccessful}
+	scheduledPipeline := newPipeline("node{}", synced)
+	scheduledPipeline.Spec.Schedules = []v1alpha3.PipelineSchedule{{Name: "nightly", Cron: "@daily"}}
+	newProject := func(policy string) *v1alpha3.DevOpsProject {
+		return &v1alpha3.DevOpsProject{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        "team",
+				Annotations: map[string]string{DriftPolicyAnnoKey: policy},
+			},
+		}
+	}
+	getDrift := func(c client.Client) (diffs []FieldDiff, ok bool) {
+		pipeline := &v1alpha3.Pipeline{}
+		if err := c.Get(context.Background(), types.NamespacedName{Namespace: "team", Name: "build"}, pipeline); err == nil {
+			var report string
+			if report, ok = pipeline.Annotations[DriftAnnoKey]; ok {
+				_ = json.Unmarshal([]byte(report), &diffs)
+			}
+		}
+		return
+	}
+
+	tests := []struct {
+		name       string
+		objects    []runtime.Object
+		jobs       []*v1alpha3.Pipeline
+		wantResult ctrl.Result
+		wantErr    bool
+		verify     func(t *testing.T, c client.Client, devops *fakeDevOps.Devops)
+	}{{
+		name:       "not found",
+		wantResult: ctrl.Result{},
+	}, {
+		name:       "not synced yet",
+		objects:    []runtime.Object{newPipeline("node{}", nil)},
+		wantResult: ctrl.Result{RequeueAfter: DefaultDriftInterval},
+		verify: func(t *testing.T, c client.Client, devops *fakeDevOps.Devops) {
+			_, ok := getDrift(c)
+			assert.False(t, ok)
+		},
+	}, {
+		name:       "no drift",
+		objects:    []runtime.Object{newPipeline("node{}", synced)},
+		jobs:       []*v1alpha3.Pipeline{newPipeline("node{}", nil)},
+		wantResult: ctrl.Result{RequeueAfter: DefaultDriftInterval},
+		verify: func(t *testing.T, c client.Client, devops *fakeDevOps.Devops) {
+			_, ok := getDrift(c)
+			assert.False(t, ok)
+		},
+	}, {
+		name:       "no drift with the schedules",
+		objects:    []runtime.Object{scheduledPipeline},
+		jobs:       []*v1alpha3.Pipeline{newPipeline("node{}", nil)},
+		wantResult: ctrl.Result{RequeueAfter: DefaultDriftInterval},
+		verify: func(t *testing.T, c client.Client, devops *fakeDevOps.Devops) {
+			_, ok := getDrift(c)
+			assert.False(t, ok)
+		},
+	}, {
+		name:       "report the drift by default",
+		objects:    []runtime.Object{newPipeline("node{}", synced)},
+		jobs:       []*v1alpha3.Pipeline{newPipeline("node{echo 1}", nil)},
+		wantResult: ctrl.Result{RequeueAfter: DefaultDriftInterval},
+		verify: func(t *testing.T, c client.Client, devops *fakeDevOps.Devops) {
+			diffs, ok := getDrift(c)
+			assert.True(t, ok)
+			assert.Equal(t, []FieldDiff{{Path: "pipeline.jenkinsfile", Desired: "node{}", Actual: "node{echo 1}"}}, diffs)
+			assert.Equal(t, "node{echo 1}", devops.Pipelines["team"]["build"].Spec.Pipeline.Jenkinsfile)
+		},
+	}, {
+		name:       "ignore the drift",
+		objects:    []runtime.Object{newPipeline("node{}", synced), newProject(DriftPolicyIgnore)},
+		jobs:       []*v1alpha3.Pipeline{newPipeline("node{echo 1}", nil)},
+		wantResult: ctrl.Result{RequeueAfter: DefaultDriftInterval},
+		verify: func(t *testing.T, c client.Client, devops *fakeDevOps.Devops) {
+			_, ok := getDrift(c)
+			assert.False(t, ok)
+		},
+	}, {
+		name: "apply the Pipeline to the drifted job",
+		objects: []runtime.Object{newPipeline("node{}", map[string]string{
+			v1alpha3.PipelineSyncStatusAnnoKey: constants.StatusSuccessful,
+			DriftAnnoKey:                       `[{"path":"pipeline.jenkinsfile"}]`,
+		}), newProject("Apply")},
+		jobs:       []*v1alpha3.Pipeline{newPipeline("node{echo 1}", nil)},
+		wantResult: ctrl.Result{RequeueAfter: DefaultDriftInterval},
+		verify: func(t *testing.T, c client.Client, devops *fakeDevOps.Devops) {
+			_, ok := getDrift(c)
+			assert.False(t, ok)
+			assert.Equal(t, "node{}", devops.Pipelines["team"]["build"].Spec.Pipeline.Jenkinsfile)
+		},
+	}, {
+		name:       "re-create the missing job",
+		objects:    []runtime.Object{newPipeline("node{}", synced), newProject(DriftPolicyApply)},
+		wantResult: ctrl.Result{RequeueAfter: DefaultDriftInterval},
+		verify: func(t *testing.T, c client.Client, devops *fakeDevOps.Devops) {
+			if assert.NotNil(t, devops.Pipelines["team"]["build"]) {
+				assert.Equal(t, "node{}", devops.Pipelines["team"]["build"].Spec.Pipeline.Jenkinsfile)
+			}
+		},
+	}, {
+		name:       "report the missing job",
+		objects:    []runtime.Object{newPipeline("node{}", synced)},
+		wantResult: ctrl.Result{RequeueAfter: DefaultDriftInterval},
+		verify: func(t *testing.T, c client.Client, devops *fakeDevOps.Devops) {
+			diffs, ok := getDrift(c)
+			assert.True(t, ok)
+			assert.Equal(t, []FieldDiff{{Path: ".", Desired: "build"}}, diffs)
+			assert.Nil(t, devops.Pipelines["team"]["build"])
+		},
+	}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := fake.NewClientBuilder().WithScheme(schema).WithRuntimeObjects(tt.objects...).Build()
+			devops := fakeDevOps.NewWithPipelines("team", tt.jobs...)
+			r := &DriftReconciler{
+				Client:       c,
+				DevOpsClient: devops,
+				log:          logr.New(log.NullLogSink{}),
+				recorder:     &record.FakeRecorder{},
+			}
+			result, err := r.Reconcile(context.Background(), ctrl.Request{
+				NamespacedName: types.NamespacedName{Namespace: "team", Name: "build"},
+			})
+			assert.Equal(t, tt.wantErr, err != nil, err)
+			assert.Equal(t, tt.wantResult, result)
+			if tt.verify != nil {
+				tt.verify(t, c, devops)
+			}
+		})
+	}
+}
+
+func TestDiffPipelineSpec(t *testing.T) {
+	tests := []struct {
+		name    string
+		desired v1alpha3.PipelineSpec
+		actual  v1alpha3.PipelineSpec
+		want    []FieldDiff
+	}{{
+		name:    "same",
+		desired: v1alpha3.PipelineSpec{Type: v1alpha3.NoScmPipelineType},
+		actual:  v1alpha3.PipelineSpec{Type: v1alpha3.NoScmPipelineType},
+	}, {
+		name: "different fields",
+		desired: v1alpha3.PipelineSpec{Type: v1alpha3.NoScmPipelineType, Pipeline: &v1alpha3.NoScmPipeline{
+			Name:        "build",
+			Description: "desc",
+			Parameters:  []v1alpha3.ParameterDefinition{{Name: "a"}, {Name: "b"}},
+		}},
+		actual: v1alpha3.PipelineSpec{Type: v1alpha3.NoScmPipelineType, Pipeline: &v1alpha3.NoScmPipeline{
+			Name:       "build",
+			Parameters: []v1alpha3.ParameterDefinition{{Name: "a"}, {Name: "c"}},
+		}},
+		want: []FieldDiff{
+			{Path: "pipeline.description", Desired: "desc"},
+			{Path: "pipeline.parameters[1].name", Desired: "b", Actual: "c"},
+		},
+	}, {
+		name: "the triggers are not stored in Jenkins",
+		desired: v1alpha3.PipelineSpec{Type: v1alpha3.NoScmPipelineType, Triggers: &v1alpha3.PipelineTriggers{
+			Upstream: []v1alpha3.UpstreamTrigger{{Pipeline: "upstream"}},
+		}},
+		actual: v1alpha3.PipelineSpec{Type: v1alpha3.NoScmPipelineType},
+	}, {
+		name: "the schedules are not stored in Jenkins",
+		desired: v1alpha3.PipelineSpec{Type: v1alpha3.NoScmPipelineType, Schedules: []v1alpha3.PipelineSchedule{{
+			Name: "nightly",
+			Cron: "@daily",
+		}}},
+		actual: v1alpha3.PipelineSpec{Type: v1alpha3.NoScmPipelineType},
+	}, {
+		name:    "missing struct",
+		desired: v1alpha3.PipelineSpec{Type: v1alpha3.NoScmPipelineType, Pipeline: &v1alpha3.NoScmPipeline{Name: "build"}},
+		actual:  v1alpha3.PipelineSpec{Type: v1alpha3.NoScmPipelineType},
+		want:    []FieldDiff{{Path: "pipeline", Desired: `{"name":"build"}`}},
+	}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := diffPipelineSpec(tt.desired, tt.actual)
+			assert.Nil(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestDriftReconciler_SetupWithManager(t *testing.T) {
+	schema, err := v1alpha3.SchemeBuilder.Register().Build()
+	assert.Nil(t, err)
+
+	r := &DriftReconciler{}
+	err = r.SetupWithManager(&core.FakeManager{
+		Client: fake.NewClientBuilder().WithScheme(schema).Build(),
+		Scheme: schema,
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, "PipelineDriftController", r.GetName())
+	assert.Equal(t, ControllerGroupName, r.GetGroupName())
+}
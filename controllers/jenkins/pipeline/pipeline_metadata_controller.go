@@ -26,6 +26,7 @@ import (
 	"github.com/jenkins-zh/jenkins-client/pkg/core"
 	"github.com/jenkins-zh/jenkins-client/pkg/job"
 	"github.com/kubesphere/ks-devops/pkg/api/devops/v1alpha3"
+	"github.com/kubesphere/ks-devops/pkg/client/devops/sharding"
 	v1 "k8s.io/api/core/v1"
 	"k8s.io/client-go/tools/record"
 	"k8s.io/client-go/util/retry"
@@ -48,6 +49,9 @@ type Reconciler struct {
 	JenkinsCore core.JenkinsCore
 	recorder    record.EventRecorder
 	log         logr.Logger
+
+	// JenkinsCores returns the Jenkins core of a namespace if there are multiple Jenkins instances
+	JenkinsCores sharding.CoreGetter
 }
 
 //+kubebuilder:rbac:groups=devops.kubesphere.io,resources=pipelines,verbs=get;list;watch;create;update;patch;delete
@@ -94,8 +98,12 @@ func (r *Reconciler) onUpdateMetaSuccessfully(pipeline *v1alpha3.Pipeline) {
 }
 
 func (r *Reconciler) obtainAndUpdatePipelineMetadata(pipeline *v1alpha3.Pipeline) error {
+	jenkinsCore, err := sharding.GetJenkinsCore(r.JenkinsCores, pipeline.Namespace, r.JenkinsCore)
+	if err != nil {
+		return err
+	}
 	boClient := job.BlueOceanClient{
-		JenkinsCore:  r.JenkinsCore,
+		JenkinsCore:  jenkinsCore,
 		Organization: "jenkins",
 	}
 	// fetch pipeline metadata from Jenkins
@@ -117,8 +125,12 @@ func (r *Reconciler) obtainAndUpdatePipelineBranches(pipeline *v1alpha3.Pipeline
 		// skip non multi-branch Pipeline
 		return nil
 	}
+	jenkinsCore, err := sharding.GetJenkinsCore(r.JenkinsCores, pipeline.Namespace, r.JenkinsCore)
+	if err != nil {
+		return err
+	}
 	boClient := job.BlueOceanClient{
-		JenkinsCore:  r.JenkinsCore,
+		JenkinsCore:  jenkinsCore,
 		Organization: "jenkins",
 	}
 	jobBranches, err := boClient.GetBranches(job.GetBranchesOption{
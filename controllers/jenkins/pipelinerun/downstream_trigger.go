@@ -0,0 +1,193 @@
+/*
+Copyright 2022 The KubeSphere Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pipelinerun
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+
+	"github.com/go-logr/logr"
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+
+	"github.com/kubesphere/ks-devops/pkg/api/devops/v1alpha3"
+	"github.com/kubesphere/ks-devops/pkg/kapis/devops/v1alpha3/pipelinerun"
+	modelspipeline "github.com/kubesphere/ks-devops/pkg/models/pipeline"
+	modelspipelinerun "github.com/kubesphere/ks-devops/pkg/models/pipelinerun"
+)
+
+// Valid values for the event reasons of the downstream triggers
+const (
+	DownstreamTriggered     = "DownstreamTriggered"
+	FailedDownstreamTrigger = "FailedDownstreamTrigger"
+	DownstreamLoopDetected  = "DownstreamLoopDetected"
+)
+
+// UpstreamTriggerName is the value of the trigger annotation of the PipelineRuns created by the upstream triggers
+const UpstreamTriggerName = "upstream"
+
+// DownstreamReconciler creates the PipelineRuns of the downstream Pipelines once a PipelineRun finishes
+type DownstreamReconciler struct {
+	client.Client
+	log      logr.Logger
+	recorder record.EventRecorder
+}
+
+//+kubebuilder:rbac:groups=devops.kubesphere.io,resources=pipelines,verbs=get;list;watch
+//+kubebuilder:rbac:groups=devops.kubesphere.io,resources=pipelineruns,verbs=get;list;watch;create
+
+// Reconcile triggers the downstream Pipelines of a finished PipelineRun
+func (r *DownstreamReconciler) Reconcile(ctx context.Context, req ctrl.Request) (result ctrl.Result, err error) {
+	log := r.log.WithValues("PipelineRun", req.NamespacedName)
+	upstream := &v1alpha3.PipelineRun{}
+	if err = r.Get(ctx, req.NamespacedName, upstream); err != nil {
+		err = client.IgnoreNotFound(err)
+		return
+	}
+	// the history synchronized from Jenkins was finished before being created, it does not trigger anything
+	if !upstream.HasCompleted() || upstream.Status.CompletionTime.Before(&upstream.CreationTimestamp) {
+		return
+	}
+
+	pipelineList := &v1alpha3.PipelineList{}
+	if err = r.List(ctx, pipelineList, client.InNamespace(upstream.Namespace)); err != nil {
+		return
+	}
+	graph := modelspipeline.BuildGraph(pipelineList.Items)
+
+	var errs []error
+	for i := range pipelineList.Items {
+		downstream := &pipelineList.Items[i]
+		triggers := downstream.GetUpstreamTriggers()
+		for j := range triggers {
+			trigger := &triggers[j]
+			if !trigger.Matches(upstream) {
+				continue
+			}
+			if graph.InCycle(trigger.Pipeline, downstream.Name) {
+				log.Info("skip the downstream Pipeline in a loop", "downstream", downstream.Name)
+				r.recorder.Eventf(upstream, v1.EventTypeWarning, DownstreamLoopDetected,
+					"Pipeline %s is not triggered because it is in a loop of the upstream triggers", downstream.Name)
+				break
+			}
+			if triggerErr := r.triggerDownstream(ctx, upstream, downstream, trigger); triggerErr != nil {
+				errs = append(errs, triggerErr)
+			}
+			// only one PipelineRun is created even if multiple triggers match
+			break
+		}
+	}
+	err = utilerrors.NewAggregate(errs)
+	return
+}
+
+func (r *DownstreamReconciler) triggerDownstream(ctx context.Context, upstream *v1alpha3.PipelineRun,
+	downstream *v1alpha3.Pipeline, trigger *v1alpha3.UpstreamTrigger) (err error) {
+	scm, scmErr := pipelinerun.CreateScm(&downstream.Spec, trigger.GetTargetBranch(upstream))
+	if scmErr != nil {
+		// it cannot be fixed by retrying, so the error is only recorded
+		r.recorder.Eventf(upstream, v1.EventTypeWarning, FailedDownstreamTrigger,
+			"Failed to trigger Pipeline %s, error: %v", downstream.Name, scmErr)
+		return
+	}
+
+	parameters, validateErr := modelspipelinerun.GetDownstreamParameters(trigger, upstream, downstream)
+	if validateErr != nil {
+		r.recorder.Eventf(upstream, v1.EventTypeWarning, FailedDownstreamTrigger,
+			"Invalid parameters of Pipeline %s: %v", downstream.Name, validateErr)
+		return
+	}
+
+	downstream.SetGroupVersionKind(v1alpha3.GroupVersion.WithKind(v1alpha3.ResourceKindPipeline))
+	run := pipelinerun.CreateBarePipelineRun(downstream, parameters, scm)
+	// the downstream PipelineRun might be created already when the reconciliation is retried,
+	// the name is fixed so that it's not created twice even if the cache is not up-to-date
+	run.GenerateName = ""
+	run.Name = getDownstreamPipelineRunName(upstream, downstream)
+	run.Annotations[v1alpha3.PipelineRunTriggerAnnoKey] = UpstreamTriggerName
+	run.Annotations[v1alpha3.PipelineRunUpstreamAnnoKey] = upstream.Name
+	if creator, ok := upstream.Annotations[v1alpha3.PipelineRunCreatorAnnoKey]; ok {
+		run.Annotations[v1alpha3.PipelineRunCreatorAnnoKey] = creator
+	}
+	run.Labels[v1alpha3.PipelineRunUpstreamUIDLabelKey] = string(upstream.UID)
+
+	if err = r.Create(ctx, run); err != nil {
+		if apierrors.IsAlreadyExists(err) {
+			// it's created by a previous reconciliation
+			err = nil
+			return
+		}
+		r.recorder.Eventf(upstream, v1.EventTypeWarning, FailedDownstreamTrigger,
+			"Failed to trigger Pipeline %s, error: %v", downstream.Name, err)
+		return
+	}
+	r.recorder.Eventf(upstream, v1.EventTypeNormal, DownstreamTriggered,
+		"Triggered Pipeline %s with PipelineRun %s", downstream.Name, run.Name)
+	return
+}
+
+// getDownstreamPipelineRunName returns the name of the PipelineRun which is triggered by the upstream PipelineRun,
+// it's like "pipeline-xxxxxxxxxx" and no longer than 63 characters as the generated names
+func getDownstreamPipelineRunName(upstream *v1alpha3.PipelineRun, downstream *v1alpha3.Pipeline) string {
+	const maxNameLength, hashLength = 63, 10
+	hash := fmt.Sprintf("%x", sha256.Sum256([]byte(string(upstream.UID)+"/"+downstream.Name)))[:hashLength]
+	prefix := downstream.Name
+	if maxPrefixLength := maxNameLength - hashLength - 1; len(prefix) > maxPrefixLength {
+		prefix = prefix[:maxPrefixLength]
+	}
+	return prefix + "-" + hash
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *DownstreamReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	r.recorder = mgr.GetEventRecorderFor("pipelinerun-downstream-trigger")
+	r.log = ctrl.Log.WithName("pipelinerun-downstream-trigger")
+
+	return ctrl.NewControllerManagedBy(mgr).
+		Named("pipelinerun_downstream_trigger").
+		For(&v1alpha3.PipelineRun{}).
+		WithEventFilter(pipelineRunCompletedPredicate()).
+		Complete(r)
+}
+
+// pipelineRunCompletedPredicate only accepts the PipelineRuns which just finished,
+// so the existing PipelineRuns do not trigger anything when the controller starts
+func pipelineRunCompletedPredicate() predicate.Predicate {
+	return predicate.Funcs{
+		CreateFunc: func(e event.CreateEvent) bool {
+			return false
+		},
+		UpdateFunc: func(e event.UpdateEvent) bool {
+			oldRun, oldOK := e.ObjectOld.(*v1alpha3.PipelineRun)
+			newRun, newOK := e.ObjectNew.(*v1alpha3.PipelineRun)
+			return oldOK && newOK && !oldRun.HasCompleted() && newRun.HasCompleted()
+		},
+		DeleteFunc: func(e event.DeleteEvent) bool {
+			return false
+		},
+		GenericFunc: func(e event.GenericEvent) bool {
+			return false
+		},
+	}
+}
@@ -0,0 +1,257 @@
+/*
+Copyright 2022 The KubeSphere Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pipelinerun
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/kubesphere/ks-devops/pkg/api/devops/v1alpha3"
+)
+
+func newDownstreamPipeline(name string, pipelineType v1alpha3.PipelineType, triggers ...v1alpha3.UpstreamTrigger) *v1alpha3.Pipeline {
+	pipeline := &v1alpha3.Pipeline{
+		ObjectMeta: v1.ObjectMeta{Namespace: "ns", Name: name},
+		Spec:       v1alpha3.PipelineSpec{Type: pipelineType},
+	}
+	if len(triggers) > 0 {
+		pipeline.Spec.Triggers = &v1alpha3.PipelineTriggers{Upstream: triggers}
+	}
+	return pipeline
+}
+
+func newUpstreamPipelineRun(pipeline string, phase v1alpha3.RunPhase, creation, completion time.Time) *v1alpha3.PipelineRun {
+	return &v1alpha3.PipelineRun{
+		ObjectMeta: v1.ObjectMeta{
+			Namespace:         "ns",
+			Name:              pipeline + "-abcde",
+			UID:               "fake-uid",
+			CreationTimestamp: v1.NewTime(creation),
+			Labels:            map[string]string{v1alpha3.PipelineNameLabelKey: pipeline},
+			Annotations:       map[string]string{v1alpha3.PipelineRunCreatorAnnoKey: "admin"},
+		},
+		Spec: v1alpha3.PipelineRunSpec{
+			Parameters: []v1alpha3.Parameter{{Name: "version", Value: "v1.0.0"}},
+		},
+		Status: v1alpha3.PipelineRunStatus{
+			Phase:          phase,
+			CompletionTime: &v1.Time{Time: completion},
+		},
+	}
+}
+
+func TestDownstreamReconciler_Reconcile(t *testing.T) {
+	schema, err := v1alpha3.SchemeBuilder.Register().Build()
+	assert.Nil(t, err)
+	now := time.Now()
+
+	tests := []struct {
+		name          string
+		objects       []client.Object
+		verify        func(t *testing.T, runs []v1alpha3.PipelineRun)
+		expectEventsN int
+	}{{
+		name:    "not found",
+		objects: []client.Object{},
+		verify: func(t *testing.T, runs []v1alpha3.PipelineRun) {
+			assert.Empty(t, runs)
+		},
+	}, {
+		name: "trigger the downstream Pipeline with parameters and artifacts",
+		objects: []client.Object{
+			newUpstreamPipelineRun("build", v1alpha3.Succeeded, now.Add(-time.Minute), now),
+			newDownstreamPipeline("build", v1alpha3.NoScmPipelineType),
+			newDownstreamPipeline("deploy", v1alpha3.NoScmPipelineType, v1alpha3.UpstreamTrigger{
+				Pipeline:   "build",
+				Parameters: []v1alpha3.UpstreamParameter{{Name: "version"}},
+				Artifacts:  []v1alpha3.UpstreamArtifact{{Filename: "app.jar", Parameter: "package"}},
+			}),
+			newDownstreamPipeline("rollback", v1alpha3.NoScmPipelineType, v1alpha3.UpstreamTrigger{
+				Pipeline: "build",
+				Phases:   []v1alpha3.RunPhase{v1alpha3.Failed},
+			}),
+		},
+		verify: func(t *testing.T, runs []v1alpha3.PipelineRun) {
+			if assert.Len(t, runs, 2) {
+				run := runs[1]
+				assert.Equal(t, "deploy", run.Labels[v1alpha3.PipelineNameLabelKey])
+				assert.Equal(t, getDownstreamPipelineRunName(newUpstreamPipelineRun("build", v1alpha3.Succeeded, now, now),
+					newDownstreamPipeline("deploy", "")), run.Name)
+				assert.Equal(t, "fake-uid", run.Labels[v1alpha3.PipelineRunUpstreamUIDLabelKey])
+				assert.Equal(t, UpstreamTriggerName, run.Annotations[v1alpha3.PipelineRunTriggerAnnoKey])
+				assert.Equal(t, "build-abcde", run.Annotations[v1alpha3.PipelineRunUpstreamAnnoKey])
+				assert.Equal(t, "admin", run.Annotations[v1alpha3.PipelineRunCreatorAnnoKey])
+				assert.Equal(t, []v1alpha3.Parameter{
+					{Name: "version", Value: "v1.0.0"},
+					{Name: "package", Value: "/kapis/devops.kubesphere.io/v1alpha3/namespaces/ns/pipelineruns/build-abcde/artifacts/download?filename=app.jar"},
+				}, run.Spec.Parameters)
+				assert.Equal(t, "Pipeline", run.OwnerReferences[0].Kind)
+			}
+		},
+		expectEventsN: 1,
+	}, {
+		name: "the downstream PipelineRun was created by a previous reconciliation",
+		objects: []client.Object{
+			newUpstreamPipelineRun("build", v1alpha3.Succeeded, now.Add(-time.Minute), now),
+			newDownstreamPipeline("deploy", v1alpha3.NoScmPipelineType, v1alpha3.UpstreamTrigger{Pipeline: "build"}),
+			&v1alpha3.PipelineRun{ObjectMeta: v1.ObjectMeta{
+				Namespace: "ns",
+				Name: getDownstreamPipelineRunName(newUpstreamPipelineRun("build", v1alpha3.Succeeded, now, now),
+					newDownstreamPipeline("deploy", "")),
+			}},
+		},
+		verify: func(t *testing.T, runs []v1alpha3.PipelineRun) {
+			assert.Len(t, runs, 2)
+		},
+	}, {
+		name: "the parameters are invalid for the downstream Pipeline",
+		objects: []client.Object{
+			newUpstreamPipelineRun("build", v1alpha3.Succeeded, now.Add(-time.Minute), now),
+			func() *v1alpha3.Pipeline {
+				pipeline := newDownstreamPipeline("deploy", v1alpha3.NoScmPipelineType, v1alpha3.UpstreamTrigger{
+					Pipeline:   "build",
+					Parameters: []v1alpha3.UpstreamParameter{{Name: "version"}},
+				})
+				pipeline.Spec.Pipeline = &v1alpha3.NoScmPipeline{Parameters: []v1alpha3.ParameterDefinition{
+					{Name: "version", Type: v1alpha3.BooleanParameterType},
+				}}
+				return pipeline
+			}(),
+		},
+		verify: func(t *testing.T, runs []v1alpha3.PipelineRun) {
+			assert.Len(t, runs, 1)
+		},
+		// the event is recorded in both reconciliations
+		expectEventsN: 2,
+	}, {
+		name: "the loop is not triggered",
+		objects: []client.Object{
+			newUpstreamPipelineRun("build", v1alpha3.Succeeded, now.Add(-time.Minute), now),
+			newDownstreamPipeline("build", v1alpha3.NoScmPipelineType, v1alpha3.UpstreamTrigger{Pipeline: "deploy"}),
+			newDownstreamPipeline("deploy", v1alpha3.NoScmPipelineType, v1alpha3.UpstreamTrigger{Pipeline: "build"}),
+		},
+		verify: func(t *testing.T, runs []v1alpha3.PipelineRun) {
+			assert.Len(t, runs, 1)
+		},
+		// the event is recorded in both reconciliations
+		expectEventsN: 2,
+	}, {
+		name: "the history synchronized from Jenkins",
+		objects: []client.Object{
+			newUpstreamPipelineRun("build", v1alpha3.Succeeded, now, now.Add(-time.Hour)),
+			newDownstreamPipeline("deploy", v1alpha3.NoScmPipelineType, v1alpha3.UpstreamTrigger{Pipeline: "build"}),
+		},
+		verify: func(t *testing.T, runs []v1alpha3.PipelineRun) {
+			assert.Len(t, runs, 1)
+		},
+	}, {
+		name: "a multi-branch Pipeline without a branch",
+		objects: []client.Object{
+			newUpstreamPipelineRun("build", v1alpha3.Succeeded, now.Add(-time.Minute), now),
+			newDownstreamPipeline("deploy", v1alpha3.MultiBranchPipelineType, v1alpha3.UpstreamTrigger{Pipeline: "build"}),
+		},
+		verify: func(t *testing.T, runs []v1alpha3.PipelineRun) {
+			assert.Len(t, runs, 1)
+		},
+		// the event is recorded in both reconciliations
+		expectEventsN: 2,
+	}, {
+		name: "a multi-branch Pipeline with the target branch",
+		objects: []client.Object{
+			newUpstreamPipelineRun("build", v1alpha3.Succeeded, now.Add(-time.Minute), now),
+			newDownstreamPipeline("deploy", v1alpha3.MultiBranchPipelineType, v1alpha3.UpstreamTrigger{
+				Pipeline:     "build",
+				TargetBranch: "main",
+			}),
+		},
+		verify: func(t *testing.T, runs []v1alpha3.PipelineRun) {
+			if assert.Len(t, runs, 2) {
+				assert.Equal(t, &v1alpha3.SCM{RefName: "main"}, runs[1].Spec.SCM)
+			}
+		},
+		expectEventsN: 1,
+	}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder := record.NewFakeRecorder(10)
+			r := &DownstreamReconciler{
+				Client:   fake.NewClientBuilder().WithScheme(schema).WithObjects(tt.objects...).Build(),
+				log:      logr.New(log.NullLogSink{}),
+				recorder: recorder,
+			}
+			req := ctrl.Request{NamespacedName: types.NamespacedName{Namespace: "ns", Name: "build-abcde"}}
+			_, err := r.Reconcile(context.Background(), req)
+			assert.Nil(t, err)
+			// the reconciliation is idempotent
+			_, err = r.Reconcile(context.Background(), req)
+			assert.Nil(t, err)
+
+			runs := &v1alpha3.PipelineRunList{}
+			assert.Nil(t, r.List(context.Background(), runs))
+			// the upstream PipelineRun is the first one since the names of the downstream ones have a hash suffix
+			for i := range runs.Items {
+				if runs.Items[i].Name == "build-abcde" && i != 0 {
+					runs.Items[0], runs.Items[i] = runs.Items[i], runs.Items[0]
+				}
+			}
+			tt.verify(t, runs.Items)
+			assert.Equal(t, tt.expectEventsN, len(recorder.Events))
+		})
+	}
+}
+
+func Test_getDownstreamPipelineRunName(t *testing.T) {
+	upstream := newUpstreamPipelineRun("build", v1alpha3.Succeeded, time.Now(), time.Now())
+	name := getDownstreamPipelineRunName(upstream, newDownstreamPipeline("deploy", ""))
+	assert.Regexp(t, "^deploy-[0-9a-f]{10}$", name)
+	assert.Equal(t, name, getDownstreamPipelineRunName(upstream, newDownstreamPipeline("deploy", "")))
+	assert.NotEqual(t, name, getDownstreamPipelineRunName(upstream, newDownstreamPipeline("rollback", "")))
+
+	anotherUpstream := upstream.DeepCopy()
+	anotherUpstream.UID = "another-uid"
+	assert.NotEqual(t, name, getDownstreamPipelineRunName(anotherUpstream, newDownstreamPipeline("deploy", "")))
+
+	longName := getDownstreamPipelineRunName(upstream, newDownstreamPipeline(strings.Repeat("a", 100), ""))
+	assert.Len(t, longName, 63)
+	assert.Regexp(t, "^a{52}-[0-9a-f]{10}$", longName)
+}
+
+func Test_pipelineRunCompletedPredicate(t *testing.T) {
+	p := pipelineRunCompletedPredicate()
+	running := &v1alpha3.PipelineRun{}
+	completed := &v1alpha3.PipelineRun{Status: v1alpha3.PipelineRunStatus{CompletionTime: &v1.Time{Time: time.Now()}}}
+
+	assert.False(t, p.Create(event.CreateEvent{Object: completed}))
+	assert.False(t, p.Delete(event.DeleteEvent{Object: completed}))
+	assert.False(t, p.Generic(event.GenericEvent{Object: completed}))
+	assert.True(t, p.Update(event.UpdateEvent{ObjectOld: running, ObjectNew: completed}))
+	assert.False(t, p.Update(event.UpdateEvent{ObjectOld: completed, ObjectNew: completed}))
+	assert.False(t, p.Update(event.UpdateEvent{ObjectOld: running, ObjectNew: running}))
+}
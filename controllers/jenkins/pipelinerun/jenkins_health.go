@@ -0,0 +1,91 @@
+/*
+Copyright 2022 The KubeSphere Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pipelinerun
+
+import (
+	"context"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	"github.com/kubesphere/ks-devops/pkg/api/devops/v1alpha3"
+	"github.com/kubesphere/ks-devops/pkg/client/devops/jenkins"
+)
+
+const (
+	// JenkinsUnavailableReason is the reason of the condition when a PipelineRun is held because Jenkins is unavailable
+	JenkinsUnavailableReason = "JenkinsUnavailable"
+	// JenkinsRecoveredReason is the reason of the condition when a held PipelineRun is released
+	JenkinsRecoveredReason = "JenkinsRecovered"
+)
+
+// waitForJenkins keeps the PipelineRun pending until its Jenkins instance recovers, instead of failing in a tight loop
+func (r *Reconciler) waitForJenkins(ctx context.Context, pr *v1alpha3.PipelineRun, health jenkins.HealthChecker) (ctrl.Result, error) {
+	if !pr.HasStarted() && holdForJenkins(&pr.Status, health.Status()) {
+		if err := r.updateStatus(ctx, &pr.Status, r.req.NamespacedName); err != nil {
+			r.log.Error(err, "unable to update PipelineRun status.")
+			return ctrl.Result{}, err
+		}
+		r.recorder.Eventf(pr, corev1.EventTypeWarning, JenkinsUnavailableReason,
+			"PipelineRun %s is pending until Jenkins recovers", r.req.NamespacedName)
+	}
+	return ctrl.Result{RequeueAfter: health.RetryAfter()}, nil
+}
+
+// isHeldForJenkins returns true if the PipelineRun is pending because Jenkins is unavailable
+func isHeldForJenkins(status *v1alpha3.PipelineRunStatus) bool {
+	condition := status.GetLatestCondition()
+	return status.Phase == v1alpha3.Pending && condition != nil && condition.Reason == JenkinsUnavailableReason
+}
+
+// holdForJenkins marks the PipelineRun as pending because Jenkins is unavailable, returns true if the status changed
+func holdForJenkins(status *v1alpha3.PipelineRunStatus, health jenkins.HealthStatus) bool {
+	if isHeldForJenkins(status) {
+		return false
+	}
+	now := v1.Now()
+	status.Phase = v1alpha3.Pending
+	status.AddCondition(&v1alpha3.Condition{
+		Type:               v1alpha3.ConditionReady,
+		Status:             v1alpha3.ConditionFalse,
+		LastProbeTime:      now,
+		LastTransitionTime: now,
+		Reason:             JenkinsUnavailableReason,
+		Message:            fmt.Sprintf("waiting for Jenkins to recover, last error: %s", health.LastError),
+	})
+	status.UpdateTime = &now
+	return true
+}
+
+// releaseFromJenkins marks a held PipelineRun as released after it was triggered
+func releaseFromJenkins(status *v1alpha3.PipelineRunStatus) {
+	if !isHeldForJenkins(status) {
+		return
+	}
+	now := v1.Now()
+	status.Phase = v1alpha3.Running
+	status.AddCondition(&v1alpha3.Condition{
+		Type:               v1alpha3.ConditionReady,
+		Status:             v1alpha3.ConditionUnknown,
+		LastProbeTime:      now,
+		LastTransitionTime: now,
+		Reason:             JenkinsRecoveredReason,
+		Message:            "Jenkins has recovered, and the PipelineRun was triggered",
+	})
+}
@@ -0,0 +1,145 @@
+/*
+Copyright 2022 The KubeSphere Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pipelinerun
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/kubesphere/ks-devops/pkg/api/devops/v1alpha3"
+	"github.com/kubesphere/ks-devops/pkg/client/devops/jenkins"
+)
+
+type fakeHealthChecker struct {
+	status jenkins.HealthStatus
+}
+
+func (f *fakeHealthChecker) Status() jenkins.HealthStatus {
+	return f.status
+}
+
+func (f *fakeHealthChecker) RetryAfter() time.Duration {
+	return time.Minute
+}
+
+func Test_holdForJenkins(t *testing.T) {
+	health := jenkins.HealthStatus{State: jenkins.BreakerOpen, LastError: "connection refused"}
+
+	status := &v1alpha3.PipelineRunStatus{}
+	assert.True(t, holdForJenkins(status, health))
+	assert.True(t, isHeldForJenkins(status))
+	assert.Equal(t, v1alpha3.Pending, status.Phase)
+	assert.NotNil(t, status.UpdateTime)
+	condition := status.GetLatestCondition()
+	assert.Equal(t, v1alpha3.ConditionReady, condition.Type)
+	assert.Equal(t, v1alpha3.ConditionFalse, condition.Status)
+	assert.Equal(t, JenkinsUnavailableReason, condition.Reason)
+	assert.Equal(t, "waiting for Jenkins to recover, last error: connection refused", condition.Message)
+
+	// hold only once
+	assert.False(t, holdForJenkins(status, health))
+	assert.Equal(t, 1, len(status.Conditions))
+
+	releaseFromJenkins(status)
+	assert.False(t, isHeldForJenkins(status))
+	assert.Equal(t, v1alpha3.Running, status.Phase)
+	assert.Equal(t, JenkinsRecoveredReason, status.GetLatestCondition().Reason)
+	assert.Equal(t, 1, len(status.Conditions))
+
+	// nothing changes if the PipelineRun was not held
+	status = &v1alpha3.PipelineRunStatus{}
+	releaseFromJenkins(status)
+	assert.Equal(t, &v1alpha3.PipelineRunStatus{}, status)
+}
+
+func TestReconciler_waitForJenkins(t *testing.T) {
+	schema, err := v1alpha3.SchemeBuilder.Register().Build()
+	assert.Nil(t, err)
+
+	tests := []struct {
+		name          string
+		pipelineRun   *v1alpha3.PipelineRun
+		expectPhase   v1alpha3.RunPhase
+		expectReason  string
+		expectEventsN int
+	}{{
+		name: "not started",
+		pipelineRun: &v1alpha3.PipelineRun{
+			ObjectMeta: v1.ObjectMeta{Namespace: "ns", Name: "run"},
+		},
+		expectPhase:   v1alpha3.Pending,
+		expectReason:  JenkinsUnavailableReason,
+		expectEventsN: 1,
+	}, {
+		name: "already held",
+		pipelineRun: &v1alpha3.PipelineRun{
+			ObjectMeta: v1.ObjectMeta{Namespace: "ns", Name: "run"},
+			Status: v1alpha3.PipelineRunStatus{
+				Phase:      v1alpha3.Pending,
+				Conditions: []v1alpha3.Condition{{Type: v1alpha3.ConditionReady, Reason: JenkinsUnavailableReason}},
+			},
+		},
+		expectPhase:  v1alpha3.Pending,
+		expectReason: JenkinsUnavailableReason,
+	}, {
+		name: "started",
+		pipelineRun: &v1alpha3.PipelineRun{
+			ObjectMeta: v1.ObjectMeta{
+				Namespace:   "ns",
+				Name:        "run",
+				Annotations: map[string]string{v1alpha3.JenkinsPipelineRunIDAnnoKey: "1"},
+			},
+			Status: v1alpha3.PipelineRunStatus{Phase: v1alpha3.Running},
+		},
+		expectPhase: v1alpha3.Running,
+	}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder := record.NewFakeRecorder(10)
+			r := &Reconciler{
+				Client: fake.NewClientBuilder().WithScheme(schema).WithObjects(tt.pipelineRun.DeepCopy()).
+					WithStatusSubresource(tt.pipelineRun.DeepCopy()).Build(),
+				log:      logr.New(log.NullLogSink{}),
+				recorder: recorder,
+				req:      ctrl.Request{NamespacedName: types.NamespacedName{Namespace: "ns", Name: "run"}},
+				Health:   &fakeHealthChecker{status: jenkins.HealthStatus{State: jenkins.BreakerOpen}},
+			}
+
+			result, err := r.waitForJenkins(context.Background(), tt.pipelineRun.DeepCopy(), r.Health)
+			assert.Nil(t, err)
+			assert.Equal(t, ctrl.Result{RequeueAfter: time.Minute}, result)
+
+			pipelineRun := &v1alpha3.PipelineRun{}
+			assert.Nil(t, r.Client.Get(context.Background(), r.req.NamespacedName, pipelineRun))
+			assert.Equal(t, tt.expectPhase, pipelineRun.Status.Phase)
+			if tt.expectReason != "" {
+				assert.Equal(t, tt.expectReason, pipelineRun.Status.GetLatestCondition().Reason)
+			}
+			assert.Equal(t, tt.expectEventsN, len(recorder.Events))
+		})
+	}
+}
@@ -37,6 +37,9 @@ import (
 
 	"github.com/kubesphere/ks-devops/pkg/api/devops/v1alpha3"
 	devopsClient "github.com/kubesphere/ks-devops/pkg/client/devops"
+	"github.com/kubesphere/ks-devops/pkg/client/devops/jenkins"
+	"github.com/kubesphere/ks-devops/pkg/client/devops/sharding"
+	"github.com/kubesphere/ks-devops/pkg/models/pipelinerun"
 	cmstore "github.com/kubesphere/ks-devops/pkg/store/configmap"
 	storeInter "github.com/kubesphere/ks-devops/pkg/store/store"
 	"github.com/kubesphere/ks-devops/pkg/utils/k8sutil"
@@ -56,10 +59,18 @@ type Reconciler struct {
 	JenkinsCore          core.JenkinsCore
 	recorder             record.EventRecorder
 	PipelineRunDataStore string
+
+	// JenkinsCores returns the Jenkins core of a namespace if there are multiple Jenkins instances
+	JenkinsCores sharding.CoreGetter
+	// Health holds the PipelineRuns when Jenkins is unavailable, it's optional
+	Health jenkins.HealthChecker
+	// Healths returns the health of the Jenkins instance of a namespace if there are multiple Jenkins instances
+	Healths sharding.HealthGetter
 }
 
 //+kubebuilder:rbac:groups=devops.kubesphere.io,resources=pipelineruns,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups=devops.kubesphere.io,resources=pipelineruns/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups="",resources=secrets,verbs=get
 
 // Reconcile is part of the main kubernetes reconciliation loop which aims to
 // move the current state of the cluster closer to the desired state.
@@ -75,7 +86,11 @@ func (r *Reconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Resu
 		return ctrl.Result{}, client.IgnoreNotFound(err)
 	}
 
-	jHandler := &jenkinsHandler{&r.JenkinsCore}
+	jenkinsCore, err := sharding.GetJenkinsCore(r.JenkinsCores, pipelineRun.Namespace, r.JenkinsCore)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	jHandler := &jenkinsHandler{&jenkinsCore}
 
 	// don't modify the cache in other places, like informer cache.
 	pipelineRunCopied := pipelineRun.DeepCopy()
@@ -121,6 +136,18 @@ func (r *Reconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Resu
 
 	log = log.WithValues("namespace", namespaceName, "Pipeline", pipelineName)
 
+	// hold the PipelineRun until its Jenkins instance recovers
+	var health jenkins.HealthChecker
+	if health, err = sharding.GetHealth(r.Healths, namespaceName, r.Health); err != nil {
+		return ctrl.Result{}, err
+	}
+	if health != nil {
+		if status := health.Status(); !status.Available() {
+			log.V(4).Info("Jenkins is unavailable, waiting for it to recover", "error", status.LastError)
+			return r.waitForJenkins(ctx, pipelineRunCopied, health)
+		}
+	}
+
 	// check PipelineRun status
 	if pipelineRunCopied.HasStarted() {
 		log.V(5).Info("pipeline has already started, and we are retrieving run data from Jenkins.")
@@ -136,6 +163,18 @@ func (r *Reconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Resu
 			return ctrl.Result{}, err
 		}
 
+		// record the commit which was built, the commit status is reported with it
+		if pipelineBuild.CommitID != "" && pipelineRunCopied.Annotations[v1alpha3.PipelineRunCommitAnnoKey] != pipelineBuild.CommitID {
+			if pipelineRunCopied.Annotations == nil {
+				pipelineRunCopied.Annotations = make(map[string]string)
+			}
+			pipelineRunCopied.Annotations[v1alpha3.PipelineRunCommitAnnoKey] = pipelineBuild.CommitID
+			if err := r.updateLabelsAndAnnotations(ctx, pipelineRunCopied); err != nil {
+				log.Error(err, "unable to update the commit of PipelineRun.")
+				return ctrl.Result{}, err
+			}
+		}
+
 		nodeDetails, err := jHandler.getPipelineNodeDetails(pipelineName, namespaceName, pipelineRunCopied)
 		if err != nil {
 			log.Error(err, "unable to get PipelineRun nodes detail")
@@ -177,9 +216,17 @@ func (r *Reconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Resu
 	}
 
 	// create trigger handler
-	triggerHandler := &jenkinsHandler{&r.JenkinsCore}
+	triggerHandler := &jenkinsHandler{&jenkinsCore}
+	// the values of password parameters are only passed to Jenkins, they are never persisted
+	runSpec := pipelineRunCopied.Spec.DeepCopy()
+	if runSpec.Parameters, err = pipelinerun.ResolveParameters(ctx, r.Client, namespaceName,
+		pipeline.GetParameterDefinitions(), runSpec.Parameters); err != nil {
+		log.Error(err, "unable to resolve the parameters", "namespace", namespaceName, "pipeline", pipeline.Name)
+		r.recorder.Eventf(pipelineRunCopied, corev1.EventTypeWarning, v1alpha3.TriggerFailed, "Failed to resolve the parameters of PipelineRun %s, and error was %v", req.NamespacedName, err)
+		return ctrl.Result{}, err
+	}
 	// first run
-	jobRun, err := triggerHandler.triggerJenkinsJob(namespaceName, pipelineName, &pipelineRunCopied.Spec)
+	jobRun, err := triggerHandler.triggerJenkinsJob(namespaceName, pipelineName, runSpec)
 	if err != nil {
 		log.Error(err, "unable to run pipeline", "namespace", namespaceName, "pipeline", pipeline.Name)
 		r.recorder.Eventf(pipelineRunCopied, corev1.EventTypeWarning, v1alpha3.TriggerFailed, "Failed to trigger PipelineRun %s, and error was %v", req.NamespacedName, err)
@@ -212,6 +259,7 @@ func (r *Reconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Resu
 		return ctrl.Result{}, err
 	}
 
+	releaseFromJenkins(&pipelineRunCopied.Status)
 	pipelineRunCopied.Status.StartTime = &v1.Time{Time: time.Now()}
 	pipelineRunCopied.Status.UpdateTime = &v1.Time{Time: time.Now()}
 	// due to the status is subresource of PipelineRun, we have to update status separately.
@@ -0,0 +1,206 @@
+/*
+Copyright 2022 The KubeSphere Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pipelinerun
+
+import (
+	"context"
+	"reflect"
+	"strconv"
+	"time"
+
+	"github.com/go-logr/logr"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/clock"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+
+	"github.com/kubesphere/ks-devops/pkg/api/devops/v1alpha3"
+	"github.com/kubesphere/ks-devops/pkg/kapis/devops/v1alpha3/pipelinerun"
+	modelspipelinerun "github.com/kubesphere/ks-devops/pkg/models/pipelinerun"
+)
+
+// Valid values for the event reasons of the schedules
+const (
+	PipelineRunScheduled      = "PipelineRunScheduled"
+	PipelineRunScheduleMissed = "PipelineRunScheduleMissed"
+	FailedPipelineRunSchedule = "FailedPipelineRunSchedule"
+)
+
+// ScheduleTriggerName is the value of the trigger annotation of the scheduled PipelineRuns
+const ScheduleTriggerName = "schedule"
+
+// MaxScheduleHistory is the max number of the scheduled times kept in the status of a schedule
+const MaxScheduleHistory = 10
+
+// ScheduleReconciler creates PipelineRuns according to the schedules of Pipelines, it's similar to the CronJob controller
+type ScheduleReconciler struct {
+	client.Client
+	// Clock is the real clock by default
+	Clock    clock.PassiveClock
+	log      logr.Logger
+	recorder record.EventRecorder
+}
+
+//+kubebuilder:rbac:groups=devops.kubesphere.io,resources=pipelines,verbs=get;list;watch
+//+kubebuilder:rbac:groups=devops.kubesphere.io,resources=pipelines/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups=devops.kubesphere.io,resources=pipelineruns,verbs=get;list;watch;create
+
+// Reconcile creates the PipelineRuns which are due, and requeues the Pipeline for the next schedule time
+func (r *ScheduleReconciler) Reconcile(ctx context.Context, req ctrl.Request) (result ctrl.Result, err error) {
+	log := r.log.WithValues("Pipeline", req.NamespacedName)
+	pipeline := &v1alpha3.Pipeline{}
+	if err = r.Get(ctx, req.NamespacedName, pipeline); err != nil {
+		err = client.IgnoreNotFound(err)
+		return
+	}
+
+	now := r.Clock.Now()
+	var statuses []v1alpha3.ScheduleStatus
+	for i := range pipeline.Spec.Schedules {
+		schedule := &pipeline.Spec.Schedules[i]
+		status := findScheduleStatus(pipeline.Status.Schedules, schedule.Name)
+
+		decision, decideErr := modelspipelinerun.DecideSchedule(schedule, status, now)
+		if decideErr != nil {
+			log.Error(decideErr, "invalid schedule", "schedule", schedule.Name)
+			r.recorder.Eventf(pipeline, v1.EventTypeWarning, FailedPipelineRunSchedule, decideErr.Error())
+			continue
+		}
+
+		if status == nil {
+			status = &v1alpha3.ScheduleStatus{Name: schedule.Name}
+		}
+		status.Cron = schedule.Cron
+		status.TimeZone = schedule.TimeZone
+		if !decision.ScheduledTime.IsZero() {
+			scheduleRecord := v1alpha3.ScheduleRecord{
+				ScheduledTime: metav1.NewTime(decision.ScheduledTime),
+				Missed:        decision.Missed,
+			}
+			if decision.Missed > 0 {
+				r.recorder.Eventf(pipeline, v1.EventTypeWarning, PipelineRunScheduleMissed,
+					"Missed %d schedule(s) of %s before %s", decision.Missed, schedule.Name, decision.ScheduledTime.Format(time.RFC3339))
+			}
+			if decision.Run {
+				var run *v1alpha3.PipelineRun
+				if run, err = r.createScheduledPipelineRun(ctx, pipeline, schedule, decision.ScheduledTime); err != nil {
+					// the status is not updated, so the PipelineRun will be created in the next reconciliation
+					return
+				}
+				if run != nil {
+					scheduleRecord.PipelineRun = run.Name
+					status.LastScheduleTime = &scheduleRecord.ScheduledTime
+				}
+			}
+			status.History = append([]v1alpha3.ScheduleRecord{scheduleRecord}, status.History...)
+			if len(status.History) > MaxScheduleHistory {
+				status.History = status.History[:MaxScheduleHistory]
+			}
+		}
+		status.NextScheduleTime = &metav1.Time{Time: decision.Next}
+		statuses = append(statuses, *status)
+
+		if requeueAfter := decision.Next.Sub(now); result.RequeueAfter == 0 || requeueAfter < result.RequeueAfter {
+			result.RequeueAfter = requeueAfter
+		}
+	}
+
+	if !reflect.DeepEqual(pipeline.Status.Schedules, statuses) {
+		pipeline.Status.Schedules = statuses
+		err = r.Status().Update(ctx, pipeline)
+	}
+	return
+}
+
+// createScheduledPipelineRun creates a PipelineRun for the scheduled time, it returns nil if the schedule is invalid
+func (r *ScheduleReconciler) createScheduledPipelineRun(ctx context.Context, pipeline *v1alpha3.Pipeline,
+	schedule *v1alpha3.PipelineSchedule, scheduledTime time.Time) (run *v1alpha3.PipelineRun, err error) {
+	scheduledTimeLabel := strconv.FormatInt(scheduledTime.Unix(), 10)
+	// the PipelineRun might be created already if the status failed to update
+	existing := &v1alpha3.PipelineRunList{}
+	if err = r.List(ctx, existing, client.InNamespace(pipeline.Namespace), client.MatchingLabels{
+		v1alpha3.PipelineNameLabelKey:             pipeline.Name,
+		v1alpha3.PipelineRunScheduleLabelKey:      schedule.Name,
+		v1alpha3.PipelineRunScheduledTimeLabelKey: scheduledTimeLabel,
+	}); err != nil {
+		return
+	}
+	if len(existing.Items) > 0 {
+		run = &existing.Items[0]
+		return
+	}
+
+	parameters, validateErr := v1alpha3.ValidateParameters(pipeline.GetParameterDefinitions(), schedule.Parameters)
+	if validateErr != nil {
+		r.recorder.Eventf(pipeline, v1.EventTypeWarning, FailedPipelineRunSchedule,
+			"Invalid parameters of schedule %s: %v", schedule.Name, validateErr)
+		return
+	}
+	scm, scmErr := pipelinerun.CreateScm(&pipeline.Spec, schedule.Branch)
+	if scmErr != nil {
+		r.recorder.Eventf(pipeline, v1.EventTypeWarning, FailedPipelineRunSchedule,
+			"Invalid branch of schedule %s: %v", schedule.Name, scmErr)
+		return
+	}
+
+	pipeline.SetGroupVersionKind(v1alpha3.GroupVersion.WithKind(v1alpha3.ResourceKindPipeline))
+	run = pipelinerun.CreateBarePipelineRun(pipeline, parameters, scm)
+	run.Annotations[v1alpha3.PipelineRunTriggerAnnoKey] = ScheduleTriggerName
+	run.Labels[v1alpha3.PipelineRunScheduleLabelKey] = schedule.Name
+	run.Labels[v1alpha3.PipelineRunScheduledTimeLabelKey] = scheduledTimeLabel
+	if err = r.Create(ctx, run); err != nil {
+		r.recorder.Eventf(pipeline, v1.EventTypeWarning, FailedPipelineRunSchedule,
+			"Failed to create PipelineRun for schedule %s: %v", schedule.Name, err)
+		return
+	}
+	r.recorder.Eventf(pipeline, v1.EventTypeNormal, PipelineRunScheduled,
+		"Created PipelineRun %s for schedule %s", run.Name, schedule.Name)
+	return
+}
+
+func findScheduleStatus(statuses []v1alpha3.ScheduleStatus, name string) *v1alpha3.ScheduleStatus {
+	for i := range statuses {
+		if statuses[i].Name == name {
+			return statuses[i].DeepCopy()
+		}
+	}
+	return nil
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *ScheduleReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	r.recorder = mgr.GetEventRecorderFor("pipelinerun-scheduler")
+	r.log = ctrl.Log.WithName("pipelinerun-scheduler")
+	if r.Clock == nil {
+		r.Clock = clock.RealClock{}
+	}
+
+	return ctrl.NewControllerManagedBy(mgr).
+		Named("pipelinerun_scheduler").
+		For(&v1alpha3.Pipeline{}).
+		WithEventFilter(predicate.And(predicate.GenerationChangedPredicate{}, predicate.NewPredicateFuncs(hasSchedules))).
+		Complete(r)
+}
+
+// hasSchedules returns true if the Pipeline has schedules, or the status of the removed schedules
+func hasSchedules(object client.Object) bool {
+	pipeline, ok := object.(*v1alpha3.Pipeline)
+	return ok && (len(pipeline.Spec.Schedules) > 0 || len(pipeline.Status.Schedules) > 0)
+}
@@ -0,0 +1,188 @@
+/*
+Copyright 2022 The KubeSphere Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pipelinerun
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	clocktesting "k8s.io/utils/clock/testing"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/kubesphere/ks-devops/pkg/api/devops/v1alpha3"
+)
+
+func newScheduledPipeline(status []v1alpha3.ScheduleStatus, schedules ...v1alpha3.PipelineSchedule) *v1alpha3.Pipeline {
+	return &v1alpha3.Pipeline{
+		ObjectMeta: v1.ObjectMeta{Namespace: "ns", Name: "pipeline"},
+		Spec: v1alpha3.PipelineSpec{
+			Type:      v1alpha3.NoScmPipelineType,
+			Schedules: schedules,
+			Pipeline: &v1alpha3.NoScmPipeline{
+				Parameters: []v1alpha3.ParameterDefinition{{Name: "env", Type: "string", DefaultValue: "dev"}},
+			},
+		},
+		Status: v1alpha3.PipelineStatus{Schedules: status},
+	}
+}
+
+func TestScheduleReconciler_Reconcile(t *testing.T) {
+	schema, err := v1alpha3.SchemeBuilder.Register().Build()
+	assert.Nil(t, err)
+	now := time.Date(2022, 3, 1, 10, 0, 30, 0, time.UTC)
+	dueStatus := []v1alpha3.ScheduleStatus{{
+		Name:             "nightly",
+		Cron:             "0 10 * * *",
+		NextScheduleTime: &v1.Time{Time: time.Date(2022, 3, 1, 10, 0, 0, 0, time.UTC)},
+	}}
+	nightly := v1alpha3.PipelineSchedule{Name: "nightly", Cron: "0 10 * * *",
+		Parameters: []v1alpha3.Parameter{{Name: "env", Value: "prod"}}}
+
+	tests := []struct {
+		name          string
+		objects       []client.Object
+		verify        func(t *testing.T, pipeline *v1alpha3.Pipeline, runs []v1alpha3.PipelineRun)
+		expectRequeue time.Duration
+		expectEventsN int
+	}{{
+		name:    "not found",
+		objects: nil,
+		verify: func(t *testing.T, pipeline *v1alpha3.Pipeline, runs []v1alpha3.PipelineRun) {
+			assert.Empty(t, runs)
+		},
+	}, {
+		name:    "new schedule, only the next schedule time is recorded",
+		objects: []client.Object{newScheduledPipeline(nil, nightly)},
+		verify: func(t *testing.T, pipeline *v1alpha3.Pipeline, runs []v1alpha3.PipelineRun) {
+			assert.Empty(t, runs)
+			if assert.Len(t, pipeline.Status.Schedules, 1) {
+				status := pipeline.Status.Schedules[0]
+				assert.Equal(t, "0 10 * * *", status.Cron)
+				assert.True(t, time.Date(2022, 3, 2, 10, 0, 0, 0, time.UTC).Equal(status.NextScheduleTime.Time))
+				assert.Nil(t, status.LastScheduleTime)
+			}
+		},
+		expectRequeue: 24*time.Hour - 30*time.Second,
+	}, {
+		name:    "due schedule, a PipelineRun is created",
+		objects: []client.Object{newScheduledPipeline(dueStatus, nightly)},
+		verify: func(t *testing.T, pipeline *v1alpha3.Pipeline, runs []v1alpha3.PipelineRun) {
+			if assert.Len(t, runs, 1) {
+				run := runs[0]
+				assert.Equal(t, ScheduleTriggerName, run.Annotations[v1alpha3.PipelineRunTriggerAnnoKey])
+				assert.Equal(t, "nightly", run.Labels[v1alpha3.PipelineRunScheduleLabelKey])
+				assert.Equal(t, "1646128800", run.Labels[v1alpha3.PipelineRunScheduledTimeLabelKey])
+				assert.Equal(t, []v1alpha3.Parameter{{Name: "env", Value: "prod"}}, run.Spec.Parameters)
+
+				status := pipeline.Status.Schedules[0]
+				assert.True(t, time.Date(2022, 3, 1, 10, 0, 0, 0, time.UTC).Equal(status.LastScheduleTime.Time))
+				if assert.Len(t, status.History, 1) {
+					assert.Equal(t, run.Name, status.History[0].PipelineRun)
+				}
+			}
+		},
+		expectRequeue: 24*time.Hour - 30*time.Second,
+		expectEventsN: 1,
+	}, {
+		name: "due schedule, the PipelineRun exists already",
+		objects: []client.Object{newScheduledPipeline(dueStatus, nightly), &v1alpha3.PipelineRun{
+			ObjectMeta: v1.ObjectMeta{Namespace: "ns", Name: "pipeline-abcde", Labels: map[string]string{
+				v1alpha3.PipelineNameLabelKey:             "pipeline",
+				v1alpha3.PipelineRunScheduleLabelKey:      "nightly",
+				v1alpha3.PipelineRunScheduledTimeLabelKey: "1646128800",
+			}},
+		}},
+		verify: func(t *testing.T, pipeline *v1alpha3.Pipeline, runs []v1alpha3.PipelineRun) {
+			assert.Len(t, runs, 1)
+			assert.Equal(t, "pipeline-abcde", pipeline.Status.Schedules[0].History[0].PipelineRun)
+		},
+		expectRequeue: 24*time.Hour - 30*time.Second,
+	}, {
+		name: "suspended schedule",
+		objects: []client.Object{newScheduledPipeline(dueStatus, v1alpha3.PipelineSchedule{
+			Name: "nightly", Cron: "0 10 * * *", Suspend: true})},
+		verify: func(t *testing.T, pipeline *v1alpha3.Pipeline, runs []v1alpha3.PipelineRun) {
+			assert.Empty(t, runs)
+			assert.Empty(t, pipeline.Status.Schedules[0].History)
+		},
+		expectRequeue: 24*time.Hour - 30*time.Second,
+	}, {
+		name: "invalid parameters",
+		objects: []client.Object{newScheduledPipeline(dueStatus, v1alpha3.PipelineSchedule{
+			Name: "nightly", Cron: "0 10 * * *", Parameters: []v1alpha3.Parameter{{Name: "unknown", Value: "a"}}})},
+		verify: func(t *testing.T, pipeline *v1alpha3.Pipeline, runs []v1alpha3.PipelineRun) {
+			assert.Empty(t, runs)
+			if assert.Len(t, pipeline.Status.Schedules[0].History, 1) {
+				assert.Empty(t, pipeline.Status.Schedules[0].History[0].PipelineRun)
+			}
+			assert.Nil(t, pipeline.Status.Schedules[0].LastScheduleTime)
+		},
+		expectRequeue: 24*time.Hour - 30*time.Second,
+		expectEventsN: 1,
+	}, {
+		name: "invalid cron, the status of the schedule is removed",
+		objects: []client.Object{newScheduledPipeline(dueStatus, v1alpha3.PipelineSchedule{
+			Name: "nightly", Cron: "invalid"})},
+		verify: func(t *testing.T, pipeline *v1alpha3.Pipeline, runs []v1alpha3.PipelineRun) {
+			assert.Empty(t, runs)
+			assert.Empty(t, pipeline.Status.Schedules)
+		},
+		expectEventsN: 1,
+	}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := fake.NewClientBuilder().WithScheme(schema).
+				WithStatusSubresource(&v1alpha3.Pipeline{}).
+				WithObjects(tt.objects...).Build()
+			recorder := record.NewFakeRecorder(10)
+			r := &ScheduleReconciler{
+				Client:   c,
+				Clock:    clocktesting.NewFakePassiveClock(now),
+				log:      logr.New(log.NullLogSink{}),
+				recorder: recorder,
+			}
+			result, err := r.Reconcile(context.Background(), ctrl.Request{
+				NamespacedName: types.NamespacedName{Namespace: "ns", Name: "pipeline"},
+			})
+			assert.Nil(t, err)
+			assert.Equal(t, tt.expectRequeue, result.RequeueAfter)
+			assert.Equal(t, tt.expectEventsN, len(recorder.Events))
+
+			pipeline := &v1alpha3.Pipeline{}
+			_ = c.Get(context.Background(), types.NamespacedName{Namespace: "ns", Name: "pipeline"}, pipeline)
+			runs := &v1alpha3.PipelineRunList{}
+			assert.Nil(t, c.List(context.Background(), runs))
+			tt.verify(t, pipeline, runs.Items)
+		})
+	}
+}
+
+func Test_hasSchedules(t *testing.T) {
+	assert.False(t, hasSchedules(&v1alpha3.PipelineRun{}))
+	assert.False(t, hasSchedules(newScheduledPipeline(nil)))
+	assert.True(t, hasSchedules(newScheduledPipeline(nil, v1alpha3.PipelineSchedule{Name: "a"})))
+	assert.True(t, hasSchedules(newScheduledPipeline([]v1alpha3.ScheduleStatus{{Name: "a"}})))
+}
@@ -23,6 +23,7 @@ import (
 	"github.com/jenkins-zh/jenkins-client/pkg/core"
 	"github.com/jenkins-zh/jenkins-client/pkg/job"
 	"github.com/kubesphere/ks-devops/pkg/api/devops/v1alpha3"
+	"github.com/kubesphere/ks-devops/pkg/client/devops/sharding"
 	"github.com/kubesphere/ks-devops/pkg/kapis/devops/v1alpha3/pipelinerun"
 	v1 "k8s.io/api/core/v1"
 	utilerrors "k8s.io/apimachinery/pkg/util/errors"
@@ -48,6 +49,8 @@ type SyncReconciler struct {
 	log         logr.Logger
 	recorder    record.EventRecorder
 	JenkinsCore core.JenkinsCore
+	// JenkinsCores returns the Jenkins core of a namespace if there are multiple Jenkins instances
+	JenkinsCores sharding.CoreGetter
 }
 
 // Reconcile is part of the main kubernetes reconciliation loop which aims to
@@ -76,8 +79,12 @@ func (r *SyncReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.
 		return ctrl.Result{}, nil
 	}
 
+	jenkinsCore, err := sharding.GetJenkinsCore(r.JenkinsCores, pipeline.Namespace, r.JenkinsCore)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
 	boClient := job.BlueOceanClient{
-		JenkinsCore:  r.JenkinsCore,
+		JenkinsCore:  jenkinsCore,
 		Organization: "jenkins",
 	}
 
@@ -199,7 +206,7 @@ func createBarePipelineRun(pipeline *v1alpha3.Pipeline, run *job.PipelineRun) *v
 		scm = &v1alpha3.SCM{}
 		scm.RefName = run.Pipeline
 	}
-	pr := pipelinerun.CreatePipelineRun(pipeline, nil, scm)
+	pr := pipelinerun.CreateBarePipelineRun(pipeline, nil, scm)
 	pr.Annotations[v1alpha3.JenkinsPipelineRunIDAnnoKey] = run.ID
 	return pr
 }
@@ -31,12 +31,38 @@ type AddonSpec struct {
 
 // AddonStatus represents the status of an addon
 type AddonStatus struct {
-	Phase string `json:"phase,omitempty"`
+	// Phase is the installation phase of the addon
+	Phase AddonPhase `json:"phase,omitempty"`
+	// Version is the installed version of the addon
+	Version string `json:"version,omitempty"`
+	// Conditions are the latest observations of the addon
+	// +optional
+	// +patchMergeKey=type
+	// +patchStrategy=merge
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
 }
 
+// AddonPhase represents the installation phase of an addon
+type AddonPhase string
+
+const (
+	// AddonPhaseInstalling indicates the addon is being installed
+	AddonPhaseInstalling AddonPhase = "Installing"
+	// AddonPhaseUpgrading indicates the addon is being upgraded from the installed version
+	AddonPhaseUpgrading AddonPhase = "Upgrading"
+	// AddonPhaseInstalled indicates the addon was installed successfully
+	AddonPhaseInstalled AddonPhase = "Installed"
+	// AddonPhaseFailed indicates the addon was failed to install or upgrade
+	AddonPhaseFailed AddonPhase = "Failed"
+)
+
+// AddonConditionReady is the condition type which indicates if the addon is ready
+const AddonConditionReady = "Ready"
+
 // +genclient
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
 // +kubebuilder:printcolumn:name="Version",type=string,JSONPath=`.spec.version`,description="The version of target addon"
+// +kubebuilder:printcolumn:name="Phase",type=string,JSONPath=`.status.phase`,description="The phase of target addon"
 // +kubebuilder:object:root=true
 // +k8s:openapi-gen=true
 
@@ -61,6 +87,9 @@ type AddonList struct {
 // AddonFinalizerName is the name of Addone finalizer
 const AddonFinalizerName = "addon.finalizers.kubesphere.io"
 
+// AddonLabelKey is the label key of the resources which belong to an addon
+const AddonLabelKey = "devops.kubesphere.io/addon"
+
 func init() {
 	SchemeBuilder.Register(&Addon{}, &AddonList{})
 }
@@ -29,8 +29,43 @@ type AddStrategySpec struct {
 	Operator       v1.ObjectReference   `json:"operator,omitempty"`
 	SimpleOperator v1.ObjectReference   `json:"simpleOperator,omitempty"`
 	HelmRepo       string               `json:"helmRepo,omitempty"`
+	Chart          string               `json:"chart,omitempty"`
 	Template       string               `json:"template,omitempty"`
 	Parameters     map[string]string    `json:"parameters,omitempty"`
+	// Compatibility describes the supported versions and the upgrade paths
+	// +optional
+	Compatibility *AddonCompatibility `json:"compatibility,omitempty"`
+	// HealthCheck describes how to check if the created object of a simple-operator addon is ready
+	// +optional
+	HealthCheck *AddonHealthCheck `json:"healthCheck,omitempty"`
+}
+
+// AddonCompatibility describes the version compatibility of an addon
+type AddonCompatibility struct {
+	// Versions is a semantic version range of the supported versions, such as ">=2.3.0 <3.0.0"
+	Versions string `json:"versions,omitempty"`
+	// Upgrades are the allowed upgrade paths, any upgrade is allowed if it's empty
+	Upgrades []AddonUpgradePath `json:"upgrades,omitempty"`
+}
+
+// AddonUpgradePath represents an allowed upgrade path of an addon
+type AddonUpgradePath struct {
+	// From is a semantic version range of the installed version
+	From string `json:"from"`
+	// To is a semantic version range of the desired version
+	To string `json:"to"`
+	// PreUpgrade is a template of the resources which need to be completed before the upgrade, such as a Job
+	PreUpgrade string `json:"preUpgrade,omitempty"`
+	// PostUpgrade is a template of the resources which need to be completed after the upgrade
+	PostUpgrade string `json:"postUpgrade,omitempty"`
+}
+
+// AddonHealthCheck describes how to check if an object is ready
+type AddonHealthCheck struct {
+	// FieldPath is the path of the field which indicates the readiness, such as status.phase
+	FieldPath string `json:"fieldPath"`
+	// Value is the expected value of the field, such as Available
+	Value string `json:"value"`
 }
 
 // AddonInstallStrategy represents the addon installation strategy
@@ -51,6 +51,38 @@ const (
 	SSHAuthPassphraseKey = "passphrase"
 	// SSHAuthPrivateKey is the key of the privatekey for SecretTypeSSHAuth secrets
 	SSHAuthPrivateKey = "private_key"
+	// SSHAuthKnownHostsKey is the key of the optional known_hosts for SecretTypeSSHAuth secrets
+	SSHAuthKnownHostsKey = "known_hosts"
+
+	// SecretTypeGitHubApp contains data needed for the GitHub App installation authentication.
+	//
+	// Required fields:
+	// - Secret.Data["app_id"] - the ID of the GitHub App
+	// - Secret.Data["installation_id"] - the installation ID of the GitHub App
+	// - Secret.Data["private_key"] - the PEM encoded private key of the GitHub App
+	// Optional fields:
+	// - Secret.Data["api_url"] - the API address of GitHub, https://api.github.com is the default value
+	SecretTypeGitHubApp v1.SecretType = DevOpsCredentialPrefix + "github-app"
+	// GitHubAppIDKey is the key of the App ID for SecretTypeGitHubApp secrets
+	GitHubAppIDKey = "app_id"
+	// GitHubAppInstallationIDKey is the key of the installation ID for SecretTypeGitHubApp secrets
+	GitHubAppInstallationIDKey = "installation_id"
+	// GitHubAppPrivateKey is the key of the private key for SecretTypeGitHubApp secrets
+	GitHubAppPrivateKey = "private_key"
+	// GitHubAppAPIURLKey is the key of the API address for SecretTypeGitHubApp secrets
+	GitHubAppAPIURLKey = "api_url"
+
+	// SecretTypeGPGKey contains data needed for the OpenPGP signing.
+	//
+	// Required fields:
+	// - Secret.Data["private_key"] - the armored private key
+	// Optional fields:
+	// - Secret.Data["passphrase"] - the passphrase of the private key
+	SecretTypeGPGKey v1.SecretType = DevOpsCredentialPrefix + "gpg-key"
+	// GPGKeyPrivateKey is the key of the armored private key for SecretTypeGPGKey secrets
+	GPGKeyPrivateKey = "private_key"
+	// GPGKeyPassphraseKey is the key of the passphrase for SecretTypeGPGKey secrets
+	GPGKeyPassphraseKey = "passphrase"
 
 	// SecretTypeSecretText contains data.
 	//
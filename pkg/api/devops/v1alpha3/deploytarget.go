@@ -0,0 +1,65 @@
+/*
+Copyright 2022 The KubeSphere Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha3
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	// PipelineDeployTargetsAnnoKey is the annotation key of the deployment targets of a Pipeline.
+	// The value is a comma-separated list in format <cluster>/<namespace>, such as: host/demo,member/demo
+	PipelineDeployTargetsAnnoKey = PipelinePrefix + "deploy-targets"
+	// DeployTargetClusterLabelKey is the label key of the cluster which a deployment credential belongs to
+	DeployTargetClusterLabelKey = "devops.kubesphere.io/deploy-target-cluster"
+	// DeployTargetNamespaceLabelKey is the label key of the namespace which a deployment credential is scoped to
+	DeployTargetNamespaceLabelKey = "devops.kubesphere.io/deploy-target-namespace"
+	// DeployTargetExpirationAnnoKey is the annotation key of the expiration time (RFC3339) of a deployment credential
+	DeployTargetExpirationAnnoKey = "devops.kubesphere.io/deploy-target-expiration"
+)
+
+// DeployTarget represents a namespace of a cluster which a Pipeline deploys to
+type DeployTarget struct {
+	Cluster   string `json:"cluster"`
+	Namespace string `json:"namespace"`
+}
+
+// GetCredentialName returns the name of the short-lived kubeconfig credential of this target.
+// The name is stable, so it could be referenced by the Jenkinsfile. The separator is a dot which never appears in
+// the namespace names, so the names of different targets never collide.
+func (t DeployTarget) GetCredentialName() string {
+	return fmt.Sprintf("deploy-%s.%s", t.Cluster, t.Namespace)
+}
+
+// GetDeployTargets returns the deployment targets of a Pipeline, the invalid items will be ignored
+func (p *Pipeline) GetDeployTargets() (targets []DeployTarget) {
+	if p == nil {
+		return
+	}
+	for _, item := range strings.Split(p.Annotations[PipelineDeployTargetsAnnoKey], ",") {
+		pair := strings.Split(strings.TrimSpace(item), "/")
+		if len(pair) != 2 || pair[0] == "" || pair[1] == "" {
+			continue
+		}
+		targets = append(targets, DeployTarget{
+			Cluster:   pair[0],
+			Namespace: pair[1],
+		})
+	}
+	return
+}
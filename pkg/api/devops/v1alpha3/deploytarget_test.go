@@ -0,0 +1,78 @@
+/*
+Copyright 2022 The KubeSphere Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha3
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestPipeline_GetDeployTargets(t *testing.T) {
+	tests := []struct {
+		name     string
+		pipeline *Pipeline
+		want     []DeployTarget
+	}{{
+		name:     "pipeline is nil",
+		pipeline: nil,
+	}, {
+		name:     "without annotations",
+		pipeline: &Pipeline{},
+	}, {
+		name: "normal case",
+		pipeline: &Pipeline{
+			ObjectMeta: metav1.ObjectMeta{
+				Annotations: map[string]string{
+					PipelineDeployTargetsAnnoKey: "host/demo, member/prod",
+				},
+			},
+		},
+		want: []DeployTarget{{
+			Cluster:   "host",
+			Namespace: "demo",
+		}, {
+			Cluster:   "member",
+			Namespace: "prod",
+		}},
+	}, {
+		name: "ignore the invalid items",
+		pipeline: &Pipeline{
+			ObjectMeta: metav1.ObjectMeta{
+				Annotations: map[string]string{
+					PipelineDeployTargetsAnnoKey: "host,/demo,member/,a/b/c,,member/prod",
+				},
+			},
+		},
+		want: []DeployTarget{{
+			Cluster:   "member",
+			Namespace: "prod",
+		}},
+	}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.pipeline.GetDeployTargets())
+		})
+	}
+}
+
+func TestDeployTarget_GetCredentialName(t *testing.T) {
+	assert.Equal(t, "deploy-member.prod", DeployTarget{Cluster: "member", Namespace: "prod"}.GetCredentialName())
+	assert.NotEqual(t, DeployTarget{Cluster: "a-b", Namespace: "c"}.GetCredentialName(),
+		DeployTarget{Cluster: "a", Namespace: "b-c"}.GetCredentialName())
+}
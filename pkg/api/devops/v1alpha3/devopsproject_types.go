@@ -31,6 +31,8 @@ const (
 	DevOpsProjectFinalizerName     = "devopsproject.finalizers.kubesphere.io"
 	DevOpeProjectSyncStatusAnnoKey = DevOpsProjectPrefix + "syncstatus"
 	DevOpeProjectSyncTimeAnnoKey   = DevOpsProjectPrefix + "synctime"
+	// DevOpsProjectJenkinsInstanceAnnoKey is the annotation key of the desired Jenkins instance of a new DevOps project
+	DevOpsProjectJenkinsInstanceAnnoKey = DevOpsProjectPrefix + "jenkins-instance"
 )
 
 // DevOpsProjectSpec defines the desired state of DevOpsProject
@@ -143,6 +145,8 @@ type SignatureKey struct {
 // DevOpsProjectStatus defines the observed state of DevOpsProject
 type DevOpsProjectStatus struct {
 	AdminNamespace string `json:"adminNamespace,omitempty"`
+	// JenkinsInstance is the name of the Jenkins instance which hosts this DevOps project
+	JenkinsInstance string `json:"jenkinsInstance,omitempty"`
 }
 
 // +genclient
@@ -17,6 +17,9 @@ limitations under the License.
 package v1alpha3
 
 import (
+	"net/url"
+	"strings"
+
 	v1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
@@ -35,6 +38,8 @@ const GitRepoFinalizerName = "finalizer.gitrepository.devops.kubesphere.io"
 // +kubebuilder:printcolumn:name="Provider",type="string",JSONPath=".spec.provider"
 // +kubebuilder:printcolumn:name="Server",type="string",JSONPath=".spec.server"
 // +kubebuilder:printcolumn:name="URL",type="string",JSONPath=".spec.url"
+// +kubebuilder:printcolumn:name="Default Branch",type="string",JSONPath=".status.defaultBranch"
+// +kubebuilder:subresource:status
 type GitRepository struct {
 	metav1.TypeMeta   `json:",inline"`
 	metav1.ObjectMeta `json:"metadata,omitempty"`
@@ -49,8 +54,80 @@ type GitRepositoryStatus struct {
 	Connection string `json:"connection,omitempty"`
 	// Message describes the message when trying to connect it
 	Message string `json:"message,omitempty"`
+	// DefaultBranch is the default branch of the repository
+	DefaultBranch string `json:"defaultBranch,omitempty"`
+	// Visibility is the visibility of the repository, it could be public or private
+	Visibility string `json:"visibility,omitempty"`
+	// LastCommit is the latest commit of the default branch
+	LastCommit *GitCommit `json:"lastCommit,omitempty"`
+	// Webhooks are the registration states of the webhooks on the git provider
+	Webhooks []GitRepositoryWebhookStatus `json:"webhooks,omitempty"`
+	// LastProbeTime is the last time the repository was checked
+	LastProbeTime *metav1.Time `json:"lastProbeTime,omitempty"`
+	// SettingsDrift describes the differences between the desired settings and the actual ones on the git provider
+	SettingsDrift []string `json:"settingsDrift,omitempty"`
+	// Conditions are the latest observations of the repository
+	// +optional
+	// +patchMergeKey=type
+	// +patchStrategy=merge
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+}
+
+// GitCommit represents a commit of a git repository
+type GitCommit struct {
+	SHA     string       `json:"sha,omitempty"`
+	Message string       `json:"message,omitempty"`
+	Author  string       `json:"author,omitempty"`
+	Time    *metav1.Time `json:"time,omitempty"`
+}
+
+// GitRepositoryWebhookStatus represents the registration state of a webhook on the git provider
+type GitRepositoryWebhookStatus struct {
+	// Name is the name of the Webhook
+	Name string `json:"name"`
+	// ID is the identity of the hook on the git provider
+	ID string `json:"id,omitempty"`
+	// Registered indicates if the hook exists on the git provider
+	Registered bool `json:"registered"`
+	// LastDelivery is the time of the latest delivery
+	LastDelivery *metav1.Time `json:"lastDelivery,omitempty"`
+	// FailureCount is the count of the failed deliveries in the recent ones
+	FailureCount int `json:"failureCount,omitempty"`
+}
+
+// GetWebhookStatus returns the registration state of a webhook, it returns nil if not found
+func (s *GitRepositoryStatus) GetWebhookStatus(name string) *GitRepositoryWebhookStatus {
+	for i := range s.Webhooks {
+		if s.Webhooks[i].Name == name {
+			return &s.Webhooks[i]
+		}
+	}
+	return nil
 }
 
+// SetWebhookStatus adds or replaces the registration state of a webhook
+func (s *GitRepositoryStatus) SetWebhookStatus(status GitRepositoryWebhookStatus) {
+	if existing := s.GetWebhookStatus(status.Name); existing != nil {
+		*existing = status
+	} else {
+		s.Webhooks = append(s.Webhooks, status)
+	}
+}
+
+const (
+	// GitRepositoryConditionConnected is the condition type which indicates if the repository is accessible with the credential
+	GitRepositoryConditionConnected = "Connected"
+	// GitRepositoryConditionWebhooksReady is the condition type which indicates if all the webhooks are registered and healthy
+	GitRepositoryConditionWebhooksReady = "WebhooksReady"
+	// GitRepositoryConditionSettingsSynced is the condition type which indicates if the settings on the git provider are as desired
+	GitRepositoryConditionSettingsSynced = "SettingsSynced"
+
+	// GitRepositoryVisibilityPublic indicates the repository is public
+	GitRepositoryVisibilityPublic = "public"
+	// GitRepositoryVisibilityPrivate indicates the repository is private
+	GitRepositoryVisibilityPrivate = "private"
+)
+
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
 
 // GitRepositoryList contains a list of GitRepository
@@ -69,6 +146,97 @@ type GitRepositorySpec struct {
 	Repo     string                    `json:"repo,omitempty"`
 	Secret   *v1.SecretReference       `json:"secret,omitempty"`
 	Webhooks []v1.LocalObjectReference `json:"webhooks,omitempty"`
+	// PullRequestComment indicates whether to comment the summary of the PipelineRuns on the pull requests
+	PullRequestComment bool `json:"pullRequestComment,omitempty"`
+	// Settings are the desired settings of the repository on the git provider, they are not managed if it's empty
+	Settings *GitRepositorySettings `json:"settings,omitempty"`
+	// CommitSigning signs the commits which are created by the GitOps file APIs, they are not signed if it's empty
+	CommitSigning *CommitSigning `json:"commitSigning,omitempty"`
+}
+
+// CommitSigning represents the key to sign the commits
+type CommitSigning struct {
+	// Secret is a ssh-auth or gpg-key DevOps credential which contains the private key,
+	// the namespace of the GitRepository is used if the namespace is empty
+	Secret *v1.SecretReference `json:"secret"`
+}
+
+// GitRepositorySettingsPolicy decides what to do when the settings on the git provider drift from the desired ones
+type GitRepositorySettingsPolicy string
+
+const (
+	// GitRepositorySettingsPolicyApply applies the desired settings to the git provider
+	GitRepositorySettingsPolicyApply GitRepositorySettingsPolicy = "Apply"
+	// GitRepositorySettingsPolicyReport only reports the drift in the status
+	GitRepositorySettingsPolicyReport GitRepositorySettingsPolicy = "Report"
+)
+
+// GitRepositorySettings represents the desired settings of a repository on the git provider
+type GitRepositorySettings struct {
+	// Policy decides what to do when the settings drift, the default value is Apply
+	// +kubebuilder:validation:Enum=Apply;Report
+	// +optional
+	Policy GitRepositorySettingsPolicy `json:"policy,omitempty"`
+	// DefaultBranch is the desired default branch, it must exist
+	DefaultBranch string `json:"defaultBranch,omitempty"`
+	// Labels are the labels which should exist, the other labels are kept as they are
+	Labels []GitLabel `json:"labels,omitempty"`
+	// BranchProtections are the protection rules of the branches
+	BranchProtections []BranchProtection `json:"branchProtections,omitempty"`
+}
+
+// GetPolicy returns the policy, Apply is the default one
+func (s *GitRepositorySettings) GetPolicy() GitRepositorySettingsPolicy {
+	if s.Policy == "" {
+		return GitRepositorySettingsPolicyApply
+	}
+	return s.Policy
+}
+
+// GitLabel represents a label of the issues and pull requests
+type GitLabel struct {
+	Name string `json:"name"`
+	// Color is the hexadecimal color code without the leading #, for example: d73a4a
+	Color       string `json:"color,omitempty"`
+	Description string `json:"description,omitempty"`
+}
+
+// BranchProtection represents the protection rule of a branch
+type BranchProtection struct {
+	// Branch is the name of the protected branch
+	Branch string `json:"branch"`
+	// RequiredStatusChecks are the contexts of the statuses which must pass before merging,
+	// for example: KubeSphere DevOps
+	RequiredStatusChecks []string `json:"requiredStatusChecks,omitempty"`
+	// StrictStatusChecks requires the branches to be up-to-date before merging
+	StrictStatusChecks bool `json:"strictStatusChecks,omitempty"`
+	// RequiredApprovingReviewCount is the count of the approving reviews which are required before merging
+	RequiredApprovingReviewCount int `json:"requiredApprovingReviewCount,omitempty"`
+	// DismissStaleReviews dismisses the approving reviews when new commits are pushed
+	DismissStaleReviews bool `json:"dismissStaleReviews,omitempty"`
+	// EnforceAdmins enforces the rule for the administrators as well
+	EnforceAdmins bool `json:"enforceAdmins,omitempty"`
+}
+
+// GetRepoPath returns the path of the repository on the git provider, such as owner/repo
+func (r *GitRepository) GetRepoPath() string {
+	owner, name := r.Spec.Owner, strings.TrimSuffix(r.Spec.Repo, ".git")
+	if owner != "" && name != "" {
+		// the repo of GitLab could be owner/repo already
+		if strings.HasPrefix(name, owner+"/") {
+			return name
+		}
+		return owner + "/" + name
+	}
+
+	// parse it from the address, like https://github.com/owner/repo.git or git@github.com:owner/repo.git
+	address := r.Spec.URL
+	if u, err := url.Parse(address); err == nil && u.Host != "" {
+		address = u.Path
+	} else if index := strings.Index(address, ":"); index >= 0 {
+		address = address[index+1:]
+	}
+	return strings.TrimSuffix(strings.Trim(address, "/"), ".git")
 }
 
 func init() {
@@ -0,0 +1,63 @@
+/*
+Copyright 2022 The KubeSphere Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha3
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGitRepository_GetRepoPath(t *testing.T) {
+	tests := []struct {
+		name string
+		spec GitRepositorySpec
+		want string
+	}{{
+		name: "owner and repo",
+		spec: GitRepositorySpec{Provider: "github", Owner: "linuxsuren", Repo: "test.git"},
+		want: "linuxsuren/test",
+	}, {
+		name: "the repo of GitLab contains the owner",
+		spec: GitRepositorySpec{Provider: "gitlab", Owner: "group", Repo: "group/sub/test"},
+		want: "group/sub/test",
+	}, {
+		name: "https address",
+		spec: GitRepositorySpec{Provider: "gitlab", URL: "https://gitlab.com/group/sub/test.git"},
+		want: "group/sub/test",
+	}, {
+		name: "https address of a self-hosted server",
+		spec: GitRepositorySpec{Provider: "gitea", URL: "https://gitea.example.com/linuxsuren/test"},
+		want: "linuxsuren/test",
+	}, {
+		name: "ssh address",
+		spec: GitRepositorySpec{URL: "git@github.com:linuxsuren/test.git"},
+		want: "linuxsuren/test",
+	}, {
+		name: "ssh address with the scheme",
+		spec: GitRepositorySpec{URL: "ssh://git@github.com/linuxsuren/test"},
+		want: "linuxsuren/test",
+	}, {
+		name: "empty",
+		want: "",
+	}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, (&GitRepository{Spec: tt.spec}).GetRepoPath())
+		})
+	}
+}
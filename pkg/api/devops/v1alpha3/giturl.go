@@ -0,0 +1,270 @@
+/*
+Copyright 2022 The KubeSphere Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha3
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	// GitHubServerURL is the address of the public GitHub
+	GitHubServerURL = "https://github.com"
+	// GitLabServerURL is the address of the public GitLab
+	GitLabServerURL = "https://gitlab.com"
+	// BitbucketCloudServerURL is the address of Bitbucket Cloud
+	BitbucketCloudServerURL = "https://bitbucket.org"
+)
+
+// GitRepoURL contains the addresses of a git repository
+type GitRepoURL struct {
+	// Server is the base address of the git server, such as https://github.com
+	Server string
+	// Web is the address for browsing the repository
+	Web string
+	// Clone is the HTTP(S) address for cloning the repository
+	Clone string
+	// CloneSSH is the SSH address for cloning the repository
+	CloneSSH string
+}
+
+// GitLabServers maps the names of the GitLab servers, which are configured in Jenkins, to their addresses
+type GitLabServers map[string]string
+
+// ParseGitLabServers parses the GitLab servers from the Jenkins configuration-as-code YAML
+func ParseGitLabServers(casc string) (servers GitLabServers, err error) {
+	config := struct {
+		Unclassified struct {
+			GitLabServers struct {
+				Servers []struct {
+					Name      string `json:"name"`
+					ServerURL string `json:"serverUrl"`
+				} `json:"servers"`
+			} `json:"gitLabServers"`
+		} `json:"unclassified"`
+	}{}
+	if err = yaml.Unmarshal([]byte(casc), &config); err != nil {
+		err = fmt.Errorf("failed to parse the GitLab servers, error: %v", err)
+		return
+	}
+
+	servers = GitLabServers{}
+	for _, server := range config.Unclassified.GitLabServers.Servers {
+		if server.Name != "" && server.ServerURL != "" {
+			servers[server.Name] = strings.TrimSuffix(server.ServerURL, "/")
+		}
+	}
+	return
+}
+
+// ResolveGitURL returns the addresses of the repository, the GitLab servers are used to find the address of a GitLab server by name
+func (b *MultiBranchPipeline) ResolveGitURL(servers GitLabServers) (repoURL GitRepoURL) {
+	switch b.SourceType {
+	case SourceTypeGit:
+		if b.GitSource != nil {
+			repoURL = gitRepoURLFromAddress(b.GitSource.Url)
+		}
+	case SourceTypeGithub:
+		if b.GitHubSource != nil {
+			repoURL = newGitRepoURL(GitHubServerURLFromAPI(b.GitHubSource.ApiUri), b.GitHubSource.Owner, b.GitHubSource.Repo)
+		}
+	case SourceTypeGitlab:
+		if b.GitlabSource != nil {
+			repo := strings.TrimPrefix(b.GitlabSource.Repo, b.GitlabSource.Owner+"/")
+			repoURL = newGitRepoURL(b.GitlabSource.GetServerURL(servers), b.GitlabSource.Owner, repo)
+		}
+	case SourceTypeBitbucket:
+		if b.BitbucketServerSource != nil {
+			repoURL = b.BitbucketServerSource.getGitRepoURL()
+		}
+	case SourceTypeBitbucketCloud:
+		if b.BitbucketCloudSource != nil {
+			repoURL = newGitRepoURL(BitbucketCloudServerURL, b.BitbucketCloudSource.Owner, b.BitbucketCloudSource.Repo)
+		}
+	case SourceTypeGitea:
+		if b.GiteaSource != nil {
+			repoURL = newGitRepoURL(b.GiteaSource.ServerUrl, b.GiteaSource.Owner, b.GiteaSource.Repo)
+		}
+	}
+	return
+}
+
+// GetServerURL returns the address of the GitLab server. The API URI takes precedence over the server name,
+// a server name which is not an address is looked up from the given servers.
+func (s *GitlabSource) GetServerURL(servers GitLabServers) string {
+	if s.ApiUri != "" {
+		return strings.TrimSuffix(strings.TrimSuffix(s.ApiUri, "/"), "/api/v4")
+	}
+	if server, ok := servers[s.ServerName]; ok {
+		return server
+	}
+	if strings.Contains(s.ServerName, "://") {
+		return strings.TrimSuffix(s.ServerName, "/")
+	}
+	return GitLabServerURL
+}
+
+// IsCloud returns true if the source points to Bitbucket Cloud instead of a Bitbucket Server
+func (s *BitbucketServerSource) IsCloud() bool {
+	return s.ApiUri == "" || hostOf(s.ApiUri) == hostOf(BitbucketCloudServerURL)
+}
+
+// GetServerURL returns the address of the Bitbucket Server
+func (s *BitbucketServerSource) GetServerURL() string {
+	if s.IsCloud() {
+		return BitbucketCloudServerURL
+	}
+	return strings.TrimSuffix(strings.TrimSuffix(s.ApiUri, "/"), "/rest/api/1.0")
+}
+
+func (s *BitbucketServerSource) getGitRepoURL() (repoURL GitRepoURL) {
+	if s.IsCloud() {
+		return newGitRepoURL(BitbucketCloudServerURL, s.Owner, s.Repo)
+	}
+
+	server := s.GetServerURL()
+	repoURL = GitRepoURL{
+		Server:   server,
+		Clone:    fmt.Sprintf("%s/scm/%s/%s.git", server, strings.ToLower(s.Owner), s.Repo),
+		CloneSSH: fmt.Sprintf("ssh://git@%s/%s/%s.git", hostOf(server), strings.ToLower(s.Owner), s.Repo),
+	}
+	if user := strings.TrimPrefix(s.Owner, "~"); user != s.Owner {
+		repoURL.Web = fmt.Sprintf("%s/users/%s/repos/%s", server, user, s.Repo)
+	} else {
+		repoURL.Web = fmt.Sprintf("%s/projects/%s/repos/%s", server, s.Owner, s.Repo)
+	}
+	return
+}
+
+// GitHubServerURLFromAPI returns the address of the GitHub server which serves the given API URI
+func GitHubServerURLFromAPI(apiURI string) string {
+	if apiURI == "" {
+		return GitHubServerURL
+	}
+
+	server := strings.TrimSuffix(strings.TrimSuffix(apiURI, "/"), "/api/v3")
+	if u, err := url.Parse(server); err == nil && strings.HasPrefix(u.Host, "api.") {
+		u.Host = strings.TrimPrefix(u.Host, "api.")
+		server = u.String()
+	}
+	return server
+}
+
+func newGitRepoURL(server, owner, repo string) GitRepoURL {
+	server = strings.TrimSuffix(server, "/")
+	web := fmt.Sprintf("%s/%s/%s", server, owner, repo)
+	return GitRepoURL{
+		Server:   server,
+		Web:      web,
+		Clone:    web + ".git",
+		CloneSSH: fmt.Sprintf("git@%s:%s/%s.git", hostOf(server), owner, repo),
+	}
+}
+
+func gitRepoURLFromAddress(address string) (repoURL GitRepoURL) {
+	repoURL.Web = address
+	if strings.HasPrefix(address, "http://") || strings.HasPrefix(address, "https://") {
+		repoURL.Clone = address
+		if u, err := url.Parse(address); err == nil {
+			repoURL.Server = fmt.Sprintf("%s://%s", u.Scheme, u.Host)
+		}
+	} else {
+		repoURL.CloneSSH = address
+	}
+	return
+}
+
+func hostOf(address string) string {
+	if u, err := url.Parse(address); err == nil && u.Host != "" {
+		return u.Hostname()
+	}
+	return address
+}
+
+// NormalizeGitURL converts the web, HTTP(S) and SSH addresses of a repository into the same form, such as:
+// github.com/owner/repo. Bitbucket Server addresses are converted into host/project/repo.
+func NormalizeGitURL(address string) string {
+	address = strings.TrimSpace(address)
+	if address == "" {
+		return ""
+	}
+
+	server, path := SplitGitURL(address)
+	if server == "" {
+		return strings.ToLower(strings.TrimSuffix(strings.TrimSuffix(address, "/"), ".git"))
+	}
+	return strings.ToLower(hostOf(server) + "/" + path)
+}
+
+// SplitGitURL returns the server address and the full name of a repository from its web, HTTP(S) or SSH address,
+// such as https://github.com and owner/repo. The SSH addresses are assumed to be served via HTTPS.
+// The server is empty if the address is not a valid one.
+func SplitGitURL(address string) (server, path string) {
+	address = strings.TrimSpace(address)
+	if strings.Contains(address, "://") {
+		u, err := url.Parse(address)
+		if err != nil || u.Host == "" {
+			return
+		}
+		path = u.Path
+		if server = fmt.Sprintf("%s://%s", u.Scheme, u.Host); u.Scheme != "http" && u.Scheme != "https" {
+			server = "https://" + u.Hostname()
+		}
+	} else if at := strings.Index(address, "@"); at >= 0 && strings.Contains(address[at:], ":") {
+		// the scp-like form, such as git@github.com:owner/repo.git
+		hostAndPath := strings.SplitN(address[at+1:], ":", 2)
+		server, path = "https://"+hostAndPath[0], hostAndPath[1]
+	} else {
+		return
+	}
+
+	path = strings.Trim(path, "/")
+	if index := strings.Index(path, "/-/"); index >= 0 {
+		// GitLab pages of a repository, such as owner/repo/-/tree/master
+		path = path[:index]
+	}
+	path = strings.TrimSuffix(path, ".git")
+
+	segments := strings.Split(path, "/")
+	switch {
+	case len(segments) == 3 && segments[0] == "scm":
+		// the Bitbucket Server clone address
+		path = strings.Join(segments[1:], "/")
+	case len(segments) >= 4 && segments[0] == "projects" && segments[2] == "repos":
+		// the Bitbucket Server web address
+		path = segments[1] + "/" + segments[3]
+	case len(segments) >= 4 && segments[0] == "users" && segments[2] == "repos":
+		path = "~" + segments[1] + "/" + segments[3]
+	}
+	return
+}
+
+// GitURLMatch returns true if any of the targets points to the same repository as the source
+func GitURLMatch(source string, targets ...string) bool {
+	if source = NormalizeGitURL(source); source == "" {
+		return false
+	}
+	for _, target := range targets {
+		if NormalizeGitURL(target) == source {
+			return true
+		}
+	}
+	return false
+}
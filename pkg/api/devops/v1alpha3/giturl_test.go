@@ -0,0 +1,237 @@
+/*
+Copyright 2022 The KubeSphere Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha3
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMultiBranchPipeline_ResolveGitURL(t *testing.T) {
+	servers := GitLabServers{"internal": "https://gitlab.example.com"}
+	tests := []struct {
+		name     string
+		pipeline MultiBranchPipeline
+		want     GitRepoURL
+	}{{
+		name: "public GitHub",
+		pipeline: MultiBranchPipeline{SourceType: SourceTypeGithub,
+			GitHubSource: &GithubSource{Owner: "kubesphere", Repo: "ks-devops"}},
+		want: GitRepoURL{
+			Server:   "https://github.com",
+			Web:      "https://github.com/kubesphere/ks-devops",
+			Clone:    "https://github.com/kubesphere/ks-devops.git",
+			CloneSSH: "git@github.com:kubesphere/ks-devops.git",
+		},
+	}, {
+		name: "GitHub Enterprise",
+		pipeline: MultiBranchPipeline{SourceType: SourceTypeGithub,
+			GitHubSource: &GithubSource{Owner: "devops", Repo: "demo", ApiUri: "https://ghe.example.com/api/v3/"}},
+		want: GitRepoURL{
+			Server:   "https://ghe.example.com",
+			Web:      "https://ghe.example.com/devops/demo",
+			Clone:    "https://ghe.example.com/devops/demo.git",
+			CloneSSH: "git@ghe.example.com:devops/demo.git",
+		},
+	}, {
+		name: "GitLab with the API URI",
+		pipeline: MultiBranchPipeline{SourceType: SourceTypeGitlab,
+			GitlabSource: &GitlabSource{Owner: "devops", Repo: "devops/demo", ApiUri: "http://10.0.0.1:8080/api/v4"}},
+		want: GitRepoURL{
+			Server:   "http://10.0.0.1:8080",
+			Web:      "http://10.0.0.1:8080/devops/demo",
+			Clone:    "http://10.0.0.1:8080/devops/demo.git",
+			CloneSSH: "git@10.0.0.1:devops/demo.git",
+		},
+	}, {
+		name: "GitLab with a server name configured in Jenkins",
+		pipeline: MultiBranchPipeline{SourceType: SourceTypeGitlab,
+			GitlabSource: &GitlabSource{Owner: "devops", Repo: "demo", ServerName: "internal"}},
+		want: GitRepoURL{
+			Server:   "https://gitlab.example.com",
+			Web:      "https://gitlab.example.com/devops/demo",
+			Clone:    "https://gitlab.example.com/devops/demo.git",
+			CloneSSH: "git@gitlab.example.com:devops/demo.git",
+		},
+	}, {
+		name: "GitLab with an unknown server name",
+		pipeline: MultiBranchPipeline{SourceType: SourceTypeGitlab,
+			GitlabSource: &GitlabSource{Owner: "devops", Repo: "demo", ServerName: "https://gitlab.internal/"}},
+		want: GitRepoURL{
+			Server:   "https://gitlab.internal",
+			Web:      "https://gitlab.internal/devops/demo",
+			Clone:    "https://gitlab.internal/devops/demo.git",
+			CloneSSH: "git@gitlab.internal:devops/demo.git",
+		},
+	}, {
+		name: "Bitbucket Server project",
+		pipeline: MultiBranchPipeline{SourceType: SourceTypeBitbucket,
+			BitbucketServerSource: &BitbucketServerSource{Owner: "PRJ", Repo: "demo", ApiUri: "https://bitbucket.example.com/"}},
+		want: GitRepoURL{
+			Server:   "https://bitbucket.example.com",
+			Web:      "https://bitbucket.example.com/projects/PRJ/repos/demo",
+			Clone:    "https://bitbucket.example.com/scm/prj/demo.git",
+			CloneSSH: "ssh://git@bitbucket.example.com/prj/demo.git",
+		},
+	}, {
+		name: "Bitbucket Server personal repository",
+		pipeline: MultiBranchPipeline{SourceType: SourceTypeBitbucket,
+			BitbucketServerSource: &BitbucketServerSource{Owner: "~alice", Repo: "demo", ApiUri: "https://bitbucket.example.com"}},
+		want: GitRepoURL{
+			Server:   "https://bitbucket.example.com",
+			Web:      "https://bitbucket.example.com/users/alice/repos/demo",
+			Clone:    "https://bitbucket.example.com/scm/~alice/demo.git",
+			CloneSSH: "ssh://git@bitbucket.example.com/~alice/demo.git",
+		},
+	}, {
+		name: "Bitbucket Server source points to Bitbucket Cloud",
+		pipeline: MultiBranchPipeline{SourceType: SourceTypeBitbucket,
+			BitbucketServerSource: &BitbucketServerSource{Owner: "devops", Repo: "demo", ApiUri: "https://bitbucket.org"}},
+		want: GitRepoURL{
+			Server:   "https://bitbucket.org",
+			Web:      "https://bitbucket.org/devops/demo",
+			Clone:    "https://bitbucket.org/devops/demo.git",
+			CloneSSH: "git@bitbucket.org:devops/demo.git",
+		},
+	}, {
+		name: "git over SSH",
+		pipeline: MultiBranchPipeline{SourceType: SourceTypeGit,
+			GitSource: &GitSource{Url: "git@github.com:devops/demo.git"}},
+		want: GitRepoURL{
+			Web:      "git@github.com:devops/demo.git",
+			CloneSSH: "git@github.com:devops/demo.git",
+		},
+	}, {
+		name: "git over HTTPS",
+		pipeline: MultiBranchPipeline{SourceType: SourceTypeGit,
+			GitSource: &GitSource{Url: "https://git.example.com/devops/demo.git"}},
+		want: GitRepoURL{
+			Server: "https://git.example.com",
+			Web:    "https://git.example.com/devops/demo.git",
+			Clone:  "https://git.example.com/devops/demo.git",
+		},
+	}, {
+		name:     "without a source",
+		pipeline: MultiBranchPipeline{SourceType: SourceTypeGithub},
+	}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.pipeline.ResolveGitURL(servers))
+		})
+	}
+}
+
+func TestGitHubServerURLFromAPI(t *testing.T) {
+	tests := []struct {
+		apiURI string
+		want   string
+	}{
+		{apiURI: "", want: "https://github.com"},
+		{apiURI: "https://api.github.com", want: "https://github.com"},
+		{apiURI: "https://api.github.com/", want: "https://github.com"},
+		{apiURI: "https://ghe.example.com/api/v3", want: "https://ghe.example.com"},
+		{apiURI: "https://api.devops.ghe.com", want: "https://devops.ghe.com"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.apiURI, func(t *testing.T) {
+			assert.Equal(t, tt.want, GitHubServerURLFromAPI(tt.apiURI))
+		})
+	}
+}
+
+func TestNormalizeGitURL(t *testing.T) {
+	tests := []struct {
+		name    string
+		address string
+		want    string
+	}{
+		{name: "empty", address: "", want: ""},
+		{name: "web", address: "https://github.com/Devops/Demo", want: "github.com/devops/demo"},
+		{name: "HTTPS clone", address: "https://github.com/devops/demo.git", want: "github.com/devops/demo"},
+		{name: "with user and port", address: "http://admin@10.0.0.1:8080/devops/demo.git/", want: "10.0.0.1/devops/demo"},
+		{name: "scp-like SSH", address: "git@gitlab.example.com:group/sub/demo.git", want: "gitlab.example.com/group/sub/demo"},
+		{name: "SSH", address: "ssh://git@bitbucket.example.com:7999/prj/demo.git", want: "bitbucket.example.com/prj/demo"},
+		{name: "GitLab tree page", address: "https://gitlab.com/devops/demo/-/tree/master", want: "gitlab.com/devops/demo"},
+		{name: "Bitbucket Server clone", address: "https://bitbucket.example.com/scm/prj/demo.git", want: "bitbucket.example.com/prj/demo"},
+		{name: "Bitbucket Server web", address: "https://bitbucket.example.com/projects/PRJ/repos/demo/browse", want: "bitbucket.example.com/prj/demo"},
+		{name: "Bitbucket Server personal", address: "https://bitbucket.example.com/users/alice/repos/demo", want: "bitbucket.example.com/~alice/demo"},
+		{name: "not an address", address: "Demo.git", want: "demo"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, NormalizeGitURL(tt.address))
+		})
+	}
+}
+
+func TestSplitGitURL(t *testing.T) {
+	tests := []struct {
+		name       string
+		address    string
+		wantServer string
+		wantPath   string
+	}{
+		{name: "empty", address: ""},
+		{name: "web", address: "https://github.com/Devops/Demo", wantServer: "https://github.com", wantPath: "Devops/Demo"},
+		{name: "with user and port", address: "http://admin@10.0.0.1:8080/devops/demo.git/", wantServer: "http://10.0.0.1:8080", wantPath: "devops/demo"},
+		{name: "scp-like SSH", address: "git@gitlab.example.com:group/sub/demo.git", wantServer: "https://gitlab.example.com", wantPath: "group/sub/demo"},
+		{name: "SSH", address: "ssh://git@bitbucket.example.com:7999/prj/demo.git", wantServer: "https://bitbucket.example.com", wantPath: "prj/demo"},
+		{name: "Bitbucket Server web", address: "https://bitbucket.example.com/projects/PRJ/repos/demo/browse", wantServer: "https://bitbucket.example.com", wantPath: "PRJ/demo"},
+		{name: "not an address", address: "Demo.git"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, path := SplitGitURL(tt.address)
+			assert.Equal(t, tt.wantServer, server)
+			assert.Equal(t, tt.wantPath, path)
+		})
+	}
+}
+
+func TestGitURLMatch(t *testing.T) {
+	assert.True(t, GitURLMatch("https://github.com/devops/demo", "", "git@github.com:devops/demo.git"))
+	assert.True(t, GitURLMatch("https://bitbucket.example.com/projects/PRJ/repos/demo",
+		"https://bitbucket.example.com/scm/prj/demo.git"))
+	assert.False(t, GitURLMatch("https://github.com/devops/demo", "https://github.com/devops/demo-fork"))
+	assert.False(t, GitURLMatch("", ""))
+}
+
+func TestParseGitLabServers(t *testing.T) {
+	servers, err := ParseGitLabServers(`
+unclassified:
+  gitLabServers:
+    servers:
+    - name: "https://gitlab.com"
+      serverUrl: "https://gitlab.com"
+    - name: internal
+      serverUrl: "https://gitlab.example.com/"
+    - name: invalid
+`)
+	assert.Nil(t, err)
+	assert.Equal(t, GitLabServers{
+		"https://gitlab.com": "https://gitlab.com",
+		"internal":           "https://gitlab.example.com",
+	}, servers)
+
+	servers, err = ParseGitLabServers("")
+	assert.Nil(t, err)
+	assert.Empty(t, servers)
+
+	_, err = ParseGitLabServers("unclassified: [")
+	assert.NotNil(t, err)
+}
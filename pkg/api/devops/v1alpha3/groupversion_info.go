@@ -36,6 +36,26 @@ const (
 	PipelineNameLabelKey = devops.GroupName + "/pipeline"
 	// PipelineRunCreatorAnnoKey is annotation key of PipelineRun's creator
 	PipelineRunCreatorAnnoKey = devops.GroupName + "/creator"
+	// PipelineRunCommitAnnoKey is annotation key of the commit SHA which is built by a PipelineRun.
+	PipelineRunCommitAnnoKey = devops.GroupName + "/commit"
+	// PipelineRunSCMProviderAnnoKey is annotation key of the go-scm driver name of a webhook-triggered PipelineRun.
+	PipelineRunSCMProviderAnnoKey = devops.GroupName + "/scm-provider"
+	// PipelineRunSCMServerAnnoKey is annotation key of the git server address of a webhook-triggered PipelineRun.
+	PipelineRunSCMServerAnnoKey = devops.GroupName + "/scm-server"
+	// PipelineRunSCMRepoAnnoKey is annotation key of the repository (owner/repo) of a webhook-triggered PipelineRun.
+	PipelineRunSCMRepoAnnoKey = devops.GroupName + "/scm-repo"
+	// PipelineRunSCMCredentialAnnoKey is annotation key of the credential which is used to report the commit status.
+	PipelineRunSCMCredentialAnnoKey = devops.GroupName + "/scm-credential"
+	// PipelineRunTriggerAnnoKey is annotation key of the source which triggers a PipelineRun, such as webhook and upstream.
+	PipelineRunTriggerAnnoKey = devops.GroupName + "/trigger"
+	// PipelineRunUpstreamAnnoKey is annotation key of the upstream PipelineRun which triggers a PipelineRun.
+	PipelineRunUpstreamAnnoKey = devops.GroupName + "/upstream-pipelinerun"
+	// PipelineRunUpstreamUIDLabelKey is label key of the UID of the upstream PipelineRun which triggers a PipelineRun.
+	PipelineRunUpstreamUIDLabelKey = devops.GroupName + "/upstream-pipelinerun-uid"
+	// PipelineRunScheduleLabelKey is label key of the schedule name of a scheduled PipelineRun.
+	PipelineRunScheduleLabelKey = devops.GroupName + "/schedule"
+	// PipelineRunScheduledTimeLabelKey is label key of the scheduled time of a scheduled PipelineRun, it's a Unix time.
+	PipelineRunScheduledTimeLabelKey = devops.GroupName + "/scheduled-time"
 	// PipelineRunSCMRefNameField is the field name of SCM reference name in PipelineRun spec.
 	PipelineRunSCMRefNameField = "spec.scm.ref-name"
 	// PipelineRunIdentifierIndexerName is an indexer name of PipelineRun identifier.
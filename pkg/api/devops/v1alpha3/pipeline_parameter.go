@@ -0,0 +1,152 @@
+/*
+Copyright 2022 The KubeSphere Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha3
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// GetParameterDefinitions returns the parameter definitions of a Pipeline,
+// the parameters of a multi-branch Pipeline are defined in its Jenkinsfile, so it has no definitions here.
+func (p *Pipeline) GetParameterDefinitions() []ParameterDefinition {
+	if p == nil || p.Spec.Pipeline == nil {
+		return nil
+	}
+	return p.Spec.Pipeline.Parameters
+}
+
+// GetChoices returns the options of a choice parameter
+func (d *ParameterDefinition) GetChoices() (choices []string) {
+	if d.Type != ChoiceParameterType {
+		return
+	}
+	for _, choice := range strings.Split(d.DefaultValue, "\n") {
+		if choice = strings.TrimSpace(choice); choice != "" {
+			choices = append(choices, choice)
+		}
+	}
+	return
+}
+
+// Validate checks if the parameter matches the definition, returns the normalized parameter
+func (d *ParameterDefinition) Validate(parameter Parameter) (result Parameter, err error) {
+	result = parameter
+	if parameter.ValueFrom != nil && d.Type != PasswordParameterType {
+		err = fmt.Errorf("parameter %s is a %s, only the password parameters could reference a credential", d.Name, d.Type)
+		return
+	}
+
+	switch d.Type {
+	case StringParameterType:
+		if strings.Contains(parameter.Value, "\n") {
+			err = fmt.Errorf("parameter %s is a string, use a text parameter for multiple lines", d.Name)
+		}
+	case TextParameterType:
+	case BooleanParameterType:
+		var value bool
+		if value, err = strconv.ParseBool(parameter.Value); err != nil {
+			err = fmt.Errorf("parameter %s is a boolean, but got %q", d.Name, parameter.Value)
+		} else {
+			result.Value = strconv.FormatBool(value)
+		}
+	case ChoiceParameterType:
+		choices := d.GetChoices()
+		found := false
+		for _, choice := range choices {
+			found = found || choice == parameter.Value
+		}
+		if !found {
+			err = fmt.Errorf("parameter %s should be one of %s, but got %q", d.Name, strings.Join(choices, ", "), parameter.Value)
+		}
+	case PasswordParameterType:
+		if parameter.Value != "" || parameter.ValueFrom == nil || parameter.ValueFrom.CredentialRef == nil ||
+			parameter.ValueFrom.CredentialRef.Name == "" {
+			err = fmt.Errorf("parameter %s is a password, it must reference a credential instead of having a value", d.Name)
+		}
+	case FileParameterType:
+		err = fmt.Errorf("parameter %s is a file, it can only be uploaded in Jenkins", d.Name)
+	default:
+		// the types provided by the Jenkins plugins are passed through as they are
+	}
+	return
+}
+
+// ValidateParameters checks the parameters of a PipelineRun against the definitions of the Pipeline.
+// The parameters which are not given use the default values in Jenkins.
+// It returns the normalized parameters, all the problems are joined in the error.
+func ValidateParameters(definitions []ParameterDefinition, parameters []Parameter) (result []Parameter, err error) {
+	definitionMap := make(map[string]*ParameterDefinition, len(definitions))
+	for i := range definitions {
+		definitionMap[definitions[i].Name] = &definitions[i]
+	}
+
+	var errs []error
+	names := make(map[string]bool, len(parameters))
+	for _, parameter := range parameters {
+		if names[parameter.Name] {
+			errs = append(errs, fmt.Errorf("parameter %s is duplicated", parameter.Name))
+			continue
+		}
+		names[parameter.Name] = true
+
+		definition, ok := definitionMap[parameter.Name]
+		if !ok {
+			if len(definitions) > 0 {
+				errs = append(errs, fmt.Errorf("parameter %s is not defined in the Pipeline", parameter.Name))
+			} else if parameter.ValueFrom != nil {
+				// only the parameters which are defined as passwords could reference a credential
+				errs = append(errs, fmt.Errorf("parameter %s is not defined as a password, it could not reference a credential", parameter.Name))
+			} else {
+				// the parameters of multi-branch Pipelines are defined in the Jenkinsfile
+				result = append(result, parameter)
+			}
+			continue
+		}
+
+		normalized, validateErr := definition.Validate(parameter)
+		if validateErr != nil {
+			errs = append(errs, validateErr)
+			continue
+		}
+		result = append(result, normalized)
+	}
+	err = errors.Join(errs...)
+	return
+}
+
+// RemovePasswordValues removes the values of the password parameters, they should never be persisted
+func RemovePasswordValues(definitions []ParameterDefinition, parameters []Parameter) []Parameter {
+	if parameters == nil {
+		return nil
+	}
+	passwords := make(map[string]bool, len(definitions))
+	for _, definition := range definitions {
+		passwords[definition.Name] = definition.Type == PasswordParameterType
+	}
+
+	result := make([]Parameter, 0, len(parameters))
+	for _, parameter := range parameters {
+		if passwords[parameter.Name] {
+			parameter.Value = ""
+		}
+		result = append(result, parameter)
+	}
+	return result
+}
@@ -0,0 +1,153 @@
+/*
+Copyright 2022 The KubeSphere Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha3
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var testParameterDefinitions = []ParameterDefinition{{
+	Name: "name", Type: StringParameterType, DefaultValue: "rick",
+}, {
+	Name: "notes", Type: TextParameterType,
+}, {
+	Name: "debug", Type: BooleanParameterType, DefaultValue: "false",
+}, {
+	Name: "env", Type: ChoiceParameterType, DefaultValue: "dev\ntest\n prod \n",
+}, {
+	Name: "token", Type: PasswordParameterType,
+}, {
+	Name: "archive", Type: FileParameterType,
+}, {
+	Name: "unknown", Type: "run",
+}}
+
+func TestPipeline_GetParameterDefinitions(t *testing.T) {
+	assert.Nil(t, (*Pipeline)(nil).GetParameterDefinitions())
+	assert.Nil(t, (&Pipeline{Spec: PipelineSpec{Type: MultiBranchPipelineType}}).GetParameterDefinitions())
+	assert.Equal(t, testParameterDefinitions, (&Pipeline{Spec: PipelineSpec{
+		Pipeline: &NoScmPipeline{Parameters: testParameterDefinitions},
+	}}).GetParameterDefinitions())
+}
+
+func TestParameterDefinition_GetChoices(t *testing.T) {
+	assert.Equal(t, []string{"dev", "test", "prod"}, testParameterDefinitions[3].GetChoices())
+	assert.Nil(t, testParameterDefinitions[0].GetChoices())
+}
+
+func TestValidateParameters(t *testing.T) {
+	credentialRef := &ParameterValueSource{CredentialRef: &CredentialKeySelector{Name: "token"}}
+	tests := []struct {
+		name        string
+		definitions []ParameterDefinition
+		parameters  []Parameter
+		expect      []Parameter
+		expectErr   string
+	}{{
+		name:        "no parameters",
+		definitions: testParameterDefinitions,
+	}, {
+		name:        "valid parameters",
+		definitions: testParameterDefinitions,
+		parameters: []Parameter{
+			{Name: "name", Value: "morty"},
+			{Name: "notes", Value: "a\nb"},
+			{Name: "debug", Value: "1"},
+			{Name: "env", Value: "prod"},
+			{Name: "token", ValueFrom: credentialRef},
+			{Name: "unknown", Value: "build-1"},
+		},
+		expect: []Parameter{
+			{Name: "name", Value: "morty"},
+			{Name: "notes", Value: "a\nb"},
+			{Name: "debug", Value: "true"},
+			{Name: "env", Value: "prod"},
+			{Name: "token", ValueFrom: credentialRef},
+			{Name: "unknown", Value: "build-1"},
+		},
+	}, {
+		name:        "invalid parameters",
+		definitions: testParameterDefinitions,
+		parameters: []Parameter{
+			{Name: "name", Value: "a\nb"},
+			{Name: "debug", Value: "yes"},
+			{Name: "env", Value: "staging"},
+			{Name: "token", Value: "plain"},
+			{Name: "archive", Value: "a.zip"},
+			{Name: "unknown", ValueFrom: credentialRef},
+			{Name: "notes", ValueFrom: credentialRef},
+			{Name: "missing", Value: "a"},
+			{Name: "name", Value: "a"},
+		},
+		expectErr: `parameter name is a string, use a text parameter for multiple lines
+parameter debug is a boolean, but got "yes"
+parameter env should be one of dev, test, prod, but got "staging"
+parameter token is a password, it must reference a credential instead of having a value
+parameter archive is a file, it can only be uploaded in Jenkins
+parameter unknown is a run, only the password parameters could reference a credential
+parameter notes is a text, only the password parameters could reference a credential
+parameter missing is not defined in the Pipeline
+parameter name is duplicated`,
+	}, {
+		name: "password without credential name",
+		definitions: []ParameterDefinition{{
+			Name: "token", Type: PasswordParameterType,
+		}},
+		parameters: []Parameter{{Name: "token", ValueFrom: &ParameterValueSource{CredentialRef: &CredentialKeySelector{}}}},
+		expectErr:  "parameter token is a password, it must reference a credential instead of having a value",
+	}, {
+		name: "no definitions, like multi-branch Pipelines",
+		parameters: []Parameter{
+			{Name: "name", Value: "morty"},
+		},
+		expect: []Parameter{
+			{Name: "name", Value: "morty"},
+		},
+	}, {
+		name: "no password definitions, like multi-branch Pipelines",
+		parameters: []Parameter{
+			{Name: "token", ValueFrom: credentialRef},
+		},
+		expectErr: "parameter token is not defined as a password, it could not reference a credential",
+	}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := ValidateParameters(tt.definitions, tt.parameters)
+			if tt.expectErr != "" {
+				assert.EqualError(t, err, tt.expectErr)
+				return
+			}
+			assert.Nil(t, err)
+			assert.Equal(t, tt.expect, result)
+		})
+	}
+}
+
+func TestRemovePasswordValues(t *testing.T) {
+	assert.Nil(t, RemovePasswordValues(testParameterDefinitions, nil))
+	assert.Equal(t, []Parameter{
+		{Name: "name", Value: "morty"},
+		{Name: "token", Value: ""},
+		{Name: "other", Value: "a"},
+	}, RemovePasswordValues(testParameterDefinitions, []Parameter{
+		{Name: "name", Value: "morty"},
+		{Name: "token", Value: "secret"},
+		{Name: "other", Value: "a"},
+	}))
+}
@@ -0,0 +1,58 @@
+/*
+Copyright 2022 The KubeSphere Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha3
+
+// GetUpstreamTriggers returns the triggers from the upstream Pipelines
+func (p *Pipeline) GetUpstreamTriggers() []UpstreamTrigger {
+	if p == nil || p.Spec.Triggers == nil {
+		return nil
+	}
+	return p.Spec.Triggers.Upstream
+}
+
+// GetPhases returns the phases of the upstream PipelineRun which trigger the downstream Pipeline
+func (t *UpstreamTrigger) GetPhases() []RunPhase {
+	if len(t.Phases) == 0 {
+		return []RunPhase{Succeeded}
+	}
+	return t.Phases
+}
+
+// Matches returns true if the finished upstream PipelineRun triggers the downstream Pipeline
+func (t *UpstreamTrigger) Matches(upstream *PipelineRun) bool {
+	if upstream == nil || !upstream.HasCompleted() ||
+		upstream.GetLabels()[PipelineNameLabelKey] != t.Pipeline {
+		return false
+	}
+	if t.Branch != "" && t.Branch != upstream.GetRefName() {
+		return false
+	}
+	for _, phase := range t.GetPhases() {
+		if phase == upstream.Status.Phase {
+			return true
+		}
+	}
+	return false
+}
+
+// GetTargetBranch returns the branch to run if the downstream is a multi-branch Pipeline
+func (t *UpstreamTrigger) GetTargetBranch(upstream *PipelineRun) string {
+	if t.TargetBranch != "" {
+		return t.TargetBranch
+	}
+	return upstream.GetRefName()
+}
@@ -0,0 +1,118 @@
+/*
+Copyright 2022 The KubeSphere Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha3
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestPipeline_GetUpstreamTriggers(t *testing.T) {
+	var pipeline *Pipeline
+	assert.Nil(t, pipeline.GetUpstreamTriggers())
+
+	pipeline = &Pipeline{}
+	assert.Nil(t, pipeline.GetUpstreamTriggers())
+
+	pipeline.Spec.Triggers = &PipelineTriggers{Upstream: []UpstreamTrigger{{Pipeline: "build"}}}
+	assert.Equal(t, []UpstreamTrigger{{Pipeline: "build"}}, pipeline.GetUpstreamTriggers())
+}
+
+func TestUpstreamTrigger_Matches(t *testing.T) {
+	newRun := func(pipeline string, phase RunPhase, branch string) *PipelineRun {
+		run := &PipelineRun{
+			ObjectMeta: metav1.ObjectMeta{
+				Labels: map[string]string{PipelineNameLabelKey: pipeline},
+			},
+			Status: PipelineRunStatus{
+				Phase:          phase,
+				CompletionTime: &metav1.Time{Time: metav1.Now().Time},
+			},
+		}
+		if branch != "" {
+			run.Spec.PipelineSpec = &PipelineSpec{Type: MultiBranchPipelineType}
+			run.Spec.SCM = &SCM{RefName: branch}
+		}
+		return run
+	}
+
+	tests := []struct {
+		name     string
+		trigger  UpstreamTrigger
+		upstream *PipelineRun
+		want     bool
+	}{{
+		name:     "nil PipelineRun",
+		trigger:  UpstreamTrigger{Pipeline: "build"},
+		upstream: nil,
+		want:     false,
+	}, {
+		name:     "succeeded by default",
+		trigger:  UpstreamTrigger{Pipeline: "build"},
+		upstream: newRun("build", Succeeded, ""),
+		want:     true,
+	}, {
+		name:     "failed is not matched by default",
+		trigger:  UpstreamTrigger{Pipeline: "build"},
+		upstream: newRun("build", Failed, ""),
+		want:     false,
+	}, {
+		name:     "specific phases",
+		trigger:  UpstreamTrigger{Pipeline: "build", Phases: []RunPhase{Failed, Cancelled}},
+		upstream: newRun("build", Failed, ""),
+		want:     true,
+	}, {
+		name:     "another Pipeline",
+		trigger:  UpstreamTrigger{Pipeline: "build"},
+		upstream: newRun("test", Succeeded, ""),
+		want:     false,
+	}, {
+		name:     "not completed",
+		trigger:  UpstreamTrigger{Pipeline: "build", Phases: []RunPhase{Running}},
+		upstream: &PipelineRun{ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{PipelineNameLabelKey: "build"}}},
+		want:     false,
+	}, {
+		name:     "matched branch",
+		trigger:  UpstreamTrigger{Pipeline: "build", Branch: "main"},
+		upstream: newRun("build", Succeeded, "main"),
+		want:     true,
+	}, {
+		name:     "another branch",
+		trigger:  UpstreamTrigger{Pipeline: "build", Branch: "main"},
+		upstream: newRun("build", Succeeded, "dev"),
+		want:     false,
+	}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.trigger.Matches(tt.upstream))
+		})
+	}
+}
+
+func TestUpstreamTrigger_GetTargetBranch(t *testing.T) {
+	upstream := &PipelineRun{Spec: PipelineRunSpec{
+		PipelineSpec: &PipelineSpec{Type: MultiBranchPipelineType},
+		SCM:          &SCM{RefName: "main"},
+	}}
+	trigger := &UpstreamTrigger{}
+	assert.Equal(t, "main", trigger.GetTargetBranch(upstream))
+
+	trigger.TargetBranch = "release"
+	assert.Equal(t, "release", trigger.GetTargetBranch(upstream))
+}
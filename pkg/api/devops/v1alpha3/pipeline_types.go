@@ -66,12 +66,76 @@ type PipelineSpec struct {
 	Type                PipelineType         `json:"type" description:"type of devops pipeline, in scm or no scm"`
 	Pipeline            *NoScmPipeline       `json:"pipeline,omitempty" description:"no scm pipeline structs"`
 	MultiBranchPipeline *MultiBranchPipeline `json:"multi_branch_pipeline,omitempty" description:"in scm pipeline structs"`
+	Triggers            *PipelineTriggers    `json:"triggers,omitempty" description:"triggers from the other Pipelines"`
+	Schedules           []PipelineSchedule   `json:"schedules,omitempty" description:"schedules which create PipelineRuns by the controller instead of Jenkins"`
 }
 
 // PipelineStatus defines the observed state of Pipeline
 type PipelineStatus struct {
-	// INSERT ADDITIONAL STATUS FIELD - define observed state of cluster
-	// Important: Run "make" to regenerate code after modifying this file
+	// Schedules are the observed states of the schedules.
+	// +optional
+	Schedules []ScheduleStatus `json:"schedules,omitempty"`
+}
+
+// MissedSchedulePolicy decides what to do if the schedules were missed, such as the controller was down
+type MissedSchedulePolicy string
+
+const (
+	// RunOnceMissedSchedulePolicy creates one PipelineRun for the latest missed schedule
+	RunOnceMissedSchedulePolicy MissedSchedulePolicy = "RunOnce"
+	// SkipMissedSchedulePolicy skips the missed schedules, and waits for the next one
+	SkipMissedSchedulePolicy MissedSchedulePolicy = "Skip"
+)
+
+// PipelineSchedule creates PipelineRuns periodically, it's similar to a CronJob
+type PipelineSchedule struct {
+	Name                    string               `json:"name" description:"name of the schedule, it's unique in a Pipeline"`
+	Cron                    string               `json:"cron" description:"the standard cron expression, such as '0 2 * * *' or '@daily'"`
+	TimeZone                string               `json:"time_zone,omitempty" mapstructure:"time_zone" description:"the time zone of the cron expression, such as 'Asia/Shanghai', it's the time zone of the controller by default"`
+	Suspend                 bool                 `json:"suspend,omitempty" description:"suspend the schedule, the schedules are not regarded as missed while suspended"`
+	MissedSchedulePolicy    MissedSchedulePolicy `json:"missed_schedule_policy,omitempty" mapstructure:"missed_schedule_policy" description:"what to do if the schedules were missed, RunOnce or Skip, it's RunOnce by default"`
+	StartingDeadlineSeconds *int64               `json:"starting_deadline_seconds,omitempty" mapstructure:"starting_deadline_seconds" description:"a schedule is missed if it's not started within the deadline, it's 60 by default"`
+	Parameters              []Parameter          `json:"parameters,omitempty" description:"parameters of the scheduled PipelineRuns"`
+	Branch                  string               `json:"branch,omitempty" description:"the branch to run, it's required by multi-branch Pipelines"`
+}
+
+// ScheduleStatus is the observed state of a schedule
+type ScheduleStatus struct {
+	// Name is the name of the schedule.
+	Name string `json:"name"`
+
+	// Cron is the cron expression which the next schedule time is calculated from.
+	Cron string `json:"cron"`
+
+	// TimeZone is the time zone which the next schedule time is calculated in.
+	// +optional
+	TimeZone string `json:"timeZone,omitempty"`
+
+	// NextScheduleTime is the time to create the next PipelineRun.
+	// +optional
+	NextScheduleTime *metav1.Time `json:"nextScheduleTime,omitempty"`
+
+	// LastScheduleTime is the last time a PipelineRun was created.
+	// +optional
+	LastScheduleTime *metav1.Time `json:"lastScheduleTime,omitempty"`
+
+	// History contains the last scheduled times, the latest is the first.
+	// +optional
+	History []ScheduleRecord `json:"history,omitempty"`
+}
+
+// ScheduleRecord is a scheduled time and the PipelineRun created for it
+type ScheduleRecord struct {
+	// ScheduledTime is the time in the cron expression.
+	ScheduledTime metav1.Time `json:"scheduledTime"`
+
+	// PipelineRun is the name of the created PipelineRun, it's empty if the schedule was skipped.
+	// +optional
+	PipelineRun string `json:"pipelineRun,omitempty"`
+
+	// Missed is the number of the schedules which were missed before this one.
+	// +optional
+	Missed int `json:"missed,omitempty"`
 }
 
 // +genclient
@@ -79,6 +143,7 @@ type PipelineStatus struct {
 
 // Pipeline is the Schema for the pipelines API
 // +k8s:openapi-gen=true
+// +kubebuilder:subresource:status
 // +kubebuilder:printcolumn:name="Type",type=string,JSONPath=`.spec.type`,description="The type of a Pipeline"
 // +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`,description="The age of a Pipeline"
 // +kubebuilder:resource:shortName="pip",categories="devops"
@@ -122,6 +187,10 @@ const (
 	SourceTypeGitlab    = "gitlab"
 	SourceTypeGithub    = "github"
 	SourceTypeBitbucket = "bitbucket_server"
+	// SourceTypeBitbucketCloud is the source type of the repositories on https://bitbucket.org
+	SourceTypeBitbucketCloud = "bitbucket_cloud"
+	// SourceTypeGitea is the source type of the repositories on a Gitea server
+	SourceTypeGitea = "gitea"
 )
 
 type NoScmPipeline struct {
@@ -148,30 +217,46 @@ type MultiBranchPipeline struct {
 	SvnSource             *SvnSource             `json:"svn_source,omitempty" description:"multi branch svn scm define"`
 	SingleSvnSource       *SingleSvnSource       `json:"single_svn_source,omitempty" description:"single branch svn scm define"`
 	BitbucketServerSource *BitbucketServerSource `json:"bitbucket_server_source,omitempty" description:"bitbucket server scm defile"`
+	BitbucketCloudSource  *BitbucketCloudSource  `json:"bitbucket_cloud_source,omitempty" description:"bitbucket cloud scm define"`
+	GiteaSource           *GiteaSource           `json:"gitea_source,omitempty" description:"gitea scm define"`
 	ScriptPath            string                 `json:"script_path" mapstructure:"script_path" description:"script path in scm"`
 	MultiBranchJobTrigger *MultiBranchJobTrigger `json:"multibranch_job_trigger,omitempty" mapstructure:"multibranch_job_trigger" description:"Pipeline tasks that need to be triggered when branch creation/deletion"`
 }
 
+// GetGitURL returns the web address of the repository
 func (b *MultiBranchPipeline) GetGitURL() string {
+	return b.ResolveGitURL(nil).Web
+}
+
+// GetCredentialID returns the credential which is used to access the git repository
+func (b *MultiBranchPipeline) GetCredentialID() (id string) {
 	switch b.SourceType {
 	case SourceTypeGit:
 		if b.GitSource != nil {
-			return b.GitSource.Url
+			id = b.GitSource.CredentialId
 		}
 	case SourceTypeGithub:
 		if b.GitHubSource != nil {
-			return fmt.Sprintf("https://github.com/%s/%s", b.GitHubSource.Owner, b.GitHubSource.Repo)
+			id = b.GitHubSource.CredentialId
 		}
 	case SourceTypeGitlab:
 		if b.GitlabSource != nil {
-			return fmt.Sprintf("https://gitlab.com/%s/%s", b.GitlabSource.Owner, b.GitlabSource.Repo)
+			id = b.GitlabSource.CredentialId
 		}
 	case SourceTypeBitbucket:
 		if b.BitbucketServerSource != nil {
-			return fmt.Sprintf("https://bitbucket.org/%s/%s", b.BitbucketServerSource.Owner, b.BitbucketServerSource.Repo)
+			id = b.BitbucketServerSource.CredentialId
+		}
+	case SourceTypeBitbucketCloud:
+		if b.BitbucketCloudSource != nil {
+			id = b.BitbucketCloudSource.CredentialId
+		}
+	case SourceTypeGitea:
+		if b.GiteaSource != nil {
+			id = b.GiteaSource.CredentialId
 		}
 	}
-	return ""
+	return
 }
 
 type GitSource struct {
@@ -243,11 +328,68 @@ type BitbucketServerSource struct {
 	AcceptJenkinsNotification bool                 `json:"accept_jenkins_notification,omitempty"  mapstructure:"accept_jenkins_notification" description:"Allow Jenkins send build status notification to Bitbucket"`
 }
 
+// BitbucketCloudSource is the repository on https://bitbucket.org, it shares the plugin with BitbucketServerSource
+type BitbucketCloudSource struct {
+	ScmId                     string               `json:"scm_id,omitempty" description:"uid of scm"`
+	Owner                     string               `json:"owner,omitempty" mapstructure:"owner" description:"owner of bitbucket repo, it's the workspace of Bitbucket Cloud"`
+	Repo                      string               `json:"repo,omitempty" mapstructure:"repo" description:"repo name of bitbucket repo"`
+	CredentialId              string               `json:"credential_id,omitempty" mapstructure:"credential_id" description:"credential id to access bitbucket source"`
+	DiscoverBranches          int                  `json:"discover_branches,omitempty" mapstructure:"discover_branches" description:"Discover branch configuration"`
+	DiscoverPRFromOrigin      int                  `json:"discover_pr_from_origin,omitempty" mapstructure:"discover_pr_from_origin" description:"Discover origin PR configuration"`
+	DiscoverPRFromForks       *DiscoverPRFromForks `json:"discover_pr_from_forks,omitempty" mapstructure:"discover_pr_from_forks" description:"Discover fork PR configuration"`
+	DiscoverTags              bool                 `json:"discover_tags,omitempty" mapstructure:"discover_tags" description:"Discover tag configuration"`
+	CloneOption               *GitCloneOption      `json:"git_clone_option,omitempty" mapstructure:"git_clone_option" description:"advavced git clone options"`
+	RegexFilter               string               `json:"regex_filter,omitempty" mapstructure:"regex_filter" description:"Regex used to match the name of the branch that needs to be run"`
+	AcceptJenkinsNotification bool                 `json:"accept_jenkins_notification,omitempty"  mapstructure:"accept_jenkins_notification" description:"Allow Jenkins send build status notification to Bitbucket"`
+}
+
+// GiteaSource is the repository on a Gitea server, the server needs to be configured in Jenkins as well
+type GiteaSource struct {
+	ScmId                string               `json:"scm_id,omitempty" description:"uid of scm"`
+	ServerUrl            string               `json:"server_url,omitempty" mapstructure:"server_url" description:"the address of gitea server, such as https://gitea.com"`
+	Owner                string               `json:"owner,omitempty" mapstructure:"owner" description:"owner of gitea repo"`
+	Repo                 string               `json:"repo,omitempty" mapstructure:"repo" description:"repo name of gitea repo"`
+	CredentialId         string               `json:"credential_id,omitempty" mapstructure:"credential_id" description:"credential id to access gitea source"`
+	DiscoverBranches     int                  `json:"discover_branches,omitempty" mapstructure:"discover_branches" description:"Discover branch configuration"`
+	DiscoverPRFromOrigin int                  `json:"discover_pr_from_origin,omitempty" mapstructure:"discover_pr_from_origin" description:"Discover origin PR configuration"`
+	DiscoverPRFromForks  *DiscoverPRFromForks `json:"discover_pr_from_forks,omitempty" mapstructure:"discover_pr_from_forks" description:"Discover fork PR configuration"`
+	DiscoverTags         bool                 `json:"discover_tags,omitempty" mapstructure:"discover_tags" description:"Discover tag configuration"`
+	CloneOption          *GitCloneOption      `json:"git_clone_option,omitempty" mapstructure:"git_clone_option" description:"advavced git clone options"`
+	RegexFilter          string               `json:"regex_filter,omitempty" mapstructure:"regex_filter" description:"Regex used to match the name of the branch that needs to be run"`
+}
+
 type MultiBranchJobTrigger struct {
 	CreateActionJobsToTrigger string `json:"create_action_job_to_trigger,omitempty" description:"pipeline name to trigger"`
 	DeleteActionJobsToTrigger string `json:"delete_action_job_to_trigger,omitempty" description:"pipeline name to trigger"`
 }
 
+// PipelineTriggers defines when the Pipeline is triggered by the other Pipelines
+type PipelineTriggers struct {
+	Upstream []UpstreamTrigger `json:"upstream,omitempty" description:"the upstream Pipelines in the same namespace which trigger this Pipeline"`
+}
+
+// UpstreamTrigger creates a PipelineRun once a PipelineRun of the upstream Pipeline finishes
+type UpstreamTrigger struct {
+	Pipeline     string              `json:"pipeline" description:"name of the upstream Pipeline"`
+	Phases       []RunPhase          `json:"phases,omitempty" description:"the phases of the upstream PipelineRun which trigger this Pipeline, Succeeded by default"`
+	Branch       string              `json:"branch,omitempty" description:"the branch of the upstream PipelineRun, any branch matches if it's empty"`
+	TargetBranch string              `json:"target_branch,omitempty" mapstructure:"target_branch" description:"the branch to run if this is a multi-branch Pipeline, it's the upstream branch by default"`
+	Parameters   []UpstreamParameter `json:"parameters,omitempty" description:"the parameters of the upstream PipelineRun which are passed to this Pipeline"`
+	Artifacts    []UpstreamArtifact  `json:"artifacts,omitempty" description:"the artifacts of the upstream PipelineRun which are passed to this Pipeline"`
+}
+
+// UpstreamParameter passes a parameter of the upstream PipelineRun
+type UpstreamParameter struct {
+	Name string `json:"name" description:"name of the parameter of this Pipeline"`
+	From string `json:"from,omitempty" description:"name of the parameter of the upstream PipelineRun, it's the same as the name by default"`
+}
+
+// UpstreamArtifact passes the download path of an artifact of the upstream PipelineRun as a parameter
+type UpstreamArtifact struct {
+	Filename  string `json:"filename" description:"filename of the artifact"`
+	Parameter string `json:"parameter" description:"name of the parameter of this Pipeline which receives the download path"`
+}
+
 type GitCloneOption struct {
 	Shallow bool `json:"shallow,omitempty" mapstructure:"shallow" description:"Whether to use git shallow clone"`
 	Timeout int  `json:"timeout,omitempty" mapstructure:"timeout" description:"git clone timeout mins"`
@@ -277,6 +419,22 @@ type DiscarderProperty struct {
 	NumToKeep  string `json:"num_to_keep,omitempty" mapstructure:"num_to_keep" description:"nums to keep pipeline"`
 }
 
+// The types of the Pipeline parameters
+const (
+	// StringParameterType is a single line string
+	StringParameterType = "string"
+	// TextParameterType is a multi-line string
+	TextParameterType = "text"
+	// BooleanParameterType is either true or false
+	BooleanParameterType = "boolean"
+	// ChoiceParameterType is one of the options, the options are separated by new lines in the default value
+	ChoiceParameterType = "choice"
+	// PasswordParameterType is a secret, it must reference a DevOps credential
+	PasswordParameterType = "password"
+	// FileParameterType is a file which can only be uploaded in Jenkins
+	FileParameterType = "file"
+)
+
 type ParameterDefinition struct {
 	Name         string `json:"name" description:"name of param"`
 	DefaultValue string `json:"default_value,omitempty" yaml:"default_value" mapstructure:"default_value" description:"default value of param"`
@@ -70,6 +70,8 @@ func TestMultiBranchPipeline_GetGitURL(t *testing.T) {
 		GitHubSource          *GithubSource
 		GitlabSource          *GitlabSource
 		BitbucketServerSource *BitbucketServerSource
+		BitbucketCloudSource  *BitbucketCloudSource
+		GiteaSource           *GiteaSource
 	}
 	tests := []struct {
 		name   string
@@ -103,6 +105,20 @@ func TestMultiBranchPipeline_GetGitURL(t *testing.T) {
 			BitbucketServerSource: &BitbucketServerSource{Owner: "linuxsuren", Repo: "tools"},
 		},
 		want: "https://bitbucket.org/linuxsuren/tools",
+	}, {
+		name: "bitbucket cloud",
+		fields: fields{
+			SourceType:           SourceTypeBitbucketCloud,
+			BitbucketCloudSource: &BitbucketCloudSource{Owner: "linuxsuren", Repo: "tools"},
+		},
+		want: "https://bitbucket.org/linuxsuren/tools",
+	}, {
+		name: "gitea",
+		fields: fields{
+			SourceType:  SourceTypeGitea,
+			GiteaSource: &GiteaSource{ServerUrl: "https://gitea.fake.com/", Owner: "linuxsuren", Repo: "tools"},
+		},
+		want: "https://gitea.fake.com/linuxsuren/tools",
 	}, {
 		name: "fake",
 		fields: fields{
@@ -119,8 +135,71 @@ func TestMultiBranchPipeline_GetGitURL(t *testing.T) {
 				GitHubSource:          tt.fields.GitHubSource,
 				GitlabSource:          tt.fields.GitlabSource,
 				BitbucketServerSource: tt.fields.BitbucketServerSource,
+				BitbucketCloudSource:  tt.fields.BitbucketCloudSource,
+				GiteaSource:           tt.fields.GiteaSource,
 			}
 			assert.Equalf(t, tt.want, b.GetGitURL(), "GetGitURL()")
 		})
 	}
 }
+
+func TestMultiBranchPipeline_GetCredentialID(t *testing.T) {
+	tests := []struct {
+		name     string
+		pipeline *MultiBranchPipeline
+		want     string
+	}{{
+		name: "github",
+		pipeline: &MultiBranchPipeline{
+			SourceType:   SourceTypeGithub,
+			GitHubSource: &GithubSource{CredentialId: "github"},
+		},
+		want: "github",
+	}, {
+		name: "gitlab",
+		pipeline: &MultiBranchPipeline{
+			SourceType:   SourceTypeGitlab,
+			GitlabSource: &GitlabSource{CredentialId: "gitlab"},
+		},
+		want: "gitlab",
+	}, {
+		name: "git",
+		pipeline: &MultiBranchPipeline{
+			SourceType: SourceTypeGit,
+			GitSource:  &GitSource{CredentialId: "git"},
+		},
+		want: "git",
+	}, {
+		name: "bitbucket server",
+		pipeline: &MultiBranchPipeline{
+			SourceType:            SourceTypeBitbucket,
+			BitbucketServerSource: &BitbucketServerSource{CredentialId: "bitbucket"},
+		},
+		want: "bitbucket",
+	}, {
+		name: "bitbucket cloud",
+		pipeline: &MultiBranchPipeline{
+			SourceType:           SourceTypeBitbucketCloud,
+			BitbucketCloudSource: &BitbucketCloudSource{CredentialId: "bitbucket-cloud"},
+		},
+		want: "bitbucket-cloud",
+	}, {
+		name: "gitea",
+		pipeline: &MultiBranchPipeline{
+			SourceType:  SourceTypeGitea,
+			GiteaSource: &GiteaSource{CredentialId: "gitea"},
+		},
+		want: "gitea",
+	}, {
+		name: "source is nil",
+		pipeline: &MultiBranchPipeline{
+			SourceType: SourceTypeGithub,
+		},
+		want: "",
+	}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.pipeline.GetCredentialID())
+		})
+	}
+}
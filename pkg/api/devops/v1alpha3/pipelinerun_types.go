@@ -214,6 +214,28 @@ type Parameter struct {
 
 	// Value indicates that value of the parameter.
 	Value string `json:"value" description:"parameter value"`
+
+	// ValueFrom indicates the source of the value, it's required by the password parameters.
+	// The value is resolved when triggering the PipelineRun, and never persisted.
+	// +optional
+	ValueFrom *ParameterValueSource `json:"valueFrom,omitempty" description:"source of the parameter value"`
+}
+
+// ParameterValueSource represents the source of a parameter value
+type ParameterValueSource struct {
+	// CredentialRef refers to a DevOps credential in the namespace of the PipelineRun.
+	CredentialRef *CredentialKeySelector `json:"credentialRef,omitempty" description:"DevOps credential which holds the value"`
+}
+
+// CredentialKeySelector selects a key of a DevOps credential
+type CredentialKeySelector struct {
+	// Name is the name of the DevOps credential.
+	Name string `json:"name" description:"name of the DevOps credential"`
+
+	// Key is the key of the credential data. It's the password of basic-auth credentials,
+	// or the secret of secret-text credentials by default.
+	// +optional
+	Key string `json:"key,omitempty" description:"key of the credential data"`
 }
 
 // RefType indicates that SCM reference type, such as branch, tag, pr, mr.
@@ -39,6 +39,16 @@ func (in *AddStrategySpec) DeepCopyInto(out *AddStrategySpec) {
 			(*out)[key] = val
 		}
 	}
+	if in.Compatibility != nil {
+		in, out := &in.Compatibility, &out.Compatibility
+		*out = new(AddonCompatibility)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.HealthCheck != nil {
+		in, out := &in.HealthCheck, &out.HealthCheck
+		*out = new(AddonHealthCheck)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AddStrategySpec.
@@ -57,7 +67,7 @@ func (in *Addon) DeepCopyInto(out *Addon) {
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
 	in.Spec.DeepCopyInto(&out.Spec)
-	out.Status = in.Status
+	in.Status.DeepCopyInto(&out.Status)
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Addon.
@@ -78,6 +88,41 @@ func (in *Addon) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AddonCompatibility) DeepCopyInto(out *AddonCompatibility) {
+	*out = *in
+	if in.Upgrades != nil {
+		in, out := &in.Upgrades, &out.Upgrades
+		*out = make([]AddonUpgradePath, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AddonCompatibility.
+func (in *AddonCompatibility) DeepCopy() *AddonCompatibility {
+	if in == nil {
+		return nil
+	}
+	out := new(AddonCompatibility)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AddonHealthCheck) DeepCopyInto(out *AddonHealthCheck) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AddonHealthCheck.
+func (in *AddonHealthCheck) DeepCopy() *AddonHealthCheck {
+	if in == nil {
+		return nil
+	}
+	out := new(AddonHealthCheck)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *AddonList) DeepCopyInto(out *AddonList) {
 	*out = *in
@@ -136,6 +181,13 @@ func (in *AddonSpec) DeepCopy() *AddonSpec {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *AddonStatus) DeepCopyInto(out *AddonStatus) {
 	*out = *in
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]v1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AddonStatus.
@@ -206,6 +258,21 @@ func (in *AddonStrategyList) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AddonUpgradePath) DeepCopyInto(out *AddonUpgradePath) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AddonUpgradePath.
+func (in *AddonUpgradePath) DeepCopy() *AddonUpgradePath {
+	if in == nil {
+		return nil
+	}
+	out := new(AddonUpgradePath)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ApplicationDestination) DeepCopyInto(out *ApplicationDestination) {
 	*out = *in
@@ -294,6 +361,51 @@ func (in *Argo) DeepCopy() *Argo {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BranchProtection) DeepCopyInto(out *BranchProtection) {
+	*out = *in
+	if in.RequiredStatusChecks != nil {
+		in, out := &in.RequiredStatusChecks, &out.RequiredStatusChecks
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BranchProtection.
+func (in *BranchProtection) DeepCopy() *BranchProtection {
+	if in == nil {
+		return nil
+	}
+	out := new(BranchProtection)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BitbucketCloudSource) DeepCopyInto(out *BitbucketCloudSource) {
+	*out = *in
+	if in.DiscoverPRFromForks != nil {
+		in, out := &in.DiscoverPRFromForks, &out.DiscoverPRFromForks
+		*out = new(DiscoverPRFromForks)
+		**out = **in
+	}
+	if in.CloneOption != nil {
+		in, out := &in.CloneOption, &out.CloneOption
+		*out = new(GitCloneOption)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BitbucketCloudSource.
+func (in *BitbucketCloudSource) DeepCopy() *BitbucketCloudSource {
+	if in == nil {
+		return nil
+	}
+	out := new(BitbucketCloudSource)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *BitbucketServerSource) DeepCopyInto(out *BitbucketServerSource) {
 	*out = *in
@@ -437,6 +549,26 @@ func (in *ClusterTemplateList) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CommitSigning) DeepCopyInto(out *CommitSigning) {
+	*out = *in
+	if in.Secret != nil {
+		in, out := &in.Secret, &out.Secret
+		*out = new(corev1.SecretReference)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CommitSigning.
+func (in *CommitSigning) DeepCopy() *CommitSigning {
+	if in == nil {
+		return nil
+	}
+	out := new(CommitSigning)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Condition) DeepCopyInto(out *Condition) {
 	*out = *in
@@ -454,6 +586,21 @@ func (in *Condition) DeepCopy() *Condition {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CredentialKeySelector) DeepCopyInto(out *CredentialKeySelector) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CredentialKeySelector.
+func (in *CredentialKeySelector) DeepCopy() *CredentialKeySelector {
+	if in == nil {
+		return nil
+	}
+	out := new(CredentialKeySelector)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *DevOpsProject) DeepCopyInto(out *DevOpsProject) {
 	*out = *in
@@ -633,13 +780,47 @@ func (in *GitCloneOption) DeepCopy() *GitCloneOption {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GitCommit) DeepCopyInto(out *GitCommit) {
+	*out = *in
+	if in.Time != nil {
+		in, out := &in.Time, &out.Time
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GitCommit.
+func (in *GitCommit) DeepCopy() *GitCommit {
+	if in == nil {
+		return nil
+	}
+	out := new(GitCommit)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GitLabel) DeepCopyInto(out *GitLabel) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GitLabel.
+func (in *GitLabel) DeepCopy() *GitLabel {
+	if in == nil {
+		return nil
+	}
+	out := new(GitLabel)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *GitRepository) DeepCopyInto(out *GitRepository) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
 	in.Spec.DeepCopyInto(&out.Spec)
-	out.Status = in.Status
+	in.Status.DeepCopyInto(&out.Status)
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GitRepository.
@@ -692,6 +873,33 @@ func (in *GitRepositoryList) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GitRepositorySettings) DeepCopyInto(out *GitRepositorySettings) {
+	*out = *in
+	if in.Labels != nil {
+		in, out := &in.Labels, &out.Labels
+		*out = make([]GitLabel, len(*in))
+		copy(*out, *in)
+	}
+	if in.BranchProtections != nil {
+		in, out := &in.BranchProtections, &out.BranchProtections
+		*out = make([]BranchProtection, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GitRepositorySettings.
+func (in *GitRepositorySettings) DeepCopy() *GitRepositorySettings {
+	if in == nil {
+		return nil
+	}
+	out := new(GitRepositorySettings)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *GitRepositorySpec) DeepCopyInto(out *GitRepositorySpec) {
 	*out = *in
@@ -705,6 +913,16 @@ func (in *GitRepositorySpec) DeepCopyInto(out *GitRepositorySpec) {
 		*out = make([]corev1.LocalObjectReference, len(*in))
 		copy(*out, *in)
 	}
+	if in.Settings != nil {
+		in, out := &in.Settings, &out.Settings
+		*out = new(GitRepositorySettings)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.CommitSigning != nil {
+		in, out := &in.CommitSigning, &out.CommitSigning
+		*out = new(CommitSigning)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GitRepositorySpec.
@@ -720,6 +938,34 @@ func (in *GitRepositorySpec) DeepCopy() *GitRepositorySpec {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *GitRepositoryStatus) DeepCopyInto(out *GitRepositoryStatus) {
 	*out = *in
+	if in.LastCommit != nil {
+		in, out := &in.LastCommit, &out.LastCommit
+		*out = new(GitCommit)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Webhooks != nil {
+		in, out := &in.Webhooks, &out.Webhooks
+		*out = make([]GitRepositoryWebhookStatus, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.LastProbeTime != nil {
+		in, out := &in.LastProbeTime, &out.LastProbeTime
+		*out = (*in).DeepCopy()
+	}
+	if in.SettingsDrift != nil {
+		in, out := &in.SettingsDrift, &out.SettingsDrift
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]v1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GitRepositoryStatus.
@@ -732,6 +978,25 @@ func (in *GitRepositoryStatus) DeepCopy() *GitRepositoryStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GitRepositoryWebhookStatus) DeepCopyInto(out *GitRepositoryWebhookStatus) {
+	*out = *in
+	if in.LastDelivery != nil {
+		in, out := &in.LastDelivery, &out.LastDelivery
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GitRepositoryWebhookStatus.
+func (in *GitRepositoryWebhookStatus) DeepCopy() *GitRepositoryWebhookStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(GitRepositoryWebhookStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *GitSource) DeepCopyInto(out *GitSource) {
 	*out = *in
@@ -752,6 +1017,31 @@ func (in *GitSource) DeepCopy() *GitSource {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GiteaSource) DeepCopyInto(out *GiteaSource) {
+	*out = *in
+	if in.DiscoverPRFromForks != nil {
+		in, out := &in.DiscoverPRFromForks, &out.DiscoverPRFromForks
+		*out = new(DiscoverPRFromForks)
+		**out = **in
+	}
+	if in.CloneOption != nil {
+		in, out := &in.CloneOption, &out.CloneOption
+		*out = new(GitCloneOption)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GiteaSource.
+func (in *GiteaSource) DeepCopy() *GiteaSource {
+	if in == nil {
+		return nil
+	}
+	out := new(GiteaSource)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *GithubSource) DeepCopyInto(out *GithubSource) {
 	*out = *in
@@ -875,6 +1165,16 @@ func (in *MultiBranchPipeline) DeepCopyInto(out *MultiBranchPipeline) {
 		*out = new(BitbucketServerSource)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.BitbucketCloudSource != nil {
+		in, out := &in.BitbucketCloudSource, &out.BitbucketCloudSource
+		*out = new(BitbucketCloudSource)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.GiteaSource != nil {
+		in, out := &in.GiteaSource, &out.GiteaSource
+		*out = new(GiteaSource)
+		(*in).DeepCopyInto(*out)
+	}
 	if in.MultiBranchJobTrigger != nil {
 		in, out := &in.MultiBranchJobTrigger, &out.MultiBranchJobTrigger
 		*out = new(MultiBranchJobTrigger)
@@ -975,6 +1275,11 @@ func (in *OrphanedResourcesMonitorSettings) DeepCopy() *OrphanedResourcesMonitor
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Parameter) DeepCopyInto(out *Parameter) {
 	*out = *in
+	if in.ValueFrom != nil {
+		in, out := &in.ValueFrom, &out.ValueFrom
+		*out = new(ParameterValueSource)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Parameter.
@@ -1032,13 +1337,33 @@ func (in *ParameterValidation) DeepCopy() *ParameterValidation {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ParameterValueSource) DeepCopyInto(out *ParameterValueSource) {
+	*out = *in
+	if in.CredentialRef != nil {
+		in, out := &in.CredentialRef, &out.CredentialRef
+		*out = new(CredentialKeySelector)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ParameterValueSource.
+func (in *ParameterValueSource) DeepCopy() *ParameterValueSource {
+	if in == nil {
+		return nil
+	}
+	out := new(ParameterValueSource)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Pipeline) DeepCopyInto(out *Pipeline) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
 	in.Spec.DeepCopyInto(&out.Spec)
-	out.Status = in.Status
+	in.Status.DeepCopyInto(&out.Status)
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Pipeline.
@@ -1166,7 +1491,9 @@ func (in *PipelineRunSpec) DeepCopyInto(out *PipelineRunSpec) {
 	if in.Parameters != nil {
 		in, out := &in.Parameters, &out.Parameters
 		*out = make([]Parameter, len(*in))
-		copy(*out, *in)
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
 	}
 	if in.SCM != nil {
 		in, out := &in.SCM, &out.SCM
@@ -1224,6 +1551,33 @@ func (in *PipelineRunStatus) DeepCopy() *PipelineRunStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PipelineSchedule) DeepCopyInto(out *PipelineSchedule) {
+	*out = *in
+	if in.StartingDeadlineSeconds != nil {
+		in, out := &in.StartingDeadlineSeconds, &out.StartingDeadlineSeconds
+		*out = new(int64)
+		**out = **in
+	}
+	if in.Parameters != nil {
+		in, out := &in.Parameters, &out.Parameters
+		*out = make([]Parameter, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PipelineSchedule.
+func (in *PipelineSchedule) DeepCopy() *PipelineSchedule {
+	if in == nil {
+		return nil
+	}
+	out := new(PipelineSchedule)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *PipelineSpec) DeepCopyInto(out *PipelineSpec) {
 	*out = *in
@@ -1237,6 +1591,18 @@ func (in *PipelineSpec) DeepCopyInto(out *PipelineSpec) {
 		*out = new(MultiBranchPipeline)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.Triggers != nil {
+		in, out := &in.Triggers, &out.Triggers
+		*out = new(PipelineTriggers)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Schedules != nil {
+		in, out := &in.Schedules, &out.Schedules
+		*out = make([]PipelineSchedule, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PipelineSpec.
@@ -1252,6 +1618,13 @@ func (in *PipelineSpec) DeepCopy() *PipelineSpec {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *PipelineStatus) DeepCopyInto(out *PipelineStatus) {
 	*out = *in
+	if in.Schedules != nil {
+		in, out := &in.Schedules, &out.Schedules
+		*out = make([]ScheduleStatus, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PipelineStatus.
@@ -1264,6 +1637,28 @@ func (in *PipelineStatus) DeepCopy() *PipelineStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PipelineTriggers) DeepCopyInto(out *PipelineTriggers) {
+	*out = *in
+	if in.Upstream != nil {
+		in, out := &in.Upstream, &out.Upstream
+		*out = make([]UpstreamTrigger, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PipelineTriggers.
+func (in *PipelineTriggers) DeepCopy() *PipelineTriggers {
+	if in == nil {
+		return nil
+	}
+	out := new(PipelineTriggers)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ProjectRole) DeepCopyInto(out *ProjectRole) {
 	*out = *in
@@ -1324,6 +1719,52 @@ func (in *SCM) DeepCopy() *SCM {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ScheduleRecord) DeepCopyInto(out *ScheduleRecord) {
+	*out = *in
+	in.ScheduledTime.DeepCopyInto(&out.ScheduledTime)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ScheduleRecord.
+func (in *ScheduleRecord) DeepCopy() *ScheduleRecord {
+	if in == nil {
+		return nil
+	}
+	out := new(ScheduleRecord)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ScheduleStatus) DeepCopyInto(out *ScheduleStatus) {
+	*out = *in
+	if in.NextScheduleTime != nil {
+		in, out := &in.NextScheduleTime, &out.NextScheduleTime
+		*out = (*in).DeepCopy()
+	}
+	if in.LastScheduleTime != nil {
+		in, out := &in.LastScheduleTime, &out.LastScheduleTime
+		*out = (*in).DeepCopy()
+	}
+	if in.History != nil {
+		in, out := &in.History, &out.History
+		*out = make([]ScheduleRecord, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ScheduleStatus.
+func (in *ScheduleStatus) DeepCopy() *ScheduleStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ScheduleStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *SecretInStep) DeepCopyInto(out *SecretInStep) {
 	*out = *in
@@ -1614,6 +2055,66 @@ func (in *TimerTrigger) DeepCopy() *TimerTrigger {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *UpstreamArtifact) DeepCopyInto(out *UpstreamArtifact) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new UpstreamArtifact.
+func (in *UpstreamArtifact) DeepCopy() *UpstreamArtifact {
+	if in == nil {
+		return nil
+	}
+	out := new(UpstreamArtifact)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *UpstreamParameter) DeepCopyInto(out *UpstreamParameter) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new UpstreamParameter.
+func (in *UpstreamParameter) DeepCopy() *UpstreamParameter {
+	if in == nil {
+		return nil
+	}
+	out := new(UpstreamParameter)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *UpstreamTrigger) DeepCopyInto(out *UpstreamTrigger) {
+	*out = *in
+	if in.Phases != nil {
+		in, out := &in.Phases, &out.Phases
+		*out = make([]RunPhase, len(*in))
+		copy(*out, *in)
+	}
+	if in.Parameters != nil {
+		in, out := &in.Parameters, &out.Parameters
+		*out = make([]UpstreamParameter, len(*in))
+		copy(*out, *in)
+	}
+	if in.Artifacts != nil {
+		in, out := &in.Artifacts, &out.Artifacts
+		*out = make([]UpstreamArtifact, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new UpstreamTrigger.
+func (in *UpstreamTrigger) DeepCopy() *UpstreamTrigger {
+	if in == nil {
+		return nil
+	}
+	out := new(UpstreamTrigger)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Webhook) DeepCopyInto(out *Webhook) {
 	*out = *in
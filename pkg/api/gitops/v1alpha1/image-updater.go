@@ -17,6 +17,10 @@ limitations under the License.
 package v1alpha1
 
 import (
+	"fmt"
+	"regexp"
+	"strings"
+
 	v1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
@@ -24,10 +28,29 @@ import (
 // ImageUpdaterSpec is the specification of the image updater
 type ImageUpdaterSpec struct {
 	// +kubebuilder:default:=argocd
-	// +kubebuilder:validation:Enum=argocd;fluxcd
-	Kind   string            `json:"kind,omitempty"`
-	Images []string          `json:"images,omitempty"`
-	Argo   *ArgoImageUpdater `json:"argo,omitempty"`
+	// +kubebuilder:validation:Enum=argocd;fluxcd;native
+	Kind   string              `json:"kind,omitempty"`
+	Images []string            `json:"images,omitempty"`
+	Argo   *ArgoImageUpdater   `json:"argo,omitempty"`
+	Flux   *FluxImageUpdater   `json:"flux,omitempty"`
+	Native *NativeImageUpdater `json:"native,omitempty"`
+}
+
+// ImageUpdaterStatus represents the status of the image updater
+type ImageUpdaterStatus struct {
+	Images        []ImageStatus `json:"images,omitempty"`
+	LastCheckTime *metav1.Time  `json:"lastCheckTime,omitempty"`
+	LastCommit    string        `json:"lastCommit,omitempty"`
+	Message       string        `json:"message,omitempty"`
+}
+
+// ImageStatus represents the latest version of an image which was found by the image updater
+type ImageStatus struct {
+	Alias          string       `json:"alias"`
+	Image          string       `json:"image"`
+	Tag            string       `json:"tag,omitempty"`
+	Digest         string       `json:"digest,omitempty"`
+	LastUpdateTime *metav1.Time `json:"lastUpdateTime,omitempty"`
 }
 
 // ArgoImageUpdater is the specification of the Argo image updater
@@ -43,6 +66,52 @@ type ArgoImageUpdater struct {
 	Secrets        map[string]string `json:"secrets,omitempty"`
 }
 
+// FluxImageUpdater is the specification of the FluxCD image automation
+type FluxImageUpdater struct {
+	// Repo is the GitRepository that the new image tags will be written back to
+	Repo v1.LocalObjectReference `json:"repo"`
+	// +kubebuilder:default:=main
+	Branch string `json:"branch,omitempty"`
+	// PushBranch is the branch to push the commits to, it is the same as Branch if it's empty
+	PushBranch string `json:"pushBranch,omitempty"`
+	// Path is the directory that contains the manifests with image policy markers
+	// +kubebuilder:default:=./
+	Path string `json:"path,omitempty"`
+	// +kubebuilder:default:="1m"
+	Interval        string            `json:"interval,omitempty"`
+	Author          CommitAuthor      `json:"author,omitempty"`
+	MessageTemplate string            `json:"messageTemplate,omitempty"`
+	UpdateStrategy  map[string]string `json:"updateStrategy,omitempty"`
+	AllowTags       map[string]string `json:"allowTags,omitempty"`
+	IgnoreTags      map[string]string `json:"ignoreTags,omitempty"`
+	Secrets         map[string]string `json:"secrets,omitempty"`
+}
+
+// NativeImageUpdater is the specification of the built-in image updater,
+// it scans the image registries and writes the new tags back to the git repository
+type NativeImageUpdater struct {
+	// Repo is the GitRepository that the new image tags will be written back to
+	Repo v1.LocalObjectReference `json:"repo"`
+	// +kubebuilder:default:=main
+	Branch string `json:"branch,omitempty"`
+	// Path is the directory or file that contains the image references
+	// +kubebuilder:default:=./
+	Path string `json:"path,omitempty"`
+	// +kubebuilder:default:="5m"
+	Interval       string            `json:"interval,omitempty"`
+	UpdateStrategy map[string]string `json:"updateStrategy,omitempty"`
+	AllowTags      map[string]string `json:"allowTags,omitempty"`
+	IgnoreTags     map[string]string `json:"ignoreTags,omitempty"`
+	// Secrets are the names of the DevOps credentials which are used to access the image registries
+	Secrets map[string]string `json:"secrets,omitempty"`
+}
+
+// CommitAuthor is the author of the commits which are made by the image updater
+type CommitAuthor struct {
+	Name  string `json:"name,omitempty"`
+	Email string `json:"email,omitempty"`
+}
+
 // WriteMethod is an alias of string that represents the write back method of Argo CD Image updater
 type WriteMethod string
 
@@ -64,6 +133,86 @@ func (w WriteMethod) GetValue() string {
 	return ""
 }
 
+// ImageItem is an item of the image list, the format is [<alias>=]<image>[:<version constraint>]
+type ImageItem struct {
+	Alias      string
+	Image      string
+	Constraint string
+}
+
+// ParseImages parses the image list, the alias is the last part of the image name if it's empty
+func ParseImages(images []string) (result []ImageItem, err error) {
+	for _, item := range images {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+
+		image := ImageItem{}
+		// the version constraint might contain '=' as well, such as nginx:>=1.0
+		if index := strings.Index(item, "="); index > 0 && !strings.Contains(item[:index], ":") {
+			image.Alias, item = item[:index], item[index+1:]
+		}
+		// the colon after the last slash separates the image name and the version constraint
+		if index := strings.LastIndex(item, ":"); index > strings.LastIndex(item, "/") {
+			image.Image, image.Constraint = item[:index], item[index+1:]
+		} else {
+			image.Image = item
+		}
+		if image.Image == "" {
+			err = fmt.Errorf("invalid image: %q", item)
+			return
+		}
+		if image.Alias == "" {
+			image.Alias = image.Image[strings.LastIndex(image.Image, "/")+1:]
+		}
+		result = append(result, image)
+	}
+	return
+}
+
+// ParseIgnoreTags converts the ignored tags to the anchored regular expressions. The same as Argo CD Image Updater,
+// the ignored tags are glob patterns which are separated by comma.
+func ParseIgnoreTags(ignoreTags string) (patterns []string) {
+	for _, tag := range strings.Split(ignoreTags, ",") {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			patterns = append(patterns, globToRegexp(tag))
+		}
+	}
+	return
+}
+
+// globToRegexp converts a glob pattern to an anchored regular expression
+func globToRegexp(glob string) string {
+	var builder strings.Builder
+	builder.WriteString("^")
+	for i := 0; i < len(glob); i++ {
+		switch c := glob[i]; c {
+		case '*':
+			builder.WriteString(".*")
+		case '?':
+			builder.WriteString(".")
+		case '[':
+			end := strings.IndexByte(glob[i+1:], ']')
+			if end < 0 {
+				builder.WriteString(regexp.QuoteMeta(glob[i:]))
+				i = len(glob)
+				break
+			}
+			class := glob[i+1 : i+1+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			builder.WriteString("[" + class + "]")
+			i += end + 1
+		default:
+			builder.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	builder.WriteString("$")
+	return builder.String()
+}
+
 // +genclient
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
 // +kubebuilder:object:root=true
@@ -76,7 +225,8 @@ type ImageUpdater struct {
 	metav1.TypeMeta   `json:",inline"`
 	metav1.ObjectMeta `json:"metadata,omitempty"`
 
-	Spec ImageUpdaterSpec `json:"spec"`
+	Spec   ImageUpdaterSpec   `json:"spec"`
+	Status ImageUpdaterStatus `json:"status,omitempty"`
 }
 
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
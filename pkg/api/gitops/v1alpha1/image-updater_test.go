@@ -45,3 +45,83 @@ func TestWriteMethod_GetValue(t *testing.T) {
 		})
 	}
 }
+
+func TestParseImages(t *testing.T) {
+	tests := []struct {
+		name    string
+		images  []string
+		want    []ImageItem
+		wantErr bool
+	}{{
+		name:   "empty",
+		images: []string{"", " "},
+	}, {
+		name:   "image without alias and constraint",
+		images: []string{"nginx", "ghcr.io/kubesphere/ks-devops"},
+		want: []ImageItem{
+			{Alias: "nginx", Image: "nginx"},
+			{Alias: "ks-devops", Image: "ghcr.io/kubesphere/ks-devops"},
+		},
+	}, {
+		name:   "image with alias and constraint",
+		images: []string{"web=nginx:^1.20", "api=localhost:5000/api:>=1.0.0", "tomcat:>=9.0"},
+		want: []ImageItem{
+			{Alias: "web", Image: "nginx", Constraint: "^1.20"},
+			{Alias: "api", Image: "localhost:5000/api", Constraint: ">=1.0.0"},
+			{Alias: "tomcat", Image: "tomcat", Constraint: ">=9.0"},
+		},
+	}, {
+		name:    "invalid image",
+		images:  []string{"web=:v1"},
+		wantErr: true,
+	}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseImages(tt.images)
+			assert.Equal(t, tt.wantErr, err != nil)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func Test_globToRegexp(t *testing.T) {
+	tests := []struct {
+		name string
+		glob string
+		want string
+	}{{
+		name: "plain tag",
+		glob: "latest",
+		want: "^latest$",
+	}, {
+		name: "wildcards",
+		glob: "*-dev?",
+		want: "^.*-dev.$",
+	}, {
+		name: "special characters of regular expression",
+		glob: "1.0+build",
+		want: `^1\.0\+build$`,
+	}, {
+		name: "character class",
+		glob: "v[0-9]*",
+		want: "^v[0-9].*$",
+	}, {
+		name: "negated character class",
+		glob: "[!v]*",
+		want: "^[^v].*$",
+	}, {
+		name: "unclosed character class",
+		glob: "v[0-9",
+		want: `^v\[0-9$`,
+	}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, globToRegexp(tt.glob))
+		})
+	}
+}
+
+func TestParseIgnoreTags(t *testing.T) {
+	assert.Nil(t, ParseIgnoreTags(""))
+	assert.Equal(t, []string{"^latest$", "^.*-rc$"}, ParseIgnoreTags("latest, *-rc,"))
+}
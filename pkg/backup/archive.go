@@ -0,0 +1,105 @@
+/*
+Copyright 2022 The KubeSphere Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package backup
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+	"time"
+
+	"sigs.k8s.io/yaml"
+)
+
+// archiveWriter writes the files into a tar.gz archive
+type archiveWriter struct {
+	gzipWriter *gzip.Writer
+	tarWriter  *tar.Writer
+}
+
+func newArchiveWriter(w io.Writer) *archiveWriter {
+	gzipWriter := gzip.NewWriter(w)
+	return &archiveWriter{
+		gzipWriter: gzipWriter,
+		tarWriter:  tar.NewWriter(gzipWriter),
+	}
+}
+
+// writeYAML writes the object as a YAML file
+func (a *archiveWriter) writeYAML(name string, obj interface{}) (err error) {
+	var data []byte
+	if data, err = yaml.Marshal(obj); err != nil {
+		return
+	}
+
+	if err = a.tarWriter.WriteHeader(&tar.Header{
+		Name:    name,
+		Mode:    0600,
+		Size:    int64(len(data)),
+		ModTime: time.Now(),
+	}); err == nil {
+		_, err = a.tarWriter.Write(data)
+	}
+	return
+}
+
+func (a *archiveWriter) Close() (err error) {
+	if err = a.tarWriter.Close(); err == nil {
+		err = a.gzipWriter.Close()
+	}
+	return
+}
+
+// readArchive reads all the files of a tar.gz archive, the keys are the file paths
+func readArchive(r io.Reader) (files map[string][]byte, err error) {
+	var gzipReader *gzip.Reader
+	if gzipReader, err = gzip.NewReader(r); err != nil {
+		err = fmt.Errorf("not a valid archive, error: %v", err)
+		return
+	}
+	defer func() {
+		_ = gzipReader.Close()
+	}()
+
+	files = map[string][]byte{}
+	tarReader := tar.NewReader(gzipReader)
+	for {
+		var header *tar.Header
+		if header, err = tarReader.Next(); err != nil {
+			if errors.Is(err, io.EOF) {
+				err = nil
+			}
+			return
+		}
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+
+		name := path.Clean(header.Name)
+		if strings.HasPrefix(name, "..") || path.IsAbs(name) {
+			err = fmt.Errorf("invalid file path %s in the archive", header.Name)
+			return
+		}
+		if files[name], err = io.ReadAll(tarReader); err != nil {
+			return
+		}
+	}
+}
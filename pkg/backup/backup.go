@@ -0,0 +1,240 @@
+/*
+Copyright 2022 The KubeSphere Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package backup
+
+import (
+	"context"
+	"crypto/cipher"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"path"
+	"sort"
+
+	"github.com/kubesphere/ks-devops/pkg/api/devops/v1alpha3"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Backup exports a DevOps project and its resources into an archive
+func Backup(ctx context.Context, c client.Client, project string, opts Options, w io.Writer) (err error) {
+	if !opts.Credentials.IsValid() {
+		err = fmt.Errorf("invalid credential mode: %s", opts.Credentials)
+		return
+	}
+
+	projectObj := newObject(projectKind)
+	if err = c.Get(ctx, types.NamespacedName{Name: project}, projectObj); err != nil {
+		err = fmt.Errorf("failed to get DevOps project %s, error: %v", project, err)
+		return
+	}
+	namespace, _, _ := unstructured.NestedString(projectObj.Object, "status", "adminNamespace")
+	if namespace == "" {
+		namespace = project
+	}
+
+	manifest := &Manifest{
+		Version:     FormatVersion,
+		Project:     project,
+		CreatedAt:   metav1.Now(),
+		Credentials: opts.Credentials,
+	}
+	var aead cipher.AEAD
+	if opts.Credentials == CredentialModeEncrypt {
+		if manifest.Salt, err = newSalt(); err != nil {
+			return
+		}
+		if aead, err = newCipher(opts.Passphrase, manifest.Salt); err != nil {
+			return
+		}
+	}
+
+	writer := newArchiveWriter(w)
+	defer func() {
+		if closeErr := writer.Close(); err == nil {
+			err = closeErr
+		}
+	}()
+
+	if err = writer.writeYAML(manifestFile, manifest); err != nil {
+		return
+	}
+	if err = writer.writeYAML(objectPath(projectKind, project), sanitize(projectObj, false)); err != nil {
+		return
+	}
+
+	for _, kind := range namespacedKinds {
+		var objects []*unstructured.Unstructured
+		switch kind {
+		case runKind, runDataKind:
+			// the PipelineRuns and their data are exported together
+			continue
+		case secretKind:
+			objects, err = listCredentials(ctx, c, namespace, opts.Credentials, aead)
+		default:
+			objects, err = listObjects(ctx, c, kind, namespace)
+		}
+		if err != nil {
+			return
+		}
+
+		for _, obj := range objects {
+			if err = writer.writeYAML(objectPath(kind, obj.GetName()), sanitize(obj, false)); err != nil {
+				return
+			}
+		}
+	}
+
+	if opts.PipelineRunLimit > 0 {
+		err = backupPipelineRuns(ctx, c, namespace, opts.PipelineRunLimit, writer)
+	}
+	return
+}
+
+// listCredentials returns the credentials of a DevOps project, the values are handled according to the mode
+func listCredentials(ctx context.Context, c client.Client, namespace string, mode CredentialMode, aead cipher.AEAD) (
+	credentials []*unstructured.Unstructured, err error) {
+	if mode == CredentialModeSkip {
+		return
+	}
+
+	var secrets []*unstructured.Unstructured
+	if secrets, err = listObjects(ctx, c, secretKind, namespace); err != nil {
+		return
+	}
+
+	supportedTypes := map[string]bool{}
+	for _, secretType := range v1alpha3.GetSupportedCredentialTypes() {
+		supportedTypes[string(secretType)] = true
+	}
+	for _, secret := range secrets {
+		secretType, _, _ := unstructured.NestedString(secret.Object, "type")
+		if !supportedTypes[secretType] {
+			continue
+		}
+		if _, ok := secret.GetLabels()[v1alpha3.DeployTargetClusterLabelKey]; ok {
+			// the short-lived deployment credentials will be issued again
+			continue
+		}
+
+		data, _, _ := unstructured.NestedStringMap(secret.Object, "data")
+		for key, val := range data {
+			switch mode {
+			case CredentialModeRedact:
+				data[key] = ""
+			case CredentialModeEncrypt:
+				var plaintext, encrypted []byte
+				if plaintext, err = base64.StdEncoding.DecodeString(val); err != nil {
+					return
+				}
+				if encrypted, err = encrypt(aead, plaintext); err != nil {
+					return
+				}
+				data[key] = base64.StdEncoding.EncodeToString(encrypted)
+			}
+		}
+		if data != nil {
+			_ = unstructured.SetNestedStringMap(secret.Object, data, "data")
+		}
+		credentials = append(credentials, secret)
+	}
+	return
+}
+
+// backupPipelineRuns exports the recent completed PipelineRuns of each Pipeline, and their data
+func backupPipelineRuns(ctx context.Context, c client.Client, namespace string, limit int, writer *archiveWriter) (err error) {
+	var runs []*unstructured.Unstructured
+	if runs, err = listObjects(ctx, c, runKind, namespace); err != nil {
+		return
+	}
+
+	// the newest PipelineRuns come first
+	sort.SliceStable(runs, func(i, j int) bool {
+		return runs[j].GetCreationTimestamp().Time.Before(runs[i].GetCreationTimestamp().Time)
+	})
+
+	counts := map[string]int{}
+	for _, run := range runs {
+		if completionTime, _, _ := unstructured.NestedString(run.Object, "status", "completionTime"); completionTime == "" {
+			continue
+		}
+		pipeline, _, _ := unstructured.NestedString(run.Object, "spec", "pipelineRef", "name")
+		if counts[pipeline] >= limit {
+			continue
+		}
+		counts[pipeline]++
+
+		if err = writer.writeYAML(objectPath(runKind, run.GetName()), sanitize(run, true)); err != nil {
+			return
+		}
+
+		data := newObject(runDataKind)
+		if err = c.Get(ctx, types.NamespacedName{Namespace: namespace, Name: run.GetName()}, data); err != nil {
+			if err = client.IgnoreNotFound(err); err != nil {
+				return
+			}
+			continue
+		}
+		if err = writer.writeYAML(objectPath(runDataKind, run.GetName()), sanitize(data, false)); err != nil {
+			return
+		}
+	}
+	return
+}
+
+func listObjects(ctx context.Context, c client.Client, kind resourceKind, namespace string) (objects []*unstructured.Unstructured, err error) {
+	list := &unstructured.UnstructuredList{}
+	list.SetGroupVersionKind(kind.gvk.GroupVersion().WithKind(kind.gvk.Kind + "List"))
+	if err = c.List(ctx, list, client.InNamespace(namespace)); err != nil {
+		err = fmt.Errorf("failed to list %s in namespace %s, error: %v", kind.dir, namespace, err)
+		return
+	}
+
+	for i := range list.Items {
+		obj := &list.Items[i]
+		obj.SetGroupVersionKind(kind.gvk)
+		objects = append(objects, obj)
+	}
+	return
+}
+
+func newObject(kind resourceKind) (obj *unstructured.Unstructured) {
+	obj = &unstructured.Unstructured{}
+	obj.SetGroupVersionKind(kind.gvk)
+	return
+}
+
+func objectPath(kind resourceKind, name string) string {
+	return path.Join(kind.dir, name+".yaml")
+}
+
+// sanitize removes the cluster specific fields, the status is kept only if keepStatus is true
+func sanitize(obj *unstructured.Unstructured, keepStatus bool) *unstructured.Unstructured {
+	result := obj.DeepCopy()
+	for _, field := range []string{"uid", "resourceVersion", "generation", "creationTimestamp", "deletionTimestamp",
+		"deletionGracePeriodSeconds", "managedFields", "selfLink", "ownerReferences", "finalizers"} {
+		unstructured.RemoveNestedField(result.Object, "metadata", field)
+	}
+	unstructured.RemoveNestedField(result.Object, "metadata", "annotations", corev1.LastAppliedConfigAnnotation)
+	if !keepStatus {
+		unstructured.RemoveNestedField(result.Object, "status")
+	}
+	return result
+}
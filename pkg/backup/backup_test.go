@@ -0,0 +1,305 @@
+/*
+Copyright 2022 The KubeSphere Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package backup
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/kubesphere/ks-devops/pkg/api/devops/v1alpha3"
+	"github.com/kubesphere/ks-devops/pkg/api/gitops/v1alpha1"
+	"github.com/kubesphere/ks-devops/pkg/constants"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestBackupAndImport(t *testing.T) {
+	schema, err := v1alpha3.SchemeBuilder.Register().Build()
+	assert.Nil(t, err)
+	assert.Nil(t, v1alpha1.AddToScheme(schema))
+	assert.Nil(t, corev1.AddToScheme(schema))
+
+	project := &v1alpha3.DevOpsProject{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            "demo",
+			ResourceVersion: "1",
+			UID:             "uid",
+			Labels:          map[string]string{constants.WorkspaceLabelKey: "ws"},
+		},
+		Status: v1alpha3.DevOpsProjectStatus{AdminNamespace: "demo"},
+	}
+	pipeline := &v1alpha3.Pipeline{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "demo", Name: "pipeline"},
+		Spec:       v1alpha3.PipelineSpec{Type: v1alpha3.NoScmPipelineType},
+	}
+	credential := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "demo", Name: "git"},
+		Type:       v1alpha3.SecretTypeBasicAuth,
+		Data:       map[string][]byte{v1alpha3.BasicAuthPasswordKey: []byte("token")},
+	}
+	// the secrets which are not credentials should not be exported
+	otherSecret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "demo", Name: "other"},
+		Type:       corev1.SecretTypeOpaque,
+	}
+	application := &v1alpha1.Application{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "demo", Name: "app"},
+	}
+	newRun := func(name string, creation, completion time.Time) *v1alpha3.PipelineRun {
+		run := &v1alpha3.PipelineRun{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "demo", Name: name, CreationTimestamp: metav1.NewTime(creation)},
+			Spec: v1alpha3.PipelineRunSpec{
+				PipelineRef: &corev1.ObjectReference{Namespace: "demo", Name: "pipeline"},
+			},
+			Status: v1alpha3.PipelineRunStatus{Phase: v1alpha3.Succeeded},
+		}
+		if !completion.IsZero() {
+			run.Status.CompletionTime = &metav1.Time{Time: completion}
+		}
+		return run
+	}
+	now := time.Now().Truncate(time.Second)
+	oldRun := newRun("run-1", now.Add(-2*time.Hour), now.Add(-time.Hour))
+	recentRun := newRun("run-2", now.Add(-time.Hour), now)
+	runningRun := newRun("run-3", now, time.Time{})
+	runData := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "demo", Name: "run-2"},
+		Data:       map[string]string{"data": "stages"},
+	}
+
+	objects := []client.Object{project, pipeline, credential, otherSecret, application,
+		oldRun, recentRun, runningRun, runData}
+
+	tests := []struct {
+		name          string
+		options       Options
+		importOptions ImportOptions
+		existing      []client.Object
+		wantErr       bool
+		wantImportErr bool
+		verify        func(*testing.T, client.Client, *Report)
+	}{{
+		name:    "invalid credential mode",
+		options: Options{Credentials: "invalid"},
+		wantErr: true,
+	}, {
+		name:    "encrypt without passphrase",
+		options: Options{Credentials: CredentialModeEncrypt},
+		wantErr: true,
+	}, {
+		name:          "import into a new project",
+		options:       Options{Credentials: CredentialModePlain, PipelineRunLimit: 1},
+		importOptions: ImportOptions{Project: "copy", Workspace: "new-ws"},
+		verify: func(t *testing.T, c client.Client, report *Report) {
+			assert.Empty(t, report.Skipped)
+			assert.Contains(t, report.Created, "DevOpsProject copy")
+			assert.Contains(t, report.Created, "Pipeline copy/pipeline")
+
+			project := &v1alpha3.DevOpsProject{}
+			assert.Nil(t, c.Get(context.Background(), types.NamespacedName{Name: "copy"}, project))
+			assert.Equal(t, "new-ws", project.Labels[constants.WorkspaceLabelKey])
+			assert.Empty(t, project.Status.AdminNamespace)
+
+			secret := &corev1.Secret{}
+			assert.Nil(t, c.Get(context.Background(), types.NamespacedName{Namespace: "copy", Name: "git"}, secret))
+			assert.Equal(t, "token", string(secret.Data[v1alpha3.BasicAuthPasswordKey]))
+			err := c.Get(context.Background(), types.NamespacedName{Namespace: "copy", Name: "other"}, secret)
+			assert.NotNil(t, err)
+
+			err = c.Get(context.Background(), types.NamespacedName{Namespace: "copy", Name: "app"}, &v1alpha1.Application{})
+			assert.Nil(t, err)
+
+			// only the recent completed PipelineRun is imported
+			runs := &v1alpha3.PipelineRunList{}
+			assert.Nil(t, c.List(context.Background(), runs, client.InNamespace("copy")))
+			if assert.Equal(t, 1, len(runs.Items)) {
+				run := runs.Items[0]
+				assert.Equal(t, "run-2", run.Name)
+				assert.Equal(t, "true", run.Labels[v1alpha3.PipelineRunOrphanLabelKey])
+				assert.Equal(t, "copy", run.Spec.PipelineRef.Namespace)
+				assert.Equal(t, v1alpha3.Succeeded, run.Status.Phase)
+				assert.NotNil(t, run.Status.CompletionTime)
+			}
+			err = c.Get(context.Background(), types.NamespacedName{Namespace: "copy", Name: "run-2"}, &corev1.ConfigMap{})
+			assert.Nil(t, err)
+		},
+	}, {
+		name:          "encrypted credentials",
+		options:       Options{Credentials: CredentialModeEncrypt, Passphrase: "secret"},
+		importOptions: ImportOptions{Project: "copy", Passphrase: "secret"},
+		verify: func(t *testing.T, c client.Client, report *Report) {
+			secret := &corev1.Secret{}
+			assert.Nil(t, c.Get(context.Background(), types.NamespacedName{Namespace: "copy", Name: "git"}, secret))
+			assert.Equal(t, "token", string(secret.Data[v1alpha3.BasicAuthPasswordKey]))
+
+			// the PipelineRuns are not included by default
+			runs := &v1alpha3.PipelineRunList{}
+			assert.Nil(t, c.List(context.Background(), runs, client.InNamespace("copy")))
+			assert.Empty(t, runs.Items)
+		},
+	}, {
+		name:          "encrypted credentials with a wrong passphrase",
+		options:       Options{Credentials: CredentialModeEncrypt, Passphrase: "secret"},
+		importOptions: ImportOptions{Project: "copy", Passphrase: "wrong"},
+		wantImportErr: true,
+	}, {
+		name:          "redacted credentials",
+		options:       Options{Credentials: CredentialModeRedact},
+		importOptions: ImportOptions{Project: "copy"},
+		verify: func(t *testing.T, c client.Client, report *Report) {
+			secret := &corev1.Secret{}
+			assert.Nil(t, c.Get(context.Background(), types.NamespacedName{Namespace: "copy", Name: "git"}, secret))
+			assert.Equal(t, v1alpha3.SecretTypeBasicAuth, secret.Type)
+			assert.Empty(t, secret.Data[v1alpha3.BasicAuthPasswordKey])
+		},
+	}, {
+		name:          "skip credentials",
+		options:       Options{Credentials: CredentialModeSkip},
+		importOptions: ImportOptions{Project: "copy"},
+		verify: func(t *testing.T, c client.Client, report *Report) {
+			err := c.Get(context.Background(), types.NamespacedName{Namespace: "copy", Name: "git"}, &corev1.Secret{})
+			assert.NotNil(t, err)
+		},
+	}, {
+		name:          "skip the existing resources",
+		options:       Options{Credentials: CredentialModePlain},
+		importOptions: ImportOptions{Project: "copy"},
+		existing: []client.Object{&v1alpha3.Pipeline{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "copy", Name: "pipeline"},
+			Spec:       v1alpha3.PipelineSpec{Type: v1alpha3.MultiBranchPipelineType},
+		}},
+		verify: func(t *testing.T, c client.Client, report *Report) {
+			assert.Equal(t, []string{"Pipeline copy/pipeline"}, report.Skipped)
+
+			pipeline := &v1alpha3.Pipeline{}
+			assert.Nil(t, c.Get(context.Background(), types.NamespacedName{Namespace: "copy", Name: "pipeline"}, pipeline))
+			assert.Equal(t, v1alpha3.MultiBranchPipelineType, pipeline.Spec.Type)
+		},
+	}, {
+		name:          "overwrite the existing resources",
+		options:       Options{Credentials: CredentialModePlain},
+		importOptions: ImportOptions{Project: "copy", Conflict: ConflictPolicyOverwrite},
+		existing: []client.Object{&v1alpha3.Pipeline{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "copy", Name: "pipeline"},
+			Spec:       v1alpha3.PipelineSpec{Type: v1alpha3.MultiBranchPipelineType},
+		}},
+		verify: func(t *testing.T, c client.Client, report *Report) {
+			assert.Equal(t, []string{"Pipeline copy/pipeline"}, report.Updated)
+
+			pipeline := &v1alpha3.Pipeline{}
+			assert.Nil(t, c.Get(context.Background(), types.NamespacedName{Namespace: "copy", Name: "pipeline"}, pipeline))
+			assert.Equal(t, v1alpha3.NoScmPipelineType, pipeline.Spec.Type)
+		},
+	}, {
+		name:          "fail with the existing resources",
+		options:       Options{Credentials: CredentialModePlain},
+		importOptions: ImportOptions{Project: "copy", Conflict: ConflictPolicyFail},
+		existing: []client.Object{&v1alpha3.Pipeline{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "copy", Name: "pipeline"},
+		}},
+		wantImportErr: true,
+	}, {
+		name:          "invalid conflict policy",
+		options:       Options{Credentials: CredentialModePlain},
+		importOptions: ImportOptions{Project: "copy", Conflict: "invalid"},
+		wantImportErr: true,
+	}, {
+		name:          "the namespace is not ready",
+		options:       Options{Credentials: CredentialModePlain},
+		importOptions: ImportOptions{Project: "absent", NamespaceTimeout: time.Millisecond},
+		wantImportErr: true,
+	}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var instances []client.Object
+			for _, obj := range objects {
+				instances = append(instances, obj.DeepCopyObject().(client.Object))
+			}
+			source := fake.NewClientBuilder().WithScheme(schema).WithObjects(instances...).Build()
+
+			buf := &bytes.Buffer{}
+			err := Backup(context.Background(), source, "demo", tt.options, buf)
+			assert.Equal(t, tt.wantErr, err != nil, err)
+			if tt.wantErr {
+				return
+			}
+
+			// the namespace is supposed to be created by the DevOps project controller
+			existing := append([]client.Object{&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "copy"}}}, tt.existing...)
+			target := fake.NewClientBuilder().WithScheme(schema).WithObjects(existing...).
+				WithStatusSubresource(&v1alpha3.PipelineRun{}).Build()
+			report, err := Import(context.Background(), target, buf, tt.importOptions)
+			assert.Equal(t, tt.wantImportErr, err != nil, err)
+			if tt.verify != nil {
+				tt.verify(t, target, report)
+			}
+		})
+	}
+}
+
+func TestImport_InvalidArchive(t *testing.T) {
+	c := fake.NewClientBuilder().WithScheme(runtime.NewScheme()).Build()
+
+	_, err := Import(context.Background(), c, bytes.NewBufferString("invalid"), ImportOptions{})
+	assert.NotNil(t, err)
+
+	buf := &bytes.Buffer{}
+	writer := newArchiveWriter(buf)
+	assert.Nil(t, writer.writeYAML(manifestFile, &Manifest{Version: "v0"}))
+	assert.Nil(t, writer.Close())
+	_, err = Import(context.Background(), c, buf, ImportOptions{})
+	assert.NotNil(t, err)
+
+	buf = &bytes.Buffer{}
+	writer = newArchiveWriter(buf)
+	assert.Nil(t, writer.writeYAML("../escape.yaml", &Manifest{}))
+	assert.Nil(t, writer.Close())
+	_, err = Import(context.Background(), c, buf, ImportOptions{})
+	assert.NotNil(t, err)
+}
+
+func TestCipher(t *testing.T) {
+	salt, err := newSalt()
+	assert.Nil(t, err)
+
+	_, err = newCipher("", salt)
+	assert.NotNil(t, err)
+
+	aead, err := newCipher("passphrase", salt)
+	assert.Nil(t, err)
+	data, err := encrypt(aead, []byte("plaintext"))
+	assert.Nil(t, err)
+	assert.NotContains(t, string(data), "plaintext")
+
+	plaintext, err := decrypt(aead, data)
+	assert.Nil(t, err)
+	assert.Equal(t, "plaintext", string(plaintext))
+
+	wrong, err := newCipher("wrong", salt)
+	assert.Nil(t, err)
+	_, err = decrypt(wrong, data)
+	assert.NotNil(t, err)
+	_, err = decrypt(aead, []byte("short"))
+	assert.NotNil(t, err)
+}
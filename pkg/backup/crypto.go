@@ -0,0 +1,75 @@
+/*
+Copyright 2022 The KubeSphere Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package backup
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+	"io"
+
+	"golang.org/x/crypto/scrypt"
+)
+
+const saltSize = 16
+
+func newSalt() (salt []byte, err error) {
+	salt = make([]byte, saltSize)
+	_, err = io.ReadFull(rand.Reader, salt)
+	return
+}
+
+// newCipher derives a key from the passphrase, then creates an AES-GCM cipher with it
+func newCipher(passphrase string, salt []byte) (aead cipher.AEAD, err error) {
+	if passphrase == "" {
+		err = errors.New("the passphrase is required for the encrypted credentials")
+		return
+	}
+
+	var key []byte
+	if key, err = scrypt.Key([]byte(passphrase), salt, 1<<15, 8, 1, 32); err != nil {
+		return
+	}
+
+	var block cipher.Block
+	if block, err = aes.NewCipher(key); err == nil {
+		aead, err = cipher.NewGCM(block)
+	}
+	return
+}
+
+// encrypt returns the nonce and the sealed data
+func encrypt(aead cipher.AEAD, plaintext []byte) (data []byte, err error) {
+	nonce := make([]byte, aead.NonceSize())
+	if _, err = io.ReadFull(rand.Reader, nonce); err == nil {
+		data = aead.Seal(nonce, nonce, plaintext, nil)
+	}
+	return
+}
+
+func decrypt(aead cipher.AEAD, data []byte) (plaintext []byte, err error) {
+	if len(data) < aead.NonceSize() {
+		err = errors.New("invalid encrypted data")
+		return
+	}
+	nonce, sealed := data[:aead.NonceSize()], data[aead.NonceSize():]
+	if plaintext, err = aead.Open(nil, nonce, sealed, nil); err != nil {
+		err = errors.New("failed to decrypt the credentials, please check the passphrase")
+	}
+	return
+}
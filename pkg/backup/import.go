@@ -0,0 +1,254 @@
+/*
+Copyright 2022 The KubeSphere Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package backup
+
+import (
+	"context"
+	"crypto/cipher"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/kubesphere/ks-devops/pkg/api/devops/v1alpha3"
+	"github.com/kubesphere/ks-devops/pkg/constants"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/yaml"
+)
+
+// DefaultNamespaceTimeout is the default timeout of waiting for the namespace of a DevOps project
+const DefaultNamespaceTimeout = 2 * time.Minute
+
+// Import recreates the DevOps project and its resources from an archive
+func Import(ctx context.Context, c client.Client, r io.Reader, opts ImportOptions) (report *Report, err error) {
+	if opts.Conflict == "" {
+		opts.Conflict = ConflictPolicySkip
+	}
+	if !opts.Conflict.IsValid() {
+		err = fmt.Errorf("invalid conflict policy: %s", opts.Conflict)
+		return
+	}
+	if opts.NamespaceTimeout == 0 {
+		opts.NamespaceTimeout = DefaultNamespaceTimeout
+	}
+
+	var files map[string][]byte
+	if files, err = readArchive(r); err != nil {
+		return
+	}
+
+	manifest := &Manifest{}
+	if data, ok := files[manifestFile]; !ok {
+		err = fmt.Errorf("%s not found in the archive", manifestFile)
+		return
+	} else if err = yaml.Unmarshal(data, manifest); err != nil {
+		return
+	}
+	if manifest.Version != FormatVersion {
+		err = fmt.Errorf("unsupported archive version: %s", manifest.Version)
+		return
+	}
+
+	var aead cipher.AEAD
+	if manifest.Credentials == CredentialModeEncrypt {
+		if aead, err = newCipher(opts.Passphrase, manifest.Salt); err != nil {
+			return
+		}
+	}
+
+	project := opts.Project
+	if project == "" {
+		project = manifest.Project
+	}
+
+	var projectObj *unstructured.Unstructured
+	if projectObj, err = decodeObject(files, objectPath(projectKind, manifest.Project), projectKind); err != nil {
+		return
+	}
+	projectObj.SetName(project)
+	if opts.Workspace != "" {
+		labels := projectObj.GetLabels()
+		if labels == nil {
+			labels = map[string]string{}
+		}
+		labels[constants.WorkspaceLabelKey] = opts.Workspace
+		projectObj.SetLabels(labels)
+	}
+
+	report = &Report{}
+	if err = apply(ctx, c, projectObj, opts.Conflict, report); err != nil {
+		return
+	}
+	// the namespace is created by the DevOps project controller
+	if err = waitForNamespace(ctx, c, project, opts.NamespaceTimeout); err != nil {
+		return
+	}
+
+	for _, kind := range namespacedKinds {
+		for _, name := range listFiles(files, kind) {
+			var obj *unstructured.Unstructured
+			if obj, err = decodeObject(files, name, kind); err != nil {
+				return
+			}
+			obj.SetNamespace(project)
+
+			switch kind {
+			case secretKind:
+				if aead != nil {
+					err = decryptSecret(aead, obj)
+				}
+			case runKind:
+				err = importPipelineRun(ctx, c, obj, manifest.Project, opts.Conflict, report)
+				if err != nil {
+					return
+				}
+				continue
+			}
+
+			if err == nil {
+				err = apply(ctx, c, obj, opts.Conflict, report)
+			}
+			if err != nil {
+				return
+			}
+		}
+	}
+	return
+}
+
+// importPipelineRun imports a PipelineRun as an orphan, then restores its status
+func importPipelineRun(ctx context.Context, c client.Client, run *unstructured.Unstructured, originalNamespace string,
+	conflict ConflictPolicy, report *Report) (err error) {
+	// make sure the imported PipelineRun will never be triggered
+	labels := run.GetLabels()
+	if labels == nil {
+		labels = map[string]string{}
+	}
+	labels[v1alpha3.PipelineRunOrphanLabelKey] = "true"
+	run.SetLabels(labels)
+	if namespace, _, _ := unstructured.NestedString(run.Object, "spec", "pipelineRef", "namespace"); namespace == originalNamespace {
+		_ = unstructured.SetNestedField(run.Object, run.GetNamespace(), "spec", "pipelineRef", "namespace")
+	}
+
+	status, hasStatus, _ := unstructured.NestedMap(run.Object, "status")
+	skipped := len(report.Skipped)
+	if err = apply(ctx, c, run, conflict, report); err != nil || !hasStatus || len(report.Skipped) > skipped {
+		return
+	}
+
+	_ = unstructured.SetNestedMap(run.Object, status, "status")
+	if err = c.Status().Update(ctx, run); err != nil {
+		err = fmt.Errorf("failed to restore the status of PipelineRun %s, error: %v", run.GetName(), err)
+	}
+	return
+}
+
+// apply creates the object, or handles the existing one according to the conflict policy
+func apply(ctx context.Context, c client.Client, obj *unstructured.Unstructured, conflict ConflictPolicy, report *Report) (err error) {
+	key := client.ObjectKeyFromObject(obj)
+	desc := fmt.Sprintf("%s %s", obj.GetKind(), strings.TrimPrefix(key.String(), "/"))
+
+	existing := &unstructured.Unstructured{}
+	existing.SetGroupVersionKind(obj.GroupVersionKind())
+	if err = c.Get(ctx, key, existing); err == nil {
+		switch conflict {
+		case ConflictPolicyOverwrite:
+			obj.SetResourceVersion(existing.GetResourceVersion())
+			if err = c.Update(ctx, obj); err == nil {
+				report.Updated = append(report.Updated, desc)
+			}
+		case ConflictPolicyFail:
+			err = fmt.Errorf("%s already exists", desc)
+		default:
+			report.Skipped = append(report.Skipped, desc)
+		}
+	} else if apierrors.IsNotFound(err) {
+		if err = c.Create(ctx, obj); err == nil {
+			report.Created = append(report.Created, desc)
+		}
+	}
+
+	if err != nil {
+		err = fmt.Errorf("failed to import %s, error: %v", desc, err)
+	}
+	return
+}
+
+func waitForNamespace(ctx context.Context, c client.Client, name string, timeout time.Duration) (err error) {
+	err = wait.PollUntilContextTimeout(ctx, time.Second, timeout, true, func(ctx context.Context) (bool, error) {
+		getErr := c.Get(ctx, types.NamespacedName{Name: name}, &corev1.Namespace{})
+		return getErr == nil, client.IgnoreNotFound(getErr)
+	})
+	if err != nil {
+		err = fmt.Errorf("failed to wait for the namespace %s, error: %v", name, err)
+	}
+	return
+}
+
+func decryptSecret(aead cipher.AEAD, secret *unstructured.Unstructured) (err error) {
+	data, _, _ := unstructured.NestedStringMap(secret.Object, "data")
+	for key, val := range data {
+		var encrypted, plaintext []byte
+		if encrypted, err = base64.StdEncoding.DecodeString(val); err != nil {
+			return
+		}
+		if plaintext, err = decrypt(aead, encrypted); err != nil {
+			return
+		}
+		data[key] = base64.StdEncoding.EncodeToString(plaintext)
+	}
+	if data != nil {
+		err = unstructured.SetNestedStringMap(secret.Object, data, "data")
+	}
+	return
+}
+
+// listFiles returns the sorted file paths of a kind of resources
+func listFiles(files map[string][]byte, kind resourceKind) (names []string) {
+	for name := range files {
+		if strings.HasPrefix(name, kind.dir+"/") {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return
+}
+
+func decodeObject(files map[string][]byte, name string, kind resourceKind) (obj *unstructured.Unstructured, err error) {
+	data, ok := files[name]
+	if !ok {
+		err = fmt.Errorf("%s not found in the archive", name)
+		return
+	}
+
+	obj = &unstructured.Unstructured{}
+	if err = yaml.Unmarshal(data, &obj.Object); err != nil {
+		err = fmt.Errorf("failed to parse %s, error: %v", name, err)
+		return
+	}
+	if obj.GroupVersionKind() != kind.gvk {
+		err = fmt.Errorf("unexpected kind %s in %s", obj.GetKind(), name)
+	}
+	return
+}
@@ -0,0 +1,144 @@
+/*
+Copyright 2022 The KubeSphere Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package backup
+
+import (
+	"time"
+
+	"github.com/kubesphere/ks-devops/pkg/api/devops/v1alpha3"
+	"github.com/kubesphere/ks-devops/pkg/api/gitops/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// FormatVersion is the version of the archive format
+const FormatVersion = "v1"
+
+const manifestFile = "manifest.yaml"
+
+// CredentialMode represents how to handle the credentials in an archive
+type CredentialMode string
+
+const (
+	// CredentialModePlain keeps the credentials as they are
+	CredentialModePlain CredentialMode = "plain"
+	// CredentialModeEncrypt encrypts the credentials with a passphrase
+	CredentialModeEncrypt CredentialMode = "encrypt"
+	// CredentialModeRedact keeps the credentials without their values
+	CredentialModeRedact CredentialMode = "redact"
+	// CredentialModeSkip does not contain any credentials
+	CredentialModeSkip CredentialMode = "skip"
+)
+
+// IsValid checks if this is valid
+func (c CredentialMode) IsValid() bool {
+	switch c {
+	case CredentialModePlain, CredentialModeEncrypt, CredentialModeRedact, CredentialModeSkip:
+		return true
+	default:
+		return false
+	}
+}
+
+// ConflictPolicy represents how to handle an existing resource when importing
+type ConflictPolicy string
+
+const (
+	// ConflictPolicySkip keeps the existing resource
+	ConflictPolicySkip ConflictPolicy = "skip"
+	// ConflictPolicyOverwrite overwrites the existing resource
+	ConflictPolicyOverwrite ConflictPolicy = "overwrite"
+	// ConflictPolicyFail stops the import
+	ConflictPolicyFail ConflictPolicy = "fail"
+)
+
+// IsValid checks if this is valid
+func (c ConflictPolicy) IsValid() bool {
+	switch c {
+	case ConflictPolicySkip, ConflictPolicyOverwrite, ConflictPolicyFail:
+		return true
+	default:
+		return false
+	}
+}
+
+// Manifest describes an archive
+type Manifest struct {
+	Version     string         `json:"version"`
+	Project     string         `json:"project"`
+	CreatedAt   metav1.Time    `json:"createdAt"`
+	Credentials CredentialMode `json:"credentials"`
+	// Salt is used to derive the key of the encrypted credentials
+	Salt []byte `json:"salt,omitempty"`
+}
+
+// Options are the options of a backup
+type Options struct {
+	Credentials CredentialMode
+	// Passphrase is required when encrypting the credentials
+	Passphrase string
+	// PipelineRunLimit is the max number of the recent completed PipelineRuns of each Pipeline,
+	// the PipelineRuns are not included if it's zero
+	PipelineRunLimit int
+}
+
+// ImportOptions are the options of an import
+type ImportOptions struct {
+	// Project is the new name of the DevOps project, keep the original name if it's empty
+	Project string
+	// Workspace is the new workspace of the DevOps project, keep the original one if it's empty
+	Workspace string
+	// Passphrase is required when the credentials are encrypted
+	Passphrase string
+	Conflict   ConflictPolicy
+	// NamespaceTimeout is the timeout of waiting for the namespace of the DevOps project
+	NamespaceTimeout time.Duration
+}
+
+// Report is the result of an import
+type Report struct {
+	Created []string `json:"created,omitempty"`
+	Updated []string `json:"updated,omitempty"`
+	Skipped []string `json:"skipped,omitempty"`
+}
+
+// resourceKind is a kind of the resources in a DevOps project
+type resourceKind struct {
+	dir string
+	gvk schema.GroupVersionKind
+}
+
+var (
+	projectKind = resourceKind{dir: "devopsprojects", gvk: v1alpha3.GroupVersion.WithKind("DevOpsProject")}
+	secretKind  = resourceKind{dir: "secrets", gvk: corev1.SchemeGroupVersion.WithKind("Secret")}
+	runKind     = resourceKind{dir: "pipelineruns", gvk: v1alpha3.GroupVersion.WithKind("PipelineRun")}
+	// runDataKind is the ConfigMap which stores the data of a PipelineRun
+	runDataKind = resourceKind{dir: "configmaps", gvk: corev1.SchemeGroupVersion.WithKind("ConfigMap")}
+
+	// namespacedKinds are the resources in the namespace of a DevOps project, in the order of importing
+	namespacedKinds = []resourceKind{
+		secretKind,
+		{dir: "gitrepositories", gvk: v1alpha3.GroupVersion.WithKind("GitRepository")},
+		{dir: "templates", gvk: v1alpha3.GroupVersion.WithKind("Template")},
+		{dir: "pipelines", gvk: v1alpha3.GroupVersion.WithKind("Pipeline")},
+		{dir: "webhooks", gvk: v1alpha3.GroupVersion.WithKind("Webhook")},
+		{dir: "applications", gvk: v1alpha1.GroupVersion.WithKind("Application")},
+		runKind,
+		runDataKind,
+	}
+)
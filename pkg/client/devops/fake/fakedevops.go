@@ -529,37 +529,8 @@ func (d *Devops) UpdateProjectPipeline(projectId string, pipeline *devopsv1alpha
 
 func (d *Devops) GetProjectPipelineConfig(projectId, pipelineId string) (*devopsv1alpha3.Pipeline, error) {
 	if _, ok := d.Pipelines[projectId][pipelineId]; !ok {
-		err := &devops.ErrorResponse{
-			Body: []byte{},
-			Response: &http.Response{
-				Status:        "404 Not Found",
-				StatusCode:    404,
-				Proto:         "HTTP/1.1",
-				ProtoMajor:    1,
-				ProtoMinor:    1,
-				ContentLength: 50,
-				Header: http.Header{
-					"Foo": []string{"Bar"},
-				},
-				Body: ioutil.NopCloser(strings.NewReader("foo")), // shouldn't be used
-				Request: &http.Request{
-					Method: "",
-					URL: &url.URL{
-						Scheme:     "",
-						Opaque:     "",
-						User:       nil,
-						Host:       "",
-						Path:       "",
-						RawPath:    "",
-						ForceQuery: false,
-						RawQuery:   "",
-						Fragment:   "",
-					},
-				},
-			},
-			Message: "",
-		}
-		return nil, err
+		// the same as the Jenkins client, the error is wrapped as restful.ServiceError
+		return nil, restful.NewError(http.StatusNotFound, fmt.Sprintf("pipeline %s/%s not found", projectId, pipelineId))
 	}
 
 	return d.Pipelines[projectId][pipelineId], nil
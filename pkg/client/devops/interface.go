@@ -17,12 +17,14 @@ limitations under the License.
 package devops
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
 	"strconv"
 	"strings"
 
 	"github.com/asaskevich/govalidator"
+	"github.com/emicklei/go-restful/v3"
 )
 
 type Interface interface {
@@ -50,6 +52,11 @@ func GetDevOpsStatusCode(devopsErr error) int {
 	if jErr, ok := devopsErr.(*ErrorResponse); ok {
 		return jErr.Response.StatusCode
 	}
+	// the Jenkins client wraps the errors as restful.ServiceError
+	var serviceErr restful.ServiceError
+	if errors.As(devopsErr, &serviceErr) && serviceErr.Code != 0 {
+		return serviceErr.Code
+	}
 	return http.StatusInternalServerError
 }
 
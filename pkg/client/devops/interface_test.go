@@ -21,6 +21,8 @@ import (
 	"net/http"
 	"net/url"
 	"testing"
+
+	"github.com/emicklei/go-restful/v3"
 )
 
 func TestGetDevOpsStatusCode(t *testing.T) {
@@ -52,6 +54,14 @@ func TestGetDevOpsStatusCode(t *testing.T) {
 			},
 		},
 		want: http.StatusNotFound,
+	}, {
+		name: "ServiceError",
+		args: args{devopsErr: restful.NewError(http.StatusNotFound, "404 Not Found")},
+		want: http.StatusNotFound,
+	}, {
+		name: "wrapped ServiceError",
+		args: args{devopsErr: fmt.Errorf("failed to get the job: %w", restful.NewError(http.StatusForbidden, "forbidden"))},
+		want: http.StatusForbidden,
 	}, {
 		name: "other error type",
 		args: args{devopsErr: fmt.Errorf("other error type")},
@@ -45,3 +45,12 @@ func NewJenkinsClient(options *jenkins.Options) (*JenkinsClient, error) {
 		jenkins: devopsClient, // For refactor purpose only
 	}, nil
 }
+
+// WithHealthMonitor makes all requests to Jenkins go through the circuit breaker of the monitor
+func (j *JenkinsClient) WithHealthMonitor(monitor *jenkins.HealthMonitor) *JenkinsClient {
+	j.Core.RoundTripper = monitor.RoundTripper(j.Core.RoundTripper)
+	if j.jenkins != nil {
+		j.jenkins.Requester.WithHealthMonitor(monitor)
+	}
+	return j
+}
@@ -0,0 +1,44 @@
+/*
+Copyright 2022 The KubeSphere Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package jenkins
+
+import (
+	"context"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/kubesphere/ks-devops/pkg/api/devops/v1alpha3"
+)
+
+const (
+	// CasCConfigMapName is the name of the ConfigMap of Jenkins configuration-as-code
+	CasCConfigMapName = "jenkins-casc-config"
+	// CasCUserKey is the key of the user configuration in the ConfigMap of Jenkins configuration-as-code
+	CasCUserKey = "jenkins_user.yaml"
+)
+
+// GetGitLabServers returns the GitLab servers configured in the Jenkins of the namespace,
+// it returns nil if there is no such configuration
+func GetGitLabServers(ctx context.Context, reader client.Reader, namespace string) (servers v1alpha3.GitLabServers) {
+	cm := &v1.ConfigMap{}
+	if err := reader.Get(ctx, types.NamespacedName{Namespace: namespace, Name: CasCConfigMapName}, cm); err == nil {
+		servers, _ = v1alpha3.ParseGitLabServers(cm.Data[CasCUserKey])
+	}
+	return
+}
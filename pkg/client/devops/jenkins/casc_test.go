@@ -0,0 +1,46 @@
+/*
+Copyright 2022 The KubeSphere Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package jenkins
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/kubesphere/ks-devops/pkg/api/devops/v1alpha3"
+)
+
+func TestGetGitLabServers(t *testing.T) {
+	reader := fake.NewClientBuilder().WithObjects(&v1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "devops-system", Name: CasCConfigMapName},
+		Data: map[string]string{CasCUserKey: `
+unclassified:
+  gitLabServers:
+    servers:
+    - name: internal
+      serverUrl: "https://gitlab.example.com/"
+`},
+	}).Build()
+
+	assert.Equal(t, v1alpha3.GitLabServers{"internal": "https://gitlab.example.com"},
+		GetGitLabServers(context.Background(), reader, "devops-system"))
+	assert.Nil(t, GetGitLabServers(context.Background(), reader, "kubesphere-devops-system"))
+}
@@ -0,0 +1,329 @@
+/*
+Copyright 2022 The KubeSphere Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package jenkins
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"k8s.io/klog/v2"
+)
+
+// ErrJenkinsUnavailable indicates that a request was rejected because Jenkins is unavailable
+var ErrJenkinsUnavailable = errors.New("jenkins is unavailable")
+
+const (
+	// DefaultHealthFailureThreshold is the default count of consecutive failures which marks Jenkins as unavailable
+	DefaultHealthFailureThreshold = 3
+	// DefaultHealthProbeInterval is the default interval of probing Jenkins
+	DefaultHealthProbeInterval = 10 * time.Second
+	// HealthConfigMapName is the name of the ConfigMap which holds the cluster-wide condition of the default Jenkins
+	HealthConfigMapName = "jenkins-health"
+)
+
+// GetHealthConfigMapName returns the name of the ConfigMap which holds the cluster-wide condition of a Jenkins instance
+func GetHealthConfigMapName(instance string) string {
+	if instance == "" || instance == DefaultInstanceName {
+		return HealthConfigMapName
+	}
+	return HealthConfigMapName + "-" + instance
+}
+
+// BreakerState is the state of the circuit breaker in front of Jenkins
+type BreakerState string
+
+const (
+	// BreakerClosed lets all requests go through, Jenkins is available
+	BreakerClosed BreakerState = "Closed"
+	// BreakerOpen rejects all requests, Jenkins is unavailable
+	BreakerOpen BreakerState = "Open"
+	// BreakerHalfOpen lets a single trial request go through to find out if Jenkins has recovered
+	BreakerHalfOpen BreakerState = "HalfOpen"
+)
+
+// HealthStatus is a snapshot of the health of Jenkins
+type HealthStatus struct {
+	State               BreakerState
+	ConsecutiveFailures int
+	LastError           string
+	LastTransitionTime  time.Time
+	LastProbeTime       time.Time
+}
+
+// Available returns false if the requests to Jenkins are being rejected
+func (s HealthStatus) Available() bool {
+	return s.State != BreakerOpen
+}
+
+// HealthChecker tells if Jenkins is available
+type HealthChecker interface {
+	// Status returns the current health of Jenkins
+	Status() HealthStatus
+	// RetryAfter returns how long the callers should wait before trying again when Jenkins is unavailable
+	RetryAfter() time.Duration
+}
+
+// HealthMonitor watches the health of Jenkins, and provides circuit-breaker semantics to the requests sent to it.
+// The breaker opens after some consecutive failures, then all requests fail fast with ErrJenkinsUnavailable.
+// It turns half-open after a probe interval and closes once a trial request or a probe succeeds.
+// A monitor should be shared by all the clients of the same Jenkins.
+type HealthMonitor struct {
+	URL              string
+	Username         string
+	Password         string
+	FailureThreshold int
+	Interval         time.Duration
+	// Client is used to send the probe requests
+	Client *http.Client
+
+	mutex         sync.RWMutex
+	status        HealthStatus
+	openedAt      time.Time
+	trialInFlight bool
+	changed       bool
+	listeners     []func(HealthStatus)
+	now           func() time.Time
+}
+
+var _ HealthChecker = &HealthMonitor{}
+
+// NewHealthMonitor creates a monitor of the Jenkins which is configured by the options
+func NewHealthMonitor(options *Options) *HealthMonitor {
+	password := options.Password
+	if password == "" {
+		password = options.ApiToken // use apiToken if without password
+	}
+	monitor := &HealthMonitor{
+		URL:              options.Host,
+		Username:         options.Username,
+		Password:         password,
+		FailureThreshold: options.HealthFailureThreshold,
+		Interval:         options.HealthProbeInterval,
+		now:              time.Now,
+	}
+	if monitor.FailureThreshold <= 0 {
+		monitor.FailureThreshold = DefaultHealthFailureThreshold
+	}
+	if monitor.Interval <= 0 {
+		monitor.Interval = DefaultHealthProbeInterval
+	}
+	monitor.Client = &http.Client{Timeout: monitor.Interval}
+	monitor.status = HealthStatus{State: BreakerClosed, LastTransitionTime: monitor.now()}
+	return monitor
+}
+
+// OnStateChange registers a listener which is called with the latest status once the state of the breaker changed
+func (m *HealthMonitor) OnStateChange(listener func(HealthStatus)) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.listeners = append(m.listeners, listener)
+}
+
+// Status returns the current health of Jenkins
+func (m *HealthMonitor) Status() HealthStatus {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+	return m.status
+}
+
+// RetryAfter returns the probe interval, an open breaker turns half-open after it
+func (m *HealthMonitor) RetryAfter() time.Duration {
+	return m.Interval
+}
+
+// Allow checks if a request could be sent to Jenkins, ErrJenkinsUnavailable is returned if not
+func (m *HealthMonitor) Allow() (err error) {
+	m.mutex.Lock()
+	if m.status.State == BreakerOpen && m.now().Sub(m.openedAt) >= m.Interval {
+		m.transit(BreakerHalfOpen)
+	}
+	switch {
+	case m.status.State == BreakerOpen:
+		err = fmt.Errorf("%w, last error: %s", ErrJenkinsUnavailable, m.status.LastError)
+	case m.status.State == BreakerHalfOpen && m.trialInFlight:
+		err = fmt.Errorf("%w, waiting for the trial request", ErrJenkinsUnavailable)
+	case m.status.State == BreakerHalfOpen:
+		m.trialInFlight = true
+	}
+	listeners, status := m.pendingNotification()
+	m.mutex.Unlock()
+
+	notify(listeners, status)
+	return
+}
+
+// RecordSuccess records a successful request, it closes the breaker
+func (m *HealthMonitor) RecordSuccess() {
+	m.record(nil)
+}
+
+// RecordFailure records a failed request, it opens the breaker if the failures reached the threshold,
+// or the trial request of a half-open breaker failed
+func (m *HealthMonitor) RecordFailure(err error) {
+	if err == nil {
+		err = ErrJenkinsUnavailable
+	}
+	m.record(err)
+}
+
+func (m *HealthMonitor) record(err error) {
+	m.mutex.Lock()
+	m.trialInFlight = false
+	if err == nil {
+		m.status.ConsecutiveFailures = 0
+		m.status.LastError = ""
+		m.transit(BreakerClosed)
+	} else {
+		m.status.ConsecutiveFailures++
+		m.status.LastError = err.Error()
+		if m.status.State != BreakerClosed || m.status.ConsecutiveFailures >= m.FailureThreshold {
+			m.openedAt = m.now()
+			m.transit(BreakerOpen)
+		}
+	}
+	listeners, status := m.pendingNotification()
+	m.mutex.Unlock()
+
+	notify(listeners, status)
+}
+
+// transit changes the state of the breaker, it must be called with the lock held
+func (m *HealthMonitor) transit(state BreakerState) {
+	if m.status.State == state {
+		return
+	}
+	klog.V(4).Infof("the circuit breaker of Jenkins %s turns from %s to %s", m.URL, m.status.State, state)
+	m.status.State = state
+	m.status.LastTransitionTime = m.now()
+	m.changed = true
+}
+
+// pendingNotification returns the listeners which need to be notified, it must be called with the lock held
+func (m *HealthMonitor) pendingNotification() (listeners []func(HealthStatus), status HealthStatus) {
+	if m.changed {
+		m.changed = false
+		listeners = m.listeners
+	}
+	status = m.status
+	return
+}
+
+func notify(listeners []func(HealthStatus), status HealthStatus) {
+	for _, listener := range listeners {
+		listener(status)
+	}
+}
+
+// Probe requests the API of Jenkins and records the result
+func (m *HealthMonitor) Probe(ctx context.Context) (err error) {
+	var req *http.Request
+	if req, err = http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimSuffix(m.URL, "/")+"/api/json", nil); err != nil {
+		return
+	}
+	if m.Username != "" {
+		req.SetBasicAuth(m.Username, m.Password)
+	}
+
+	var resp *http.Response
+	if resp, err = m.Client.Do(req); err == nil {
+		_ = resp.Body.Close()
+		err = checkAvailable(resp)
+	}
+	if ctx.Err() != nil {
+		// being cancelled is not a failure of Jenkins
+		return ctx.Err()
+	}
+
+	m.mutex.Lock()
+	m.status.LastProbeTime = m.now()
+	m.mutex.Unlock()
+	m.record(err)
+	return
+}
+
+// Start probes Jenkins periodically until the context is done, it implements manager.Runnable
+func (m *HealthMonitor) Start(ctx context.Context) error {
+	ticker := time.NewTicker(m.Interval)
+	defer ticker.Stop()
+	for {
+		if err := m.Probe(ctx); err != nil && ctx.Err() == nil {
+			klog.V(4).Infof("failed to probe Jenkins %s, error: %v", m.URL, err)
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// NeedLeaderElection returns false, every replica needs to know the health of Jenkins
+func (m *HealthMonitor) NeedLeaderElection() bool {
+	return false
+}
+
+// RoundTripper wraps a transport with the circuit breaker, http.DefaultTransport is wrapped if next is nil
+func (m *HealthMonitor) RoundTripper(next http.RoundTripper) http.RoundTripper {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return &breakerRoundTripper{monitor: m, next: next}
+}
+
+type breakerRoundTripper struct {
+	monitor *HealthMonitor
+	next    http.RoundTripper
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *breakerRoundTripper) RoundTrip(req *http.Request) (resp *http.Response, err error) {
+	if err = t.monitor.Allow(); err != nil {
+		return
+	}
+	if resp, err = t.next.RoundTrip(req); err != nil {
+		t.monitor.RecordFailure(err)
+	} else if unavailableErr := checkAvailable(resp); unavailableErr != nil {
+		t.monitor.RecordFailure(unavailableErr)
+	} else {
+		t.monitor.RecordSuccess()
+	}
+	return
+}
+
+// checkAvailable returns an error if the response means that Jenkins or the gateway in front of it is unavailable
+func checkAvailable(resp *http.Response) error {
+	switch resp.StatusCode {
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return fmt.Errorf("unexpected status code %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// WithHealthMonitor makes the requests go through the circuit breaker of the monitor
+func (r *Requester) WithHealthMonitor(monitor *HealthMonitor) *Requester {
+	client := http.Client{}
+	if r.Client != nil {
+		client = *r.Client
+	}
+	client.Transport = monitor.RoundTripper(client.Transport)
+	return r.SetClient(&client)
+}
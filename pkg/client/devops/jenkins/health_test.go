@@ -0,0 +1,210 @@
+/*
+Copyright 2022 The KubeSphere Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package jenkins
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeJenkins is a Jenkins server which could be taken down
+type fakeJenkins struct {
+	*httptest.Server
+	down     atomic.Bool
+	requests atomic.Int32
+}
+
+func newFakeJenkins() *fakeJenkins {
+	fake := &fakeJenkins{}
+	fake.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fake.requests.Add(1)
+		if fake.down.Load() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		if username, password, ok := r.BasicAuth(); !ok || username != "admin" || password != "token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"mode":"NORMAL"}`))
+	}))
+	return fake
+}
+
+func newFakeHealthMonitor(url string) (monitor *HealthMonitor, clock *time.Time) {
+	monitor = NewHealthMonitor(&Options{
+		Host:                   url,
+		Username:               "admin",
+		ApiToken:               "token",
+		HealthFailureThreshold: 2,
+		HealthProbeInterval:    time.Minute,
+	})
+	now := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+	clock = &now
+	monitor.now = func() time.Time {
+		return *clock
+	}
+	return
+}
+
+func TestNewHealthMonitor(t *testing.T) {
+	monitor := NewHealthMonitor(&Options{Host: "http://jenkins", Username: "admin", Password: "password"})
+	assert.Equal(t, DefaultHealthFailureThreshold, monitor.FailureThreshold)
+	assert.Equal(t, DefaultHealthProbeInterval, monitor.Interval)
+	assert.Equal(t, DefaultHealthProbeInterval, monitor.RetryAfter())
+	assert.Equal(t, "password", monitor.Password)
+	assert.Equal(t, BreakerClosed, monitor.Status().State)
+	assert.True(t, monitor.Status().Available())
+	assert.False(t, monitor.NeedLeaderElection())
+}
+
+func TestGetHealthConfigMapName(t *testing.T) {
+	assert.Equal(t, "jenkins-health", GetHealthConfigMapName(""))
+	assert.Equal(t, "jenkins-health", GetHealthConfigMapName(DefaultInstanceName))
+	assert.Equal(t, "jenkins-health-b", GetHealthConfigMapName("b"))
+}
+
+func TestHealthMonitor_Probe(t *testing.T) {
+	server := newFakeJenkins()
+	defer server.Close()
+	monitor, _ := newFakeHealthMonitor(server.URL)
+
+	var states []BreakerState
+	monitor.OnStateChange(func(status HealthStatus) {
+		states = append(states, status.State)
+	})
+
+	assert.Nil(t, monitor.Probe(context.Background()))
+	assert.Equal(t, BreakerClosed, monitor.Status().State)
+	assert.False(t, monitor.Status().LastProbeTime.IsZero())
+
+	server.down.Store(true)
+	assert.NotNil(t, monitor.Probe(context.Background()))
+	assert.Equal(t, BreakerClosed, monitor.Status().State, "the breaker should not open before reaching the threshold")
+	assert.NotNil(t, monitor.Probe(context.Background()))
+	assert.Equal(t, BreakerOpen, monitor.Status().State)
+	assert.Equal(t, 2, monitor.Status().ConsecutiveFailures)
+	assert.Equal(t, "unexpected status code 503", monitor.Status().LastError)
+	assert.True(t, errors.Is(monitor.Allow(), ErrJenkinsUnavailable))
+
+	server.down.Store(false)
+	assert.Nil(t, monitor.Probe(context.Background()))
+	assert.Equal(t, HealthStatus{
+		State:              BreakerClosed,
+		LastTransitionTime: monitor.now(),
+		LastProbeTime:      monitor.now(),
+	}, monitor.Status())
+	assert.Nil(t, monitor.Allow())
+	assert.Equal(t, []BreakerState{BreakerOpen, BreakerClosed}, states)
+
+	// a cancelled probe is not a failure of Jenkins
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.NotNil(t, monitor.Probe(ctx))
+	assert.Equal(t, 0, monitor.Status().ConsecutiveFailures)
+}
+
+func TestHealthMonitor_HalfOpen(t *testing.T) {
+	monitor, clock := newFakeHealthMonitor("http://jenkins")
+	monitor.RecordFailure(nil)
+	monitor.RecordFailure(errors.New("connection refused"))
+	assert.Equal(t, BreakerOpen, monitor.Status().State)
+	assert.True(t, errors.Is(monitor.Allow(), ErrJenkinsUnavailable))
+
+	// a trial request is allowed after the interval
+	*clock = clock.Add(time.Minute)
+	assert.Nil(t, monitor.Allow())
+	assert.Equal(t, BreakerHalfOpen, monitor.Status().State)
+	assert.True(t, monitor.Status().Available())
+	assert.True(t, errors.Is(monitor.Allow(), ErrJenkinsUnavailable), "only one trial request is allowed")
+
+	// the breaker opens again if the trial request failed
+	monitor.RecordFailure(errors.New("connection refused"))
+	assert.Equal(t, BreakerOpen, monitor.Status().State)
+	*clock = clock.Add(time.Second)
+	assert.True(t, errors.Is(monitor.Allow(), ErrJenkinsUnavailable))
+
+	// the breaker closes if the trial request succeeded
+	*clock = clock.Add(time.Minute)
+	assert.Nil(t, monitor.Allow())
+	monitor.RecordSuccess()
+	assert.Equal(t, BreakerClosed, monitor.Status().State)
+	assert.Nil(t, monitor.Allow())
+}
+
+func TestHealthMonitor_Start(t *testing.T) {
+	server := newFakeJenkins()
+	defer server.Close()
+	server.down.Store(true)
+	monitor, _ := newFakeHealthMonitor(server.URL)
+	monitor.FailureThreshold = 1
+
+	opened := make(chan HealthStatus, 1)
+	monitor.OnStateChange(func(status HealthStatus) {
+		opened <- status
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- monitor.Start(ctx)
+	}()
+
+	select {
+	case status := <-opened:
+		assert.Equal(t, BreakerOpen, status.State)
+	case <-time.After(10 * time.Second):
+		t.Fatal("the breaker was not opened by the probe")
+	}
+	cancel()
+	assert.Nil(t, <-done)
+}
+
+func TestRequester_WithHealthMonitor(t *testing.T) {
+	server := newFakeJenkins()
+	defer server.Close()
+	monitor, _ := newFakeHealthMonitor(server.URL)
+
+	jenkins := CreateJenkins(&http.Client{}, server.URL, 10, "admin", "token")
+	jenkins.Requester.WithHealthMonitor(monitor)
+
+	_, err := jenkins.Requester.GetJSON("/", nil, nil)
+	assert.Nil(t, err)
+
+	server.down.Store(true)
+	for i := 0; i < monitor.FailureThreshold; i++ {
+		_, err = jenkins.Requester.GetJSON("/", nil, nil)
+		assert.NotNil(t, err)
+		assert.False(t, errors.Is(err, ErrJenkinsUnavailable))
+	}
+	assert.Equal(t, BreakerOpen, monitor.Status().State)
+
+	// fail fast without sending requests to Jenkins
+	requests := server.requests.Load()
+	_, err = jenkins.Requester.GetJSON("/", nil, nil)
+	assert.True(t, errors.Is(err, ErrJenkinsUnavailable))
+	_, err = jenkins.Requester.Post("/job/test/build", nil, nil, nil)
+	assert.True(t, errors.Is(err, ErrJenkinsUnavailable))
+	assert.Equal(t, requests, server.requests.Load())
+}
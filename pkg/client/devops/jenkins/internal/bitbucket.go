@@ -41,7 +41,7 @@ func AppendBitbucketServerSourceToEtree(source *etree.Element, gitSource *devops
 
 	traits := source.CreateElement("traits")
 	if gitSource.DiscoverBranches != 0 {
-		traits.CreateElement("com.cloudbees.jenkins.plugins.bitbucket.BranchDiscoveryTrait>").
+		traits.CreateElement("com.cloudbees.jenkins.plugins.bitbucket.BranchDiscoveryTrait").
 			CreateElement("strategyId").SetText(strconv.Itoa(gitSource.DiscoverBranches))
 	}
 	if gitSource.DiscoverPRFromOrigin != 0 {
@@ -140,20 +140,73 @@ func GetBitbucketServerSourceFromEtree(source *etree.Element) *devopsv1alpha3.Bi
 				klog.Warningf("invalid Bitbucket discover PR trust value: %s", trust[1])
 			}
 		}
+	}
 
-		s.CloneOption = parseFromCloneTrait(traits.SelectElement("jenkins.plugins.git.traits.CloneOptionTrait"))
+	s.CloneOption = parseFromCloneTrait(traits.SelectElement("jenkins.plugins.git.traits.CloneOptionTrait"))
 
-		if regexTrait := traits.SelectElement(
-			"jenkins.scm.impl.trait.RegexSCMHeadFilterTrait"); regexTrait != nil {
-			if regex := regexTrait.SelectElement("regex"); regex != nil {
-				s.RegexFilter = regex.Text()
-			}
+	if regexTrait := traits.SelectElement(
+		"jenkins.scm.impl.trait.RegexSCMHeadFilterTrait"); regexTrait != nil {
+		if regex := regexTrait.SelectElement("regex"); regex != nil {
+			s.RegexFilter = regex.Text()
 		}
+	}
 
-		if skipNotificationTrait := traits.SelectElement(
-			"com.cloudbees.jenkins.plugins.bitbucket.notifications.SkipNotificationsTrait"); skipNotificationTrait == nil {
-			s.AcceptJenkinsNotification = true
-		}
+	if skipNotificationTrait := traits.SelectElement(
+		"com.cloudbees.jenkins.plugins.bitbucket.notifications.SkipNotificationsTrait"); skipNotificationTrait == nil {
+		s.AcceptJenkinsNotification = true
 	}
 	return &s
 }
+
+// BitbucketCloudServerURL is the server URL of Bitbucket Cloud. The existing jobs with the server URL
+// https://bitbucket.org were created as Bitbucket Server sources, so the API address is used to tell Cloud apart
+const BitbucketCloudServerURL = "https://api.bitbucket.org"
+
+// AppendBitbucketCloudSourceToEtree appends the Bitbucket Cloud source,
+// it's a Bitbucket source with the server URL of Bitbucket Cloud
+func AppendBitbucketCloudSourceToEtree(source *etree.Element, gitSource *devopsv1alpha3.BitbucketCloudSource) {
+	if gitSource == nil {
+		klog.Warning("please provide BitbucketCloud source when the sourceType is BitbucketCloud")
+		return
+	}
+	AppendBitbucketServerSourceToEtree(source, &devopsv1alpha3.BitbucketServerSource{
+		ScmId:                     gitSource.ScmId,
+		Owner:                     gitSource.Owner,
+		Repo:                      gitSource.Repo,
+		CredentialId:              gitSource.CredentialId,
+		ApiUri:                    BitbucketCloudServerURL,
+		DiscoverBranches:          gitSource.DiscoverBranches,
+		DiscoverPRFromOrigin:      gitSource.DiscoverPRFromOrigin,
+		DiscoverPRFromForks:       gitSource.DiscoverPRFromForks,
+		DiscoverTags:              gitSource.DiscoverTags,
+		CloneOption:               gitSource.CloneOption,
+		RegexFilter:               gitSource.RegexFilter,
+		AcceptJenkinsNotification: gitSource.AcceptJenkinsNotification,
+	})
+}
+
+// IsBitbucketCloudSource returns true if the Bitbucket source points to Bitbucket Cloud
+func IsBitbucketCloudSource(source *etree.Element) bool {
+	if serverUrl := source.SelectElement("serverUrl"); serverUrl != nil {
+		return strings.TrimSuffix(serverUrl.Text(), "/") == BitbucketCloudServerURL
+	}
+	return false
+}
+
+// GetBitbucketCloudSourceFromEtree parses the Bitbucket Cloud source
+func GetBitbucketCloudSourceFromEtree(source *etree.Element) *devopsv1alpha3.BitbucketCloudSource {
+	s := GetBitbucketServerSourceFromEtree(source)
+	return &devopsv1alpha3.BitbucketCloudSource{
+		ScmId:                     s.ScmId,
+		Owner:                     s.Owner,
+		Repo:                      s.Repo,
+		CredentialId:              s.CredentialId,
+		DiscoverBranches:          s.DiscoverBranches,
+		DiscoverPRFromOrigin:      s.DiscoverPRFromOrigin,
+		DiscoverPRFromForks:       s.DiscoverPRFromForks,
+		DiscoverTags:              s.DiscoverTags,
+		CloneOption:               s.CloneOption,
+		RegexFilter:               s.RegexFilter,
+		AcceptJenkinsNotification: s.AcceptJenkinsNotification,
+	}
+}
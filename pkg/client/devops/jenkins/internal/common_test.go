@@ -29,6 +29,8 @@ func TestCommonSituation(t *testing.T) {
 	AppendGitlabSourceToEtree(nil, nil)
 	AppendGithubSourceToEtree(nil, nil)
 	AppendBitbucketServerSourceToEtree(nil, nil)
+	AppendBitbucketCloudSourceToEtree(nil, nil)
+	AppendGiteaSourceToEtree(nil, nil)
 	AppendGitSourceToEtree(nil, nil)
 	AppendSingleSvnSourceToEtree(nil, nil)
 	AppendSvnSourceToEtree(nil, nil)
@@ -57,6 +59,13 @@ func TestSkipJenkinsNotification(t *testing.T) {
 	AppendBitbucketServerSourceToEtree(source, &devopsv1alpha3.BitbucketServerSource{DiscoverPRFromForks: PRForks})
 	bitbucketServerSource := GetBitbucketServerSourceFromEtree(source)
 	assert.False(t, bitbucketServerSource.AcceptJenkinsNotification)
+
+	// bitbucketCloud
+	source = etree.NewDocument().CreateElement("source")
+	AppendBitbucketCloudSourceToEtree(source, &devopsv1alpha3.BitbucketCloudSource{DiscoverPRFromForks: PRForks})
+	assert.True(t, IsBitbucketCloudSource(source))
+	bitbucketCloudSource := GetBitbucketCloudSourceFromEtree(source)
+	assert.False(t, bitbucketCloudSource.AcceptJenkinsNotification)
 }
 
 func TestAcceptJenkinsNotification(t *testing.T) {
@@ -82,4 +91,30 @@ func TestAcceptJenkinsNotification(t *testing.T) {
 	AppendBitbucketServerSourceToEtree(source, &devopsv1alpha3.BitbucketServerSource{DiscoverPRFromForks: PRForks, AcceptJenkinsNotification: true})
 	bitbucketServerSource := GetBitbucketServerSourceFromEtree(source)
 	assert.True(t, bitbucketServerSource.AcceptJenkinsNotification)
+
+	// bitbucketCloud
+	source = etree.NewDocument().CreateElement("source")
+	AppendBitbucketCloudSourceToEtree(source, &devopsv1alpha3.BitbucketCloudSource{DiscoverPRFromForks: PRForks, AcceptJenkinsNotification: true})
+	bitbucketCloudSource := GetBitbucketCloudSourceFromEtree(source)
+	assert.True(t, bitbucketCloudSource.AcceptJenkinsNotification)
+}
+
+func TestBitbucketServerSourceWithoutForkTrait(t *testing.T) {
+	source := etree.NewDocument().CreateElement("source")
+	AppendBitbucketServerSourceToEtree(source, &devopsv1alpha3.BitbucketServerSource{
+		ApiUri:           "https://bitbucket.example.com",
+		DiscoverBranches: 1,
+		CloneOption: &devopsv1alpha3.GitCloneOption{
+			Timeout: 10,
+			Depth:   1,
+		},
+		RegexFilter:               "release-*",
+		AcceptJenkinsNotification: true,
+	})
+	bitbucketServerSource := GetBitbucketServerSourceFromEtree(source)
+	assert.Equal(t, 1, bitbucketServerSource.DiscoverBranches)
+	assert.Nil(t, bitbucketServerSource.DiscoverPRFromForks)
+	assert.Equal(t, &devopsv1alpha3.GitCloneOption{Timeout: 10, Depth: 1}, bitbucketServerSource.CloneOption)
+	assert.Equal(t, "release-*", bitbucketServerSource.RegexFilter)
+	assert.True(t, bitbucketServerSource.AcceptJenkinsNotification)
 }
@@ -0,0 +1,157 @@
+/*
+Copyright 2022 The KubeSphere Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package internal
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/beevik/etree"
+	"k8s.io/klog/v2"
+
+	devopsv1alpha3 "github.com/kubesphere/ks-devops/pkg/api/devops/v1alpha3"
+)
+
+// GiteaSCMSourceClass is the class of the Gitea branch source
+const GiteaSCMSourceClass = "org.jenkinsci.plugin.gitea.GiteaSCMSource"
+
+// AppendGiteaSourceToEtree appends the Gitea source which requires the Jenkins plugin gitea
+func AppendGiteaSourceToEtree(source *etree.Element, giteaSource *devopsv1alpha3.GiteaSource) {
+	if giteaSource == nil {
+		klog.Warning("please provide Gitea source when the sourceType is Gitea")
+		return
+	}
+	source.CreateAttr("class", GiteaSCMSourceClass)
+	source.CreateAttr("plugin", "gitea")
+	source.CreateElement("id").SetText(giteaSource.ScmId)
+	source.CreateElement("serverUrl").SetText(giteaSource.ServerUrl)
+	source.CreateElement("repoOwner").SetText(giteaSource.Owner)
+	source.CreateElement("repository").SetText(giteaSource.Repo)
+	source.CreateElement("credentialsId").SetText(giteaSource.CredentialId)
+
+	traits := source.CreateElement("traits")
+	if giteaSource.DiscoverBranches != 0 {
+		traits.CreateElement("org.jenkinsci.plugin.gitea.BranchDiscoveryTrait").
+			CreateElement("strategyId").SetText(strconv.Itoa(giteaSource.DiscoverBranches))
+	}
+	if giteaSource.DiscoverPRFromOrigin != 0 {
+		traits.CreateElement("org.jenkinsci.plugin.gitea.OriginPullRequestDiscoveryTrait").
+			CreateElement("strategyId").SetText(strconv.Itoa(giteaSource.DiscoverPRFromOrigin))
+	}
+	if giteaSource.DiscoverPRFromForks != nil {
+		forkTrait := traits.CreateElement("org.jenkinsci.plugin.gitea.ForkPullRequestDiscoveryTrait")
+		forkTrait.CreateElement("strategyId").SetText(strconv.Itoa(giteaSource.DiscoverPRFromForks.Strategy))
+		trustClass := "org.jenkinsci.plugin.gitea.ForkPullRequestDiscoveryTrait$"
+		// Gitea has the same trust options as GitHub except TrustPermission
+		if prTrust := GitHubPRDiscoverTrust(giteaSource.DiscoverPRFromForks.Trust); prTrust.IsValid() {
+			trustClass += prTrust.String()
+		} else {
+			klog.Warningf("invalid Gitea discover PR trust value: %d", prTrust.Value())
+		}
+		forkTrait.CreateElement("trust").CreateAttr("class", trustClass)
+	}
+	if giteaSource.DiscoverTags {
+		traits.CreateElement("org.jenkinsci.plugin.gitea.TagDiscoveryTrait")
+	}
+	if giteaSource.CloneOption != nil {
+		cloneExtension := traits.CreateElement("jenkins.plugins.git.traits.CloneOptionTrait").CreateElement("extension")
+		cloneExtension.CreateAttr("class", "hudson.plugins.git.extensions.impl.CloneOption")
+		cloneExtension.CreateElement("shallow").SetText(strconv.FormatBool(giteaSource.CloneOption.Shallow))
+		cloneExtension.CreateElement("noTags").SetText(strconv.FormatBool(false))
+		cloneExtension.CreateElement("honorRefspec").SetText(strconv.FormatBool(true))
+		cloneExtension.CreateElement("reference")
+		if giteaSource.CloneOption.Timeout >= 0 {
+			cloneExtension.CreateElement("timeout").SetText(strconv.Itoa(giteaSource.CloneOption.Timeout))
+		} else {
+			cloneExtension.CreateElement("timeout").SetText(strconv.Itoa(10))
+		}
+
+		if giteaSource.CloneOption.Depth >= 0 {
+			cloneExtension.CreateElement("depth").SetText(strconv.Itoa(giteaSource.CloneOption.Depth))
+		} else {
+			cloneExtension.CreateElement("depth").SetText(strconv.Itoa(1))
+		}
+	}
+	if giteaSource.RegexFilter != "" {
+		regexTraits := traits.CreateElement("jenkins.scm.impl.trait.RegexSCMHeadFilterTrait")
+		regexTraits.CreateAttr("plugin", "scm-api")
+		regexTraits.CreateElement("regex").SetText(giteaSource.RegexFilter)
+	}
+}
+
+// GetGiteaSourceFromEtree parses the Gitea source
+func GetGiteaSourceFromEtree(source *etree.Element) *devopsv1alpha3.GiteaSource {
+	var s devopsv1alpha3.GiteaSource
+	if serverUrl := source.SelectElement("serverUrl"); serverUrl != nil {
+		s.ServerUrl = serverUrl.Text()
+	}
+	if credential := source.SelectElement("credentialsId"); credential != nil {
+		s.CredentialId = credential.Text()
+	}
+	if repoOwner := source.SelectElement("repoOwner"); repoOwner != nil {
+		s.Owner = repoOwner.Text()
+	}
+	if repository := source.SelectElement("repository"); repository != nil {
+		s.Repo = repository.Text()
+	}
+
+	traits := source.SelectElement("traits")
+	if traits == nil {
+		return &s
+	}
+	if branchDiscoverTrait := traits.SelectElement(
+		"org.jenkinsci.plugin.gitea.BranchDiscoveryTrait"); branchDiscoverTrait != nil {
+		s.DiscoverBranches, _ = strconv.Atoi(getTextOrEmpty(branchDiscoverTrait, "strategyId"))
+	}
+	if tagDiscoverTrait := traits.SelectElement(
+		"org.jenkinsci.plugin.gitea.TagDiscoveryTrait"); tagDiscoverTrait != nil {
+		s.DiscoverTags = true
+	}
+	if originPRDiscoverTrait := traits.SelectElement(
+		"org.jenkinsci.plugin.gitea.OriginPullRequestDiscoveryTrait"); originPRDiscoverTrait != nil {
+		s.DiscoverPRFromOrigin, _ = strconv.Atoi(getTextOrEmpty(originPRDiscoverTrait, "strategyId"))
+	}
+	if forkPRDiscoverTrait := traits.SelectElement(
+		"org.jenkinsci.plugin.gitea.ForkPullRequestDiscoveryTrait"); forkPRDiscoverTrait != nil {
+		strategyId, _ := strconv.Atoi(getTextOrEmpty(forkPRDiscoverTrait, "strategyId"))
+		if trustEle := forkPRDiscoverTrait.SelectElement("trust"); trustEle != nil {
+			trust := strings.Split(trustEle.SelectAttrValue("class", ""), "$")
+			if prTrust := GitHubPRDiscoverTrust(1).ParseFromString(trust[len(trust)-1]); prTrust.IsValid() {
+				s.DiscoverPRFromForks = &devopsv1alpha3.DiscoverPRFromForks{
+					Strategy: strategyId,
+					Trust:    prTrust.Value(),
+				}
+			} else {
+				klog.Warningf("invalid Gitea discover PR trust value: %s", trust[len(trust)-1])
+			}
+		}
+	}
+
+	s.CloneOption = parseFromCloneTrait(traits.SelectElement("jenkins.plugins.git.traits.CloneOptionTrait"))
+	if regexTrait := traits.SelectElement(
+		"jenkins.scm.impl.trait.RegexSCMHeadFilterTrait"); regexTrait != nil {
+		s.RegexFilter = getTextOrEmpty(regexTrait, "regex")
+	}
+	return &s
+}
+
+func getTextOrEmpty(element *etree.Element, tag string) string {
+	if child := element.SelectElement(tag); child != nil {
+		return child.Text()
+	}
+	return ""
+}
@@ -0,0 +1,243 @@
+/*
+Copyright 2022 The KubeSphere Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package jenkins
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/beevik/etree"
+
+	devopsv1alpha3 "github.com/kubesphere/ks-devops/pkg/api/devops/v1alpha3"
+	"github.com/kubesphere/ks-devops/pkg/client/devops/jenkins/internal"
+)
+
+const (
+	// WorkflowJobClass is the class of a Pipeline job
+	WorkflowJobClass = "org.jenkinsci.plugins.workflow.job.WorkflowJob"
+	// MultiBranchProjectClass is the class of a multi-branch Pipeline job
+	MultiBranchProjectClass = "org.jenkinsci.plugins.workflow.multibranch.WorkflowMultiBranchProject"
+	// FolderClass is the class of a folder
+	FolderClass = "com.cloudbees.hudson.plugins.folder.Folder"
+
+	cpsFlowDefinitionClass          = "org.jenkinsci.plugins.workflow.cps.CpsFlowDefinition"
+	workflowBranchProjectFactoryCls = "org.jenkinsci.plugins.workflow.multibranch.WorkflowBranchProjectFactory"
+)
+
+// JobSummary is a job in a Jenkins folder
+type JobSummary struct {
+	Name  string `json:"name"`
+	Class string `json:"_class"`
+}
+
+// ListJobs returns the jobs in a folder, the folder could be nested, such as "parent/child"
+func (j *Jenkins) ListJobs(folder string) (jobs []JobSummary, err error) {
+	result := struct {
+		Jobs []JobSummary `json:"jobs"`
+	}{}
+
+	var rsp *http.Response
+	if rsp, err = j.Requester.GetJSON(getFolderBase(folder), &result, map[string]string{
+		"tree": "jobs[name]",
+	}); err == nil {
+		if rsp.StatusCode != http.StatusOK {
+			err = fmt.Errorf("failed to list the jobs of folder %s, status code: %d", folder, rsp.StatusCode)
+		} else {
+			jobs = result.Jobs
+		}
+	}
+	return
+}
+
+// GetJobConfig returns the config.xml of a job in a folder
+func (j *Jenkins) GetJobConfig(folder, name string) (config string, err error) {
+	job := &Job{Jenkins: j, Raw: new(JobResponse), Base: getFolderBase(folder) + "/job/" + name}
+	config, err = job.GetConfig()
+	return
+}
+
+func getFolderBase(folder string) string {
+	return "/job/" + strings.Join(strings.Split(strings.Trim(folder, "/"), "/"), "/job/")
+}
+
+// ParseJobConfig converts the config.xml of a job to a Pipeline, and returns the settings which cannot be converted.
+// Those settings will be lost once the Pipeline is synchronized to Jenkins.
+func ParseJobConfig(class, name, config string) (pipeline *devopsv1alpha3.Pipeline, unsupported []string, err error) {
+	doc := etree.NewDocument()
+	if err = doc.ReadFromString(replaceXmlVersion(config, "1.1", "1.0")); err != nil {
+		return
+	}
+
+	pipeline = &devopsv1alpha3.Pipeline{}
+	pipeline.Name = name
+	switch class {
+	case WorkflowJobClass:
+		flow := doc.SelectElement(FlowTag)
+		if flow == nil || flow.SelectElement(PropertiesTag) == nil {
+			err = fmt.Errorf("can not find pipeline definition")
+			return
+		}
+		unsupported = findUnsupportedPipelineSettings(flow)
+
+		var noScmPipeline *devopsv1alpha3.NoScmPipeline
+		if noScmPipeline, err = parsePipelineConfigXml(config); err == nil {
+			noScmPipeline.Name = name
+			pipeline.Spec = devopsv1alpha3.PipelineSpec{
+				Type:     devopsv1alpha3.NoScmPipelineType,
+				Pipeline: noScmPipeline,
+			}
+		}
+	case MultiBranchProjectClass:
+		project := doc.SelectElement(MultiBranchProjectClass)
+		if project == nil || project.SelectElement("factory") == nil {
+			err = fmt.Errorf("can not parse mutibranch pipeline config")
+			return
+		}
+		unsupported = findUnsupportedMultiBranchSettings(project)
+
+		var multiBranchPipeline *devopsv1alpha3.MultiBranchPipeline
+		if multiBranchPipeline, err = parseMultiBranchPipelineConfigXml(config); err == nil {
+			multiBranchPipeline.Name = name
+			pipeline.Spec = devopsv1alpha3.PipelineSpec{
+				Type:                devopsv1alpha3.MultiBranchPipelineType,
+				MultiBranchPipeline: multiBranchPipeline,
+			}
+		}
+	default:
+		err = fmt.Errorf("unsupported job type: %s", class)
+	}
+	return
+}
+
+func findUnsupportedPipelineSettings(flow *etree.Element) (unsupported []string) {
+	for _, child := range flow.ChildElements() {
+		switch child.Tag {
+		case "actions", "description", "keepDependencies", AuthTokenTag:
+		case DisabledTag:
+			if child.Text() == "true" {
+				unsupported = append(unsupported, "the job is disabled")
+			}
+		case DefinitionTag:
+			if class := child.SelectAttrValue(ClassKey, ""); class != cpsFlowDefinitionClass {
+				unsupported = append(unsupported, fmt.Sprintf("definition %s, only the inline Jenkinsfile is supported", class))
+			}
+		case TriggersTag:
+			unsupported = append(unsupported, getUnknownChildren(child, "trigger")...)
+		case PropertiesTag:
+			for _, property := range child.ChildElements() {
+				switch property.Tag {
+				case DisableConcurrentJobTag, BuildDiscarderTag:
+				case ParamDefiPropTag:
+					if definitions := property.SelectElement(ParamDefiTag); definitions != nil {
+						for _, param := range definitions.ChildElements() {
+							if _, ok := ParameterTypeMap[param.Tag]; !ok {
+								unsupported = append(unsupported, fmt.Sprintf("parameter %s of type %s",
+									getElementTextValueOrEmpty(param, "name"), param.Tag))
+							}
+						}
+					}
+				case PipelineTriggersJobTag:
+					if triggers := property.SelectElement(TriggersTag); triggers != nil {
+						unsupported = append(unsupported, getUnknownChildren(triggers, "trigger",
+							TimerTriggerTag, "org.jenkinsci.plugins.gwt.GenericTrigger")...)
+					}
+				default:
+					unsupported = append(unsupported, "property "+property.Tag)
+				}
+			}
+		default:
+			if !isEmptyElement(child) {
+				unsupported = append(unsupported, "setting "+child.Tag)
+			}
+		}
+	}
+	return
+}
+
+func findUnsupportedMultiBranchSettings(project *etree.Element) (unsupported []string) {
+	for _, child := range project.ChildElements() {
+		switch child.Tag {
+		case "actions", "description", "folderViews", "healthMetrics", "icon", "orphanedItemStrategy":
+		case DisabledTag:
+			if child.Text() == "true" {
+				unsupported = append(unsupported, "the job is disabled")
+			}
+		case PropertiesTag:
+			unsupported = append(unsupported, getUnknownChildren(child, "property",
+				"org.jenkinsci.plugins.workflow.multibranch.PipelineTriggerProperty",
+				"org.jenkinsci.plugins.pipeline.modeldefinition.config.FolderConfig")...)
+		case TriggersTag:
+			unsupported = append(unsupported, getUnknownChildren(child, "trigger",
+				"com.cloudbees.hudson.plugins.folder.computed.PeriodicFolderTrigger", DisabledTag)...)
+		case "sources":
+			var branchSources []*etree.Element
+			if data := child.SelectElement("data"); data != nil {
+				branchSources = data.SelectElements("jenkins.branch.BranchSource")
+			}
+			if len(branchSources) > 1 {
+				unsupported = append(unsupported, fmt.Sprintf("%d branch sources, only the first one is supported",
+					len(branchSources)))
+			}
+			if len(branchSources) > 0 {
+				if source := branchSources[0].SelectElement("source"); source != nil {
+					switch class := source.SelectAttrValue(ClassKey, ""); class {
+					case "org.jenkinsci.plugins.github_branch_source.GitHubSCMSource",
+						"com.cloudbees.jenkins.plugins.bitbucket.BitbucketSCMSource",
+						"io.jenkins.plugins.gitlabbranchsource.GitLabSCMSource",
+						"jenkins.plugins.git.GitSCMSource",
+						"jenkins.scm.impl.SingleSCMSource",
+						"jenkins.scm.impl.subversion.SubversionSCMSource",
+						internal.GiteaSCMSourceClass:
+					default:
+						unsupported = append(unsupported, "branch source "+class)
+					}
+				}
+			}
+		case "factory":
+			if class := child.SelectAttrValue(ClassKey, ""); class != workflowBranchProjectFactoryCls {
+				unsupported = append(unsupported, "project factory "+class)
+			}
+		default:
+			if !isEmptyElement(child) {
+				unsupported = append(unsupported, "setting "+child.Tag)
+			}
+		}
+	}
+	return
+}
+
+// getUnknownChildren returns the non-empty children which are not in the known tags
+func getUnknownChildren(parent *etree.Element, kind string, known ...string) (unknown []string) {
+	for _, child := range parent.ChildElements() {
+		isKnown := false
+		for _, tag := range known {
+			if child.Tag == tag {
+				isKnown = true
+				break
+			}
+		}
+		if !isKnown && !isEmptyElement(child) {
+			unknown = append(unknown, kind+" "+child.Tag)
+		}
+	}
+	return
+}
+
+func isEmptyElement(e *etree.Element) bool {
+	return len(e.ChildElements()) == 0 && strings.TrimSpace(e.Text()) == ""
+}
@@ -0,0 +1,206 @@
+/*
+Copyright 2022 The KubeSphere Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package jenkins
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	devopsv1alpha3 "github.com/kubesphere/ks-devops/pkg/api/devops/v1alpha3"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseJobConfig(t *testing.T) {
+	noScmConfig, err := createPipelineConfigXml(&devopsv1alpha3.NoScmPipeline{
+		Description:       "for test",
+		Jenkinsfile:       "node{echo 'hello'}",
+		DisableConcurrent: true,
+		TimerTrigger:      &devopsv1alpha3.TimerTrigger{Cron: "H * * * *"},
+		Parameters: []devopsv1alpha3.ParameterDefinition{{
+			Name: "version", Type: "string", DefaultValue: "v1",
+		}},
+	})
+	assert.Nil(t, err)
+	multiBranchConfig, err := createMultiBranchPipelineConfigXml("", &devopsv1alpha3.MultiBranchPipeline{
+		ScriptPath: "Jenkinsfile",
+		SourceType: devopsv1alpha3.SourceTypeGit,
+		GitSource:  &devopsv1alpha3.GitSource{Url: "https://github.com/kubesphere/ks-devops"},
+		TimerTrigger: &devopsv1alpha3.TimerTrigger{
+			Interval: "3600000",
+		},
+	})
+	assert.Nil(t, err)
+
+	tests := []struct {
+		name            string
+		class           string
+		config          string
+		wantType        devopsv1alpha3.PipelineType
+		wantUnsupported []string
+		wantErr         bool
+	}{{
+		name:     "a Pipeline with the supported settings",
+		class:    WorkflowJobClass,
+		config:   noScmConfig,
+		wantType: devopsv1alpha3.NoScmPipelineType,
+	}, {
+		name:     "a multi-branch Pipeline with the supported settings",
+		class:    MultiBranchProjectClass,
+		config:   multiBranchConfig,
+		wantType: devopsv1alpha3.MultiBranchPipelineType,
+	}, {
+		name:  "a Pipeline with the unsupported settings",
+		class: WorkflowJobClass,
+		config: `<?xml version='1.1' encoding='UTF-8'?>
+<flow-definition plugin="workflow-job">
+  <description>from SCM</description>
+  <keepDependencies>false</keepDependencies>
+  <properties>
+    <com.coravy.hudson.plugins.github.GithubProjectProperty plugin="github"/>
+    <hudson.model.ParametersDefinitionProperty>
+      <parameterDefinitions>
+        <hudson.model.StringParameterDefinition>
+          <name>version</name>
+        </hudson.model.StringParameterDefinition>
+        <hudson.model.RunParameterDefinition>
+          <name>upstream</name>
+        </hudson.model.RunParameterDefinition>
+      </parameterDefinitions>
+    </hudson.model.ParametersDefinitionProperty>
+    <org.jenkinsci.plugins.workflow.job.properties.PipelineTriggersJobProperty>
+      <triggers>
+        <hudson.triggers.SCMTrigger>
+          <spec>H/5 * * * *</spec>
+        </hudson.triggers.SCMTrigger>
+      </triggers>
+    </org.jenkinsci.plugins.workflow.job.properties.PipelineTriggersJobProperty>
+  </properties>
+  <definition class="org.jenkinsci.plugins.workflow.cps.CpsScmFlowDefinition" plugin="workflow-cps">
+    <scriptPath>Jenkinsfile</scriptPath>
+  </definition>
+  <triggers/>
+  <quietPeriod>10</quietPeriod>
+  <disabled>true</disabled>
+</flow-definition>`,
+		wantType: devopsv1alpha3.NoScmPipelineType,
+		wantUnsupported: []string{
+			"property com.coravy.hudson.plugins.github.GithubProjectProperty",
+			"parameter upstream of type hudson.model.RunParameterDefinition",
+			"trigger hudson.triggers.SCMTrigger",
+			"definition org.jenkinsci.plugins.workflow.cps.CpsScmFlowDefinition, only the inline Jenkinsfile is supported",
+			"setting quietPeriod",
+			"the job is disabled",
+		},
+	}, {
+		name:  "a multi-branch Pipeline with the unsupported settings",
+		class: MultiBranchProjectClass,
+		config: `<?xml version='1.1' encoding='UTF-8'?>
+<org.jenkinsci.plugins.workflow.multibranch.WorkflowMultiBranchProject plugin="workflow-multibranch">
+  <properties>
+    <org.jenkinsci.plugins.docker.workflow.declarative.FolderConfig plugin="docker-workflow">
+      <dockerLabel>docker</dockerLabel>
+    </org.jenkinsci.plugins.docker.workflow.declarative.FolderConfig>
+  </properties>
+  <sources class="jenkins.branch.MultiBranchProject$BranchSourceList" plugin="branch-api">
+    <data>
+      <jenkins.branch.BranchSource>
+        <source class="jenkins.plugins.git.GitSCMSource" plugin="git">
+          <remote>https://github.com/kubesphere/ks-devops</remote>
+          <traits/>
+        </source>
+      </jenkins.branch.BranchSource>
+      <jenkins.branch.BranchSource>
+        <source class="jenkins.plugins.git.GitSCMSource" plugin="git">
+          <remote>https://github.com/kubesphere/ks-devops</remote>
+          <traits/>
+        </source>
+      </jenkins.branch.BranchSource>
+    </data>
+  </sources>
+  <factory class="org.jenkinsci.plugins.pipeline.multibranch.defaults.PipelineBranchDefaultsProjectFactory">
+    <scriptId>Jenkinsfile</scriptId>
+  </factory>
+</org.jenkinsci.plugins.workflow.multibranch.WorkflowMultiBranchProject>`,
+		wantType: devopsv1alpha3.MultiBranchPipelineType,
+		wantUnsupported: []string{
+			"property org.jenkinsci.plugins.docker.workflow.declarative.FolderConfig",
+			"2 branch sources, only the first one is supported",
+			"project factory org.jenkinsci.plugins.pipeline.multibranch.defaults.PipelineBranchDefaultsProjectFactory",
+		},
+	}, {
+		name:    "a freestyle job",
+		class:   "hudson.model.FreeStyleProject",
+		config:  `<project/>`,
+		wantErr: true,
+	}, {
+		name:    "invalid XML",
+		class:   WorkflowJobClass,
+		config:  `<flow-definition>`,
+		wantErr: true,
+	}, {
+		name:    "without properties",
+		class:   WorkflowJobClass,
+		config:  `<flow-definition/>`,
+		wantErr: true,
+	}, {
+		name:    "without factory",
+		class:   MultiBranchProjectClass,
+		config:  `<org.jenkinsci.plugins.workflow.multibranch.WorkflowMultiBranchProject/>`,
+		wantErr: true,
+	}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pipeline, unsupported, err := ParseJobConfig(tt.class, "fake", tt.config)
+			assert.Equal(t, tt.wantErr, err != nil, err)
+			assert.Equal(t, tt.wantUnsupported, unsupported)
+			if !tt.wantErr {
+				assert.Equal(t, "fake", pipeline.Name)
+				assert.Equal(t, tt.wantType, pipeline.Spec.Type)
+			}
+		})
+	}
+}
+
+func TestJenkins_ListJobs(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// the requester always appends a slash to the GET requests
+		switch strings.TrimSuffix(r.URL.Path, "/") {
+		case "/job/parent/job/child/api/json":
+			assert.Equal(t, "jobs[name]", r.URL.Query().Get("tree"))
+			_, _ = w.Write([]byte(`{"jobs":[{"_class":"` + WorkflowJobClass + `","name":"pipeline"}]}`))
+		case "/job/parent/job/child/job/pipeline/config.xml":
+			_, _ = w.Write([]byte(`<flow-definition/>`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	jenkins := CreateJenkins(nil, server.URL, 0, "admin", "token")
+	jobs, err := jenkins.ListJobs("/parent/child/")
+	assert.Nil(t, err)
+	assert.Equal(t, []JobSummary{{Name: "pipeline", Class: WorkflowJobClass}}, jobs)
+
+	config, err := jenkins.GetJobConfig("parent/child", "pipeline")
+	assert.Nil(t, err)
+	assert.Equal(t, `<flow-definition/>`, config)
+
+	_, err = jenkins.ListJobs("missing")
+	assert.NotNil(t, err)
+}
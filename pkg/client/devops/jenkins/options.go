@@ -34,6 +34,48 @@ type Options struct {
 	WorkerNamespace string        `json:"workerNamespace,omitempty" yaml:"workerNamespace"`
 	ReloadCasCDelay time.Duration `json:"reloadCasCDelay,omitempty" yaml:"reloadCasCDelay"`
 	SkipVerify      bool
+	// HealthFailureThreshold is the count of consecutive failures which marks Jenkins as unavailable
+	HealthFailureThreshold int `json:"healthFailureThreshold,omitempty" yaml:"healthFailureThreshold"`
+	// HealthProbeInterval is the interval of probing Jenkins, requests are released again after it when Jenkins is unavailable
+	HealthProbeInterval time.Duration `json:"healthProbeInterval,omitempty" yaml:"healthProbeInterval"`
+	// Instances are the additional Jenkins instances, the DevOps projects are placed among them and the default one
+	Instances []InstanceOptions `json:"instances,omitempty" yaml:"instances"`
+}
+
+// DefaultInstanceName is the name of the Jenkins instance which is configured by Host
+const DefaultInstanceName = "default"
+
+// InstanceOptions represents an additional Jenkins instance
+type InstanceOptions struct {
+	Name     string `json:"name" yaml:"name" description:"unique name of the Jenkins instance"`
+	Host     string `json:"host" yaml:"host" description:"Jenkins service host address"`
+	Username string `json:"username,omitempty" yaml:"username" description:"Jenkins admin username"`
+	Password string `json:"password,omitempty" yaml:"password" description:"Jenkins admin password"`
+	ApiToken string `json:"apiToken,omitempty" yaml:"apiToken" description:"Jenkins admin apiToken"`
+}
+
+// GetInstance returns the options of a Jenkins instance, the default instance is returned if the name is empty
+func (s *Options) GetInstance(name string) (options *Options, ok bool) {
+	if name == "" || name == DefaultInstanceName {
+		return s, true
+	}
+	for _, instance := range s.Instances {
+		if instance.Name == name {
+			options = &Options{
+				Host:           instance.Host,
+				Username:       instance.Username,
+				Password:       instance.Password,
+				ApiToken:       instance.ApiToken,
+				MaxConnections: s.MaxConnections,
+				SkipVerify:     s.SkipVerify,
+
+				HealthFailureThreshold: s.HealthFailureThreshold,
+				HealthProbeInterval:    s.HealthProbeInterval,
+			}
+			return options, true
+		}
+	}
+	return
 }
 
 // NewJenkinsOptions returns a `zero` instance
@@ -49,7 +91,9 @@ func NewJenkinsOptions() *Options {
 		// Default syncFrequency of Kubernetes is "1m", and increasing it will result in longer refresh times for
 		// ConfigMap, so we use 70s as the default value of ReloadCasCDelay. Please see also:
 		// https://kubernetes.io/docs/reference/config-api/kubelet-config.v1beta1/#kubelet-config-k8s-io-v1beta1-KubeletConfiguration
-		ReloadCasCDelay: 70 * time.Second,
+		ReloadCasCDelay:        70 * time.Second,
+		HealthFailureThreshold: DefaultHealthFailureThreshold,
+		HealthProbeInterval:    DefaultHealthProbeInterval,
 	}
 }
 
@@ -73,6 +117,16 @@ func (s *Options) Validate() []error {
 		errors = append(errors, fmt.Errorf("jenkins's username or api-token is empty"))
 	}
 
+	names := map[string]bool{DefaultInstanceName: true}
+	for _, instance := range s.Instances {
+		if instance.Name == "" || instance.Host == "" {
+			errors = append(errors, fmt.Errorf("the name or host of Jenkins instance is empty"))
+		} else if names[instance.Name] {
+			errors = append(errors, fmt.Errorf("the name of Jenkins instance %s is duplicated", instance.Name))
+		}
+		names[instance.Name] = true
+	}
+
 	return errors
 }
 
@@ -100,4 +154,8 @@ func (s *Options) AddFlags(fs *pflag.FlagSet, c *Options) {
 	fs.DurationVar(&s.ReloadCasCDelay, "reload-casc-delay", c.ReloadCasCDelay,
 		"ReloadCasCDelay specifies the total duration that controller should delay the reload action for "+
 			"jenkins-casc-config ConfigMap change, and it is only valid for controller manager.")
+	fs.IntVar(&s.HealthFailureThreshold, "jenkins-health-failure-threshold", c.HealthFailureThreshold,
+		"Count of consecutive failed requests or probes before Jenkins is considered unavailable.")
+	fs.DurationVar(&s.HealthProbeInterval, "jenkins-health-probe-interval", c.HealthProbeInterval,
+		"Interval of probing Jenkins. Requests to an unavailable Jenkins fail fast until a probe succeeds.")
 }
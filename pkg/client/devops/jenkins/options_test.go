@@ -0,0 +1,94 @@
+/*
+Copyright 2022 The KubeSphere Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package jenkins
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOptions_GetInstance(t *testing.T) {
+	options := &Options{
+		Host:           "http://jenkins-a",
+		MaxConnections: 10,
+
+		HealthFailureThreshold: 5,
+		HealthProbeInterval:    time.Minute,
+		Instances: []InstanceOptions{{
+			Name:     "b",
+			Host:     "http://jenkins-b",
+			Username: "admin",
+			ApiToken: "token",
+		}},
+	}
+
+	instance, ok := options.GetInstance("")
+	assert.True(t, ok)
+	assert.Equal(t, options, instance)
+	instance, ok = options.GetInstance(DefaultInstanceName)
+	assert.True(t, ok)
+	assert.Equal(t, options, instance)
+
+	instance, ok = options.GetInstance("b")
+	assert.True(t, ok)
+	assert.Equal(t, &Options{
+		Host:           "http://jenkins-b",
+		Username:       "admin",
+		ApiToken:       "token",
+		MaxConnections: 10,
+
+		HealthFailureThreshold: 5,
+		HealthProbeInterval:    time.Minute,
+	}, instance)
+
+	_, ok = options.GetInstance("c")
+	assert.False(t, ok)
+}
+
+func TestOptions_Validate(t *testing.T) {
+	tests := []struct {
+		name      string
+		options   *Options
+		wantCount int
+	}{{
+		name:    "Jenkins is not needed",
+		options: &Options{},
+	}, {
+		name:    "valid",
+		options: &Options{Host: "http://jenkins", Username: "admin", ApiToken: "token"},
+	}, {
+		name:      "empty token",
+		options:   &Options{Host: "http://jenkins", Username: "admin"},
+		wantCount: 1,
+	}, {
+		name: "invalid instances",
+		options: &Options{Host: "http://jenkins", Username: "admin", ApiToken: "token", Instances: []InstanceOptions{
+			{Name: "b", Host: "http://jenkins-b"},
+			{Name: "b", Host: "http://jenkins-c"},
+			{Name: DefaultInstanceName, Host: "http://jenkins-d"},
+			{Name: "e"},
+		}},
+		wantCount: 3,
+	}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Len(t, tt.options.Validate(), tt.wantCount)
+		})
+	}
+}
@@ -487,6 +487,10 @@ func createMultiBranchPipelineConfigXml(projectName string, pipeline *devopsv1al
 		internal.AppendSingleSvnSourceToEtree(source, pipeline.SingleSvnSource)
 	case devopsv1alpha3.SourceTypeBitbucket:
 		internal.AppendBitbucketServerSourceToEtree(source, pipeline.BitbucketServerSource)
+	case devopsv1alpha3.SourceTypeBitbucketCloud:
+		internal.AppendBitbucketCloudSourceToEtree(source, pipeline.BitbucketCloudSource)
+	case devopsv1alpha3.SourceTypeGitea:
+		internal.AppendGiteaSourceToEtree(source, pipeline.GiteaSource)
 
 	default:
 		return "", fmt.Errorf("unsupport source type: %s", pipeline.SourceType)
@@ -552,8 +556,16 @@ func parseMultiBranchPipelineConfigXml(config string) (*devopsv1alpha3.MultiBran
 					pipeline.GitHubSource = internal.GetGithubSourcefromEtree(source)
 					pipeline.SourceType = devopsv1alpha3.SourceTypeGithub
 				case "com.cloudbees.jenkins.plugins.bitbucket.BitbucketSCMSource":
-					pipeline.BitbucketServerSource = internal.GetBitbucketServerSourceFromEtree(source)
-					pipeline.SourceType = devopsv1alpha3.SourceTypeBitbucket
+					if internal.IsBitbucketCloudSource(source) {
+						pipeline.BitbucketCloudSource = internal.GetBitbucketCloudSourceFromEtree(source)
+						pipeline.SourceType = devopsv1alpha3.SourceTypeBitbucketCloud
+					} else {
+						pipeline.BitbucketServerSource = internal.GetBitbucketServerSourceFromEtree(source)
+						pipeline.SourceType = devopsv1alpha3.SourceTypeBitbucket
+					}
+				case internal.GiteaSCMSourceClass:
+					pipeline.GiteaSource = internal.GetGiteaSourceFromEtree(source)
+					pipeline.SourceType = devopsv1alpha3.SourceTypeGitea
 				case "io.jenkins.plugins.gitlabbranchsource.GitLabSCMSource":
 					pipeline.GitlabSource = internal.GetGitlabSourceFromEtree(source)
 					pipeline.SourceType = devopsv1alpha3.SourceTypeGitlab
@@ -21,6 +21,7 @@ import (
 	"github.com/kubesphere/ks-devops/pkg/client/devops/jenkins/internal"
 	"github.com/stretchr/testify/assert"
 	"reflect"
+	"strings"
 	"testing"
 
 	devopsv1alpha3 "github.com/kubesphere/ks-devops/pkg/api/devops/v1alpha3"
@@ -464,7 +465,50 @@ func Test_MultiBranchPipelineConfig_Source(t *testing.T) {
 				},
 			},
 		},
-
+		{
+			Name:        "",
+			Description: "for test",
+			ScriptPath:  "Jenkinsfile",
+			SourceType:  "bitbucket_cloud",
+			BitbucketCloudSource: &devopsv1alpha3.BitbucketCloudSource{
+				Owner:                "kubesphere",
+				Repo:                 "devops",
+				CredentialId:         "bitbucket",
+				DiscoverBranches:     1,
+				DiscoverPRFromOrigin: 2,
+				DiscoverPRFromForks: &devopsv1alpha3.DiscoverPRFromForks{
+					Strategy: 1,
+					Trust:    1,
+				},
+				DiscoverTags:              true,
+				RegexFilter:               "release-*",
+				AcceptJenkinsNotification: true,
+			},
+		},
+		{
+			Name:        "",
+			Description: "for test",
+			ScriptPath:  "Jenkinsfile",
+			SourceType:  "gitea",
+			GiteaSource: &devopsv1alpha3.GiteaSource{
+				ServerUrl:            "https://gitea.example.com",
+				Owner:                "kubesphere",
+				Repo:                 "devops",
+				CredentialId:         "gitea",
+				DiscoverBranches:     1,
+				DiscoverPRFromOrigin: 2,
+				DiscoverPRFromForks: &devopsv1alpha3.DiscoverPRFromForks{
+					Strategy: 1,
+					Trust:    1,
+				},
+				DiscoverTags: true,
+				CloneOption: &devopsv1alpha3.GitCloneOption{
+					Timeout: 10,
+					Depth:   1,
+				},
+				RegexFilter: "release-*",
+			},
+		},
 		{
 			Name:        "",
 			Description: "for test",
@@ -771,6 +815,13 @@ func TestForkPullRequestDiscoveryTrait(t *testing.T) {
 	assert.Nil(t, err)
 	assert.Equal(t, "bitbucket_server", pipeline.SourceType)
 	assert.Equal(t, internal.BitbucketPRDiscoverTrustTeamForks.Value(), pipeline.BitbucketServerSource.DiscoverPRFromForks.Trust)
+
+	// the server URL of Bitbucket Cloud
+	pipeline, err = parseMultiBranchPipelineConfigXml(strings.ReplaceAll(withTrustForBitbucketJobXML,
+		"https://bitbucket.org", internal.BitbucketCloudServerURL))
+	assert.Nil(t, err)
+	assert.Equal(t, "bitbucket_cloud", pipeline.SourceType)
+	assert.Equal(t, internal.BitbucketPRDiscoverTrustTeamForks.Value(), pipeline.BitbucketCloudSource.DiscoverPRFromForks.Trust)
 }
 
 var noTrustForGitlabJobXML = `<?xml version='1.1' encoding='UTF-8'?>
@@ -23,6 +23,8 @@ import (
 	"net/http"
 	"net/url"
 	"strings"
+
+	"github.com/kubesphere/ks-devops/pkg/api/devops/v1alpha3"
 )
 
 type PipelineList struct {
@@ -549,6 +551,8 @@ type PipelineBranchItem struct {
 type Parameter struct {
 	Name  string      `json:"name,omitempty" description:"name"`
 	Value interface{} `json:"value,omitempty" description:"value"`
+	// ValueFrom references a DevOps credential, it's required by the password parameters
+	ValueFrom *v1alpha3.ParameterValueSource `json:"valueFrom,omitempty" description:"source of the value"`
 }
 
 type RunPayload struct {
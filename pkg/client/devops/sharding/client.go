@@ -0,0 +1,464 @@
+/*
+Copyright 2022 The KubeSphere Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sharding
+
+import (
+	"io"
+	"net/http"
+
+	"github.com/jenkins-zh/jenkins-client/pkg/core"
+	"github.com/kubesphere/ks-devops/pkg/api/devops/v1alpha3"
+	"github.com/kubesphere/ks-devops/pkg/client/devops"
+	"github.com/kubesphere/ks-devops/pkg/client/devops/jenkins"
+	v1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// CoreGetter returns the Jenkins core of the DevOps project which owns the namespace
+type CoreGetter interface {
+	GetJenkinsCore(namespace string) (core.JenkinsCore, error)
+}
+
+// Client routes the project-scoped requests to the Jenkins instance of the DevOps project.
+// The other requests, such as the SCM and webhook ones, are sent to the default Jenkins instance.
+type Client struct {
+	devops.Interface
+	registry *Registry
+	locator  Locator
+}
+
+var _ devops.Interface = &Client{}
+var _ CoreGetter = &Client{}
+
+// NewClient creates a client which routes the requests among the Jenkins instances
+func NewClient(registry *Registry, locator Locator) (c *Client, err error) {
+	var defaultInstance *Instance
+	if defaultInstance, err = registry.Get(""); err == nil {
+		c = &Client{
+			Interface: defaultInstance.Client,
+			registry:  registry,
+			locator:   locator,
+		}
+	}
+	return
+}
+
+// NewClientFromOptions creates a client with the Jenkins instances from the options,
+// the Jenkins instance of a DevOps project is read from its status
+func NewClientFromOptions(options *jenkins.Options, reader client.Reader) (c *Client, err error) {
+	var registry *Registry
+	if registry, err = NewRegistryFromOptions(options); err == nil {
+		c, err = NewClient(registry, NewProjectLocator(reader, registry))
+	}
+	return
+}
+
+// Registry returns the registry of the Jenkins instances
+func (c *Client) Registry() *Registry {
+	return c.registry
+}
+
+func (c *Client) getInstance(namespace string) (instance *Instance, err error) {
+	var name string
+	if name, err = c.locator.Locate(namespace); err == nil {
+		instance, err = c.registry.Get(name)
+	}
+	return
+}
+
+// GetJenkinsCore returns the Jenkins core of the DevOps project which owns the namespace
+func (c *Client) GetJenkinsCore(namespace string) (jenkinsCore core.JenkinsCore, err error) {
+	var instance *Instance
+	if instance, err = c.getInstance(namespace); err == nil {
+		jenkinsCore = instance.Core
+	}
+	return
+}
+
+// ReloadConfiguration reloads the configuration of all the Jenkins instances
+func (c *Client) ReloadConfiguration() (err error) {
+	for _, name := range c.registry.Names() {
+		instance, _ := c.registry.Get(name)
+		if err = instance.Client.ReloadConfiguration(); err != nil {
+			return
+		}
+	}
+	return
+}
+
+// ApplyNewSource applies the new configuration source to all the Jenkins instances
+func (c *Client) ApplyNewSource(source string) (err error) {
+	for _, name := range c.registry.Names() {
+		instance, _ := c.registry.Get(name)
+		if err = instance.Client.ApplyNewSource(source); err != nil {
+			return
+		}
+	}
+	return
+}
+
+func (c *Client) CheckPipelineName(projectName, pipelineName string, httpParameters *devops.HttpParameters) (map[string]interface{}, error) {
+	instance, err := c.getInstance(projectName)
+	if err != nil {
+		return nil, err
+	}
+	return instance.Client.CheckPipelineName(projectName, pipelineName, httpParameters)
+}
+
+func (c *Client) GetPipeline(projectName, pipelineName string, httpParameters *devops.HttpParameters) (*devops.Pipeline, error) {
+	instance, err := c.getInstance(projectName)
+	if err != nil {
+		return nil, err
+	}
+	return instance.Client.GetPipeline(projectName, pipelineName, httpParameters)
+}
+
+func (c *Client) GetPipelineRun(projectName, pipelineName, runId string, httpParameters *devops.HttpParameters) (*devops.PipelineRun, error) {
+	instance, err := c.getInstance(projectName)
+	if err != nil {
+		return nil, err
+	}
+	return instance.Client.GetPipelineRun(projectName, pipelineName, runId, httpParameters)
+}
+
+func (c *Client) ListPipelineRuns(projectName, pipelineName string, httpParameters *devops.HttpParameters) (*devops.PipelineRunList, error) {
+	instance, err := c.getInstance(projectName)
+	if err != nil {
+		return nil, err
+	}
+	return instance.Client.ListPipelineRuns(projectName, pipelineName, httpParameters)
+}
+
+func (c *Client) StopPipeline(projectName, pipelineName, runId string, httpParameters *devops.HttpParameters) (*devops.StopPipeline, error) {
+	instance, err := c.getInstance(projectName)
+	if err != nil {
+		return nil, err
+	}
+	return instance.Client.StopPipeline(projectName, pipelineName, runId, httpParameters)
+}
+
+func (c *Client) ReplayPipeline(projectName, pipelineName, runId string, httpParameters *devops.HttpParameters) (*devops.ReplayPipeline, error) {
+	instance, err := c.getInstance(projectName)
+	if err != nil {
+		return nil, err
+	}
+	return instance.Client.ReplayPipeline(projectName, pipelineName, runId, httpParameters)
+}
+
+func (c *Client) RunPipeline(projectName, pipelineName string, httpParameters *devops.HttpParameters) (*devops.RunPipeline, error) {
+	instance, err := c.getInstance(projectName)
+	if err != nil {
+		return nil, err
+	}
+	return instance.Client.RunPipeline(projectName, pipelineName, httpParameters)
+}
+
+func (c *Client) GetArtifacts(projectName, pipelineName, runId string, httpParameters *devops.HttpParameters) ([]devops.Artifacts, error) {
+	instance, err := c.getInstance(projectName)
+	if err != nil {
+		return nil, err
+	}
+	return instance.Client.GetArtifacts(projectName, pipelineName, runId, httpParameters)
+}
+
+func (c *Client) DownloadArtifact(projectName, pipelineName, runId, filename string, isMultiBranch bool, branchName string) (io.ReadCloser, error) {
+	instance, err := c.getInstance(projectName)
+	if err != nil {
+		return nil, err
+	}
+	return instance.Client.DownloadArtifact(projectName, pipelineName, runId, filename, isMultiBranch, branchName)
+}
+
+func (c *Client) GetRunLog(projectName, pipelineName, runId string, httpParameters *devops.HttpParameters) ([]byte, error) {
+	instance, err := c.getInstance(projectName)
+	if err != nil {
+		return nil, err
+	}
+	return instance.Client.GetRunLog(projectName, pipelineName, runId, httpParameters)
+}
+
+func (c *Client) GetStepLog(projectName, pipelineName, runId, nodeId, stepId string, httpParameters *devops.HttpParameters) ([]byte, http.Header, error) {
+	instance, err := c.getInstance(projectName)
+	if err != nil {
+		return nil, nil, err
+	}
+	return instance.Client.GetStepLog(projectName, pipelineName, runId, nodeId, stepId, httpParameters)
+}
+
+func (c *Client) GetNodeSteps(projectName, pipelineName, runId, nodeId string, httpParameters *devops.HttpParameters) ([]devops.NodeSteps, error) {
+	instance, err := c.getInstance(projectName)
+	if err != nil {
+		return nil, err
+	}
+	return instance.Client.GetNodeSteps(projectName, pipelineName, runId, nodeId, httpParameters)
+}
+
+func (c *Client) GetPipelineRunNodes(projectName, pipelineName, runId string, httpParameters *devops.HttpParameters) ([]devops.PipelineRunNodes, error) {
+	instance, err := c.getInstance(projectName)
+	if err != nil {
+		return nil, err
+	}
+	return instance.Client.GetPipelineRunNodes(projectName, pipelineName, runId, httpParameters)
+}
+
+func (c *Client) SubmitInputStep(projectName, pipelineName, runId, nodeId, stepId string, httpParameters *devops.HttpParameters) ([]byte, error) {
+	instance, err := c.getInstance(projectName)
+	if err != nil {
+		return nil, err
+	}
+	return instance.Client.SubmitInputStep(projectName, pipelineName, runId, nodeId, stepId, httpParameters)
+}
+
+func (c *Client) GetBranchPipeline(projectName, pipelineName, branchName string, httpParameters *devops.HttpParameters) (*devops.BranchPipeline, error) {
+	instance, err := c.getInstance(projectName)
+	if err != nil {
+		return nil, err
+	}
+	return instance.Client.GetBranchPipeline(projectName, pipelineName, branchName, httpParameters)
+}
+
+func (c *Client) GetBranchPipelineRun(projectName, pipelineName, branchName, runId string, httpParameters *devops.HttpParameters) (*devops.PipelineRun, error) {
+	instance, err := c.getInstance(projectName)
+	if err != nil {
+		return nil, err
+	}
+	return instance.Client.GetBranchPipelineRun(projectName, pipelineName, branchName, runId, httpParameters)
+}
+
+func (c *Client) StopBranchPipeline(projectName, pipelineName, branchName, runId string, httpParameters *devops.HttpParameters) (*devops.StopPipeline, error) {
+	instance, err := c.getInstance(projectName)
+	if err != nil {
+		return nil, err
+	}
+	return instance.Client.StopBranchPipeline(projectName, pipelineName, branchName, runId, httpParameters)
+}
+
+func (c *Client) ReplayBranchPipeline(projectName, pipelineName, branchName, runId string, httpParameters *devops.HttpParameters) (*devops.ReplayPipeline, error) {
+	instance, err := c.getInstance(projectName)
+	if err != nil {
+		return nil, err
+	}
+	return instance.Client.ReplayBranchPipeline(projectName, pipelineName, branchName, runId, httpParameters)
+}
+
+func (c *Client) RunBranchPipeline(projectName, pipelineName, branchName string, httpParameters *devops.HttpParameters) (*devops.RunPipeline, error) {
+	instance, err := c.getInstance(projectName)
+	if err != nil {
+		return nil, err
+	}
+	return instance.Client.RunBranchPipeline(projectName, pipelineName, branchName, httpParameters)
+}
+
+func (c *Client) GetBranchArtifacts(projectName, pipelineName, branchName, runId string, httpParameters *devops.HttpParameters) ([]devops.Artifacts, error) {
+	instance, err := c.getInstance(projectName)
+	if err != nil {
+		return nil, err
+	}
+	return instance.Client.GetBranchArtifacts(projectName, pipelineName, branchName, runId, httpParameters)
+}
+
+func (c *Client) GetBranchRunLog(projectName, pipelineName, branchName, runId string, httpParameters *devops.HttpParameters) ([]byte, error) {
+	instance, err := c.getInstance(projectName)
+	if err != nil {
+		return nil, err
+	}
+	return instance.Client.GetBranchRunLog(projectName, pipelineName, branchName, runId, httpParameters)
+}
+
+func (c *Client) GetBranchStepLog(projectName, pipelineName, branchName, runId, nodeId, stepId string, httpParameters *devops.HttpParameters) ([]byte, http.Header, error) {
+	instance, err := c.getInstance(projectName)
+	if err != nil {
+		return nil, nil, err
+	}
+	return instance.Client.GetBranchStepLog(projectName, pipelineName, branchName, runId, nodeId, stepId, httpParameters)
+}
+
+func (c *Client) GetBranchNodeSteps(projectName, pipelineName, branchName, runId, nodeId string, httpParameters *devops.HttpParameters) ([]devops.NodeSteps, error) {
+	instance, err := c.getInstance(projectName)
+	if err != nil {
+		return nil, err
+	}
+	return instance.Client.GetBranchNodeSteps(projectName, pipelineName, branchName, runId, nodeId, httpParameters)
+}
+
+func (c *Client) GetBranchPipelineRunNodes(projectName, pipelineName, branchName, runId string, httpParameters *devops.HttpParameters) ([]devops.BranchPipelineRunNodes, error) {
+	instance, err := c.getInstance(projectName)
+	if err != nil {
+		return nil, err
+	}
+	return instance.Client.GetBranchPipelineRunNodes(projectName, pipelineName, branchName, runId, httpParameters)
+}
+
+func (c *Client) SubmitBranchInputStep(projectName, pipelineName, branchName, runId, nodeId, stepId string, httpParameters *devops.HttpParameters) ([]byte, error) {
+	instance, err := c.getInstance(projectName)
+	if err != nil {
+		return nil, err
+	}
+	return instance.Client.SubmitBranchInputStep(projectName, pipelineName, branchName, runId, nodeId, stepId, httpParameters)
+}
+
+func (c *Client) GetPipelineBranch(projectName, pipelineName string, httpParameters *devops.HttpParameters) (*devops.PipelineBranch, error) {
+	instance, err := c.getInstance(projectName)
+	if err != nil {
+		return nil, err
+	}
+	return instance.Client.GetPipelineBranch(projectName, pipelineName, httpParameters)
+}
+
+func (c *Client) ScanBranch(projectName, pipelineName string, httpParameters *devops.HttpParameters) ([]byte, error) {
+	instance, err := c.getInstance(projectName)
+	if err != nil {
+		return nil, err
+	}
+	return instance.Client.ScanBranch(projectName, pipelineName, httpParameters)
+}
+
+func (c *Client) GetConsoleLog(projectName, pipelineName string, httpParameters *devops.HttpParameters) ([]byte, error) {
+	instance, err := c.getInstance(projectName)
+	if err != nil {
+		return nil, err
+	}
+	return instance.Client.GetConsoleLog(projectName, pipelineName, httpParameters)
+}
+
+func (c *Client) CheckScriptCompile(projectName, pipelineName string, httpParameters *devops.HttpParameters) (*devops.CheckScript, error) {
+	instance, err := c.getInstance(projectName)
+	if err != nil {
+		return nil, err
+	}
+	return instance.Client.CheckScriptCompile(projectName, pipelineName, httpParameters)
+}
+
+func (c *Client) CheckCron(projectName string, httpParameters *devops.HttpParameters) (*devops.CheckCronRes, error) {
+	instance, err := c.getInstance(projectName)
+	if err != nil {
+		return nil, err
+	}
+	return instance.Client.CheckCron(projectName, httpParameters)
+}
+
+func (c *Client) CreateCredentialInProject(projectId string, credential *v1.Secret) (string, error) {
+	instance, err := c.getInstance(projectId)
+	if err != nil {
+		return "", err
+	}
+	return instance.Client.CreateCredentialInProject(projectId, credential)
+}
+
+func (c *Client) UpdateCredentialInProject(projectId string, credential *v1.Secret) (string, error) {
+	instance, err := c.getInstance(projectId)
+	if err != nil {
+		return "", err
+	}
+	return instance.Client.UpdateCredentialInProject(projectId, credential)
+}
+
+func (c *Client) GetCredentialInProject(projectId, id string) (*devops.Credential, error) {
+	instance, err := c.getInstance(projectId)
+	if err != nil {
+		return nil, err
+	}
+	return instance.Client.GetCredentialInProject(projectId, id)
+}
+
+func (c *Client) DeleteCredentialInProject(projectId, id string) (string, error) {
+	instance, err := c.getInstance(projectId)
+	if err != nil {
+		return "", err
+	}
+	return instance.Client.DeleteCredentialInProject(projectId, id)
+}
+
+func (c *Client) GetProjectPipelineBuildByType(projectId, pipelineId string, status string) (*devops.Build, error) {
+	instance, err := c.getInstance(projectId)
+	if err != nil {
+		return nil, err
+	}
+	return instance.Client.GetProjectPipelineBuildByType(projectId, pipelineId, status)
+}
+
+func (c *Client) GetMultiBranchPipelineBuildByType(projectId, pipelineId, branch string, status string) (*devops.Build, error) {
+	instance, err := c.getInstance(projectId)
+	if err != nil {
+		return nil, err
+	}
+	return instance.Client.GetMultiBranchPipelineBuildByType(projectId, pipelineId, branch, status)
+}
+
+func (c *Client) CreateDevOpsProject(projectId string) (string, error) {
+	instance, err := c.getInstance(projectId)
+	if err != nil {
+		return "", err
+	}
+	return instance.Client.CreateDevOpsProject(projectId)
+}
+
+func (c *Client) DeleteDevOpsProject(projectId string) error {
+	instance, err := c.getInstance(projectId)
+	if err != nil {
+		return err
+	}
+	return instance.Client.DeleteDevOpsProject(projectId)
+}
+
+func (c *Client) GetDevOpsProject(projectId string) (string, error) {
+	instance, err := c.getInstance(projectId)
+	if err != nil {
+		return "", err
+	}
+	return instance.Client.GetDevOpsProject(projectId)
+}
+
+func (c *Client) CreateProjectPipeline(projectId string, pipeline *v1alpha3.Pipeline) (string, error) {
+	instance, err := c.getInstance(projectId)
+	if err != nil {
+		return "", err
+	}
+	return instance.Client.CreateProjectPipeline(projectId, pipeline)
+}
+
+func (c *Client) DeleteProjectPipeline(projectId string, pipelineId string) (string, error) {
+	instance, err := c.getInstance(projectId)
+	if err != nil {
+		return "", err
+	}
+	return instance.Client.DeleteProjectPipeline(projectId, pipelineId)
+}
+
+func (c *Client) UpdateProjectPipeline(projectId string, pipeline *v1alpha3.Pipeline) (string, error) {
+	instance, err := c.getInstance(projectId)
+	if err != nil {
+		return "", err
+	}
+	return instance.Client.UpdateProjectPipeline(projectId, pipeline)
+}
+
+func (c *Client) GetProjectPipelineConfig(projectId, pipelineId string) (*v1alpha3.Pipeline, error) {
+	instance, err := c.getInstance(projectId)
+	if err != nil {
+		return nil, err
+	}
+	return instance.Client.GetProjectPipelineConfig(projectId, pipelineId)
+}
+
+// GetJenkinsCore returns the Jenkins core of the namespace from the getter, or the default one if the getter is nil
+func GetJenkinsCore(getter CoreGetter, namespace string, defaultCore core.JenkinsCore) (core.JenkinsCore, error) {
+	if getter == nil {
+		return defaultCore, nil
+	}
+	return getter.GetJenkinsCore(namespace)
+}
@@ -0,0 +1,96 @@
+/*
+Copyright 2022 The KubeSphere Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sharding
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/jenkins-zh/jenkins-client/pkg/core"
+	"github.com/kubesphere/ks-devops/pkg/api/devops/v1alpha3"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+type fakeLocator struct {
+	instances map[string]string
+}
+
+func (f *fakeLocator) Locate(namespace string) (instance string, err error) {
+	var ok bool
+	if instance, ok = f.instances[namespace]; !ok {
+		err = ErrNotPlaced
+	}
+	return
+}
+
+func TestClient(t *testing.T) {
+	registry := newFakeRegistry("default", "b")
+	c, err := NewClient(registry, &fakeLocator{instances: map[string]string{
+		"team-a": "default",
+		"team-b": "b",
+	}})
+	assert.Nil(t, err)
+	assert.Equal(t, registry, c.Registry())
+
+	_, err = c.CreateDevOpsProject("team-b")
+	assert.Nil(t, err)
+	_, err = c.CreateProjectPipeline("team-b", &v1alpha3.Pipeline{ObjectMeta: metav1.ObjectMeta{Name: "build"}})
+	assert.Nil(t, err)
+
+	defaultInstance, _ := registry.Get("default")
+	instanceB, _ := registry.Get("b")
+	_, err = defaultInstance.Client.GetDevOpsProject("team-b")
+	assert.NotNil(t, err, "the project should not be created in the default instance")
+	_, err = instanceB.Client.GetDevOpsProject("team-b")
+	assert.Nil(t, err)
+	pipeline, err := c.GetProjectPipelineConfig("team-b", "build")
+	assert.Nil(t, err)
+	assert.Equal(t, "build", pipeline.Name)
+
+	// the project which is not placed yet
+	_, err = c.CreateDevOpsProject("team-c")
+	assert.True(t, errors.Is(err, ErrNotPlaced))
+	err = c.DeleteDevOpsProject("team-c")
+	assert.True(t, errors.Is(err, ErrNotPlaced))
+
+	// the non project-scoped requests go to the default instance
+	assert.Equal(t, defaultInstance.Client, c.Interface)
+	assert.Nil(t, c.ReloadConfiguration())
+	assert.Nil(t, c.ApplyNewSource("source"))
+
+	_, err = NewClient(NewRegistry(), &fakeLocator{})
+	assert.NotNil(t, err)
+}
+
+func TestGetJenkinsCore(t *testing.T) {
+	c, err := NewClient(newFakeRegistry("default", "b"), &fakeLocator{instances: map[string]string{"team-b": "b"}})
+	assert.Nil(t, err)
+
+	jenkinsCore, err := c.GetJenkinsCore("team-b")
+	assert.Nil(t, err)
+	assert.Equal(t, "http://b", jenkinsCore.URL)
+	_, err = c.GetJenkinsCore("team-c")
+	assert.Equal(t, ErrNotPlaced, err)
+
+	jenkinsCore, err = GetJenkinsCore(c, "team-b", core.JenkinsCore{URL: "http://fallback"})
+	assert.Nil(t, err)
+	assert.Equal(t, "http://b", jenkinsCore.URL)
+	jenkinsCore, err = GetJenkinsCore(nil, "team-b", core.JenkinsCore{URL: "http://fallback"})
+	assert.Nil(t, err)
+	assert.Equal(t, "http://fallback", jenkinsCore.URL)
+}
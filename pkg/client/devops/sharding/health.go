@@ -0,0 +1,133 @@
+/*
+Copyright 2022 The KubeSphere Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sharding
+
+import (
+	"context"
+	"time"
+
+	"github.com/kubesphere/ks-devops/pkg/client/devops/jclient"
+	"github.com/kubesphere/ks-devops/pkg/client/devops/jenkins"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// staleHealthReportIntervals is how many probe intervals the reported health is trusted for,
+// the controller manager might be down if it's not renewed in time
+const staleHealthReportIntervals = 3
+
+// HealthGetter returns the health of the Jenkins instance which hosts the namespace
+type HealthGetter interface {
+	GetHealth(namespace string) (jenkins.HealthChecker, error)
+}
+
+var _ HealthGetter = &Client{}
+var _ Locator = &Client{}
+
+// EnableHealthMonitors makes the requests to each Jenkins instance go through the circuit breaker of its own monitor.
+// The given monitor is used by the default instance if it's not nil, so it could be shared with the other clients.
+func (r *Registry) EnableHealthMonitors(options *jenkins.Options, defaultMonitor *jenkins.HealthMonitor) {
+	for _, name := range r.names {
+		instance := r.instances[name]
+		if instance.Health != nil {
+			continue
+		}
+
+		monitor := defaultMonitor
+		if name != r.Default() || monitor == nil {
+			instanceOptions, ok := options.GetInstance(name)
+			if !ok {
+				continue
+			}
+			monitor = jenkins.NewHealthMonitor(instanceOptions)
+		}
+		if jenkinsClient, ok := instance.Client.(*jclient.JenkinsClient); ok {
+			instance.Core = jenkinsClient.WithHealthMonitor(monitor).Core
+		} else {
+			instance.Core.RoundTripper = monitor.RoundTripper(instance.Core.RoundTripper)
+		}
+		instance.Health = monitor
+	}
+}
+
+// Locate returns the name of the Jenkins instance which hosts the namespace
+func (c *Client) Locate(namespace string) (string, error) {
+	return c.locator.Locate(namespace)
+}
+
+// GetHealth returns the health of the Jenkins instance which hosts the namespace,
+// it's nil if the health monitors are not enabled
+func (c *Client) GetHealth(namespace string) (health jenkins.HealthChecker, err error) {
+	var instance *Instance
+	if instance, err = c.getInstance(namespace); err == nil && instance.Health != nil {
+		health = instance.Health
+	}
+	return
+}
+
+// GetHealth returns the health of the namespace from the getter, or the default one if the getter is nil
+func GetHealth(getter HealthGetter, namespace string, defaultHealth jenkins.HealthChecker) (jenkins.HealthChecker, error) {
+	if getter == nil {
+		return defaultHealth, nil
+	}
+	return getter.GetHealth(namespace)
+}
+
+// GetReportedHealth reads the health of the Jenkins instance which hosts the namespace from the ConfigMap written by
+// the controller manager. It's available if the health has not been reported yet, or the report is stale.
+// The default instance is used if the locator is nil.
+func GetReportedHealth(ctx context.Context, reader client.Reader, locator Locator, jenkinsNamespace, namespace string) (
+	available bool, message string, err error) {
+	var instance string
+	if locator != nil {
+		if instance, err = locator.Locate(namespace); err != nil {
+			return
+		}
+	}
+
+	cm := &corev1.ConfigMap{}
+	if err = reader.Get(ctx, types.NamespacedName{Namespace: jenkinsNamespace, Name: jenkins.GetHealthConfigMapName(instance)}, cm); err != nil {
+		if apierrors.IsNotFound(err) {
+			available, err = true, nil
+		}
+		return
+	}
+	if isStaleHealthReport(cm.Data, time.Now()) {
+		klog.V(4).Infof("the health of Jenkins instance %q is stale, last probe time: %q", instance, cm.Data["lastProbeTime"])
+		available = true
+		return
+	}
+	available = cm.Data["available"] != "false"
+	message = cm.Data["message"]
+	return
+}
+
+// isStaleHealthReport returns true if the last probe time is missing, or older than a few probe intervals
+func isStaleHealthReport(data map[string]string, now time.Time) bool {
+	lastProbeTime, err := time.Parse(time.RFC3339, data["lastProbeTime"])
+	if err != nil {
+		return true
+	}
+	interval, err := time.ParseDuration(data["probeInterval"])
+	if err != nil || interval <= 0 {
+		interval = jenkins.DefaultHealthProbeInterval
+	}
+	return now.Sub(lastProbeTime) > staleHealthReportIntervals*interval
+}
@@ -0,0 +1,225 @@
+/*
+Copyright 2022 The KubeSphere Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sharding
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/kubesphere/ks-devops/pkg/client/devops/jenkins"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestRegistry_EnableHealthMonitors(t *testing.T) {
+	options := &jenkins.Options{
+		Host:     "http://jenkins-a",
+		Username: "admin",
+		ApiToken: "token-a",
+		Instances: []jenkins.InstanceOptions{{
+			Name:     "b",
+			Host:     "http://jenkins-b",
+			Username: "admin",
+			ApiToken: "token-b",
+		}},
+		HealthFailureThreshold: 5,
+	}
+	registry, err := NewRegistryFromOptions(options)
+	assert.Nil(t, err)
+
+	defaultMonitor := jenkins.NewHealthMonitor(options)
+	registry.EnableHealthMonitors(options, defaultMonitor)
+
+	defaultInstance, _ := registry.Get("")
+	assert.Equal(t, defaultMonitor, defaultInstance.Health)
+	assert.NotNil(t, defaultInstance.Core.RoundTripper)
+
+	instanceB, _ := registry.Get("b")
+	assert.NotNil(t, instanceB.Health)
+	assert.NotEqual(t, defaultMonitor, instanceB.Health)
+	assert.Equal(t, "http://jenkins-b", instanceB.Health.URL)
+	assert.Equal(t, 5, instanceB.Health.FailureThreshold)
+	assert.NotNil(t, instanceB.Core.RoundTripper)
+
+	// the monitors are kept if they are enabled again
+	registry.EnableHealthMonitors(options, nil)
+	instanceB2, _ := registry.Get("b")
+	assert.Equal(t, instanceB.Health, instanceB2.Health)
+
+	// the instances without options are skipped
+	fakeRegistry := newFakeRegistry("default", "c")
+	fakeRegistry.EnableHealthMonitors(options, nil)
+	defaultInstance, _ = fakeRegistry.Get("")
+	assert.NotNil(t, defaultInstance.Health)
+	assert.Equal(t, "http://jenkins-a", defaultInstance.Health.URL)
+	instanceC, _ := fakeRegistry.Get("c")
+	assert.Nil(t, instanceC.Health)
+}
+
+func TestGetHealth(t *testing.T) {
+	options := &jenkins.Options{Host: "http://jenkins-a", Instances: []jenkins.InstanceOptions{{Name: "b", Host: "http://jenkins-b"}}}
+	registry := newFakeRegistry(jenkins.DefaultInstanceName, "b")
+	c, err := NewClient(registry, &fakeLocator{instances: map[string]string{"team-b": "b"}})
+	assert.Nil(t, err)
+
+	// the health monitors are not enabled
+	health, err := c.GetHealth("team-b")
+	assert.Nil(t, err)
+	assert.Nil(t, health)
+
+	registry.EnableHealthMonitors(options, nil)
+	instanceB, _ := registry.Get("b")
+	health, err = c.GetHealth("team-b")
+	assert.Nil(t, err)
+	assert.Equal(t, instanceB.Health, health)
+	_, err = c.GetHealth("team-c")
+	assert.Equal(t, ErrNotPlaced, err)
+
+	instance, err := c.Locate("team-b")
+	assert.Nil(t, err)
+	assert.Equal(t, "b", instance)
+
+	defaultHealth := jenkins.NewHealthMonitor(options)
+	health, err = GetHealth(c, "team-b", defaultHealth)
+	assert.Nil(t, err)
+	assert.Equal(t, instanceB.Health, health)
+	health, err = GetHealth(nil, "team-b", defaultHealth)
+	assert.Nil(t, err)
+	assert.Equal(t, defaultHealth, health)
+}
+
+func TestGetReportedHealth(t *testing.T) {
+	newConfigMap := func(name, available, message string) *corev1.ConfigMap {
+		return &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "kubesphere-devops-system", Name: name},
+			Data: map[string]string{"available": available, "message": message,
+				"lastProbeTime": time.Now().UTC().Format(time.RFC3339), "probeInterval": "10s"},
+		}
+	}
+	staleConfigMap := newConfigMap("jenkins-health", "false", "connection refused")
+	staleConfigMap.Data["lastProbeTime"] = time.Now().Add(-time.Minute).UTC().Format(time.RFC3339)
+	withoutHeartbeat := newConfigMap("jenkins-health", "false", "connection refused")
+	delete(withoutHeartbeat.Data, "lastProbeTime")
+	locator := &fakeLocator{instances: map[string]string{"team-a": jenkins.DefaultInstanceName, "team-b": "b"}}
+
+	tests := []struct {
+		name          string
+		locator       Locator
+		namespace     string
+		objects       []runtime.Object
+		wantAvailable bool
+		wantMessage   string
+		wantErr       bool
+	}{{
+		name:          "not reported yet",
+		locator:       locator,
+		namespace:     "team-b",
+		wantAvailable: true,
+	}, {
+		name:          "the default instance is unavailable",
+		locator:       locator,
+		namespace:     "team-a",
+		objects:       []runtime.Object{newConfigMap("jenkins-health", "false", "connection refused")},
+		wantAvailable: false,
+		wantMessage:   "connection refused",
+	}, {
+		name:      "only the default instance is unavailable",
+		locator:   locator,
+		namespace: "team-b",
+		objects: []runtime.Object{newConfigMap("jenkins-health", "false", "connection refused"),
+			newConfigMap("jenkins-health-b", "true", "")},
+		wantAvailable: true,
+	}, {
+		name:          "the instance of the project is unavailable",
+		locator:       locator,
+		namespace:     "team-b",
+		objects:       []runtime.Object{newConfigMap("jenkins-health-b", "false", "timeout")},
+		wantAvailable: false,
+		wantMessage:   "timeout",
+	}, {
+		name:          "without locator",
+		namespace:     "team-b",
+		objects:       []runtime.Object{newConfigMap("jenkins-health", "false", "timeout")},
+		wantAvailable: false,
+		wantMessage:   "timeout",
+	}, {
+		name:          "the report is stale",
+		locator:       locator,
+		namespace:     "team-a",
+		objects:       []runtime.Object{staleConfigMap},
+		wantAvailable: true,
+	}, {
+		name:          "the report without the last probe time",
+		locator:       locator,
+		namespace:     "team-a",
+		objects:       []runtime.Object{withoutHeartbeat},
+		wantAvailable: true,
+	}, {
+		name:      "not placed",
+		locator:   locator,
+		namespace: "team-c",
+		wantErr:   true,
+	}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reader := fake.NewClientBuilder().WithRuntimeObjects(tt.objects...).Build()
+			available, message, err := GetReportedHealth(context.Background(), reader, tt.locator,
+				"kubesphere-devops-system", tt.namespace)
+			assert.Equal(t, tt.wantErr, err != nil)
+			assert.Equal(t, tt.wantAvailable, available)
+			assert.Equal(t, tt.wantMessage, message)
+		})
+	}
+}
+
+func Test_isStaleHealthReport(t *testing.T) {
+	now := time.Date(2022, 1, 1, 0, 1, 0, 0, time.UTC)
+	tests := []struct {
+		name   string
+		data   map[string]string
+		expect bool
+	}{{
+		name:   "renewed in time",
+		data:   map[string]string{"lastProbeTime": "2022-01-01T00:00:40Z", "probeInterval": "10s"},
+		expect: false,
+	}, {
+		name:   "not renewed for a few probe intervals",
+		data:   map[string]string{"lastProbeTime": "2022-01-01T00:00:20Z", "probeInterval": "10s"},
+		expect: true,
+	}, {
+		name:   "a longer probe interval",
+		data:   map[string]string{"lastProbeTime": "2022-01-01T00:00:20Z", "probeInterval": "1m"},
+		expect: false,
+	}, {
+		name:   "the default probe interval",
+		data:   map[string]string{"lastProbeTime": "2022-01-01T00:00:40Z", "probeInterval": "invalid"},
+		expect: false,
+	}, {
+		name:   "without the last probe time",
+		data:   map[string]string{"probeInterval": "10s"},
+		expect: true,
+	}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expect, isStaleHealthReport(tt.data, now))
+		})
+	}
+}
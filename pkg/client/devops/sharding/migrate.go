@@ -0,0 +1,146 @@
+/*
+Copyright 2022 The KubeSphere Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sharding
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/kubesphere/ks-devops/pkg/api/devops/v1alpha3"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// MigrateOptions is the options of moving a DevOps project to another Jenkins instance
+type MigrateOptions struct {
+	Project string
+	Target  string
+	// DryRun only reports what is going to be migrated
+	DryRun bool
+	// DeleteSource deletes the Jenkins folder of the DevOps project from the source instance
+	DeleteSource bool
+}
+
+// MigrateResult is the result of moving a DevOps project to another Jenkins instance
+type MigrateResult struct {
+	Source      string   `json:"source"`
+	Target      string   `json:"target"`
+	Credentials []string `json:"credentials,omitempty"`
+	Pipelines   []string `json:"pipelines,omitempty"`
+}
+
+// Migrator moves the DevOps projects among the Jenkins instances.
+// The folder, credentials and Pipelines are created in the target instance from the Kubernetes resources,
+// the build history stays in the source instance.
+type Migrator struct {
+	client.Client
+	Registry *Registry
+}
+
+// Migrate moves a DevOps project to the target Jenkins instance, then records the target instance in its status
+func (m *Migrator) Migrate(ctx context.Context, opts MigrateOptions) (result *MigrateResult, err error) {
+	project := &v1alpha3.DevOpsProject{}
+	if err = m.Get(ctx, types.NamespacedName{Name: opts.Project}, project); err != nil {
+		err = fmt.Errorf("failed to get DevOps project %s, error: %v", opts.Project, err)
+		return
+	}
+	namespace := project.Status.AdminNamespace
+	if namespace == "" {
+		err = fmt.Errorf("the admin namespace of DevOps project %s is not ready", opts.Project)
+		return
+	}
+
+	var source, target *Instance
+	if source, err = m.Registry.Get(project.Status.JenkinsInstance); err != nil {
+		return
+	}
+	if target, err = m.Registry.Get(opts.Target); err != nil {
+		return
+	}
+	result = &MigrateResult{Source: source.Name, Target: target.Name}
+	if source.Name == target.Name {
+		return
+	}
+
+	secrets := &corev1.SecretList{}
+	if err = m.List(ctx, secrets, client.InNamespace(namespace)); err != nil {
+		return
+	}
+	pipelines := &v1alpha3.PipelineList{}
+	if err = m.List(ctx, pipelines, client.InNamespace(namespace)); err != nil {
+		return
+	}
+	for i := range secrets.Items {
+		if strings.HasPrefix(string(secrets.Items[i].Type), v1alpha3.DevOpsCredentialPrefix) {
+			result.Credentials = append(result.Credentials, secrets.Items[i].Name)
+		}
+	}
+	for i := range pipelines.Items {
+		result.Pipelines = append(result.Pipelines, pipelines.Items[i].Name)
+	}
+	if opts.DryRun {
+		return
+	}
+
+	if _, err = target.Client.GetDevOpsProject(namespace); err != nil {
+		if _, err = target.Client.CreateDevOpsProject(namespace); err != nil {
+			err = fmt.Errorf("failed to create the Jenkins folder %s, error: %v", namespace, err)
+			return
+		}
+	}
+	// the credentials are created before the Pipelines which might refer to them
+	for i := range secrets.Items {
+		secret := &secrets.Items[i]
+		if !strings.HasPrefix(string(secret.Type), v1alpha3.DevOpsCredentialPrefix) {
+			continue
+		}
+		if _, err = target.Client.GetCredentialInProject(namespace, secret.Name); err == nil {
+			_, err = target.Client.UpdateCredentialInProject(namespace, secret)
+		} else {
+			_, err = target.Client.CreateCredentialInProject(namespace, secret)
+		}
+		if err != nil {
+			err = fmt.Errorf("failed to migrate credential %s, error: %v", secret.Name, err)
+			return
+		}
+	}
+	for i := range pipelines.Items {
+		pipeline := &pipelines.Items[i]
+		if _, err = target.Client.GetProjectPipelineConfig(namespace, pipeline.Name); err == nil {
+			_, err = target.Client.UpdateProjectPipeline(namespace, pipeline)
+		} else {
+			_, err = target.Client.CreateProjectPipeline(namespace, pipeline)
+		}
+		if err != nil {
+			err = fmt.Errorf("failed to migrate Pipeline %s, error: %v", pipeline.Name, err)
+			return
+		}
+	}
+
+	project.Status.JenkinsInstance = target.Name
+	if err = m.Update(ctx, project); err != nil {
+		return
+	}
+	if opts.DeleteSource {
+		if err = source.Client.DeleteDevOpsProject(namespace); err != nil {
+			err = fmt.Errorf("failed to delete the Jenkins folder %s from instance %s, error: %v", namespace, source.Name, err)
+		}
+	}
+	return
+}
@@ -0,0 +1,128 @@
+/*
+Copyright 2022 The KubeSphere Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sharding
+
+import (
+	"context"
+	"testing"
+
+	"github.com/kubesphere/ks-devops/pkg/api/devops/v1alpha3"
+	fakedevops "github.com/kubesphere/ks-devops/pkg/client/devops/fake"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestMigrator_Migrate(t *testing.T) {
+	schema, err := v1alpha3.SchemeBuilder.Register().Build()
+	assert.Nil(t, err)
+	assert.Nil(t, corev1.AddToScheme(schema))
+
+	objects := []runtime.Object{
+		newProject("team", "default", nil),
+		&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "team-ns", Name: "git"},
+			Type:       v1alpha3.SecretTypeBasicAuth,
+		},
+		&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "team-ns", Name: "token"},
+			Type:       corev1.SecretTypeServiceAccountToken,
+		},
+		&v1alpha3.Pipeline{ObjectMeta: metav1.ObjectMeta{Namespace: "team-ns", Name: "build"}},
+	}
+	getInstance := func(c client.Client) string {
+		project := &v1alpha3.DevOpsProject{}
+		_ = c.Get(context.Background(), types.NamespacedName{Name: "team"}, project)
+		return project.Status.JenkinsInstance
+	}
+
+	tests := []struct {
+		name       string
+		opts       MigrateOptions
+		wantResult *MigrateResult
+		wantErr    bool
+		verify     func(t *testing.T, c client.Client, source, target *fakedevops.Devops)
+	}{{
+		name:    "project not found",
+		opts:    MigrateOptions{Project: "fake", Target: "b"},
+		wantErr: true,
+	}, {
+		name:    "target not registered",
+		opts:    MigrateOptions{Project: "team", Target: "c"},
+		wantErr: true,
+	}, {
+		name:       "same instance",
+		opts:       MigrateOptions{Project: "team", Target: "default"},
+		wantResult: &MigrateResult{Source: "default", Target: "default"},
+	}, {
+		name: "dry run",
+		opts: MigrateOptions{Project: "team", Target: "b", DryRun: true},
+		wantResult: &MigrateResult{Source: "default", Target: "b",
+			Credentials: []string{"git"}, Pipelines: []string{"build"}},
+		verify: func(t *testing.T, c client.Client, source, target *fakedevops.Devops) {
+			assert.Equal(t, "default", getInstance(c))
+			assert.Empty(t, target.Projects)
+		},
+	}, {
+		name: "migrate",
+		opts: MigrateOptions{Project: "team", Target: "b"},
+		wantResult: &MigrateResult{Source: "default", Target: "b",
+			Credentials: []string{"git"}, Pipelines: []string{"build"}},
+		verify: func(t *testing.T, c client.Client, source, target *fakedevops.Devops) {
+			assert.Equal(t, "b", getInstance(c))
+			assert.NotNil(t, target.Credentials["team-ns"]["git"])
+			assert.Nil(t, target.Credentials["team-ns"]["token"])
+			assert.NotNil(t, target.Pipelines["team-ns"]["build"])
+			assert.NotNil(t, source.Projects["team-ns"])
+		},
+	}, {
+		name: "migrate and delete the source",
+		opts: MigrateOptions{Project: "team", Target: "b", DeleteSource: true},
+		wantResult: &MigrateResult{Source: "default", Target: "b",
+			Credentials: []string{"git"}, Pipelines: []string{"build"}},
+		verify: func(t *testing.T, c client.Client, source, target *fakedevops.Devops) {
+			assert.Equal(t, "b", getInstance(c))
+			assert.NotNil(t, target.Pipelines["team-ns"]["build"])
+			assert.Nil(t, source.Projects["team-ns"])
+		},
+	}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			registry := newFakeRegistry("default", "b")
+			defaultInstance, _ := registry.Get("default")
+			source := defaultInstance.Client.(*fakedevops.Devops)
+			_, _ = source.CreateDevOpsProject("team-ns")
+			instanceB, _ := registry.Get("b")
+			target := instanceB.Client.(*fakedevops.Devops)
+
+			c := fake.NewClientBuilder().WithScheme(schema).WithRuntimeObjects(objects...).Build()
+			migrator := &Migrator{Client: c, Registry: registry}
+			result, err := migrator.Migrate(context.Background(), tt.opts)
+			assert.Equal(t, tt.wantErr, err != nil, err)
+			if !tt.wantErr {
+				assert.Equal(t, tt.wantResult, result)
+			}
+			if tt.verify != nil {
+				tt.verify(t, c, source, target)
+			}
+		})
+	}
+}
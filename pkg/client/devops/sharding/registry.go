@@ -0,0 +1,196 @@
+/*
+Copyright 2022 The KubeSphere Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sharding
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/jenkins-zh/jenkins-client/pkg/core"
+	"github.com/kubesphere/ks-devops/pkg/api/devops/v1alpha3"
+	"github.com/kubesphere/ks-devops/pkg/client/devops"
+	"github.com/kubesphere/ks-devops/pkg/client/devops/jclient"
+	"github.com/kubesphere/ks-devops/pkg/client/devops/jenkins"
+	"github.com/kubesphere/ks-devops/pkg/constants"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// ErrNotPlaced indicates the DevOps project has not been placed on a Jenkins instance yet
+var ErrNotPlaced = errors.New("the DevOps project has not been placed on a Jenkins instance yet")
+
+// Instance is a Jenkins instance which hosts a part of the DevOps projects
+type Instance struct {
+	Name   string
+	Core   core.JenkinsCore
+	Client devops.Interface
+	// Health is the health monitor of the instance, it's nil unless the health monitors are enabled
+	Health *jenkins.HealthMonitor
+}
+
+// Registry holds all the Jenkins instances
+type Registry struct {
+	instances map[string]*Instance
+	names     []string
+}
+
+// NewRegistry creates a registry, the first instance is the default one
+func NewRegistry(instances ...*Instance) *Registry {
+	registry := &Registry{instances: map[string]*Instance{}}
+	for _, instance := range instances {
+		registry.instances[instance.Name] = instance
+		registry.names = append(registry.names, instance.Name)
+	}
+	return registry
+}
+
+// NewRegistryFromOptions creates a registry with the default Jenkins and the additional instances
+func NewRegistryFromOptions(options *jenkins.Options) (registry *Registry, err error) {
+	names := []string{jenkins.DefaultInstanceName}
+	for _, instance := range options.Instances {
+		names = append(names, instance.Name)
+	}
+
+	instances := make([]*Instance, 0, len(names))
+	for _, name := range names {
+		instanceOptions, _ := options.GetInstance(name)
+		var jenkinsClient *jclient.JenkinsClient
+		if jenkinsClient, err = jclient.NewJenkinsClient(instanceOptions); err != nil {
+			err = fmt.Errorf("failed to create the client of Jenkins instance %s, error: %v", name, err)
+			return
+		}
+		instances = append(instances, &Instance{
+			Name:   name,
+			Core:   jenkinsClient.Core,
+			Client: jenkinsClient,
+		})
+	}
+	registry = NewRegistry(instances...)
+	return
+}
+
+// Get returns the Jenkins instance by name, the default instance is returned if the name is empty
+func (r *Registry) Get(name string) (instance *Instance, err error) {
+	if name == "" {
+		name = r.Default()
+	}
+	var ok bool
+	if instance, ok = r.instances[name]; !ok {
+		err = fmt.Errorf("the Jenkins instance %s is not registered", name)
+	}
+	return
+}
+
+// Default returns the name of the default Jenkins instance
+func (r *Registry) Default() string {
+	if len(r.names) == 0 {
+		return ""
+	}
+	return r.names[0]
+}
+
+// Names returns the names of all the Jenkins instances, the default one is the first
+func (r *Registry) Names() []string {
+	return append([]string{}, r.names...)
+}
+
+// IsSharded returns true if there are more than one Jenkins instance
+func (r *Registry) IsSharded() bool {
+	return len(r.names) > 1
+}
+
+// Locator finds the Jenkins instance of a DevOps project
+type Locator interface {
+	// Locate returns the name of the Jenkins instance which hosts the admin namespace of a DevOps project
+	Locate(namespace string) (instance string, err error)
+}
+
+type projectLocator struct {
+	reader   client.Reader
+	registry *Registry
+}
+
+// NewProjectLocator creates a locator which reads the Jenkins instance from the status of DevOps projects
+func NewProjectLocator(reader client.Reader, registry *Registry) Locator {
+	return &projectLocator{reader: reader, registry: registry}
+}
+
+// Locate returns the Jenkins instance of the DevOps project which owns the namespace
+func (l *projectLocator) Locate(namespace string) (instance string, err error) {
+	if !l.registry.IsSharded() {
+		instance = l.registry.Default()
+		return
+	}
+
+	ctx := context.Background()
+	ns := &corev1.Namespace{}
+	if err = l.reader.Get(ctx, types.NamespacedName{Name: namespace}, ns); err != nil {
+		return
+	}
+	projectName := namespace
+	if name, ok := ns.Labels[constants.DevOpsProjectLabelKey]; ok && name != "" {
+		projectName = name
+	}
+
+	project := &v1alpha3.DevOpsProject{}
+	if err = l.reader.Get(ctx, types.NamespacedName{Name: projectName}, project); err == nil {
+		if instance = project.Status.JenkinsInstance; instance == "" {
+			err = ErrNotPlaced
+		}
+	}
+	return
+}
+
+// Policy decides which Jenkins instance a new DevOps project is placed on
+type Policy interface {
+	Place(project *v1alpha3.DevOpsProject, projects []v1alpha3.DevOpsProject) (instance string, err error)
+}
+
+// LeastProjectsPolicy places a DevOps project on the Jenkins instance which has the least projects.
+// The desired instance in annotation JenkinsInstanceAnnoKey takes precedence.
+type LeastProjectsPolicy struct {
+	Registry *Registry
+}
+
+// Place returns the Jenkins instance of the DevOps project
+func (p *LeastProjectsPolicy) Place(project *v1alpha3.DevOpsProject, projects []v1alpha3.DevOpsProject) (instance string, err error) {
+	if desired, ok := project.Annotations[v1alpha3.DevOpsProjectJenkinsInstanceAnnoKey]; ok && desired != "" {
+		if _, err = p.Registry.Get(desired); err == nil {
+			instance = desired
+		}
+		return
+	}
+
+	counts := map[string]int{}
+	for i := range projects {
+		if name := projects[i].Status.JenkinsInstance; name != "" && projects[i].Name != project.Name {
+			counts[name]++
+		}
+	}
+	names := p.Registry.Names()
+	// keep the default instance first if the counts are equal
+	sort.SliceStable(names, func(i, j int) bool {
+		return counts[names[i]] < counts[names[j]]
+	})
+	if len(names) > 0 {
+		instance = names[0]
+	}
+	return
+}
@@ -0,0 +1,190 @@
+/*
+Copyright 2022 The KubeSphere Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sharding
+
+import (
+	"testing"
+
+	"github.com/jenkins-zh/jenkins-client/pkg/core"
+	"github.com/kubesphere/ks-devops/pkg/api/devops/v1alpha3"
+	fakedevops "github.com/kubesphere/ks-devops/pkg/client/devops/fake"
+	"github.com/kubesphere/ks-devops/pkg/client/devops/jenkins"
+	"github.com/kubesphere/ks-devops/pkg/constants"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func newFakeRegistry(names ...string) *Registry {
+	instances := make([]*Instance, len(names))
+	for i, name := range names {
+		instances[i] = &Instance{
+			Name:   name,
+			Core:   core.JenkinsCore{URL: "http://" + name},
+			Client: fakedevops.New(),
+		}
+	}
+	return NewRegistry(instances...)
+}
+
+func newProject(name, instance string, annotations map[string]string) *v1alpha3.DevOpsProject {
+	return &v1alpha3.DevOpsProject{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Annotations: annotations},
+		Status:     v1alpha3.DevOpsProjectStatus{AdminNamespace: name + "-ns", JenkinsInstance: instance},
+	}
+}
+
+func newNamespace(name, project string) *corev1.Namespace {
+	return &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{
+		Name:   name,
+		Labels: map[string]string{constants.DevOpsProjectLabelKey: project},
+	}}
+}
+
+func TestRegistry(t *testing.T) {
+	registry := newFakeRegistry("default", "b")
+	assert.True(t, registry.IsSharded())
+	assert.Equal(t, "default", registry.Default())
+	assert.Equal(t, []string{"default", "b"}, registry.Names())
+
+	instance, err := registry.Get("")
+	assert.Nil(t, err)
+	assert.Equal(t, "default", instance.Name)
+	instance, err = registry.Get("b")
+	assert.Nil(t, err)
+	assert.Equal(t, "http://b", instance.Core.URL)
+	_, err = registry.Get("c")
+	assert.NotNil(t, err)
+
+	assert.False(t, newFakeRegistry("default").IsSharded())
+	assert.Equal(t, "", NewRegistry().Default())
+}
+
+func TestNewRegistryFromOptions(t *testing.T) {
+	registry, err := NewRegistryFromOptions(&jenkins.Options{
+		Host:     "http://jenkins-a",
+		Username: "admin",
+		ApiToken: "token-a",
+		Instances: []jenkins.InstanceOptions{{
+			Name:     "b",
+			Host:     "http://jenkins-b",
+			Username: "admin",
+			ApiToken: "token-b",
+		}},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{jenkins.DefaultInstanceName, "b"}, registry.Names())
+
+	instance, err := registry.Get("b")
+	assert.Nil(t, err)
+	assert.Equal(t, "http://jenkins-b", instance.Core.URL)
+	assert.Equal(t, "token-b", instance.Core.Token)
+	assert.NotNil(t, instance.Client)
+}
+
+func TestProjectLocator(t *testing.T) {
+	schema, err := v1alpha3.SchemeBuilder.Register().Build()
+	assert.Nil(t, err)
+	assert.Nil(t, corev1.AddToScheme(schema))
+
+	tests := []struct {
+		name         string
+		registry     *Registry
+		objects      []runtime.Object
+		namespace    string
+		wantInstance string
+		wantErr      error
+	}{{
+		name:         "single instance",
+		registry:     newFakeRegistry("default"),
+		namespace:    "team-ns",
+		wantInstance: "default",
+	}, {
+		name:         "placed project",
+		registry:     newFakeRegistry("default", "b"),
+		objects:      []runtime.Object{newNamespace("team-ns", "team"), newProject("team", "b", nil)},
+		namespace:    "team-ns",
+		wantInstance: "b",
+	}, {
+		name:         "the namespace without label",
+		registry:     newFakeRegistry("default", "b"),
+		objects:      []runtime.Object{newNamespace("team", ""), newProject("team", "b", nil)},
+		namespace:    "team",
+		wantInstance: "b",
+	}, {
+		name:      "not placed yet",
+		registry:  newFakeRegistry("default", "b"),
+		objects:   []runtime.Object{newNamespace("team-ns", "team"), newProject("team", "", nil)},
+		namespace: "team-ns",
+		wantErr:   ErrNotPlaced,
+	}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reader := fake.NewClientBuilder().WithScheme(schema).WithRuntimeObjects(tt.objects...).Build()
+			instance, err := NewProjectLocator(reader, tt.registry).Locate(tt.namespace)
+			assert.Equal(t, tt.wantErr, err)
+			assert.Equal(t, tt.wantInstance, instance)
+		})
+	}
+
+	t.Run("namespace not found", func(t *testing.T) {
+		reader := fake.NewClientBuilder().WithScheme(schema).Build()
+		_, err := NewProjectLocator(reader, newFakeRegistry("default", "b")).Locate("team-ns")
+		assert.NotNil(t, err)
+	})
+}
+
+func TestLeastProjectsPolicy(t *testing.T) {
+	policy := &LeastProjectsPolicy{Registry: newFakeRegistry("default", "b", "c")}
+	tests := []struct {
+		name         string
+		project      *v1alpha3.DevOpsProject
+		projects     []v1alpha3.DevOpsProject
+		wantInstance string
+		wantErr      bool
+	}{{
+		name:         "no projects",
+		project:      newProject("team", "", nil),
+		wantInstance: "default",
+	}, {
+		name:    "the instance has the least projects",
+		project: newProject("team", "", nil),
+		projects: []v1alpha3.DevOpsProject{
+			*newProject("a", "default", nil), *newProject("b", "b", nil),
+			*newProject("c", "default", nil), *newProject("d", "c", nil), *newProject("e", "c", nil),
+		},
+		wantInstance: "b",
+	}, {
+		name:         "the desired instance",
+		project:      newProject("team", "", map[string]string{v1alpha3.DevOpsProjectJenkinsInstanceAnnoKey: "c"}),
+		projects:     []v1alpha3.DevOpsProject{*newProject("a", "c", nil)},
+		wantInstance: "c",
+	}, {
+		name:    "the desired instance is not registered",
+		project: newProject("team", "", map[string]string{v1alpha3.DevOpsProjectJenkinsInstanceAnnoKey: "d"}),
+		wantErr: true,
+	}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			instance, err := policy.Place(tt.project, tt.projects)
+			assert.Equal(t, tt.wantErr, err != nil, err)
+			assert.Equal(t, tt.wantInstance, instance)
+		})
+	}
+}
@@ -22,7 +22,6 @@ import (
 
 	goscm "github.com/jenkins-x/go-scm/scm"
 	"github.com/jenkins-x/go-scm/scm/factory"
-	"github.com/kubesphere/ks-devops/pkg/api/devops/v1alpha3"
 	v1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/types"
 	"sigs.k8s.io/controller-runtime/pkg/client"
@@ -79,14 +78,11 @@ func (c *ClientFactory) getTokenFromSecret(secretRef *v1.SecretReference) (token
 		return
 	}
 
-	switch gitSecret.Type {
-	case v1.SecretTypeBasicAuth, v1alpha3.SecretTypeBasicAuth:
-		token = string(gitSecret.Data[v1.BasicAuthPasswordKey])
-		username = string(gitSecret.Data[v1.BasicAuthUsernameKey])
-	case v1.SecretTypeOpaque:
-		token = string(gitSecret.Data[v1.ServiceAccountTokenKey])
-	case v1alpha3.SecretTypeSecretText:
-		token = string(gitSecret.Data["secret"])
+	// a SSH private key does not work with the API, so the token is empty in that case
+	var cred *Credential
+	if cred, err = ParseCredential(context.TODO(), gitSecret); err == nil {
+		token = cred.Token
+		username = cred.Username
 	}
 	return
 }
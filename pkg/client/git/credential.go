@@ -0,0 +1,259 @@
+/*
+Copyright 2022 The KubeSphere Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package git
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/form3tech-oss/jwt-go"
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	githttp "github.com/go-git/go-git/v5/plumbing/transport/http"
+	gitssh "github.com/go-git/go-git/v5/plumbing/transport/ssh"
+	"github.com/kubesphere/ks-devops/pkg/api/devops/v1alpha3"
+	cryptossh "golang.org/x/crypto/ssh"
+	v1 "k8s.io/api/core/v1"
+)
+
+const (
+	// defaultSSHUser is the user of most git servers over SSH
+	defaultSSHUser = "git"
+	// gitHubAppUser is the username of the GitHub App installation token over HTTP
+	gitHubAppUser = "x-access-token"
+	// defaultGitHubAPI is the default API address of GitHub
+	defaultGitHubAPI = "https://api.github.com"
+	// gitHubAppTokenLeeway is the time before the expiration when a cached installation token is refreshed
+	gitHubAppTokenLeeway = 5 * time.Minute
+	// gitHubAppTokenTimeout is the timeout of requesting an installation token
+	gitHubAppTokenTimeout = 30 * time.Second
+)
+
+// Credential represents the auth data of a git repository which comes from a secret
+type Credential struct {
+	Username   string
+	Token      string
+	PrivateKey []byte
+	Passphrase string
+	KnownHosts []byte
+}
+
+// IsSSH returns true if the credential is a SSH private key
+func (c *Credential) IsSSH() bool {
+	return len(c.PrivateKey) > 0
+}
+
+// IsEmpty returns true if there is neither a token nor a private key
+func (c *Credential) IsEmpty() bool {
+	return c.Token == "" && !c.IsSSH()
+}
+
+// GetAuthMethod returns the auth method for the git transport.
+// The host key is checked against the known_hosts from the secret, or the default known_hosts files of the system.
+func (c *Credential) GetAuthMethod(insecureIgnoreHostKey bool) (auth transport.AuthMethod, err error) {
+	if !c.IsSSH() {
+		auth = &githttp.BasicAuth{
+			Username: c.Username,
+			Password: c.Token,
+		}
+		return
+	}
+
+	username := c.Username
+	if username == "" {
+		username = defaultSSHUser
+	}
+
+	var publicKeys *gitssh.PublicKeys
+	if publicKeys, err = gitssh.NewPublicKeys(username, c.PrivateKey, c.Passphrase); err != nil {
+		err = fmt.Errorf("failed to parse the SSH private key: %v", err)
+		return
+	}
+
+	switch {
+	case insecureIgnoreHostKey:
+		publicKeys.HostKeyCallback = cryptossh.InsecureIgnoreHostKey()
+	case len(c.KnownHosts) > 0:
+		if publicKeys.HostKeyCallback, err = newKnownHostsCallback(c.KnownHosts); err != nil {
+			return
+		}
+	}
+	auth = publicKeys
+	return
+}
+
+// newKnownHostsCallback creates the host key callback from the content of known_hosts
+func newKnownHostsCallback(knownHosts []byte) (callback cryptossh.HostKeyCallback, err error) {
+	var file *os.File
+	if file, err = os.CreateTemp("", "known_hosts"); err != nil {
+		return
+	}
+	defer func() {
+		_ = os.Remove(file.Name())
+	}()
+
+	if _, err = file.Write(knownHosts); err != nil {
+		_ = file.Close()
+		return
+	}
+	if err = file.Close(); err != nil {
+		return
+	}
+
+	// the content is loaded into memory, so it's safe to remove the file
+	if callback, err = gitssh.NewKnownHostsCallback(file.Name()); err != nil {
+		err = fmt.Errorf("failed to parse the known_hosts: %v", err)
+	}
+	return
+}
+
+// ParseCredential parses the git credential from a secret.
+// An installation token is requested if the secret is a GitHub App.
+func ParseCredential(ctx context.Context, secret *v1.Secret) (cred *Credential, err error) {
+	cred = &Credential{}
+	switch secret.Type {
+	case v1.SecretTypeBasicAuth, v1alpha3.SecretTypeBasicAuth:
+		cred.Token = string(secret.Data[v1.BasicAuthPasswordKey])
+		cred.Username = string(secret.Data[v1.BasicAuthUsernameKey])
+	case v1.SecretTypeOpaque:
+		cred.Token = string(secret.Data[v1.ServiceAccountTokenKey])
+	case v1alpha3.SecretTypeSecretText:
+		cred.Token = string(secret.Data[v1alpha3.SecretTextSecretKey])
+	case v1.SecretTypeSSHAuth:
+		cred.PrivateKey = secret.Data[v1.SSHAuthPrivateKey]
+		cred.KnownHosts = secret.Data[v1alpha3.SSHAuthKnownHostsKey]
+	case v1alpha3.SecretTypeSSHAuth:
+		cred.Username = string(secret.Data[v1alpha3.SSHAuthUsernameKey])
+		cred.PrivateKey = secret.Data[v1alpha3.SSHAuthPrivateKey]
+		cred.Passphrase = string(secret.Data[v1alpha3.SSHAuthPassphraseKey])
+		cred.KnownHosts = secret.Data[v1alpha3.SSHAuthKnownHostsKey]
+	case v1alpha3.SecretTypeGitHubApp:
+		cred.Username = gitHubAppUser
+		cred.Token, err = getGitHubAppToken(ctx, secret)
+	}
+	return
+}
+
+type gitHubAppToken struct {
+	Token     string    `json:"token"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// gitHubAppTokens caches the installation tokens, the key is the API address, App ID, installation ID and the hash
+// of the private key. The private key is part of the key, so a Secret with the same IDs never gets the token of another one.
+var gitHubAppTokens = struct {
+	sync.Mutex
+	tokens map[string]gitHubAppToken
+}{tokens: map[string]gitHubAppToken{}}
+
+// gitHubAppClient requests the installation tokens, a GitHub which does not respond never blocks the callers
+var gitHubAppClient = &http.Client{Timeout: gitHubAppTokenTimeout}
+
+func getGitHubAppToken(ctx context.Context, secret *v1.Secret) (token string, err error) {
+	appID := strings.TrimSpace(string(secret.Data[v1alpha3.GitHubAppIDKey]))
+	installationID := strings.TrimSpace(string(secret.Data[v1alpha3.GitHubAppInstallationIDKey]))
+	privateKey := secret.Data[v1alpha3.GitHubAppPrivateKey]
+	apiURL := strings.TrimSuffix(strings.TrimSpace(string(secret.Data[v1alpha3.GitHubAppAPIURLKey])), "/")
+	if apiURL == "" {
+		apiURL = defaultGitHubAPI
+	}
+	if appID == "" || installationID == "" || len(privateKey) == 0 {
+		err = fmt.Errorf("the GitHub App secret %s/%s requires %s, %s and %s", secret.Namespace, secret.Name,
+			v1alpha3.GitHubAppIDKey, v1alpha3.GitHubAppInstallationIDKey, v1alpha3.GitHubAppPrivateKey)
+		return
+	}
+
+	key := fmt.Sprintf("%s/%s/%s/%x", apiURL, appID, installationID, sha256.Sum256(privateKey))
+	gitHubAppTokens.Lock()
+	cached, ok := gitHubAppTokens.tokens[key]
+	gitHubAppTokens.Unlock()
+	if ok && time.Now().Add(gitHubAppTokenLeeway).Before(cached.ExpiresAt) {
+		token = cached.Token
+		return
+	}
+
+	// the lock is not held during the request, the concurrent callers might request the tokens at the same time,
+	// which is fine because every one of them is valid
+	var appToken *gitHubAppToken
+	if appToken, err = requestGitHubAppToken(ctx, apiURL, appID, installationID, privateKey); err != nil {
+		return
+	}
+	gitHubAppTokens.Lock()
+	gitHubAppTokens.tokens[key] = *appToken
+	gitHubAppTokens.Unlock()
+	token = appToken.Token
+	return
+}
+
+// requestGitHubAppToken exchanges a JWT signed by the App private key for an installation token
+func requestGitHubAppToken(ctx context.Context, apiURL, appID, installationID string, privateKey []byte) (
+	appToken *gitHubAppToken, err error) {
+	var jwtToken string
+	if jwtToken, err = signGitHubAppJWT(appID, privateKey); err != nil {
+		return
+	}
+
+	var req *http.Request
+	address := fmt.Sprintf("%s/app/installations/%s/access_tokens", apiURL, installationID)
+	if req, err = http.NewRequestWithContext(ctx, http.MethodPost, address, nil); err != nil {
+		return
+	}
+	req.Header.Set("Authorization", "Bearer "+jwtToken)
+	req.Header.Set("Accept", "application/vnd.github+json")
+
+	var resp *http.Response
+	if resp, err = gitHubAppClient.Do(req); err != nil {
+		return
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	if resp.StatusCode != http.StatusCreated {
+		err = fmt.Errorf("failed to create the installation token of GitHub App %s, status code: %d", appID, resp.StatusCode)
+		return
+	}
+
+	appToken = &gitHubAppToken{}
+	if err = json.NewDecoder(resp.Body).Decode(appToken); err == nil && appToken.Token == "" {
+		err = fmt.Errorf("no installation token of GitHub App %s found in the response", appID)
+	}
+	return
+}
+
+func signGitHubAppJWT(appID string, privateKey []byte) (token string, err error) {
+	key, err := jwt.ParseRSAPrivateKeyFromPEM(privateKey)
+	if err != nil {
+		err = fmt.Errorf("failed to parse the private key of GitHub App %s: %v", appID, err)
+		return
+	}
+
+	now := time.Now()
+	// issue it a bit earlier to allow the clock drift
+	token, err = jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.StandardClaims{
+		IssuedAt:  now.Add(-time.Minute).Unix(),
+		ExpiresAt: now.Add(10 * time.Minute).Unix(),
+		Issuer:    appID,
+	}).SignedString(key)
+	return
+}
@@ -0,0 +1,324 @@
+/*
+Copyright 2022 The KubeSphere Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package git
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/form3tech-oss/jwt-go"
+	githttp "github.com/go-git/go-git/v5/plumbing/transport/http"
+	gitssh "github.com/go-git/go-git/v5/plumbing/transport/ssh"
+	"github.com/h2non/gock"
+	"github.com/kubesphere/ks-devops/pkg/api/devops/v1alpha3"
+	"github.com/stretchr/testify/assert"
+	cryptossh "golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func generatePrivateKey(t *testing.T) (*rsa.PrivateKey, []byte) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.Nil(t, err)
+	return key, pem.EncodeToMemory(&pem.Block{
+		Type:  "RSA PRIVATE KEY",
+		Bytes: x509.MarshalPKCS1PrivateKey(key),
+	})
+}
+
+func TestParseCredential(t *testing.T) {
+	_, privateKey := generatePrivateKey(t)
+
+	tests := []struct {
+		name    string
+		secret  *v1.Secret
+		want    *Credential
+		wantErr bool
+	}{{
+		name: "basic auth",
+		secret: &v1.Secret{
+			Type: v1alpha3.SecretTypeBasicAuth,
+			Data: map[string][]byte{v1.BasicAuthUsernameKey: []byte("admin"), v1.BasicAuthPasswordKey: []byte("token")},
+		},
+		want: &Credential{Username: "admin", Token: "token"},
+	}, {
+		name: "opaque token",
+		secret: &v1.Secret{
+			Type: v1.SecretTypeOpaque,
+			Data: map[string][]byte{v1.ServiceAccountTokenKey: []byte("token")},
+		},
+		want: &Credential{Token: "token"},
+	}, {
+		name: "secret text",
+		secret: &v1.Secret{
+			Type: v1alpha3.SecretTypeSecretText,
+			Data: map[string][]byte{v1alpha3.SecretTextSecretKey: []byte("token")},
+		},
+		want: &Credential{Token: "token"},
+	}, {
+		name: "ssh auth",
+		secret: &v1.Secret{
+			Type: v1alpha3.SecretTypeSSHAuth,
+			Data: map[string][]byte{
+				v1alpha3.SSHAuthUsernameKey:   []byte("git"),
+				v1alpha3.SSHAuthPrivateKey:    privateKey,
+				v1alpha3.SSHAuthPassphraseKey: []byte("pass"),
+				v1alpha3.SSHAuthKnownHostsKey: []byte("hosts"),
+			},
+		},
+		want: &Credential{Username: "git", PrivateKey: privateKey, Passphrase: "pass", KnownHosts: []byte("hosts")},
+	}, {
+		name: "kubernetes ssh auth",
+		secret: &v1.Secret{
+			Type: v1.SecretTypeSSHAuth,
+			Data: map[string][]byte{v1.SSHAuthPrivateKey: privateKey},
+		},
+		want: &Credential{PrivateKey: privateKey},
+	}, {
+		name: "github app without the installation id",
+		secret: &v1.Secret{
+			Type: v1alpha3.SecretTypeGitHubApp,
+			Data: map[string][]byte{v1alpha3.GitHubAppIDKey: []byte("1"), v1alpha3.GitHubAppPrivateKey: privateKey},
+		},
+		wantErr: true,
+	}, {
+		name:   "unknown type",
+		secret: &v1.Secret{Type: v1.SecretTypeDockercfg},
+		want:   &Credential{},
+	}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseCredential(context.TODO(), tt.secret)
+			if tt.wantErr {
+				assert.NotNil(t, err)
+				return
+			}
+			assert.Nil(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestParseCredentialWithGitHubApp(t *testing.T) {
+	key, privateKey := generatePrivateKey(t)
+	secret := &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "app"},
+		Type:       v1alpha3.SecretTypeGitHubApp,
+		Data: map[string][]byte{
+			v1alpha3.GitHubAppIDKey:             []byte("123"),
+			v1alpha3.GitHubAppInstallationIDKey: []byte("456"),
+			v1alpha3.GitHubAppPrivateKey:        privateKey,
+			v1alpha3.GitHubAppAPIURLKey:         []byte("https://github.example.com/api/v3/"),
+		},
+	}
+
+	defer gock.Off()
+	gock.New("https://github.example.com").
+		Post("/api/v3/app/installations/456/access_tokens").
+		// the matchers are added to a new matcher, or they are shared by all the mocks
+		SetMatcher(gock.NewMatcher()).
+		AddMatcher(func(req *http.Request, _ *gock.Request) (bool, error) {
+			// the JWT must be signed by the App private key
+			return verifyGitHubAppJWT(key, req.Header.Get("Authorization")), nil
+		}).
+		Reply(http.StatusCreated).
+		JSON(map[string]interface{}{"token": "ghs_token", "expires_at": time.Now().Add(time.Hour)})
+
+	cred, err := ParseCredential(context.TODO(), secret)
+	assert.Nil(t, err)
+	assert.Equal(t, &Credential{Username: "x-access-token", Token: "ghs_token"}, cred)
+	assert.True(t, gock.IsDone())
+
+	// the token is cached, no more request is sent
+	cred, err = ParseCredential(context.TODO(), secret)
+	assert.Nil(t, err)
+	assert.Equal(t, "ghs_token", cred.Token)
+
+	// the token is not shared with a Secret which has the same IDs but a different private key
+	otherKey, otherPrivateKey := generatePrivateKey(t)
+	otherSecret := secret.DeepCopy()
+	otherSecret.Data[v1alpha3.GitHubAppPrivateKey] = otherPrivateKey
+	gock.New("https://github.example.com").
+		Post("/api/v3/app/installations/456/access_tokens").
+		SetMatcher(gock.NewMatcher()).
+		AddMatcher(func(req *http.Request, _ *gock.Request) (bool, error) {
+			return verifyGitHubAppJWT(otherKey, req.Header.Get("Authorization")), nil
+		}).
+		Reply(http.StatusCreated).
+		JSON(map[string]interface{}{"token": "ghs_other", "expires_at": time.Now().Add(time.Hour)})
+	cred, err = ParseCredential(context.TODO(), otherSecret)
+	assert.Nil(t, err)
+	assert.Equal(t, "ghs_other", cred.Token)
+	assert.True(t, gock.IsDone())
+
+	// it fails when the installation is not found
+	secret.Data[v1alpha3.GitHubAppInstallationIDKey] = []byte("789")
+	gock.New("https://github.example.com").
+		Post("/api/v3/app/installations/789/access_tokens").
+		Reply(http.StatusNotFound)
+	_, err = ParseCredential(context.TODO(), secret)
+	assert.NotNil(t, err)
+}
+
+func TestParseCredentialWithHungGitHubApp(t *testing.T) {
+	_, privateKey := generatePrivateKey(t)
+	received := make(chan struct{}, 1)
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/app/installations/hung/access_tokens" {
+			received <- struct{}{}
+			select {
+			case <-release:
+			case <-r.Context().Done():
+			}
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte(`{"token": "ghs_token", "expires_at": "2099-01-01T00:00:00Z"}`))
+	}))
+	defer server.Close()
+
+	newSecret := func(installationID string) *v1.Secret {
+		return &v1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: installationID},
+			Type:       v1alpha3.SecretTypeGitHubApp,
+			Data: map[string][]byte{
+				v1alpha3.GitHubAppIDKey:             []byte("123"),
+				v1alpha3.GitHubAppInstallationIDKey: []byte(installationID),
+				v1alpha3.GitHubAppPrivateKey:        privateKey,
+				v1alpha3.GitHubAppAPIURLKey:         []byte(server.URL),
+			},
+		}
+	}
+
+	// the other installations are not blocked by a hung request
+	done := make(chan error)
+	go func() {
+		_, err := ParseCredential(context.TODO(), newSecret("hung"))
+		done <- err
+	}()
+	<-received
+	cred, err := ParseCredential(context.TODO(), newSecret("456"))
+	assert.Nil(t, err)
+	assert.Equal(t, "ghs_token", cred.Token)
+	close(release)
+	<-done
+
+	// the hung request times out
+	defaultClient := gitHubAppClient
+	gitHubAppClient = &http.Client{Timeout: 100 * time.Millisecond}
+	defer func() {
+		gitHubAppClient = defaultClient
+	}()
+	release = make(chan struct{})
+	defer close(release)
+	_, err = ParseCredential(context.TODO(), newSecret("hung"))
+	assert.NotNil(t, err)
+}
+
+func verifyGitHubAppJWT(key *rsa.PrivateKey, authorization string) bool {
+	claims := &jwt.StandardClaims{}
+	token, err := jwt.ParseWithClaims(strings.TrimPrefix(authorization, "Bearer "), claims, func(token *jwt.Token) (interface{}, error) {
+		return &key.PublicKey, nil
+	})
+	return err == nil && token.Valid && token.Method == jwt.SigningMethodRS256 && claims.Issuer == "123"
+}
+
+func TestGetAuthMethod(t *testing.T) {
+	key, privateKey := generatePrivateKey(t)
+	publicKey, err := cryptossh.NewPublicKey(&key.PublicKey)
+	assert.Nil(t, err)
+	knownHosts := []byte(knownhosts.Line([]string{"github.com"}, publicKey))
+
+	tests := []struct {
+		name     string
+		cred     *Credential
+		insecure bool
+		verify   func(*testing.T, interface{})
+		wantErr  bool
+	}{{
+		name: "basic auth",
+		cred: &Credential{Username: "admin", Token: "token"},
+		verify: func(t *testing.T, auth interface{}) {
+			assert.Equal(t, &githttp.BasicAuth{Username: "admin", Password: "token"}, auth)
+		},
+	}, {
+		name: "ssh with the default user",
+		cred: &Credential{PrivateKey: privateKey},
+		verify: func(t *testing.T, auth interface{}) {
+			publicKeys, ok := auth.(*gitssh.PublicKeys)
+			assert.True(t, ok)
+			assert.Equal(t, "git", publicKeys.User)
+			assert.Nil(t, publicKeys.HostKeyCallback)
+		},
+	}, {
+		name: "ssh with known hosts",
+		cred: &Credential{Username: "admin", PrivateKey: privateKey, KnownHosts: knownHosts},
+		verify: func(t *testing.T, auth interface{}) {
+			publicKeys, ok := auth.(*gitssh.PublicKeys)
+			assert.True(t, ok)
+			assert.Equal(t, "admin", publicKeys.User)
+			addr := &fakeAddr{address: "github.com:22"}
+			assert.Nil(t, publicKeys.HostKeyCallback("github.com:22", addr, publicKey))
+			assert.NotNil(t, publicKeys.HostKeyCallback("gitlab.com:22", addr, publicKey))
+		},
+	}, {
+		name:     "ssh without checking the host key",
+		cred:     &Credential{PrivateKey: privateKey, KnownHosts: knownHosts},
+		insecure: true,
+		verify: func(t *testing.T, auth interface{}) {
+			publicKeys, ok := auth.(*gitssh.PublicKeys)
+			assert.True(t, ok)
+			assert.Nil(t, publicKeys.HostKeyCallback("gitlab.com:22", &fakeAddr{address: "gitlab.com:22"}, publicKey))
+		},
+	}, {
+		name:    "invalid private key",
+		cred:    &Credential{PrivateKey: []byte("invalid")},
+		wantErr: true,
+	}, {
+		name:    "invalid known hosts",
+		cred:    &Credential{PrivateKey: privateKey, KnownHosts: []byte("github.com invalid")},
+		wantErr: true,
+	}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			auth, err := tt.cred.GetAuthMethod(tt.insecure)
+			if tt.wantErr {
+				assert.NotNil(t, err)
+				return
+			}
+			assert.Nil(t, err)
+			tt.verify(t, auth)
+		})
+	}
+}
+
+type fakeAddr struct {
+	address string
+}
+
+func (a *fakeAddr) Network() string { return "tcp" }
+func (a *fakeAddr) String() string  { return a.address }
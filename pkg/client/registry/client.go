@@ -0,0 +1,339 @@
+/*
+Copyright 2022 The KubeSphere Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package registry
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultRegistry is the registry which is used when the image does not contain a registry host
+	DefaultRegistry = "registry-1.docker.io"
+
+	mediaTypeDockerManifest     = "application/vnd.docker.distribution.manifest.v2+json"
+	mediaTypeDockerManifestList = "application/vnd.docker.distribution.manifest.list.v2+json"
+	mediaTypeOCIManifest        = "application/vnd.oci.image.manifest.v1+json"
+	mediaTypeOCIIndex           = "application/vnd.oci.image.index.v1+json"
+
+	// defaultTimeout is the timeout of the requests to the registries
+	defaultTimeout = 30 * time.Second
+)
+
+var manifestAcceptTypes = strings.Join([]string{
+	mediaTypeDockerManifest, mediaTypeDockerManifestList, mediaTypeOCIManifest, mediaTypeOCIIndex,
+}, ",")
+
+// Interface is the client of the Docker Registry HTTP API V2
+type Interface interface {
+	ListTags(ctx context.Context, image string) ([]string, error)
+	GetDigest(ctx context.Context, image, tag string) (string, error)
+	GetCreatedTime(ctx context.Context, image, tag string) (time.Time, error)
+}
+
+// Reference represents the location of an image repository
+type Reference struct {
+	Registry   string
+	Repository string
+}
+
+// ParseReference parses an image name without tag and digest into a Reference
+func ParseReference(image string) (ref Reference) {
+	parts := strings.SplitN(image, "/", 2)
+	if len(parts) == 2 && (strings.ContainsAny(parts[0], ".:") || parts[0] == "localhost") {
+		ref.Registry, ref.Repository = parts[0], parts[1]
+	} else {
+		ref.Registry, ref.Repository = DefaultRegistry, image
+	}
+
+	if ref.Registry == "docker.io" || ref.Registry == "index.docker.io" {
+		ref.Registry = DefaultRegistry
+	}
+	if ref.Registry == DefaultRegistry && !strings.Contains(ref.Repository, "/") {
+		ref.Repository = "library/" + ref.Repository
+	}
+	return
+}
+
+// baseURL returns the URL of the registry API, the plain HTTP is only allowed for the loopback registries
+func (r Reference) baseURL() string {
+	host := r.Registry
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	if ip := net.ParseIP(host); host == "localhost" || (ip != nil && ip.IsLoopback()) {
+		return "http://" + r.Registry
+	}
+	return "https://" + r.Registry
+}
+
+type client struct {
+	username   string
+	password   string
+	httpClient *http.Client
+
+	tokens sync.Map
+}
+
+// NewClient creates a registry client with the optional basic auth, the default HTTP client has a timeout
+// so that an unresponsive registry never blocks the callers
+func NewClient(username, password string, httpClient *http.Client) Interface {
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: defaultTimeout}
+	}
+	return &client{
+		username:   username,
+		password:   password,
+		httpClient: httpClient,
+	}
+}
+
+// ListTags returns all the tags of an image
+func (c *client) ListTags(ctx context.Context, image string) (tags []string, err error) {
+	ref := ParseReference(image)
+	next := fmt.Sprintf("%s/v2/%s/tags/list", ref.baseURL(), ref.Repository)
+	for next != "" {
+		var resp *http.Response
+		if resp, err = c.do(ctx, http.MethodGet, next, ref, nil); err != nil {
+			return
+		}
+
+		result := struct {
+			Tags []string `json:"tags"`
+		}{}
+		err = json.NewDecoder(resp.Body).Decode(&result)
+		_ = resp.Body.Close()
+		if err != nil {
+			return
+		}
+		tags = append(tags, result.Tags...)
+		next = getNextLink(next, resp.Header.Get("Link"))
+	}
+	return
+}
+
+// GetDigest returns the digest of the manifest of a tag
+func (c *client) GetDigest(ctx context.Context, image, tag string) (digest string, err error) {
+	ref := ParseReference(image)
+	var resp *http.Response
+	if resp, err = c.do(ctx, http.MethodHead, fmt.Sprintf("%s/v2/%s/manifests/%s", ref.baseURL(), ref.Repository, tag),
+		ref, map[string]string{"Accept": manifestAcceptTypes}); err != nil {
+		return
+	}
+	_ = resp.Body.Close()
+
+	if digest = resp.Header.Get("Docker-Content-Digest"); digest == "" {
+		err = fmt.Errorf("no digest found for %s:%s", image, tag)
+	}
+	return
+}
+
+type manifest struct {
+	MediaType string `json:"mediaType"`
+	Config    struct {
+		Digest string `json:"digest"`
+	} `json:"config"`
+	Manifests []struct {
+		Digest string `json:"digest"`
+	} `json:"manifests"`
+}
+
+// GetCreatedTime returns the creation time of the image config of a tag,
+// the first image is taken if the tag refers to a multi-platform image
+func (c *client) GetCreatedTime(ctx context.Context, image, tag string) (created time.Time, err error) {
+	ref := ParseReference(image)
+	m := &manifest{}
+	if err = c.getJSON(ctx, fmt.Sprintf("%s/v2/%s/manifests/%s", ref.baseURL(), ref.Repository, tag), ref,
+		map[string]string{"Accept": manifestAcceptTypes}, m); err != nil {
+		return
+	}
+
+	if m.MediaType == mediaTypeDockerManifestList || m.MediaType == mediaTypeOCIIndex || len(m.Manifests) > 0 {
+		if len(m.Manifests) == 0 {
+			err = fmt.Errorf("no manifests found in the index of %s:%s", image, tag)
+			return
+		}
+		digest := m.Manifests[0].Digest
+		m = &manifest{}
+		if err = c.getJSON(ctx, fmt.Sprintf("%s/v2/%s/manifests/%s", ref.baseURL(), ref.Repository, digest), ref,
+			map[string]string{"Accept": manifestAcceptTypes}, m); err != nil {
+			return
+		}
+	}
+
+	if m.Config.Digest == "" {
+		err = fmt.Errorf("no image config found for %s:%s", image, tag)
+		return
+	}
+	config := struct {
+		Created time.Time `json:"created"`
+	}{}
+	if err = c.getJSON(ctx, fmt.Sprintf("%s/v2/%s/blobs/%s", ref.baseURL(), ref.Repository, m.Config.Digest), ref,
+		nil, &config); err == nil {
+		created = config.Created
+	}
+	return
+}
+
+func (c *client) getJSON(ctx context.Context, api string, ref Reference, header map[string]string, obj interface{}) (err error) {
+	var resp *http.Response
+	if resp, err = c.do(ctx, http.MethodGet, api, ref, header); err != nil {
+		return
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+	err = json.NewDecoder(resp.Body).Decode(obj)
+	return
+}
+
+// do sends the request, and tries again with the credential if the registry asks for it
+func (c *client) do(ctx context.Context, method, api string, ref Reference, header map[string]string) (resp *http.Response, err error) {
+	send := func(auth string) (*http.Response, error) {
+		req, err := http.NewRequestWithContext(ctx, method, api, nil)
+		if err != nil {
+			return nil, err
+		}
+		for key, val := range header {
+			req.Header.Set(key, val)
+		}
+		if auth != "" {
+			req.Header.Set("Authorization", auth)
+		}
+		return c.httpClient.Do(req)
+	}
+
+	auth, _ := c.tokens.Load(ref.Registry + "/" + ref.Repository)
+	authStr, _ := auth.(string)
+	if resp, err = send(authStr); err != nil {
+		return
+	}
+
+	if resp.StatusCode == http.StatusUnauthorized {
+		challenge := resp.Header.Get("WWW-Authenticate")
+		_ = resp.Body.Close()
+		if authStr, err = c.authorize(ctx, challenge); err != nil {
+			return
+		}
+		c.tokens.Store(ref.Registry+"/"+ref.Repository, authStr)
+		if resp, err = send(authStr); err != nil {
+			return
+		}
+	}
+
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		data, _ := io.ReadAll(resp.Body)
+		_ = resp.Body.Close()
+		err = fmt.Errorf("unexpected status code %d from %s, response: %s", resp.StatusCode, api, string(data))
+	}
+	return
+}
+
+var challengeParamPattern = regexp.MustCompile(`(\w+)="([^"]*)"`)
+
+// authorize returns the value of the Authorization header according to the challenge of the registry
+func (c *client) authorize(ctx context.Context, challenge string) (auth string, err error) {
+	scheme := strings.ToLower(strings.SplitN(challenge, " ", 2)[0])
+	switch scheme {
+	case "basic":
+		req, _ := http.NewRequest(http.MethodGet, "/", nil)
+		req.SetBasicAuth(c.username, c.password)
+		auth = req.Header.Get("Authorization")
+		return
+	case "bearer":
+	default:
+		err = fmt.Errorf("unsupported auth challenge: %q", challenge)
+		return
+	}
+
+	params := map[string]string{}
+	for _, match := range challengeParamPattern.FindAllStringSubmatch(challenge, -1) {
+		params[match[1]] = match[2]
+	}
+	if params["realm"] == "" {
+		err = fmt.Errorf("no realm found in the auth challenge: %q", challenge)
+		return
+	}
+
+	query := url.Values{}
+	for _, key := range []string{"service", "scope"} {
+		if params[key] != "" {
+			query.Set(key, params[key])
+		}
+	}
+	var req *http.Request
+	if req, err = http.NewRequestWithContext(ctx, http.MethodGet, params["realm"]+"?"+query.Encode(), nil); err != nil {
+		return
+	}
+	if c.username != "" || c.password != "" {
+		req.SetBasicAuth(c.username, c.password)
+	}
+
+	var resp *http.Response
+	if resp, err = c.httpClient.Do(req); err != nil {
+		return
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+	if resp.StatusCode != http.StatusOK {
+		err = fmt.Errorf("failed to get the token from %s, status code: %d", params["realm"], resp.StatusCode)
+		return
+	}
+
+	token := struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}{}
+	if err = json.NewDecoder(resp.Body).Decode(&token); err != nil {
+		return
+	}
+	if token.Token == "" {
+		token.Token = token.AccessToken
+	}
+	auth = "Bearer " + token.Token
+	return
+}
+
+var linkPattern = regexp.MustCompile(`<([^>]+)>;\s*rel="next"`)
+
+// getNextLink returns the next page URL from the Link header, see also RFC5988
+func getNextLink(current, link string) string {
+	match := linkPattern.FindStringSubmatch(link)
+	if len(match) != 2 {
+		return ""
+	}
+
+	next, err := url.Parse(match[1])
+	if err != nil {
+		return ""
+	}
+	base, err := url.Parse(current)
+	if err != nil {
+		return ""
+	}
+	return base.ResolveReference(next).String()
+}
@@ -0,0 +1,157 @@
+/*
+Copyright 2022 The KubeSphere Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package registry
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseReference(t *testing.T) {
+	tests := []struct {
+		image string
+		want  Reference
+	}{{
+		image: "nginx",
+		want:  Reference{Registry: DefaultRegistry, Repository: "library/nginx"},
+	}, {
+		image: "kubesphere/ks-devops",
+		want:  Reference{Registry: DefaultRegistry, Repository: "kubesphere/ks-devops"},
+	}, {
+		image: "docker.io/nginx",
+		want:  Reference{Registry: DefaultRegistry, Repository: "library/nginx"},
+	}, {
+		image: "ghcr.io/kubesphere/ks-devops",
+		want:  Reference{Registry: "ghcr.io", Repository: "kubesphere/ks-devops"},
+	}, {
+		image: "localhost:5000/demo",
+		want:  Reference{Registry: "localhost:5000", Repository: "demo"},
+	}}
+	for _, tt := range tests {
+		t.Run(tt.image, func(t *testing.T) {
+			assert.Equal(t, tt.want, ParseReference(tt.image))
+		})
+	}
+}
+
+func TestReference_baseURL(t *testing.T) {
+	assert.Equal(t, "https://ghcr.io", Reference{Registry: "ghcr.io"}.baseURL())
+	assert.Equal(t, "http://localhost:5000", Reference{Registry: "localhost:5000"}.baseURL())
+	assert.Equal(t, "http://127.0.0.1:5000", Reference{Registry: "127.0.0.1:5000"}.baseURL())
+}
+
+// newFakeRegistry creates a registry stub which requires a bearer token
+func newFakeRegistry(t *testing.T) *httptest.Server {
+	var server *httptest.Server
+	mux := http.NewServeMux()
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		username, password, ok := r.BasicAuth()
+		if !ok || username != "admin" || password != "password" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		assert.Equal(t, "repository:demo:pull", r.URL.Query().Get("scope"))
+		_, _ = w.Write([]byte(`{"token":"fake-token"}`))
+	})
+	mux.HandleFunc("/v2/", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer fake-token" {
+			w.Header().Set("WWW-Authenticate",
+				fmt.Sprintf(`Bearer realm="%s/token",service="fake",scope="repository:demo:pull"`, server.URL))
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		switch r.URL.Path {
+		case "/v2/demo/tags/list":
+			if r.URL.Query().Get("last") == "" {
+				w.Header().Set("Link", `</v2/demo/tags/list?n=2&last=v0.2.0>; rel="next"`)
+				_, _ = w.Write([]byte(`{"name":"demo","tags":["v0.1.0","v0.2.0"]}`))
+			} else {
+				_, _ = w.Write([]byte(`{"name":"demo","tags":["latest"]}`))
+			}
+		case "/v2/demo/manifests/v0.1.0":
+			w.Header().Set("Docker-Content-Digest", "sha256:v010")
+			_, _ = w.Write([]byte(`{"mediaType":"` + mediaTypeDockerManifest + `","config":{"digest":"sha256:config"}}`))
+		case "/v2/demo/manifests/latest":
+			_, _ = w.Write([]byte(`{"mediaType":"` + mediaTypeOCIIndex + `","manifests":[{"digest":"sha256:v010"}]}`))
+		case "/v2/demo/manifests/sha256:v010":
+			_, _ = w.Write([]byte(`{"mediaType":"` + mediaTypeOCIManifest + `","config":{"digest":"sha256:config"}}`))
+		case "/v2/demo/blobs/sha256:config":
+			_, _ = w.Write([]byte(`{"created":"2022-08-01T00:00:00Z"}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+	server = httptest.NewServer(mux)
+	return server
+}
+
+func TestClient(t *testing.T) {
+	server := newFakeRegistry(t)
+	defer server.Close()
+	image := strings.TrimPrefix(server.URL, "http://") + "/demo"
+	ctx := context.Background()
+
+	t.Run("without credential", func(t *testing.T) {
+		_, err := NewClient("", "", nil).ListTags(ctx, image)
+		assert.NotNil(t, err)
+	})
+
+	t.Run("the default HTTP client has a timeout", func(t *testing.T) {
+		assert.Equal(t, defaultTimeout, NewClient("", "", nil).(*client).httpClient.Timeout)
+	})
+
+	c := NewClient("admin", "password", server.Client())
+	t.Run("list tags", func(t *testing.T) {
+		tags, err := c.ListTags(ctx, image)
+		assert.Nil(t, err)
+		assert.Equal(t, []string{"v0.1.0", "v0.2.0", "latest"}, tags)
+	})
+
+	t.Run("get digest", func(t *testing.T) {
+		digest, err := c.GetDigest(ctx, image, "v0.1.0")
+		assert.Nil(t, err)
+		assert.Equal(t, "sha256:v010", digest)
+
+		_, err = c.GetDigest(ctx, image, "not-found")
+		assert.NotNil(t, err)
+	})
+
+	t.Run("get created time", func(t *testing.T) {
+		expected := time.Date(2022, 8, 1, 0, 0, 0, 0, time.UTC)
+		created, err := c.GetCreatedTime(ctx, image, "v0.1.0")
+		assert.Nil(t, err)
+		assert.True(t, expected.Equal(created))
+
+		created, err = c.GetCreatedTime(ctx, image, "latest")
+		assert.Nil(t, err)
+		assert.True(t, expected.Equal(created))
+	})
+}
+
+func Test_getNextLink(t *testing.T) {
+	assert.Equal(t, "", getNextLink("https://ghcr.io/v2/demo/tags/list", ""))
+	assert.Equal(t, "https://ghcr.io/v2/demo/tags/list?last=a",
+		getNextLink("https://ghcr.io/v2/demo/tags/list", `</v2/demo/tags/list?last=a>; rel="next"`))
+}
@@ -18,6 +18,7 @@ package config
 
 import (
 	"os"
+	"time"
 
 	"github.com/spf13/pflag"
 
@@ -30,12 +31,20 @@ type GitOpsOptions struct {
 
 	// NewFilePerm is the permissions for new files or folders in git repository, default is 0755.
 	NewFilePerm os.FileMode `json:"newFilePerm,omitempty" yaml:"newFilePerm,omitempty"`
+
+	// LeaseNamespace is the namespace of the Leases which lock the git repositories among multiple replicas.
+	// The git repositories are only locked in-process if it's empty.
+	LeaseNamespace string `json:"leaseNamespace,omitempty" yaml:"leaseNamespace,omitempty"`
+
+	// LockTimeout is the max duration of waiting for the lock of a git repository, default is 1 minute.
+	LockTimeout time.Duration `json:"lockTimeout,omitempty" yaml:"lockTimeout,omitempty"`
 }
 
 func NewGitOpsOptions() *GitOpsOptions {
 	return &GitOpsOptions{
 		RootDir:     "/gitops",
 		NewFilePerm: 0755,
+		LockTimeout: time.Minute,
 	}
 }
 
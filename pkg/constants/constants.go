@@ -36,6 +36,7 @@ const (
 	DevOpsTemplateTag        = "DevOps Template"
 	DevOpsStepTemplateTag    = "DevOps StepTemplate"
 	DevOpsClusterTemplateTag = "DevOps ClusterTemplate"
+	DevOpsDeployTargetTag    = "DevOps DeployTarget"
 	GitOpsTag                = "GitOps"
 
 	DevOpsManagedKey = "devops.kubesphere.io/managed"
@@ -52,6 +53,7 @@ var (
 	DevOpsTemplateTags        = []string{DevOpsTemplateTag}
 	DevOpsStepTemplateTags    = []string{DevOpsStepTemplateTag}
 	DevOpsClusterTemplateTags = []string{DevOpsClusterTemplateTag}
+	DevOpsDeployTargetTags    = []string{DevOpsDeployTargetTag}
 	GitOpsTags                = []string{GitOpsTag}
 )
 
@@ -0,0 +1,106 @@
+/*
+Copyright 2022 The KubeSphere Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package doctor
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// Status is the status of a check
+type Status string
+
+const (
+	// StatusPass means the integration works well
+	StatusPass Status = "PASS"
+	// StatusWarn means the integration works, but something might be wrong
+	StatusWarn Status = "WARN"
+	// StatusFail means the integration does not work
+	StatusFail Status = "FAIL"
+	// StatusSkip means the integration is not enabled
+	StatusSkip Status = "SKIP"
+)
+
+// Result is the result of a check
+type Result struct {
+	Name       string `json:"name"`
+	Status     Status `json:"status"`
+	Message    string `json:"message,omitempty"`
+	Suggestion string `json:"suggestion,omitempty"`
+}
+
+// Checker checks an integration of DevOps
+type Checker interface {
+	Check(ctx context.Context) Result
+}
+
+// Report is the results of all the checks
+type Report struct {
+	Results []Result `json:"results"`
+}
+
+// Run runs the checkers one by one
+func Run(ctx context.Context, checkers ...Checker) (report *Report) {
+	report = &Report{}
+	for _, checker := range checkers {
+		report.Results = append(report.Results, checker.Check(ctx))
+	}
+	return
+}
+
+// Failures returns the number of the failed checks
+func (r *Report) Failures() (count int) {
+	for _, result := range r.Results {
+		if result.Status == StatusFail {
+			count++
+		}
+	}
+	return
+}
+
+// Print prints the report in a human-readable format
+func (r *Report) Print(w io.Writer) {
+	for _, result := range r.Results {
+		_, _ = fmt.Fprintf(w, "[%s] %s: %s\n", result.Status, result.Name, result.Message)
+		if result.Suggestion != "" && (result.Status == StatusFail || result.Status == StatusWarn) {
+			_, _ = fmt.Fprintf(w, "       suggestion: %s\n", result.Suggestion)
+		}
+	}
+	_, _ = fmt.Fprintf(w, "\n%d checks, %d failures\n", len(r.Results), r.Failures())
+}
+
+func newResult(name string, status Status, suggestion, format string, args ...interface{}) Result {
+	return Result{
+		Name:       name,
+		Status:     status,
+		Message:    fmt.Sprintf(format, args...),
+		Suggestion: suggestion,
+	}
+}
+
+// defaultHTTPClient is used when a checker does not have its own HTTP client
+var defaultHTTPClient = &http.Client{Timeout: 10 * time.Second}
+
+func getHTTPClient(client *http.Client) *http.Client {
+	if client == nil {
+		return defaultHTTPClient
+	}
+	return client
+}
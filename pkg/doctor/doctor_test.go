@@ -0,0 +1,52 @@
+/*
+Copyright 2022 The KubeSphere Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package doctor
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeChecker struct {
+	result Result
+}
+
+func (f *fakeChecker) Check(ctx context.Context) Result {
+	return f.result
+}
+
+func TestRun(t *testing.T) {
+	report := Run(context.Background(),
+		&fakeChecker{result: Result{Name: "a", Status: StatusPass, Message: "good"}},
+		&fakeChecker{result: Result{Name: "b", Status: StatusFail, Message: "bad", Suggestion: "fix it"}},
+		&fakeChecker{result: Result{Name: "c", Status: StatusSkip, Message: "disabled", Suggestion: "ignored"}})
+	assert.Equal(t, 3, len(report.Results))
+	assert.Equal(t, 1, report.Failures())
+
+	buf := &bytes.Buffer{}
+	report.Print(buf)
+	assert.Equal(t, `[PASS] a: good
+[FAIL] b: bad
+       suggestion: fix it
+[SKIP] c: disabled
+
+3 checks, 1 failures
+`, buf.String())
+}
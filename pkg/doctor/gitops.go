@@ -0,0 +1,123 @@
+/*
+Copyright 2022 The KubeSphere Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package doctor
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/kubesphere/ks-devops/pkg/config"
+	v1 "k8s.io/api/core/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// CRDChecker checks if the CRDs of a GitOps engine are installed with the expected versions
+type CRDChecker struct {
+	Name    string
+	Enabled bool
+	// Namespace is where the engine located, skip checking it if it's empty
+	Namespace string
+	// CRDs are the names of the required CRDs and their served versions
+	CRDs   map[string]string
+	Client client.Client
+}
+
+// NewArgoCDChecker creates a checker for ArgoCD
+func NewArgoCDChecker(c client.Client, option *config.ArgoCDOption) *CRDChecker {
+	checker := &CRDChecker{
+		Name:   "ArgoCD",
+		Client: c,
+		CRDs: map[string]string{
+			"applications.argoproj.io": "v1alpha1",
+			"appprojects.argoproj.io":  "v1alpha1",
+		},
+	}
+	if option != nil {
+		checker.Enabled = option.Enabled
+		checker.Namespace = option.Namespace
+	}
+	return checker
+}
+
+// NewFluxCDChecker creates a checker for FluxCD
+func NewFluxCDChecker(c client.Client, option *config.FluxCDOption) *CRDChecker {
+	checker := &CRDChecker{
+		Name:   "FluxCD",
+		Client: c,
+		CRDs: map[string]string{
+			"gitrepositories.source.toolkit.fluxcd.io":   "v1beta2",
+			"helmrepositories.source.toolkit.fluxcd.io":  "v1beta2",
+			"helmreleases.helm.toolkit.fluxcd.io":        "v2beta1",
+			"kustomizations.kustomize.toolkit.fluxcd.io": "v1beta2",
+		},
+	}
+	if option != nil {
+		checker.Enabled = option.Enabled
+	}
+	return checker
+}
+
+// Check checks the namespace and the CRDs
+func (c *CRDChecker) Check(ctx context.Context) (result Result) {
+	if !c.Enabled {
+		return newResult(c.Name, StatusSkip, "", "%s is not enabled", c.Name)
+	}
+
+	if c.Namespace != "" {
+		if err := c.Client.Get(ctx, types.NamespacedName{Name: c.Namespace}, &v1.Namespace{}); err != nil {
+			if apierrors.IsNotFound(err) {
+				return newResult(c.Name, StatusFail,
+					fmt.Sprintf("install %s into namespace %s, or correct its namespace in the configuration", c.Name, c.Namespace),
+					"namespace %s of %s not found", c.Namespace, c.Name)
+			}
+			return newResult(c.Name, StatusFail, "", "failed to get namespace %s, error: %v", c.Namespace, err)
+		}
+	}
+
+	var problems []string
+	for name, version := range c.CRDs {
+		crd := &apiextensionsv1.CustomResourceDefinition{}
+		if err := c.Client.Get(ctx, types.NamespacedName{Name: name}, crd); err != nil {
+			if !apierrors.IsNotFound(err) {
+				return newResult(c.Name, StatusFail, "", "failed to get CRD %s, error: %v", name, err)
+			}
+			problems = append(problems, fmt.Sprintf("%s is missing", name))
+		} else if !isVersionServed(crd, version) {
+			problems = append(problems, fmt.Sprintf("%s does not serve %s", name, version))
+		}
+	}
+	if len(problems) > 0 {
+		sort.Strings(problems)
+		return newResult(c.Name, StatusFail, fmt.Sprintf("install a compatible version of %s", c.Name),
+			"unexpected CRDs of %s: %s", c.Name, strings.Join(problems, ", "))
+	}
+	return newResult(c.Name, StatusPass, "", "all the %d CRDs of %s are installed", len(c.CRDs), c.Name)
+}
+
+func isVersionServed(crd *apiextensionsv1.CustomResourceDefinition, version string) bool {
+	for _, item := range crd.Spec.Versions {
+		if item.Name == version && item.Served {
+			return true
+		}
+	}
+	return false
+}
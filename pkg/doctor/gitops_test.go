@@ -0,0 +1,103 @@
+/*
+Copyright 2022 The KubeSphere Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package doctor
+
+import (
+	"context"
+	"testing"
+
+	"github.com/kubesphere/ks-devops/pkg/config"
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/api/core/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestCRDChecker_Check(t *testing.T) {
+	schema := runtime.NewScheme()
+	assert.Nil(t, v1.AddToScheme(schema))
+	assert.Nil(t, apiextensionsv1.AddToScheme(schema))
+
+	newCRD := func(name, version string) *apiextensionsv1.CustomResourceDefinition {
+		return &apiextensionsv1.CustomResourceDefinition{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Spec: apiextensionsv1.CustomResourceDefinitionSpec{
+				Versions: []apiextensionsv1.CustomResourceDefinitionVersion{{Name: version, Served: true}},
+			},
+		}
+	}
+	argoNamespace := &v1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "argocd"}}
+	argoCRDs := []runtime.Object{newCRD("applications.argoproj.io", "v1alpha1"), newCRD("appprojects.argoproj.io", "v1alpha1")}
+
+	tests := []struct {
+		name        string
+		argo        *config.ArgoCDOption
+		flux        *config.FluxCDOption
+		objects     []runtime.Object
+		wantStatus  Status
+		wantMessage string
+	}{{
+		name:       "ArgoCD is not enabled",
+		argo:       &config.ArgoCDOption{},
+		wantStatus: StatusSkip,
+	}, {
+		name:        "the namespace of ArgoCD is missing",
+		argo:        &config.ArgoCDOption{Enabled: true, Namespace: "argocd"},
+		objects:     argoCRDs,
+		wantStatus:  StatusFail,
+		wantMessage: "namespace argocd of ArgoCD not found",
+	}, {
+		name:        "ArgoCD is installed",
+		argo:        &config.ArgoCDOption{Enabled: true, Namespace: "argocd"},
+		objects:     append([]runtime.Object{argoNamespace}, argoCRDs...),
+		wantStatus:  StatusPass,
+		wantMessage: "all the 2 CRDs of ArgoCD are installed",
+	}, {
+		name:       "FluxCD is not enabled",
+		flux:       nil,
+		wantStatus: StatusSkip,
+	}, {
+		name: "unknown FluxCD CRDs",
+		flux: &config.FluxCDOption{Enabled: true},
+		objects: []runtime.Object{
+			newCRD("gitrepositories.source.toolkit.fluxcd.io", "v1beta2"),
+			newCRD("helmrepositories.source.toolkit.fluxcd.io", "v1beta2"),
+			newCRD("helmreleases.helm.toolkit.fluxcd.io", "v2"),
+		},
+		wantStatus: StatusFail,
+		wantMessage: "unexpected CRDs of FluxCD: helmreleases.helm.toolkit.fluxcd.io does not serve v2beta1, " +
+			"kustomizations.kustomize.toolkit.fluxcd.io is missing",
+	}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := fake.NewClientBuilder().WithScheme(schema).WithRuntimeObjects(tt.objects...).Build()
+			var checker *CRDChecker
+			if tt.argo != nil {
+				checker = NewArgoCDChecker(c, tt.argo)
+			} else {
+				checker = NewFluxCDChecker(c, tt.flux)
+			}
+			result := checker.Check(context.Background())
+			assert.Equal(t, tt.wantStatus, result.Status, result.Message)
+			if tt.wantMessage != "" {
+				assert.Equal(t, tt.wantMessage, result.Message)
+			}
+		})
+	}
+}
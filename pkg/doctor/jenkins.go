@@ -0,0 +1,238 @@
+/*
+Copyright 2022 The KubeSphere Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package doctor
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/jenkins-zh/jenkins-client/pkg/k8s"
+	"github.com/kubesphere/ks-devops/pkg/client/devops/jenkins"
+	"github.com/kubesphere/ks-devops/pkg/config"
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/yaml"
+)
+
+// DefaultRequiredPlugins are the Jenkins plugins which DevOps depends on
+var DefaultRequiredPlugins = []string{
+	"configuration-as-code",
+	"kubernetes",
+	"git",
+	"workflow-multibranch",
+	"pipeline-model-definition",
+	"blueocean-rest",
+	"generic-webhook-trigger",
+}
+
+// JenkinsChecker checks the connection, the version and the plugins of Jenkins
+type JenkinsChecker struct {
+	Options         *jenkins.Options
+	RequiredPlugins []string
+	HTTPClient      *http.Client
+}
+
+type jenkinsPlugins struct {
+	Plugins []struct {
+		ShortName string `json:"shortName"`
+		Version   string `json:"version"`
+		Active    bool   `json:"active"`
+		Enabled   bool   `json:"enabled"`
+	} `json:"plugins"`
+}
+
+// Check checks Jenkins
+func (c *JenkinsChecker) Check(ctx context.Context) (result Result) {
+	const name = "Jenkins"
+	if c.Options == nil || c.Options.Host == "" {
+		return newResult(name, StatusSkip, "", "Jenkins is not configured")
+	}
+
+	resp, err := c.get(ctx, "/api/json")
+	if err != nil {
+		return newResult(name, StatusFail, "make sure Jenkins is running, and devops.host is reachable",
+			"failed to connect to %s, error: %v", c.Options.Host, err)
+	}
+	_ = resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden:
+		return newResult(name, StatusFail,
+			"check devops.username and devops.apiToken, or the environment variable "+config.ENV_JENKINS_ADMIN_TOKEN,
+			"the credential of user %q is rejected by Jenkins", c.Options.Username)
+	case resp.StatusCode != http.StatusOK:
+		return newResult(name, StatusFail, "make sure devops.host is the address of Jenkins",
+			"unexpected status code %d from %s", resp.StatusCode, c.Options.Host)
+	}
+	version := resp.Header.Get("X-Jenkins")
+	if version == "" {
+		return newResult(name, StatusWarn, "make sure devops.host is the address of Jenkins",
+			"%s does not look like a Jenkins server", c.Options.Host)
+	}
+
+	plugins := &jenkinsPlugins{}
+	if resp, err = c.get(ctx, "/pluginManager/api/json?depth=1"); err == nil {
+		defer func() {
+			_ = resp.Body.Close()
+		}()
+		err = json.NewDecoder(resp.Body).Decode(plugins)
+	}
+	if err != nil {
+		return newResult(name, StatusFail, "make sure the user has the permission to read the plugins",
+			"failed to get the plugins of Jenkins %s, error: %v", version, err)
+	}
+
+	installed := map[string]bool{}
+	for _, plugin := range plugins.Plugins {
+		installed[plugin.ShortName] = plugin.Active && plugin.Enabled
+	}
+	var missing []string
+	for _, plugin := range c.getRequiredPlugins() {
+		if !installed[plugin] {
+			missing = append(missing, plugin)
+		}
+	}
+	if len(missing) > 0 {
+		sort.Strings(missing)
+		return newResult(name, StatusFail, "install or enable them via the Jenkins plugin manager",
+			"Jenkins %s lacks the required plugins: %s", version, strings.Join(missing, ", "))
+	}
+	return newResult(name, StatusPass, "", "Jenkins %s with all the required plugins", version)
+}
+
+func (c *JenkinsChecker) getRequiredPlugins() []string {
+	if c.RequiredPlugins == nil {
+		return DefaultRequiredPlugins
+	}
+	return c.RequiredPlugins
+}
+
+func (c *JenkinsChecker) get(ctx context.Context, api string) (resp *http.Response, err error) {
+	var req *http.Request
+	if req, err = http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimSuffix(c.Options.Host, "/")+api, nil); err != nil {
+		return
+	}
+	password := c.Options.Password
+	if password == "" {
+		// use apiToken if without password
+		password = c.Options.ApiToken
+	}
+	req.SetBasicAuth(c.Options.Username, password)
+	resp, err = getHTTPClient(c.HTTPClient).Do(req)
+	return
+}
+
+// CasCChecker checks if the Jenkins CasC ConfigMap contains all the PodTemplates
+type CasCChecker struct {
+	Client        client.Client
+	Namespace     string
+	ConfigMapName string
+	ConfigMapKey  string
+	LabelSelector string
+}
+
+// Check checks the PodTemplates in the Jenkins CasC
+func (c *CasCChecker) Check(ctx context.Context) (result Result) {
+	const name = "Jenkins CasC"
+	const suggestion = "make sure the pod-template controller is running, it syncs the PodTemplates into the CasC periodically"
+	key := types.NamespacedName{Namespace: c.Namespace, Name: c.ConfigMapName}
+
+	cm := &v1.ConfigMap{}
+	if err := c.Client.Get(ctx, key, cm); err != nil {
+		if apierrors.IsNotFound(err) {
+			return newResult(name, StatusFail, "reinstall DevOps to recreate it", "ConfigMap %s not found", key)
+		}
+		return newResult(name, StatusFail, "", "failed to get ConfigMap %s, error: %v", key, err)
+	}
+
+	templates, err := getCasCPodTemplates([]byte(cm.Data[c.ConfigMapKey]))
+	if err != nil {
+		return newResult(name, StatusFail, "correct the Jenkins CasC", "invalid %s in ConfigMap %s, error: %v",
+			c.ConfigMapKey, key, err)
+	}
+
+	podTemplates := &v1.PodTemplateList{}
+	if err = c.Client.List(ctx, podTemplates, client.HasLabels{c.LabelSelector}); err != nil {
+		return newResult(name, StatusFail, "", "failed to list the PodTemplates, error: %v", err)
+	}
+
+	var drifted []string
+	for i := range podTemplates.Items {
+		podTemplate := &podTemplates.Items[i]
+		var expected map[string]interface{}
+		if expected, err = convertPodTemplate(podTemplate); err != nil {
+			return newResult(name, StatusFail, "", "failed to convert PodTemplate %s, error: %v", podTemplate.Name, err)
+		}
+		if !reflect.DeepEqual(expected, templates[podTemplate.Name]) {
+			drifted = append(drifted, podTemplate.Name)
+		}
+	}
+	if len(drifted) > 0 {
+		return newResult(name, StatusFail, suggestion, "the PodTemplates are different from the CasC: %s",
+			strings.Join(drifted, ", "))
+	}
+	return newResult(name, StatusPass, "", "all the %d PodTemplates are in the CasC", len(podTemplates.Items))
+}
+
+// getCasCPodTemplates returns the PodTemplates of the first cloud of the Jenkins CasC
+func getCasCPodTemplates(data []byte) (templates map[string]interface{}, err error) {
+	casc := struct {
+		Jenkins struct {
+			Clouds []struct {
+				Kubernetes struct {
+					Templates []map[string]interface{} `json:"templates"`
+				} `json:"kubernetes"`
+			} `json:"clouds"`
+		} `json:"jenkins"`
+	}{}
+	if err = yaml.Unmarshal(data, &casc); err != nil {
+		return
+	}
+	if len(casc.Jenkins.Clouds) == 0 {
+		err = fmt.Errorf("no jenkins.clouds found")
+		return
+	}
+
+	templates = map[string]interface{}{}
+	for _, template := range casc.Jenkins.Clouds[0].Kubernetes.Templates {
+		if name, ok := template["name"].(string); ok {
+			templates[name] = template
+		}
+	}
+	return
+}
+
+// convertPodTemplate converts a PodTemplate in the same way of the pod-template controller
+func convertPodTemplate(podTemplate *v1.PodTemplate) (template map[string]interface{}, err error) {
+	var target k8s.JenkinsPodTemplate
+	if target, err = k8s.ConvertToJenkinsPodTemplate(podTemplate.DeepCopy()); err != nil {
+		return
+	}
+
+	var data []byte
+	if data, err = json.Marshal(target); err == nil {
+		err = json.Unmarshal(data, &template)
+	}
+	return
+}
@@ -0,0 +1,190 @@
+/*
+Copyright 2022 The KubeSphere Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package doctor
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/jenkins-zh/jenkins-client/pkg/k8s"
+	"github.com/kubesphere/ks-devops/pkg/client/devops/jenkins"
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestJenkinsChecker_Check(t *testing.T) {
+	const plugins = `{"plugins":[
+{"shortName":"git","active":true,"enabled":true},
+{"shortName":"kubernetes","active":false,"enabled":true}]}`
+
+	tests := []struct {
+		name            string
+		options         *jenkins.Options
+		requiredPlugins []string
+		handler         http.HandlerFunc
+		wantStatus      Status
+		wantMessage     string
+	}{{
+		name:       "not configured",
+		wantStatus: StatusSkip,
+	}, {
+		name:            "all the plugins are installed",
+		options:         &jenkins.Options{Username: "admin", ApiToken: "token"},
+		requiredPlugins: []string{"git"},
+		wantStatus:      StatusPass,
+		wantMessage:     "Jenkins 2.346 with all the required plugins",
+	}, {
+		name:            "missing plugins",
+		options:         &jenkins.Options{Username: "admin", ApiToken: "token"},
+		requiredPlugins: []string{"git", "kubernetes", "blueocean-rest"},
+		wantStatus:      StatusFail,
+		wantMessage:     "Jenkins 2.346 lacks the required plugins: blueocean-rest, kubernetes",
+	}, {
+		name:        "wrong token",
+		options:     &jenkins.Options{Username: "admin", ApiToken: "wrong"},
+		wantStatus:  StatusFail,
+		wantMessage: `the credential of user "admin" is rejected by Jenkins`,
+	}, {
+		name:    "not a Jenkins server",
+		options: &jenkins.Options{Username: "admin", ApiToken: "token"},
+		handler: func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusOK)
+		},
+		wantStatus: StatusWarn,
+	}, {
+		name:    "unexpected status code",
+		options: &jenkins.Options{Username: "admin", ApiToken: "token"},
+		handler: func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusBadGateway)
+		},
+		wantStatus: StatusFail,
+	}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.handler == nil {
+				tt.handler = func(w http.ResponseWriter, r *http.Request) {
+					if user, token, _ := r.BasicAuth(); user != "admin" || token != "token" {
+						w.WriteHeader(http.StatusUnauthorized)
+						return
+					}
+					w.Header().Set("X-Jenkins", "2.346")
+					if r.URL.Path == "/pluginManager/api/json" {
+						_, _ = w.Write([]byte(plugins))
+					}
+				}
+			}
+			server := httptest.NewServer(tt.handler)
+			defer server.Close()
+			if tt.options != nil {
+				tt.options.Host = server.URL
+			}
+
+			checker := &JenkinsChecker{Options: tt.options, RequiredPlugins: tt.requiredPlugins}
+			result := checker.Check(context.Background())
+			assert.Equal(t, tt.wantStatus, result.Status, result.Message)
+			if tt.wantMessage != "" {
+				assert.Equal(t, tt.wantMessage, result.Message)
+			}
+		})
+	}
+
+	// unreachable Jenkins
+	checker := &JenkinsChecker{Options: &jenkins.Options{Host: "http://127.0.0.1:0"}}
+	assert.Equal(t, StatusFail, checker.Check(context.Background()).Status)
+}
+
+func TestCasCChecker_Check(t *testing.T) {
+	const casc = `jenkins:
+  clouds:
+  - kubernetes:
+      name: kubernetes
+      templates: []
+`
+	podTemplate := &v1.PodTemplate{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: "kubesphere-devops-worker",
+			Name:      "base",
+			Labels:    map[string]string{"jenkins.agent.pod": ""},
+		},
+		Template: v1.PodTemplateSpec{
+			Spec: v1.PodSpec{Containers: []v1.Container{{Name: "base", Image: "builder-base"}}},
+		},
+	}
+	changedPodTemplate := podTemplate.DeepCopy()
+	changedPodTemplate.Template.Spec.Containers[0].Image = "builder-base:v2"
+
+	config := k8s.JenkinsConfig{Config: []byte(casc)}
+	assert.Nil(t, config.AddPodTemplate(podTemplate.DeepCopy()))
+	newConfigMap := func(data string) *v1.ConfigMap {
+		return &v1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "kubesphere-devops-system", Name: "jenkins-casc-config"},
+			Data:       map[string]string{"jenkins_user.yaml": data},
+		}
+	}
+
+	tests := []struct {
+		name        string
+		objects     []runtime.Object
+		wantStatus  Status
+		wantMessage string
+	}{{
+		name:        "ConfigMap not found",
+		wantStatus:  StatusFail,
+		wantMessage: "ConfigMap kubesphere-devops-system/jenkins-casc-config not found",
+	}, {
+		name:       "invalid CasC",
+		objects:    []runtime.Object{newConfigMap("jenkins: {}")},
+		wantStatus: StatusFail,
+	}, {
+		name:        "in sync",
+		objects:     []runtime.Object{newConfigMap(config.GetConfigAsString()), podTemplate.DeepCopy()},
+		wantStatus:  StatusPass,
+		wantMessage: "all the 1 PodTemplates are in the CasC",
+	}, {
+		name:        "missing PodTemplate",
+		objects:     []runtime.Object{newConfigMap(casc), podTemplate.DeepCopy()},
+		wantStatus:  StatusFail,
+		wantMessage: "the PodTemplates are different from the CasC: base",
+	}, {
+		name:        "changed PodTemplate",
+		objects:     []runtime.Object{newConfigMap(config.GetConfigAsString()), changedPodTemplate},
+		wantStatus:  StatusFail,
+		wantMessage: "the PodTemplates are different from the CasC: base",
+	}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			checker := &CasCChecker{
+				Client:        fake.NewClientBuilder().WithScheme(scheme.Scheme).WithRuntimeObjects(tt.objects...).Build(),
+				Namespace:     "kubesphere-devops-system",
+				ConfigMapName: "jenkins-casc-config",
+				ConfigMapKey:  "jenkins_user.yaml",
+				LabelSelector: "jenkins.agent.pod",
+			}
+			result := checker.Check(context.Background())
+			assert.Equal(t, tt.wantStatus, result.Status, result.Message)
+			if tt.wantMessage != "" {
+				assert.Equal(t, tt.wantMessage, result.Message)
+			}
+		})
+	}
+}
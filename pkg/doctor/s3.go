@@ -0,0 +1,64 @@
+/*
+Copyright 2022 The KubeSphere Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package doctor
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/s3"
+	s3client "github.com/kubesphere/ks-devops/pkg/client/s3"
+)
+
+// S3Checker checks if the bucket of S3 is accessible
+type S3Checker struct {
+	Options *s3client.Options
+}
+
+// Check checks the bucket
+func (c *S3Checker) Check(ctx context.Context) (result Result) {
+	const name = "S3"
+	if c.Options == nil || c.Options.Endpoint == "" {
+		return newResult(name, StatusSkip, "", "S3 is not configured")
+	}
+
+	client, err := s3client.NewS3Client(c.Options)
+	if err != nil {
+		return newResult(name, StatusFail, "check the s3 options in the configuration", "failed to create the S3 client, error: %v", err)
+	}
+
+	_, err = client.(*s3client.Client).Client().HeadBucketWithContext(ctx, &s3.HeadBucketInput{
+		Bucket: aws.String(c.Options.Bucket),
+	})
+	if err != nil {
+		if awsErr, ok := err.(awserr.Error); ok {
+			switch awsErr.Code() {
+			case "NotFound", s3.ErrCodeNoSuchBucket:
+				return newResult(name, StatusFail, fmt.Sprintf("create the bucket %q, or correct s3.bucket", c.Options.Bucket),
+					"bucket %q not found in %s", c.Options.Bucket, c.Options.Endpoint)
+			case "Forbidden":
+				return newResult(name, StatusFail, "check s3.accessKeyID and s3.secretAccessKey",
+					"access to bucket %q is denied", c.Options.Bucket)
+			}
+		}
+		return newResult(name, StatusFail, "make sure s3.endpoint is reachable",
+			"failed to access bucket %q in %s, error: %v", c.Options.Bucket, c.Options.Endpoint, err)
+	}
+	return newResult(name, StatusPass, "", "bucket %q is accessible", c.Options.Bucket)
+}
@@ -0,0 +1,89 @@
+/*
+Copyright 2022 The KubeSphere Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package doctor
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/kubesphere/ks-devops/pkg/client/s3"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestS3Checker_Check(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/devops":
+			w.WriteHeader(http.StatusOK)
+		case "/private":
+			w.WriteHeader(http.StatusForbidden)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	newOptions := func(bucket string) *s3.Options {
+		return &s3.Options{
+			Endpoint:        server.URL,
+			Region:          "us-east-1",
+			DisableSSL:      true,
+			ForcePathStyle:  true,
+			AccessKeyID:     "id",
+			SecretAccessKey: "key",
+			Bucket:          bucket,
+		}
+	}
+
+	tests := []struct {
+		name        string
+		options     *s3.Options
+		wantStatus  Status
+		wantMessage string
+	}{{
+		name:       "not configured",
+		options:    s3.NewS3Options(),
+		wantStatus: StatusSkip,
+	}, {
+		name:        "bucket exists",
+		options:     newOptions("devops"),
+		wantStatus:  StatusPass,
+		wantMessage: `bucket "devops" is accessible`,
+	}, {
+		name:        "bucket not found",
+		options:     newOptions("missing"),
+		wantStatus:  StatusFail,
+		wantMessage: `bucket "missing" not found in ` + server.URL,
+	}, {
+		name:        "access denied",
+		options:     newOptions("private"),
+		wantStatus:  StatusFail,
+		wantMessage: `access to bucket "private" is denied`,
+	}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			checker := &S3Checker{Options: tt.options}
+			result := checker.Check(context.Background())
+			assert.Equal(t, tt.wantStatus, result.Status, result.Message)
+			if tt.wantMessage != "" {
+				assert.Equal(t, tt.wantMessage, result.Message)
+			}
+		})
+	}
+}
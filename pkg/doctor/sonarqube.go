@@ -0,0 +1,86 @@
+/*
+Copyright 2022 The KubeSphere Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package doctor
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/kubesphere/ks-devops/pkg/client/sonarqube"
+)
+
+// SonarQubeChecker checks if SonarQube is up and the token is valid
+type SonarQubeChecker struct {
+	Options    *sonarqube.Options
+	HTTPClient *http.Client
+}
+
+// Check checks SonarQube
+func (c *SonarQubeChecker) Check(ctx context.Context) (result Result) {
+	const name = "SonarQube"
+	if c.Options == nil || c.Options.Host == "" {
+		return newResult(name, StatusSkip, "", "SonarQube is not configured")
+	}
+
+	systemStatus := struct {
+		Status  string `json:"status"`
+		Version string `json:"version"`
+	}{}
+	if err := c.getJSON(ctx, "/api/system/status", &systemStatus); err != nil {
+		return newResult(name, StatusFail, "make sure SonarQube is running, and sonarqube.host is reachable",
+			"failed to connect to %s, error: %v", c.Options.Host, err)
+	}
+	if systemStatus.Status != "UP" {
+		return newResult(name, StatusFail, "wait for SonarQube to be ready, or check its logs",
+			"the status of SonarQube is %s", systemStatus.Status)
+	}
+
+	validation := struct {
+		Valid bool `json:"valid"`
+	}{}
+	if err := c.getJSON(ctx, "/api/authentication/validate", &validation); err != nil || !validation.Valid {
+		return newResult(name, StatusFail, "generate a new token in SonarQube, then update sonarqube.token",
+			"the token is rejected by SonarQube %s", systemStatus.Version)
+	}
+	return newResult(name, StatusPass, "", "SonarQube %s is up", systemStatus.Version)
+}
+
+func (c *SonarQubeChecker) getJSON(ctx context.Context, api string, obj interface{}) (err error) {
+	var req *http.Request
+	if req, err = http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimSuffix(c.Options.Host, "/")+api, nil); err != nil {
+		return
+	}
+	// the token of SonarQube is the username without password
+	req.SetBasicAuth(c.Options.Token, "")
+
+	var resp *http.Response
+	if resp, err = getHTTPClient(c.HTTPClient).Do(req); err != nil {
+		return
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+	if resp.StatusCode != http.StatusOK {
+		err = fmt.Errorf("unexpected status code %d", resp.StatusCode)
+		return
+	}
+	err = json.NewDecoder(resp.Body).Decode(obj)
+	return
+}
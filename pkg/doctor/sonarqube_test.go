@@ -0,0 +1,90 @@
+/*
+Copyright 2022 The KubeSphere Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package doctor
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/kubesphere/ks-devops/pkg/client/sonarqube"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSonarQubeChecker_Check(t *testing.T) {
+	status := "UP"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/system/status":
+			_, _ = w.Write([]byte(`{"status":"` + status + `","version":"9.9"}`))
+		case "/api/authentication/validate":
+			token, _, _ := r.BasicAuth()
+			if token == "token" {
+				_, _ = w.Write([]byte(`{"valid":true}`))
+			} else {
+				_, _ = w.Write([]byte(`{"valid":false}`))
+			}
+		}
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name        string
+		options     *sonarqube.Options
+		status      string
+		wantStatus  Status
+		wantMessage string
+	}{{
+		name:       "not configured",
+		wantStatus: StatusSkip,
+	}, {
+		name:        "valid token",
+		options:     &sonarqube.Options{Host: server.URL, Token: "token"},
+		wantStatus:  StatusPass,
+		wantMessage: "SonarQube 9.9 is up",
+	}, {
+		name:        "invalid token",
+		options:     &sonarqube.Options{Host: server.URL + "/", Token: "wrong"},
+		wantStatus:  StatusFail,
+		wantMessage: "the token is rejected by SonarQube 9.9",
+	}, {
+		name:        "not ready",
+		options:     &sonarqube.Options{Host: server.URL, Token: "token"},
+		status:      "STARTING",
+		wantStatus:  StatusFail,
+		wantMessage: "the status of SonarQube is STARTING",
+	}, {
+		name:       "unreachable",
+		options:    &sonarqube.Options{Host: "http://127.0.0.1:0"},
+		wantStatus: StatusFail,
+	}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status = "UP"
+			if tt.status != "" {
+				status = tt.status
+			}
+			checker := &SonarQubeChecker{Options: tt.options}
+			result := checker.Check(context.Background())
+			assert.Equal(t, tt.wantStatus, result.Status, result.Message)
+			if tt.wantMessage != "" {
+				assert.Equal(t, tt.wantMessage, result.Message)
+			}
+		})
+	}
+}
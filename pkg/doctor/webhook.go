@@ -0,0 +1,85 @@
+/*
+Copyright 2022 The KubeSphere Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package doctor
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// DefaultWebhookEndpoint is the default address of the DevOps API server
+const DefaultWebhookEndpoint = "http://devops-apiserver.kubesphere-devops-system:9090"
+
+// webhookPaths are the webhook APIs which receive the events from Jenkins and the SCMs
+var webhookPaths = []string{
+	"/kapis/devops.kubesphere.io/v1alpha3/webhooks/jenkins",
+	"/kapis/devops.kubesphere.io/v1alpha3/webhooks/scm",
+	"/kapis/devops.kubesphere.io/v1alpha2/webhook/github",
+	"/kapis/devops.kubesphere.io/v1alpha2/webhook/generic-trigger",
+}
+
+// WebhookChecker checks if the webhook endpoints of the DevOps API server are reachable
+type WebhookChecker struct {
+	Endpoint   string
+	HTTPClient *http.Client
+}
+
+// Check checks the webhook endpoints. The webhooks only accept POST requests, so a GET request without side effects
+// is used. A 405 status code means the route exists.
+func (c *WebhookChecker) Check(ctx context.Context) (result Result) {
+	const name = "Webhooks"
+	if c.Endpoint == "" {
+		return newResult(name, StatusSkip, "", "the webhook endpoint is not specified")
+	}
+
+	var unreachable, protected []string
+	for _, path := range webhookPaths {
+		address := strings.TrimSuffix(c.Endpoint, "/") + path
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, address, nil)
+		if err != nil {
+			return newResult(name, StatusFail, "correct the webhook endpoint", "invalid webhook endpoint %s", c.Endpoint)
+		}
+
+		var resp *http.Response
+		if resp, err = getHTTPClient(c.HTTPClient).Do(req); err != nil {
+			unreachable = append(unreachable, fmt.Sprintf("%s (%v)", path, err))
+			continue
+		}
+		_ = resp.Body.Close()
+
+		switch {
+		case resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden:
+			protected = append(protected, path)
+		case resp.StatusCode == http.StatusNotFound || resp.StatusCode >= http.StatusInternalServerError:
+			unreachable = append(unreachable, fmt.Sprintf("%s (%d)", path, resp.StatusCode))
+		}
+	}
+
+	if len(unreachable) > 0 {
+		sort.Strings(unreachable)
+		return newResult(name, StatusFail, "make sure the DevOps API server is running, and the endpoint is correct",
+			"unreachable webhooks: %s", strings.Join(unreachable, ", "))
+	}
+	if len(protected) > 0 {
+		return newResult(name, StatusWarn, "allow the anonymous access to the webhooks in the gateway",
+			"the webhooks require authentication, the events might be rejected: %s", strings.Join(protected, ", "))
+	}
+	return newResult(name, StatusPass, "", "all the webhooks of %s are reachable", c.Endpoint)
+}
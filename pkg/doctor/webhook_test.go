@@ -0,0 +1,85 @@
+/*
+Copyright 2022 The KubeSphere Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package doctor
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWebhookChecker_Check(t *testing.T) {
+	tests := []struct {
+		name        string
+		handler     http.HandlerFunc
+		endpoint    string
+		wantStatus  Status
+		wantMessage string
+	}{{
+		name:       "no endpoint",
+		wantStatus: StatusSkip,
+	}, {
+		name: "reachable",
+		handler: func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		},
+		wantStatus: StatusPass,
+	}, {
+		name: "some webhooks are missing",
+		handler: func(w http.ResponseWriter, r *http.Request) {
+			if strings.Contains(r.URL.Path, "v1alpha3") {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		},
+		wantStatus: StatusFail,
+		wantMessage: "unreachable webhooks: /kapis/devops.kubesphere.io/v1alpha3/webhooks/jenkins (404), " +
+			"/kapis/devops.kubesphere.io/v1alpha3/webhooks/scm (404)",
+	}, {
+		name: "require authentication",
+		handler: func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusUnauthorized)
+		},
+		wantStatus: StatusWarn,
+	}, {
+		name:       "unreachable",
+		endpoint:   "http://127.0.0.1:0",
+		wantStatus: StatusFail,
+	}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			endpoint := tt.endpoint
+			if tt.handler != nil {
+				server := httptest.NewServer(tt.handler)
+				defer server.Close()
+				endpoint = server.URL + "/"
+			}
+
+			checker := &WebhookChecker{Endpoint: endpoint}
+			result := checker.Check(context.Background())
+			assert.Equal(t, tt.wantStatus, result.Status, result.Message)
+			if tt.wantMessage != "" {
+				assert.Equal(t, tt.wantMessage, result.Message)
+			}
+		})
+	}
+}
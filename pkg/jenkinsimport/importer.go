@@ -0,0 +1,258 @@
+/*
+Copyright 2022 The KubeSphere Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package jenkinsimport adopts the existing Jenkins jobs by creating the matching DevOpsProject and Pipeline objects
+package jenkinsimport
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/kubesphere/ks-devops/pkg/api/devops/v1alpha3"
+	"github.com/kubesphere/ks-devops/pkg/client/devops/jenkins"
+	"github.com/kubesphere/ks-devops/pkg/constants"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// PipelineImportedFromAnnoKey is the annotation key of the Jenkins job which a Pipeline is imported from
+	PipelineImportedFromAnnoKey = v1alpha3.PipelinePrefix + "imported-from"
+
+	// DefaultNamespaceTimeout is the default timeout of waiting for the namespace of a DevOps project
+	DefaultNamespaceTimeout = 2 * time.Minute
+)
+
+// Action represents what the importer does with a Jenkins job
+type Action string
+
+const (
+	// ActionCreate means the Pipeline is created, or going to be created in the dry-run mode
+	ActionCreate Action = "create"
+	// ActionSkip means the job is skipped, see the message for the reason
+	ActionSkip Action = "skip"
+	// ActionUnsupported means the job cannot be converted to a Pipeline
+	ActionUnsupported Action = "unsupported"
+)
+
+// JobSource provides the jobs of Jenkins, it's implemented by *jenkins.Jenkins
+type JobSource interface {
+	ListJobs(folder string) ([]jenkins.JobSummary, error)
+	GetJobConfig(folder, name string) (string, error)
+}
+
+// Options are the options of importing a Jenkins folder
+type Options struct {
+	// Folder is the Jenkins folder which contains the jobs, it could be nested, such as "parent/child"
+	Folder string
+	// Namespace is the namespace of the DevOps project which the Pipelines are created in
+	Namespace string
+	// DryRun only reports what is going to be done
+	DryRun bool
+	// Force imports the jobs even if they have unsupported settings, those settings will be lost
+	// once the Pipelines are synchronized to Jenkins
+	Force bool
+}
+
+// JobResult is the import result of a Jenkins job
+type JobResult struct {
+	Name        string   `json:"name"`
+	Type        string   `json:"type,omitempty"`
+	Action      Action   `json:"action"`
+	Unsupported []string `json:"unsupported,omitempty"`
+	Message     string   `json:"message,omitempty"`
+}
+
+// Report is the result of importing a Jenkins folder
+type Report struct {
+	Folder string      `json:"folder"`
+	DryRun bool        `json:"dryRun,omitempty"`
+	Jobs   []JobResult `json:"jobs"`
+}
+
+// Count returns the number of jobs with the specific action
+func (r *Report) Count(action Action) (count int) {
+	for _, job := range r.Jobs {
+		if job.Action == action {
+			count++
+		}
+	}
+	return
+}
+
+// Print prints the report as a table
+func (r *Report) Print(writer io.Writer) {
+	w := tabwriter.NewWriter(writer, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "JOB\tTYPE\tACTION\tMESSAGE")
+	for _, job := range r.Jobs {
+		_, _ = fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", job.Name, job.Type, job.Action, job.Message)
+		for _, item := range job.Unsupported {
+			_, _ = fmt.Fprintf(w, "\t\t\t- unsupported %s\n", item)
+		}
+	}
+	_ = w.Flush()
+
+	mode := ""
+	if r.DryRun {
+		mode = " (dry run)"
+	}
+	_, _ = fmt.Fprintf(writer, "\n%d to create, %d skipped, %d unsupported%s\n",
+		r.Count(ActionCreate), r.Count(ActionSkip), r.Count(ActionUnsupported), mode)
+}
+
+// Importer converts the jobs of a Jenkins folder to Pipelines
+type Importer struct {
+	Client client.Client
+	Source JobSource
+}
+
+// Import creates the Pipelines of the jobs in a Jenkins folder. The existing Pipelines are never changed.
+func (i *Importer) Import(ctx context.Context, opts Options) (report *Report, err error) {
+	if opts.Folder == "" || opts.Namespace == "" {
+		err = fmt.Errorf("both the folder and namespace are required")
+		return
+	}
+
+	var jobs []jenkins.JobSummary
+	if jobs, err = i.Source.ListJobs(opts.Folder); err != nil {
+		err = fmt.Errorf("failed to list the jobs of Jenkins folder %s, error: %v", opts.Folder, err)
+		return
+	}
+
+	report = &Report{Folder: opts.Folder, DryRun: opts.DryRun, Jobs: make([]JobResult, 0, len(jobs))}
+	for _, job := range jobs {
+		var result JobResult
+		if result, err = i.importJob(ctx, job, opts); err != nil {
+			err = fmt.Errorf("failed to import job %s, error: %v", job.Name, err)
+			return
+		}
+		report.Jobs = append(report.Jobs, result)
+	}
+	return
+}
+
+func (i *Importer) importJob(ctx context.Context, job jenkins.JobSummary, opts Options) (result JobResult, err error) {
+	result = JobResult{Name: job.Name}
+	switch job.Class {
+	case jenkins.WorkflowJobClass:
+		result.Type = string(v1alpha3.NoScmPipelineType)
+	case jenkins.MultiBranchProjectClass:
+		result.Type = string(v1alpha3.MultiBranchPipelineType)
+	case jenkins.FolderClass:
+		result.Action = ActionUnsupported
+		result.Message = "nested folders are not supported, import it as another DevOps project"
+		return
+	default:
+		result.Action = ActionUnsupported
+		result.Message = fmt.Sprintf("job class %s is not supported", job.Class)
+		return
+	}
+
+	// the name of a Pipeline is the name of its Jenkins job, so it must be a valid name of Kubernetes
+	if errs := validation.IsDNS1123Subdomain(job.Name); len(errs) > 0 {
+		result.Action = ActionUnsupported
+		result.Message = fmt.Sprintf("invalid Pipeline name: %s", strings.Join(errs, ", "))
+		return
+	}
+
+	if err = i.Client.Get(ctx, types.NamespacedName{Namespace: opts.Namespace, Name: job.Name},
+		&v1alpha3.Pipeline{}); err == nil {
+		result.Action = ActionSkip
+		result.Message = "the Pipeline already exists"
+		return
+	} else if !apierrors.IsNotFound(err) {
+		return
+	}
+	err = nil
+
+	var config string
+	if config, err = i.Source.GetJobConfig(opts.Folder, job.Name); err != nil {
+		return
+	}
+
+	var pipeline *v1alpha3.Pipeline
+	if pipeline, result.Unsupported, err = jenkins.ParseJobConfig(job.Class, job.Name, config); err != nil {
+		result.Action = ActionUnsupported
+		result.Message = fmt.Sprintf("failed to parse the job configuration: %v", err)
+		err = nil
+		return
+	}
+
+	if len(result.Unsupported) > 0 && !opts.Force {
+		result.Action = ActionSkip
+		result.Message = "the unsupported settings would be lost, import it with the force option to accept it"
+		return
+	}
+
+	result.Action = ActionCreate
+	if opts.DryRun {
+		return
+	}
+
+	pipeline.Namespace = opts.Namespace
+	pipeline.Annotations = map[string]string{
+		PipelineImportedFromAnnoKey: path.Join(strings.Trim(opts.Folder, "/"), job.Name),
+	}
+	if err = i.Client.Create(ctx, pipeline); apierrors.IsAlreadyExists(err) {
+		result.Action = ActionSkip
+		result.Message = "the Pipeline already exists"
+		err = nil
+	}
+	return
+}
+
+// EnsureProject creates the DevOps project if it does not exist, then waits for its namespace.
+// The name of a DevOps project is the name of its namespace, and the name of its Jenkins folder as well.
+func EnsureProject(ctx context.Context, c client.Client, name, workspace string, timeout time.Duration) (err error) {
+	project := &v1alpha3.DevOpsProject{}
+	if err = c.Get(ctx, types.NamespacedName{Name: name}, project); apierrors.IsNotFound(err) {
+		project = &v1alpha3.DevOpsProject{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: name,
+			},
+		}
+		if workspace != "" {
+			project.Labels = map[string]string{constants.WorkspaceLabelKey: workspace}
+		}
+		err = c.Create(ctx, project)
+	}
+	if err != nil {
+		return
+	}
+
+	if timeout <= 0 {
+		timeout = DefaultNamespaceTimeout
+	}
+	// the namespace is created by the DevOps project controller
+	err = wait.PollUntilContextTimeout(ctx, time.Second, timeout, true, func(ctx context.Context) (bool, error) {
+		getErr := c.Get(ctx, types.NamespacedName{Name: name}, &corev1.Namespace{})
+		return getErr == nil, client.IgnoreNotFound(getErr)
+	})
+	if err != nil {
+		err = fmt.Errorf("failed to wait for the namespace %s, error: %v", name, err)
+	}
+	return
+}
@@ -0,0 +1,259 @@
+/*
+Copyright 2022 The KubeSphere Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package jenkinsimport
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/kubesphere/ks-devops/pkg/api/devops/v1alpha3"
+	"github.com/kubesphere/ks-devops/pkg/client/devops/jenkins"
+	"github.com/kubesphere/ks-devops/pkg/constants"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+const pipelineConfig = `<?xml version='1.1' encoding='UTF-8'?>
+<flow-definition plugin="workflow-job">
+  <description>imported</description>
+  <keepDependencies>false</keepDependencies>
+  <properties/>
+  <definition class="org.jenkinsci.plugins.workflow.cps.CpsFlowDefinition" plugin="workflow-cps">
+    <script>node{echo 'hello'}</script>
+    <sandbox>true</sandbox>
+  </definition>
+  <triggers/>
+  <disabled>false</disabled>
+</flow-definition>`
+
+const scmPipelineConfig = `<?xml version='1.1' encoding='UTF-8'?>
+<flow-definition plugin="workflow-job">
+  <keepDependencies>false</keepDependencies>
+  <properties/>
+  <definition class="org.jenkinsci.plugins.workflow.cps.CpsScmFlowDefinition" plugin="workflow-cps">
+    <scriptPath>Jenkinsfile</scriptPath>
+  </definition>
+  <triggers/>
+  <disabled>false</disabled>
+</flow-definition>`
+
+type fakeSource struct {
+	jobs    []jenkins.JobSummary
+	configs map[string]string
+	err     error
+}
+
+func (f *fakeSource) ListJobs(folder string) ([]jenkins.JobSummary, error) {
+	return f.jobs, f.err
+}
+
+func (f *fakeSource) GetJobConfig(folder, name string) (config string, err error) {
+	var ok bool
+	if config, ok = f.configs[folder+"/"+name]; !ok {
+		err = errors.New("not found")
+	}
+	return
+}
+
+func TestImporter_Import(t *testing.T) {
+	schema, err := v1alpha3.SchemeBuilder.Register().Build()
+	assert.Nil(t, err)
+
+	source := &fakeSource{
+		jobs: []jenkins.JobSummary{
+			{Name: "build", Class: jenkins.WorkflowJobClass},
+			{Name: "scm", Class: jenkins.WorkflowJobClass},
+			{Name: "existing", Class: jenkins.WorkflowJobClass},
+			{Name: "Invalid_Name", Class: jenkins.WorkflowJobClass},
+			{Name: "nested", Class: jenkins.FolderClass},
+			{Name: "freestyle", Class: "hudson.model.FreeStyleProject"},
+		},
+		configs: map[string]string{
+			"team/build": pipelineConfig,
+			"team/scm":   scmPipelineConfig,
+		},
+	}
+	existing := &v1alpha3.Pipeline{ObjectMeta: metav1.ObjectMeta{Namespace: "team", Name: "existing"}}
+
+	getPipeline := func(c client.Client, name string) (pipeline *v1alpha3.Pipeline, err error) {
+		pipeline = &v1alpha3.Pipeline{}
+		err = c.Get(context.Background(), types.NamespacedName{Namespace: "team", Name: name}, pipeline)
+		return
+	}
+
+	tests := []struct {
+		name        string
+		source      *fakeSource
+		opts        Options
+		wantActions map[string]Action
+		wantErr     bool
+		verify      func(*testing.T, client.Client)
+	}{{
+		name:    "missing folder",
+		source:  source,
+		opts:    Options{Namespace: "team"},
+		wantErr: true,
+	}, {
+		name:    "failed to list the jobs",
+		source:  &fakeSource{err: errors.New("fake")},
+		opts:    Options{Folder: "team", Namespace: "team"},
+		wantErr: true,
+	}, {
+		name:   "dry run",
+		source: source,
+		opts:   Options{Folder: "team", Namespace: "team", DryRun: true},
+		wantActions: map[string]Action{
+			"build":        ActionCreate,
+			"scm":          ActionSkip,
+			"existing":     ActionSkip,
+			"Invalid_Name": ActionUnsupported,
+			"nested":       ActionUnsupported,
+			"freestyle":    ActionUnsupported,
+		},
+		verify: func(t *testing.T, c client.Client) {
+			_, err := getPipeline(c, "build")
+			assert.True(t, client.IgnoreNotFound(err) == nil && err != nil)
+		},
+	}, {
+		name:   "import the supported jobs",
+		source: source,
+		opts:   Options{Folder: "team", Namespace: "team"},
+		wantActions: map[string]Action{
+			"build":        ActionCreate,
+			"scm":          ActionSkip,
+			"existing":     ActionSkip,
+			"Invalid_Name": ActionUnsupported,
+			"nested":       ActionUnsupported,
+			"freestyle":    ActionUnsupported,
+		},
+		verify: func(t *testing.T, c client.Client) {
+			pipeline, err := getPipeline(c, "build")
+			assert.Nil(t, err)
+			assert.Equal(t, "team/build", pipeline.Annotations[PipelineImportedFromAnnoKey])
+			assert.Equal(t, v1alpha3.NoScmPipelineType, pipeline.Spec.Type)
+			if assert.NotNil(t, pipeline.Spec.Pipeline) {
+				assert.Equal(t, "node{echo 'hello'}", pipeline.Spec.Pipeline.Jenkinsfile)
+			}
+
+			_, err = getPipeline(c, "scm")
+			assert.True(t, client.IgnoreNotFound(err) == nil && err != nil)
+		},
+	}, {
+		name:   "force to import the jobs with unsupported settings",
+		source: source,
+		opts:   Options{Folder: "team", Namespace: "team", Force: true},
+		wantActions: map[string]Action{
+			"build":        ActionCreate,
+			"scm":          ActionCreate,
+			"existing":     ActionSkip,
+			"Invalid_Name": ActionUnsupported,
+			"nested":       ActionUnsupported,
+			"freestyle":    ActionUnsupported,
+		},
+		verify: func(t *testing.T, c client.Client) {
+			_, err := getPipeline(c, "scm")
+			assert.Nil(t, err)
+		},
+	}, {
+		name: "failed to get the job config",
+		source: &fakeSource{
+			jobs: []jenkins.JobSummary{{Name: "missing", Class: jenkins.WorkflowJobClass}},
+		},
+		opts:    Options{Folder: "team", Namespace: "team"},
+		wantErr: true,
+	}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := fake.NewClientBuilder().WithScheme(schema).WithObjects(existing.DeepCopy()).Build()
+			importer := &Importer{Client: c, Source: tt.source}
+			report, err := importer.Import(context.Background(), tt.opts)
+			assert.Equal(t, tt.wantErr, err != nil, err)
+			if tt.wantActions != nil && assert.NotNil(t, report) {
+				actions := map[string]Action{}
+				for _, job := range report.Jobs {
+					actions[job.Name] = job.Action
+				}
+				assert.Equal(t, tt.wantActions, actions)
+				assert.Equal(t, tt.opts.DryRun, report.DryRun)
+			}
+			if tt.verify != nil {
+				tt.verify(t, c)
+			}
+		})
+	}
+}
+
+func TestReport_Print(t *testing.T) {
+	report := &Report{
+		Folder: "team",
+		DryRun: true,
+		Jobs: []JobResult{
+			{Name: "build", Type: "pipeline", Action: ActionCreate},
+			{Name: "scm", Type: "pipeline", Action: ActionSkip, Unsupported: []string{"setting quietPeriod"}},
+		},
+	}
+	buf := &bytes.Buffer{}
+	report.Print(buf)
+	assert.Contains(t, buf.String(), "- unsupported setting quietPeriod")
+	assert.Contains(t, buf.String(), "1 to create, 1 skipped, 0 unsupported (dry run)")
+}
+
+func TestEnsureProject(t *testing.T) {
+	schema, err := v1alpha3.SchemeBuilder.Register().Build()
+	assert.Nil(t, err)
+	assert.Nil(t, corev1.AddToScheme(schema))
+
+	namespace := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "team"}}
+
+	tests := []struct {
+		name    string
+		objects []runtime.Object
+		wantErr bool
+	}{{
+		name:    "create the project",
+		objects: []runtime.Object{namespace.DeepCopy()},
+	}, {
+		name: "the project exists",
+		objects: []runtime.Object{namespace.DeepCopy(), &v1alpha3.DevOpsProject{
+			ObjectMeta: metav1.ObjectMeta{Name: "team"},
+		}},
+	}, {
+		name:    "the namespace is not ready",
+		wantErr: true,
+	}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := fake.NewClientBuilder().WithScheme(schema).WithRuntimeObjects(tt.objects...).Build()
+			err := EnsureProject(context.Background(), c, "team", "ws", time.Millisecond*10)
+			assert.Equal(t, tt.wantErr, err != nil, err)
+
+			project := &v1alpha3.DevOpsProject{}
+			assert.Nil(t, c.Get(context.Background(), types.NamespacedName{Name: "team"}, project))
+			if len(tt.objects) < 2 {
+				assert.Equal(t, "ws", project.Labels[constants.WorkspaceLabelKey])
+			}
+		})
+	}
+}
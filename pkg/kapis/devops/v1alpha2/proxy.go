@@ -19,10 +19,12 @@ package v1alpha2
 import (
 	"fmt"
 	"net/http"
+	"net/url"
 	"strings"
 
 	"github.com/emicklei/go-restful/v3"
 	"github.com/jenkins-zh/jenkins-client/pkg/core"
+	"github.com/kubesphere/ks-devops/pkg/client/devops/sharding"
 	"k8s.io/apimachinery/pkg/util/proxy"
 	"k8s.io/klog/v2"
 )
@@ -32,6 +34,8 @@ type jenkinsProxy struct {
 	host         string
 	scheme       string
 	roundTripper http.RoundTripper
+	// cores returns the Jenkins core of a DevOps project if there are multiple Jenkins instances
+	cores sharding.CoreGetter
 }
 
 func newJenkinsProxy(client core.JenkinsCore, host, scheme string, roundTripper http.RoundTripper) *jenkinsProxy {
@@ -49,15 +53,22 @@ func newJenkinsProxy(client core.JenkinsCore, host, scheme string, roundTripper
 func (p *jenkinsProxy) proxyWithDevOps(request *restful.Request, response *restful.Response) {
 	u := request.Request.URL
 	devopsPath := request.PathParameter("devops")
-	u.Host = p.host
-	u.Scheme = p.scheme
+	jenkinsCore, host, scheme, err := p.getJenkins(devopsPath)
+	if err != nil {
+		msg := "failed to find the Jenkins instance of the DevOps project"
+		klog.V(4).Infof("%s, error: %v", msg, err)
+		_ = response.WriteErrorString(http.StatusServiceUnavailable, msg)
+		return
+	}
+	u.Host = host
+	u.Scheme = scheme
 	u.Path = strings.Replace(request.Request.URL.Path, fmt.Sprintf("/kapis/%s/%s/namespaces/%s/jenkins",
 		GroupVersion.Group, GroupVersion.Version, devopsPath), "", 1)
 	u.Path = strings.Replace(u.Path, fmt.Sprintf("/%s/namespaces/%s/jenkins",
 		GroupVersion.Version, devopsPath), "", 1)
 	httpProxy := proxy.NewUpgradeAwareHandler(u, p.roundTripper, false, false, &errorResponder{})
 
-	if err := p.client.AuthHandle(request.Request); err != nil {
+	if err := jenkinsCore.AuthHandle(request.Request); err != nil {
 		msg := "failed to set auth header for Jenkins API request"
 		klog.V(4).Infof("%s, error: %v", msg, err)
 		_, _ = response.Write([]byte(msg))
@@ -65,3 +76,19 @@ func (p *jenkinsProxy) proxyWithDevOps(request *restful.Request, response *restf
 	}
 	httpProxy.ServeHTTP(response, request.Request)
 }
+
+// getJenkins returns the Jenkins core and address of a DevOps project
+func (p *jenkinsProxy) getJenkins(namespace string) (jenkinsCore core.JenkinsCore, host, scheme string, err error) {
+	jenkinsCore, host, scheme = p.client, p.host, p.scheme
+	if p.cores == nil {
+		return
+	}
+	if jenkinsCore, err = p.cores.GetJenkinsCore(namespace); err != nil {
+		return
+	}
+	var jenkinsURL *url.URL
+	if jenkinsURL, err = url.Parse(jenkinsCore.URL); err == nil {
+		host, scheme = jenkinsURL.Host, jenkinsURL.Scheme
+	}
+	return
+}
@@ -108,3 +108,36 @@ func (r *fakeHTTPResponse) WriteHeader(statusCode int) {
 func (r *fakeHTTPResponse) CloseNotify() <-chan bool {
 	return nil
 }
+
+type fakeCoreGetter struct {
+	cores map[string]core.JenkinsCore
+}
+
+func (f *fakeCoreGetter) GetJenkinsCore(namespace string) (jenkinsCore core.JenkinsCore, err error) {
+	var ok bool
+	if jenkinsCore, ok = f.cores[namespace]; !ok {
+		err = fmt.Errorf("not placed")
+	}
+	return
+}
+
+func TestJenkinsProxy_getJenkins(t *testing.T) {
+	proxy := newJenkinsProxy(core.JenkinsCore{URL: "http://fake.com"}, "fake.com", "http", nil)
+	jenkinsCore, host, scheme, err := proxy.getJenkins("team")
+	assert.Nil(t, err)
+	assert.Equal(t, "http://fake.com", jenkinsCore.URL)
+	assert.Equal(t, "fake.com", host)
+	assert.Equal(t, "http", scheme)
+
+	proxy.cores = &fakeCoreGetter{cores: map[string]core.JenkinsCore{
+		"team": {URL: "https://jenkins-b.com:8443"},
+	}}
+	jenkinsCore, host, scheme, err = proxy.getJenkins("team")
+	assert.Nil(t, err)
+	assert.Equal(t, "https://jenkins-b.com:8443", jenkinsCore.URL)
+	assert.Equal(t, "jenkins-b.com:8443", host)
+	assert.Equal(t, "https", scheme)
+
+	_, _, _, err = proxy.getJenkins("other")
+	assert.NotNil(t, err)
+}
@@ -36,6 +36,7 @@ import (
 	"github.com/kubesphere/ks-devops/pkg/apiserver/runtime"
 	"github.com/kubesphere/ks-devops/pkg/client/clientset/versioned"
 	"github.com/kubesphere/ks-devops/pkg/client/devops"
+	"github.com/kubesphere/ks-devops/pkg/client/devops/sharding"
 	"github.com/kubesphere/ks-devops/pkg/client/informers/externalversions"
 	"github.com/kubesphere/ks-devops/pkg/client/k8s"
 	"github.com/kubesphere/ks-devops/pkg/client/s3"
@@ -690,6 +691,8 @@ func addJenkinsToContainer(webservice *restful.WebService, devopsClient devops.I
 		Metadata(restfulspec.KeyOpenAPITags, constants.DevOpsJenkinsTags))
 
 	jenkinsProxy := newJenkinsProxy(jenkinsClient, parse.Host, parse.Scheme, nil)
+	// the DevOps projects are placed among multiple Jenkins instances
+	jenkinsProxy.cores, _ = devopsClient.(sharding.CoreGetter)
 	// some Jenkins API against with POST method
 	webservice.Route(webservice.GET("/namespaces/{devops}/jenkins/{path:*}").
 		Param(webservice.PathParameter("path", "Path stands for any suffix path.")).
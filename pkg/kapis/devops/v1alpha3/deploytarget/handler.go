@@ -0,0 +1,95 @@
+/*
+Copyright 2022 The KubeSphere Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package deploytarget
+
+import (
+	"github.com/emicklei/go-restful/v3"
+	"github.com/kubesphere/ks-devops/pkg/api/devops/v1alpha3"
+	"github.com/kubesphere/ks-devops/pkg/kapis/common"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Cluster represents a KubeSphere cluster which the Pipelines are able to deploy to
+type Cluster struct {
+	Name  string `json:"name"`
+	Host  bool   `json:"host"`
+	Ready bool   `json:"ready"`
+}
+
+// DeployTarget is a deployment target of a Pipeline with the name of its credential
+type DeployTarget struct {
+	v1alpha3.DeployTarget `json:",inline"`
+	Credential            string `json:"credential"`
+}
+
+type handler struct {
+	client.Client
+}
+
+func (h *handler) listClusters(req *restful.Request, resp *restful.Response) {
+	clusterList := &unstructured.UnstructuredList{}
+	clusterList.SetGroupVersionKind(schema.GroupVersionKind{
+		Group:   "cluster.kubesphere.io",
+		Version: "v1alpha1",
+		Kind:    "ClusterList",
+	})
+
+	clusters := make([]Cluster, 0)
+	err := h.List(req.Request.Context(), clusterList)
+	if err == nil {
+		for i := range clusterList.Items {
+			clusters = append(clusters, convertCluster(&clusterList.Items[i]))
+		}
+	}
+	common.Response(req, resp, clusters, err)
+}
+
+func convertCluster(object *unstructured.Unstructured) (cluster Cluster) {
+	cluster.Name = object.GetName()
+	_, cluster.Host = object.GetLabels()["cluster-role.kubesphere.io/host"]
+
+	conditions, _, _ := unstructured.NestedSlice(object.Object, "status", "conditions")
+	for _, item := range conditions {
+		if condition, ok := item.(map[string]interface{}); ok &&
+			condition["type"] == "Ready" && condition["status"] == "True" {
+			cluster.Ready = true
+		}
+	}
+	return
+}
+
+func (h *handler) getDeployTargets(req *restful.Request, resp *restful.Response) {
+	pipeline := &v1alpha3.Pipeline{}
+	err := h.Get(req.Request.Context(), types.NamespacedName{
+		Namespace: common.GetPathParameter(req, common.DevopsPathParameter),
+		Name:      common.GetPathParameter(req, pathParameterPipeline),
+	}, pipeline)
+
+	targets := make([]DeployTarget, 0)
+	if err == nil {
+		for _, target := range pipeline.GetDeployTargets() {
+			targets = append(targets, DeployTarget{
+				DeployTarget: target,
+				Credential:   target.GetCredentialName(),
+			})
+		}
+	}
+	common.Response(req, resp, targets, err)
+}
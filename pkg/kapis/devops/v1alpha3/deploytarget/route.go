@@ -0,0 +1,52 @@
+/*
+Copyright 2022 The KubeSphere Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package deploytarget
+
+import (
+	"net/http"
+
+	restfulspec "github.com/emicklei/go-restful-openapi"
+	"github.com/emicklei/go-restful/v3"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/kubesphere/ks-devops/pkg/api"
+	"github.com/kubesphere/ks-devops/pkg/constants"
+	"github.com/kubesphere/ks-devops/pkg/kapis/common"
+)
+
+//+kubebuilder:rbac:groups=cluster.kubesphere.io,resources=clusters,verbs=get;list;watch
+
+var pathParameterPipeline = restful.PathParameter("pipeline", "The name of a Pipeline")
+
+// RegisterRoutes registers the APIs of the pipeline deployment targets
+func RegisterRoutes(service *restful.WebService, k8sClient client.Client) {
+	h := &handler{Client: k8sClient}
+
+	service.Route(service.GET("/clusters").
+		To(h.listClusters).
+		Metadata(restfulspec.KeyOpenAPITags, constants.DevOpsDeployTargetTags).
+		Doc("Return the clusters which the Pipelines are able to deploy to").
+		Returns(http.StatusOK, api.StatusOK, []Cluster{}))
+
+	service.Route(service.GET("/namespaces/{devops}/pipelines/{pipeline}/deploytargets").
+		To(h.getDeployTargets).
+		Metadata(restfulspec.KeyOpenAPITags, constants.DevOpsDeployTargetTags).
+		Param(common.DevopsPathParameter).
+		Param(pathParameterPipeline).
+		Doc("Return the deployment targets of a Pipeline, and the names of their credentials").
+		Returns(http.StatusOK, api.StatusOK, []DeployTarget{}))
+}
@@ -0,0 +1,129 @@
+/*
+Copyright 2022 The KubeSphere Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package deploytarget
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/emicklei/go-restful/v3"
+	"github.com/kubesphere/ks-devops/pkg/api"
+	"github.com/kubesphere/ks-devops/pkg/api/devops/v1alpha3"
+	ksruntime "github.com/kubesphere/ks-devops/pkg/apiserver/runtime"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	runtimeSchema "k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestAPIs(t *testing.T) {
+	schema, err := v1alpha3.SchemeBuilder.Register().Build()
+	assert.Nil(t, err)
+
+	host := &unstructured.Unstructured{}
+	host.SetAPIVersion("cluster.kubesphere.io/v1alpha1")
+	host.SetKind("Cluster")
+	host.SetName("host")
+	host.SetLabels(map[string]string{"cluster-role.kubesphere.io/host": ""})
+	_ = unstructured.SetNestedSlice(host.Object, []interface{}{map[string]interface{}{
+		"type":   "Ready",
+		"status": "True",
+	}}, "status", "conditions")
+	member := &unstructured.Unstructured{}
+	member.SetAPIVersion("cluster.kubesphere.io/v1alpha1")
+	member.SetKind("Cluster")
+	member.SetName("member")
+
+	tests := []struct {
+		name         string
+		api          string
+		getInstances func() []client.Object
+		wantCode     int
+		verify       func([]byte, *testing.T)
+	}{{
+		name:     "an empty list of the clusters",
+		api:      "/clusters",
+		wantCode: http.StatusOK,
+		verify: func(data []byte, t *testing.T) {
+			assert.JSONEq(t, `[]`, string(data))
+		},
+	}, {
+		name: "the list of the clusters",
+		api:  "/clusters",
+		getInstances: func() []client.Object {
+			return []client.Object{host.DeepCopy(), member.DeepCopy()}
+		},
+		wantCode: http.StatusOK,
+		verify: func(data []byte, t *testing.T) {
+			var clusters []Cluster
+			assert.Nil(t, json.Unmarshal(data, &clusters))
+			assert.ElementsMatch(t, []Cluster{{
+				Name:  "host",
+				Host:  true,
+				Ready: true,
+			}, {
+				Name: "member",
+			}}, clusters)
+		},
+	}, {
+		name: "the deployment targets of a Pipeline",
+		api:  "/namespaces/devops/pipelines/fake/deploytargets",
+		getInstances: func() []client.Object {
+			return []client.Object{&v1alpha3.Pipeline{
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace: "devops",
+					Name:      "fake",
+					Annotations: map[string]string{
+						v1alpha3.PipelineDeployTargetsAnnoKey: "member/prod",
+					},
+				},
+			}}
+		},
+		wantCode: http.StatusOK,
+		verify: func(data []byte, t *testing.T) {
+			assert.JSONEq(t, `[{"cluster":"member","namespace":"prod","credential":"deploy-member.prod"}]`, string(data))
+		},
+	}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.getInstances == nil {
+				tt.getInstances = func() []client.Object {
+					return []client.Object{}
+				}
+			}
+
+			ws := ksruntime.NewWebService(runtimeSchema.GroupVersion{Group: api.GroupName, Version: "v1alpha3"})
+			RegisterRoutes(ws, fake.NewClientBuilder().WithScheme(schema).WithObjects(tt.getInstances()...).Build())
+			container := restful.NewContainer()
+			container.Add(ws)
+
+			httpRequest, _ := http.NewRequest(http.MethodGet,
+				"http://fake.com/kapis/devops.kubesphere.io/v1alpha3"+tt.api, nil)
+			httpWriter := httptest.NewRecorder()
+			container.Dispatch(httpWriter, httpRequest)
+			assert.Equal(t, tt.wantCode, httpWriter.Code)
+
+			if tt.verify != nil {
+				tt.verify(httpWriter.Body.Bytes(), t)
+			}
+		})
+	}
+}
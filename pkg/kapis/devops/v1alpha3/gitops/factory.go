@@ -8,14 +8,17 @@ import (
 	"path/filepath"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/go-git/go-git/v5"
 	"github.com/go-git/go-git/v5/plumbing/object"
-	"github.com/go-git/go-git/v5/plumbing/transport/http"
+	"github.com/go-git/go-git/v5/plumbing/transport"
 	"github.com/kubesphere/ks-devops/pkg/api/devops/v1alpha3"
+	gitclient "github.com/kubesphere/ks-devops/pkg/client/git"
 	"github.com/kubesphere/ks-devops/pkg/config"
 	"github.com/kubesphere/ks-devops/pkg/constants"
 	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apiserver/pkg/authentication/user"
 	"sigs.k8s.io/controller-runtime/pkg/client"
@@ -24,6 +27,7 @@ import (
 type gitRepoFactory struct {
 	k8sClient client.Client
 	config    *config.GitOpsOptions
+	locker    RepoLocker
 }
 
 func (g *gitRepoFactory) DeleteRepoClone(ctx context.Context, repoName types.NamespacedName) error {
@@ -34,10 +38,33 @@ func (g *gitRepoFactory) DeleteRepoClone(ctx context.Context, repoName types.Nam
 
 	repoDir := g.getRepoDirForUser(ctx, repoName.Namespace, gitRepo.Spec.URL)
 
+	unlock, err := g.lockRepo(ctx, repoName, repoDir)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
 	err = os.RemoveAll(repoDir)
 	return err
 }
 
+// lockRepo locks the working copy of a git repository, it returns a conflict error if it's locked by others for a long time
+func (g *gitRepoFactory) lockRepo(ctx context.Context, repoName types.NamespacedName, repoDir string) (func(), error) {
+	timeout := g.config.LockTimeout
+	if timeout <= 0 {
+		timeout = time.Minute
+	}
+	lockCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	unlock, err := g.locker.Lock(lockCtx, repoDir)
+	if err != nil {
+		return nil, apierrors.NewConflict(v1alpha3.GroupVersion.WithResource("gitrepositories").GroupResource(), repoName.Name,
+			fmt.Errorf("the repository is being modified by others, please retry later: %v", err))
+	}
+	return unlock, nil
+}
+
 func (g *gitRepoFactory) parseSkipTLSFromRepo(ctx context.Context, repo *v1alpha3.GitRepository) bool {
 	v, ok := repo.Annotations[constants.InsecureSkipTLSAnnotationKey]
 	if !ok {
@@ -47,6 +74,22 @@ func (g *gitRepoFactory) parseSkipTLSFromRepo(ctx context.Context, repo *v1alpha
 	return yes
 }
 
+// getCommitSigner returns the signer of the commits, it's nil if the commit signing is not configured
+func (g *gitRepoFactory) getCommitSigner(ctx context.Context, repo *v1alpha3.GitRepository) (CommitSigner, error) {
+	if repo.Spec.CommitSigning == nil || repo.Spec.CommitSigning.Secret == nil {
+		return nil, nil
+	}
+	secretRef := repo.Spec.CommitSigning.Secret.DeepCopy()
+	if secretRef.Namespace == "" {
+		secretRef.Namespace = repo.Namespace
+	}
+	secret, err := g.getSecret(ctx, secretRef)
+	if err != nil {
+		return nil, err
+	}
+	return NewCommitSigner(secret)
+}
+
 func (g *gitRepoFactory) parseAuthorFromSecret(ctx context.Context, secret *v1.Secret) *object.Signature {
 	authorName := secret.Annotations[constants.GitAuthorNameAnnotationKey]
 	authorEmail := secret.Annotations[constants.GitAuthorEmailAnnotationKey]
@@ -67,31 +110,57 @@ func (g *gitRepoFactory) NewRepoService(ctx context.Context, user user.Info, rep
 	if err != nil {
 		return nil, err
 	}
-	token, tokenUser, err := g.getTokenFromSecret(ctx, secret)
+	cred, err := gitclient.ParseCredential(ctx, secret)
 	if err != nil {
 		return nil, err
 	}
-	if token == "" {
-		return nil, fmt.Errorf("failed to get token")
+	if cred.IsEmpty() {
+		return nil, fmt.Errorf("no token or SSH private key found in secret %s/%s", secret.Namespace, secret.Name)
 	}
-	if tokenUser == "" {
-		tokenUser = user.GetName() // yes, this can be anything except an empty string
+	if !cred.IsSSH() && cred.Username == "" {
+		cred.Username = user.GetName() // yes, this can be anything except an empty string
 	}
 
-	auth := &http.BasicAuth{
-		Username: tokenUser,
-		Password: token,
+	// skipping the TLS verification also ignores the SSH host key, the same as the insecure flag of Argo CD repositories
+	insecureSkipTLS := g.parseSkipTLSFromRepo(ctx, gitRepo)
+	auth, err := cred.GetAuthMethod(insecureSkipTLS)
+	if err != nil {
+		return nil, err
+	}
+
+	signer, err := g.getCommitSigner(ctx, gitRepo)
+	if err != nil {
+		return nil, err
 	}
 
-	insecureSkipTLS := g.parseSkipTLSFromRepo(ctx, gitRepo)
 	author := g.parseAuthorFromSecret(ctx, secret)
 	repoDir := g.getRepoDirForUser(ctx, repoName.Namespace, gitRepo.Spec.URL)
 
 	if author.Name == "" {
-		author.Name = tokenUser
+		// neither the SSH user nor the GitHub App user is a meaningful author
+		author.Name = user.GetName()
+		if !cred.IsSSH() && secret.Type != v1alpha3.SecretTypeGitHubApp {
+			author.Name = cred.Username
+		}
 	}
 
+	// the lock is released when the service is closed
+	unlock, err := g.lockRepo(ctx, repoName, repoDir)
+	if err != nil {
+		return nil, err
+	}
+	repoService, err := g.newRepoService(user, gitRepo, repoDir, auth, author, insecureSkipTLS, signer, unlock)
+
+	if err != nil {
+		unlock()
+	}
+	return repoService, err
+}
+
+func (g *gitRepoFactory) newRepoService(user user.Info, gitRepo *v1alpha3.GitRepository, repoDir string, auth transport.AuthMethod,
+	author *object.Signature, insecureSkipTLS bool, signer CommitSigner, unlock func()) (GitRepoService, error) {
 	var repo *git.Repository
+	var err error
 	_, err = os.Stat(repoDir)
 	if err == nil {
 		repo, err = git.PlainOpen(repoDir)
@@ -100,9 +169,10 @@ func (g *gitRepoFactory) NewRepoService(ctx context.Context, user user.Info, rep
 		}
 	} else if errors.Is(err, os.ErrNotExist) {
 		repo, err = git.PlainClone(repoDir, false, &git.CloneOptions{
-			Auth:     auth,
-			URL:      gitRepo.Spec.URL,
-			Progress: os.Stdout,
+			Auth:            auth,
+			URL:             gitRepo.Spec.URL,
+			Progress:        os.Stdout,
+			InsecureSkipTLS: insecureSkipTLS,
 		})
 		if err != nil {
 			return nil, err
@@ -122,12 +192,45 @@ func (g *gitRepoFactory) NewRepoService(ctx context.Context, user user.Info, rep
 		auth:            auth,
 		insecureSkipTLS: insecureSkipTLS,
 		newFilePerm:     g.config.NewFilePerm,
+		signer:          signer,
+		unlock:          unlock,
+		pullRequests: func() (PullRequestService, error) {
+			return g.newPullRequestService(gitRepo)
+		},
 	}
 	repoService := NewGitRepoService(gitRepoOpts)
 
 	return repoService, nil
 }
 
+func (g *gitRepoFactory) NewPullRequestService(ctx context.Context, repoName types.NamespacedName) (PullRequestService, error) {
+	gitRepo, err := g.getAndCheckGitRepo(ctx, repoName)
+	if err != nil {
+		return nil, err
+	}
+	return g.newPullRequestService(gitRepo)
+}
+
+func (g *gitRepoFactory) newPullRequestService(gitRepo *v1alpha3.GitRepository) (PullRequestService, error) {
+	repoPath := gitRepo.GetRepoPath()
+	if gitRepo.Spec.Provider == "" || repoPath == "" {
+		return nil, fmt.Errorf("the provider and the repository path of git repository %s/%s are required by pull requests",
+			gitRepo.Namespace, gitRepo.Name)
+	}
+
+	secretRef := gitRepo.Spec.Secret.DeepCopy()
+	if secretRef.Namespace == "" {
+		secretRef.Namespace = gitRepo.Namespace
+	}
+	clientFactory := gitclient.NewClientFactory(gitRepo.Spec.Provider, secretRef, g.k8sClient)
+	clientFactory.Server = gitRepo.Spec.Server
+	scmClient, err := clientFactory.GetClient()
+	if err != nil {
+		return nil, err
+	}
+	return NewPullRequestService(scmClient, repoPath), nil
+}
+
 func (g *gitRepoFactory) getRepoDirForUser(ctx context.Context, namespace string, repoURL string) string {
 	repoURL = strings.TrimPrefix(repoURL, "http://")
 	repoURL = strings.TrimPrefix(repoURL, "https://")
@@ -159,20 +262,6 @@ func (g *gitRepoFactory) getAndCheckGitRepo(ctx context.Context, repoName types.
 	return gitRepo, nil
 }
 
-func (g *gitRepoFactory) getTokenFromSecret(ctx context.Context, gitSecret *v1.Secret) (token, username string, err error) {
-	switch gitSecret.Type {
-	case v1.SecretTypeBasicAuth, v1alpha3.SecretTypeBasicAuth:
-		token = string(gitSecret.Data[v1.BasicAuthPasswordKey])
-		username = string(gitSecret.Data[v1.BasicAuthUsernameKey])
-	case v1.SecretTypeOpaque:
-		token = string(gitSecret.Data[v1.ServiceAccountTokenKey])
-	case v1alpha3.SecretTypeSecretText:
-		token = string(gitSecret.Data["secret"])
-	}
-
-	return token, username, err
-}
-
 func (g *gitRepoFactory) getSecret(ctx context.Context, ref *v1.SecretReference) (*v1.Secret, error) {
 	secret := &v1.Secret{}
 	err := g.k8sClient.Get(ctx, types.NamespacedName{
@@ -188,5 +277,6 @@ func NewGitRepoFactory(k8sClient client.Client, config *config.GitOpsOptions) Gi
 	return &gitRepoFactory{
 		k8sClient: k8sClient,
 		config:    config,
+		locker:    NewRepoLocker(k8sClient, config.LeaseNamespace),
 	}
 }
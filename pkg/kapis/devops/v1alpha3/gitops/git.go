@@ -9,6 +9,7 @@ import (
 	"path"
 	"path/filepath"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/emirpasic/gods/trees/binaryheap"
@@ -37,6 +38,21 @@ type gitRepoService struct {
 	// ProxyOptions provides info required for connecting to a proxy.
 	proxyOptions transport.ProxyOptions
 	newFilePerm  os.FileMode
+	unlock       func()
+	closeOnce    sync.Once
+	pullRequests func() (PullRequestService, error)
+	// signer signs the commits, they are not signed if it's nil
+	signer CommitSigner
+}
+
+// Close releases the lock of the repository
+func (s *gitRepoService) Close() error {
+	s.closeOnce.Do(func() {
+		if s.unlock != nil {
+			s.unlock()
+		}
+	})
+	return nil
 }
 
 func (s *gitRepoService) UploadFiles(ctx context.Context, input *UploadFilesInput) (*UploadFilesOutput, error) {
@@ -129,7 +145,7 @@ func (s *gitRepoService) GetCommit(ctx context.Context, input *GetCommitInput) (
 		return nil, err
 	}
 	out := &GetCommitOutput{
-		Commit: convertCommit(commit),
+		Commit: s.convertCommit(commit),
 	}
 	return out, nil
 }
@@ -526,6 +542,14 @@ func (s *gitRepoService) CleanAndPull(ctx context.Context, input *CleanAndPullIn
 		CABundle:        s.caBundle,
 		ProxyOptions:    s.proxyOptions,
 	})
+	if errors.Is(err, git.ErrNonFastForwardUpdate) {
+		// the local branch has the commits which failed to push, discard them
+		klog.InfoS("the local branch diverged from the remote one, resetting it", "branch", input.Branch)
+		var remoteHash plumbing.Hash
+		if remoteHash, err = s.fetchBranch(input.Branch); err == nil {
+			err = w.Reset(&git.ResetOptions{Commit: remoteHash, Mode: git.HardReset})
+		}
+	}
 	if err != nil && !errors.Is(err, git.NoErrAlreadyUpToDate) {
 		return nil, err
 	}
@@ -578,25 +602,26 @@ func (s *gitRepoService) CommitAndPush(ctx context.Context, input *CommitAndPush
 		commitMessage = fmt.Sprintf("%s\n\n%s", commitMessage, signOff)
 	}
 
+	branch := input.Branch
+	if branch == "" {
+		head, err := s.repo.Head()
+		if err != nil {
+			return nil, err
+		}
+		branch = head.Name().Short()
+	}
+
 	// Commit the changes with the message and author information.
 	hash, err := w.Commit(commitMessage, &git.CommitOptions{
 		All:    true,
 		Author: author,
+		Signer: s.signer,
 	})
 	if err != nil {
 		return nil, err
 	}
 
-	err = s.repo.Push(&git.PushOptions{
-		RemoteName:      "origin",
-		Auth:            s.auth,
-		Progress:        os.Stdout,
-		Force:           true,
-		InsecureSkipTLS: s.insecureSkipTLS,
-		CABundle:        s.caBundle,
-		Atomic:          true,
-		ProxyOptions:    s.proxyOptions,
-	})
+	hash, err = s.pushWithRetry(w, branch, hash)
 	if err != nil {
 		return nil, err
 	}
@@ -606,7 +631,7 @@ func (s *gitRepoService) CommitAndPush(ctx context.Context, input *CommitAndPush
 		return nil, err
 	}
 	out := &CommitAndPushOutput{
-		Commit: convertCommit(commit),
+		Commit: s.convertCommit(commit),
 	}
 
 	return out, nil
@@ -790,7 +815,7 @@ func (s *gitRepoService) ListCommits(ctx context.Context, input *ListCommitsInpu
 	commits, out.TotalItems = utils.GetPage(commits, input.Options.Page, input.Options.Limit)
 
 	for _, c := range commits {
-		ci := &CommitInfo{Commit: convertCommit(c)}
+		ci := &CommitInfo{Commit: s.convertCommit(c)}
 		out.Items = append(out.Items, ci)
 	}
 
@@ -845,38 +870,12 @@ func (s *gitRepoService) AddFiles(ctx context.Context, input *AddFilesInput) (*A
 		if err != nil {
 			return nil, err
 		}
-	} else {
-		for _, file := range input.Files {
-			_, err = w.Filesystem.Stat(file.Name)
-			if err == nil && !input.Overwrite {
-				continue
-			}
-
-			if len(file.OldName) > 0 {
-				oldFilePath := w.Filesystem.Join(root, file.OldName)
-				err = os.RemoveAll(oldFilePath)
-				if err != nil {
-					return nil, err
-				}
-			}
-
-			filePath := w.Filesystem.Join(root, file.Name)
-			err = os.RemoveAll(filePath)
-			if err != nil {
-				return nil, err
-			}
-			err = os.MkdirAll(filepath.Dir(filePath), s.newFilePerm)
-			if err != nil {
-				return nil, err
-			}
-			err = os.WriteFile(filePath, file.Data, s.newFilePerm)
-			if err != nil {
-				return nil, err
-			}
-		}
+	} else if err = s.writeFiles(w, input.Files, input.Overwrite); err != nil {
+		return nil, err
 	}
 
 	cpOut, err := s.CommitAndPush(ctx, &CommitAndPushInput{
+		Branch:   input.Branch,
 		WorkTree: w,
 		Message:  input.Message,
 		SignOff:  true,
@@ -892,6 +891,40 @@ func (s *gitRepoService) AddFiles(ctx context.Context, input *AddFilesInput) (*A
 	return out, nil
 }
 
+// writeFiles writes the files into the work tree, the old ones are removed if the files were renamed
+func (s *gitRepoService) writeFiles(w *git.Worktree, files []*FileNameData, overwrite bool) error {
+	root := w.Filesystem.Root()
+	for _, file := range files {
+		_, err := w.Filesystem.Stat(file.Name)
+		if err == nil && !overwrite {
+			continue
+		}
+
+		if len(file.OldName) > 0 {
+			oldFilePath := w.Filesystem.Join(root, file.OldName)
+			err = os.RemoveAll(oldFilePath)
+			if err != nil {
+				return err
+			}
+		}
+
+		filePath := w.Filesystem.Join(root, file.Name)
+		err = os.RemoveAll(filePath)
+		if err != nil {
+			return err
+		}
+		err = os.MkdirAll(filepath.Dir(filePath), s.newFilePerm)
+		if err != nil {
+			return err
+		}
+		err = os.WriteFile(filePath, file.Data, s.newFilePerm)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func (s *gitRepoService) DeleteFiles(ctx context.Context, input *DeleteFilesInput) (*DeleteFilesOutput, error) {
 	if len(input.Branch) == 0 || len(input.Files) == 0 || len(input.Message) == 0 {
 		return nil, os.ErrInvalid
@@ -921,6 +954,7 @@ func (s *gitRepoService) DeleteFiles(ctx context.Context, input *DeleteFilesInpu
 	}
 
 	cpOut, err := s.CommitAndPush(ctx, &CommitAndPushInput{
+		Branch:   input.Branch,
 		WorkTree: w,
 		Message:  input.Message,
 		SignOff:  true,
@@ -999,6 +1033,15 @@ func (s *gitRepoService) GetFile(ctx context.Context, input *GetFileInput) (*Get
 	return out, nil
 }
 
+// convertCommit converts the commit with the verification result of its signature
+func (s *gitRepoService) convertCommit(c *object.Commit) *Commit {
+	commit := convertCommit(c)
+	if commit != nil {
+		commit.Signature = verifyCommitSignature(s.signer, c)
+	}
+	return commit
+}
+
 var _ GitRepoService = &gitRepoService{}
 
 func NewGitRepoService(opts *GitRepoOptions) GitRepoService {
@@ -1010,5 +1053,8 @@ func NewGitRepoService(opts *GitRepoOptions) GitRepoService {
 		caBundle:        opts.caBundle,
 		proxyOptions:    opts.proxyOptions,
 		newFilePerm:     opts.newFilePerm,
+		unlock:          opts.unlock,
+		pullRequests:    opts.pullRequests,
+		signer:          opts.signer,
 	}
 }
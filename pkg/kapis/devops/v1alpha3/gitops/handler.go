@@ -36,6 +36,9 @@ func (h *handler) DownloadFile(req *restful.Request, res *restful.Response) {
 		kapis.HandleError(req, res, err)
 		return
 	}
+	defer func() {
+		_ = repoService.Close()
+	}()
 
 	branch := common.GetPathParameter(req, pathParameterBranch)
 	commit := common.GetPathParameter(req, pathParameterCommit)
@@ -98,6 +101,9 @@ func (h *handler) GetFile(req *restful.Request, res *restful.Response) {
 		kapis.HandleError(req, res, err)
 		return
 	}
+	defer func() {
+		_ = repoService.Close()
+	}()
 
 	branch := common.GetPathParameter(req, pathParameterBranch)
 	commit := common.GetPathParameter(req, pathParameterCommit)
@@ -130,6 +136,9 @@ func (h *handler) UploadFiles(req *restful.Request, res *restful.Response) {
 		kapis.HandleError(req, res, err)
 		return
 	}
+	defer func() {
+		_ = repoService.Close()
+	}()
 
 	var files []*FileNameData
 	for i := 0; i < 10; i++ {
@@ -191,6 +200,9 @@ func (h *handler) GetBranch(req *restful.Request, res *restful.Response) {
 		kapis.HandleError(req, res, err)
 		return
 	}
+	defer func() {
+		_ = repoService.Close()
+	}()
 
 	branch := common.GetPathParameter(req, pathParameterBranch)
 
@@ -214,6 +226,9 @@ func (h *handler) GetCommit(req *restful.Request, res *restful.Response) {
 		kapis.HandleError(req, res, err)
 		return
 	}
+	defer func() {
+		_ = repoService.Close()
+	}()
 
 	out, err := repoService.GetCommit(ctx, &GetCommitInput{
 		Commit: common.GetPathParameter(req, pathParameterCommit),
@@ -234,6 +249,9 @@ func (h *handler) GetConfig(req *restful.Request, res *restful.Response) {
 		kapis.HandleError(req, res, err)
 		return
 	}
+	defer func() {
+		_ = repoService.Close()
+	}()
 	out, err := repoService.GetConfig(ctx, &GetConfigInput{})
 	if err != nil {
 		kapis.HandleError(req, res, err)
@@ -256,6 +274,9 @@ func (h *handler) UpdateConfig(req *restful.Request, res *restful.Response) {
 		kapis.HandleError(req, res, err)
 		return
 	}
+	defer func() {
+		_ = repoService.Close()
+	}()
 
 	out, err := repoService.UpdateConfig(ctx, input)
 	if err != nil {
@@ -292,6 +313,9 @@ func (h *handler) ListBranches(req *restful.Request, res *restful.Response) {
 		kapis.HandleError(req, res, err)
 		return
 	}
+	defer func() {
+		_ = repoService.Close()
+	}()
 
 	withHeadStr := common.GetQueryParameter(req, queryParameterWithHead)
 	withHead, _ := strconv.ParseBool(withHeadStr)
@@ -316,6 +340,9 @@ func (h *handler) CheckOutBranch(req *restful.Request, res *restful.Response) {
 		kapis.HandleError(req, res, err)
 		return
 	}
+	defer func() {
+		_ = repoService.Close()
+	}()
 
 	branch := common.GetPathParameter(req, pathParameterBranch)
 	out, err := repoService.CheckOutBranch(ctx, &CheckOutBranchInput{
@@ -336,6 +363,9 @@ func (h *handler) CleanAndPullBranch(req *restful.Request, res *restful.Response
 		kapis.HandleError(req, res, err)
 		return
 	}
+	defer func() {
+		_ = repoService.Close()
+	}()
 
 	branch := common.GetPathParameter(req, pathParameterBranch)
 
@@ -366,6 +396,9 @@ func (h *handler) ListCommits(req *restful.Request, res *restful.Response) {
 		kapis.HandleError(req, res, err)
 		return
 	}
+	defer func() {
+		_ = repoService.Close()
+	}()
 
 	listOptions := ParseListOptionsFromRequest(req)
 	branch := common.GetPathParameter(req, pathParameterBranch)
@@ -390,6 +423,9 @@ func (h *handler) AddFiles(req *restful.Request, res *restful.Response) {
 		kapis.HandleError(req, res, err)
 		return
 	}
+	defer func() {
+		_ = repoService.Close()
+	}()
 
 	branch := common.GetPathParameter(req, pathParameterBranch)
 
@@ -416,6 +452,9 @@ func (h *handler) DeleteFiles(req *restful.Request, res *restful.Response) {
 		kapis.HandleError(req, res, err)
 		return
 	}
+	defer func() {
+		_ = repoService.Close()
+	}()
 
 	branch := common.GetPathParameter(req, pathParameterBranch)
 	files := common.GetQueryParameters(req, queryParameterFile)
@@ -442,6 +481,9 @@ func (h *handler) ListFiles(req *restful.Request, res *restful.Response) {
 		kapis.HandleError(req, res, err)
 		return
 	}
+	defer func() {
+		_ = repoService.Close()
+	}()
 
 	branch := common.GetPathParameter(req, pathParameterBranch)
 	file := common.GetQueryParameter(req, queryParameterFile)
@@ -461,6 +503,83 @@ func (h *handler) ListFiles(req *restful.Request, res *restful.Response) {
 	_ = res.WriteEntity(out)
 }
 
+func (h *handler) CreatePullRequest(req *restful.Request, res *restful.Response) {
+	ctx := req.Request.Context()
+	input := &CreatePullRequestInput{}
+	err := req.ReadEntity(input)
+	if err != nil {
+		kapis.HandleBadRequest(res, req, err)
+		return
+	}
+	input.Base = common.GetPathParameter(req, pathParameterBranch)
+
+	repoService, err := h.getRepoService(req)
+	if err != nil {
+		kapis.HandleError(req, res, err)
+		return
+	}
+	defer func() {
+		_ = repoService.Close()
+	}()
+
+	out, err := repoService.CreatePullRequest(ctx, input)
+	if err != nil {
+		kapis.HandleError(req, res, err)
+		return
+	}
+	_ = res.WriteEntity(out)
+}
+
+func (h *handler) ListPullRequests(req *restful.Request, res *restful.Response) {
+	ctx := req.Request.Context()
+	prService, err := h.getPullRequestService(req)
+	if err != nil {
+		kapis.HandleError(req, res, err)
+		return
+	}
+
+	out, err := prService.ListPullRequests(ctx, &ListPullRequestsInput{
+		Options: ParseListOptionsFromRequest(req),
+	})
+	if err != nil {
+		kapis.HandleError(req, res, err)
+		return
+	}
+	_ = res.WriteEntity(out)
+}
+
+func (h *handler) GetPullRequest(req *restful.Request, res *restful.Response) {
+	ctx := req.Request.Context()
+	number, err := strconv.Atoi(common.GetPathParameter(req, pathParameterPullRequest))
+	if err != nil {
+		kapis.HandleBadRequest(res, req, err)
+		return
+	}
+
+	prService, err := h.getPullRequestService(req)
+	if err != nil {
+		kapis.HandleError(req, res, err)
+		return
+	}
+
+	out, err := prService.GetPullRequest(ctx, &GetPullRequestInput{
+		Number: number,
+	})
+	if err != nil {
+		kapis.HandleError(req, res, err)
+		return
+	}
+	_ = res.WriteEntity(out.PullRequest)
+}
+
+// getPullRequestService returns the pull request service, it does not need the working copy of the repository
+func (h *handler) getPullRequestService(req *restful.Request) (PullRequestService, error) {
+	return h.factory.NewPullRequestService(req.Request.Context(), types.NamespacedName{
+		Namespace: common.GetPathParameter(req, common.NamespacePathParameter),
+		Name:      common.GetPathParameter(req, pathParameterGitRepository),
+	})
+}
+
 var _ Handler = &handler{}
 
 func NewHandler(k8sClient client.Client, config *config.GitOpsOptions) Handler {
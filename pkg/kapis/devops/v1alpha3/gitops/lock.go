@@ -0,0 +1,268 @@
+/*
+Copyright 2022 The KubeSphere Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gitops
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	coordinationv1 "k8s.io/api/coordination/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/rand"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+//+kubebuilder:rbac:groups=coordination.k8s.io,resources=leases,verbs=get;list;watch;create;update;delete
+
+// RepoLocker locks a git repository, it avoids the working copy being modified concurrently
+type RepoLocker interface {
+	// Lock blocks until the lock is acquired or the context is done
+	Lock(ctx context.Context, key string) (unlock func(), err error)
+}
+
+// memoryLocker locks the git repositories in-process
+type memoryLocker struct {
+	mutex sync.Mutex
+	locks map[string]chan struct{}
+}
+
+// defaultMemoryLocker is shared by all the factories, there might be several factories in one process
+var defaultMemoryLocker = newMemoryLocker()
+
+func newMemoryLocker() *memoryLocker {
+	return &memoryLocker{locks: map[string]chan struct{}{}}
+}
+
+// Lock acquires the in-process lock of a key
+func (l *memoryLocker) Lock(ctx context.Context, key string) (unlock func(), err error) {
+	l.mutex.Lock()
+	lock, ok := l.locks[key]
+	if !ok {
+		lock = make(chan struct{}, 1)
+		l.locks[key] = lock
+	}
+	l.mutex.Unlock()
+
+	select {
+	case lock <- struct{}{}:
+		unlock = func() {
+			<-lock
+		}
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+	return
+}
+
+const (
+	defaultLeaseDuration = 30 * time.Second
+	defaultRetryPeriod   = time.Second
+)
+
+// leaseLocker locks the git repositories among multiple replicas by the Leases
+type leaseLocker struct {
+	client    client.Client
+	namespace string
+	identity  string
+	// leaseDuration is the duration that the other replicas wait before taking over a lease which is not renewed
+	leaseDuration time.Duration
+	retryPeriod   time.Duration
+}
+
+func newLeaseLocker(k8sClient client.Client, namespace string) *leaseLocker {
+	hostname, _ := os.Hostname()
+	return &leaseLocker{
+		client:        k8sClient,
+		namespace:     namespace,
+		identity:      fmt.Sprintf("%s_%s", hostname, rand.String(8)),
+		leaseDuration: defaultLeaseDuration,
+		retryPeriod:   defaultRetryPeriod,
+	}
+}
+
+// Lock acquires the Lease of a key, the Lease is renewed until it's unlocked
+func (l *leaseLocker) Lock(ctx context.Context, key string) (unlock func(), err error) {
+	name := getLeaseName(key)
+	for {
+		var acquired bool
+		if acquired, err = l.tryAcquire(ctx, name); err != nil {
+			return
+		} else if acquired {
+			break
+		}
+
+		select {
+		case <-ctx.Done():
+			err = ctx.Err()
+			return
+		case <-time.After(l.retryPeriod):
+		}
+	}
+
+	stop := make(chan struct{})
+	go l.renew(name, stop)
+	unlock = func() {
+		close(stop)
+		l.release(name)
+	}
+	return
+}
+
+// tryAcquire creates the Lease, or takes it over if it's expired
+func (l *leaseLocker) tryAcquire(ctx context.Context, name string) (acquired bool, err error) {
+	now := metav1.NewMicroTime(time.Now())
+	leaseDurationSeconds := int32(l.leaseDuration.Seconds())
+
+	lease := &coordinationv1.Lease{}
+	if err = l.client.Get(ctx, types.NamespacedName{Namespace: l.namespace, Name: name}, lease); err != nil {
+		if !apierrors.IsNotFound(err) {
+			return
+		}
+
+		lease = &coordinationv1.Lease{
+			ObjectMeta: metav1.ObjectMeta{Namespace: l.namespace, Name: name},
+			Spec: coordinationv1.LeaseSpec{
+				HolderIdentity:       &l.identity,
+				LeaseDurationSeconds: &leaseDurationSeconds,
+				AcquireTime:          &now,
+				RenewTime:            &now,
+			},
+		}
+		if err = l.client.Create(ctx, lease); err == nil {
+			acquired = true
+		} else if apierrors.IsAlreadyExists(err) {
+			err = nil
+		}
+		return
+	}
+
+	if l.isHeldByOthers(lease, now.Time) {
+		return
+	}
+	lease.Spec.HolderIdentity = &l.identity
+	lease.Spec.LeaseDurationSeconds = &leaseDurationSeconds
+	lease.Spec.AcquireTime = &now
+	lease.Spec.RenewTime = &now
+	if err = l.client.Update(ctx, lease); err == nil {
+		acquired = true
+	} else if apierrors.IsConflict(err) {
+		err = nil
+	}
+	return
+}
+
+func (l *leaseLocker) isHeldByOthers(lease *coordinationv1.Lease, now time.Time) bool {
+	spec := lease.Spec
+	if spec.HolderIdentity == nil || *spec.HolderIdentity == "" || *spec.HolderIdentity == l.identity {
+		return false
+	}
+	if spec.RenewTime == nil || spec.LeaseDurationSeconds == nil {
+		return false
+	}
+	expireTime := spec.RenewTime.Add(time.Duration(*spec.LeaseDurationSeconds) * time.Second)
+	return now.Before(expireTime)
+}
+
+// renew keeps the Lease alive until it's stopped
+func (l *leaseLocker) renew(name string, stop <-chan struct{}) {
+	ticker := time.NewTicker(l.leaseDuration / 3)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			lease := &coordinationv1.Lease{}
+			ctx := context.Background()
+			if err := l.client.Get(ctx, types.NamespacedName{Namespace: l.namespace, Name: name}, lease); err != nil {
+				klog.ErrorS(err, "failed to get the lease of git repository", "lease", name)
+				continue
+			}
+			if l.isHeldByOthers(lease, time.Now()) {
+				klog.InfoS("the lease of git repository was taken over", "lease", name)
+				return
+			}
+			now := metav1.NewMicroTime(time.Now())
+			lease.Spec.RenewTime = &now
+			if err := l.client.Update(ctx, lease); err != nil {
+				klog.ErrorS(err, "failed to renew the lease of git repository", "lease", name)
+			}
+		}
+	}
+}
+
+// release deletes the Lease if it's still held by this replica
+func (l *leaseLocker) release(name string) {
+	ctx := context.Background()
+	lease := &coordinationv1.Lease{}
+	if err := l.client.Get(ctx, types.NamespacedName{Namespace: l.namespace, Name: name}, lease); err != nil {
+		return
+	}
+	if holder := lease.Spec.HolderIdentity; holder == nil || *holder != l.identity {
+		return
+	}
+	if err := l.client.Delete(ctx, lease, client.Preconditions{ResourceVersion: &lease.ResourceVersion}); err != nil &&
+		!apierrors.IsNotFound(err) {
+		klog.ErrorS(err, "failed to release the lease of git repository", "lease", name)
+	}
+}
+
+// getLeaseName returns a valid Lease name of a key, the key might be a long path
+func getLeaseName(key string) string {
+	hash := sha256.Sum256([]byte(key))
+	return "gitops-repo-" + hex.EncodeToString(hash[:])[:16]
+}
+
+// chainLocker acquires the locks one by one, then releases them in the reverse order
+type chainLocker []RepoLocker
+
+// Lock acquires all the locks, the acquired ones are released if any one fails
+func (c chainLocker) Lock(ctx context.Context, key string) (unlock func(), err error) {
+	var unlocks []func()
+	unlock = func() {
+		for i := len(unlocks) - 1; i >= 0; i-- {
+			unlocks[i]()
+		}
+	}
+	for _, locker := range c {
+		var itemUnlock func()
+		if itemUnlock, err = locker.Lock(ctx, key); err != nil {
+			unlock()
+			unlock = nil
+			return
+		}
+		unlocks = append(unlocks, itemUnlock)
+	}
+	return
+}
+
+// NewRepoLocker creates a RepoLocker, the Leases are used only if the namespace is not empty
+func NewRepoLocker(k8sClient client.Client, leaseNamespace string) RepoLocker {
+	if leaseNamespace == "" {
+		return defaultMemoryLocker
+	}
+	// acquire the in-process lock first, it avoids requesting the Leases when the lock is held by this replica
+	return chainLocker{defaultMemoryLocker, newLeaseLocker(k8sClient, leaseNamespace)}
+}
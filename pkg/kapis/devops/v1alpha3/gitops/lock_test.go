@@ -0,0 +1,162 @@
+/*
+Copyright 2022 The KubeSphere Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gitops
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	coordinationv1 "k8s.io/api/coordination/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestMemoryLocker(t *testing.T) {
+	locker := newMemoryLocker()
+
+	unlock, err := locker.Lock(context.Background(), "a")
+	assert.Nil(t, err)
+
+	// other keys are not affected
+	unlockB, err := locker.Lock(context.Background(), "b")
+	assert.Nil(t, err)
+	unlockB()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err = locker.Lock(ctx, "a")
+	assert.Equal(t, context.DeadlineExceeded, err)
+
+	unlock()
+	unlock, err = locker.Lock(context.Background(), "a")
+	assert.Nil(t, err)
+	unlock()
+}
+
+func TestLeaseLocker(t *testing.T) {
+	schema := runtime.NewScheme()
+	assert.Nil(t, coordinationv1.AddToScheme(schema))
+	key := "/gitops/ns/github.com/linuxsuren/tools"
+	leaseName := types.NamespacedName{Namespace: "ns", Name: getLeaseName(key)}
+
+	newLocker := func(k8sClient *fake.ClientBuilder, identity string) *leaseLocker {
+		locker := newLeaseLocker(k8sClient.Build(), "ns")
+		locker.identity = identity
+		locker.retryPeriod = 10 * time.Millisecond
+		return locker
+	}
+
+	t.Run("lock and unlock", func(t *testing.T) {
+		k8sClient := fake.NewClientBuilder().WithScheme(schema).Build()
+		locker := newLeaseLocker(k8sClient, "ns")
+		locker.retryPeriod = 10 * time.Millisecond
+		other := newLeaseLocker(k8sClient, "ns")
+		other.retryPeriod = 10 * time.Millisecond
+
+		unlock, err := locker.Lock(context.Background(), key)
+		assert.Nil(t, err)
+		lease := &coordinationv1.Lease{}
+		assert.Nil(t, k8sClient.Get(context.Background(), leaseName, lease))
+		assert.Equal(t, locker.identity, *lease.Spec.HolderIdentity)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		_, err = other.Lock(ctx, key)
+		assert.Equal(t, context.DeadlineExceeded, err)
+
+		unlock()
+		err = k8sClient.Get(context.Background(), leaseName, lease)
+		assert.True(t, apierrors.IsNotFound(err))
+
+		unlock, err = other.Lock(context.Background(), key)
+		assert.Nil(t, err)
+		unlock()
+	})
+
+	t.Run("take over an expired lease", func(t *testing.T) {
+		holder := "other"
+		duration := int32(30)
+		renewTime := metav1.NewMicroTime(time.Now().Add(-time.Minute))
+		builder := fake.NewClientBuilder().WithScheme(schema).WithObjects(&coordinationv1.Lease{
+			ObjectMeta: metav1.ObjectMeta{Namespace: leaseName.Namespace, Name: leaseName.Name},
+			Spec: coordinationv1.LeaseSpec{
+				HolderIdentity:       &holder,
+				LeaseDurationSeconds: &duration,
+				RenewTime:            &renewTime,
+			},
+		})
+		locker := newLocker(builder, "me")
+
+		unlock, err := locker.Lock(context.Background(), key)
+		assert.Nil(t, err)
+		unlock()
+	})
+
+	t.Run("wait for a lease held by others", func(t *testing.T) {
+		holder := "other"
+		duration := int32(30)
+		renewTime := metav1.NewMicroTime(time.Now())
+		builder := fake.NewClientBuilder().WithScheme(schema).WithObjects(&coordinationv1.Lease{
+			ObjectMeta: metav1.ObjectMeta{Namespace: leaseName.Namespace, Name: leaseName.Name},
+			Spec: coordinationv1.LeaseSpec{
+				HolderIdentity:       &holder,
+				LeaseDurationSeconds: &duration,
+				RenewTime:            &renewTime,
+			},
+		})
+		locker := newLocker(builder, "me")
+
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		_, err := locker.Lock(ctx, key)
+		assert.Equal(t, context.DeadlineExceeded, err)
+	})
+}
+
+func TestChainLocker(t *testing.T) {
+	first, second := newMemoryLocker(), newMemoryLocker()
+	locker := chainLocker{first, second}
+
+	unlock, err := locker.Lock(context.Background(), "a")
+	assert.Nil(t, err)
+	unlock()
+
+	// the first lock should be released if the second one fails
+	unlockSecond, err := second.Lock(context.Background(), "a")
+	assert.Nil(t, err)
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err = locker.Lock(ctx, "a")
+	assert.NotNil(t, err)
+	unlockSecond()
+
+	unlockFirst, err := first.Lock(context.Background(), "a")
+	assert.Nil(t, err)
+	unlockFirst()
+}
+
+func TestGetLeaseName(t *testing.T) {
+	name := getLeaseName("/gitops/ns/github.com/linuxsuren/tools")
+	assert.Equal(t, name, getLeaseName("/gitops/ns/github.com/linuxsuren/tools"))
+	assert.NotEqual(t, name, getLeaseName("/gitops/ns/github.com/linuxsuren/test"))
+	assert.Len(t, name, len("gitops-repo-")+16)
+}
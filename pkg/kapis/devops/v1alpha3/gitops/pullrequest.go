@@ -0,0 +1,310 @@
+/*
+Copyright 2022 The KubeSphere Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gitops
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	goscm "github.com/jenkins-x/go-scm/scm"
+	"k8s.io/klog/v2"
+)
+
+// maxOpenPullRequests is the max number of the open pull requests which are listed
+const maxOpenPullRequests = 500
+
+func (s *gitRepoService) CreatePullRequest(ctx context.Context, input *CreatePullRequestInput) (*CreatePullRequestOutput, error) {
+	if len(input.Base) == 0 || len(input.Title) == 0 || len(input.Files) == 0 {
+		return nil, os.ErrInvalid
+	}
+	if s.pullRequests == nil {
+		return nil, errors.New("pull requests are not supported by this repository")
+	}
+	pullRequests, err := s.pullRequests()
+	if err != nil {
+		return nil, err
+	}
+
+	head := input.Head
+	if head == "" {
+		head = fmt.Sprintf("gitops/%s-%s", input.Base, time.Now().Format("20060102150405"))
+	}
+	if head == input.Base {
+		return nil, fmt.Errorf("the head branch must be different from the base branch %s", input.Base)
+	}
+	message := input.Message
+	if message == "" {
+		message = input.Title
+	}
+
+	w, err := s.checkOutHeadBranch(ctx, input.Base, head)
+	if err != nil {
+		return nil, err
+	}
+	if err = s.writeFiles(w, input.Files, true); err != nil {
+		return nil, err
+	}
+	cpOut, err := s.CommitAndPush(ctx, &CommitAndPushInput{
+		Branch:   head,
+		WorkTree: w,
+		Message:  message,
+		SignOff:  true,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	prOut, err := pullRequests.OpenPullRequest(ctx, &OpenPullRequestInput{
+		Base:  input.Base,
+		Head:  head,
+		Title: input.Title,
+		Body:  input.Body,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &CreatePullRequestOutput{
+		Commit:      cpOut.Commit,
+		PullRequest: prOut.PullRequest,
+	}, nil
+}
+
+// checkOutHeadBranch checks out the head branch, it's created from the base branch if it does not exist in the remote.
+// The commits are added to the existing head branch, so that its pull request can be updated.
+func (s *gitRepoService) checkOutHeadBranch(ctx context.Context, base, head string) (*git.Worktree, error) {
+	branch := base
+	_, err := s.fetchBranch(head)
+	exists := err == nil
+	if exists {
+		branch = head
+	}
+
+	coOut, err := s.CheckOutBranch(ctx, &CheckOutBranchInput{
+		Branch: branch,
+		Force:  true,
+	})
+	if err != nil {
+		return nil, err
+	}
+	w := coOut.WorkTree
+	if _, err = s.CleanAndPull(ctx, &CleanAndPullInput{
+		WorkTree: w,
+		Branch:   branch,
+	}); err != nil || exists {
+		return w, err
+	}
+
+	baseHead, err := s.repo.Head()
+	if err != nil {
+		return nil, err
+	}
+	// the local branch might be left by a merged pull request whose branch was deleted
+	headRefName := plumbing.NewBranchReferenceName(head)
+	if err = s.repo.Storer.RemoveReference(headRefName); err != nil {
+		return nil, err
+	}
+	klog.InfoS("creating the head branch of the pull request", "base", base, "head", head)
+	err = w.Checkout(&git.CheckoutOptions{
+		Branch: headRefName,
+		Hash:   baseHead.Hash(),
+		Create: true,
+		Force:  true,
+	})
+	return w, err
+}
+
+type pullRequestService struct {
+	client *goscm.Client
+	// repo is the full name of the repository, like owner/name
+	repo string
+}
+
+var _ PullRequestService = &pullRequestService{}
+
+// NewPullRequestService creates a pull request service of a repository
+func NewPullRequestService(client *goscm.Client, repo string) PullRequestService {
+	return &pullRequestService{
+		client: client,
+		repo:   repo,
+	}
+}
+
+func (p *pullRequestService) OpenPullRequest(ctx context.Context, input *OpenPullRequestInput) (*OpenPullRequestOutput, error) {
+	if len(input.Base) == 0 || len(input.Head) == 0 || len(input.Title) == 0 {
+		return nil, os.ErrInvalid
+	}
+
+	pullRequests, err := p.listOpenPullRequests(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var pr *goscm.PullRequest
+	for _, item := range pullRequests {
+		if item.Head.Ref == input.Head && item.Base.Ref == input.Base {
+			pr = item
+			break
+		}
+	}
+
+	if pr == nil {
+		if pr, _, err = p.client.PullRequests.Create(ctx, p.repo, &goscm.PullRequestInput{
+			Title: input.Title,
+			Head:  input.Head,
+			Base:  input.Base,
+			Body:  input.Body,
+		}); err != nil {
+			return nil, fmt.Errorf("failed to create the pull request from %s to %s: %v", input.Head, input.Base, err)
+		}
+	}
+
+	out := &OpenPullRequestOutput{
+		PullRequest: convertPullRequest(pr),
+	}
+	p.fillCheckStatus(ctx, out.PullRequest)
+	return out, nil
+}
+
+func (p *pullRequestService) ListPullRequests(ctx context.Context, input *ListPullRequestsInput) (*ListPullRequestsOutput, error) {
+	pullRequests, err := p.listOpenPullRequests(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	options := input.Options
+	if options == nil {
+		options = &ListOptions{}
+	}
+	options.Correct()
+	out := &ListPullRequestsOutput{
+		Items:      []*PullRequest{},
+		TotalItems: len(pullRequests),
+		Options:    options,
+	}
+
+	start := (options.Page - 1) * options.Limit
+	for i := start; i < len(pullRequests) && i < start+options.Limit; i++ {
+		// the mergeable state is only available in the details of a pull request
+		pr, _, err := p.client.PullRequests.Find(ctx, p.repo, pullRequests[i].Number)
+		if err != nil {
+			return nil, err
+		}
+		item := convertPullRequest(pr)
+		p.fillCheckStatus(ctx, item)
+		out.Items = append(out.Items, item)
+	}
+	return out, nil
+}
+
+func (p *pullRequestService) GetPullRequest(ctx context.Context, input *GetPullRequestInput) (*GetPullRequestOutput, error) {
+	if input.Number <= 0 {
+		return nil, os.ErrInvalid
+	}
+	pr, _, err := p.client.PullRequests.Find(ctx, p.repo, input.Number)
+	if err != nil {
+		return nil, err
+	}
+	out := &GetPullRequestOutput{
+		PullRequest: convertPullRequest(pr),
+	}
+	p.fillCheckStatus(ctx, out.PullRequest)
+	return out, nil
+}
+
+func (p *pullRequestService) listOpenPullRequests(ctx context.Context) (result []*goscm.PullRequest, err error) {
+	opts := &goscm.PullRequestListOptions{Page: 1, Size: 100, Open: true}
+	for len(result) < maxOpenPullRequests {
+		var pullRequests []*goscm.PullRequest
+		var resp *goscm.Response
+		if pullRequests, resp, err = p.client.PullRequests.List(ctx, p.repo, opts); err != nil {
+			err = fmt.Errorf("failed to list the pull requests of %s: %v", p.repo, err)
+			return
+		}
+		result = append(result, pullRequests...)
+		if resp == nil || resp.Page.Next == 0 || len(pullRequests) == 0 {
+			break
+		}
+		opts.Page = resp.Page.Next
+	}
+	return
+}
+
+// fillCheckStatus fills the combined status of the head commit, the pull request is still returned if it fails
+func (p *pullRequestService) fillCheckStatus(ctx context.Context, pr *PullRequest) {
+	if pr.Sha == "" {
+		return
+	}
+	status, _, err := p.client.Repositories.FindCombinedStatus(ctx, p.repo, pr.Sha)
+	if err != nil {
+		klog.V(4).InfoS("failed to get the check status of pull request", "repo", p.repo, "number", pr.Number, "error", err)
+		return
+	}
+
+	pr.CheckState = status.State.String()
+	pr.Checks = make([]*PullRequestCheck, 0, len(status.Statuses))
+	for _, item := range status.Statuses {
+		link := item.Target
+		if link == "" {
+			link = item.Link
+		}
+		pr.Checks = append(pr.Checks, &PullRequestCheck{
+			Name:        item.Label,
+			State:       item.State.String(),
+			Description: item.Desc,
+			Link:        link,
+		})
+	}
+}
+
+func convertPullRequest(pr *goscm.PullRequest) *PullRequest {
+	mergeableState := string(pr.MergeableState)
+	if mergeableState == "" {
+		mergeableState = "unknown"
+	}
+	head, base := pr.Head.Ref, pr.Base.Ref
+	if head == "" {
+		head = pr.Source
+	}
+	if base == "" {
+		base = pr.Target
+	}
+	sha := pr.Head.Sha
+	if sha == "" {
+		sha = pr.Sha
+	}
+	return &PullRequest{
+		Number:         pr.Number,
+		Title:          pr.Title,
+		Body:           pr.Body,
+		Link:           pr.Link,
+		Author:         pr.Author.Login,
+		Head:           head,
+		Base:           base,
+		Sha:            sha,
+		State:          pr.State,
+		Draft:          pr.Draft,
+		Merged:         pr.Merged,
+		Mergeable:      pr.Mergeable,
+		MergeableState: mergeableState,
+		Created:        pr.Created,
+		Updated:        pr.Updated,
+	}
+}
@@ -0,0 +1,250 @@
+/*
+Copyright 2022 The KubeSphere Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gitops
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"testing"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/h2non/gock"
+	"github.com/jenkins-x/go-scm/scm/driver/github"
+	"github.com/stretchr/testify/assert"
+)
+
+type fakePullRequestService struct {
+	opened []*OpenPullRequestInput
+}
+
+func (f *fakePullRequestService) OpenPullRequest(ctx context.Context, input *OpenPullRequestInput) (*OpenPullRequestOutput, error) {
+	f.opened = append(f.opened, input)
+	return &OpenPullRequestOutput{PullRequest: &PullRequest{Number: len(f.opened), Head: input.Head, Base: input.Base}}, nil
+}
+
+func (f *fakePullRequestService) ListPullRequests(ctx context.Context, input *ListPullRequestsInput) (*ListPullRequestsOutput, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (f *fakePullRequestService) GetPullRequest(ctx context.Context, input *GetPullRequestInput) (*GetPullRequestOutput, error) {
+	return nil, errors.New("not implemented")
+}
+
+func getRemoteFile(t *testing.T, remoteDir, branch, name string) string {
+	remote, err := git.PlainOpen(remoteDir)
+	assert.Nil(t, err)
+	ref, err := remote.Reference(plumbing.NewBranchReferenceName(branch), true)
+	if !assert.Nil(t, err) {
+		return ""
+	}
+	commit, err := remote.CommitObject(ref.Hash())
+	assert.Nil(t, err)
+	file, err := commit.File(name)
+	if !assert.Nil(t, err) {
+		return ""
+	}
+	content, err := file.Contents()
+	assert.Nil(t, err)
+	return content
+}
+
+func TestCreatePullRequest(t *testing.T) {
+	remoteDir := newTestRemote(t, map[string]string{"a.txt": "a"})
+	s := newTestService(t, remoteDir, "alice")
+	prService := &fakePullRequestService{}
+	s.pullRequests = func() (PullRequestService, error) {
+		return prService, nil
+	}
+	ctx := context.Background()
+
+	// invalid input
+	_, err := s.CreatePullRequest(ctx, &CreatePullRequestInput{Base: "master", Title: "update"})
+	assert.Equal(t, os.ErrInvalid, err)
+	_, err = s.CreatePullRequest(ctx, &CreatePullRequestInput{Base: "master", Head: "master", Title: "update",
+		Files: []*FileNameData{{Name: "a.txt", Data: []byte("b")}}})
+	assert.NotNil(t, err)
+
+	// create the head branch from the base one
+	out, err := s.CreatePullRequest(ctx, &CreatePullRequestInput{
+		Base:  "master",
+		Head:  "feature",
+		Title: "update a.txt",
+		Body:  "body",
+		Files: []*FileNameData{{Name: "a.txt", Data: []byte("feature")}, {Name: "dir/b.txt", Data: []byte("b")}},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, &OpenPullRequestInput{Base: "master", Head: "feature", Title: "update a.txt", Body: "body"}, prService.opened[0])
+	assert.Equal(t, "update a.txt\n\nSigned-off-by: alice", out.Commit.Message)
+	assert.Equal(t, []string{getRemoteHead(t, remoteDir).String()}, out.Commit.ParentHashes)
+	assert.Equal(t, "feature", getRemoteFile(t, remoteDir, "feature", "a.txt"))
+	assert.Equal(t, "b", getRemoteFile(t, remoteDir, "feature", "dir/b.txt"))
+	assert.Equal(t, "a", getRemoteFile(t, remoteDir, "master", "a.txt"))
+
+	// add a commit to the existing head branch by another clone
+	bob := newTestService(t, remoteDir, "bob")
+	bob.pullRequests = s.pullRequests
+	out2, err := bob.CreatePullRequest(ctx, &CreatePullRequestInput{
+		Base:    "master",
+		Head:    "feature",
+		Title:   "update a.txt",
+		Message: "more changes",
+		Files:   []*FileNameData{{Name: "c.txt", Data: []byte("c")}},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{out.Commit.Hash}, out2.Commit.ParentHashes)
+	assert.Equal(t, "feature", getRemoteFile(t, remoteDir, "feature", "a.txt"))
+	assert.Equal(t, "c", getRemoteFile(t, remoteDir, "feature", "c.txt"))
+
+	// generate the head branch
+	out3, err := s.CreatePullRequest(ctx, &CreatePullRequestInput{
+		Base:  "master",
+		Title: "update",
+		Files: []*FileNameData{{Name: "a.txt", Data: []byte("generated")}},
+	})
+	assert.Nil(t, err)
+	assert.Regexp(t, "^gitops/master-[0-9]+$", out3.PullRequest.Head)
+	assert.Equal(t, "generated", getRemoteFile(t, remoteDir, out3.PullRequest.Head, "a.txt"))
+
+	// not supported
+	s.pullRequests = nil
+	_, err = s.CreatePullRequest(ctx, &CreatePullRequestInput{Base: "master", Title: "update",
+		Files: []*FileNameData{{Name: "a.txt", Data: []byte("b")}}})
+	assert.NotNil(t, err)
+}
+
+func TestPullRequestService(t *testing.T) {
+	defer gock.Off()
+	ctx := context.Background()
+	service := NewPullRequestService(github.NewDefault(), "linuxsuren/test")
+
+	openPullRequests := []map[string]interface{}{{
+		"number": 1, "title": "first", "state": "open", "html_url": "https://github.com/linuxsuren/test/pull/1",
+		"head": map[string]string{"ref": "feature", "sha": "sha1"}, "base": map[string]string{"ref": "master"},
+		"user": map[string]string{"login": "alice"},
+	}, {
+		"number": 2, "title": "second", "state": "open",
+		"head": map[string]string{"ref": "fix", "sha": "sha2"}, "base": map[string]string{"ref": "master"},
+	}}
+	mockList := func() {
+		gock.New("https://api.github.com").
+			Get("/repos/linuxsuren/test/pulls").
+			MatchParam("per_page", "100").
+			Reply(http.StatusOK).
+			JSON(openPullRequests)
+	}
+	mockFind := func(number int, pr map[string]interface{}, mergeableState string) {
+		details := map[string]interface{}{"mergeable": mergeableState == "clean", "mergeable_state": mergeableState}
+		for k, v := range pr {
+			details[k] = v
+		}
+		gock.New("https://api.github.com").
+			Get(fmt.Sprintf("/repos/linuxsuren/test/pulls/%d", number)).
+			Reply(http.StatusOK).
+			JSON(details)
+	}
+	mockStatus := func(sha string, code int) {
+		gock.New("https://api.github.com").
+			Get("/repos/linuxsuren/test/commits/" + sha + "/status").
+			Reply(code).
+			JSON(map[string]interface{}{"state": "pending", "sha": sha, "statuses": []map[string]string{{
+				"state": "success", "context": "ci/lint", "description": "passed", "target_url": "https://ci.example.com/1",
+			}, {
+				"state": "pending", "context": "ci/test",
+			}}})
+	}
+
+	t.Run("list the open pull requests", func(t *testing.T) {
+		mockList()
+		mockFind(2, openPullRequests[1], "dirty")
+		mockStatus("sha2", http.StatusNotFound)
+
+		out, err := service.ListPullRequests(ctx, &ListPullRequestsInput{Options: &ListOptions{Page: 2, Limit: 1}})
+		assert.Nil(t, err)
+		assert.Equal(t, 2, out.TotalItems)
+		if assert.Equal(t, 1, len(out.Items)) {
+			// the check status is missing, but the pull request is still returned
+			assert.Equal(t, &PullRequest{Number: 2, Title: "second", State: "open", Head: "fix", Base: "master",
+				Sha: "sha2", MergeableState: "conflicting"}, out.Items[0])
+		}
+		assert.True(t, gock.IsDone())
+	})
+
+	t.Run("get a pull request", func(t *testing.T) {
+		mockFind(1, openPullRequests[0], "clean")
+		mockStatus("sha1", http.StatusOK)
+
+		out, err := service.GetPullRequest(ctx, &GetPullRequestInput{Number: 1})
+		assert.Nil(t, err)
+		assert.Equal(t, &PullRequest{Number: 1, Title: "first", State: "open", Author: "alice",
+			Link: "https://github.com/linuxsuren/test/pull/1", Head: "feature", Base: "master", Sha: "sha1",
+			Mergeable: true, MergeableState: "mergeable", CheckState: "pending",
+			Checks: []*PullRequestCheck{
+				{Name: "ci/lint", State: "success", Description: "passed", Link: "https://ci.example.com/1"},
+				{Name: "ci/test", State: "pending"},
+			}}, out.PullRequest)
+		assert.True(t, gock.IsDone())
+
+		_, err = service.GetPullRequest(ctx, &GetPullRequestInput{})
+		assert.NotNil(t, err)
+	})
+
+	t.Run("reuse the existing pull request", func(t *testing.T) {
+		mockList()
+		mockStatus("sha1", http.StatusNotFound)
+
+		out, err := service.OpenPullRequest(ctx, &OpenPullRequestInput{Base: "master", Head: "feature", Title: "new"})
+		assert.Nil(t, err)
+		assert.Equal(t, 1, out.PullRequest.Number)
+		assert.True(t, gock.IsDone())
+	})
+
+	t.Run("open a new pull request", func(t *testing.T) {
+		mockList()
+		gock.New("https://api.github.com").
+			Post("/repos/linuxsuren/test/pulls").
+			MatchType("json").
+			JSON(map[string]string{"title": "new", "head": "new-feature", "base": "master", "body": "body"}).
+			Reply(http.StatusCreated).
+			JSON(map[string]interface{}{
+				"number": 3, "title": "new", "state": "open",
+				"head": map[string]string{"ref": "new-feature", "sha": "sha3"}, "base": map[string]string{"ref": "master"},
+			})
+		mockStatus("sha3", http.StatusNotFound)
+
+		out, err := service.OpenPullRequest(ctx, &OpenPullRequestInput{Base: "master", Head: "new-feature", Title: "new", Body: "body"})
+		assert.Nil(t, err)
+		assert.Equal(t, 3, out.PullRequest.Number)
+		assert.Equal(t, "new-feature", out.PullRequest.Head)
+		assert.True(t, gock.IsDone())
+
+		_, err = service.OpenPullRequest(ctx, &OpenPullRequestInput{Base: "master"})
+		assert.NotNil(t, err)
+	})
+
+	t.Run("failed to list", func(t *testing.T) {
+		gock.New("https://api.github.com").
+			Get("/repos/linuxsuren/test/pulls").
+			Reply(http.StatusUnauthorized)
+
+		_, err := service.ListPullRequests(ctx, &ListPullRequestsInput{})
+		assert.NotNil(t, err)
+	})
+}
@@ -0,0 +1,279 @@
+/*
+Copyright 2022 The KubeSphere Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gitops
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/filemode"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"k8s.io/klog/v2"
+)
+
+// maxPushRetries is the max times of rebasing and pushing again when the push was rejected
+const maxPushRetries = 3
+
+// pushWithRetry pushes the branch without force. If the push was rejected because of the new commits on the remote
+// branch, the local commit will be rebased onto them, then pushed again. It returns the hash of the pushed commit.
+// The local branch is reset to the remote one if it fails, so the working copy is clean for the next time.
+func (s *gitRepoService) pushWithRetry(w *git.Worktree, branch string, hash plumbing.Hash) (plumbing.Hash, error) {
+	var remoteHash plumbing.Hash
+	var err error
+	for i := 0; ; i++ {
+		if err = s.push(branch); err == nil || !isPushRejected(err) {
+			break
+		}
+		if i >= maxPushRetries {
+			err = fmt.Errorf("failed to push to branch %s after %d retries: %v", branch, maxPushRetries, err)
+			break
+		}
+
+		klog.InfoS("the push was rejected, fetching and rebasing", "branch", branch, "retry", i+1)
+		if remoteHash, err = s.fetchBranch(branch); err != nil {
+			break
+		}
+		if hash, err = s.rebaseOnto(w, branch, hash, remoteHash); err != nil {
+			break
+		}
+	}
+
+	if err != nil {
+		s.discardCommit(w, hash, remoteHash)
+		return plumbing.ZeroHash, err
+	}
+	return hash, nil
+}
+
+func (s *gitRepoService) push(branch string) error {
+	refSpec := config.RefSpec(fmt.Sprintf("refs/heads/%s:refs/heads/%s", branch, branch))
+	err := s.repo.Push(&git.PushOptions{
+		RemoteName:      "origin",
+		RefSpecs:        []config.RefSpec{refSpec},
+		Auth:            s.auth,
+		Progress:        os.Stdout,
+		InsecureSkipTLS: s.insecureSkipTLS,
+		CABundle:        s.caBundle,
+		Atomic:          true,
+		ProxyOptions:    s.proxyOptions,
+	})
+	if errors.Is(err, git.NoErrAlreadyUpToDate) {
+		err = nil
+	}
+	return err
+}
+
+// isPushRejected checks if the push was rejected because the remote branch has the commits which the local one doesn't have
+func isPushRejected(err error) bool {
+	if errors.Is(err, git.ErrForceNeeded) {
+		return true
+	}
+	message := err.Error()
+	return strings.Contains(message, "non-fast-forward") || strings.Contains(message, "fetch first")
+}
+
+// fetchBranch fetches the remote branch, then returns its latest commit
+func (s *gitRepoService) fetchBranch(branch string) (plumbing.Hash, error) {
+	remoteRefName := plumbing.NewRemoteReferenceName("origin", branch)
+	if err := s.fetchOrigin(fmt.Sprintf("+refs/heads/%s:%s", branch, remoteRefName)); err != nil {
+		return plumbing.ZeroHash, err
+	}
+	ref, err := s.repo.Reference(remoteRefName, true)
+	if err != nil {
+		return plumbing.ZeroHash, err
+	}
+	return ref.Hash(), nil
+}
+
+// rebaseOnto replays the changes of the local commit onto the remote commit, like `git rebase`.
+// It returns a ConflictError if the files were changed differently on the remote branch.
+func (s *gitRepoService) rebaseOnto(w *git.Worktree, branch string, hash, remoteHash plumbing.Hash) (plumbing.Hash, error) {
+	commit, err := s.repo.CommitObject(hash)
+	if err != nil {
+		return plumbing.ZeroHash, err
+	}
+	remoteCommit, err := s.repo.CommitObject(remoteHash)
+	if err != nil {
+		return plumbing.ZeroHash, err
+	}
+
+	// the parent of the local commit is where the local branch forked from the remote one
+	var baseTree *object.Tree
+	if commit.NumParents() > 0 {
+		parent, err := commit.Parent(0)
+		if err != nil {
+			return plumbing.ZeroHash, err
+		}
+		if baseTree, err = parent.Tree(); err != nil {
+			return plumbing.ZeroHash, err
+		}
+	}
+	tree, err := commit.Tree()
+	if err != nil {
+		return plumbing.ZeroHash, err
+	}
+	remoteTree, err := remoteCommit.Tree()
+	if err != nil {
+		return plumbing.ZeroHash, err
+	}
+
+	ours, err := getChangedFiles(baseTree, tree)
+	if err != nil {
+		return plumbing.ZeroHash, err
+	}
+	theirs, err := getChangedFiles(baseTree, remoteTree)
+	if err != nil {
+		return plumbing.ZeroHash, err
+	}
+	if conflicts := getConflictPaths(ours, theirs); len(conflicts) > 0 {
+		return plumbing.ZeroHash, &ConflictError{Branch: branch, Paths: conflicts}
+	}
+
+	if err = w.Reset(&git.ResetOptions{Commit: remoteHash, Mode: git.HardReset}); err != nil {
+		return plumbing.ZeroHash, err
+	}
+	root := w.Filesystem.Root()
+	for path, fileHash := range ours {
+		if err = s.applyFile(root, tree, path, fileHash); err != nil {
+			return plumbing.ZeroHash, err
+		}
+	}
+
+	if _, err = w.Add("."); err != nil {
+		return plumbing.ZeroHash, err
+	}
+	status, err := w.Status()
+	if err != nil {
+		return plumbing.ZeroHash, err
+	}
+	if status.IsClean() {
+		// the same changes were pushed by others
+		return remoteHash, nil
+	}
+	author := commit.Author
+	return w.Commit(commit.Message, &git.CommitOptions{
+		All:    true,
+		Author: &author,
+		Signer: s.signer,
+	})
+}
+
+// getChangedFiles returns the changed files between two trees, the hash is zero if the file was deleted
+func getChangedFiles(from, to *object.Tree) (files map[string]plumbing.Hash, err error) {
+	var changes object.Changes
+	if changes, err = object.DiffTree(from, to); err != nil {
+		return
+	}
+
+	files = map[string]plumbing.Hash{}
+	for _, change := range changes {
+		if change.From.Name != "" {
+			files[change.From.Name] = plumbing.ZeroHash
+		}
+		if change.To.Name != "" {
+			files[change.To.Name] = change.To.TreeEntry.Hash
+		}
+	}
+	return
+}
+
+// getConflictPaths returns the sorted paths which were changed on both sides, but have different results
+func getConflictPaths(ours, theirs map[string]plumbing.Hash) (paths []string) {
+	for path, hash := range ours {
+		if theirHash, ok := theirs[path]; ok && theirHash != hash {
+			paths = append(paths, path)
+		}
+	}
+	sort.Strings(paths)
+	return
+}
+
+// applyFile writes the file of the tree into the work tree, or deletes it if the hash is zero
+func (s *gitRepoService) applyFile(root string, tree *object.Tree, path string, hash plumbing.Hash) (err error) {
+	filePath := filepath.Join(root, path)
+	if hash.IsZero() {
+		if err = os.Remove(filePath); errors.Is(err, os.ErrNotExist) {
+			err = nil
+		}
+		return
+	}
+
+	var file *object.File
+	if file, err = tree.File(path); err != nil {
+		return
+	}
+	if err = os.RemoveAll(filePath); err != nil {
+		return
+	}
+	if err = os.MkdirAll(filepath.Dir(filePath), s.newFilePerm); err != nil {
+		return
+	}
+
+	var reader io.ReadCloser
+	if reader, err = file.Reader(); err != nil {
+		return
+	}
+	defer func() {
+		_ = reader.Close()
+	}()
+
+	if file.Mode == filemode.Symlink {
+		var target []byte
+		if target, err = io.ReadAll(reader); err == nil {
+			err = os.Symlink(string(target), filePath)
+		}
+		return
+	}
+
+	// keep the mode of the commit, otherwise the file is considered changed
+	var perm os.FileMode
+	if perm, err = file.Mode.ToOSFileMode(); err != nil {
+		return
+	}
+	var dst *os.File
+	if dst, err = os.OpenFile(filePath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, perm); err != nil {
+		return
+	}
+	defer func() {
+		_ = dst.Close()
+	}()
+	_, err = io.Copy(dst, reader)
+	return
+}
+
+// discardCommit resets the local branch to the remote commit, or the parent of the local commit if the remote one is unknown
+func (s *gitRepoService) discardCommit(w *git.Worktree, hash, remoteHash plumbing.Hash) {
+	target := remoteHash
+	if target.IsZero() {
+		commit, err := s.repo.CommitObject(hash)
+		if err != nil || commit.NumParents() == 0 {
+			return
+		}
+		target = commit.ParentHashes[0]
+	}
+	if err := w.Reset(&git.ResetOptions{Commit: target, Mode: git.HardReset}); err != nil {
+		klog.ErrorS(err, "failed to discard the local commit", "commit", hash)
+	}
+}
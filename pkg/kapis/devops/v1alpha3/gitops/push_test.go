@@ -0,0 +1,167 @@
+/*
+Copyright 2022 The KubeSphere Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gitops
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/stretchr/testify/assert"
+)
+
+// newTestRemote creates a bare repository with the files in the master branch
+func newTestRemote(t *testing.T, files map[string]string) string {
+	remoteDir := filepath.Join(t.TempDir(), "remote.git")
+	_, err := git.PlainInit(remoteDir, true)
+	assert.Nil(t, err)
+
+	seedDir := filepath.Join(t.TempDir(), "seed")
+	seed, err := git.PlainInit(seedDir, false)
+	assert.Nil(t, err)
+	w, err := seed.Worktree()
+	assert.Nil(t, err)
+	for name, content := range files {
+		assert.Nil(t, os.WriteFile(filepath.Join(seedDir, name), []byte(content), 0644))
+	}
+	_, err = w.Add(".")
+	assert.Nil(t, err)
+	_, err = w.Commit("init", &git.CommitOptions{Author: &object.Signature{Name: "seed"}})
+	assert.Nil(t, err)
+
+	_, err = seed.CreateRemote(&config.RemoteConfig{Name: "origin", URLs: []string{remoteDir}})
+	assert.Nil(t, err)
+	assert.Nil(t, seed.Push(&git.PushOptions{
+		RemoteName: "origin",
+		RefSpecs:   []config.RefSpec{"refs/heads/master:refs/heads/master"},
+	}))
+	return remoteDir
+}
+
+func newTestService(t *testing.T, remoteDir, name string) *gitRepoService {
+	repo, err := git.PlainClone(filepath.Join(t.TempDir(), name), false, &git.CloneOptions{URL: remoteDir})
+	assert.Nil(t, err)
+	return &gitRepoService{
+		author:      &object.Signature{Name: name},
+		repo:        repo,
+		newFilePerm: 0755,
+	}
+}
+
+func commitFiles(t *testing.T, s *gitRepoService, files map[string]string) (*CommitAndPushOutput, error) {
+	w, err := s.repo.Worktree()
+	assert.Nil(t, err)
+	for name, content := range files {
+		filePath := filepath.Join(w.Filesystem.Root(), name)
+		if content == "" {
+			assert.Nil(t, os.Remove(filePath))
+		} else {
+			assert.Nil(t, os.WriteFile(filePath, []byte(content), 0644))
+		}
+	}
+	return s.CommitAndPush(context.Background(), &CommitAndPushInput{
+		Branch:   "master",
+		WorkTree: w,
+		Message:  "update",
+	})
+}
+
+func getRemoteHead(t *testing.T, remoteDir string) plumbing.Hash {
+	remote, err := git.PlainOpen(remoteDir)
+	assert.Nil(t, err)
+	ref, err := remote.Reference(plumbing.NewBranchReferenceName("master"), true)
+	assert.Nil(t, err)
+	return ref.Hash()
+}
+
+func TestCommitAndPush(t *testing.T) {
+	files := map[string]string{"a.txt": "a", "b.txt": "b", "c.txt": "c"}
+
+	t.Run("push without conflicts", func(t *testing.T) {
+		remoteDir := newTestRemote(t, files)
+		alice := newTestService(t, remoteDir, "alice")
+		bob := newTestService(t, remoteDir, "bob")
+
+		aliceOut, err := commitFiles(t, alice, map[string]string{"a.txt": "alice"})
+		assert.Nil(t, err)
+
+		// bob's clone is behind, the commit should be rebased onto alice's one
+		bobOut, err := commitFiles(t, bob, map[string]string{"b.txt": "bob", "c.txt": ""})
+		assert.Nil(t, err)
+		assert.Equal(t, getRemoteHead(t, remoteDir).String(), bobOut.Commit.Hash)
+		assert.Equal(t, []string{aliceOut.Commit.Hash}, bobOut.Commit.ParentHashes)
+
+		commit, err := bob.repo.CommitObject(plumbing.NewHash(bobOut.Commit.Hash))
+		assert.Nil(t, err)
+		for name, expected := range map[string]string{"a.txt": "alice", "b.txt": "bob"} {
+			file, err := commit.File(name)
+			if assert.Nil(t, err) {
+				content, _ := file.Contents()
+				assert.Equal(t, expected, content)
+			}
+		}
+		_, err = commit.File("c.txt")
+		assert.Equal(t, object.ErrFileNotFound, err)
+	})
+
+	t.Run("conflict with others", func(t *testing.T) {
+		remoteDir := newTestRemote(t, files)
+		alice := newTestService(t, remoteDir, "alice")
+		bob := newTestService(t, remoteDir, "bob")
+
+		aliceOut, err := commitFiles(t, alice, map[string]string{"a.txt": "alice", "b.txt": "alice"})
+		assert.Nil(t, err)
+
+		_, err = commitFiles(t, bob, map[string]string{"a.txt": "bob", "b.txt": "alice", "c.txt": "bob"})
+		assert.Equal(t, &ConflictError{Branch: "master", Paths: []string{"a.txt"}}, err)
+		assert.Equal(t, aliceOut.Commit.Hash, getRemoteHead(t, remoteDir).String())
+
+		// the local branch should be the same as the remote one
+		head, err := bob.repo.Head()
+		assert.Nil(t, err)
+		assert.Equal(t, aliceOut.Commit.Hash, head.Hash().String())
+	})
+
+	t.Run("same changes as others", func(t *testing.T) {
+		remoteDir := newTestRemote(t, files)
+		alice := newTestService(t, remoteDir, "alice")
+		bob := newTestService(t, remoteDir, "bob")
+
+		aliceOut, err := commitFiles(t, alice, map[string]string{"a.txt": "same"})
+		assert.Nil(t, err)
+		bobOut, err := commitFiles(t, bob, map[string]string{"a.txt": "same"})
+		assert.Nil(t, err)
+		assert.Equal(t, aliceOut.Commit.Hash, bobOut.Commit.Hash)
+	})
+}
+
+func TestConflictError(t *testing.T) {
+	err := &ConflictError{Branch: "master", Paths: []string{"a.txt", "b.txt"}}
+	assert.Equal(t, "the following files of branch master were changed by others, please reload them and retry: a.txt, b.txt",
+		err.Error())
+
+	status := err.Status()
+	assert.Equal(t, int32(409), status.Code)
+	if assert.NotNil(t, status.Details) && assert.Len(t, status.Details.Causes, 2) {
+		assert.Equal(t, "a.txt", status.Details.Causes[0].Field)
+	}
+}
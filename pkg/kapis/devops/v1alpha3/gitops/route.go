@@ -38,6 +38,7 @@ var (
 	pathParameterBranch          = restful.PathParameter("branch", "The branch of git repository").DataType("string")
 	pathParameterCommit          = restful.PathParameter("commit", "The commit hash").DataType("string")
 	pathParameterFile            = restful.PathParameter("file", "base64 encoded file path").DataType("string")
+	pathParameterPullRequest     = restful.PathParameter("pullrequest", "The number of the pull request").DataType("integer")
 	queryParameterFile           = restful.QueryParameter("file", "the relative path of the file or directory in the git repository").DataType("string")
 	queryParameterMessage        = restful.QueryParameter("message", "the commit message").DataType("string")
 	queryParameterWithContent    = restful.QueryParameter("withContent", "whether get the base64 encoded content of the file").DataType("boolean")
@@ -103,6 +104,36 @@ func RegisterRouters(ws *restful.WebService, h Handler) {
 		Doc("clean and pull branch").
 		Returns(http.StatusOK, api.StatusOK, CleanAndPullOutput{}))
 
+	ws.Route(ws.POST("/namespaces/{namespace}/gitrepositories/{gitrepository}/branches/{branch}/pullrequests").
+		To(h.CreatePullRequest).
+		Metadata(restfulspec.KeyOpenAPITags, constants.GitOpsTags).
+		Param(common.NamespacePathParameter).
+		Param(pathParameterGitRepository).
+		Param(pathParameterBranch.Description("The base branch which the pull request is merged into")).
+		Reads(CreatePullRequestInput{}).
+		Doc("commit the files to a feature branch, then open a pull request against the branch").
+		Notes("the feature branch is generated if the head is empty, the existing open pull request of the same branches is reused").
+		Returns(http.StatusOK, api.StatusOK, CreatePullRequestOutput{}))
+
+	ws.Route(ws.GET("/namespaces/{namespace}/gitrepositories/{gitrepository}/pullrequests").
+		To(h.ListPullRequests).
+		Metadata(restfulspec.KeyOpenAPITags, constants.GitOpsTags).
+		Param(common.NamespacePathParameter).
+		Param(pathParameterGitRepository).
+		Param(ws.QueryParameter(query.ParameterPage, "page").Required(false).DataFormat("page=%d").DefaultValue("page=1")).
+		Param(ws.QueryParameter(query.ParameterLimit, "limit").Required(false)).
+		Doc("list the open pull requests with their merge and check status").
+		Returns(http.StatusOK, api.StatusOK, ListPullRequestsOutput{}))
+
+	ws.Route(ws.GET("/namespaces/{namespace}/gitrepositories/{gitrepository}/pullrequests/{pullrequest}").
+		To(h.GetPullRequest).
+		Metadata(restfulspec.KeyOpenAPITags, constants.GitOpsTags).
+		Param(common.NamespacePathParameter).
+		Param(pathParameterGitRepository).
+		Param(pathParameterPullRequest).
+		Doc("get the pull request with its merge and check status").
+		Returns(http.StatusOK, api.StatusOK, PullRequest{}))
+
 	ws.Route(ws.GET("/namespaces/{namespace}/gitrepositories/{gitrepository}/branches/{branch}/commits").
 		To(h.ListCommits).
 		Metadata(restfulspec.KeyOpenAPITags, constants.GitOpsTags).
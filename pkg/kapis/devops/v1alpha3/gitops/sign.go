@@ -0,0 +1,308 @@
+/*
+Copyright 2022 The KubeSphere Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gitops
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/kubesphere/ks-devops/pkg/api/devops/v1alpha3"
+	"golang.org/x/crypto/ssh"
+	v1 "k8s.io/api/core/v1"
+)
+
+const (
+	// SignatureFormatGPG is the format of the OpenPGP signatures
+	SignatureFormatGPG = "gpg"
+	// SignatureFormatSSH is the format of the SSH signatures
+	SignatureFormatSSH = "ssh"
+
+	pgpSignaturePrefix = "-----BEGIN PGP SIGNATURE-----"
+	sshSignatureType   = "SSH SIGNATURE"
+
+	// the constants of https://github.com/openssh/openssh-portable/blob/master/PROTOCOL.sshsig
+	sshSigMagic         = "SSHSIG"
+	sshSigVersion       = 1
+	sshSigNamespace     = "git"
+	sshSigHashAlgorithm = "sha512"
+)
+
+// CommitSignature is the verification result of the signature of a commit
+type CommitSignature struct {
+	Format   string `json:"format"`
+	Verified bool   `json:"verified"`
+	// KeyID is the ID of the OpenPGP key, or the fingerprint of the SSH key
+	KeyID string `json:"keyID,omitempty"`
+	// Reason is why the signature is not verified
+	Reason string `json:"reason,omitempty"`
+}
+
+// CommitSigner signs the commits and verifies the signatures with the same key
+type CommitSigner interface {
+	git.Signer
+	// Verify verifies the signature of a commit, it returns nil if the commit is not signed
+	Verify(commit *object.Commit) *CommitSignature
+}
+
+// NewCommitSigner creates a signer from a ssh-auth or gpg-key DevOps credential
+func NewCommitSigner(secret *v1.Secret) (CommitSigner, error) {
+	switch secret.Type {
+	case v1alpha3.SecretTypeGPGKey:
+		return newGPGSigner(secret.Data[v1alpha3.GPGKeyPrivateKey], secret.Data[v1alpha3.GPGKeyPassphraseKey])
+	case v1alpha3.SecretTypeSSHAuth:
+		return newSSHSigner(secret.Data[v1alpha3.SSHAuthPrivateKey], secret.Data[v1alpha3.SSHAuthPassphraseKey])
+	case v1.SecretTypeSSHAuth:
+		return newSSHSigner(secret.Data[v1.SSHAuthPrivateKey], nil)
+	default:
+		return nil, fmt.Errorf("secret %s/%s of type %s cannot sign commits, the supported types are %s and %s",
+			secret.Namespace, secret.Name, secret.Type, v1alpha3.SecretTypeGPGKey, v1alpha3.SecretTypeSSHAuth)
+	}
+}
+
+// verifyCommitSignature verifies the signature of a commit, the signer could be nil if there is no signing key
+func verifyCommitSignature(signer CommitSigner, commit *object.Commit) *CommitSignature {
+	if commit == nil || commit.PGPSignature == "" {
+		return nil
+	}
+	if signer == nil {
+		return &CommitSignature{
+			Format: getSignatureFormat(commit.PGPSignature),
+			Reason: "no signing key is configured in the git repository",
+		}
+	}
+	return signer.Verify(commit)
+}
+
+func getSignatureFormat(signature string) string {
+	switch {
+	case strings.HasPrefix(signature, pgpSignaturePrefix):
+		return SignatureFormatGPG
+	case strings.HasPrefix(signature, "-----BEGIN "+sshSignatureType+"-----"):
+		return SignatureFormatSSH
+	default:
+		return "unknown"
+	}
+}
+
+// getSignedPayload returns the content of a commit without the signature, it's the payload of the signature
+func getSignedPayload(commit *object.Commit) ([]byte, error) {
+	encoded := &plumbing.MemoryObject{}
+	if err := commit.EncodeWithoutSignature(encoded); err != nil {
+		return nil, err
+	}
+	reader, err := encoded.Reader()
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = reader.Close()
+	}()
+	return io.ReadAll(reader)
+}
+
+type gpgSigner struct {
+	entity *openpgp.Entity
+}
+
+func newGPGSigner(privateKey, passphrase []byte) (CommitSigner, error) {
+	entities, err := openpgp.ReadArmoredKeyRing(bytes.NewReader(privateKey))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the OpenPGP private key: %v", err)
+	}
+	if len(entities) == 0 || entities[0].PrivateKey == nil {
+		return nil, errors.New("no OpenPGP private key found")
+	}
+	entity := entities[0]
+	if err = entity.DecryptPrivateKeys(passphrase); err != nil {
+		return nil, fmt.Errorf("failed to decrypt the OpenPGP private key: %v", err)
+	}
+	return &gpgSigner{entity: entity}, nil
+}
+
+func (s *gpgSigner) Sign(message io.Reader) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := openpgp.ArmoredDetachSign(&buf, s.entity, message, nil); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (s *gpgSigner) Verify(commit *object.Commit) *CommitSignature {
+	result := &CommitSignature{Format: getSignatureFormat(commit.PGPSignature)}
+	if result.Format != SignatureFormatGPG {
+		result.Reason = "the signature is not an OpenPGP signature"
+		return result
+	}
+
+	payload, err := getSignedPayload(commit)
+	if err == nil {
+		var entity *openpgp.Entity
+		entity, err = openpgp.CheckArmoredDetachedSignature(openpgp.EntityList{s.entity}, bytes.NewReader(payload),
+			strings.NewReader(commit.PGPSignature), nil)
+		if err == nil {
+			result.Verified = true
+			result.KeyID = fmt.Sprintf("%X", entity.PrimaryKey.KeyId)
+		}
+	}
+	if err != nil {
+		result.Reason = err.Error()
+	}
+	return result
+}
+
+// sshSigner signs the commits in the format of `ssh-keygen -Y sign`, it's the same as `git config gpg.format ssh`
+type sshSigner struct {
+	signer ssh.Signer
+}
+
+func newSSHSigner(privateKey, passphrase []byte) (CommitSigner, error) {
+	var signer ssh.Signer
+	var err error
+	if len(passphrase) > 0 {
+		signer, err = ssh.ParsePrivateKeyWithPassphrase(privateKey, passphrase)
+	} else {
+		signer, err = ssh.ParsePrivateKey(privateKey)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse the SSH private key: %v", err)
+	}
+	return &sshSigner{signer: signer}, nil
+}
+
+// sshSignedData is the data which is signed actually
+type sshSignedData struct {
+	Namespace     string
+	Reserved      string
+	HashAlgorithm string
+	Hash          string
+}
+
+// sshSignatureBlob is the content of the armored SSH signature
+type sshSignatureBlob struct {
+	Version       uint32
+	PublicKey     string
+	Namespace     string
+	Reserved      string
+	HashAlgorithm string
+	Signature     string
+}
+
+func getSSHSignedData(message []byte) []byte {
+	hash := sha512.Sum512(message)
+	return append([]byte(sshSigMagic), ssh.Marshal(sshSignedData{
+		Namespace:     sshSigNamespace,
+		HashAlgorithm: sshSigHashAlgorithm,
+		Hash:          string(hash[:]),
+	})...)
+}
+
+func (s *sshSigner) Sign(message io.Reader) ([]byte, error) {
+	data, err := io.ReadAll(message)
+	if err != nil {
+		return nil, err
+	}
+
+	var signature *ssh.Signature
+	signedData := getSSHSignedData(data)
+	if algorithmSigner, ok := s.signer.(ssh.AlgorithmSigner); ok && s.signer.PublicKey().Type() == ssh.KeyAlgoRSA {
+		// SHA-1 is not allowed by the SSH signatures
+		signature, err = algorithmSigner.SignWithAlgorithm(rand.Reader, signedData, ssh.KeyAlgoRSASHA512)
+	} else {
+		signature, err = s.signer.Sign(rand.Reader, signedData)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	blob := append([]byte(sshSigMagic), ssh.Marshal(sshSignatureBlob{
+		Version:       sshSigVersion,
+		PublicKey:     string(s.signer.PublicKey().Marshal()),
+		Namespace:     sshSigNamespace,
+		HashAlgorithm: sshSigHashAlgorithm,
+		Signature:     string(ssh.Marshal(signature)),
+	})...)
+	return armorSSHSignature(blob), nil
+}
+
+// armorSSHSignature encodes the signature like a PEM block, but the lines are wrapped at 70 characters as ssh-keygen does
+func armorSSHSignature(blob []byte) []byte {
+	encoded := base64.StdEncoding.EncodeToString(blob)
+	var buf bytes.Buffer
+	buf.WriteString("-----BEGIN " + sshSignatureType + "-----\n")
+	for len(encoded) > 70 {
+		buf.WriteString(encoded[:70] + "\n")
+		encoded = encoded[70:]
+	}
+	buf.WriteString(encoded + "\n")
+	buf.WriteString("-----END " + sshSignatureType + "-----\n")
+	return buf.Bytes()
+}
+
+func (s *sshSigner) Verify(commit *object.Commit) *CommitSignature {
+	result := &CommitSignature{Format: getSignatureFormat(commit.PGPSignature)}
+	if result.Format != SignatureFormatSSH {
+		result.Reason = "the signature is not a SSH signature"
+		return result
+	}
+
+	if err := s.verify(commit); err != nil {
+		result.Reason = err.Error()
+	} else {
+		result.Verified = true
+		result.KeyID = ssh.FingerprintSHA256(s.signer.PublicKey())
+	}
+	return result
+}
+
+func (s *sshSigner) verify(commit *object.Commit) error {
+	block, _ := pem.Decode([]byte(commit.PGPSignature))
+	if block == nil || block.Type != sshSignatureType || !bytes.HasPrefix(block.Bytes, []byte(sshSigMagic)) {
+		return errors.New("invalid SSH signature")
+	}
+	blob := &sshSignatureBlob{}
+	if err := ssh.Unmarshal(block.Bytes[len(sshSigMagic):], blob); err != nil {
+		return fmt.Errorf("invalid SSH signature: %v", err)
+	}
+	if blob.Version != sshSigVersion || blob.Namespace != sshSigNamespace || blob.HashAlgorithm != sshSigHashAlgorithm {
+		return fmt.Errorf("unsupported SSH signature, version: %d, namespace: %s, hash algorithm: %s",
+			blob.Version, blob.Namespace, blob.HashAlgorithm)
+	}
+	if !bytes.Equal([]byte(blob.PublicKey), s.signer.PublicKey().Marshal()) {
+		return errors.New("the commit is signed by another key")
+	}
+
+	signature := &ssh.Signature{}
+	if err := ssh.Unmarshal([]byte(blob.Signature), signature); err != nil {
+		return fmt.Errorf("invalid SSH signature: %v", err)
+	}
+	payload, err := getSignedPayload(commit)
+	if err != nil {
+		return err
+	}
+	return s.signer.PublicKey().Verify(getSSHSignedData(payload), signature)
+}
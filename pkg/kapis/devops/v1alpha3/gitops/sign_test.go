@@ -0,0 +1,223 @@
+/*
+Copyright 2022 The KubeSphere Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gitops
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/pem"
+	"strings"
+	"testing"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/armor"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/kubesphere/ks-devops/pkg/api/devops/v1alpha3"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/ssh"
+	v1 "k8s.io/api/core/v1"
+)
+
+func newGPGKey(t *testing.T, passphrase string) []byte {
+	entity, err := openpgp.NewEntity("bot", "", "bot@example.com", nil)
+	assert.Nil(t, err)
+	if passphrase != "" {
+		assert.Nil(t, entity.EncryptPrivateKeys([]byte(passphrase), nil))
+	}
+
+	var buf bytes.Buffer
+	writer, err := armor.Encode(&buf, openpgp.PrivateKeyType, nil)
+	assert.Nil(t, err)
+	assert.Nil(t, entity.SerializePrivateWithoutSigning(writer, nil))
+	assert.Nil(t, writer.Close())
+	return buf.Bytes()
+}
+
+func newSSHKey(t *testing.T, rsaKey bool, passphrase string) []byte {
+	var key interface{}
+	if rsaKey {
+		rsaPrivateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+		assert.Nil(t, err)
+		key = rsaPrivateKey
+	} else {
+		_, ed25519PrivateKey, err := ed25519.GenerateKey(rand.Reader)
+		assert.Nil(t, err)
+		key = ed25519PrivateKey
+	}
+
+	var block *pem.Block
+	var err error
+	if passphrase != "" {
+		block, err = ssh.MarshalPrivateKeyWithPassphrase(key, "", []byte(passphrase))
+	} else {
+		block, err = ssh.MarshalPrivateKey(key, "")
+	}
+	assert.Nil(t, err)
+	return pem.EncodeToMemory(block)
+}
+
+func TestNewCommitSigner(t *testing.T) {
+	tests := []struct {
+		name    string
+		secret  *v1.Secret
+		wantErr bool
+	}{{
+		name: "gpg key",
+		secret: &v1.Secret{Type: v1alpha3.SecretTypeGPGKey, Data: map[string][]byte{
+			v1alpha3.GPGKeyPrivateKey: newGPGKey(t, ""),
+		}},
+	}, {
+		name: "encrypted gpg key",
+		secret: &v1.Secret{Type: v1alpha3.SecretTypeGPGKey, Data: map[string][]byte{
+			v1alpha3.GPGKeyPrivateKey:    newGPGKey(t, "pass"),
+			v1alpha3.GPGKeyPassphraseKey: []byte("pass"),
+		}},
+	}, {
+		name: "encrypted gpg key with a wrong passphrase",
+		secret: &v1.Secret{Type: v1alpha3.SecretTypeGPGKey, Data: map[string][]byte{
+			v1alpha3.GPGKeyPrivateKey:    newGPGKey(t, "pass"),
+			v1alpha3.GPGKeyPassphraseKey: []byte("wrong"),
+		}},
+		wantErr: true,
+	}, {
+		name:    "invalid gpg key",
+		secret:  &v1.Secret{Type: v1alpha3.SecretTypeGPGKey, Data: map[string][]byte{v1alpha3.GPGKeyPrivateKey: []byte("invalid")}},
+		wantErr: true,
+	}, {
+		name: "encrypted ssh key",
+		secret: &v1.Secret{Type: v1alpha3.SecretTypeSSHAuth, Data: map[string][]byte{
+			v1alpha3.SSHAuthPrivateKey:    newSSHKey(t, false, "pass"),
+			v1alpha3.SSHAuthPassphraseKey: []byte("pass"),
+		}},
+	}, {
+		name:   "kubernetes ssh key",
+		secret: &v1.Secret{Type: v1.SecretTypeSSHAuth, Data: map[string][]byte{v1.SSHAuthPrivateKey: newSSHKey(t, false, "")}},
+	}, {
+		name:    "invalid ssh key",
+		secret:  &v1.Secret{Type: v1alpha3.SecretTypeSSHAuth, Data: map[string][]byte{v1alpha3.SSHAuthPrivateKey: []byte("invalid")}},
+		wantErr: true,
+	}, {
+		name:    "not supported",
+		secret:  &v1.Secret{Type: v1alpha3.SecretTypeBasicAuth},
+		wantErr: true,
+	}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			signer, err := NewCommitSigner(tt.secret)
+			if tt.wantErr {
+				assert.NotNil(t, err)
+				return
+			}
+			assert.Nil(t, err)
+			assert.NotNil(t, signer)
+		})
+	}
+}
+
+func TestSignedCommits(t *testing.T) {
+	gpgKeySigner, err := newGPGSigner(newGPGKey(t, ""), nil)
+	assert.Nil(t, err)
+	anotherGPGSigner, err := newGPGSigner(newGPGKey(t, ""), nil)
+	assert.Nil(t, err)
+	ed25519Signer, err := newSSHSigner(newSSHKey(t, false, ""), nil)
+	assert.Nil(t, err)
+	rsaSigner, err := newSSHSigner(newSSHKey(t, true, ""), nil)
+	assert.Nil(t, err)
+	anotherSSHSigner, err := newSSHSigner(newSSHKey(t, false, ""), nil)
+	assert.Nil(t, err)
+
+	tests := []struct {
+		name   string
+		signer CommitSigner
+		format string
+		keyID  string
+		others []CommitSigner
+	}{{
+		name:   "gpg",
+		signer: gpgKeySigner,
+		format: SignatureFormatGPG,
+		keyID:  strings.ToUpper(gpgKeySigner.(*gpgSigner).entity.PrimaryKey.KeyIdString()),
+		others: []CommitSigner{anotherGPGSigner, ed25519Signer},
+	}, {
+		name:   "ssh ed25519",
+		signer: ed25519Signer,
+		format: SignatureFormatSSH,
+		keyID:  ssh.FingerprintSHA256(ed25519Signer.(*sshSigner).signer.PublicKey()),
+		others: []CommitSigner{anotherSSHSigner, rsaSigner, gpgKeySigner},
+	}, {
+		name:   "ssh rsa",
+		signer: rsaSigner,
+		format: SignatureFormatSSH,
+		keyID:  ssh.FingerprintSHA256(rsaSigner.(*sshSigner).signer.PublicKey()),
+		others: []CommitSigner{ed25519Signer},
+	}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			remoteDir := newTestRemote(t, map[string]string{"a.txt": "a"})
+			s := newTestService(t, remoteDir, "alice")
+			s.signer = tt.signer
+
+			out, err := commitFiles(t, s, map[string]string{"a.txt": "signed"})
+			assert.Nil(t, err)
+			assert.Equal(t, &CommitSignature{Format: tt.format, Verified: true, KeyID: tt.keyID}, out.Commit.Signature)
+
+			commit, err := s.repo.CommitObject(plumbing.NewHash(out.Commit.Hash))
+			assert.Nil(t, err)
+			for _, other := range tt.others {
+				result := other.Verify(commit)
+				assert.Equal(t, tt.format, result.Format)
+				assert.False(t, result.Verified)
+				assert.NotEmpty(t, result.Reason)
+			}
+
+			// the signature is broken if the commit was changed
+			commit.Message = "changed"
+			assert.False(t, tt.signer.Verify(commit).Verified)
+
+			// no signing key
+			s.signer = nil
+			getOut, err := s.GetCommit(context.Background(), &GetCommitInput{Commit: out.Commit.Hash})
+			assert.Nil(t, err)
+			assert.Equal(t, tt.format, getOut.Commit.Signature.Format)
+			assert.False(t, getOut.Commit.Signature.Verified)
+
+			// the rebased commit is signed as well
+			s.signer = tt.signer
+			bob := newTestService(t, remoteDir, "bob")
+			bob.signer = tt.signer
+			_, err = commitFiles(t, s, map[string]string{"b.txt": "alice"})
+			assert.Nil(t, err)
+			_, err = commitFiles(t, bob, map[string]string{"c.txt": "bob"})
+			assert.Nil(t, err)
+			commit, err = bob.repo.CommitObject(getRemoteHead(t, remoteDir))
+			assert.Nil(t, err)
+			assert.Equal(t, "bob", commit.Author.Name)
+			assert.True(t, tt.signer.Verify(commit).Verified)
+		})
+	}
+
+	t.Run("not signed", func(t *testing.T) {
+		remoteDir := newTestRemote(t, map[string]string{"a.txt": "a"})
+		s := newTestService(t, remoteDir, "alice")
+		out, err := commitFiles(t, s, map[string]string{"a.txt": "not signed"})
+		assert.Nil(t, err)
+		assert.Nil(t, out.Commit.Signature)
+	})
+}
@@ -3,8 +3,12 @@ package gitops
 import (
 	"context"
 	"errors"
+	"fmt"
 	"io"
+	"net/http"
 	"os"
+	"strings"
+	"time"
 
 	"github.com/emicklei/go-restful/v3"
 	"github.com/go-git/go-git/v5"
@@ -12,6 +16,7 @@ import (
 	"github.com/go-git/go-git/v5/plumbing/object"
 	"github.com/go-git/go-git/v5/plumbing/transport"
 	"github.com/kubesphere/ks-devops/pkg/kapis/common"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apiserver/pkg/authentication/user"
 )
@@ -21,6 +26,36 @@ var (
 	ErrBranchNotFound = errors.New("branch not found")
 )
 
+// ConflictError indicates the changes conflict with the ones which were pushed to the remote branch by others
+type ConflictError struct {
+	Branch string   `json:"branch"`
+	Paths  []string `json:"paths"`
+}
+
+func (e *ConflictError) Error() string {
+	return fmt.Sprintf("the following files of branch %s were changed by others, please reload them and retry: %s",
+		e.Branch, strings.Join(e.Paths, ", "))
+}
+
+// Status makes the error be handled as a 409 Conflict, it implements errors.APIStatus
+func (e *ConflictError) Status() metav1.Status {
+	causes := make([]metav1.StatusCause, 0, len(e.Paths))
+	for _, path := range e.Paths {
+		causes = append(causes, metav1.StatusCause{
+			Type:    metav1.CauseTypeFieldValueInvalid,
+			Message: "changed by others",
+			Field:   path,
+		})
+	}
+	return metav1.Status{
+		Status:  metav1.StatusFailure,
+		Code:    http.StatusConflict,
+		Reason:  metav1.StatusReasonConflict,
+		Message: e.Error(),
+		Details: &metav1.StatusDetails{Causes: causes},
+	}
+}
+
 const (
 	UploadDownloadFileSizeLimit = 1024 * 1024 * 10 // 10 MB
 )
@@ -43,6 +78,8 @@ type Commit struct {
 	TreeHash string
 	// ParentHashes are the hashes of the parent commits of the commit.
 	ParentHashes []string
+	// Signature is the verification result of the signature, it's nil if the commit is not signed.
+	Signature *CommitSignature
 }
 
 func convertCommit(c *object.Commit) *Commit {
@@ -257,6 +294,89 @@ type UploadFilesInput struct {
 type UploadFilesOutput struct {
 }
 
+// CreatePullRequestInput commits the files to the head branch, then opens a pull request against the base branch
+type CreatePullRequestInput struct {
+	// Base is the branch which the changes are merged into
+	Base string `json:"base"`
+	// Head is the feature branch, it's generated if it's empty. It's created from the base branch if it does not exist
+	Head  string          `json:"head"`
+	Title string          `json:"title"`
+	Body  string          `json:"body"`
+	Files []*FileNameData `json:"files"`
+	// Message is the commit message, the title is used if it's empty
+	Message string `json:"message"`
+}
+
+type CreatePullRequestOutput struct {
+	Commit      *Commit      `json:"commit"`
+	PullRequest *PullRequest `json:"pullRequest"`
+}
+
+// PullRequest is a pull request of GitHub, or a merge request of GitLab
+type PullRequest struct {
+	Number int    `json:"number"`
+	Title  string `json:"title"`
+	Body   string `json:"body"`
+	Link   string `json:"link"`
+	Author string `json:"author"`
+	Head   string `json:"head"`
+	Base   string `json:"base"`
+	Sha    string `json:"sha"`
+	State  string `json:"state"`
+	Draft  bool   `json:"draft"`
+	Merged bool   `json:"merged"`
+	// Mergeable and MergeableState might be unknown until the git provider computes them
+	Mergeable      bool   `json:"mergeable"`
+	MergeableState string `json:"mergeableState"`
+	// CheckState is the combined state of the checks, like success, pending or failure
+	CheckState string              `json:"checkState"`
+	Checks     []*PullRequestCheck `json:"checks"`
+	Created    time.Time           `json:"created"`
+	Updated    time.Time           `json:"updated"`
+}
+
+// PullRequestCheck is a commit status of the head of a pull request
+type PullRequestCheck struct {
+	Name        string `json:"name"`
+	State       string `json:"state"`
+	Description string `json:"description"`
+	Link        string `json:"link"`
+}
+
+type OpenPullRequestInput struct {
+	Base  string `json:"base"`
+	Head  string `json:"head"`
+	Title string `json:"title"`
+	Body  string `json:"body"`
+}
+
+type OpenPullRequestOutput struct {
+	PullRequest *PullRequest `json:"pullRequest"`
+}
+
+type ListPullRequestsInput struct {
+	Options *ListOptions `json:"options"`
+}
+
+type ListPullRequestsOutput ListResult[*PullRequest]
+
+type GetPullRequestInput struct {
+	Number int `json:"number"`
+}
+
+type GetPullRequestOutput struct {
+	PullRequest *PullRequest `json:"pullRequest"`
+}
+
+// PullRequestService manages the pull requests through the API of the git provider
+type PullRequestService interface {
+	// OpenPullRequest opens a pull request, the existing open one of the same branches is returned if there is
+	OpenPullRequest(ctx context.Context, input *OpenPullRequestInput) (*OpenPullRequestOutput, error)
+	// ListPullRequests lists the open pull requests with their merge and check status
+	ListPullRequests(ctx context.Context, input *ListPullRequestsInput) (*ListPullRequestsOutput, error)
+	GetPullRequest(ctx context.Context, input *GetPullRequestInput) (*GetPullRequestOutput, error)
+}
+
 type GitRepoService interface {
 	ListBranches(ctx context.Context, input *ListBranchesInput) (*ListBranchesOutput, error)
 	GetBranch(ctx context.Context, input *GetBranchInput) (*GetBranchOutput, error)
@@ -273,6 +393,9 @@ type GitRepoService interface {
 	DeleteClone(ctx context.Context, input *DeleteCloneInput) (*DeleteCloneOutput, error)
 	GetConfig(ctx context.Context, input *GetConfigInput) (*GetConfigOutput, error)
 	UpdateConfig(ctx context.Context, input *UpdateConfigInput) (*UpdateConfigOutput, error)
+	CreatePullRequest(ctx context.Context, input *CreatePullRequestInput) (*CreatePullRequestOutput, error)
+	// Close releases the lock of the repository, the service cannot be used after closed
+	Close() error
 }
 
 type Handler interface {
@@ -290,11 +413,15 @@ type Handler interface {
 	DownloadFile(req *restful.Request, res *restful.Response)
 	GetConfig(req *restful.Request, res *restful.Response)
 	UpdateConfig(req *restful.Request, res *restful.Response)
+	CreatePullRequest(req *restful.Request, res *restful.Response)
+	ListPullRequests(req *restful.Request, res *restful.Response)
+	GetPullRequest(req *restful.Request, res *restful.Response)
 }
 
 type GitRepoFactory interface {
 	NewRepoService(ctx context.Context, user user.Info, repo types.NamespacedName) (GitRepoService, error)
 	DeleteRepoClone(ctx context.Context, repo types.NamespacedName) error
+	NewPullRequestService(ctx context.Context, repo types.NamespacedName) (PullRequestService, error)
 }
 
 func ParseListOptionsFromRequest(req *restful.Request) *ListOptions {
@@ -318,4 +445,10 @@ type GitRepoOptions struct {
 	// ProxyOptions provides info required for connecting to a proxy.
 	proxyOptions transport.ProxyOptions
 	newFilePerm  os.FileMode
+	// unlock releases the lock of the repository
+	unlock func()
+	// pullRequests creates the pull request service when it's needed
+	pullRequests func() (PullRequestService, error)
+	// signer signs the commits, they are not signed if it's nil
+	signer CommitSigner
 }
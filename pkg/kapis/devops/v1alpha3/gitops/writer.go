@@ -0,0 +1,87 @@
+/*
+Copyright 2022 The KubeSphere Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gitops
+
+import (
+	"context"
+	"errors"
+
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apiserver/pkg/authentication/user"
+)
+
+// RepoWriter updates the files of a GitRepository through the GitRepoService, then commits and pushes the changes
+type RepoWriter struct {
+	factory GitRepoFactory
+	user    user.Info
+}
+
+// NewRepoWriter creates a RepoWriter, the username is the fallback commit author
+func NewRepoWriter(factory GitRepoFactory, username string) *RepoWriter {
+	return &RepoWriter{
+		factory: factory,
+		user:    &user.DefaultInfo{Name: username},
+	}
+}
+
+// Write checks out the branch, calls the update function with the root directory of the work tree,
+// then commits and pushes the changes. The commit hash is empty if there is nothing changed.
+func (w *RepoWriter) Write(ctx context.Context, repo types.NamespacedName, branch, message string,
+	update func(root string) (bool, error)) (string, error) {
+	repoService, err := w.factory.NewRepoService(ctx, w.user, repo)
+	if err != nil {
+		return "", err
+	}
+	defer func() {
+		_ = repoService.Close()
+	}()
+
+	coOut, err := repoService.CheckOutBranch(ctx, &CheckOutBranchInput{
+		Branch: branch,
+		Force:  true,
+	})
+	if err != nil {
+		return "", err
+	}
+	wt := coOut.WorkTree
+
+	if _, err = repoService.CleanAndPull(ctx, &CleanAndPullInput{
+		WorkTree: wt,
+		Branch:   branch,
+	}); err != nil {
+		return "", err
+	}
+
+	changed, err := update(wt.Filesystem.Root())
+	if err != nil || !changed {
+		return "", err
+	}
+
+	cpOut, err := repoService.CommitAndPush(ctx, &CommitAndPushInput{
+		Branch:   branch,
+		WorkTree: wt,
+		Message:  message,
+		SignOff:  true,
+	})
+	if err != nil {
+		if errors.Is(err, ErrWorkTreeClean) {
+			return "", nil
+		}
+		return "", err
+	}
+	return cpOut.Commit.Hash, nil
+}
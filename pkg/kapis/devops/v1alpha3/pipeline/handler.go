@@ -113,3 +113,20 @@ func (h *apiHandler) getBranch(request *restful.Request, response *restful.Respo
 	}
 	_ = response.WriteEntity(searchedBranch)
 }
+
+func (h *apiHandler) getGraph(request *restful.Request, response *restful.Response) {
+	namespaceName := request.PathParameter("namespace")
+	pipelineName := request.QueryParameter("pipeline")
+
+	pipelineList := &v1alpha3.PipelineList{}
+	if err := h.client.List(context.Background(), pipelineList, client.InNamespace(namespaceName)); err != nil {
+		kapis.HandleError(request, response, err)
+		return
+	}
+
+	graph := models.BuildGraph(pipelineList.Items)
+	if pipelineName != "" {
+		graph = graph.Subgraph(pipelineName)
+	}
+	_ = response.WriteEntity(graph)
+}
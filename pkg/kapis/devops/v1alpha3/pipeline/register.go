@@ -56,4 +56,12 @@ func RegisterRoutes(ws *restful.WebService, c client.Client) {
 		Param(ws.PathParameter("pipeline", "Name of the Pipeline")).
 		Param(ws.PathParameter("branch", "Name of branch, tag or pull request")).
 		Returns(http.StatusOK, api.StatusOK, pipeline.Branch{}))
+
+	ws.Route(ws.GET("/namespaces/{namespace}/pipelinegraph").
+		To(handler.getGraph).
+		Metadata(restfulspec.KeyOpenAPITags, constants.DevOpsPipelineTags).
+		Doc("Get the dependency graph of the Pipelines which are connected by the upstream triggers").
+		Param(ws.PathParameter("namespace", "Namespace of the Pipelines")).
+		Param(ws.QueryParameter("pipeline", "Only return the Pipelines which are connected with this one").Required(false)).
+		Returns(http.StatusOK, api.StatusOK, pipeline.Graph{}))
 }
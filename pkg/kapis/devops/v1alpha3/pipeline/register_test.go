@@ -57,6 +57,12 @@ func TestAPIsExist(t *testing.T) {
 			method: http.MethodGet,
 			uri:    "/namespaces/fake/pipelines/fake/branches/fake",
 		},
+	}, {
+		name: "get the dependency graph of the pipelines",
+		args: args{
+			method: http.MethodGet,
+			uri:    "/namespaces/fake/pipelinegraph",
+		},
 	}}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -149,7 +149,11 @@ func (h *apiHandler) createPipelineRun(request *restful.Request, response *restf
 		return
 	}
 	// create PipelineRun
-	pr := CreatePipelineRun(&pipeline, &payload, scm)
+	pr, err := CreatePipelineRun(&pipeline, &payload, scm)
+	if err != nil {
+		kapis.HandleBadRequest(response, request, err)
+		return
+	}
 	if user.GetName() != "" {
 		pr.GetAnnotations()[v1alpha3.PipelineRunCreatorAnnoKey] = user.GetName()
 	}
@@ -34,6 +34,7 @@ import (
 	"github.com/emicklei/go-restful/v3"
 	"github.com/kubesphere/ks-devops/pkg/apiserver/runtime"
 	fakedevops "github.com/kubesphere/ks-devops/pkg/client/devops/fake"
+	"github.com/kubesphere/ks-devops/pkg/kapis"
 	"github.com/stretchr/testify/assert"
 	"sigs.k8s.io/controller-runtime/pkg/client/fake"
 )
@@ -51,7 +52,7 @@ func TestApis(t *testing.T) {
 		Spec: v1alpha3.PipelineSpec{
 			Type: v1alpha3.NoScmPipelineType,
 		},
-	}).Build())
+	}).Build(), kapis.JenkinsHealthFilter(nil, nil, ""))
 	restful.DefaultContainer.Add(wsWithGroup)
 
 	type args struct {
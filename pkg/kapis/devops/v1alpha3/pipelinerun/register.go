@@ -33,7 +33,8 @@ import (
 )
 
 // RegisterRoutes register routes into web service.
-func RegisterRoutes(ws *restful.WebService, devopsClient dclient.Interface, c client.Client) {
+// The jenkinsHealth filter rejects the creations of PipelineRun while Jenkins is unavailable.
+func RegisterRoutes(ws *restful.WebService, devopsClient dclient.Interface, c client.Client, jenkinsHealth restful.FilterFunction) {
 	handler := newAPIHandler(apiHandlerOption{
 		devopsClient: devopsClient,
 		client:       c,
@@ -57,6 +58,7 @@ func RegisterRoutes(ws *restful.WebService, devopsClient dclient.Interface, c cl
 
 	ws.Route(ws.POST("/namespaces/{namespace}/pipelines/{pipeline}/pipelineruns").
 		To(handler.createPipelineRun).
+		Filter(jenkinsHealth).
 		Doc("Create a PipelineRun for the specified pipeline").
 		Metadata(restfulspec.KeyOpenAPITags, constants.DevOpsPipelineTags).
 		Param(ws.PathParameter("namespace", "Namespace of the pipeline")).
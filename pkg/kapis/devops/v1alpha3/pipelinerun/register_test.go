@@ -25,6 +25,7 @@ import (
 	"github.com/kubesphere/ks-devops/pkg/api/devops/v1alpha1"
 	"github.com/kubesphere/ks-devops/pkg/apiserver/runtime"
 	fakedevops "github.com/kubesphere/ks-devops/pkg/client/devops/fake"
+	"github.com/kubesphere/ks-devops/pkg/kapis"
 	"github.com/stretchr/testify/assert"
 	"sigs.k8s.io/controller-runtime/pkg/client/fake"
 )
@@ -36,7 +37,8 @@ func TestAPIsExist(t *testing.T) {
 	schema, err := v1alpha1.SchemeBuilder.Register().Build()
 	assert.Nil(t, err)
 
-	RegisterRoutes(wsWithGroup, fakedevops.NewFakeDevops(nil), fake.NewClientBuilder().WithScheme(schema).Build())
+	RegisterRoutes(wsWithGroup, fakedevops.NewFakeDevops(nil), fake.NewClientBuilder().WithScheme(schema).Build(),
+		kapis.JenkinsHealthFilter(nil, nil, ""))
 	restful.DefaultContainer.Add(wsWithGroup)
 
 	type args struct {
@@ -59,9 +59,14 @@ func convertParameters(payload *devops.RunPayload) []v1alpha3.Parameter {
 		if parameter.Name == "" {
 			continue
 		}
+		var value string
+		if parameter.Value != nil {
+			value = fmt.Sprint(parameter.Value)
+		}
 		parameters = append(parameters, v1alpha3.Parameter{
-			Name:  parameter.Name,
-			Value: fmt.Sprint(parameter.Value),
+			Name:      parameter.Name,
+			Value:     value,
+			ValueFrom: parameter.ValueFrom,
 		})
 	}
 	return parameters
@@ -94,9 +99,13 @@ func getPipelineRef(pipeline *v1alpha3.Pipeline) *corev1.ObjectReference {
 	}
 }
 
-// CreatePipelineRun creates a bare PipelineRun.
-func CreatePipelineRun(pipeline *v1alpha3.Pipeline, payload *devops.RunPayload, scm *v1alpha3.SCM) *v1alpha3.PipelineRun {
-	return CreateBarePipelineRun(pipeline, convertParameters(payload), scm)
+// CreatePipelineRun creates a bare PipelineRun, the parameters are validated against the Pipeline.
+func CreatePipelineRun(pipeline *v1alpha3.Pipeline, payload *devops.RunPayload, scm *v1alpha3.SCM) (*v1alpha3.PipelineRun, error) {
+	parameters, err := v1alpha3.ValidateParameters(pipeline.GetParameterDefinitions(), convertParameters(payload))
+	if err != nil {
+		return nil, err
+	}
+	return CreateBarePipelineRun(pipeline, parameters, scm), nil
 }
 
 // CreateBarePipelineRun creates a bare PipelineRun.
@@ -186,6 +186,24 @@ func Test_convertParameters(t *testing.T) {
 			Name:  "fakeName",
 			Value: "",
 		}},
+	}, {
+		name: "Nil value and credential reference",
+		args: args{
+			payload: &devops.RunPayload{
+				Parameters: []devops.Parameter{{
+					Name: "token",
+					ValueFrom: &v1alpha3.ParameterValueSource{
+						CredentialRef: &v1alpha3.CredentialKeySelector{Name: "github"},
+					},
+				}},
+			},
+		},
+		want: []v1alpha3.Parameter{{
+			Name: "token",
+			ValueFrom: &v1alpha3.ParameterValueSource{
+				CredentialRef: &v1alpha3.CredentialKeySelector{Name: "github"},
+			},
+		}},
 	}, {
 		name: "Two parameters",
 		args: args{
@@ -221,9 +239,28 @@ func TestCreatePipelineRun(t *testing.T) {
 	pipeline := &v1alpha3.Pipeline{}
 	pipeline.SetName("name")
 	pipeline.Namespace = "namespace"
-	pipelineRun := CreatePipelineRun(pipeline, nil, nil)
+	pipelineRun, err := CreatePipelineRun(pipeline, nil, nil)
+	assert.Nil(t, err)
 
 	assert.Equal(t, pipelineRun.GenerateName, pipeline.Name+"-")
 	assert.Equal(t, pipelineRun.Namespace, pipeline.Namespace)
 	assert.NotNil(t, pipelineRun.Annotations)
+
+	pipeline.Spec.Pipeline = &v1alpha3.NoScmPipeline{
+		Parameters: []v1alpha3.ParameterDefinition{{
+			Name: "debug",
+			Type: v1alpha3.BooleanParameterType,
+		}},
+	}
+	pipelineRun, err = CreatePipelineRun(pipeline, &devops.RunPayload{
+		Parameters: []devops.Parameter{{Name: "debug", Value: "True"}},
+	}, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, []v1alpha3.Parameter{{Name: "debug", Value: "true"}}, pipelineRun.Spec.Parameters)
+
+	pipelineRun, err = CreatePipelineRun(pipeline, &devops.RunPayload{
+		Parameters: []devops.Parameter{{Name: "debug", Value: "yes"}},
+	}, nil)
+	assert.NotNil(t, err)
+	assert.Nil(t, pipelineRun)
 }
@@ -35,9 +35,12 @@ import (
 	"github.com/kubesphere/ks-devops/pkg/api/devops/v1alpha3"
 	"github.com/kubesphere/ks-devops/pkg/apiserver/runtime"
 	dclient "github.com/kubesphere/ks-devops/pkg/client/devops"
+	"github.com/kubesphere/ks-devops/pkg/client/devops/sharding"
 	"github.com/kubesphere/ks-devops/pkg/client/k8s"
 	"github.com/kubesphere/ks-devops/pkg/constants"
+	"github.com/kubesphere/ks-devops/pkg/kapis"
 	"github.com/kubesphere/ks-devops/pkg/kapis/devops/v1alpha3/common"
+	"github.com/kubesphere/ks-devops/pkg/kapis/devops/v1alpha3/deploytarget"
 	"github.com/kubesphere/ks-devops/pkg/kapis/devops/v1alpha3/pipeline"
 	"github.com/kubesphere/ks-devops/pkg/kapis/devops/v1alpha3/pipelinerun"
 	"github.com/kubesphere/ks-devops/pkg/kapis/devops/v1alpha3/scm"
@@ -64,9 +67,17 @@ func AddToContainer(container *restful.Container, devopsClient dclient.Interface
 		runtime.NewWebService(v1alpha3.GroupVersion),
 	}
 
+	var jenkinsNamespace string
+	if cfg.JenkinsOptions != nil {
+		jenkinsNamespace = cfg.JenkinsOptions.Namespace
+	}
+	// the creations which need Jenkins are rejected while the Jenkins instance of the DevOps project is unavailable
+	locator, _ := devopsClient.(sharding.Locator)
+	jenkinsHealth := kapis.JenkinsHealthFilter(client, locator, jenkinsNamespace)
+
 	for _, service := range services {
-		registerRoutes(cfg, devopsClient, k8sClient, client, runtimeCache, service)
-		pipelinerun.RegisterRoutes(service, devopsClient, client)
+		registerRoutes(cfg, devopsClient, k8sClient, client, runtimeCache, service, jenkinsHealth)
+		pipelinerun.RegisterRoutes(service, devopsClient, client, jenkinsHealth)
 		pipeline.RegisterRoutes(service, client)
 		template.RegisterRoutes(service, &common.Options{
 			GenericClient: client,
@@ -74,16 +85,18 @@ func AddToContainer(container *restful.Container, devopsClient dclient.Interface
 		steptemplate.RegisterRoutes(service, &common.Options{
 			GenericClient: client,
 		})
-		webhook.RegisterWebhooks(client, service, jenkins)
+		deploytarget.RegisterRoutes(service, client)
+		webhook.RegisterWebhooks(client, service, jenkins, devopsClient, jenkinsNamespace)
 		container.Add(service)
 	}
 	return services
 }
 
-func registerRoutes(cfg *config.Config, devopsClient dclient.Interface, k8sClient k8s.Client, client client.Client, runtimeCache cache.Cache, ws *restful.WebService) {
+func registerRoutes(cfg *config.Config, devopsClient dclient.Interface, k8sClient k8s.Client, client client.Client, runtimeCache cache.Cache,
+	ws *restful.WebService, jenkinsHealth restful.FilterFunction) {
 	handler := newDevOpsHandler(client, devopsClient, k8sClient, runtimeCache)
-	registerRoutersForCredentials(handler, ws)
-	registerRoutersForPipelines(handler, ws)
+	registerRoutersForCredentials(handler, ws, jenkinsHealth)
+	registerRoutersForPipelines(handler, ws, jenkinsHealth)
 	registerRoutersForWorkspace(handler, ws)
 	scm.RegisterRoutersForSCM(client, ws)
 	registerRoutersForCI(handler, ws)
@@ -92,7 +105,7 @@ func registerRoutes(cfg *config.Config, devopsClient dclient.Interface, k8sClien
 	gitops.RegisterRouters(ws, gitopsHandler)
 }
 
-func registerRoutersForCredentials(handler *devopsHandler, ws *restful.WebService) {
+func registerRoutersForCredentials(handler *devopsHandler, ws *restful.WebService, jenkinsHealth restful.FilterFunction) {
 	ws.Route(ws.GET("/namespaces/{devops}/credentials").
 		To(handler.ListCredential).
 		Param(ws.PathParameter("devops", "devops name")).
@@ -107,6 +120,7 @@ func registerRoutersForCredentials(handler *devopsHandler, ws *restful.WebServic
 
 	ws.Route(ws.POST("/namespaces/{devops}/credentials").
 		To(handler.CreateCredential).
+		Filter(jenkinsHealth).
 		Param(ws.PathParameter("devops", "devops name")).
 		Reads(corev1.Secret{}).
 		Doc("create the credential of the specified devops for the current user").
@@ -139,7 +153,7 @@ func registerRoutersForCredentials(handler *devopsHandler, ws *restful.WebServic
 		Metadata(restfulspec.KeyOpenAPITags, constants.DevOpsCredentialTags))
 }
 
-func registerRoutersForPipelines(handler *devopsHandler, ws *restful.WebService) {
+func registerRoutersForPipelines(handler *devopsHandler, ws *restful.WebService, jenkinsHealth restful.FilterFunction) {
 	ws.Route(ws.GET("/namespaces/{devops}/pipelines").
 		To(handler.ListPipeline).
 		Param(ws.PathParameter("devops", "devops name")).
@@ -153,6 +167,7 @@ func registerRoutersForPipelines(handler *devopsHandler, ws *restful.WebService)
 
 	ws.Route(ws.POST("/namespaces/{devops}/pipelines").
 		To(handler.CreatePipeline).
+		Filter(jenkinsHealth).
 		Param(ws.PathParameter("devops", "devops name")).
 		Reads(v1alpha3.Pipeline{}).
 		Doc("create the pipeline of the specified devops for the current user").
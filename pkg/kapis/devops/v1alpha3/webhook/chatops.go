@@ -0,0 +1,354 @@
+/*
+Copyright 2022 The KubeSphere Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/jenkins-x/go-scm/scm"
+	v1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/kubesphere/ks-devops/pkg/api/devops/v1alpha3"
+	"github.com/kubesphere/ks-devops/pkg/client/devops"
+	"github.com/kubesphere/ks-devops/pkg/client/git"
+	"github.com/kubesphere/ks-devops/pkg/kapis/devops/v1alpha3/pipelinerun"
+	modelpipelinerun "github.com/kubesphere/ks-devops/pkg/models/pipelinerun"
+)
+
+// the slash commands which are supported in the comments of pull requests
+const (
+	commandRetest  = "/retest"
+	commandAbort   = "/abort"
+	commandApprove = "/approve"
+)
+
+// chatOpsCommand is a slash command from a comment, e.g. "/approve deploy"
+type chatOpsCommand struct {
+	name string
+	args []string
+}
+
+func (c chatOpsCommand) String() string {
+	return strings.TrimSpace(c.name + " " + strings.Join(c.args, " "))
+}
+
+// pullRequestComment is a new comment of a pull request, or a merge request
+type pullRequestComment struct {
+	repo   scm.Repository
+	number int
+	author string
+	body   string
+}
+
+// parseChatOpsCommands returns the supported commands, each command must be placed at the beginning of a line
+func parseChatOpsCommands(body string) (commands []chatOpsCommand) {
+	for _, line := range strings.Split(body, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+
+		switch name := strings.ToLower(fields[0]); name {
+		case commandRetest, commandAbort, commandApprove:
+			commands = append(commands, chatOpsCommand{name: name, args: fields[1:]})
+		}
+	}
+	return
+}
+
+// getPullRequestComment returns the new comment of a pull request, it returns nil if the webhook is not the case
+func getPullRequestComment(webhook scm.Webhook) (comment *pullRequestComment) {
+	switch hook := webhook.(type) {
+	case *scm.IssueCommentHook:
+		// the comments of issues and pull requests are the same event on GitHub
+		if hook.Action == scm.ActionCreate && hook.Issue.PullRequest != nil {
+			comment = &pullRequestComment{
+				repo:   hook.Repo,
+				number: hook.Issue.Number,
+				author: getCommentAuthor(hook.Comment, hook.Sender),
+				body:   hook.Comment.Body,
+			}
+		}
+	case *scm.PullRequestCommentHook:
+		if hook.Action == scm.ActionCreate {
+			comment = &pullRequestComment{
+				repo:   hook.Repo,
+				number: hook.PullRequest.Number,
+				author: getCommentAuthor(hook.Comment, hook.Sender),
+				body:   hook.Comment.Body,
+			}
+		}
+	}
+	return
+}
+
+func getCommentAuthor(comment scm.Comment, sender scm.User) string {
+	if comment.Author.Login != "" {
+		return comment.Author.Login
+	}
+	return sender.Login
+}
+
+// handlePullRequestComment runs the slash commands against the multi-branch Pipelines of the repository,
+// then replies the results as a comment. The payload must be verified by the webhook secret of the Pipeline.
+func (h *SCMHandler) handlePullRequestComment(ctx context.Context, comment *pullRequestComment, driver scm.Driver,
+	parse func(secret string) (scm.Webhook, error)) (found bool, err error) {
+	commands := parseChatOpsCommands(comment.body)
+	if len(commands) == 0 {
+		return
+	}
+
+	gitLabServers := h.getGitLabServers(ctx)
+	pipelineList := &v1alpha3.PipelineList{}
+	if err = h.List(ctx, pipelineList); err != nil {
+		return
+	}
+
+	for i := range pipelineList.Items {
+		pipeline := pipelineList.Items[i]
+		if !pipeline.IsMultiBranch() ||
+			!gitRepoMatch(pipeline.Spec.MultiBranchPipeline.ResolveGitURL(gitLabServers), comment.repo) {
+			continue
+		}
+		found = true
+		if err = h.verifyWebhook(ctx, &pipeline, parse); err != nil {
+			return
+		}
+
+		// the server and the repository come from the Pipeline, so the credential is only sent to the configured server
+		scmClient, repo, clientErr := h.newSCMClient(pipeline, driver, gitLabServers)
+		if clientErr != nil {
+			err = fmt.Errorf("failed to create the git client of Pipeline %s/%s, error: %v", pipeline.Namespace, pipeline.Name, clientErr)
+			return
+		}
+
+		var results []string
+		var writable bool
+		if writable, err = canWrite(ctx, scmClient, repo, comment.author); err != nil {
+			err = fmt.Errorf("failed to check the permission of %s on %s, error: %v", comment.author, repo, err)
+			return
+		} else if !writable {
+			results = append(results, fmt.Sprintf("only the users who are able to write %s are allowed to run the commands", repo))
+		} else {
+			for _, command := range commands {
+				results = append(results, fmt.Sprintf("`%s`: %s", command, h.runChatOpsCommand(ctx, pipeline, comment.number, command)))
+			}
+		}
+
+		body := buildChatOpsReply(pipeline, comment.author, results)
+		if _, _, err = scmClient.PullRequests.CreateComment(ctx, repo, comment.number, &scm.CommentInput{Body: body}); err != nil {
+			err = fmt.Errorf("failed to reply the comment of %s#%d, error: %v", repo, comment.number, err)
+			return
+		}
+	}
+	return
+}
+
+// newSCMClient creates a git client with the credential of the multi-branch Pipeline,
+// it returns the full name of the repository as well
+func (h *SCMHandler) newSCMClient(pipeline v1alpha3.Pipeline, driver scm.Driver, servers v1alpha3.GitLabServers) (
+	scmClient *scm.Client, repo string, err error) {
+	repoURL := pipeline.Spec.MultiBranchPipeline.ResolveGitURL(servers)
+	var server string
+	if server, repo = v1alpha3.SplitGitURL(repoURL.Web); repoURL.Server != "" {
+		server = repoURL.Server
+	}
+	if server == "" || repo == "" {
+		err = fmt.Errorf("cannot find the repository of Pipeline %s/%s", pipeline.Namespace, pipeline.Name)
+		return
+	}
+
+	var secretRef *v1.SecretReference
+	if credential := pipeline.Spec.MultiBranchPipeline.GetCredentialID(); credential != "" {
+		secretRef = &v1.SecretReference{Namespace: pipeline.Namespace, Name: credential}
+	}
+
+	factory := git.NewClientFactory(driver.String(), secretRef, h.Client)
+	factory.Server = getSCMServer(driver, server)
+	scmClient, err = factory.GetClient()
+	return
+}
+
+// canWrite returns true if the user is able to write the repository, the read-only collaborators are not the case
+func canWrite(ctx context.Context, scmClient *scm.Client, repo, user string) (ok bool, err error) {
+	var permission string
+	if permission, _, err = scmClient.Repositories.FindUserPermission(ctx, repo, user); errors.Is(err, scm.ErrNotSupported) {
+		// Bitbucket Cloud only regards the users who are able to write as the collaborators
+		ok, _, err = scmClient.Repositories.IsCollaborator(ctx, repo, user)
+		return
+	}
+	ok = permission == scm.WritePermission || permission == scm.AdminPermission || permission == "maintain"
+	return
+}
+
+func buildChatOpsReply(pipeline v1alpha3.Pipeline, author string, results []string) string {
+	builder := &strings.Builder{}
+	_, _ = fmt.Fprintf(builder, "@%s Pipeline %s/%s:\n", author, pipeline.Namespace, pipeline.Name)
+	for _, result := range results {
+		_, _ = fmt.Fprintf(builder, "\n- %s", result)
+	}
+	return builder.String()
+}
+
+// runChatOpsCommand runs a command, and returns the result which is readable for humans
+func (h *SCMHandler) runChatOpsCommand(ctx context.Context, pipeline v1alpha3.Pipeline, number int, command chatOpsCommand) (result string) {
+	var err error
+	branch := fmt.Sprintf("PR-%d", number)
+	switch command.name {
+	case commandRetest:
+		result, err = h.retest(ctx, pipeline, branch)
+	case commandAbort:
+		result, err = h.abort(ctx, pipeline, branch)
+	case commandApprove:
+		result, err = h.approve(ctx, pipeline, branch, strings.Join(command.args, " "))
+	}
+
+	if err != nil {
+		result = fmt.Sprintf("failed, %v", err)
+	}
+	return
+}
+
+func (h *SCMHandler) retest(ctx context.Context, pipeline v1alpha3.Pipeline, branch string) (result string, err error) {
+	var scmObj *v1alpha3.SCM
+	if scmObj, err = pipelinerun.CreateScm(&pipeline.Spec, branch); err != nil {
+		return
+	}
+
+	var run *v1alpha3.PipelineRun
+	if run, err = pipelinerun.CreatePipelineRun(&pipeline, &devops.RunPayload{}, scmObj); err != nil {
+		return
+	}
+	run.Annotations[triggerAnnotationKey] = "chatops"
+	if err = h.Create(ctx, run); err == nil {
+		result = fmt.Sprintf("PipelineRun %s was created", run.Name)
+	}
+	return
+}
+
+func (h *SCMHandler) abort(ctx context.Context, pipeline v1alpha3.Pipeline, branch string) (result string, err error) {
+	var runs []v1alpha3.PipelineRun
+	if runs, err = h.getRunningPipelineRuns(ctx, pipeline, branch); err != nil {
+		return
+	} else if len(runs) == 0 {
+		result = "no running PipelineRun found"
+		return
+	}
+
+	var aborted []string
+	for _, run := range runs {
+		runID, _ := run.GetPipelineRunID()
+		if _, err = h.devopsClient.StopBranchPipeline(pipeline.Namespace, pipeline.Name, branch, runID, &devops.HttpParameters{
+			Method: http.MethodPut,
+			Url:    &url.URL{RawQuery: "blocking=true&timeOutInSecs=10"},
+		}); err != nil {
+			err = fmt.Errorf("failed to abort PipelineRun %s, error: %v", run.Name, err)
+			return
+		}
+		aborted = append(aborted, run.Name)
+	}
+	result = fmt.Sprintf("PipelineRun %s was aborted", strings.Join(aborted, ", "))
+	return
+}
+
+// jenkinsInputSubmission is the payload of proceeding an input step of Jenkins
+type jenkinsInputSubmission struct {
+	ID         string                           `json:"id"`
+	Parameters []devops.CheckPlayloadParameters `json:"parameters"`
+}
+
+func (h *SCMHandler) approve(ctx context.Context, pipeline v1alpha3.Pipeline, branch, input string) (result string, err error) {
+	var runs []v1alpha3.PipelineRun
+	if runs, err = h.getRunningPipelineRuns(ctx, pipeline, branch); err != nil {
+		return
+	}
+
+	for _, run := range runs {
+		nodeID, step := findPendingInputStep(modelpipelinerun.GetStages(ctx, h.Client, &run), input)
+		if step == nil {
+			continue
+		}
+
+		var payload []byte
+		if payload, err = json.Marshal(jenkinsInputSubmission{ID: step.Input.ID, Parameters: []devops.CheckPlayloadParameters{}}); err != nil {
+			return
+		}
+		runID, _ := run.GetPipelineRunID()
+		if _, err = h.devopsClient.SubmitBranchInputStep(pipeline.Namespace, pipeline.Name, branch, runID, nodeID, step.ID, &devops.HttpParameters{
+			Method: http.MethodPost,
+			Header: http.Header{"Content-Type": []string{"application/json"}},
+			Body:   io.NopCloser(bytes.NewReader(payload)),
+			Url:    &url.URL{},
+		}); err == nil {
+			result = fmt.Sprintf("the input %q of PipelineRun %s was approved", step.Input.ID, run.Name)
+		}
+		return
+	}
+
+	if input == "" {
+		result = "no pending input found"
+	} else {
+		result = fmt.Sprintf("no pending input %q found", input)
+	}
+	return
+}
+
+// getRunningPipelineRuns returns the PipelineRuns of a branch which have started but not completed, the newest one comes first
+func (h *SCMHandler) getRunningPipelineRuns(ctx context.Context, pipeline v1alpha3.Pipeline, branch string) (runs []v1alpha3.PipelineRun, err error) {
+	runList := &v1alpha3.PipelineRunList{}
+	if err = h.List(ctx, runList, client.InNamespace(pipeline.Namespace),
+		client.MatchingLabels{v1alpha3.PipelineNameLabelKey: pipeline.Name}); err != nil {
+		return
+	}
+
+	for _, run := range runList.Items {
+		if run.Spec.SCM != nil && run.Spec.SCM.RefName == branch && run.HasStarted() && !run.HasCompleted() {
+			runs = append(runs, run)
+		}
+	}
+	sort.SliceStable(runs, func(i, j int) bool {
+		return runs[j].CreationTimestamp.Before(&runs[i].CreationTimestamp)
+	})
+	return
+}
+
+// findPendingInputStep returns the first paused input step, the input ID or message must be matched if it is not empty
+func findPendingInputStep(stages []modelpipelinerun.NodeDetail, input string) (nodeID string, step *modelpipelinerun.Step) {
+	for i := range stages {
+		for j := range stages[i].Steps {
+			candidate := &stages[i].Steps[j]
+			if candidate.Input == nil || candidate.State != "PAUSED" {
+				continue
+			}
+			if input == "" || strings.EqualFold(candidate.Input.ID, input) || strings.EqualFold(candidate.Input.Message, input) {
+				nodeID, step = stages[i].ID, candidate
+				return
+			}
+		}
+	}
+	return
+}
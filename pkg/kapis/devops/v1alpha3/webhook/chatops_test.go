@@ -0,0 +1,348 @@
+/*
+Copyright 2022 The KubeSphere Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/h2non/gock"
+	"github.com/jenkins-x/go-scm/scm"
+	"github.com/jenkins-zh/jenkins-client/pkg/core"
+	"github.com/jenkins-zh/jenkins-client/pkg/job"
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/kubesphere/ks-devops/pkg/api/devops/v1alpha3"
+	"github.com/kubesphere/ks-devops/pkg/client/devops"
+	fakedevops "github.com/kubesphere/ks-devops/pkg/client/devops/fake"
+	modelpipelinerun "github.com/kubesphere/ks-devops/pkg/models/pipelinerun"
+)
+
+func Test_parseChatOpsCommands(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want []chatOpsCommand
+	}{{
+		name: "no commands",
+		body: "LGTM",
+	}, {
+		name: "retest",
+		body: "/retest",
+		want: []chatOpsCommand{{name: commandRetest, args: []string{}}},
+	}, {
+		name: "multiple commands with arguments",
+		body: "please have a look\r\n/approve Deploy to production\n/ABORT\n  /unknown",
+		want: []chatOpsCommand{
+			{name: commandApprove, args: []string{"Deploy", "to", "production"}},
+			{name: commandAbort, args: []string{}},
+		},
+	}, {
+		name: "not at the beginning of a line",
+		body: "do not /retest",
+	}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, parseChatOpsCommands(tt.body))
+		})
+	}
+}
+
+func Test_getPullRequestComment(t *testing.T) {
+	repo := scm.Repository{FullName: "linuxsuren/tools"}
+	tests := []struct {
+		name    string
+		webhook scm.Webhook
+		want    *pullRequestComment
+	}{{
+		name: "a comment of a pull request from GitHub",
+		webhook: &scm.IssueCommentHook{
+			Action:  scm.ActionCreate,
+			Repo:    repo,
+			Issue:   scm.Issue{Number: 1, PullRequest: &scm.PullRequest{}},
+			Comment: scm.Comment{Body: "/retest", Author: scm.User{Login: "rick"}},
+		},
+		want: &pullRequestComment{repo: repo, number: 1, author: "rick", body: "/retest"},
+	}, {
+		name: "a comment of an issue",
+		webhook: &scm.IssueCommentHook{
+			Action:  scm.ActionCreate,
+			Repo:    repo,
+			Issue:   scm.Issue{Number: 1},
+			Comment: scm.Comment{Body: "/retest"},
+		},
+	}, {
+		name: "an edited comment",
+		webhook: &scm.IssueCommentHook{
+			Action:  scm.ActionEdited,
+			Repo:    repo,
+			Issue:   scm.Issue{Number: 1, PullRequest: &scm.PullRequest{}},
+			Comment: scm.Comment{Body: "/retest"},
+		},
+	}, {
+		name: "a comment of a merge request from GitLab",
+		webhook: &scm.PullRequestCommentHook{
+			Action:      scm.ActionCreate,
+			Repo:        repo,
+			PullRequest: scm.PullRequest{Number: 2},
+			Comment:     scm.Comment{Body: "/abort"},
+			Sender:      scm.User{Login: "rick"},
+		},
+		want: &pullRequestComment{repo: repo, number: 2, author: "rick", body: "/abort"},
+	}, {
+		name:    "push event",
+		webhook: &scm.PushHook{},
+	}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, getPullRequestComment(tt.webhook))
+		})
+	}
+}
+
+func Test_findPendingInputStep(t *testing.T) {
+	stages := []modelpipelinerun.NodeDetail{{
+		Node: job.Node{ID: "1"},
+		Steps: []modelpipelinerun.Step{{
+			Step: job.Step{ID: "2", State: "FINISHED", Input: &job.Input{ID: "Test"}},
+		}},
+	}, {
+		Node: job.Node{ID: "3"},
+		Steps: []modelpipelinerun.Step{{
+			Step: job.Step{ID: "4", State: "RUNNING"},
+		}, {
+			Step: job.Step{ID: "5", State: "PAUSED", Input: &job.Input{ID: "Deploy", Message: "Deploy to production"}},
+		}},
+	}}
+
+	tests := []struct {
+		name       string
+		input      string
+		wantNodeID string
+		wantStepID string
+	}{{
+		name:       "any pending input",
+		wantNodeID: "3",
+		wantStepID: "5",
+	}, {
+		name:       "match the input ID",
+		input:      "deploy",
+		wantNodeID: "3",
+		wantStepID: "5",
+	}, {
+		name:       "match the input message",
+		input:      "Deploy to production",
+		wantNodeID: "3",
+		wantStepID: "5",
+	}, {
+		name:  "the input was submitted",
+		input: "Test",
+	}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			nodeID, step := findPendingInputStep(stages, tt.input)
+			assert.Equal(t, tt.wantNodeID, nodeID)
+			if tt.wantStepID == "" {
+				assert.Nil(t, step)
+			} else if assert.NotNil(t, step) {
+				assert.Equal(t, tt.wantStepID, step.ID)
+			}
+		})
+	}
+}
+
+// recordDevops records the branch Pipeline operations
+type recordDevops struct {
+	*fakedevops.Devops
+	stopped   []string
+	submitted []string
+}
+
+func (d *recordDevops) StopBranchPipeline(projectName, pipelineName, branchName, runID string, httpParameters *devops.HttpParameters) (*devops.StopPipeline, error) {
+	d.stopped = append(d.stopped, branchName+"/"+runID)
+	return nil, nil
+}
+
+func (d *recordDevops) SubmitBranchInputStep(projectName, pipelineName, branchName, runID, nodeID, stepID string, httpParameters *devops.HttpParameters) ([]byte, error) {
+	d.submitted = append(d.submitted, branchName+"/"+runID+"/"+nodeID+"/"+stepID)
+	return nil, nil
+}
+
+func TestSCMHandler_handlePullRequestComment(t *testing.T) {
+	schema := runtime.NewScheme()
+	_ = v1alpha3.AddToScheme(schema)
+	_ = v1.AddToScheme(schema)
+
+	pipeline := &v1alpha3.Pipeline{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "tools", Annotations: map[string]string{
+			scmWebhookSecretAnnotationKey: "webhook-secret",
+		}},
+		Spec: v1alpha3.PipelineSpec{
+			Type: v1alpha3.MultiBranchPipelineType,
+			MultiBranchPipeline: &v1alpha3.MultiBranchPipeline{
+				SourceType:   v1alpha3.SourceTypeGithub,
+				GitHubSource: &v1alpha3.GithubSource{Owner: "linuxsuren", Repo: "tools", CredentialId: "github"},
+			},
+		},
+	}
+	secret := &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "github"},
+		Type:       v1alpha3.SecretTypeBasicAuth,
+		Data:       map[string][]byte{v1.BasicAuthUsernameKey: []byte("bot"), v1.BasicAuthPasswordKey: []byte("token")},
+	}
+	webhookSecret := &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "webhook-secret"},
+		Type:       v1alpha3.SecretTypeSecretText,
+		Data:       map[string][]byte{v1alpha3.SecretTextSecretKey: []byte("webhook-token")},
+	}
+	running := &v1alpha3.PipelineRun{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: "ns",
+			Name:      "tools-abc",
+			Labels:    map[string]string{v1alpha3.PipelineNameLabelKey: "tools"},
+			Annotations: map[string]string{
+				v1alpha3.JenkinsPipelineRunIDAnnoKey:           "3",
+				v1alpha3.JenkinsPipelineRunStagesStatusAnnoKey: `[{"id":"6","steps":[{"id":"7","state":"PAUSED","input":{"id":"Deploy"}}]}]`,
+			},
+		},
+		Spec: v1alpha3.PipelineRunSpec{SCM: &v1alpha3.SCM{RefName: "PR-1"}},
+	}
+	repo := scm.Repository{
+		FullName: "linuxsuren/tools",
+		Link:     "https://github.com/linuxsuren/tools",
+		Clone:    "https://github.com/linuxsuren/tools.git",
+	}
+
+	tests := []struct {
+		name          string
+		comment       *pullRequestComment
+		prepare       func()
+		forged        bool
+		wantFound     bool
+		wantErr       bool
+		wantRuns      int
+		wantStopped   []string
+		wantSubmitted []string
+	}{{
+		name:     "no commands",
+		comment:  &pullRequestComment{repo: repo, number: 1, author: "rick", body: "LGTM"},
+		prepare:  func() {},
+		wantRuns: 1,
+	}, {
+		name:      "forged payload",
+		comment:   &pullRequestComment{repo: repo, number: 1, author: "rick", body: "/approve"},
+		prepare:   func() {},
+		forged:    true,
+		wantFound: true,
+		wantErr:   true,
+		wantRuns:  1,
+	}, {
+		name:    "read-only collaborator",
+		comment: &pullRequestComment{repo: repo, number: 1, author: "rick", body: "/retest"},
+		prepare: func() {
+			gock.New("https://api.github.com").Get("/repos/linuxsuren/tools/collaborators/rick/permission").
+				Reply(http.StatusOK).JSON(map[string]string{"permission": "read"})
+			gock.New("https://api.github.com").Post("/repos/linuxsuren/tools/issues/1/comments").
+				BodyString("only the users who are able to write").Reply(http.StatusCreated).JSON(map[string]interface{}{})
+		},
+		wantFound: true,
+		wantRuns:  1,
+	}, {
+		name: "the server comes from the Pipeline instead of the payload",
+		comment: &pullRequestComment{repo: scm.Repository{
+			FullName: "attacker/tools",
+			Link:     "https://attacker.example.com/linuxsuren/tools",
+			Clone:    "https://github.com/linuxsuren/tools.git",
+		}, number: 1, author: "rick", body: "/retest"},
+		prepare: func() {
+			gock.New("https://api.github.com").Get("/repos/linuxsuren/tools/collaborators/rick/permission").
+				Reply(http.StatusOK).JSON(map[string]string{"permission": "write"})
+			gock.New("https://api.github.com").Post("/repos/linuxsuren/tools/issues/1/comments").
+				Reply(http.StatusCreated).JSON(map[string]interface{}{})
+		},
+		wantFound: true,
+		wantRuns:  2,
+	}, {
+		name:    "retest, abort and approve",
+		comment: &pullRequestComment{repo: repo, number: 1, author: "rick", body: "/retest\n/approve Deploy\n/abort"},
+		prepare: func() {
+			gock.New("https://api.github.com").Get("/repos/linuxsuren/tools/collaborators/rick/permission").
+				Reply(http.StatusOK).JSON(map[string]string{"permission": "admin"})
+			gock.New("https://api.github.com").Post("/repos/linuxsuren/tools/issues/1/comments").
+				BodyString("approved").Reply(http.StatusCreated).JSON(map[string]interface{}{})
+		},
+		wantFound:     true,
+		wantRuns:      2,
+		wantStopped:   []string{"PR-1/3"},
+		wantSubmitted: []string{"PR-1/3/6/7"},
+	}, {
+		name:    "failed to reply",
+		comment: &pullRequestComment{repo: repo, number: 1, author: "rick", body: "/abort"},
+		prepare: func() {
+			gock.New("https://api.github.com").Get("/repos/linuxsuren/tools/collaborators/rick/permission").
+				Reply(http.StatusOK).JSON(map[string]string{"permission": "admin"})
+			gock.New("https://api.github.com").Post("/repos/linuxsuren/tools/issues/1/comments").Reply(http.StatusInternalServerError)
+		},
+		wantFound:   true,
+		wantErr:     true,
+		wantRuns:    1,
+		wantStopped: []string{"PR-1/3"},
+	}, {
+		name:     "another repository",
+		comment:  &pullRequestComment{repo: scm.Repository{FullName: "a/b", Link: "https://github.com/a/b"}, number: 1, body: "/retest"},
+		prepare:  func() {},
+		wantRuns: 1,
+	}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer gock.Off()
+			tt.prepare()
+
+			devopsClient := &recordDevops{Devops: fakedevops.New()}
+			handler := NewSCMHandler(fake.NewClientBuilder().WithScheme(schema).
+				WithObjects(pipeline.DeepCopy(), secret.DeepCopy(), webhookSecret.DeepCopy(), running.DeepCopy()).Build(),
+				core.JenkinsCore{}, devopsClient, "kubesphere-devops-system")
+			parse := func(secret string) (scm.Webhook, error) {
+				if tt.forged || secret != "webhook-token" {
+					return nil, scm.ErrSignatureInvalid
+				}
+				return nil, nil
+			}
+
+			found, err := handler.handlePullRequestComment(context.TODO(), tt.comment, scm.DriverGithub, parse)
+			assert.Equal(t, tt.wantFound, found)
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+				assert.True(t, gock.IsDone())
+			}
+
+			runs := &v1alpha3.PipelineRunList{}
+			assert.NoError(t, handler.List(context.TODO(), runs, client.InNamespace("ns")))
+			assert.Len(t, runs.Items, tt.wantRuns)
+			assert.Equal(t, tt.wantStopped, devopsClient.stopped)
+			assert.Equal(t, tt.wantSubmitted, devopsClient.submitted)
+		})
+	}
+}
@@ -26,11 +26,13 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	"github.com/kubesphere/ks-devops/pkg/api"
+	"github.com/kubesphere/ks-devops/pkg/client/devops"
 	"github.com/kubesphere/ks-devops/pkg/constants"
 )
 
 // RegisterWebhooks registers all webhooks into web service.
-func RegisterWebhooks(genericClient client.Client, ws *restful.WebService, jenkins core.JenkinsCore) {
+func RegisterWebhooks(genericClient client.Client, ws *restful.WebService, jenkins core.JenkinsCore, devopsClient devops.PipelineOperator,
+	jenkinsNamespace string) {
 	webhookHandler := NewHandler(genericClient)
 	ws.Route(ws.POST("/webhooks/jenkins").
 		To(webhookHandler.ReceiveEventsFromJenkins).
@@ -39,7 +41,7 @@ func RegisterWebhooks(genericClient client.Client, ws *restful.WebService, jenki
 		Reads(json.RawMessage{}).
 		Returns(http.StatusOK, api.StatusOK, json.RawMessage{}))
 
-	scmHandler := NewSCMHandler(genericClient, jenkins)
+	scmHandler := NewSCMHandler(genericClient, jenkins, devopsClient, jenkinsNamespace)
 	ws.Route(ws.POST("/webhooks/scm").
 		Metadata(restfulspec.KeyOpenAPITags, constants.DevOpsWebhookTags).
 		Reads(json.RawMessage{}).
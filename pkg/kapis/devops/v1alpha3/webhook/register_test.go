@@ -36,6 +36,7 @@ import (
 	"github.com/kubesphere/ks-devops/pkg/api/devops/v1alpha3"
 	apiserverruntime "github.com/kubesphere/ks-devops/pkg/apiserver/runtime"
 	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/client/fake"
@@ -159,7 +160,7 @@ func TestJenkinsWebhook(t *testing.T) {
 
 			container := restful.NewContainer()
 			wsWithGroup := apiserverruntime.NewWebService(v1alpha3.GroupVersion)
-			RegisterWebhooks(fakeClient, wsWithGroup, core.JenkinsCore{})
+			RegisterWebhooks(fakeClient, wsWithGroup, core.JenkinsCore{}, nil, "kubesphere-devops-system")
 			container.Add(wsWithGroup)
 
 			var bodyReader io.Reader
@@ -188,6 +189,13 @@ func TestSCMWebhook(t *testing.T) {
 		scmRefAnnotationKey: `["master"]`,
 		scmAnnotationKey:    "https://gitlab.com/linuxsuren/test",
 	})
+	webhookSecret := &corev1.Secret{
+		ObjectMeta: v1.ObjectMeta{Namespace: "default", Name: "webhook-secret"},
+		Type:       v1alpha3.SecretTypeSecretText,
+		Data:       map[string][]byte{v1alpha3.SecretTextSecretKey: []byte("token")},
+	}
+	securedPipeline := defaultPipeline.DeepCopy()
+	securedPipeline.Annotations[scmWebhookSecretAnnotationKey] = "webhook-secret"
 
 	type args struct {
 		method     string
@@ -199,6 +207,7 @@ func TestSCMWebhook(t *testing.T) {
 	tests := []struct {
 		name      string
 		args      args
+		wantCode  int
 		assertion func(t *testing.T, c client.Client, body string)
 	}{{
 		name: "unknown SCM webhook",
@@ -238,6 +247,60 @@ func TestSCMWebhook(t *testing.T) {
 		assertion: func(t *testing.T, c client.Client, body string) {
 			assert.Equal(t, "ok", body)
 		},
+	}, {
+		name: "gitlab webhook with a valid token",
+		args: args{
+			method:     http.MethodPost,
+			uri:        "/webhooks/scm",
+			initObject: []client.Object{securedPipeline.DeepCopy(), webhookSecret.DeepCopy()},
+			bodyJSON:   gitlabWebhookBody,
+			header: map[string]string{
+				"X-Gitlab-Event": "Push Hook",
+				"X-Gitlab-Token": "token",
+			},
+		},
+		assertion: func(t *testing.T, c client.Client, body string) {
+			assert.Equal(t, "ok", body)
+			runs := &v1alpha3.PipelineRunList{}
+			assert.Nil(t, c.List(context.Background(), runs))
+			assert.Len(t, runs.Items, 1)
+		},
+	}, {
+		name: "gitlab webhook with an invalid token",
+		args: args{
+			method:     http.MethodPost,
+			uri:        "/webhooks/scm",
+			initObject: []client.Object{securedPipeline.DeepCopy(), webhookSecret.DeepCopy()},
+			bodyJSON:   gitlabWebhookBody,
+			header: map[string]string{
+				"X-Gitlab-Event": "Push Hook",
+				"X-Gitlab-Token": "forged",
+			},
+		},
+		wantCode: http.StatusBadRequest,
+		assertion: func(t *testing.T, c client.Client, body string) {
+			runs := &v1alpha3.PipelineRunList{}
+			assert.Nil(t, c.List(context.Background(), runs))
+			assert.Empty(t, runs.Items)
+		},
+	}, {
+		name: "gitlab webhook without the secret which the Pipeline refers to",
+		args: args{
+			method:     http.MethodPost,
+			uri:        "/webhooks/scm",
+			initObject: []client.Object{securedPipeline.DeepCopy()},
+			bodyJSON:   gitlabWebhookBody,
+			header: map[string]string{
+				"X-Gitlab-Event": "Push Hook",
+				"X-Gitlab-Token": "token",
+			},
+		},
+		wantCode: http.StatusBadRequest,
+		assertion: func(t *testing.T, c client.Client, body string) {
+			runs := &v1alpha3.PipelineRunList{}
+			assert.Nil(t, c.List(context.Background(), runs))
+			assert.Empty(t, runs.Items)
+		},
 	}}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -246,7 +309,7 @@ func TestSCMWebhook(t *testing.T) {
 
 			container := restful.NewContainer()
 			wsWithGroup := apiserverruntime.NewWebService(v1alpha3.GroupVersion)
-			RegisterWebhooks(fakeClient, wsWithGroup, core.JenkinsCore{})
+			RegisterWebhooks(fakeClient, wsWithGroup, core.JenkinsCore{}, nil, "kubesphere-devops-system")
 			container.Add(wsWithGroup)
 
 			var bodyReader io.Reader
@@ -262,7 +325,10 @@ func TestSCMWebhook(t *testing.T) {
 			}
 			httpWriter := httptest.NewRecorder()
 			container.Dispatch(httpWriter, httpRequest)
-			assert.Equal(t, 200, httpWriter.Code)
+			if tt.wantCode == 0 {
+				tt.wantCode = http.StatusOK
+			}
+			assert.Equal(t, tt.wantCode, httpWriter.Code)
 			if tt.assertion != nil {
 				body := httpWriter.Body
 				var bodyResponse string
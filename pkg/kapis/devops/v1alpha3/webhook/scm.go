@@ -17,20 +17,28 @@ limitations under the License.
 package webhook
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
 	"github.com/emicklei/go-restful/v3"
 	"github.com/jenkins-x/go-scm/scm"
 	"github.com/jenkins-x/go-scm/scm/driver/bitbucket"
+	"github.com/jenkins-x/go-scm/scm/driver/gitea"
 	"github.com/jenkins-x/go-scm/scm/driver/github"
 	"github.com/jenkins-x/go-scm/scm/driver/gitlab"
 	"github.com/jenkins-zh/jenkins-client/pkg/core"
 	"github.com/jenkins-zh/jenkins-client/pkg/job"
 	"github.com/kubesphere/ks-devops/pkg/api/devops/v1alpha3"
 	"github.com/kubesphere/ks-devops/pkg/client/devops"
+	jenkinsclient "github.com/kubesphere/ks-devops/pkg/client/devops/jenkins"
+	"github.com/kubesphere/ks-devops/pkg/client/devops/sharding"
 	"github.com/kubesphere/ks-devops/pkg/kapis/devops/v1alpha3/pipelinerun"
+	"io"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
 	"net/http"
+	"net/url"
 	"regexp"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"strings"
@@ -41,19 +49,36 @@ import (
 const tokenExpireIn time.Duration = 5 * time.Minute
 const scmAnnotationKey = "scm.devops.kubesphere.io"
 const scmRefAnnotationKey = "scm.devops.kubesphere.io/ref"
-const triggerAnnotationKey = "devops.kubesphere.io/trigger"
+const triggerAnnotationKey = v1alpha3.PipelineRunTriggerAnnoKey
+
+// scmCredentialAnnotationKey is the credential which is used to report the commit status of the webhook-triggered PipelineRuns
+const scmCredentialAnnotationKey = "scm.devops.kubesphere.io/credential"
+
+// scmWebhookSecretAnnotationKey is the secret-text credential which verifies the webhook payloads of a Pipeline,
+// the payloads are not verified for the Pipelines without it
+const scmWebhookSecretAnnotationKey = "scm.devops.kubesphere.io/webhook-secret"
 
 // SCMHandler handles requests from webhooks.
 type SCMHandler struct {
 	client.Client
-	jenkins core.JenkinsCore
+	jenkins      core.JenkinsCore
+	devopsClient devops.PipelineOperator
+	// cores finds the Jenkins instance of the DevOps projects, it's nil if there is only one Jenkins instance
+	cores sharding.CoreGetter
+	// jenkinsNamespace is the namespace of Jenkins, the GitLab servers are read from its configuration-as-code
+	jenkinsNamespace string
 }
 
 // NewSCMHandler creates a new handler for handling webhooks.
-func NewSCMHandler(genericClient client.Client, jenkins core.JenkinsCore) *SCMHandler {
+func NewSCMHandler(genericClient client.Client, jenkins core.JenkinsCore, devopsClient devops.PipelineOperator,
+	jenkinsNamespace string) *SCMHandler {
+	cores, _ := devopsClient.(sharding.CoreGetter)
 	return &SCMHandler{
-		Client:  genericClient,
-		jenkins: jenkins,
+		Client:           genericClient,
+		jenkins:          jenkins,
+		devopsClient:     devopsClient,
+		cores:            cores,
+		jenkinsNamespace: jenkinsNamespace,
 	}
 }
 
@@ -62,10 +87,16 @@ func getSCMClient(request *http.Request) *scm.Client {
 		return gitlab.NewDefault()
 	}
 
+	// Gitea sends the header X-GitHub-Event as well, so check it before GitHub
+	if request.Header.Get("X-Gitea-Event") != "" {
+		return &scm.Client{Driver: scm.DriverGitea, Webhooks: gitea.NewWebHookService()}
+	}
+
 	if request.Header.Get("X-GitHub-Event") != "" {
 		return github.NewDefault()
 	}
 
+	// the webhooks of Bitbucket Cloud
 	if strings.HasPrefix(request.Header.Get("User-Agent"), "Bitbucket-Webhooks") {
 		return bitbucket.NewDefault()
 	}
@@ -79,9 +110,21 @@ func (h *SCMHandler) scmWebhook(request *restful.Request, response *restful.Resp
 		return
 	}
 
-	webhook, err := scmClient.Webhooks.Parse(request.Request, func(webhook scm.Webhook) (string, error) {
-		return "", nil
-	})
+	body, err := io.ReadAll(request.Request.Body)
+	if err != nil {
+		_, _ = response.Write([]byte(err.Error()))
+		return
+	}
+	// the payload is parsed without verification to find the Pipelines, then it's verified by the secret of each Pipeline
+	parse := func(secret string) (scm.Webhook, error) {
+		req := request.Request.Clone(request.Request.Context())
+		req.Body = io.NopCloser(bytes.NewReader(body))
+		return scmClient.Webhooks.Parse(req, func(webhook scm.Webhook) (string, error) {
+			return secret, nil
+		})
+	}
+
+	webhook, err := parse("")
 	if err != nil {
 		_, _ = response.Write([]byte(err.Error()))
 		return
@@ -89,9 +132,11 @@ func (h *SCMHandler) scmWebhook(request *restful.Request, response *restful.Resp
 
 	ctx := context.TODO()
 	found := false
-	if webhook.Kind() == scm.WebhookKindPush {
+	switch webhook.Kind() {
+	case scm.WebhookKindPush:
 		repo := webhook.Repository()
 		pushHook := webhook.(*scm.PushHook)
+		gitLabServers := h.getGitLabServers(ctx)
 
 		pipelineList := &v1alpha3.PipelineList{}
 		if err = h.List(ctx, pipelineList); err == nil {
@@ -104,19 +149,27 @@ func (h *SCMHandler) scmWebhook(request *restful.Request, response *restful.Resp
 
 				gitURL := pipeline.GetAnnotations()[scmAnnotationKey]
 				if pipeline.IsMultiBranch() {
-					gitURL = pipeline.Spec.MultiBranchPipeline.GetGitURL()
-					if gitURL != "" && gitRepoMatch(gitURL, repo.Link, repo.Clone, repo.CloneSSH) {
-						err = scanJenkinsMultiBranchPipeline(pipeline, h.jenkins)
+					repoURL := pipeline.Spec.MultiBranchPipeline.ResolveGitURL(gitLabServers)
+					if gitRepoMatch(repoURL, repo) {
+						if err = h.verifyWebhook(ctx, &pipeline, parse); err == nil {
+							err = h.scanJenkinsMultiBranchPipeline(pipeline)
+						}
 					}
 				} else if gitURL != "" {
-					if gitRepoMatch(gitURL, repo.Link, repo.Clone, repo.CloneSSH) {
-						err = h.createPipelineRun(pipeline, pushHook)
+					if v1alpha3.GitURLMatch(gitURL, repo.Link, repo.Clone, repo.CloneSSH) {
+						if err = h.verifyWebhook(ctx, &pipeline, parse); err == nil {
+							err = h.createPipelineRun(pipeline, pushHook, scmClient.Driver)
+						}
 					} else {
 						err = fmt.Errorf("expect URL: %s, got: %v", gitURL, []string{repo.Link, repo.Clone, repo.CloneSSH})
 					}
 				}
 			}
 		}
+	case scm.WebhookKindIssueComment, scm.WebhookKindPullRequestComment:
+		if comment := getPullRequestComment(webhook); comment != nil {
+			found, err = h.handlePullRequestComment(ctx, comment, scmClient.Driver, parse)
+		}
 	}
 
 	if !found {
@@ -129,19 +182,81 @@ func (h *SCMHandler) scmWebhook(request *restful.Request, response *restful.Resp
 	}
 }
 
-func (h *SCMHandler) createPipelineRun(pipeline v1alpha3.Pipeline, hook *scm.PushHook) (err error) {
+func (h *SCMHandler) createPipelineRun(pipeline v1alpha3.Pipeline, hook *scm.PushHook, driver scm.Driver) (err error) {
 	branch := strings.TrimPrefix(hook.Ref, "refs/heads/")
 
 	var scmObj *v1alpha3.SCM
-	if scmObj, err = pipelinerun.CreateScm(&pipeline.Spec, branch); err == nil {
-		run := pipelinerun.CreatePipelineRun(&pipeline, &devops.RunPayload{}, scmObj)
+	var run *v1alpha3.PipelineRun
+	if scmObj, err = pipelinerun.CreateScm(&pipeline.Spec, branch); err != nil {
+		return
+	}
+	if run, err = pipelinerun.CreatePipelineRun(&pipeline, &devops.RunPayload{}, scmObj); err == nil {
 		run.Annotations[triggerAnnotationKey] = "webhook"
+		setCommitAnnotations(run, &pipeline, hook, driver)
 		err = h.Create(context.Background(), run)
 	}
 	return
 }
 
-func scanJenkinsMultiBranchPipeline(pipeline v1alpha3.Pipeline, jenkins core.JenkinsCore) (err error) {
+// setCommitAnnotations records the commit and its repository, then the commit status could be reported.
+// The server and the repository come from the Pipeline instead of the payload, so the credential is only sent to the
+// server which the Pipeline points to.
+func setCommitAnnotations(run *v1alpha3.PipelineRun, pipeline *v1alpha3.Pipeline, hook *scm.PushHook, driver scm.Driver) {
+	if hook.After != "" {
+		run.Annotations[v1alpha3.PipelineRunCommitAnnoKey] = hook.After
+	}
+	credential := pipeline.GetAnnotations()[scmCredentialAnnotationKey]
+	server, repo := v1alpha3.SplitGitURL(pipeline.GetAnnotations()[scmAnnotationKey])
+	if credential == "" || server == "" {
+		return
+	}
+	run.Annotations[v1alpha3.PipelineRunSCMCredentialAnnoKey] = credential
+	run.Annotations[v1alpha3.PipelineRunSCMProviderAnnoKey] = driver.String()
+	run.Annotations[v1alpha3.PipelineRunSCMRepoAnnoKey] = repo
+	if server = getSCMServer(driver, server); server != "" {
+		run.Annotations[v1alpha3.PipelineRunSCMServerAnnoKey] = server
+	}
+}
+
+// verifyWebhook verifies the payload by the webhook secret of the Pipeline, it's skipped if there is no webhook secret
+func (h *SCMHandler) verifyWebhook(ctx context.Context, pipeline *v1alpha3.Pipeline, parse func(secret string) (scm.Webhook, error)) (err error) {
+	secretName := pipeline.GetAnnotations()[scmWebhookSecretAnnotationKey]
+	if secretName == "" {
+		return
+	}
+
+	secret := &v1.Secret{}
+	if err = h.Get(ctx, types.NamespacedName{Namespace: pipeline.Namespace, Name: secretName}, secret); err != nil {
+		return
+	}
+	token := string(secret.Data[v1alpha3.SecretTextSecretKey])
+	if token == "" {
+		err = fmt.Errorf("the webhook secret %s/%s is empty", pipeline.Namespace, secretName)
+		return
+	}
+	if _, err = parse(token); err != nil {
+		err = fmt.Errorf("failed to verify the webhook for Pipeline %s/%s, error: %v", pipeline.Namespace, pipeline.Name, err)
+	}
+	return
+}
+
+// getSCMServer returns the server address for go-scm, it is empty for the public GitHub
+func getSCMServer(driver scm.Driver, link string) (server string) {
+	if u, err := url.Parse(link); err == nil && u.Host != "" {
+		server = fmt.Sprintf("%s://%s", u.Scheme, u.Host)
+	}
+	if driver == scm.DriverGithub && server == v1alpha3.GitHubServerURL {
+		server = ""
+	}
+	return
+}
+
+// scanJenkinsMultiBranchPipeline scans the multi-branch Pipeline on the Jenkins instance of its DevOps project
+func (h *SCMHandler) scanJenkinsMultiBranchPipeline(pipeline v1alpha3.Pipeline) (err error) {
+	var jenkins core.JenkinsCore
+	if jenkins, err = sharding.GetJenkinsCore(h.cores, pipeline.Namespace, h.jenkins); err != nil {
+		return
+	}
 	jclient := job.Client{
 		JenkinsCore: jenkins,
 	}
@@ -171,13 +286,17 @@ func branchMatch(pipeline v1alpha3.Pipeline, branch string) (ok bool) {
 	return
 }
 
-// gitRepoMatch if the source matches target
-func gitRepoMatch(source string, targets ...string) (ok bool) {
-	for _, target := range targets {
-		if target == source {
-			ok = true
-			break
+// gitRepoMatch returns true if any address of the repository matches the one from a webhook
+func gitRepoMatch(repoURL v1alpha3.GitRepoURL, repo scm.Repository) bool {
+	for _, address := range []string{repoURL.Web, repoURL.Clone, repoURL.CloneSSH} {
+		if v1alpha3.GitURLMatch(address, repo.Link, repo.Clone, repo.CloneSSH) {
+			return true
 		}
 	}
-	return
+	return false
+}
+
+// getGitLabServers returns the GitLab servers configured in Jenkins, it returns nil if there is no such configuration
+func (h *SCMHandler) getGitLabServers(ctx context.Context) v1alpha3.GitLabServers {
+	return jenkinsclient.GetGitLabServers(ctx, h, h.jenkinsNamespace)
 }
@@ -17,14 +17,18 @@ limitations under the License.
 package webhook
 
 import (
+	"fmt"
 	"github.com/jenkins-x/go-scm/scm"
 	"github.com/jenkins-x/go-scm/scm/driver/bitbucket"
+	"github.com/jenkins-x/go-scm/scm/driver/gitea"
 	"github.com/jenkins-x/go-scm/scm/driver/github"
 	"github.com/jenkins-x/go-scm/scm/driver/gitlab"
+	"github.com/jenkins-zh/jenkins-client/pkg/core"
 	"github.com/kubesphere/ks-devops/pkg/api/devops/v1alpha3"
 	"github.com/stretchr/testify/assert"
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"net/http"
+	"net/http/httptest"
 	"testing"
 )
 
@@ -69,6 +73,18 @@ func Test_getSCMClient(t *testing.T) {
 			},
 		},
 		want: bitbucket.NewDefault(),
+	}, {
+		name: "gitea",
+		args: args{
+			request: func() *http.Request {
+				defaultRequest := &http.Request{}
+				defaultRequest.Header = map[string][]string{}
+				defaultRequest.Header.Add("X-Gitea-Event", "push")
+				defaultRequest.Header.Add("X-GitHub-Event", "push")
+				return defaultRequest
+			},
+		},
+		want: &scm.Client{Driver: scm.DriverGitea, Webhooks: gitea.NewWebHookService()},
 	}, {
 		name: "unknown SCM provider",
 		args: args{
@@ -134,3 +150,114 @@ func Test_branchMatch(t *testing.T) {
 		})
 	}
 }
+
+func Test_gitRepoMatch(t *testing.T) {
+	tests := []struct {
+		name    string
+		repoURL v1alpha3.GitRepoURL
+		repo    scm.Repository
+		want    bool
+	}{{
+		name:    "matched by the SSH clone address",
+		repoURL: v1alpha3.GitRepoURL{Web: "https://gitlab.example.com/devops/demo"},
+		repo:    scm.Repository{CloneSSH: "git@gitlab.example.com:devops/demo.git"},
+		want:    true,
+	}, {
+		name: "Bitbucket Server addresses",
+		repoURL: v1alpha3.GitRepoURL{
+			Web:   "https://bitbucket.example.com/projects/PRJ/repos/demo",
+			Clone: "https://bitbucket.example.com/scm/prj/demo.git",
+		},
+		repo: scm.Repository{Link: "https://bitbucket.example.com/projects/PRJ/repos/demo/browse"},
+		want: true,
+	}, {
+		name:    "different servers",
+		repoURL: v1alpha3.GitRepoURL{Web: "https://gitlab.com/devops/demo"},
+		repo:    scm.Repository{Link: "https://gitlab.example.com/devops/demo"},
+		want:    false,
+	}, {
+		name: "empty addresses",
+		want: false,
+	}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, gitRepoMatch(tt.repoURL, tt.repo))
+		})
+	}
+}
+
+func Test_setCommitAnnotations(t *testing.T) {
+	hook := &scm.PushHook{
+		After: "6dcb09b5b57875f334f61aebed695e2e4193db5e",
+		Repo: scm.Repository{
+			FullName: "attacker/demo",
+			Link:     "https://attacker.example.com/devops/demo",
+		},
+	}
+	pipeline := &v1alpha3.Pipeline{ObjectMeta: v1.ObjectMeta{Annotations: map[string]string{
+		scmAnnotationKey: "https://gitlab.example.com/devops/demo.git",
+	}}}
+
+	run := &v1alpha3.PipelineRun{ObjectMeta: v1.ObjectMeta{Annotations: map[string]string{}}}
+	setCommitAnnotations(run, pipeline, hook, scm.DriverGitlab)
+	assert.Equal(t, map[string]string{
+		v1alpha3.PipelineRunCommitAnnoKey: "6dcb09b5b57875f334f61aebed695e2e4193db5e",
+	}, run.Annotations)
+
+	// the server and the repository come from the Pipeline instead of the payload
+	pipeline.Annotations[scmCredentialAnnotationKey] = "gitlab-token"
+	run = &v1alpha3.PipelineRun{ObjectMeta: v1.ObjectMeta{Annotations: map[string]string{}}}
+	setCommitAnnotations(run, pipeline, hook, scm.DriverGitlab)
+	assert.Equal(t, map[string]string{
+		v1alpha3.PipelineRunCommitAnnoKey:        "6dcb09b5b57875f334f61aebed695e2e4193db5e",
+		v1alpha3.PipelineRunSCMCredentialAnnoKey: "gitlab-token",
+		v1alpha3.PipelineRunSCMProviderAnnoKey:   "gitlab",
+		v1alpha3.PipelineRunSCMRepoAnnoKey:       "devops/demo",
+		v1alpha3.PipelineRunSCMServerAnnoKey:     "https://gitlab.example.com",
+	}, run.Annotations)
+}
+
+func Test_getSCMServer(t *testing.T) {
+	assert.Equal(t, "", getSCMServer(scm.DriverGithub, "https://github.com/devops/demo"))
+	assert.Equal(t, "https://ghe.example.com", getSCMServer(scm.DriverGithub, "https://ghe.example.com/devops/demo"))
+	assert.Equal(t, "http://10.0.0.1:3000", getSCMServer(scm.DriverGitea, "http://10.0.0.1:3000/devops/demo"))
+	assert.Equal(t, "", getSCMServer(scm.DriverGitea, ""))
+}
+
+type fakeCoreGetter struct {
+	cores map[string]core.JenkinsCore
+}
+
+func (g *fakeCoreGetter) GetJenkinsCore(namespace string) (jenkinsCore core.JenkinsCore, err error) {
+	var ok bool
+	if jenkinsCore, ok = g.cores[namespace]; !ok {
+		err = fmt.Errorf("no Jenkins instance found for namespace %s", namespace)
+	}
+	return
+}
+
+func TestSCMHandler_scanJenkinsMultiBranchPipeline(t *testing.T) {
+	var scanned []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			scanned = append(scanned, r.URL.Path)
+			w.WriteHeader(http.StatusCreated)
+			return
+		}
+		// the crumb
+		_, _ = w.Write([]byte(`{"crumbRequestField": "Jenkins-Crumb", "crumb": "crumb"}`))
+	}))
+	defer server.Close()
+
+	pipeline := v1alpha3.Pipeline{ObjectMeta: v1.ObjectMeta{Namespace: "ns", Name: "demo"}}
+	// the default Jenkins is not accessible, the Pipeline is on the instance of its DevOps project
+	handler := &SCMHandler{
+		jenkins: core.JenkinsCore{URL: "http://127.0.0.1:0"},
+		cores:   &fakeCoreGetter{cores: map[string]core.JenkinsCore{"ns": {URL: server.URL}}},
+	}
+	assert.Nil(t, handler.scanJenkinsMultiBranchPipeline(pipeline))
+	assert.Equal(t, []string{"/job/ns/job/demo/build"}, scanned)
+
+	pipeline.Namespace = "unknown"
+	assert.NotNil(t, handler.scanJenkinsMultiBranchPipeline(pipeline))
+}
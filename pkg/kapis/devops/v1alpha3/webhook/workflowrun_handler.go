@@ -155,6 +155,8 @@ func (handler *Handler) createPipelineRun(identifier *pipelineRunIdentifier, par
 		return nil, err
 	}
 
+	// the run was triggered in Jenkins already, so only make sure the passwords are not persisted
+	parameters = v1alpha3.RemovePasswordValues(pipeline.GetParameterDefinitions(), parameters)
 	pipelineRun := pipelinerun.CreateBarePipelineRun(pipeline, parameters, scm)
 
 	// Set the RunID manually
@@ -0,0 +1,54 @@
+/*
+Copyright 2022 The KubeSphere Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kapis
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/emicklei/go-restful/v3"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/kubesphere/ks-devops/pkg/client/devops/sharding"
+)
+
+// JenkinsHealthFilter rejects the requests with http.StatusServiceUnavailable while the Jenkins instance of the
+// namespace is unavailable, according to the health reported by the controller manager. It's for the creations
+// which need Jenkins, otherwise the created objects would be pending silently until Jenkins recovers.
+// The namespace is read from the path parameter "devops" or "namespace". The requests go through if the health is
+// unknown or stale. The locator is optional, the default Jenkins instance is checked if it's nil.
+func JenkinsHealthFilter(reader client.Reader, locator sharding.Locator, jenkinsNamespace string) restful.FilterFunction {
+	return func(req *restful.Request, resp *restful.Response, chain *restful.FilterChain) {
+		namespace := req.PathParameter("devops")
+		if namespace == "" {
+			namespace = req.PathParameter("namespace")
+		}
+
+		if reader != nil && jenkinsNamespace != "" && namespace != "" {
+			available, message, err := sharding.GetReportedHealth(req.Request.Context(), reader, locator, jenkinsNamespace, namespace)
+			if err != nil {
+				klog.V(4).Infof("failed to get the health of Jenkins for namespace %s, error: %v", namespace, err)
+			} else if !available {
+				handle(http.StatusServiceUnavailable, req, resp,
+					fmt.Errorf("jenkins is unavailable, please try again later: %s", message))
+				return
+			}
+		}
+		chain.ProcessFilter(req, resp)
+	}
+}
@@ -0,0 +1,124 @@
+/*
+Copyright 2022 The KubeSphere Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kapis
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/emicklei/go-restful/v3"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+type fakeLocator struct {
+	instances map[string]string
+}
+
+func (f *fakeLocator) Locate(namespace string) (string, error) {
+	return f.instances[namespace], nil
+}
+
+func TestJenkinsHealthFilter(t *testing.T) {
+	newConfigMap := func(name, available string) *corev1.ConfigMap {
+		return &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "kubesphere-devops-system", Name: name},
+			Data: map[string]string{"available": available, "message": "connection refused",
+				"lastProbeTime": time.Now().UTC().Format(time.RFC3339), "probeInterval": "10s"},
+		}
+	}
+	staleConfigMap := newConfigMap("jenkins-health", "false")
+	staleConfigMap.Data["lastProbeTime"] = time.Now().Add(-time.Minute).UTC().Format(time.RFC3339)
+
+	tests := []struct {
+		name             string
+		objects          []runtime.Object
+		withoutReader    bool
+		jenkinsNamespace string
+		path             string
+		expectCode       int
+	}{{
+		name:             "the health is not reported",
+		jenkinsNamespace: "kubesphere-devops-system",
+		path:             "/namespaces/team-a/credentials",
+		expectCode:       http.StatusOK,
+	}, {
+		name:             "Jenkins is available",
+		objects:          []runtime.Object{newConfigMap("jenkins-health", "true")},
+		jenkinsNamespace: "kubesphere-devops-system",
+		path:             "/namespaces/team-a/credentials",
+		expectCode:       http.StatusOK,
+	}, {
+		name:             "Jenkins is unavailable",
+		objects:          []runtime.Object{newConfigMap("jenkins-health", "false")},
+		jenkinsNamespace: "kubesphere-devops-system",
+		path:             "/namespaces/team-a/credentials",
+		expectCode:       http.StatusServiceUnavailable,
+	}, {
+		name:             "the controller manager stopped reporting the health",
+		objects:          []runtime.Object{staleConfigMap},
+		jenkinsNamespace: "kubesphere-devops-system",
+		path:             "/namespaces/team-a/credentials",
+		expectCode:       http.StatusOK,
+	}, {
+		name:             "the Jenkins instance of the namespace is unavailable",
+		objects:          []runtime.Object{newConfigMap("jenkins-health-b", "false")},
+		jenkinsNamespace: "kubesphere-devops-system",
+		path:             "/namespaces/team-b/pipelines/build/pipelineruns",
+		expectCode:       http.StatusServiceUnavailable,
+	}, {
+		name:             "another Jenkins instance is unavailable",
+		objects:          []runtime.Object{newConfigMap("jenkins-health-b", "false")},
+		jenkinsNamespace: "kubesphere-devops-system",
+		path:             "/namespaces/team-a/pipelines/build/pipelineruns",
+		expectCode:       http.StatusOK,
+	}, {
+		name:          "without reader",
+		withoutReader: true,
+		path:          "/namespaces/team-a/credentials",
+		expectCode:    http.StatusOK,
+	}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var reader client.Reader
+			if !tt.withoutReader {
+				reader = fake.NewClientBuilder().WithRuntimeObjects(tt.objects...).Build()
+			}
+			filter := JenkinsHealthFilter(reader, &fakeLocator{instances: map[string]string{"team-b": "b"}}, tt.jenkinsNamespace)
+
+			ok := func(req *restful.Request, resp *restful.Response) {
+				resp.WriteHeader(http.StatusOK)
+			}
+			ws := new(restful.WebService)
+			ws.Route(ws.POST("/namespaces/{devops}/credentials").To(ok).Filter(filter))
+			ws.Route(ws.POST("/namespaces/{namespace}/pipelines/{pipeline}/pipelineruns").To(ok).Filter(filter))
+			container := restful.NewContainer()
+			container.Add(ws)
+
+			httpRequest := httptest.NewRequest(http.MethodPost, tt.path, nil)
+			httpWriter := httptest.NewRecorder()
+			container.ServeHTTP(httpWriter, httpRequest)
+			assert.Equal(t, tt.expectCode, httpWriter.Code)
+		})
+	}
+}
@@ -0,0 +1,220 @@
+/*
+Copyright 2022 The KubeSphere Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pipeline
+
+import (
+	"sort"
+
+	"github.com/kubesphere/ks-devops/pkg/api/devops/v1alpha3"
+)
+
+// GraphNode is a Pipeline in the dependency graph
+type GraphNode struct {
+	Name string                `json:"name"`
+	Type v1alpha3.PipelineType `json:"type,omitempty"`
+	// Missing indicates the upstream Pipeline does not exist
+	Missing bool `json:"missing,omitempty"`
+}
+
+// GraphEdge is an upstream trigger, it points from the upstream Pipeline to the downstream one
+type GraphEdge struct {
+	Upstream   string              `json:"upstream"`
+	Downstream string              `json:"downstream"`
+	Phases     []v1alpha3.RunPhase `json:"phases,omitempty"`
+	Branch     string              `json:"branch,omitempty"`
+}
+
+// Graph is the dependency graph of the Pipelines in a namespace
+type Graph struct {
+	Nodes []GraphNode `json:"nodes"`
+	Edges []GraphEdge `json:"edges"`
+	// Cycles are the loops in the graph, the triggers in a loop never create PipelineRuns
+	Cycles [][]string `json:"cycles,omitempty"`
+
+	downstream map[string][]string
+}
+
+// BuildGraph builds the dependency graph from the upstream triggers of the Pipelines
+func BuildGraph(pipelines []v1alpha3.Pipeline) (graph *Graph) {
+	graph = &Graph{
+		Nodes:      []GraphNode{},
+		Edges:      []GraphEdge{},
+		downstream: map[string][]string{},
+	}
+	nodes := map[string]*GraphNode{}
+	for i := range pipelines {
+		pipeline := &pipelines[i]
+		nodes[pipeline.Name] = &GraphNode{Name: pipeline.Name, Type: pipeline.Spec.Type}
+	}
+	for i := range pipelines {
+		pipeline := &pipelines[i]
+		for _, trigger := range pipeline.GetUpstreamTriggers() {
+			if _, ok := nodes[trigger.Pipeline]; !ok {
+				nodes[trigger.Pipeline] = &GraphNode{Name: trigger.Pipeline, Missing: true}
+			}
+			graph.Edges = append(graph.Edges, GraphEdge{
+				Upstream:   trigger.Pipeline,
+				Downstream: pipeline.Name,
+				Phases:     trigger.GetPhases(),
+				Branch:     trigger.Branch,
+			})
+			graph.downstream[trigger.Pipeline] = append(graph.downstream[trigger.Pipeline], pipeline.Name)
+		}
+	}
+
+	for _, node := range nodes {
+		graph.Nodes = append(graph.Nodes, *node)
+	}
+	sort.Slice(graph.Nodes, func(i, j int) bool {
+		return graph.Nodes[i].Name < graph.Nodes[j].Name
+	})
+	sort.SliceStable(graph.Edges, func(i, j int) bool {
+		if graph.Edges[i].Upstream != graph.Edges[j].Upstream {
+			return graph.Edges[i].Upstream < graph.Edges[j].Upstream
+		}
+		return graph.Edges[i].Downstream < graph.Edges[j].Downstream
+	})
+	graph.Cycles = graph.findCycles()
+	return
+}
+
+// Reachable returns true if the Pipeline "to" is triggered by the Pipeline "from" directly or indirectly
+func (g *Graph) Reachable(from, to string) bool {
+	visited := map[string]bool{from: true}
+	queue := []string{from}
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		if current == to {
+			return true
+		}
+		for _, next := range g.downstream[current] {
+			if !visited[next] {
+				visited[next] = true
+				queue = append(queue, next)
+			}
+		}
+	}
+	return false
+}
+
+// InCycle returns true if the trigger from upstream to downstream is a part of a loop
+func (g *Graph) InCycle(upstream, downstream string) bool {
+	return g.Reachable(downstream, upstream)
+}
+
+// Subgraph returns the Pipelines which are connected with the given one
+func (g *Graph) Subgraph(name string) (graph *Graph) {
+	neighbours := map[string][]string{}
+	for _, edge := range g.Edges {
+		neighbours[edge.Upstream] = append(neighbours[edge.Upstream], edge.Downstream)
+		neighbours[edge.Downstream] = append(neighbours[edge.Downstream], edge.Upstream)
+	}
+	connected := map[string]bool{name: true}
+	queue := []string{name}
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		for _, next := range neighbours[current] {
+			if !connected[next] {
+				connected[next] = true
+				queue = append(queue, next)
+			}
+		}
+	}
+
+	graph = &Graph{
+		Nodes:      []GraphNode{},
+		Edges:      []GraphEdge{},
+		downstream: map[string][]string{},
+	}
+	for _, node := range g.Nodes {
+		if connected[node.Name] {
+			graph.Nodes = append(graph.Nodes, node)
+		}
+	}
+	for _, edge := range g.Edges {
+		if connected[edge.Upstream] {
+			graph.Edges = append(graph.Edges, edge)
+			graph.downstream[edge.Upstream] = append(graph.downstream[edge.Upstream], edge.Downstream)
+		}
+	}
+	for _, cycle := range g.Cycles {
+		if connected[cycle[0]] {
+			graph.Cycles = append(graph.Cycles, cycle)
+		}
+	}
+	return
+}
+
+// findCycles finds the strongly connected components which have more than one Pipeline or a self-loop
+func (g *Graph) findCycles() (cycles [][]string) {
+	index := 0
+	indexes := map[string]int{}
+	lowLinks := map[string]int{}
+	onStack := map[string]bool{}
+	var stack []string
+
+	var connect func(name string)
+	connect = func(name string) {
+		indexes[name] = index
+		lowLinks[name] = index
+		index++
+		stack = append(stack, name)
+		onStack[name] = true
+
+		selfLoop := false
+		for _, next := range g.downstream[name] {
+			if next == name {
+				selfLoop = true
+			}
+			if _, visited := indexes[next]; !visited {
+				connect(next)
+				lowLinks[name] = min(lowLinks[name], lowLinks[next])
+			} else if onStack[next] {
+				lowLinks[name] = min(lowLinks[name], indexes[next])
+			}
+		}
+
+		if lowLinks[name] == indexes[name] {
+			var component []string
+			for {
+				last := stack[len(stack)-1]
+				stack = stack[:len(stack)-1]
+				onStack[last] = false
+				component = append(component, last)
+				if last == name {
+					break
+				}
+			}
+			if len(component) > 1 || selfLoop {
+				sort.Strings(component)
+				cycles = append(cycles, component)
+			}
+		}
+	}
+
+	for _, node := range g.Nodes {
+		if _, visited := indexes[node.Name]; !visited {
+			connect(node.Name)
+		}
+	}
+	sort.Slice(cycles, func(i, j int) bool {
+		return cycles[i][0] < cycles[j][0]
+	})
+	return
+}
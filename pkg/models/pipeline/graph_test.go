@@ -0,0 +1,127 @@
+/*
+Copyright 2022 The KubeSphere Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pipeline
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/kubesphere/ks-devops/pkg/api/devops/v1alpha3"
+)
+
+func newPipeline(name string, upstream ...string) v1alpha3.Pipeline {
+	pipeline := v1alpha3.Pipeline{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Spec:       v1alpha3.PipelineSpec{Type: v1alpha3.NoScmPipelineType},
+	}
+	if len(upstream) > 0 {
+		pipeline.Spec.Triggers = &v1alpha3.PipelineTriggers{}
+		for _, item := range upstream {
+			pipeline.Spec.Triggers.Upstream = append(pipeline.Spec.Triggers.Upstream, v1alpha3.UpstreamTrigger{Pipeline: item})
+		}
+	}
+	return pipeline
+}
+
+func TestBuildGraph(t *testing.T) {
+	graph := BuildGraph([]v1alpha3.Pipeline{
+		newPipeline("deploy", "test", "scan"),
+		newPipeline("build"),
+		newPipeline("test", "build"),
+	})
+	assert.Equal(t, []GraphNode{
+		{Name: "build", Type: v1alpha3.NoScmPipelineType},
+		{Name: "deploy", Type: v1alpha3.NoScmPipelineType},
+		{Name: "scan", Missing: true},
+		{Name: "test", Type: v1alpha3.NoScmPipelineType},
+	}, graph.Nodes)
+	succeeded := []v1alpha3.RunPhase{v1alpha3.Succeeded}
+	assert.Equal(t, []GraphEdge{
+		{Upstream: "build", Downstream: "test", Phases: succeeded},
+		{Upstream: "scan", Downstream: "deploy", Phases: succeeded},
+		{Upstream: "test", Downstream: "deploy", Phases: succeeded},
+	}, graph.Edges)
+	assert.Empty(t, graph.Cycles)
+
+	assert.True(t, graph.Reachable("build", "deploy"))
+	assert.False(t, graph.Reachable("deploy", "build"))
+	assert.False(t, graph.InCycle("build", "test"))
+
+	subgraph := BuildGraph([]v1alpha3.Pipeline{
+		newPipeline("build"),
+		newPipeline("test", "build"),
+		newPipeline("lint"),
+	}).Subgraph("test")
+	assert.Equal(t, []GraphNode{
+		{Name: "build", Type: v1alpha3.NoScmPipelineType},
+		{Name: "test", Type: v1alpha3.NoScmPipelineType},
+	}, subgraph.Nodes)
+	assert.Len(t, subgraph.Edges, 1)
+	assert.True(t, subgraph.Reachable("build", "test"))
+}
+
+func TestGraphCycles(t *testing.T) {
+	tests := []struct {
+		name      string
+		pipelines []v1alpha3.Pipeline
+		cycles    [][]string
+		inCycle   [2]string
+	}{{
+		name:      "self-loop",
+		pipelines: []v1alpha3.Pipeline{newPipeline("build", "build")},
+		cycles:    [][]string{{"build"}},
+		inCycle:   [2]string{"build", "build"},
+	}, {
+		name: "two Pipelines trigger each other",
+		pipelines: []v1alpha3.Pipeline{
+			newPipeline("build", "deploy"),
+			newPipeline("deploy", "build"),
+		},
+		cycles:  [][]string{{"build", "deploy"}},
+		inCycle: [2]string{"build", "deploy"},
+	}, {
+		name: "a loop with a tail",
+		pipelines: []v1alpha3.Pipeline{
+			newPipeline("a"),
+			newPipeline("b", "a", "d"),
+			newPipeline("c", "b"),
+			newPipeline("d", "c"),
+			newPipeline("e", "d"),
+			newPipeline("x", "x"),
+		},
+		cycles:  [][]string{{"b", "c", "d"}, {"x"}},
+		inCycle: [2]string{"d", "b"},
+	}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			graph := BuildGraph(tt.pipelines)
+			assert.Equal(t, tt.cycles, graph.Cycles)
+			assert.True(t, graph.InCycle(tt.inCycle[0], tt.inCycle[1]))
+		})
+	}
+
+	graph := BuildGraph([]v1alpha3.Pipeline{
+		newPipeline("a"),
+		newPipeline("b", "a", "c"),
+		newPipeline("c", "b"),
+	})
+	assert.False(t, graph.InCycle("a", "b"))
+	assert.True(t, graph.InCycle("c", "b"))
+	assert.Equal(t, [][]string{{"b", "c"}}, graph.Subgraph("a").Cycles)
+}
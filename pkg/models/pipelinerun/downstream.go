@@ -0,0 +1,64 @@
+/*
+Copyright 2022 The KubeSphere Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pipelinerun
+
+import (
+	"fmt"
+	"net/url"
+
+	"github.com/kubesphere/ks-devops/pkg/api/devops"
+	"github.com/kubesphere/ks-devops/pkg/api/devops/v1alpha3"
+)
+
+// GetArtifactDownloadPath returns the API path to download an artifact of a PipelineRun
+func GetArtifactDownloadPath(namespace, pipelineRunName, filename string) string {
+	return fmt.Sprintf("/kapis/%s/%s/namespaces/%s/pipelineruns/%s/artifacts/download?filename=%s",
+		devops.GroupName, v1alpha3.GroupVersion.Version, namespace, pipelineRunName, url.QueryEscape(filename))
+}
+
+// GetDownstreamParameters returns the parameters which are passed from the upstream PipelineRun to the downstream one.
+// The parameters missing in the upstream PipelineRun are skipped, so the downstream Pipeline uses the default values.
+// The parameters are validated against the definitions of the downstream Pipeline.
+func GetDownstreamParameters(trigger *v1alpha3.UpstreamTrigger, upstream *v1alpha3.PipelineRun,
+	downstream *v1alpha3.Pipeline) (result []v1alpha3.Parameter, err error) {
+	var parameters []v1alpha3.Parameter
+	for _, item := range trigger.Parameters {
+		from := item.From
+		if from == "" {
+			from = item.Name
+		}
+		for _, parameter := range upstream.Spec.Parameters {
+			if parameter.Name == from {
+				// the passwords are not persisted, the credential reference is passed instead
+				parameter.Name = item.Name
+				if parameter.ValueFrom != nil {
+					parameter.ValueFrom = parameter.ValueFrom.DeepCopy()
+				}
+				parameters = append(parameters, parameter)
+				break
+			}
+		}
+	}
+	for _, artifact := range trigger.Artifacts {
+		parameters = append(parameters, v1alpha3.Parameter{
+			Name:  artifact.Parameter,
+			Value: GetArtifactDownloadPath(upstream.Namespace, upstream.Name, artifact.Filename),
+		})
+	}
+	result, err = v1alpha3.ValidateParameters(downstream.GetParameterDefinitions(), parameters)
+	return
+}
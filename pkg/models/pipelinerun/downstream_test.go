@@ -0,0 +1,139 @@
+/*
+Copyright 2022 The KubeSphere Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pipelinerun
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/kubesphere/ks-devops/pkg/api/devops/v1alpha3"
+)
+
+func TestGetArtifactDownloadPath(t *testing.T) {
+	assert.Equal(t, "/kapis/devops.kubesphere.io/v1alpha3/namespaces/ns/pipelineruns/build-abcde/artifacts/download?filename=target%2Fapp.jar",
+		GetArtifactDownloadPath("ns", "build-abcde", "target/app.jar"))
+}
+
+func TestGetDownstreamParameters(t *testing.T) {
+	credential := &v1alpha3.ParameterValueSource{
+		CredentialRef: &v1alpha3.CredentialKeySelector{Name: "github"},
+	}
+	upstream := &v1alpha3.PipelineRun{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "build-abcde"},
+		Spec: v1alpha3.PipelineRunSpec{
+			Parameters: []v1alpha3.Parameter{
+				{Name: "version", Value: "v1.0.0"},
+				{Name: "env", Value: "dev"},
+				{Name: "token", ValueFrom: credential},
+			},
+		},
+	}
+
+	downstream := &v1alpha3.Pipeline{
+		Spec: v1alpha3.PipelineSpec{
+			Type: v1alpha3.NoScmPipelineType,
+			Pipeline: &v1alpha3.NoScmPipeline{
+				Parameters: []v1alpha3.ParameterDefinition{
+					{Name: "version", Type: v1alpha3.StringParameterType},
+					{Name: "environment", Type: v1alpha3.ChoiceParameterType, DefaultValue: "dev\nprod"},
+					{Name: "token", Type: v1alpha3.PasswordParameterType},
+					{Name: "package", Type: v1alpha3.StringParameterType},
+				},
+			},
+		},
+	}
+	multiBranchDownstream := &v1alpha3.Pipeline{
+		Spec: v1alpha3.PipelineSpec{Type: v1alpha3.MultiBranchPipelineType},
+	}
+
+	tests := []struct {
+		name       string
+		trigger    *v1alpha3.UpstreamTrigger
+		downstream *v1alpha3.Pipeline
+		want       []v1alpha3.Parameter
+		wantErr    bool
+	}{{
+		name:       "no parameters",
+		trigger:    &v1alpha3.UpstreamTrigger{Pipeline: "build"},
+		downstream: downstream,
+		want:       nil,
+	}, {
+		name: "parameters with the same or different names",
+		trigger: &v1alpha3.UpstreamTrigger{
+			Parameters: []v1alpha3.UpstreamParameter{
+				{Name: "version"},
+				{Name: "environment", From: "env"},
+				{Name: "missing"},
+			},
+		},
+		downstream: downstream,
+		want: []v1alpha3.Parameter{
+			{Name: "version", Value: "v1.0.0"},
+			{Name: "environment", Value: "dev"},
+		},
+	}, {
+		name: "password and artifact",
+		trigger: &v1alpha3.UpstreamTrigger{
+			Parameters: []v1alpha3.UpstreamParameter{{Name: "token"}},
+			Artifacts:  []v1alpha3.UpstreamArtifact{{Filename: "app.jar", Parameter: "package"}},
+		},
+		downstream: downstream,
+		want: []v1alpha3.Parameter{
+			{Name: "token", ValueFrom: credential},
+			{Name: "package", Value: "/kapis/devops.kubesphere.io/v1alpha3/namespaces/ns/pipelineruns/build-abcde/artifacts/download?filename=app.jar"},
+		},
+	}, {
+		name: "not defined in the downstream Pipeline",
+		trigger: &v1alpha3.UpstreamTrigger{
+			Parameters: []v1alpha3.UpstreamParameter{{Name: "env"}},
+		},
+		downstream: downstream,
+		wantErr:    true,
+	}, {
+		name: "invalid value for the downstream Pipeline",
+		trigger: &v1alpha3.UpstreamTrigger{
+			Parameters: []v1alpha3.UpstreamParameter{{Name: "environment", From: "version"}},
+		},
+		downstream: downstream,
+		wantErr:    true,
+	}, {
+		name: "a password is passed to a string parameter",
+		trigger: &v1alpha3.UpstreamTrigger{
+			Parameters: []v1alpha3.UpstreamParameter{{Name: "version", From: "token"}},
+		},
+		downstream: downstream,
+		wantErr:    true,
+	}, {
+		name: "multi-branch Pipeline defines the parameters in the Jenkinsfile",
+		trigger: &v1alpha3.UpstreamTrigger{
+			Parameters: []v1alpha3.UpstreamParameter{{Name: "env"}},
+		},
+		downstream: multiBranchDownstream,
+		want:       []v1alpha3.Parameter{{Name: "env", Value: "dev"}},
+	}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := GetDownstreamParameters(tt.trigger, upstream, tt.downstream)
+			assert.Equal(t, tt.wantErr, err != nil, err)
+			if !tt.wantErr {
+				assert.Equal(t, tt.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,90 @@
+/*
+Copyright 2022 The KubeSphere Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pipelinerun
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/kubesphere/ks-devops/pkg/api/devops/v1alpha3"
+)
+
+// ResolveParameters returns the parameters whose values are resolved from the DevOps credentials.
+// Only the parameters which are defined as passwords could reference a credential.
+// The result contains secrets, it must not be persisted.
+func ResolveParameters(ctx context.Context, reader client.Reader, namespace string,
+	definitions []v1alpha3.ParameterDefinition, parameters []v1alpha3.Parameter) (result []v1alpha3.Parameter, err error) {
+	passwords := make(map[string]bool, len(definitions))
+	for _, definition := range definitions {
+		passwords[definition.Name] = definition.Type == v1alpha3.PasswordParameterType
+	}
+
+	for _, parameter := range parameters {
+		if parameter.ValueFrom != nil && !passwords[parameter.Name] {
+			err = fmt.Errorf("parameter %s is not defined as a password, it could not reference a credential", parameter.Name)
+			return
+		}
+		if parameter.ValueFrom != nil && parameter.ValueFrom.CredentialRef != nil {
+			if parameter.Value, err = getCredentialValue(ctx, reader, namespace, parameter.ValueFrom.CredentialRef); err != nil {
+				err = fmt.Errorf("failed to resolve parameter %s, error: %v", parameter.Name, err)
+				return
+			}
+			parameter.ValueFrom = nil
+		}
+		result = append(result, parameter)
+	}
+	return
+}
+
+func getCredentialValue(ctx context.Context, reader client.Reader, namespace string, ref *v1alpha3.CredentialKeySelector) (
+	value string, err error) {
+	secret := &v1.Secret{}
+	if err = reader.Get(ctx, types.NamespacedName{Namespace: namespace, Name: ref.Name}, secret); err != nil {
+		return
+	}
+	// only the DevOps credentials are allowed, instead of any secrets in the namespace
+	if !strings.HasPrefix(string(secret.Type), v1alpha3.DevOpsCredentialPrefix) {
+		err = fmt.Errorf("%s is not a DevOps credential", ref.Name)
+		return
+	}
+
+	key := ref.Key
+	if key == "" {
+		switch secret.Type {
+		case v1alpha3.SecretTypeBasicAuth:
+			key = v1alpha3.BasicAuthPasswordKey
+		case v1alpha3.SecretTypeSecretText:
+			key = v1alpha3.SecretTextSecretKey
+		default:
+			err = fmt.Errorf("the key is required for the credential %s of type %s", ref.Name, secret.Type)
+			return
+		}
+	}
+
+	data, ok := secret.Data[key]
+	if !ok {
+		err = fmt.Errorf("the credential %s has no key %s", ref.Name, key)
+		return
+	}
+	value = string(data)
+	return
+}
@@ -0,0 +1,131 @@
+/*
+Copyright 2022 The KubeSphere Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pipelinerun
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/kubesphere/ks-devops/pkg/api/devops/v1alpha3"
+)
+
+func TestResolveParameters(t *testing.T) {
+	schema := runtime.NewScheme()
+	assert.Nil(t, v1.AddToScheme(schema))
+
+	newSecret := func(name string, secretType v1.SecretType, data map[string][]byte) *v1.Secret {
+		return &v1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: name},
+			Type:       secretType,
+			Data:       data,
+		}
+	}
+	credentialRef := func(name, key string) *v1alpha3.ParameterValueSource {
+		return &v1alpha3.ParameterValueSource{CredentialRef: &v1alpha3.CredentialKeySelector{Name: name, Key: key}}
+	}
+	reader := fake.NewClientBuilder().WithScheme(schema).WithObjects(
+		newSecret("basic", v1alpha3.SecretTypeBasicAuth, map[string][]byte{
+			v1alpha3.BasicAuthUsernameKey: []byte("admin"),
+			v1alpha3.BasicAuthPasswordKey: []byte("password"),
+		}),
+		newSecret("text", v1alpha3.SecretTypeSecretText, map[string][]byte{v1alpha3.SecretTextSecretKey: []byte("secret")}),
+		newSecret("ssh", v1alpha3.SecretTypeSSHAuth, map[string][]byte{v1alpha3.SSHAuthPassphraseKey: []byte("passphrase")}),
+		newSecret("opaque", v1.SecretTypeOpaque, map[string][]byte{"password": []byte("password")}),
+	).Build()
+
+	definitions := []v1alpha3.ParameterDefinition{
+		{Name: "name", Type: v1alpha3.StringParameterType},
+		{Name: "password", Type: v1alpha3.PasswordParameterType},
+		{Name: "username", Type: v1alpha3.PasswordParameterType},
+		{Name: "secret", Type: v1alpha3.PasswordParameterType},
+		{Name: "passphrase", Type: v1alpha3.PasswordParameterType},
+	}
+
+	tests := []struct {
+		name       string
+		parameters []v1alpha3.Parameter
+		expect     []v1alpha3.Parameter
+		expectErr  string
+	}{{
+		name: "no parameters",
+	}, {
+		name: "resolve the credentials",
+		parameters: []v1alpha3.Parameter{
+			{Name: "name", Value: "rick"},
+			{Name: "password", ValueFrom: credentialRef("basic", "")},
+			{Name: "username", ValueFrom: credentialRef("basic", v1alpha3.BasicAuthUsernameKey)},
+			{Name: "secret", ValueFrom: credentialRef("text", "")},
+			{Name: "passphrase", ValueFrom: credentialRef("ssh", v1alpha3.SSHAuthPassphraseKey)},
+		},
+		expect: []v1alpha3.Parameter{
+			{Name: "name", Value: "rick"},
+			{Name: "password", Value: "password"},
+			{Name: "username", Value: "admin"},
+			{Name: "secret", Value: "secret"},
+			{Name: "passphrase", Value: "passphrase"},
+		},
+	}, {
+		name:       "not a password",
+		parameters: []v1alpha3.Parameter{{Name: "name", ValueFrom: credentialRef("basic", "")}},
+		expectErr:  "parameter name is not defined as a password, it could not reference a credential",
+	}, {
+		name:       "not defined",
+		parameters: []v1alpha3.Parameter{{Name: "token", ValueFrom: credentialRef("text", "")}},
+		expectErr:  "parameter token is not defined as a password, it could not reference a credential",
+	}, {
+		name:       "credential not found",
+		parameters: []v1alpha3.Parameter{{Name: "password", ValueFrom: credentialRef("missing", "")}},
+		expectErr:  `failed to resolve parameter password, error: secrets "missing" not found`,
+	}, {
+		name:       "not a DevOps credential",
+		parameters: []v1alpha3.Parameter{{Name: "password", ValueFrom: credentialRef("opaque", "")}},
+		expectErr:  "failed to resolve parameter password, error: opaque is not a DevOps credential",
+	}, {
+		name:       "key is required",
+		parameters: []v1alpha3.Parameter{{Name: "password", ValueFrom: credentialRef("ssh", "")}},
+		expectErr: "failed to resolve parameter password, error: the key is required for the credential ssh of type " +
+			string(v1alpha3.SecretTypeSSHAuth),
+	}, {
+		name:       "key not found",
+		parameters: []v1alpha3.Parameter{{Name: "password", ValueFrom: credentialRef("text", "token")}},
+		expectErr:  "failed to resolve parameter password, error: the credential text has no key token",
+	}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var parameters []v1alpha3.Parameter
+			for i := range tt.parameters {
+				parameters = append(parameters, *tt.parameters[i].DeepCopy())
+			}
+
+			result, err := ResolveParameters(context.Background(), reader, "ns", definitions, tt.parameters)
+			if tt.expectErr != "" {
+				assert.EqualError(t, err, tt.expectErr)
+				return
+			}
+			assert.Nil(t, err)
+			assert.Equal(t, tt.expect, result)
+			// the given parameters are not changed
+			assert.Equal(t, parameters, tt.parameters)
+		})
+	}
+}
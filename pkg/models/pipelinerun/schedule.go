@@ -0,0 +1,109 @@
+/*
+Copyright 2022 The KubeSphere Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pipelinerun
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/robfig/cron/v3"
+
+	"github.com/kubesphere/ks-devops/pkg/api/devops/v1alpha3"
+)
+
+// DefaultStartingDeadline is the deadline of a schedule if it's not set
+const DefaultStartingDeadline = time.Minute
+
+// maxScheduleScan limits the schedules to scan, more missed schedules are regarded as all missed
+const maxScheduleScan = 100000
+
+// ScheduleDecision is what to do with a schedule at a moment
+type ScheduleDecision struct {
+	// Run indicates a PipelineRun should be created for the ScheduledTime
+	Run bool
+	// ScheduledTime is the latest schedule time which is due, it's zero if nothing is due
+	ScheduledTime time.Time
+	// Missed is the number of the schedules which are missed
+	Missed int
+	// Next is the next schedule time
+	Next time.Time
+}
+
+// ParseSchedule parses the cron expression in the time zone of the schedule
+func ParseSchedule(schedule *v1alpha3.PipelineSchedule) (cronSchedule cron.Schedule, err error) {
+	if schedule.TimeZone != "" {
+		if _, err = time.LoadLocation(schedule.TimeZone); err != nil {
+			err = fmt.Errorf("invalid time zone %q of schedule %s: %v", schedule.TimeZone, schedule.Name, err)
+			return
+		}
+		cronSchedule, err = cron.ParseStandard(fmt.Sprintf("CRON_TZ=%s %s", schedule.TimeZone, schedule.Cron))
+	} else {
+		cronSchedule, err = cron.ParseStandard(schedule.Cron)
+	}
+	if err != nil {
+		err = fmt.Errorf("invalid cron expression %q of schedule %s: %v", schedule.Cron, schedule.Name, err)
+	}
+	return
+}
+
+// DecideSchedule decides whether to create a PipelineRun for the schedule at the moment of now.
+// The status is the observed state of the schedule, it's nil if the schedule is new.
+func DecideSchedule(schedule *v1alpha3.PipelineSchedule, status *v1alpha3.ScheduleStatus, now time.Time) (
+	decision ScheduleDecision, err error) {
+	var cronSchedule cron.Schedule
+	if cronSchedule, err = ParseSchedule(schedule); err != nil {
+		return
+	}
+
+	// nothing was missed while a schedule was not there, or suspended
+	if status == nil || status.NextScheduleTime == nil || schedule.Suspend ||
+		status.Cron != schedule.Cron || status.TimeZone != schedule.TimeZone {
+		decision.Next = cronSchedule.Next(now)
+		return
+	}
+
+	next := status.NextScheduleTime.Time
+	if now.Before(next) {
+		decision.Next = next
+		return
+	}
+
+	// find the latest schedule time which is due
+	latest, count := next, 1
+	for scheduled := cronSchedule.Next(latest); !scheduled.After(now); scheduled = cronSchedule.Next(scheduled) {
+		if count >= maxScheduleScan {
+			break
+		}
+		latest = scheduled
+		count++
+	}
+	decision.ScheduledTime = latest
+	decision.Next = cronSchedule.Next(now)
+
+	deadline := DefaultStartingDeadline
+	if schedule.StartingDeadlineSeconds != nil {
+		deadline = time.Duration(*schedule.StartingDeadlineSeconds) * time.Second
+	}
+	late := count >= maxScheduleScan || now.Sub(latest) > deadline
+	if !late || schedule.MissedSchedulePolicy != v1alpha3.SkipMissedSchedulePolicy {
+		decision.Run = true
+		decision.Missed = count - 1
+	} else {
+		decision.Missed = count
+	}
+	return
+}
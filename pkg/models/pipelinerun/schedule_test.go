@@ -0,0 +1,129 @@
+/*
+Copyright 2022 The KubeSphere Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pipelinerun
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+
+	"github.com/kubesphere/ks-devops/pkg/api/devops/v1alpha3"
+)
+
+func TestParseSchedule(t *testing.T) {
+	_, err := ParseSchedule(&v1alpha3.PipelineSchedule{Name: "nightly", Cron: "0 2 * * *"})
+	assert.Nil(t, err)
+	_, err = ParseSchedule(&v1alpha3.PipelineSchedule{Name: "nightly", Cron: "@daily", TimeZone: "Asia/Shanghai"})
+	assert.Nil(t, err)
+	_, err = ParseSchedule(&v1alpha3.PipelineSchedule{Name: "nightly", Cron: "H 2 * * *"})
+	assert.NotNil(t, err)
+	_, err = ParseSchedule(&v1alpha3.PipelineSchedule{Name: "nightly", Cron: "0 2 * * *", TimeZone: "Mars/Base"})
+	assert.NotNil(t, err)
+
+	schedule, err := ParseSchedule(&v1alpha3.PipelineSchedule{Name: "nightly", Cron: "0 2 * * *", TimeZone: "Asia/Shanghai"})
+	assert.Nil(t, err)
+	// 02:00 in Shanghai is 18:00 in UTC
+	assert.Equal(t, time.Date(2024, 1, 1, 18, 0, 0, 0, time.UTC),
+		schedule.Next(time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)).UTC())
+}
+
+func TestDecideSchedule(t *testing.T) {
+	at := func(hour, minute int) time.Time {
+		return time.Date(2024, 1, 1, hour, minute, 0, 0, time.UTC)
+	}
+	statusOf := func(cron string, next time.Time) *v1alpha3.ScheduleStatus {
+		return &v1alpha3.ScheduleStatus{Name: "hourly", Cron: cron, NextScheduleTime: &metav1.Time{Time: next}}
+	}
+	hourly := v1alpha3.PipelineSchedule{Name: "hourly", Cron: "0 * * * *"}
+
+	tests := []struct {
+		name     string
+		schedule v1alpha3.PipelineSchedule
+		status   *v1alpha3.ScheduleStatus
+		now      time.Time
+		want     ScheduleDecision
+		wantErr  bool
+	}{{
+		name:     "invalid cron",
+		schedule: v1alpha3.PipelineSchedule{Name: "hourly", Cron: "invalid"},
+		now:      at(10, 30),
+		wantErr:  true,
+	}, {
+		name:     "a new schedule",
+		schedule: hourly,
+		now:      at(10, 30),
+		want:     ScheduleDecision{Next: at(11, 0)},
+	}, {
+		name:     "the cron was changed",
+		schedule: hourly,
+		status:   statusOf("0 0 * * *", at(0, 0)),
+		now:      at(10, 30),
+		want:     ScheduleDecision{Next: at(11, 0)},
+	}, {
+		name:     "not due yet",
+		schedule: hourly,
+		status:   statusOf(hourly.Cron, at(11, 0)),
+		now:      at(10, 30),
+		want:     ScheduleDecision{Next: at(11, 0)},
+	}, {
+		name:     "due in time",
+		schedule: hourly,
+		status:   statusOf(hourly.Cron, at(11, 0)),
+		now:      at(11, 0).Add(time.Second),
+		want:     ScheduleDecision{Run: true, ScheduledTime: at(11, 0), Next: at(12, 0)},
+	}, {
+		name:     "run once for the missed schedules",
+		schedule: hourly,
+		status:   statusOf(hourly.Cron, at(8, 0)),
+		now:      at(10, 30),
+		want:     ScheduleDecision{Run: true, ScheduledTime: at(10, 0), Missed: 2, Next: at(11, 0)},
+	}, {
+		name: "skip the missed schedules",
+		schedule: v1alpha3.PipelineSchedule{Name: "hourly", Cron: hourly.Cron,
+			MissedSchedulePolicy: v1alpha3.SkipMissedSchedulePolicy},
+		status: statusOf(hourly.Cron, at(8, 0)),
+		now:    at(10, 30),
+		want:   ScheduleDecision{ScheduledTime: at(10, 0), Missed: 3, Next: at(11, 0)},
+	}, {
+		name: "the latest schedule is within the deadline",
+		schedule: v1alpha3.PipelineSchedule{Name: "hourly", Cron: hourly.Cron,
+			MissedSchedulePolicy: v1alpha3.SkipMissedSchedulePolicy, StartingDeadlineSeconds: ptr.To[int64](3600)},
+		status: statusOf(hourly.Cron, at(8, 0)),
+		now:    at(10, 30),
+		want:   ScheduleDecision{Run: true, ScheduledTime: at(10, 0), Missed: 2, Next: at(11, 0)},
+	}, {
+		name:     "suspended",
+		schedule: v1alpha3.PipelineSchedule{Name: "hourly", Cron: hourly.Cron, Suspend: true},
+		status:   statusOf(hourly.Cron, at(8, 0)),
+		now:      at(10, 30),
+		want:     ScheduleDecision{Next: at(11, 0)},
+	}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := DecideSchedule(&tt.schedule, tt.status, tt.now)
+			if tt.wantErr {
+				assert.NotNil(t, err)
+				return
+			}
+			assert.Nil(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
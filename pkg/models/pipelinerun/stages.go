@@ -0,0 +1,41 @@
+/*
+Copyright 2022 The KubeSphere Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pipelinerun
+
+import (
+	"context"
+	"encoding/json"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/kubesphere/ks-devops/pkg/api/devops/v1alpha3"
+	cmstore "github.com/kubesphere/ks-devops/pkg/store/configmap"
+)
+
+// GetStages returns the stages of the PipelineRun from its annotation or the data store
+func GetStages(ctx context.Context, c client.Client, run *v1alpha3.PipelineRun) (stages []NodeDetail) {
+	stagesJSON, ok := run.GetAnnotations()[v1alpha3.JenkinsPipelineRunStagesStatusAnnoKey]
+	if !ok {
+		if dataStore, err := cmstore.NewConfigMapStore(ctx, client.ObjectKeyFromObject(run), c); err == nil {
+			stagesJSON = dataStore.GetStages()
+		}
+	}
+	if stagesJSON != "" {
+		_ = json.Unmarshal([]byte(stagesJSON), &stages)
+	}
+	return
+}
@@ -0,0 +1,67 @@
+/*
+Copyright 2022 The KubeSphere Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pipelinerun
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/kubesphere/ks-devops/pkg/api/devops/v1alpha3"
+	"github.com/kubesphere/ks-devops/pkg/store/store"
+)
+
+func TestGetStages(t *testing.T) {
+	tests := []struct {
+		name    string
+		run     *v1alpha3.PipelineRun
+		objects []client.Object
+		want    []string
+	}{{
+		name: "from the annotation",
+		run: &v1alpha3.PipelineRun{ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "run", Annotations: map[string]string{
+			v1alpha3.JenkinsPipelineRunStagesStatusAnnoKey: `[{"id":"1","displayName":"build"}]`,
+		}}},
+		want: []string{"build"},
+	}, {
+		name: "from the data store",
+		run:  &v1alpha3.PipelineRun{ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "run"}},
+		objects: []client.Object{&corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "run"},
+			Data:       map[string]string{store.DataKeyStage: `[{"id":"1","displayName":"build"},{"id":"2","displayName":"test"}]`},
+		}},
+		want: []string{"build", "test"},
+	}, {
+		name: "no stages",
+		run:  &v1alpha3.PipelineRun{ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "run"}},
+	}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := fake.NewClientBuilder().WithObjects(tt.objects...).Build()
+			var names []string
+			for _, stage := range GetStages(context.Background(), c, tt.run) {
+				names = append(names, stage.DisplayName)
+			}
+			assert.Equal(t, tt.want, names)
+		})
+	}
+}